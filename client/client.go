@@ -0,0 +1,213 @@
+// Package client is a small Go client for the operator station's HTTP API, for companion tools and
+// scripts that upload recordings, read flights and statistics, or log events.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+	"github.com/kaireichart/master-thesis-operator-station/events"
+)
+
+// DefaultBaseURL is the address the station listens on
+const DefaultBaseURL = "http://127.0.0.1:8080"
+
+// importPollInterval is how often an upload checks whether its imports finished
+const importPollInterval = time.Second
+
+// Client calls the station's API
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// APIError is returned when the station answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string // Response body, as written by http.Error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("station returned %d: %s", e.StatusCode, e.Message)
+}
+
+// UploadResult is the response to an upload
+type UploadResult struct {
+	Status  string                           `json:"status"` // "success", "partial" when some records or files failed to import, "skipped" when every file was imported before, or "failed"
+	Message string                           `json:"message"`
+	Flights []data_analysis.Flight           `json:"flights"`
+	Errors  []data_analysis.ImportError      `json:"errors"`
+	Files   []data_analysis.UploadFileResult `json:"files"` // Outcome per uploaded file
+}
+
+// New returns a client for the station at baseURL, e.g. DefaultBaseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 5 * time.Minute}, // Large recordings take a while to import
+	}
+}
+
+// do sends a request and decodes a JSON response into out, unless out is nil
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// Upload imports a Sky Dolly logbook (.sdlog, .sqlite, .db) or CSV recording from a file
+func (c *Client) Upload(ctx context.Context, path string) (*UploadResult, error) {
+	return c.UploadFiles(ctx, path)
+}
+
+// UploadFiles imports several recordings in one request and waits until the station imported them.
+// Files are imported independently; those that fail are reported in the result's Files.
+func (c *Client) UploadFiles(ctx context.Context, paths ...string) (*UploadResult, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, path := range paths {
+		if err := addFormFile(writer, path); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish form: %w", err)
+	}
+
+	var queued struct {
+		Jobs []data_analysis.ImportJob `json:"jobs"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/data-analysis/upload", writer.FormDataContentType(), body, &queued); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(queued.Jobs))
+	for i, job := range queued.Jobs {
+		ids[i] = job.ID
+	}
+
+	// The station imports the files in the background
+	ticker := time.NewTicker(importPollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := c.ImportStatus(ctx, ids...)
+		if err != nil {
+			return nil, err
+		}
+		if status.Summary != nil {
+			result := UploadResult(*status.Summary)
+			return &result, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ImportStatus reports the station's import jobs, or those with the given IDs together with their
+// combined result once all of them finished
+func (c *Client) ImportStatus(ctx context.Context, ids ...string) (*data_analysis.ImportStatus, error) {
+	path := "/data-analysis/import-status"
+	if len(ids) > 0 {
+		path += "?ids=" + url.QueryEscape(strings.Join(ids, ","))
+	}
+	var status data_analysis.ImportStatus
+	if err := c.do(ctx, http.MethodGet, path, "", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// CancelImport cancels a queued or running import job
+func (c *Client) CancelImport(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/data-analysis/import-status/"+url.PathEscape(id)+"/cancel", "", nil, nil)
+}
+
+// addFormFile adds a file to the upload form
+func addFormFile(writer *multipart.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	part, err := writer.CreateFormFile("database", filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// Flights lists all imported flights
+func (c *Client) Flights(ctx context.Context) ([]data_analysis.Flight, error) {
+	var flights []data_analysis.Flight
+	if err := c.do(ctx, http.MethodGet, "/data-analysis/flights", "", nil, &flights); err != nil {
+		return nil, err
+	}
+	return flights, nil
+}
+
+// FlightStatistics returns the statistics of a flight, keyed by aircraft label
+func (c *Client) FlightStatistics(ctx context.Context, flightID int) (map[string]*data_analysis.FlightStatistics, error) {
+	var statistics map[string]*data_analysis.FlightStatistics
+	path := fmt.Sprintf("/data-analysis/flights/%d/statistics", flightID)
+	if err := c.do(ctx, http.MethodGet, path, "", nil, &statistics); err != nil {
+		return nil, err
+	}
+	return statistics, nil
+}
+
+// LogEvent records an event in the station's event log, stamped with the station's time
+func (c *Client) LogEvent(ctx context.Context, eventType, program string) error {
+	body, err := json.Marshal(map[string]string{"type": eventType, "program": program})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	return c.do(ctx, http.MethodPost, "/manual-event", "application/json", bytes.NewReader(body), nil)
+}
+
+// Events returns the most recent events of the station's event log
+func (c *Client) Events(ctx context.Context) ([]events.Event, error) {
+	var list []events.Event
+	if err := c.do(ctx, http.MethodGet, "/events", "", nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
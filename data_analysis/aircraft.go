@@ -0,0 +1,201 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errDuplicateAircraftLabel is returned when an edit would give two aircraft of a flight the same label
+var errDuplicateAircraftLabel = errors.New("another aircraft of this flight already has this label")
+
+// Label returns the name an aircraft is keyed by in flight data, e.g. "C172 (G-ABCD)"
+func (a Aircraft) Label() string {
+	if a.TailNumber == "" {
+		return a.Type
+	}
+	return fmt.Sprintf("%s (%s)", a.Type, a.TailNumber)
+}
+
+// handleGetAircraft lists the aircraft of a flight with their sample counts and time ranges
+func handleGetAircraft(w http.ResponseWriter, r *http.Request, flightId int) {
+	summaries, err := getAircraftSummaries(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get aircraft: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
+
+// handleGetAircraftQuery serves the aircraft of the flight given by the flightId query parameter
+func handleGetAircraftQuery(w http.ResponseWriter, r *http.Request) {
+	r.SetPathValue("id", r.URL.Query().Get("flightId"))
+	if _, err := strconv.Atoi(r.PathValue("id")); err != nil {
+		httpError(w, "Missing or invalid flightId parameter", http.StatusBadRequest)
+		return
+	}
+	withFlightID(handleGetAircraft)(w, r)
+}
+
+// handleUpdateAircraft edits the type, tail number or airline of an aircraft
+func handleUpdateAircraft(w http.ResponseWriter, r *http.Request, flightId int) {
+	aircraftId, err := strconv.Atoi(r.PathValue("aircraftId"))
+	if err != nil {
+		httpError(w, "Invalid aircraft ID", http.StatusBadRequest)
+		return
+	}
+
+	var update AircraftUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if update.Type != nil && strings.TrimSpace(*update.Type) == "" {
+		httpError(w, "Type must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	aircraft, err := updateAircraft(flightId, aircraftId, update)
+	if err == sql.ErrNoRows {
+		httpError(w, "Aircraft not found", http.StatusNotFound)
+		return
+	} else if err == errDuplicateAircraftLabel {
+		httpError(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		httpError(w, fmt.Sprintf("Failed to update aircraft: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(aircraft)
+}
+
+// updateAircraft applies a metadata edit to an aircraft of a flight. Since the aircraft label keys
+// all per-aircraft analysis data, cached series and stored metrics of the flight are invalidated.
+func updateAircraft(flightID, aircraftID int, update AircraftUpdate) (*Aircraft, error) {
+	aircraft, err := getAircraftByFlightIDFromMainDB(flightID)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *Aircraft
+	for i := range aircraft {
+		if aircraft[i].ID == aircraftID {
+			target = &aircraft[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if update.Type != nil {
+		target.Type = strings.TrimSpace(*update.Type)
+	}
+	if update.TailNumber != nil {
+		target.TailNumber = strings.TrimSpace(*update.TailNumber)
+	}
+	if update.Airline != nil {
+		target.Airline = strings.TrimSpace(*update.Airline)
+	}
+
+	for _, other := range aircraft {
+		if other.ID != target.ID && other.Label() == target.Label() {
+			return nil, errDuplicateAircraftLabel
+		}
+	}
+
+	tx, err := mainDB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE aircraft SET type = ?, tail_number = ?, airline = ? WHERE id = ? AND flight_id = ?`
+	if _, err := tx.Exec(query, target.Type, target.TailNumber, target.Airline, aircraftID, flightID); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM flight_metrics WHERE flight_id = ?", flightID); err != nil {
+		return nil, fmt.Errorf("failed to invalidate stored metrics: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit aircraft update: %w", err)
+	}
+
+	invalidateFlightColumns(flightID)
+
+	log.Printf("Updated aircraft %d of flight %d to '%s'", aircraftID, flightID, target.Label())
+	return target, nil
+}
+
+// getAircraftSummaries returns the aircraft of a flight with position sample counts and time ranges
+func getAircraftSummaries(flightID int) ([]AircraftSummary, error) {
+	aircraft, err := getAircraftByFlightIDFromMainDB(flightID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT COUNT(*), COALESCE(MIN(timestamp), 0), COALESCE(MAX(timestamp), 0)
+		FROM position
+		WHERE aircraft_id = ?
+	`
+
+	target, err := getUserAircraftLabel(flightID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get target aircraft: %w", err)
+	}
+
+	summaries := make([]AircraftSummary, 0, len(aircraft))
+	for _, ac := range aircraft {
+		summary := AircraftSummary{Aircraft: ac, Label: ac.Label()}
+		summary.Target = summary.Label == target
+
+		err := mainDB.QueryRow(query, ac.ID).Scan(&summary.SampleCount, &summary.StartTimestamp, &summary.EndTimestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize position data for aircraft %d: %w", ac.ID, err)
+		}
+		summary.DurationSeconds = float64(summary.EndTimestamp-summary.StartTimestamp) / 1000.0
+
+		provenance, err := getPositionProvenance(ac.ID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get position provenance for aircraft %d: %w", ac.ID, err)
+		}
+		summary.Provenance = provenance
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, nil
+}
+
+// getPositionProvenance loads the position provenance of an aircraft, if it was recorded
+func getPositionProvenance(aircraftID int) (*PositionProvenance, error) {
+	provenance := &PositionProvenance{AircraftID: aircraftID}
+	query := `
+		SELECT source_sample_count, source_sample_rate_hz, imported_sample_count,
+		       thin_every_n, thin_min_delta_ms
+		FROM position_provenance
+		WHERE aircraft_id = ?
+	`
+
+	err := mainDB.QueryRow(query, aircraftID).Scan(
+		&provenance.SourceSampleCount, &provenance.SourceSampleRateHz, &provenance.ImportedSampleCount,
+		&provenance.ThinEveryN, &provenance.ThinMinDeltaMs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return provenance, nil
+}
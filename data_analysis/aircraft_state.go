@@ -0,0 +1,190 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// StatePoint is a sample of the aircraft state channels of CSV recordings. Channels the recording
+// does not have are left out.
+type StatePoint struct {
+	Timestamp           int64    `json:"timestamp"`
+	TimestampSeconds    float64  `json:"timestamp_seconds"`
+	FlapsHandlePosition *float64 `json:"flaps_handle_position,omitempty"`
+	GearDown            *bool    `json:"gear_down,omitempty"`
+	FuelTotalGallons    *float64 `json:"fuel_total_gallons,omitempty"`
+	GForce              *float64 `json:"g_force,omitempty"`
+	StallWarning        *bool    `json:"stall_warning,omitempty"`
+	OverspeedWarning    *bool    `json:"overspeed_warning,omitempty"`
+}
+
+// csvStateChannels maps the CSV header channels to the aircraft_state columns they are stored in
+var csvStateChannels = []struct {
+	name   string
+	column string
+	value  func(*CSVFlightRecord) interface{}
+}{
+	{"flapshandleposition", "flaps_handle_position", func(r *CSVFlightRecord) interface{} { return r.FlapsHandlePosition }},
+	{"geardown", "gear_down", func(r *CSVFlightRecord) interface{} { return r.GearDown }},
+	{"fueltotalquantity", "fuel_total_gallons", func(r *CSVFlightRecord) interface{} { return r.FuelTotalQuantity }},
+	{"gforce", "g_force", func(r *CSVFlightRecord) interface{} { return r.GForce }},
+	{"stallwarning", "stall_warning", func(r *CSVFlightRecord) interface{} { return r.StallWarning }},
+	{"overspeedwarning", "overspeed_warning", func(r *CSVFlightRecord) interface{} { return r.OverspeedWarning }},
+}
+
+// ensureAircraftStateTable creates the table of the aircraft state channels recorded in CSV files,
+// which the Sky Dolly schema has no columns for
+func ensureAircraftStateTable() error {
+	stateSchema := `
+		CREATE TABLE IF NOT EXISTS aircraft_state (
+			aircraft_id INTEGER NOT NULL,
+			timestamp INTEGER NOT NULL,
+			flaps_handle_position REAL,
+			gear_down INTEGER,
+			fuel_total_gallons REAL,
+			g_force REAL,
+			stall_warning INTEGER,
+			overspeed_warning INTEGER,
+			PRIMARY KEY(aircraft_id, timestamp),
+			FOREIGN KEY(aircraft_id) REFERENCES aircraft(id)
+		);
+	`
+
+	if _, err := mainDB.Exec(stateSchema); err != nil {
+		return fmt.Errorf("failed to create aircraft_state table: %w", err)
+	}
+	return nil
+}
+
+// importAircraftStateFromCSV stores the state channels a CSV recording has; channels it lacks are
+// stored as NULL, and nothing is stored without any of them
+func importAircraftStateFromCSV(tx *sql.Tx, aircraftID int, csvData *CSVFlightData) error {
+	var recorded []bool
+	any := false
+	for _, channel := range csvStateChannels {
+		present := false
+		for _, header := range csvData.Headers {
+			if strings.Contains(strings.ToLower(header), channel.name) {
+				present = true
+				break
+			}
+		}
+		recorded = append(recorded, present)
+		any = any || present
+	}
+	if !any {
+		return nil
+	}
+
+	columns := make([]string, len(csvStateChannels))
+	for i, channel := range csvStateChannels {
+		columns[i] = channel.column
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO aircraft_state (aircraft_id, timestamp, %s) VALUES (?, ?%s)",
+		strings.Join(columns, ", "), strings.Repeat(", ?", len(columns))))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	baseTimestamp := csvBaseTimestamp(csvData)
+
+	args := make([]interface{}, len(columns)+2)
+	for i := range csvData.Records {
+		record := &csvData.Records[i]
+		args[0], args[1] = aircraftID, baseTimestamp+int64(record.TimestampSeconds*1000)
+		for c, channel := range csvStateChannels {
+			args[c+2] = nil
+			if recorded[c] {
+				args[c+2] = channel.value(record)
+			}
+		}
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getAircraftStateFromMainDB returns the state samples of an aircraft with times relative to its
+// first state sample
+func getAircraftStateFromMainDB(aircraftID int) ([]StatePoint, error) {
+	query := `
+		SELECT timestamp, flaps_handle_position, gear_down, fuel_total_gallons, g_force,
+		       stall_warning, overspeed_warning
+		FROM aircraft_state
+		WHERE aircraft_id = ?
+		ORDER BY timestamp
+	`
+
+	rows, err := mainDB.Query(query, aircraftID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []StatePoint
+	var minTimestamp int64
+	for rows.Next() {
+		var point StatePoint
+		var flaps, fuel, gForce sql.NullFloat64
+		var gearDown, stall, overspeed sql.NullBool
+		if err := rows.Scan(&point.Timestamp, &flaps, &gearDown, &fuel, &gForce, &stall, &overspeed); err != nil {
+			return nil, err
+		}
+		if len(points) == 0 {
+			minTimestamp = point.Timestamp
+		}
+		point.TimestampSeconds = float64(point.Timestamp-minTimestamp) / 1000.0
+
+		if flaps.Valid {
+			point.FlapsHandlePosition = &flaps.Float64
+		}
+		if gearDown.Valid {
+			point.GearDown = &gearDown.Bool
+		}
+		if fuel.Valid {
+			point.FuelTotalGallons = &fuel.Float64
+		}
+		if gForce.Valid {
+			point.GForce = &gForce.Float64
+		}
+		if stall.Valid {
+			point.StallWarning = &stall.Bool
+		}
+		if overspeed.Valid {
+			point.OverspeedWarning = &overspeed.Bool
+		}
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
+
+// downsampleState reduces a state series to at most maxPoints samples, preserving the shape and
+// extremes of the g-force and flaps
+func downsampleState(points []StatePoint, maxPoints int) []StatePoint {
+	if len(points) <= maxPoints {
+		return points
+	}
+
+	x := make([]float64, len(points))
+	gForce := make([]float64, len(points))
+	flaps := make([]float64, len(points))
+	for i, p := range points {
+		x[i] = p.TimestampSeconds
+		if p.GForce != nil {
+			gForce[i] = *p.GForce
+		}
+		if p.FlapsHandlePosition != nil {
+			flaps[i] = *p.FlapsHandlePosition
+		}
+	}
+
+	indices := lttbIndices(x, [][]float64{gForce, flaps}, maxPoints)
+	result := make([]StatePoint, len(indices))
+	for i, index := range indices {
+		result[i] = points[index]
+	}
+	return result
+}
@@ -0,0 +1,159 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+// International Standard Atmosphere at sea level and in the troposphere
+const (
+	isaSeaLevelTemperatureK = 288.15
+	isaLapseRateKPerFt      = 0.0019812
+	isaPressureExponent     = 5.25588
+	celsiusToKelvin         = 273.15
+)
+
+// Airspeed sources of a position sample
+const (
+	AirspeedRecorded  = "recorded"  // Indicated airspeed recorded by the simulator
+	AirspeedEstimated = "estimated" // Derived from the ground speed, wind and air density
+)
+
+// ensurePositionAirDataColumns adds the true airspeed and ambient temperature recorded per sample by CSV
+// imports, used to convert between indicated and true airspeed
+func ensurePositionAirDataColumns() error {
+	for _, column := range []string{"true_airspeed", "ambient_temperature"} {
+		var exists bool
+		err := mainDB.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('position') WHERE name = ?", column).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to get position table info: %w", err)
+		}
+		if exists {
+			continue
+		}
+
+		log.Printf("Adding %s column to position table...", column)
+		if _, err := mainDB.Exec(fmt.Sprintf("ALTER TABLE position ADD COLUMN %s REAL", column)); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// csvHasTrueAirspeedColumn reports whether a CSV file records the true airspeed
+func csvHasTrueAirspeedColumn(headers []string) bool {
+	for _, header := range headers {
+		if strings.Contains(strings.ToLower(header), "airspeedtrue") {
+			return true
+		}
+	}
+	return false
+}
+
+// csvHasTemperatureColumn reports whether a CSV file records the ambient temperature
+func csvHasTemperatureColumn(headers []string) bool {
+	for _, header := range headers {
+		headerLower := strings.ToLower(header)
+		if strings.Contains(headerLower, "ambienttemperature") && !strings.Contains(headerLower, "total") {
+			return true
+		}
+	}
+	return false
+}
+
+// densityRatio returns the air density relative to the ISA sea level density at a pressure altitude in
+// feet. The temperature in Celsius defaults to the ISA temperature at that altitude.
+func densityRatio(pressureAltitudeFt float64, temperatureC *float64) float64 {
+	isaTemperatureK := isaSeaLevelTemperatureK - isaLapseRateKPerFt*pressureAltitudeFt
+	pressureRatio := math.Pow(isaTemperatureK/isaSeaLevelTemperatureK, isaPressureExponent)
+	temperatureK := isaTemperatureK
+	if temperatureC != nil {
+		temperatureK = *temperatureC + celsiusToKelvin
+	}
+	return pressureRatio * isaSeaLevelTemperatureK / temperatureK
+}
+
+// getAircraftFlightWeather returns the weather stored with the flight of an aircraft, nil if none
+func getAircraftFlightWeather(aircraftID int) (*FlightWeather, error) {
+	var weather flightWeatherScan
+	err := mainDB.QueryRow(`
+		SELECT `+flightWeatherColumns+`
+		FROM flight f
+		JOIN aircraft a ON a.flight_id = f.id
+		WHERE a.id = ?
+	`, aircraftID).Scan(weather.dest()...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return weather.weather(), nil
+}
+
+// deriveAirspeeds fills the true airspeed of samples without a recorded one and estimates the indicated
+// airspeed of samples without a recorded one. The true airspeed of the latter is the ground velocity minus the wind (per-sample wind,
+// else the flight's, else none, leaving the ground speed). Indicated and true airspeed are converted with
+// the air density at the pressure altitude and the per-sample or flight ambient temperature, or the ISA
+// temperature. Indicated airspeed is taken as equivalent airspeed, which holds well below 200 kt.
+func deriveAirspeeds(positions []PositionPoint, weather *FlightWeather) {
+	var flightTemperature *float64
+	var flightWindEast, flightWindNorth float64
+	if weather != nil {
+		flightTemperature = weather.AmbientTemperature
+		if weather.WindSpeed != nil && weather.WindDirection != nil {
+			flightWindEast, flightWindNorth = windVector(*weather.WindSpeed, *weather.WindDirection)
+		}
+	}
+
+	for i := range positions {
+		pos := &positions[i]
+		temperature := pos.AmbientTemperature
+		if temperature == nil {
+			temperature = flightTemperature
+		}
+		pressureAltitude := pos.PressureAltitude
+		if pressureAltitude == 0 {
+			pressureAltitude = pos.IndicatedAltitude
+		}
+		sqrtSigma := math.Sqrt(densityRatio(pressureAltitude, temperature))
+
+		if pos.Airspeed > 0 {
+			pos.AirspeedSource = AirspeedRecorded
+			if pos.TrueAirspeed == 0 {
+				pos.TrueAirspeed = pos.Airspeed / sqrtSigma
+			}
+			continue
+		}
+
+		windEast, windNorth := flightWindEast, flightWindNorth
+		if pos.WindSpeed != nil && pos.WindDirection != nil {
+			windEast, windNorth = windVector(*pos.WindSpeed, *pos.WindDirection)
+		}
+		trackRad := pos.GroundTrack * math.Pi / 180
+		groundEast, groundNorth := pos.GroundSpeed*math.Sin(trackRad), pos.GroundSpeed*math.Cos(trackRad)
+		pos.TrueAirspeed = math.Hypot(groundEast-windEast, groundNorth-windNorth)
+		pos.Airspeed = pos.TrueAirspeed * sqrtSigma
+		pos.AirspeedSource = AirspeedEstimated
+	}
+}
+
+// airspeedSource describes whether the indicated airspeeds of a series were recorded, estimated or both
+func airspeedSource(estimated []bool) string {
+	var recorded, derived bool
+	for _, e := range estimated {
+		derived = derived || e
+		recorded = recorded || !e
+	}
+	switch {
+	case recorded && derived:
+		return "mixed"
+	case derived:
+		return AirspeedEstimated
+	default:
+		return AirspeedRecorded
+	}
+}
@@ -0,0 +1,184 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// FlightAlignment describes the marker a flight's times were shifted to, so that it is at t=0
+type FlightAlignment struct {
+	MarkerID      int     `json:"marker_id"`
+	Label         string  `json:"label"`
+	OffsetSeconds float64 `json:"offset_seconds"` // Marker time in the unaligned flight, subtracted from all times
+}
+
+// Errors resolving the marker a flight is aligned on
+var (
+	errAlignmentMarkerNotFound = errors.New("alignment marker not found")
+	errInvalidAlignmentMarker  = errors.New("invalid align_marker")
+)
+
+// findAlignmentMarker returns the marker with the given ID or, if markerID is 0, the earliest marker
+// with the given label
+func findAlignmentMarker(flightID, markerID int, label string) (*FlightAlignment, error) {
+	markers, err := getMarkersForFlight(flightID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range markers {
+		if (markerID != 0 && m.ID == markerID) || (markerID == 0 && m.Label == label) {
+			return &FlightAlignment{MarkerID: m.ID, Label: m.Label, OffsetSeconds: m.Time}, nil
+		}
+	}
+	return nil, errAlignmentMarkerNotFound
+}
+
+// parseAlignment reads the align_marker (marker ID) or align_label parameter. It returns nil when
+// neither is given.
+func parseAlignment(r *http.Request, flightID int) (*FlightAlignment, error) {
+	query := r.URL.Query()
+	if value := query.Get("align_marker"); value != "" {
+		markerID, err := strconv.Atoi(value)
+		if err != nil || markerID <= 0 {
+			return nil, fmt.Errorf("%w '%s'", errInvalidAlignmentMarker, value)
+		}
+		return findAlignmentMarker(flightID, markerID, "")
+	}
+	if label := query.Get("align_label"); label != "" {
+		return findAlignmentMarker(flightID, 0, label)
+	}
+	return nil, nil
+}
+
+// writeAlignmentError answers a request whose alignment parameter could not be resolved
+func writeAlignmentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errAlignmentMarkerNotFound):
+		httpError(w, "Alignment marker not found", http.StatusNotFound)
+	case errors.Is(err, errInvalidAlignmentMarker):
+		httpError(w, err.Error(), http.StatusBadRequest)
+	default:
+		httpError(w, fmt.Sprintf("Failed to get markers: %v", err), http.StatusInternalServerError)
+	}
+}
+
+// alignFlightData shifts the relative times of all series so the alignment marker is at t=0;
+// samples before it get negative times
+func alignFlightData(flightData *FlightData, alignment *FlightAlignment) {
+	offset := alignment.OffsetSeconds
+	for _, positions := range flightData.PositionData {
+		for i := range positions {
+			positions[i].TimestampSeconds -= offset
+		}
+	}
+	for _, attitudes := range flightData.AttitudeData {
+		for i := range attitudes {
+			attitudes[i].TimestampSeconds -= offset
+		}
+	}
+	for _, engines := range flightData.EngineData {
+		for i := range engines {
+			engines[i].TimestampSeconds -= offset
+		}
+	}
+	for _, states := range flightData.StateData {
+		for i := range states {
+			states[i].TimestampSeconds -= offset
+		}
+	}
+	flightData.Alignment = alignment
+}
+
+// alignMarkers shifts marker times so the alignment marker is at t=0
+func alignMarkers(markers []Marker, alignment *FlightAlignment) {
+	for i := range markers {
+		markers[i].Time -= alignment.OffsetSeconds
+	}
+}
+
+// flightDurationSeconds returns the time between the first and last sample of any table of a flight
+func flightDurationSeconds(flightID int) (float64, error) {
+	query := `
+		SELECT COALESCE((MAX(timestamp) - MIN(timestamp)) / 1000.0, 0)
+		FROM (
+			SELECT p.timestamp FROM position p JOIN aircraft a ON a.id = p.aircraft_id WHERE a.flight_id = ?
+			UNION ALL
+			SELECT t.timestamp FROM attitude t JOIN aircraft a ON a.id = t.aircraft_id WHERE a.flight_id = ?
+			UNION ALL
+			SELECT e.timestamp FROM engine e JOIN aircraft a ON a.id = e.aircraft_id WHERE a.flight_id = ?
+		)
+	`
+	var duration float64
+	err := mainDB.QueryRow(query, flightID, flightID, flightID).Scan(&duration)
+	return duration, err
+}
+
+// handleAlignFlight creates a copy of a flight starting at a marker, which becomes t=0 of the copy.
+// Data before the marker is left out; use align_marker or align_label on the flight data to keep it.
+func handleAlignFlight(w http.ResponseWriter, r *http.Request, flightId int) {
+	var request struct {
+		NewTitle    string `json:"new_title"`
+		MarkerID    int    `json:"marker_id"`
+		MarkerLabel string `json:"marker_label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if request.NewTitle == "" {
+		httpError(w, "New title is required", http.StatusBadRequest)
+		return
+	}
+	if request.MarkerID == 0 && request.MarkerLabel == "" {
+		httpError(w, "marker_id or marker_label is required", http.StatusBadRequest)
+		return
+	}
+
+	alignment, err := findAlignmentMarker(flightId, request.MarkerID, request.MarkerLabel)
+	if err != nil {
+		writeAlignmentError(w, err)
+		return
+	}
+
+	duration, err := flightDurationSeconds(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight duration: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if duration-alignment.OffsetSeconds < analysisConfig.MinTrimSeconds {
+		httpError(w, fmt.Sprintf("Less than %g seconds of data after the marker", analysisConfig.MinTrimSeconds), http.StatusBadRequest)
+		return
+	}
+
+	exists, err := flightTitleExists(request.NewTitle)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to check title uniqueness: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if exists {
+		httpError(w, "A flight with this title already exists", http.StatusConflict)
+		return
+	}
+
+	newFlightID, err := trimFlight(flightId, request.NewTitle, alignment.OffsetSeconds, duration)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to align flight: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if _, err := assessFlightQuality(newFlightID); err != nil {
+		log.Printf("Failed to assess quality of flight %d: %v", newFlightID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "success",
+		"message":       fmt.Sprintf("Flight aligned on '%s' with ID %d", alignment.Label, newFlightID),
+		"new_flight_id": newFlightID,
+		"alignment":     alignment,
+	})
+}
@@ -0,0 +1,273 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// Approaches are the parts of a flight inside the approach gate of a runway and within the corridor
+// either side of its extended centerline, flying towards the threshold
+const (
+	approachCorridorNM    = 1.0  // Lateral distance from the extended centerline within which a flight is on approach
+	approachMinSeconds    = 10.0 // Shortest time on approach that counts as an approach
+	approachMinProgressNM = 0.5  // Distance an approach has to close on the threshold, so taxiing and holding are left out
+
+	feetPerNauticalMile = metersPerNauticalMile / 0.3048
+)
+
+// Violations of the stabilized approach criteria
+const (
+	approachViolationSpeed       = "speed"
+	approachViolationDescentRate = "descent_rate"
+	approachViolationLateral     = "lateral"
+)
+
+// ApproachCriteria are the limits an approach has to stay within to be stabilized
+type ApproachCriteria struct {
+	GateNM                float64 `json:"gate_nm"` // Distance from the threshold at which the approach is assessed from
+	TargetSpeedKnots      float64 `json:"target_speed_kt"`
+	SpeedAboveKnots       float64 `json:"speed_above_kt"` // Largest airspeed above the target speed
+	SpeedBelowKnots       float64 `json:"speed_below_kt"` // Largest airspeed below the target speed
+	MaxDescentRateFPM     float64 `json:"max_descent_rate_fpm"`
+	MaxLateralDeviationFt float64 `json:"max_lateral_deviation_ft"`
+}
+
+// ApproachStability holds the metrics of one approach and whether it was stabilized. Deviations from the
+// target speed are positive when faster; lateral deviations are positive right of the extended centerline.
+type ApproachStability struct {
+	StartTime              float64  `json:"start_time"`
+	EndTime                float64  `json:"end_time"`
+	Samples                int      `json:"samples"`
+	ReachedThreshold       bool     `json:"reached_threshold"` // The flight passed the threshold at the end of the approach
+	StartDistanceNM        float64  `json:"start_distance_nm"`
+	StartHeightFt          float64  `json:"start_height_ft"` // Height above the threshold at the start of the approach
+	MeanAirspeedKnots      float64  `json:"mean_airspeed_kt"`
+	MinSpeedDeviationKnots float64  `json:"min_speed_deviation_kt"`
+	MaxSpeedDeviationKnots float64  `json:"max_speed_deviation_kt"`
+	MeanDescentRateFPM     float64  `json:"mean_descent_rate_fpm"`
+	MaxDescentRateFPM      float64  `json:"max_descent_rate_fpm"`
+	MeanLateralDeviationFt float64  `json:"mean_lateral_deviation_ft"`
+	MaxLateralDeviationFt  float64  `json:"max_lateral_deviation_ft"` // Largest deviation to either side
+	UnstableSeconds        float64  `json:"unstable_seconds"`         // Time outside any of the criteria
+	Violations             []string `json:"violations"`               // Criteria exceeded: "speed", "descent_rate" or "lateral"
+	Stabilized             bool     `json:"stabilized"`
+}
+
+// ApproachReport holds the approaches of a flight's aircraft to a runway
+type ApproachReport struct {
+	FlightID int                            `json:"flight_id"`
+	Runway   Runway                         `json:"runway"`
+	Criteria ApproachCriteria               `json:"criteria"`
+	Aircraft map[string][]ApproachStability `json:"aircraft"`
+}
+
+// defaultApproachCriteria returns the criteria of the analysis configuration
+func defaultApproachCriteria() ApproachCriteria {
+	return ApproachCriteria{
+		GateNM:                analysisConfig.ApproachGateNM,
+		TargetSpeedKnots:      analysisConfig.ApproachSpeedKnots,
+		SpeedAboveKnots:       analysisConfig.ApproachSpeedAboveKnots,
+		SpeedBelowKnots:       analysisConfig.ApproachSpeedBelowKnots,
+		MaxDescentRateFPM:     analysisConfig.ApproachMaxDescentRateFPM,
+		MaxLateralDeviationFt: analysisConfig.ApproachMaxLateralFt,
+	}
+}
+
+// finalApproachPosition returns the distance of a position before the threshold along the extended
+// centerline, negative past the threshold, and its lateral distance from the centerline in feet, positive
+// to the right. Within a few miles of the threshold a flat projection is accurate enough.
+func (rw *Runway) finalApproachPosition(lat, lon float64) (distanceNM, lateralFt float64) {
+	north := (lat - rw.Latitude) * 60
+	east := (lon - rw.Longitude) * 60 * math.Cos(rw.Latitude*math.Pi/180)
+
+	heading := rw.HeadingDeg * math.Pi / 180
+	along := east*math.Sin(heading) + north*math.Cos(heading)
+	lateral := east*math.Cos(heading) - north*math.Sin(heading)
+	return -along, lateral * feetPerNauticalMile
+}
+
+// approachSegment is one approach of an aircraft: the samples start to end, with the distance from the
+// threshold and lateral deviation of every sample of the flight
+type approachSegment struct {
+	start, end          int
+	distances, laterals []float64
+	reachedThreshold    bool
+}
+
+// findApproaches finds the approaches of one aircraft to a runway within gateNM of its threshold
+func findApproaches(series *SeriesColumns, rw *Runway, gateNM float64) []approachSegment {
+	n := len(series.Time)
+	distances := make([]float64, n)
+	laterals := make([]float64, n)
+	onApproach := make([]bool, n)
+	for i := 0; i < n; i++ {
+		lat, lon := series.Latitude[i], series.Longitude[i]
+		if math.IsNaN(lat) || math.IsNaN(lon) {
+			distances[i] = math.NaN()
+			continue
+		}
+		distances[i], laterals[i] = rw.finalApproachPosition(lat, lon)
+		onApproach[i] = distances[i] >= 0 && distances[i] <= gateNM &&
+			math.Abs(laterals[i]) <= approachCorridorNM*feetPerNauticalMile
+	}
+
+	segments := []approachSegment{}
+	for start := 0; start < n; start++ {
+		if !onApproach[start] {
+			continue
+		}
+		end := start
+		for end+1 < n && onApproach[end+1] {
+			end++
+		}
+		if series.Time[end]-series.Time[start] >= approachMinSeconds &&
+			distances[start]-distances[end] >= approachMinProgressNM {
+			segments = append(segments, approachSegment{
+				start:            start,
+				end:              end,
+				distances:        distances,
+				laterals:         laterals,
+				reachedThreshold: end+1 < n && distances[end+1] < 0,
+			})
+		}
+		start = end
+	}
+	return segments
+}
+
+// calculateApproaches finds the approaches of one aircraft to a runway and assesses each against the criteria
+func calculateApproaches(series *SeriesColumns, rw *Runway, criteria ApproachCriteria) []ApproachStability {
+	approaches := []ApproachStability{}
+	for _, segment := range findApproaches(series, rw, criteria.GateNM) {
+		approaches = append(approaches, assessApproach(series, segment, rw, criteria))
+	}
+	return approaches
+}
+
+// assessApproach computes the metrics of the samples of an approach
+func assessApproach(series *SeriesColumns, segment approachSegment, rw *Runway, criteria ApproachCriteria) ApproachStability {
+	start, end, laterals := segment.start, segment.end, segment.laterals
+	approach := ApproachStability{
+		StartTime:        series.Time[start],
+		EndTime:          series.Time[end],
+		Samples:          end - start + 1,
+		ReachedThreshold: segment.reachedThreshold,
+		StartDistanceNM:  segment.distances[start],
+		StartHeightFt:    series.Altitude[start] - rw.ElevationFt,
+		Violations:       []string{},
+	}
+
+	violated := map[string]bool{}
+	var speedSum, descentSum, lateralSum float64
+	var speedSamples, descentSamples int
+	approach.MinSpeedDeviationKnots = math.Inf(1)
+	approach.MaxSpeedDeviationKnots = math.Inf(-1)
+	approach.MaxDescentRateFPM = math.Inf(-1)
+	for i := start; i <= end; i++ {
+		unstable := false
+
+		if airspeed := series.Airspeed[i]; !math.IsNaN(airspeed) {
+			deviation := airspeed - criteria.TargetSpeedKnots
+			speedSum += airspeed
+			speedSamples++
+			approach.MinSpeedDeviationKnots = math.Min(approach.MinSpeedDeviationKnots, deviation)
+			approach.MaxSpeedDeviationKnots = math.Max(approach.MaxSpeedDeviationKnots, deviation)
+			if deviation > criteria.SpeedAboveKnots || deviation < -criteria.SpeedBelowKnots {
+				violated[approachViolationSpeed], unstable = true, true
+			}
+		}
+
+		if verticalSpeed := series.VerticalSpeed[i]; !math.IsNaN(verticalSpeed) {
+			descentRate := -verticalSpeed
+			descentSum += descentRate
+			descentSamples++
+			approach.MaxDescentRateFPM = math.Max(approach.MaxDescentRateFPM, descentRate)
+			if descentRate > criteria.MaxDescentRateFPM {
+				violated[approachViolationDescentRate], unstable = true, true
+			}
+		}
+
+		lateralSum += laterals[i]
+		approach.MaxLateralDeviationFt = math.Max(approach.MaxLateralDeviationFt, math.Abs(laterals[i]))
+		if math.Abs(laterals[i]) > criteria.MaxLateralDeviationFt {
+			violated[approachViolationLateral], unstable = true, true
+		}
+
+		// A sample counts until the next one
+		if unstable && i < end {
+			approach.UnstableSeconds += series.Time[i+1] - series.Time[i]
+		}
+	}
+
+	if speedSamples > 0 {
+		approach.MeanAirspeedKnots = speedSum / float64(speedSamples)
+	} else {
+		approach.MinSpeedDeviationKnots, approach.MaxSpeedDeviationKnots = 0, 0
+	}
+	if descentSamples > 0 {
+		approach.MeanDescentRateFPM = descentSum / float64(descentSamples)
+	} else {
+		approach.MaxDescentRateFPM = 0
+	}
+	approach.MeanLateralDeviationFt = lateralSum / float64(approach.Samples)
+
+	for _, violation := range []string{approachViolationSpeed, approachViolationDescentRate, approachViolationLateral} {
+		if violated[violation] {
+			approach.Violations = append(approach.Violations, violation)
+		}
+	}
+	approach.Stabilized = len(approach.Violations) == 0
+	return approach
+}
+
+// handleGetApproachStability assesses the approaches of the target aircraft to a runway of the library,
+// or of every aircraft with aircraft=all. The target speed can be set per request with target_speed.
+func handleGetApproachStability(w http.ResponseWriter, r *http.Request, flightId int) {
+	query := r.URL.Query()
+	if query.Get("runway") == "" {
+		httpError(w, "runway is required", http.StatusBadRequest)
+		return
+	}
+	rw, ok := lookupRunway(w, query.Get("runway"))
+	if !ok {
+		return
+	}
+
+	criteria := defaultApproachCriteria()
+	if value := query.Get("target_speed"); value != "" {
+		speed, err := strconv.ParseFloat(value, 64)
+		if err != nil || !(speed > 0) || math.IsInf(speed, 1) {
+			httpError(w, fmt.Sprintf("Invalid target_speed '%s' (expected knots above 0)", value), http.StatusBadRequest)
+			return
+		}
+		criteria.TargetSpeedKnots = speed
+	}
+
+	columns, err := getFlightColumns(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	aircraft := map[string][]ApproachStability{}
+	for label, series := range columns {
+		aircraft[label] = calculateApproaches(series, rw, criteria)
+	}
+	if !allAircraftRequested(r) {
+		if aircraft, err = targetAircraftOnly(flightId, aircraft); err != nil {
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ApproachReport{
+		FlightID: flightId,
+		Runway:   *rw,
+		Criteria: criteria,
+		Aircraft: aircraft,
+	})
+}
@@ -0,0 +1,167 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// ensureFlightArchivedColumn adds the time a flight was archived; archived flights keep all their data
+// so an accidental delete can be undone
+func ensureFlightArchivedColumn() error {
+	var exists bool
+	err := mainDB.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('flight') WHERE name = 'archived_at'").Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get flight table info: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	log.Println("Adding archived_at column to flight table...")
+	if _, err := mainDB.Exec("ALTER TABLE flight ADD COLUMN archived_at TEXT"); err != nil {
+		return fmt.Errorf("failed to add archived_at column: %w", err)
+	}
+	return nil
+}
+
+// archivePurgeInterval is how often flights archived longer than the retention window are purged
+const archivePurgeInterval = 1 * time.Hour
+
+// archivePurgeTime returns when a flight archived at archivedAt is purged automatically, empty if archived
+// flights are kept until purged by hand
+func archivePurgeTime(archivedAt string) string {
+	if archivedAt == "" || analysisConfig.ArchiveRetentionDays == 0 {
+		return ""
+	}
+	archived, err := time.Parse(time.RFC3339, archivedAt)
+	if err != nil {
+		return ""
+	}
+	return archived.AddDate(0, 0, analysisConfig.ArchiveRetentionDays).Format(time.RFC3339)
+}
+
+// startArchivePurge purges flights archived longer than the retention window until the process exits
+func startArchivePurge() {
+	if analysisConfig.ArchiveRetentionDays == 0 {
+		return
+	}
+	for {
+		purgeExpiredArchivedFlights(time.Now())
+		time.Sleep(archivePurgeInterval)
+	}
+}
+
+// purgeExpiredArchivedFlights permanently deletes the flights archived longer than the retention window
+// before now, returning the IDs of the flights purged. Nothing is purged while the dataset is frozen.
+func purgeExpiredArchivedFlights(now time.Time) []int {
+	if readOnly.Load() {
+		return nil
+	}
+
+	// Archive times are RFC 3339 in UTC, so they compare as strings
+	cutoff := now.UTC().AddDate(0, 0, -analysisConfig.ArchiveRetentionDays).Format(time.RFC3339)
+	rows, err := mainDB.Query("SELECT id FROM flight WHERE archived_at IS NOT NULL AND archived_at < ? ORDER BY id", cutoff)
+	if err != nil {
+		log.Printf("Failed to find expired archived flights: %v", err)
+		return nil
+	}
+	var flightIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Printf("Failed to find expired archived flights: %v", err)
+			return nil
+		}
+		flightIDs = append(flightIDs, id)
+	}
+	rows.Close()
+	if len(flightIDs) == 0 {
+		return nil
+	}
+
+	log.Printf("Warning: purging %d flights archived more than %d days ago (archive_retention_days): %v",
+		len(flightIDs), analysisConfig.ArchiveRetentionDays, flightIDs)
+	var purged []int
+	for _, flightID := range flightIDs {
+		if err := DeleteFlight(flightID); err != nil {
+			log.Printf("Failed to purge expired archived flight %d: %v", flightID, err)
+			continue
+		}
+		purged = append(purged, flightID)
+		log.Printf("Purged flight %d, archived more than %d days ago", flightID, analysisConfig.ArchiveRetentionDays)
+	}
+	return purged
+}
+
+// archiveFlight hides a flight from the flight list, exports and statistics
+func archiveFlight(flightID int) error {
+	archivedAt := time.Now().UTC().Format(time.RFC3339)
+	if _, err := mainDB.Exec("UPDATE flight SET archived_at = ? WHERE id = ?", archivedAt, flightID); err != nil {
+		return fmt.Errorf("failed to archive flight: %w", err)
+	}
+	log.Printf("Archived flight %d", flightID)
+	return nil
+}
+
+// restoreFlight returns an archived flight to the flight list
+func restoreFlight(flightID int) error {
+	if _, err := mainDB.Exec("UPDATE flight SET archived_at = NULL WHERE id = ?", flightID); err != nil {
+		return fmt.Errorf("failed to restore flight: %w", err)
+	}
+	log.Printf("Restored flight %d", flightID)
+	return nil
+}
+
+// handleRestoreFlight returns an archived flight to the flight list
+func handleRestoreFlight(w http.ResponseWriter, r *http.Request, flightId int) {
+	flight, err := getFlightByIDFromMainDB(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if flight.ArchivedAt == "" {
+		httpError(w, fmt.Sprintf("Flight %d is not archived", flightId), http.StatusConflict)
+		return
+	}
+
+	if err := restoreFlight(flightId); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Flight '%s' (ID: %d) restored", flight.Title, flightId),
+	})
+}
+
+// handlePurgeFlight permanently deletes an archived flight and all its data. Only archived flights can
+// be purged, so removing a flight always takes two deliberate steps.
+func handlePurgeFlight(w http.ResponseWriter, r *http.Request, flightId int) {
+	flight, err := getFlightByIDFromMainDB(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if flight.ArchivedAt == "" {
+		httpError(w, fmt.Sprintf("Flight %d is not archived (archive it before purging)", flightId), http.StatusConflict)
+		return
+	}
+
+	if err := DeleteFlight(flightId); err != nil {
+		httpError(w, fmt.Sprintf("Failed to purge flight: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Purged flight %d", flightId)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Flight '%s' (ID: %d) purged permanently", flight.Title, flightId),
+	})
+}
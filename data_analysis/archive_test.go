@@ -0,0 +1,101 @@
+package data_analysis
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// archiveTestFlights adds a flight archived at each time, empty for one that is not archived, returning
+// their IDs
+func archiveTestFlights(t *testing.T, archivedAt ...string) []int {
+	t.Helper()
+
+	ids := make([]int, len(archivedAt))
+	for i, at := range archivedAt {
+		ids[i], _ = insertTestFlight(t, "Flight", "", "D-TEST")
+		if at == "" {
+			continue
+		}
+		if _, err := mainDB.Exec("UPDATE flight SET archived_at = ? WHERE id = ?", at, ids[i]); err != nil {
+			t.Fatalf("failed to archive flight: %v", err)
+		}
+	}
+	return ids
+}
+
+func remainingFlightIDs(t *testing.T) []int {
+	t.Helper()
+
+	rows, err := mainDB.Query("SELECT id FROM flight ORDER BY id")
+	if err != nil {
+		t.Fatalf("failed to list flights: %v", err)
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("failed to scan flight: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestPurgeExpiredArchivedFlights(t *testing.T) {
+	openTestDatabase(t)
+	previous := analysisConfig
+	analysisConfig.ArchiveRetentionDays = 30
+	t.Cleanup(func() { analysisConfig = previous })
+
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	ids := archiveTestFlights(t,
+		now.AddDate(0, 0, -31).Format(time.RFC3339),                   // Expired
+		now.AddDate(0, 0, -30).Add(-time.Second).Format(time.RFC3339), // Just expired
+		now.AddDate(0, 0, -30).Add(time.Second).Format(time.RFC3339),  // Just within the window
+		now.AddDate(0, 0, -1).Format(time.RFC3339),                    // Recently archived
+		"", // Not archived
+	)
+
+	purged := purgeExpiredArchivedFlights(now)
+	if want := ids[:2]; !slices.Equal(purged, want) {
+		t.Errorf("purged %v, want %v", purged, want)
+	}
+	if remaining, want := remainingFlightIDs(t), ids[2:]; !slices.Equal(remaining, want) {
+		t.Errorf("remaining flights %v, want %v", remaining, want)
+	}
+}
+
+func TestPurgeExpiredArchivedFlightsFrozen(t *testing.T) {
+	openTestDatabase(t)
+	previous := analysisConfig
+	analysisConfig.ArchiveRetentionDays = 30
+	t.Cleanup(func() { analysisConfig = previous })
+
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	ids := archiveTestFlights(t, now.AddDate(0, 0, -60).Format(time.RFC3339))
+
+	readOnly.Store(true)
+	t.Cleanup(func() { readOnly.Store(false) })
+
+	if purged := purgeExpiredArchivedFlights(now); len(purged) != 0 {
+		t.Errorf("purged %v while the dataset is frozen", purged)
+	}
+	if remaining := remainingFlightIDs(t); !slices.Equal(remaining, ids) {
+		t.Errorf("remaining flights %v, want %v", remaining, ids)
+	}
+}
+
+func TestArchiveRetentionDisabledByDefault(t *testing.T) {
+	if defaultAnalysisConfig.ArchiveRetentionDays != 0 {
+		t.Errorf("archive_retention_days defaults to %d, want 0 so archived flights are only purged by hand", defaultAnalysisConfig.ArchiveRetentionDays)
+	}
+
+	previous := analysisConfig
+	analysisConfig = defaultAnalysisConfig
+	t.Cleanup(func() { analysisConfig = previous })
+	if purgeAt := archivePurgeTime("2025-01-01T00:00:00Z"); purgeAt != "" {
+		t.Errorf("purge time %q without a retention window", purgeAt)
+	}
+}
@@ -0,0 +1,70 @@
+package data_analysis
+
+import (
+	"math"
+)
+
+// maxBankAngle is the bank angle in degrees beyond which a sample counts as an exceedance
+const maxBankAngle = 30.0
+
+// BankExceedances summarizes the episodes in which the bank angle exceeded maxBankAngle
+type BankExceedances struct {
+	ThresholdDegrees float64 `json:"threshold_degrees"`
+	Count            int     `json:"count"`            // Number of separate episodes beyond the threshold
+	DurationSeconds  float64 `json:"duration_seconds"` // Total time beyond the threshold
+	MaxBank          float64 `json:"max_bank"`         // Largest absolute bank angle of the flight
+}
+
+// calculateAttitudeStatistics adds bank angle, pitch, turn rate and bank exceedance statistics
+func calculateAttitudeStatistics(stats *FlightStatistics, attitude *AttitudeColumns) {
+	n := len(attitude.Time)
+	if n == 0 {
+		return
+	}
+
+	absBank := make([]float64, n)
+	for i, bank := range attitude.Bank {
+		absBank[i] = math.Abs(bank)
+	}
+	stats.BankAngleStats = calculateDataStatistics(absBank)
+	stats.PitchStats = calculateDataStatistics(attitude.Pitch)
+	stats.TurnRateStats = calculateDataStatistics(turnRates(attitude))
+	stats.BankExceedances = bankExceedances(attitude.Time, absBank, maxBankAngle)
+}
+
+// turnRates returns the absolute heading change between consecutive attitude samples in degrees per second
+func turnRates(attitude *AttitudeColumns) []float64 {
+	var rates []float64
+	for i := 1; i < len(attitude.Time); i++ {
+		dt := attitude.Time[i] - attitude.Time[i-1]
+		if dt <= 0 {
+			continue
+		}
+		// Shortest heading change, so crossing north is not a full turn
+		change := math.Mod(attitude.TrueHeading[i]-attitude.TrueHeading[i-1]+540, 360) - 180
+		rates = append(rates, math.Abs(change)/dt)
+	}
+	return rates
+}
+
+// bankExceedances counts the episodes with an absolute bank beyond the threshold and their total
+// duration; each sample beyond it counts until the next sample
+func bankExceedances(times, absBank []float64, threshold float64) *BankExceedances {
+	result := &BankExceedances{ThresholdDegrees: threshold}
+	exceeding := false
+	for i, bank := range absBank {
+		result.MaxBank = math.Max(result.MaxBank, bank)
+		if bank <= threshold {
+			exceeding = false
+			continue
+		}
+		if !exceeding {
+			result.Count++
+			exceeding = true
+		}
+		if i+1 < len(times) {
+			result.DurationSeconds += times[i+1] - times[i]
+		}
+	}
+	return result
+}
@@ -0,0 +1,50 @@
+package data_analysis
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// handleBackup sends a consistent snapshot of the analysis database as a download. The snapshot is
+// written to a temporary file with VACUUM INTO, so imports running meanwhile do not corrupt it.
+func handleBackup(w http.ResponseWriter, r *http.Request) {
+	dir, err := os.MkdirTemp("", "data_analysis_backup")
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create backup directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data_analysis.db")
+	if err := SnapshotDatabase(path); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to open backup: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to read backup: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("data_analysis_%s.db", time.Now().Format("20060102_150405"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Backup: %v", err)
+		return
+	}
+	log.Printf("Backup: sent %s (%d bytes)", filename, info.Size())
+}
@@ -0,0 +1,155 @@
+package data_analysis
+
+import (
+	"archive/zip"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// flightFolderName returns the folder of a flight in a batch export, e.g. "003_Baseline_P001"
+func flightFolderName(flight *Flight) string {
+	title := strings.Trim(unsafeFileNameChars.ReplaceAllString(flight.Title, "_"), "_")
+	if title == "" {
+		title = "Flight"
+	}
+	return fmt.Sprintf("%03d_%s", flight.ID, title)
+}
+
+// parseFlightIDList parses a comma-separated list of flight IDs
+func parseFlightIDList(value string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid flight ID '%s'", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// selectExportFlights returns all flights with a review status in the review_status parameter (all but
+// rejected flights by default), or those listed in the flight_ids parameter.
+// On failure it writes the error response and returns false.
+func selectExportFlights(w http.ResponseWriter, r *http.Request) ([]*Flight, bool) {
+	var flights []*Flight
+
+	if value := r.URL.Query().Get("flight_ids"); value != "" {
+		ids, err := parseFlightIDList(value)
+		if err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		for _, id := range ids {
+			flight, err := getFlightByIDFromMainDB(id)
+			if err == sql.ErrNoRows {
+				httpError(w, fmt.Sprintf("Flight %d not found", id), http.StatusNotFound)
+				return nil, false
+			}
+			if err != nil {
+				httpError(w, fmt.Sprintf("Failed to get flight %d: %v", id, err), http.StatusInternalServerError)
+				return nil, false
+			}
+			flights = append(flights, flight)
+		}
+	} else {
+		statuses, err := parseReviewFilter(r)
+		if err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		all, err := getFlightsFromMainDB()
+		if err != nil {
+			httpError(w, fmt.Sprintf("Failed to get flights: %v", err), http.StatusInternalServerError)
+			return nil, false
+		}
+		for i := range all {
+			if slices.Contains(statuses, all[i].ReviewStatus) {
+				flights = append(flights, &all[i])
+			}
+		}
+	}
+
+	if len(flights) == 0 {
+		httpError(w, "No flights to export", http.StatusNotFound)
+		return nil, false
+	}
+	return flights, true
+}
+
+// handleBatchExport exports all flights, or those listed in the flight_ids parameter, into one ZIP with a
+// folder per flight containing its CSV files and markers
+func handleBatchExport(w http.ResponseWriter, r *http.Request) {
+	flights, ok := selectExportFlights(w, r)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("flights_export_%s.zip", time.Now().Format("20060102_150405"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	// The archive is streamed, so errors after the first flight can only be logged
+	if err := writeBatchExport(w, flights); err != nil {
+		log.Printf("Batch export: %v", err)
+		return
+	}
+	log.Printf("Batch export: exported %d flights", len(flights))
+}
+
+// writeBatchExport writes a ZIP with a folder per flight containing its CSV files and markers
+func writeBatchExport(w io.Writer, flights []*Flight) error {
+	zw := zip.NewWriter(w)
+	if _, err := WriteFlightFolders(zw, "", flights); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to close zip writer: %w", err)
+	}
+	return nil
+}
+
+// WriteFlightFolders writes a folder per flight containing its CSV files and markers below dir of a ZIP,
+// and returns the paths of the folders
+func WriteFlightFolders(zw *zip.Writer, dir string, flights []*Flight) ([]string, error) {
+	var folders []string
+	for _, flight := range flights {
+		columns, err := getFlightColumns(flight.ID)
+		if err != nil {
+			return folders, fmt.Errorf("failed to get data of flight %d: %w", flight.ID, err)
+		}
+		markers, err := getExportMarkers(flight.ID)
+		if err != nil {
+			return folders, fmt.Errorf("failed to get markers of flight %d: %w", flight.ID, err)
+		}
+
+		options := CSVExportOptions{
+			FlightID: flight.ID,
+			Format:   "airspeed-altitude",
+			Flight:   flight,
+			Markers:  markers,
+		}
+		folder := path.Join(dir, flightFolderName(flight))
+		if err := writeFlightCSVFiles(zw, folder, columns, options); err != nil {
+			return folders, fmt.Errorf("failed to write flight %d: %w", flight.ID, err)
+		}
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
@@ -0,0 +1,326 @@
+package data_analysis
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxUploadChunkBytes limits the body of one chunk; clients send recordings in chunks of at most this size
+	maxUploadChunkBytes = 64 << 20
+	// chunkedUploadExpiry is how long an upload is kept without activity, so abandoned partial files
+	// do not pile up in the temp directory
+	chunkedUploadExpiry = 24 * time.Hour
+)
+
+// Statuses of a chunked upload
+const (
+	uploadStatusUploading = "uploading"
+	uploadStatusImporting = "importing"
+	uploadStatusCompleted = "completed"
+	uploadStatusFailed    = "failed"
+)
+
+// ChunkedUpload is a recording uploaded in chunks and imported once all its bytes arrived. An interrupted
+// upload is resumed by sending the remaining bytes from Received.
+type ChunkedUpload struct {
+	ID        string            `json:"id"`
+	Filename  string            `json:"filename"`
+	Size      int64             `json:"size"`
+	Received  int64             `json:"received"`
+	Progress  float64           `json:"progress"`         // Fraction of the bytes received, 0 to 1
+	Status    string            `json:"status"`           // "uploading", "importing", "completed" or "failed"
+	JobID     string            `json:"job_id,omitempty"` // Import job of the recording once all bytes arrived
+	Result    *UploadFileResult `json:"result,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// CreateChunkedUploadRequest announces a recording to be uploaded in chunks, with the options it is
+// imported with
+type CreateChunkedUploadRequest struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"` // Bytes
+	ImportOptions
+}
+
+// chunkedUpload is an upload in progress with the options its recording is imported with
+type chunkedUpload struct {
+	ChunkedUpload
+	path    string
+	options ImportOptions
+	writing bool // A chunk is being written
+}
+
+var (
+	chunkedUploads     = map[string]*chunkedUpload{}
+	chunkedUploadMutex = &sync.Mutex{}
+)
+
+// state returns the upload as reported to clients. Must be called with chunkedUploadMutex held.
+func (u *chunkedUpload) state() ChunkedUpload {
+	state := u.ChunkedUpload
+	state.Progress = float64(u.Received) / float64(u.Size)
+	return state
+}
+
+// newRandomID returns a random ID for an upload or import job
+func newRandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// removeStaleUploads removes uploads without activity for chunkedUploadExpiry with their partial files.
+// Must be called with chunkedUploadMutex held.
+func removeStaleUploads(now time.Time) {
+	for id, u := range chunkedUploads {
+		if u.Status == uploadStatusImporting || u.writing || now.Sub(u.UpdatedAt) < chunkedUploadExpiry {
+			continue
+		}
+		os.Remove(u.path)
+		delete(chunkedUploads, id)
+	}
+}
+
+// lookupChunkedUpload resolves the uploadId path value; on failure it writes the error response.
+// Must be called with chunkedUploadMutex held.
+func lookupChunkedUpload(w http.ResponseWriter, r *http.Request) (*chunkedUpload, bool) {
+	upload, exists := chunkedUploads[r.PathValue("uploadId")]
+	if !exists {
+		httpError(w, "Upload not found", http.StatusNotFound)
+		return nil, false
+	}
+	return upload, true
+}
+
+// handleCreateChunkedUpload starts an upload of a recording too large for a single request
+func handleCreateChunkedUpload(w http.ResponseWriter, r *http.Request) {
+	var req CreateChunkedUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	filename := filepath.Base(req.Filename)
+	if !isSupportedUploadFile(filename) {
+		httpError(w, fmt.Sprintf("Invalid file format of %s. Please upload SQLite database files (.sdlog, .sqlite, .db) or CSV files (.csv).", filename), http.StatusBadRequest)
+		return
+	}
+	if req.Size <= 0 {
+		httpError(w, "size must be above 0", http.StatusBadRequest)
+		return
+	}
+
+	id, err := newRandomID()
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+	upload := &chunkedUpload{
+		ChunkedUpload: ChunkedUpload{
+			ID:        id,
+			Filename:  filename,
+			Size:      req.Size,
+			Status:    uploadStatusUploading,
+			UpdatedAt: time.Now(),
+		},
+		path:    filepath.Join(tempDir, fmt.Sprintf("chunked_%s_%s", id, filename)),
+		options: req.ImportOptions,
+	}
+	file, err := os.Create(upload.path)
+	if err != nil {
+		httpError(w, "Failed to save file", http.StatusInternalServerError)
+		return
+	}
+	file.Close()
+
+	chunkedUploadMutex.Lock()
+	removeStaleUploads(upload.UpdatedAt)
+	chunkedUploads[id] = upload
+	state := upload.state()
+	chunkedUploadMutex.Unlock()
+
+	log.Printf("Chunked upload %s: receiving %s (%d bytes)", id, filename, req.Size)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(state)
+}
+
+// handleGetChunkedUpload reports the progress of an upload and, once imported, its result
+func handleGetChunkedUpload(w http.ResponseWriter, r *http.Request) {
+	chunkedUploadMutex.Lock()
+	upload, ok := lookupChunkedUpload(w, r)
+	if !ok {
+		chunkedUploadMutex.Unlock()
+		return
+	}
+	state := upload.state()
+	chunkedUploadMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// handleUploadChunk appends the request body to an upload at the offset query parameter, which must be
+// the number of bytes received so far. When the last byte arrived the recording is imported in the
+// background. A chunk cut off by a dropped connection keeps the bytes that arrived.
+func handleUploadChunk(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		httpError(w, "offset is required (bytes received so far)", http.StatusBadRequest)
+		return
+	}
+
+	chunkedUploadMutex.Lock()
+	upload, ok := lookupChunkedUpload(w, r)
+	if !ok {
+		chunkedUploadMutex.Unlock()
+		return
+	}
+	switch {
+	case upload.Status != uploadStatusUploading:
+		status := upload.Status
+		chunkedUploadMutex.Unlock()
+		httpError(w, fmt.Sprintf("Upload is %s", status), http.StatusConflict)
+		return
+	case upload.writing:
+		chunkedUploadMutex.Unlock()
+		httpError(w, "Another chunk of this upload is being written", http.StatusConflict)
+		return
+	case offset != upload.Received:
+		// The client resumes from the bytes received, which it reads from the response
+		state := upload.state()
+		chunkedUploadMutex.Unlock()
+		httpErrorDetails(w, fmt.Sprintf("Chunk offset %d does not match the %d bytes received", offset, state.Received), http.StatusConflict, state)
+		return
+	}
+	remaining := upload.Size - upload.Received
+	if r.ContentLength > remaining {
+		chunkedUploadMutex.Unlock()
+		httpError(w, fmt.Sprintf("Chunk exceeds the %d bytes remaining of the upload", remaining), http.StatusBadRequest)
+		return
+	}
+	upload.writing = true
+	chunkedUploadMutex.Unlock()
+
+	written, err := writeChunk(upload.path, offset, http.MaxBytesReader(w, r.Body, maxUploadChunkBytes), remaining)
+
+	chunkedUploadMutex.Lock()
+	upload.writing = false
+	upload.Received += written
+	upload.UpdatedAt = time.Now()
+	// Once every byte arrived the recording is imported, even if the request failed afterwards
+	complete := upload.Received == upload.Size
+	if complete {
+		upload.Status = uploadStatusImporting
+	}
+	state := upload.state()
+	chunkedUploadMutex.Unlock()
+
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			httpError(w, fmt.Sprintf("Chunk exceeds %d bytes", maxUploadChunkBytes), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, errChunkBeyondSize):
+			httpError(w, fmt.Sprintf("Chunk exceeds the %d bytes remaining of the upload", remaining), http.StatusBadRequest)
+		default:
+			httpError(w, fmt.Sprintf("Failed to save chunk: %v", err), http.StatusInternalServerError)
+		}
+	}
+	if complete {
+		go importChunkedUpload(upload)
+	}
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// errChunkBeyondSize is returned when a chunk holds more bytes than remain of the upload
+var errChunkBeyondSize = errors.New("chunk exceeds the announced size")
+
+// writeChunk writes the chunk to the partial file at offset, returning how many bytes were written even
+// on failure. At most limit bytes are accepted.
+func writeChunk(path string, offset int64, chunk io.Reader, limit int64) (int64, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	written, err := io.Copy(file, io.LimitReader(chunk, limit))
+	if err != nil {
+		return written, err
+	}
+	// Anything after the remaining bytes does not belong to the recording and is dropped
+	if n, _ := chunk.Read(make([]byte, 1)); n > 0 {
+		return written, errChunkBeyondSize
+	}
+	return written, nil
+}
+
+// importChunkedUpload queues the import of a completely received upload, which removes its file, and
+// waits for the result
+func importChunkedUpload(upload *chunkedUpload) {
+	var result UploadFileResult
+	job, err := enqueueImport(upload.path, upload.Filename, upload.options)
+	if err != nil {
+		os.Remove(upload.path)
+		result = failedImport(upload.Filename, err.Error())
+	} else {
+		chunkedUploadMutex.Lock()
+		upload.JobID = job.ID
+		chunkedUploadMutex.Unlock()
+		result = waitForImport(job)
+	}
+	logImportEvent(result)
+	log.Printf("Chunked upload %s: %s", upload.ID, result.Message)
+
+	chunkedUploadMutex.Lock()
+	defer chunkedUploadMutex.Unlock()
+	upload.Result = &result
+	upload.Status = uploadStatusCompleted
+	if result.Status == "failed" {
+		upload.Status = uploadStatusFailed
+	}
+	upload.UpdatedAt = time.Now()
+}
+
+// handleCancelChunkedUpload discards an upload and its partial file; uploads being imported cannot
+// be cancelled
+func handleCancelChunkedUpload(w http.ResponseWriter, r *http.Request) {
+	chunkedUploadMutex.Lock()
+	defer chunkedUploadMutex.Unlock()
+
+	upload, ok := lookupChunkedUpload(w, r)
+	if !ok {
+		return
+	}
+	if upload.Status == uploadStatusImporting || upload.writing {
+		httpError(w, "Upload is being written or imported", http.StatusConflict)
+		return
+	}
+	os.Remove(upload.path)
+	delete(chunkedUploads, upload.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
@@ -0,0 +1,202 @@
+package data_analysis
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxCachedFlights limits how many flights are kept in the series cache at once
+const maxCachedFlights = 8
+
+// SeriesColumns holds the hot analysis series of one aircraft as contiguous slices,
+// all indexed by the same sample position
+type SeriesColumns struct {
+	Time              []float64
+	Latitude          []float64
+	Longitude         []float64
+	Altitude          []float64
+	IndicatedAltitude []float64
+	PressureAltitude  []float64
+	Airspeed          []float64 // Indicated, recorded or estimated
+	TrueAirspeed      []float64
+	AirspeedEstimated []bool // The indicated airspeed was estimated rather than recorded
+	VerticalSpeed     []float64
+
+	Attitude *AttitudeColumns // Attitude samples on their own time base; nil without attitude data
+	Controls *ControlColumns  // Pilot inputs on their own time bases; nil without throttle or control surface data
+}
+
+// AttitudeColumns holds the attitude series of one aircraft, all indexed by the same attitude sample
+type AttitudeColumns struct {
+	Time        []float64
+	Pitch       []float64
+	Bank        []float64
+	TrueHeading []float64
+}
+
+// newAttitudeColumns converts per-point attitude data into columnar form
+func newAttitudeColumns(points []AttitudePoint) *AttitudeColumns {
+	n := len(points)
+	c := &AttitudeColumns{
+		Time:        make([]float64, n),
+		Pitch:       make([]float64, n),
+		Bank:        make([]float64, n),
+		TrueHeading: make([]float64, n),
+	}
+
+	for i, p := range points {
+		c.Time[i] = p.TimestampSeconds
+		c.Pitch[i] = p.Pitch
+		c.Bank[i] = p.Bank
+		c.TrueHeading[i] = p.TrueHeading
+	}
+
+	return c
+}
+
+// Len returns the number of samples in the series
+func (c *SeriesColumns) Len() int {
+	return len(c.Time)
+}
+
+// seriesCacheEntry holds the columnar series for all aircraft of one flight
+type seriesCacheEntry struct {
+	columns  map[string]*SeriesColumns
+	lastUsed time.Time
+}
+
+var (
+	seriesCache      = map[int]*seriesCacheEntry{}
+	seriesCacheMutex = &sync.Mutex{}
+	// seriesGenerations counts the invalidations of each flight, so series loaded before an invalidation
+	// are not cached after it
+	seriesGenerations = map[int]uint64{}
+)
+
+// newSeriesColumns converts per-point position data into columnar form
+func newSeriesColumns(points []PositionPoint) *SeriesColumns {
+	n := len(points)
+	c := &SeriesColumns{
+		Time:              make([]float64, n),
+		Latitude:          make([]float64, n),
+		Longitude:         make([]float64, n),
+		Altitude:          make([]float64, n),
+		IndicatedAltitude: make([]float64, n),
+		PressureAltitude:  make([]float64, n),
+		Airspeed:          make([]float64, n),
+		TrueAirspeed:      make([]float64, n),
+		AirspeedEstimated: make([]bool, n),
+		VerticalSpeed:     make([]float64, n),
+	}
+
+	for i, p := range points {
+		c.Time[i] = p.TimestampSeconds
+		c.Latitude[i] = p.Latitude
+		c.Longitude[i] = p.Longitude
+		c.Altitude[i] = p.Altitude
+		c.IndicatedAltitude[i] = p.IndicatedAltitude
+		c.PressureAltitude[i] = p.PressureAltitude
+		c.Airspeed[i] = p.Airspeed
+		c.TrueAirspeed[i] = p.TrueAirspeed
+		c.AirspeedEstimated[i] = p.AirspeedSource == AirspeedEstimated
+		c.VerticalSpeed[i] = p.VerticalSpeed
+	}
+
+	return c
+}
+
+// flightDataToColumns converts the position and attitude data of all aircraft into columnar form
+func flightDataToColumns(flightData *FlightData) map[string]*SeriesColumns {
+	columns := make(map[string]*SeriesColumns, len(flightData.PositionData))
+	for aircraftLabel, positionData := range flightData.PositionData {
+		columns[aircraftLabel] = newSeriesColumns(positionData)
+		if attitudeData := flightData.AttitudeData[aircraftLabel]; len(attitudeData) > 0 {
+			columns[aircraftLabel].Attitude = newAttitudeColumns(attitudeData)
+		}
+	}
+	return columns
+}
+
+// getFlightColumns returns the columnar series for a flight, loading them into the cache if needed
+func getFlightColumns(flightID int) (map[string]*SeriesColumns, error) {
+	seriesCacheMutex.Lock()
+	if entry, exists := seriesCache[flightID]; exists {
+		entry.lastUsed = time.Now()
+		seriesCacheMutex.Unlock()
+		return entry.columns, nil
+	}
+	generation := seriesGenerations[flightID]
+	seriesCacheMutex.Unlock()
+
+	flightData, err := getFlightDataFromMainDB(flightID)
+	if err != nil {
+		return nil, err
+	}
+	columns := flightDataToColumns(flightData)
+	if err := addControlColumns(flightData, columns); err != nil {
+		log.Printf("Failed to get control inputs for flight %d: %v", flightID, err)
+	}
+
+	cacheFlightColumns(flightID, generation, columns)
+	return columns, nil
+}
+
+// cacheFlightColumns caches the series of a flight loaded at a generation, unless the flight was
+// invalidated since; such series are served once but not cached
+func cacheFlightColumns(flightID int, generation uint64, columns map[string]*SeriesColumns) {
+	seriesCacheMutex.Lock()
+	defer seriesCacheMutex.Unlock()
+
+	if seriesGenerations[flightID] != generation {
+		return
+	}
+	seriesCache[flightID] = &seriesCacheEntry{columns: columns, lastUsed: time.Now()}
+	evictSeriesCache()
+}
+
+// evictSeriesCache drops the least recently used flights until the cache fits its limit.
+// Must be called with seriesCacheMutex held.
+func evictSeriesCache() {
+	for len(seriesCache) > maxCachedFlights {
+		oldestID := 0
+		var oldest time.Time
+		for id, entry := range seriesCache {
+			if oldest.IsZero() || entry.lastUsed.Before(oldest) {
+				oldestID = id
+				oldest = entry.lastUsed
+			}
+		}
+		delete(seriesCache, oldestID)
+	}
+}
+
+// invalidateFlightColumns removes a flight from the series cache, also discarding series being loaded
+func invalidateFlightColumns(flightID int) {
+	seriesCacheMutex.Lock()
+	defer seriesCacheMutex.Unlock()
+	delete(seriesCache, flightID)
+	seriesGenerations[flightID]++
+}
+
+// sortedAircraftLabels returns the aircraft labels of a columnar flight in stable order
+func sortedAircraftLabels(columns map[string]*SeriesColumns) []string {
+	labels := make([]string, 0, len(columns))
+	for label := range columns {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// filterValues returns the values of a column that satisfy keep
+func filterValues(values []float64, keep func(float64) bool) []float64 {
+	filtered := make([]float64, 0, len(values))
+	for _, v := range values {
+		if keep(v) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,31 @@
+package data_analysis
+
+import "testing"
+
+func TestCacheFlightColumnsAfterInvalidation(t *testing.T) {
+	const flightID = 1 << 30 // Not used by other tests
+	t.Cleanup(func() { invalidateFlightColumns(flightID) })
+
+	seriesCacheMutex.Lock()
+	generation := seriesGenerations[flightID]
+	seriesCacheMutex.Unlock()
+
+	// Invalidated while loading, e.g. by a metrics invalidation or a velocity fix
+	invalidateFlightColumns(flightID)
+	cacheFlightColumns(flightID, generation, map[string]*SeriesColumns{"stale": {}})
+	seriesCacheMutex.Lock()
+	_, cached := seriesCache[flightID]
+	generation = seriesGenerations[flightID]
+	seriesCacheMutex.Unlock()
+	if cached {
+		t.Fatalf("series loaded before an invalidation were cached")
+	}
+
+	cacheFlightColumns(flightID, generation, map[string]*SeriesColumns{"current": {}})
+	seriesCacheMutex.Lock()
+	entry, cached := seriesCache[flightID]
+	seriesCacheMutex.Unlock()
+	if !cached || entry.columns["current"] == nil {
+		t.Errorf("series loaded after the invalidation were not cached")
+	}
+}
@@ -0,0 +1,123 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/sessions"
+)
+
+// ensureFlightConditionsTable creates the table holding the simulator conditions flights were recorded under
+func ensureFlightConditionsTable() error {
+	conditionsSchema := `
+		CREATE TABLE IF NOT EXISTS flight_conditions (
+			flight_id INTEGER PRIMARY KEY,
+			session_id INTEGER NOT NULL,
+			logged_at TEXT NOT NULL,
+			weather_preset TEXT,
+			failures TEXT,
+			notes TEXT,
+			FOREIGN KEY(flight_id) REFERENCES flight(id) ON DELETE CASCADE
+		);
+	`
+
+	if _, err := mainDB.Exec(conditionsSchema); err != nil {
+		return fmt.Errorf("failed to create flight_conditions table: %w", err)
+	}
+	return nil
+}
+
+// attachSessionConditions attaches the conditions last logged on the active session to newly imported
+// flights. Flights imported outside a session, or before any conditions were logged, get none.
+func attachSessionConditions(flights []Flight) {
+	session, ok := sessions.Current()
+	if !ok {
+		return
+	}
+	conditions, ok := session.CurrentConditions()
+	if !ok {
+		return
+	}
+
+	failures, err := json.Marshal(conditions.Failures)
+	if err != nil {
+		log.Printf("Failed to encode failures of session %d: %v", session.ID, err)
+		return
+	}
+
+	flightConditions := &FlightConditions{
+		SessionID:     session.ID,
+		LoggedAt:      conditions.LoggedAt.Format(time.RFC3339),
+		WeatherPreset: conditions.WeatherPreset,
+		Failures:      conditions.Failures,
+		Notes:         conditions.Notes,
+	}
+
+	query := `
+		INSERT OR REPLACE INTO flight_conditions (flight_id, session_id, logged_at, weather_preset, failures, notes)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	for i := range flights {
+		_, err := mainDB.Exec(query, flights[i].ID, session.ID, flightConditions.LoggedAt,
+			conditions.WeatherPreset, string(failures), conditions.Notes)
+		if err != nil {
+			log.Printf("Failed to attach session conditions to flight %d: %v", flights[i].ID, err)
+			continue
+		}
+		flights[i].Conditions = flightConditions
+	}
+}
+
+// getFlightConditions returns the simulator conditions attached to a flight, or nil
+func getFlightConditions(flightID int) (*FlightConditions, error) {
+	query := `
+		SELECT session_id, logged_at, weather_preset, failures, notes
+		FROM flight_conditions
+		WHERE flight_id = ?
+	`
+
+	var c FlightConditions
+	var weatherPreset, failures, notes sql.NullString
+	err := mainDB.QueryRow(query, flightID).Scan(&c.SessionID, &c.LoggedAt, &weatherPreset, &failures, &notes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.WeatherPreset = weatherPreset.String
+	c.Notes = notes.String
+	if failures.String != "" {
+		if err := json.Unmarshal([]byte(failures.String), &c.Failures); err != nil {
+			return nil, fmt.Errorf("failed to decode failures: %w", err)
+		}
+	}
+	return &c, nil
+}
+
+// SessionFlights returns the flights of a session: those imported while it was active, and those assigned
+// to its participant that were not imported during another session, e.g. recordings uploaded afterwards
+func SessionFlights(sessionID int, participantID string) ([]*Flight, error) {
+	all, err := getFlightsFromMainDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flights: %w", err)
+	}
+
+	var flights []*Flight
+	for i := range all {
+		f := &all[i]
+		importedInSession := f.Conditions != nil && f.Conditions.SessionID == sessionID
+		assignedOutsideSessions := f.Conditions == nil && participantID != "" && f.ParticipantID == participantID
+		if importedInSession || assignedOutsideSessions {
+			flights = append(flights, f)
+		}
+	}
+	// Oldest first, in the order they were flown
+	slices.Reverse(flights)
+	return flights, nil
+}
@@ -0,0 +1,188 @@
+package data_analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// AnalysisConfig holds the constants of the analysis that a deployment may tune. Values are read
+// from data/analysis_config.json at startup; omitted fields keep their defaults.
+type AnalysisConfig struct {
+	DistanceToleranceNM float64 `json:"distance_tolerance_nm"` // Distance from the target at which a distance marker is placed without a crossing
+	MinTrimSeconds      float64 `json:"min_trim_seconds"`      // Shortest range a trimmed flight may cover
+	UploadMemoryMB      int64   `json:"upload_memory_mb"`      // Upload size held in memory; larger uploads are buffered on disk
+	CSVBaseTimestampMs  int64   `json:"csv_base_timestamp_ms"` // Epoch the relative times of CSV recordings without a start time are stored from
+	StallSpeedKnots     float64 `json:"stall_speed_kt"`        // Airborne airspeed below which a stall warning is marked, 0 to disable
+	OverspeedKnots      float64 `json:"overspeed_kt"`          // Airspeed above which an overspeed warning is marked, 0 to disable
+	// The participant's aircraft in recordings with formation or AI traffic is designated on import by
+	// its tail number or, if no aircraft has it, its sequence number; empty and 0 keep the recorded user aircraft
+	TargetTailNumber string `json:"target_tail_number"`
+	TargetSeqNr      int    `json:"target_seq_nr"`
+	// A response to a failure is the first change of the throttle (fraction of full travel) or pitch
+	// (degrees) by more than these from their values when the failure started
+	ResponseThrottleThreshold float64 `json:"response_throttle_threshold"`
+	ResponsePitchThresholdDeg float64 `json:"response_pitch_threshold_deg"`
+	// Units of the altitudes, speeds and vertical speeds in flight data, statistics and CSV exports
+	Units string `json:"units"` // "imperial" or "metric"
+	// An approach to a runway is stabilized when, from the gate on, the airspeed stays within the band
+	// around the target speed and the descent rate and the deviation from the extended centerline below their limits
+	ApproachGateNM            float64 `json:"approach_gate_nm"`
+	ApproachSpeedKnots        float64 `json:"approach_speed_kt"`
+	ApproachSpeedAboveKnots   float64 `json:"approach_speed_above_kt"`
+	ApproachSpeedBelowKnots   float64 `json:"approach_speed_below_kt"`
+	ApproachMaxDescentRateFPM float64 `json:"approach_max_descent_rate_fpm"`
+	ApproachMaxLateralFt      float64 `json:"approach_max_lateral_deviation_ft"`
+	// Name of the runway in the runway library whose glidepath deviation the flight statistics include,
+	// empty to leave it out
+	TargetRunway string `json:"target_runway"`
+	// Name of the altitude reference profile the study metrics compute each participant flight's altitude
+	// RMSE against, empty to leave it out
+	AltitudeReferenceProfile string `json:"altitude_reference_profile"`
+	// Days an archived flight is kept before it is purged automatically, 0 (the default) to keep it until
+	// purged by hand
+	ArchiveRetentionDays int `json:"archive_retention_days"`
+	// A pilot-induced oscillation is a swing of the pitch or bank about its moving mean within the frequency
+	// band and above the amplitude for at least the cycles, with at least the peak-to-peak travel of the
+	// elevator or aileron (fraction of full travel, 0 to ignore the controls)
+	PIOMinFrequencyHz   float64 `json:"pio_min_frequency_hz"`
+	PIOMaxFrequencyHz   float64 `json:"pio_max_frequency_hz"`
+	PIOMinAmplitudeDeg  float64 `json:"pio_min_amplitude_deg"`
+	PIOMinCycles        int     `json:"pio_min_cycles"`
+	PIOMinControlTravel float64 `json:"pio_min_control_travel"`
+}
+
+// defaultAnalysisConfig are the values used unless configured otherwise
+var defaultAnalysisConfig = AnalysisConfig{
+	DistanceToleranceNM:       0.05,
+	MinTrimSeconds:            1.0,
+	UploadMemoryMB:            32,
+	CSVBaseTimestampMs:        1690000000000,
+	StallSpeedKnots:           48,  // Cessna 172 clean stall speed
+	OverspeedKnots:            163, // Cessna 172 never-exceed speed
+	ResponseThrottleThreshold: 0.05,
+	ResponsePitchThresholdDeg: 2,
+	Units:                     UnitsImperial,
+	ApproachGateNM:            5,
+	ApproachSpeedKnots:        65, // Cessna 172 final approach speed
+	ApproachSpeedAboveKnots:   10,
+	ApproachSpeedBelowKnots:   5,
+	ApproachMaxDescentRateFPM: 1000,
+	ApproachMaxLateralFt:      500,
+	PIOMinFrequencyHz:         0.2,
+	PIOMaxFrequencyHz:         1.5,
+	PIOMinAmplitudeDeg:        2,
+	PIOMinCycles:              3,
+	PIOMinControlTravel:       0.1,
+}
+
+var (
+	analysisConfigFile = filepath.Join("data", "analysis_config.json")
+	// analysisConfig is set once by initAnalysisConfig before the module starts working
+	analysisConfig       = defaultAnalysisConfig
+	analysisConfigSource = "defaults"
+)
+
+// initAnalysisConfig loads the analysis configuration of this deployment; an invalid file is ignored
+func initAnalysisConfig() {
+	data, err := os.ReadFile(analysisConfigFile)
+	if err != nil {
+		return
+	}
+
+	// Unknown fields are rejected so a misspelled setting does not silently keep its default
+	config := defaultAnalysisConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		log.Printf("Failed to load analysis configuration: %v", err)
+		return
+	}
+	if err := config.validate(); err != nil {
+		log.Printf("Ignoring invalid analysis configuration: %v", err)
+		return
+	}
+	analysisConfig = config
+	analysisConfigSource = analysisConfigFile
+	log.Printf("Loaded analysis configuration from %s", analysisConfigFile)
+}
+
+// validate checks that every value is within its usable range
+func (c AnalysisConfig) validate() error {
+	if c.DistanceToleranceNM < 0 || c.DistanceToleranceNM > 1 {
+		return fmt.Errorf("distance_tolerance_nm must be between 0 and 1")
+	}
+	if !(c.MinTrimSeconds > 0) {
+		return fmt.Errorf("min_trim_seconds must be positive")
+	}
+	if c.UploadMemoryMB < 1 || c.UploadMemoryMB > 1024 {
+		return fmt.Errorf("upload_memory_mb must be between 1 and 1024")
+	}
+	if c.CSVBaseTimestampMs < 0 {
+		return fmt.Errorf("csv_base_timestamp_ms must not be negative")
+	}
+	if c.StallSpeedKnots < 0 || c.OverspeedKnots < 0 {
+		return fmt.Errorf("stall_speed_kt and overspeed_kt must not be negative")
+	}
+	if c.StallSpeedKnots > 0 && c.OverspeedKnots > 0 && c.OverspeedKnots <= c.StallSpeedKnots {
+		return fmt.Errorf("overspeed_kt must be above stall_speed_kt")
+	}
+	if c.TargetSeqNr < 0 {
+		return fmt.Errorf("target_seq_nr must not be negative")
+	}
+	if !(c.ResponseThrottleThreshold > 0) || c.ResponseThrottleThreshold > 1 {
+		return fmt.Errorf("response_throttle_threshold must be above 0 and at most 1")
+	}
+	if !(c.ResponsePitchThresholdDeg > 0) {
+		return fmt.Errorf("response_pitch_threshold_deg must be positive")
+	}
+	if _, ok := unitSystems[c.Units]; !ok {
+		return fmt.Errorf("units must be %q or %q", UnitsImperial, UnitsMetric)
+	}
+	if !(c.ApproachGateNM > 0) || c.ApproachGateNM > 20 {
+		return fmt.Errorf("approach_gate_nm must be above 0 and at most 20")
+	}
+	if !(c.ApproachSpeedKnots > 0) {
+		return fmt.Errorf("approach_speed_kt must be positive")
+	}
+	if c.ApproachSpeedAboveKnots < 0 || c.ApproachSpeedBelowKnots < 0 {
+		return fmt.Errorf("approach_speed_above_kt and approach_speed_below_kt must not be negative")
+	}
+	if !(c.ApproachMaxDescentRateFPM > 0) || !(c.ApproachMaxLateralFt > 0) {
+		return fmt.Errorf("approach_max_descent_rate_fpm and approach_max_lateral_deviation_ft must be positive")
+	}
+	if c.ArchiveRetentionDays < 0 {
+		return fmt.Errorf("archive_retention_days must not be negative")
+	}
+	if !(c.PIOMinFrequencyHz > 0) || !(c.PIOMaxFrequencyHz > c.PIOMinFrequencyHz) {
+		return fmt.Errorf("pio_min_frequency_hz must be positive and below pio_max_frequency_hz")
+	}
+	if !(c.PIOMinAmplitudeDeg > 0) || c.PIOMinCycles < 1 {
+		return fmt.Errorf("pio_min_amplitude_deg must be positive and pio_min_cycles at least 1")
+	}
+	if c.PIOMinControlTravel < 0 || c.PIOMinControlTravel > 2 {
+		return fmt.Errorf("pio_min_control_travel must be between 0 and 2")
+	}
+	return nil
+}
+
+// handleGetAnalysisConfig returns the effective analysis configuration, including the distance marker
+// target, which is configured with the distance marker settings
+func handleGetAnalysisConfig(w http.ResponseWriter, r *http.Request) {
+	response := struct {
+		AnalysisConfig
+		DistanceMarkerTargetNM float64 `json:"distance_marker_target_nm"`
+		Source                 string  `json:"source"` // File the configuration was read from, or "defaults"
+	}{
+		AnalysisConfig:         analysisConfig,
+		DistanceMarkerTargetNM: GetDistanceMarkerSettings().DistanceNM,
+		Source:                 analysisConfigSource,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,239 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+)
+
+const (
+	// skyDollyPositionScale is full travel of the control positions Sky Dolly records as 16-bit integers
+	skyDollyPositionScale = 32767.0
+	// controlReversalDeadband is how far, as a fraction of full travel, a control must move back from
+	// its last extreme to count as a reversal; smaller wiggles are sensor noise
+	controlReversalDeadband = 0.02
+)
+
+// ControlColumns holds the pilot inputs of one aircraft as fractions of full travel. The throttle comes
+// from the engine table and the control surfaces from primary_flight_control, each on its own time base.
+type ControlColumns struct {
+	ThrottleTime []float64
+	Throttle     []float64 // Lever of engine 1
+
+	SurfaceTime []float64
+	Elevator    []float64
+	Aileron     []float64
+	Rudder      []float64
+}
+
+// InputActivity measures how often and how fast one control was moved
+type InputActivity struct {
+	DurationSeconds    float64         `json:"duration_seconds"`
+	Reversals          int             `json:"reversals"` // Changes of direction beyond the deadband
+	ReversalsPerMinute float64         `json:"reversals_per_minute"`
+	RateStats          *DataStatistics `json:"rate_stats"` // Signed rate in full travel per second; its variance is the input rate variance
+}
+
+// ControlActivity quantifies the pilot inputs of one aircraft as an objective proxy of workload: smooth
+// flying needs few reversals and slow inputs. An input is nil without data for it.
+type ControlActivity struct {
+	DeadbandFraction float64        `json:"deadband_fraction"`
+	Throttle         *InputActivity `json:"throttle"`
+	Elevator         *InputActivity `json:"elevator"`
+	Aileron          *InputActivity `json:"aileron"`
+	Rudder           *InputActivity `json:"rudder"`
+	StickActivity    *float64       `json:"stick_activity"` // Mean absolute elevator plus aileron rate, full travel per second
+}
+
+// addControlColumns adds the throttle and control surface inputs to the columns of every aircraft that
+// has any
+func addControlColumns(flightData *FlightData, columns map[string]*SeriesColumns) error {
+	aircraft, err := getAircraftByFlightIDFromMainDB(flightData.Flight.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get aircraft: %w", err)
+	}
+
+	for _, ac := range aircraft {
+		label := ac.Label()
+		series, exists := columns[label]
+		if !exists {
+			continue
+		}
+
+		controls := &ControlColumns{}
+		if engineData := flightData.EngineData[label]; len(engineData) > 0 {
+			controls.ThrottleTime = make([]float64, len(engineData))
+			controls.Throttle = make([]float64, len(engineData))
+			for i, e := range engineData {
+				controls.ThrottleTime[i] = e.TimestampSeconds
+				controls.Throttle[i] = e.ThrottlePosition1
+			}
+			normalizeControlPositions(controls.Throttle)
+		}
+		if err := loadControlSurfaces(ac.ID, controls); err != nil {
+			return fmt.Errorf("failed to get control surfaces of aircraft %d: %w", ac.ID, err)
+		}
+
+		if len(controls.ThrottleTime) > 0 || len(controls.SurfaceTime) > 0 {
+			series.Controls = controls
+		}
+	}
+	return nil
+}
+
+// loadControlSurfaces reads the elevator, aileron and rudder positions of an aircraft; samples missing
+// any of them are left out
+func loadControlSurfaces(aircraftID int, controls *ControlColumns) error {
+	query := `
+		SELECT timestamp, elevator_position, aileron_position, rudder_position
+		FROM primary_flight_control
+		WHERE aircraft_id = ? AND elevator_position IS NOT NULL AND aileron_position IS NOT NULL
+		  AND rudder_position IS NOT NULL
+		ORDER BY timestamp
+	`
+	rows, err := mainDB.Query(query, aircraftID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var minTimestamp int64
+	for rows.Next() {
+		var timestamp int64
+		var elevator, aileron, rudder sql.NullFloat64
+		if err := rows.Scan(&timestamp, &elevator, &aileron, &rudder); err != nil {
+			return err
+		}
+		if len(controls.SurfaceTime) == 0 {
+			minTimestamp = timestamp
+		}
+		controls.SurfaceTime = append(controls.SurfaceTime, float64(timestamp-minTimestamp)/1000.0)
+		controls.Elevator = append(controls.Elevator, elevator.Float64)
+		controls.Aileron = append(controls.Aileron, aileron.Float64)
+		controls.Rudder = append(controls.Rudder, rudder.Float64)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	normalizeControlPositions(controls.Elevator)
+	normalizeControlPositions(controls.Aileron)
+	normalizeControlPositions(controls.Rudder)
+	return nil
+}
+
+// normalizeControlPositions scales positions to fractions of full travel. CSV imports already store
+// fractions, while Sky Dolly records 16-bit integers, which is assumed when any position exceeds 1.
+func normalizeControlPositions(positions []float64) {
+	for _, p := range positions {
+		if math.Abs(p) > 1 {
+			for i := range positions {
+				positions[i] /= skyDollyPositionScale
+			}
+			return
+		}
+	}
+}
+
+// calculateControlActivity adds the control activity of an aircraft's inputs to its statistics
+func calculateControlActivity(stats *FlightStatistics, controls *ControlColumns) {
+	activity := &ControlActivity{
+		DeadbandFraction: controlReversalDeadband,
+		Throttle:         inputActivity(controls.ThrottleTime, controls.Throttle),
+		Elevator:         inputActivity(controls.SurfaceTime, controls.Elevator),
+		Aileron:          inputActivity(controls.SurfaceTime, controls.Aileron),
+		Rudder:           inputActivity(controls.SurfaceTime, controls.Rudder),
+	}
+
+	elevatorRates := inputRates(controls.SurfaceTime, controls.Elevator)
+	aileronRates := inputRates(controls.SurfaceTime, controls.Aileron)
+	if len(elevatorRates) > 0 {
+		var sum float64
+		for i := range elevatorRates {
+			sum += math.Abs(elevatorRates[i]) + math.Abs(aileronRates[i])
+		}
+		stickActivity := sum / float64(len(elevatorRates))
+		activity.StickActivity = &stickActivity
+	}
+
+	stats.ControlActivity = activity
+}
+
+// inputActivity counts the reversals of one input and describes its rates; nil with fewer than two samples
+func inputActivity(times, positions []float64) *InputActivity {
+	rates := inputRates(times, positions)
+	if len(rates) == 0 {
+		return nil
+	}
+
+	activity := &InputActivity{
+		DurationSeconds: times[len(times)-1] - times[0],
+		Reversals:       countReversals(positions, controlReversalDeadband),
+		RateStats:       calculateDataStatistics(rates),
+	}
+	if activity.DurationSeconds > 0 {
+		activity.ReversalsPerMinute = float64(activity.Reversals) / activity.DurationSeconds * 60
+	}
+	return activity
+}
+
+// rateStats returns the rate statistics of an input, nil without data for it
+func (a *InputActivity) rateStats() *DataStatistics {
+	if a == nil {
+		return nil
+	}
+	return a.RateStats
+}
+
+// inputRates returns the signed rate between consecutive samples in full travel per second, skipping
+// samples without time between them
+func inputRates(times, positions []float64) []float64 {
+	var rates []float64
+	for i := 1; i < len(times); i++ {
+		dt := times[i] - times[i-1]
+		if dt <= 0 {
+			continue
+		}
+		rates = append(rates, (positions[i]-positions[i-1])/dt)
+	}
+	return rates
+}
+
+// countReversals counts the changes of direction of a series that move back from the last extreme by
+// more than the deadband
+func countReversals(positions []float64, deadband float64) int {
+	if len(positions) == 0 {
+		return 0
+	}
+
+	reversals := 0
+	direction := 0 // 1 rising, -1 falling, 0 before the first movement beyond the deadband
+	extreme := positions[0]
+	for _, p := range positions[1:] {
+		switch direction {
+		case 0:
+			if math.Abs(p-extreme) > deadband {
+				direction = 1
+				if p < extreme {
+					direction = -1
+				}
+				extreme = p
+			}
+		case 1:
+			if p > extreme {
+				extreme = p
+			} else if extreme-p > deadband {
+				reversals++
+				direction, extreme = -1, p
+			}
+		case -1:
+			if p < extreme {
+				extreme = p
+			} else if p-extreme > deadband {
+				reversals++
+				direction, extreme = 1, p
+			}
+		}
+	}
+	return reversals
+}
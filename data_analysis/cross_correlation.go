@@ -0,0 +1,229 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// Defaults and limits of the cross-correlation parameters
+const (
+	defaultCorrelationRateHz = 2.0
+	maxCorrelationRateHz     = 20.0
+	defaultCorrelationMaxLag = 10.0  // Seconds
+	maxCorrelationMaxLag     = 120.0 // Seconds
+	// minCorrelationOverlap is the fewest sample pairs a lag is correlated over
+	minCorrelationOverlap = 10
+)
+
+// CorrelationPoint is the correlation of the input with the response delayed by a lag
+type CorrelationPoint struct {
+	LagSeconds  float64 `json:"lag_seconds"`
+	Correlation float64 `json:"correlation"`
+}
+
+// CrossCorrelation is the lagged cross-correlation of the throttle with one response series. A
+// positive lag means the response follows the throttle.
+type CrossCorrelation struct {
+	Samples         int                `json:"samples"`          // Resampled sample pairs without lag
+	PeakLagSeconds  float64            `json:"peak_lag_seconds"` // Lag of the strongest correlation, positive or negative
+	PeakCorrelation float64            `json:"peak_correlation"` // Correlation at the peak lag
+	Curve           []CorrelationPoint `json:"curve"`
+}
+
+// AircraftCrossCorrelation holds the cross-correlations of one aircraft's throttle; a response is nil
+// when the throttle or the response did not change
+type AircraftCrossCorrelation struct {
+	Airspeed *CrossCorrelation `json:"airspeed"`
+	Altitude *CrossCorrelation `json:"altitude"`
+}
+
+// timedSeries is a series of values at absolute times in milliseconds, in time order
+type timedSeries struct {
+	times  []int64
+	values []float64
+}
+
+// sampleSeries interpolates a series linearly at evenly spaced times from start to end
+func sampleSeries(series timedSeries, start, end, intervalMs int64) []float64 {
+	var sampled []float64
+	i := 0
+	for t := start; t <= end; t += intervalMs {
+		for i+1 < len(series.times) && series.times[i+1] <= t {
+			i++
+		}
+		if i+1 == len(series.times) || series.times[i+1] == series.times[i] {
+			sampled = append(sampled, series.values[i])
+			continue
+		}
+		ratio := float64(t-series.times[i]) / float64(series.times[i+1]-series.times[i])
+		sampled = append(sampled, series.values[i]+(series.values[i+1]-series.values[i])*ratio)
+	}
+	return sampled
+}
+
+// pearsonCorrelation returns the correlation coefficient of two equally long series, NaN if either
+// is constant
+func pearsonCorrelation(x, y []float64) float64 {
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return math.NaN()
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// crossCorrelate correlates input with response shifted by up to maxLag samples either way; both are
+// sampled at the same times
+func crossCorrelate(input, response []float64, maxLag int, intervalSeconds float64) *CrossCorrelation {
+	result := &CrossCorrelation{Samples: len(input), Curve: []CorrelationPoint{}}
+	peakFound := false
+	for lag := -maxLag; lag <= maxLag; lag++ {
+		n := len(input) - max(lag, -lag)
+		if n < minCorrelationOverlap {
+			continue
+		}
+		var x, y []float64
+		if lag >= 0 {
+			x, y = input[:n], response[lag:]
+		} else {
+			x, y = input[-lag:], response[:n]
+		}
+
+		r := pearsonCorrelation(x, y)
+		if math.IsNaN(r) {
+			continue
+		}
+		point := CorrelationPoint{LagSeconds: float64(lag) * intervalSeconds, Correlation: r}
+		result.Curve = append(result.Curve, point)
+		if !peakFound || math.Abs(r) > math.Abs(result.PeakCorrelation) {
+			result.PeakLagSeconds, result.PeakCorrelation = point.LagSeconds, r
+			peakFound = true
+		}
+	}
+	if !peakFound {
+		return nil
+	}
+	return result
+}
+
+// throttleSeries returns the lever position of one engine (1 to 4) over time
+func throttleSeries(engineData []EnginePoint, engine int) timedSeries {
+	series := timedSeries{times: make([]int64, len(engineData)), values: make([]float64, len(engineData))}
+	for i, e := range engineData {
+		series.times[i] = e.Timestamp
+		series.values[i] = [4]float64{e.ThrottlePosition1, e.ThrottlePosition2, e.ThrottlePosition3, e.ThrottlePosition4}[engine-1]
+	}
+	return series
+}
+
+// calculateCrossCorrelation correlates the throttle of one engine with the airspeed and altitude of
+// every aircraft with engine and position data, resampled to a common time base
+func calculateCrossCorrelation(flightData *FlightData, engine int, rateHz, maxLagSeconds float64) map[string]*AircraftCrossCorrelation {
+	intervalMs := int64(math.Round(1000 / rateHz))
+	intervalSeconds := float64(intervalMs) / 1000
+	maxLag := int(math.Round(maxLagSeconds / intervalSeconds))
+
+	result := make(map[string]*AircraftCrossCorrelation)
+	for aircraftLabel, engineData := range flightData.EngineData {
+		positions := flightData.PositionData[aircraftLabel]
+		if len(positions) < 2 || len(engineData) < 2 {
+			continue
+		}
+
+		airspeed := timedSeries{times: make([]int64, len(positions)), values: make([]float64, len(positions))}
+		altitude := timedSeries{times: airspeed.times, values: make([]float64, len(positions))}
+		for i, p := range positions {
+			airspeed.times[i] = p.Timestamp
+			airspeed.values[i] = p.Airspeed
+			altitude.values[i] = p.Altitude
+		}
+		throttle := throttleSeries(engineData, engine)
+
+		// Correlate over the time both tables were recorded
+		start := max(throttle.times[0], airspeed.times[0])
+		end := min(throttle.times[len(throttle.times)-1], airspeed.times[len(airspeed.times)-1])
+		if end <= start {
+			continue
+		}
+
+		input := sampleSeries(throttle, start, end, intervalMs)
+		result[aircraftLabel] = &AircraftCrossCorrelation{
+			Airspeed: crossCorrelate(input, sampleSeries(airspeed, start, end, intervalMs), maxLag, intervalSeconds),
+			Altitude: crossCorrelate(input, sampleSeries(altitude, start, end, intervalMs), maxLag, intervalSeconds),
+		}
+	}
+	return result
+}
+
+// parseCorrelationParameter parses a positive float query parameter within a maximum, returning def
+// if absent
+func parseCorrelationParameter(r *http.Request, name string, def, maximum float64) (float64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(f > 0) || f > maximum {
+		return 0, fmt.Errorf("invalid %s '%s' (expected a number above 0 and up to %g)", name, value, maximum)
+	}
+	return f, nil
+}
+
+// handleGetCrossCorrelation returns the lagged cross-correlation of the throttle with airspeed and
+// altitude per aircraft, a measure of the pilot's response latency
+func handleGetCrossCorrelation(w http.ResponseWriter, r *http.Request, flightId int) {
+	rateHz, err := parseCorrelationParameter(r, "rate_hz", defaultCorrelationRateHz, maxCorrelationRateHz)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	maxLagSeconds, err := parseCorrelationParameter(r, "max_lag", defaultCorrelationMaxLag, maxCorrelationMaxLag)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	engine := 1
+	if value := r.URL.Query().Get("engine"); value != "" {
+		engine, err = strconv.Atoi(value)
+		if err != nil || engine < 1 || engine > 4 {
+			httpError(w, fmt.Sprintf("Invalid engine '%s' (expected 1 to 4)", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	flightData, err := getFlightDataFromMainDB(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if len(flightData.EngineData) == 0 {
+		httpError(w, "Flight has no engine data", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"flight_id":       flightId,
+		"engine":          engine,
+		"rate_hz":         rateHz,
+		"max_lag_seconds": maxLagSeconds,
+		"aircraft":        calculateCrossCorrelation(flightData, engine, rateHz, maxLagSeconds),
+	})
+}
@@ -4,91 +4,309 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// ParseCSVFlightData parses a CSV file and returns structured flight data
-func ParseCSVFlightData(reader io.Reader, options CSVImportOptions) (*CSVFlightData, error) {
+// csvMetadataRows is how many leading rows of a CSV file are searched for the recording metadata
+const csvMetadataRows = 5
+
+// csvRecordStream reads the data records of a CSV file one at a time, so large recordings are never
+// held in memory as a whole. Rows that cannot be read are skipped with a warning.
+type csvRecordStream struct {
+	reader        *csv.Reader
+	metadata      *CSVMetadata
+	headers       []string
+	engineHeaders []csvEngineHeader
+	recordedAt    time.Time // Dates records that only carry the time of day
+	warnings      *csvWarningCollector
+	warningSeries *csvWarningSeries // Recorded stall and overspeed warnings, nil without warning columns
+
+	rows       int // Rows read from the file so far
+	records    int // Valid records returned so far
+	startTime  time.Time
+	endTime    time.Time
+	localTimes bool
+}
+
+// newCSVRecordStream reads the metadata and header rows of a CSV file, leaving the reader at the first
+// data row
+func newCSVRecordStream(reader io.Reader, options CSVImportOptions) (*csvRecordStream, error) {
 	csvReader := csv.NewReader(reader)
 	csvReader.FieldsPerRecord = -1 // Allow variable number of fields
-	
-	// Read all records
-	records, err := csvReader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV: %w", err)
-	}
-	
-	if len(records) < 3 {
-		return nil, fmt.Errorf("CSV file too short, expected at least 3 rows (metadata, header, data)")
+	csvReader.ReuseRecord = true   // Rows are parsed right away, so their slice can be reused
+
+	stream := &csvRecordStream{reader: csvReader, warnings: &csvWarningCollector{}}
+
+	// Find header row (contains column names), keeping the leading rows for the metadata
+	var leading [][]string
+	for stream.headers == nil {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			if stream.rows < 3 {
+				return nil, fmt.Errorf("CSV file too short, expected at least 3 rows (metadata, header, data)")
+			}
+			return nil, fmt.Errorf("could not find header row with flight data columns")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		stream.rows++
+
+		if len(leading) < csvMetadataRows {
+			leading = append(leading, slices.Clone(record))
+		}
+		// Look for row that contains "Time" and other expected columns
+		if stream.rows > options.SkipRows && containsFlightDataHeaders(record) {
+			stream.headers = slices.Clone(record)
+		}
 	}
-	
+
 	// Parse metadata from the first few rows
-	metadata, err := parseCSVMetadata(records, options)
+	metadata, err := parseCSVMetadata(leading, options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse metadata: %w", err)
 	}
-	
-	// Find header row (contains column names)
-	headerRowIndex := -1
-	var headers []string
-	for i, record := range records {
-		if i < options.SkipRows {
-			continue
+	stream.metadata = metadata
+
+	stream.engineHeaders = findCSVEngineHeaders(stream.headers)
+	stream.recordedAt, _ = time.ParseInLocation(csvRecordedAtLayout, metadata.RecordedAt, time.Local)
+	if csvHasWarningColumns(stream.headers) {
+		stream.warningSeries = &csvWarningSeries{}
+	}
+	return stream, nil
+}
+
+// next returns the next valid data record, or io.EOF after the last one
+func (s *csvRecordStream) next() (*CSVFlightRecord, error) {
+	for {
+		record, err := s.reader.Read()
+		if err == io.EOF {
+			return nil, io.EOF
 		}
-		// Look for row that contains "Time" and other expected columns
-		if containsFlightDataHeaders(record) {
-			headerRowIndex = i
-			headers = record
-			break
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
 		}
-	}
-	
-	if headerRowIndex == -1 {
-		return nil, fmt.Errorf("could not find header row with flight data columns")
-	}
-	
-	// Parse data records
-	var flightRecords []CSVFlightRecord
-	startTime := time.Time{}
-	
-	for i := headerRowIndex + 1; i < len(records); i++ {
-		record := records[i]
-		if len(record) != len(headers) {
-			continue // Skip malformed rows
+		s.rows++
+		row := s.rows
+
+		if len(record) != len(s.headers) {
+			s.warnings.skip(row, "has %d fields, expected %d", len(record), len(s.headers))
+			continue
 		}
-		
-		flightRecord, err := parseCSVRecord(headers, record)
+
+		flightRecord, err := parseCSVRecord(s.headers, record)
 		if err != nil {
-			// Log error but continue with other records
+			s.warnings.skip(row, "%v", err)
 			continue
 		}
-		
+		flightRecord.Engine = parseCSVEngineValues(s.engineHeaders, record)
+
+		recordTime, hasOffset, err := parseCSVTime(flightRecord.Time, s.recordedAt, s.endTime)
+		if err != nil {
+			s.warnings.skip(row, "%v", err)
+			continue
+		}
+		if !hasOffset {
+			s.localTimes = true
+		}
+
 		// Calculate relative timestamp in seconds
-		if recordTime, err := time.Parse("2006-01-02T15:04:05.9999999-07:00", flightRecord.Time); err == nil {
-			if startTime.IsZero() {
-				startTime = recordTime
-				flightRecord.TimestampSeconds = 0
-			} else {
-				flightRecord.TimestampSeconds = recordTime.Sub(startTime).Seconds()
+		if s.startTime.IsZero() {
+			s.startTime = recordTime
+		} else {
+			if recordTime.Before(s.endTime) {
+				s.warnings.add(row, "time %s is %.1f seconds before the previous row", flightRecord.Time, s.endTime.Sub(recordTime).Seconds())
+			}
+			if recordTime.Format("-07:00") != s.endTime.Format("-07:00") {
+				s.warnings.add(row, "UTC offset changes from %s to %s", s.endTime.Format("-07:00"), recordTime.Format("-07:00"))
 			}
 		}
-		
-		flightRecords = append(flightRecords, *flightRecord)
+		flightRecord.TimestampSeconds = recordTime.Sub(s.startTime).Seconds()
+		s.endTime = recordTime
+
+		if s.warningSeries != nil {
+			s.warningSeries.add(flightRecord)
+		}
+		s.records++
+		return flightRecord, nil
 	}
-	
-	if len(flightRecords) == 0 {
-		return nil, fmt.Errorf("no valid flight data records found")
+}
+
+// data returns what was parsed so far, without the records. Only the start time is known before the
+// first record was read.
+func (s *csvRecordStream) data() *CSVFlightData {
+	metadata := *s.metadata
+	metadata.TotalRecords = s.records
+	metadata.SkippedRows = s.warnings.skipped
+	metadata.StartTime = s.startTime
+	metadata.EndTime = s.endTime
+
+	var engineColumns []string
+	for _, h := range s.engineHeaders {
+		engineColumns = append(engineColumns, h.column)
 	}
-	
-	metadata.TotalRecords = len(flightRecords)
-	
+
 	return &CSVFlightData{
-		Metadata: *metadata,
-		Headers:  headers,
-		Records:  flightRecords,
-	}, nil
+		Metadata:      metadata,
+		Headers:       s.headers,
+		EngineColumns: engineColumns,
+		warningSeries: s.warningSeries,
+	}
+}
+
+// finish completes the parsed data once all records were read; it fails if there were none
+func (s *csvRecordStream) finish() (*CSVFlightData, error) {
+	if s.rows < 3 {
+		return nil, fmt.Errorf("CSV file too short, expected at least 3 rows (metadata, header, data)")
+	}
+	if s.records == 0 {
+		if first := s.warnings.first(); first != nil {
+			return nil, fmt.Errorf("no valid flight data records found (row %d %s)", first.Row, first.Message)
+		}
+		return nil, fmt.Errorf("no valid flight data records found")
+	}
+
+	if s.localTimes {
+		zone, _ := time.Now().Zone()
+		s.warnings.add(0, "record times have no UTC offset and were read in the station's local time (%s)", zone)
+	}
+
+	csvData := s.data()
+	csvData.Warnings = s.warnings.list()
+	return csvData, nil
+}
+
+// ParseCSVFlightData parses a CSV file and returns structured flight data with all its records.
+// Imports stream the records into the database instead, see ImportFlightFromCSV.
+func ParseCSVFlightData(reader io.Reader, options CSVImportOptions) (*CSVFlightData, error) {
+	stream, err := newCSVRecordStream(reader, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []CSVFlightRecord
+	for {
+		record, err := stream.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *record)
+	}
+
+	csvData, err := stream.finish()
+	if err != nil {
+		return nil, err
+	}
+	csvData.Records = records
+	return csvData, nil
+}
+
+// csvRecordedAtLayout is the format of the recording timestamp in the header of FS-FlightControl exports
+const csvRecordedAtLayout = "1/2/2006 3:04:05 PM"
+
+// csvTimeLayouts are the record time formats read from CSV files, tried in order. Layouts without a
+// UTC offset are read in the station's local time; times of day are placed on the recording date.
+var csvTimeLayouts = []struct {
+	layout    string
+	hasOffset bool
+	timeOnly  bool
+}{
+	{time.RFC3339Nano, true, false},
+	{"2006-01-02 15:04:05Z07:00", true, false},
+	{"2006-01-02T15:04:05", false, false},
+	{"2006-01-02 15:04:05", false, false},
+	{csvRecordedAtLayout, false, false},
+	{"1/2/2006 15:04:05", false, false},
+	{"02.01.2006 15:04:05", false, false},
+	{"15:04:05", false, true},
+	{"3:04:05 PM", false, true},
+}
+
+// parseCSVTime parses the time of a record with the first matching layout, reporting whether it
+// carried a UTC offset. Fractional seconds are accepted with every layout. A time of day before the
+// previous record's by more than half a day is taken to have passed midnight.
+func parseCSVTime(value string, recordedAt, previous time.Time) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, fmt.Errorf("has no time")
+	}
+	for _, l := range csvTimeLayouts {
+		t, err := time.ParseInLocation(l.layout, value, time.Local)
+		if err != nil {
+			continue
+		}
+		if !l.timeOnly {
+			return t, l.hasOffset, nil
+		}
+
+		date := recordedAt
+		if !previous.IsZero() {
+			date = previous
+		}
+		if date.IsZero() {
+			return time.Time{}, false, fmt.Errorf("has a time of day (%s) but the file has no recording date", value)
+		}
+		t = time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+		if !previous.IsZero() && previous.Sub(t) > 12*time.Hour {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("has an unrecognized time format '%s'", value)
+}
+
+// maxCSVRowWarnings limits the row warnings listed for one file; further ones are only counted
+const maxCSVRowWarnings = 20
+
+// csvWarningCollector gathers the warnings of parsing a CSV file
+type csvWarningCollector struct {
+	warnings    []CSVWarning
+	rowWarnings int
+	omitted     int
+	skipped     int
+}
+
+// add records a warning about a row, or about the whole file for row 0
+func (c *csvWarningCollector) add(row int, format string, args ...interface{}) {
+	if row > 0 {
+		if c.rowWarnings >= maxCSVRowWarnings {
+			c.omitted++
+			return
+		}
+		c.rowWarnings++
+	}
+	c.warnings = append(c.warnings, CSVWarning{Row: row, Message: fmt.Sprintf(format, args...)})
+}
+
+// skip records a row left out of the import
+func (c *csvWarningCollector) skip(row int, format string, args ...interface{}) {
+	c.skipped++
+	c.add(row, "skipped, "+format, args...)
+}
+
+// first returns the first warning, nil if there is none
+func (c *csvWarningCollector) first() *CSVWarning {
+	if len(c.warnings) == 0 {
+		return nil
+	}
+	return &c.warnings[0]
+}
+
+// list returns the warnings, noting how many row warnings were left out
+func (c *csvWarningCollector) list() []CSVWarning {
+	warnings := c.warnings
+	if c.omitted > 0 {
+		warnings = append(warnings, CSVWarning{Message: fmt.Sprintf("%d more row warnings not listed", c.omitted)})
+	}
+	if warnings == nil {
+		warnings = []CSVWarning{}
+	}
+	return warnings
 }
 
 // parseCSVMetadata extracts metadata from the first few rows of the CSV
@@ -216,11 +434,13 @@ func parseCSVRecord(headers []string, record []string) (*CSVFlightRecord, error)
 		case strings.Contains(headerLower, "bankangle"):
 			if val, err := strconv.ParseFloat(value, 64); err == nil {
 				flightRecord.BankAngle = val
+				flightRecord.HasBank = true
 			}
 			
 		case strings.Contains(headerLower, "pitchangle"):
 			if val, err := strconv.ParseFloat(value, 64); err == nil {
 				flightRecord.PitchAngle = val
+				flightRecord.HasPitch = true
 			}
 			
 		case strings.Contains(headerLower, "headingmagnetic"):
@@ -231,6 +451,7 @@ func parseCSVRecord(headers []string, record []string) (*CSVFlightRecord, error)
 		case strings.Contains(headerLower, "headingtrue"):
 			if val, err := strconv.ParseFloat(value, 64); err == nil {
 				flightRecord.HeadingTrue = val
+				flightRecord.HasHeadingTrue = true
 			}
 			
 		case strings.Contains(headerLower, "ambienttemperature") && !strings.Contains(headerLower, "total"):
@@ -285,39 +506,138 @@ func parseCSVRecord(headers []string, record []string) (*CSVFlightRecord, error)
 	return flightRecord, nil
 }
 
+// csvEngineChannels maps the channel names of CSV engine headers to engine table columns without their
+// engine number; switch channels are stored as 0 or 1
+var csvEngineChannels = []struct {
+	name   string
+	column string
+	isBool bool
+}{
+	{"throttleleverposition", "throttle_lever_position", false},
+	{"propellerleverposition", "propeller_lever_position", false},
+	{"mixtureleverposition", "mixture_lever_position", false},
+	{"cowlflapposition", "cowl_flap_position", false},
+	{"masterbattery", "electrical_master_battery", true},
+	{"starter", "general_engine_starter", true},
+	{"combustion", "general_engine_combustion", true},
+}
+
+// csvEngineHeader is a CSV column holding an engine channel
+type csvEngineHeader struct {
+	index   int
+	column  string // Engine table column, e.g. throttle_lever_position2
+	isBool  bool
+	percent bool // Lever positions in percent are stored as 0 to 1
+}
+
+// findCSVEngineHeaders finds the engine columns of a CSV, e.g. "ThrottleLeverPosition2 (percent)" or
+// "GeneralEngCombustion:1 (bool)". Headers without an engine number of 1 to 4 belong to engine 1.
+func findCSVEngineHeaders(headers []string) []csvEngineHeader {
+	var found []csvEngineHeader
+	seen := map[string]bool{}
+	for i, header := range headers {
+		name, unit, _ := strings.Cut(strings.ToLower(header), "(")
+		name = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+				return r
+			}
+			return -1
+		}, name)
+
+		engine := "1"
+		if n := len(name); n > 0 && name[n-1] >= '1' && name[n-1] <= '4' {
+			engine = name[n-1:]
+			name = name[:n-1]
+		}
+
+		for _, channel := range csvEngineChannels {
+			if !strings.Contains(name, channel.name) {
+				continue
+			}
+			column := channel.column + engine
+			if !seen[column] {
+				seen[column] = true
+				found = append(found, csvEngineHeader{
+					index:   i,
+					column:  column,
+					isBool:  channel.isBool,
+					percent: strings.Contains(unit, "percent"),
+				})
+			}
+			break
+		}
+	}
+	return found
+}
+
+// parseCSVEngineValues reads the engine values of a record; empty and unparsable values are left out
+func parseCSVEngineValues(engineHeaders []csvEngineHeader, record []string) map[string]float64 {
+	if len(engineHeaders) == 0 {
+		return nil
+	}
+	values := make(map[string]float64, len(engineHeaders))
+	for _, h := range engineHeaders {
+		value := strings.TrimSpace(record[h.index])
+		if value == "" {
+			continue
+		}
+		if h.isBool {
+			if parseBool(value) {
+				values[h.column] = 1
+			} else {
+				values[h.column] = 0
+			}
+			continue
+		}
+		val, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		if h.percent {
+			val /= 100
+		}
+		values[h.column] = val
+	}
+	return values
+}
+
 // parseBool parses boolean values from CSV (handles "True"/"False" strings)
 func parseBool(value string) bool {
 	value = strings.ToLower(strings.TrimSpace(value))
 	return value == "true" || value == "1" || value == "yes"
 }
 
-// ValidateCSVStructure validates that the CSV has the required structure for flight data
+// ValidateCSVStructure validates that the CSV has the required structure for flight data. It reads the
+// file only up to its header row.
 func ValidateCSVStructure(reader io.Reader) error {
 	csvReader := csv.NewReader(reader)
 	csvReader.FieldsPerRecord = -1
-	
-	// Read first few records to validate structure
-	records, err := csvReader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("failed to read CSV: %w", err)
-	}
-	
-	if len(records) < 3 {
-		return fmt.Errorf("CSV file too short, expected at least 3 rows")
-	}
-	
-	// Check for header row
+	csvReader.ReuseRecord = true
+
+	// Check for header row, which must be followed by data
+	rows := 0
 	headerFound := false
-	for _, record := range records {
+	for !headerFound || rows < 3 {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV: %w", err)
+		}
+		rows++
 		if containsFlightDataHeaders(record) {
 			headerFound = true
-			break
 		}
 	}
-	
+
+	if rows < 3 {
+		return fmt.Errorf("CSV file too short, expected at least 3 rows")
+	}
+
 	if !headerFound {
 		return fmt.Errorf("no valid flight data headers found in CSV")
 	}
-	
+
 	return nil
-}
\ No newline at end of file
+}
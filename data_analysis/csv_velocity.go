@@ -0,0 +1,185 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+)
+
+const (
+	knotsToMetersPerSecond = 0.514444
+	fpmToMetersPerSecond   = 0.00508
+)
+
+// csvAttitudeVelocity derives the velocity components of a CSV attitude sample in m/s from the ground
+// speed in knots, the true heading in degrees and the vertical speed in ft/min: x east, y north, z up
+func csvAttitudeVelocity(groundSpeedKnots, headingDeg, verticalSpeedFPM float64) (x, y, z float64) {
+	groundSpeedMS := groundSpeedKnots * knotsToMetersPerSecond
+	headingRad := headingDeg * math.Pi / 180
+	return groundSpeedMS * math.Sin(headingRad), groundSpeedMS * math.Cos(headingRad), verticalSpeedFPM * fpmToMetersPerSecond
+}
+
+// legacyCSVSin is the Taylor-series sine CSV imports used before the math package, kept to recognize
+// the velocities it produced; it is far off beyond a quarter turn
+func legacyCSVSin(x float64) float64 {
+	x = x - 2*3.14159*float64(int(x/(2*3.14159)))
+	return x - (x*x*x)/6 + (x*x*x*x*x)/120
+}
+
+func legacyCSVCos(x float64) float64 {
+	return legacyCSVSin(x + 3.14159/2)
+}
+
+// csvVelocityFit tells which derivation a stored horizontal velocity fits
+type csvVelocityFit int
+
+const (
+	csvVelocityAmbiguous csvVelocityFit = iota // Fits both, e.g. standing still or heading north
+	csvVelocityLegacy
+	csvVelocityCurrent
+	csvVelocityNeither
+)
+
+// fitGroundSpeed returns the ground speed in m/s that best explains a horizontal velocity given the
+// factors of its east and north components, and whether it explains it exactly with a ground speed that
+// is not negative
+func fitGroundSpeed(vx, vy, east, north float64) (float64, bool) {
+	norm := east*east + north*north
+	if norm == 0 {
+		return 0, vx == 0 && vy == 0
+	}
+	tolerance := 1e-6 * math.Max(1, math.Hypot(vx, vy))
+	groundSpeed := (vx*east + vy*north) / norm
+	residual := math.Hypot(vx-groundSpeed*east, vy-groundSpeed*north)
+	return groundSpeed, residual <= tolerance && groundSpeed*math.Sqrt(norm) >= -tolerance
+}
+
+// classifyCSVVelocity tells whether a stored horizontal velocity was derived with the legacy or the
+// current sine and cosine at a true heading, returning the ground speed in m/s the legacy derivation
+// started from
+func classifyCSVVelocity(vx, vy, headingDeg float64) (csvVelocityFit, float64) {
+	legacyRad := headingDeg * 3.14159 / 180
+	legacyGroundSpeed, legacy := fitGroundSpeed(vx, vy, legacyCSVSin(legacyRad), legacyCSVCos(legacyRad))
+	currentRad := headingDeg * math.Pi / 180
+	_, current := fitGroundSpeed(vx, vy, math.Sin(currentRad), math.Cos(currentRad))
+
+	switch {
+	case legacy && current:
+		return csvVelocityAmbiguous, legacyGroundSpeed
+	case legacy:
+		return csvVelocityLegacy, legacyGroundSpeed
+	case current:
+		return csvVelocityCurrent, 0
+	}
+	return csvVelocityNeither, 0
+}
+
+// ensureCSVAttitudeVelocities recomputes the horizontal velocities of CSV-imported aircraft stored with
+// the legacy sine and cosine. An aircraft is converted when its samples fit only the legacy derivation;
+// the stored metrics of its flight are dropped so they are computed again.
+func ensureCSVAttitudeVelocities() error {
+	rows, err := mainDB.Query(`
+		SELECT a.id, a.flight_id
+		FROM aircraft a
+		JOIN flight f ON f.id = a.flight_id
+		WHERE a.tail_number = 'CSV-IMPORT' OR f.description LIKE 'Imported from CSV%'
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to get CSV aircraft: %w", err)
+	}
+	type csvAircraft struct{ id, flightID int }
+	var aircraft []csvAircraft
+	for rows.Next() {
+		var a csvAircraft
+		if err := rows.Scan(&a.id, &a.flightID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan CSV aircraft: %w", err)
+		}
+		aircraft = append(aircraft, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get CSV aircraft: %w", err)
+	}
+
+	converted := 0
+	for _, a := range aircraft {
+		samples, err := fixLegacyCSVVelocities(a.id)
+		if err != nil {
+			return fmt.Errorf("failed to recompute velocities of aircraft %d: %w", a.id, err)
+		}
+		if samples == 0 {
+			continue
+		}
+		converted++
+		if err := invalidateFlightMetrics(a.flightID); err != nil {
+			return fmt.Errorf("failed to invalidate metrics of flight %d: %w", a.flightID, err)
+		}
+		log.Printf("Recomputed the velocities of %d attitude samples of CSV aircraft %d (flight %d)", samples, a.id, a.flightID)
+	}
+	if converted > 0 {
+		log.Printf("Recomputed the velocities of %d CSV aircraft imported with the legacy trigonometry", converted)
+	}
+	return nil
+}
+
+// fixLegacyCSVVelocities recomputes the horizontal velocities of one CSV aircraft if they were derived
+// with the legacy sine and cosine, returning the number of samples changed
+func fixLegacyCSVVelocities(aircraftID int) (int, error) {
+	rows, err := mainDB.Query("SELECT timestamp, true_heading, velocity_x, velocity_y FROM attitude WHERE aircraft_id = ? ORDER BY timestamp", aircraftID)
+	if err != nil {
+		return 0, err
+	}
+
+	type update struct {
+		timestamp int64
+		vx, vy    float64
+	}
+	var updates []update
+	legacyFound := false
+	for rows.Next() {
+		var timestamp int64
+		var heading, vx, vy sql.NullFloat64
+		if err := rows.Scan(&timestamp, &heading, &vx, &vy); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		// Samples without a heading were derived as if heading north
+		fit, groundSpeed := classifyCSVVelocity(vx.Float64, vy.Float64, heading.Float64)
+		switch fit {
+		case csvVelocityCurrent:
+			// Already derived with the math package, e.g. imported after the fix
+			rows.Close()
+			return 0, nil
+		case csvVelocityLegacy, csvVelocityAmbiguous:
+			legacyFound = legacyFound || fit == csvVelocityLegacy
+			x, y, _ := csvAttitudeVelocity(groundSpeed/knotsToMetersPerSecond, heading.Float64, 0)
+			updates = append(updates, update{timestamp, x, y})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if !legacyFound {
+		return 0, nil
+	}
+
+	tx, err := mainDB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare("UPDATE attitude SET velocity_x = ?, velocity_y = ? WHERE aircraft_id = ? AND timestamp = ?")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, u := range updates {
+		if _, err := stmt.Exec(u.vx, u.vy, aircraftID, u.timestamp); err != nil {
+			return 0, err
+		}
+	}
+	return len(updates), tx.Commit()
+}
@@ -0,0 +1,129 @@
+package data_analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCSVAttitudeVelocity(t *testing.T) {
+	const groundSpeedMS = 100 * knotsToMetersPerSecond
+	tests := []struct {
+		headingDeg float64
+		wantX      float64 // East
+		wantY      float64 // North
+	}{
+		{0, 0, groundSpeedMS},
+		{90, groundSpeedMS, 0},
+		{180, 0, -groundSpeedMS},
+		{270, -groundSpeedMS, 0},
+		{350, -groundSpeedMS * math.Sin(10*math.Pi/180), groundSpeedMS * math.Cos(10*math.Pi/180)},
+	}
+	for _, tt := range tests {
+		x, y, z := csvAttitudeVelocity(100, tt.headingDeg, 500)
+		if math.Abs(x-tt.wantX) > 1e-9 || math.Abs(y-tt.wantY) > 1e-9 {
+			t.Errorf("heading %g: velocity (%g, %g), want (%g, %g)", tt.headingDeg, x, y, tt.wantX, tt.wantY)
+		}
+		if math.Abs(z-2.54) > 1e-9 {
+			t.Errorf("heading %g: vertical velocity %g, want 2.54", tt.headingDeg, z)
+		}
+		if speed := math.Hypot(x, y); math.Abs(speed-groundSpeedMS) > 1e-9 {
+			t.Errorf("heading %g: horizontal speed %g, want %g", tt.headingDeg, speed, groundSpeedMS)
+		}
+	}
+}
+
+func TestDeriveAirspeedsGroundVelocity(t *testing.T) {
+	// Without wind at sea level in ISA conditions, the estimated airspeeds are the ground speed
+	for _, track := range []float64{0, 90, 180, 270, 350} {
+		positions := []PositionPoint{{GroundSpeed: 100, GroundTrack: track}}
+		deriveAirspeeds(positions, nil)
+		if math.Abs(positions[0].TrueAirspeed-100) > 1e-9 || math.Abs(positions[0].Airspeed-100) > 1e-9 {
+			t.Errorf("track %g: airspeed %g, true airspeed %g, want 100", track, positions[0].Airspeed, positions[0].TrueAirspeed)
+		}
+		if positions[0].AirspeedSource != AirspeedEstimated {
+			t.Errorf("track %g: airspeed source %q, want %q", track, positions[0].AirspeedSource, AirspeedEstimated)
+		}
+	}
+
+	// A 30 kt wind from the east against a 40 kt northbound ground track leaves 50 kt through the air
+	speed, direction := 30.0, 90.0
+	positions := []PositionPoint{{GroundSpeed: 40, GroundTrack: 0, WindSpeed: &speed, WindDirection: &direction}}
+	deriveAirspeeds(positions, nil)
+	if math.Abs(positions[0].TrueAirspeed-50) > 1e-9 {
+		t.Errorf("true airspeed %g, want 50", positions[0].TrueAirspeed)
+	}
+}
+
+// legacyVelocity is the horizontal velocity CSV imports stored before the math package was used
+func legacyVelocity(groundSpeedKnots, headingDeg float64) (float64, float64) {
+	headingRad := headingDeg * 3.14159 / 180
+	return groundSpeedKnots * knotsToMetersPerSecond * legacyCSVSin(headingRad), groundSpeedKnots * knotsToMetersPerSecond * legacyCSVCos(headingRad)
+}
+
+func TestClassifyCSVVelocity(t *testing.T) {
+	for _, heading := range []float64{45, 90, 180, 270, 350} {
+		vx, vy := legacyVelocity(100, heading)
+		fit, groundSpeed := classifyCSVVelocity(vx, vy, heading)
+		if fit != csvVelocityLegacy {
+			t.Errorf("heading %g: legacy velocity classified as %d", heading, fit)
+		}
+		if math.Abs(groundSpeed-100*knotsToMetersPerSecond) > 1e-6 {
+			t.Errorf("heading %g: recovered ground speed %g, want %g", heading, groundSpeed, 100*knotsToMetersPerSecond)
+		}
+
+		x, y, _ := csvAttitudeVelocity(100, heading, 0)
+		if fit, _ := classifyCSVVelocity(x, y, heading); fit != csvVelocityCurrent {
+			t.Errorf("heading %g: current velocity classified as %d", heading, fit)
+		}
+	}
+
+	if fit, _ := classifyCSVVelocity(0, 0, 90); fit != csvVelocityAmbiguous {
+		t.Errorf("standing still classified as %d, want ambiguous", fit)
+	}
+}
+
+func TestEnsureCSVAttitudeVelocities(t *testing.T) {
+	openTestDatabase(t)
+	_, legacyID := insertTestFlight(t, "Legacy", "Imported from CSV (test) - 3 data points", "CSV-IMPORT")
+	_, currentID := insertTestFlight(t, "Current", "Imported from CSV (test) - 1 data points", "CSV-IMPORT")
+
+	headings := []float64{0, 90, 200}
+	for i, heading := range headings {
+		vx, vy := legacyVelocity(80, heading)
+		if _, err := mainDB.Exec("INSERT INTO attitude (aircraft_id, timestamp, true_heading, velocity_x, velocity_y, velocity_z) VALUES (?, ?, ?, ?, ?, 0)",
+			legacyID, i*1000, heading, vx, vy); err != nil {
+			t.Fatalf("failed to insert attitude: %v", err)
+		}
+	}
+	currentX, currentY, _ := csvAttitudeVelocity(80, 200, 0)
+	if _, err := mainDB.Exec("INSERT INTO attitude (aircraft_id, timestamp, true_heading, velocity_x, velocity_y, velocity_z) VALUES (?, 0, 200, ?, ?, 0)",
+		currentID, currentX, currentY); err != nil {
+		t.Fatalf("failed to insert attitude: %v", err)
+	}
+
+	// A second run finds nothing left to convert
+	for run := 0; run < 2; run++ {
+		if err := ensureCSVAttitudeVelocities(); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+
+		for i, heading := range headings {
+			var vx, vy float64
+			if err := mainDB.QueryRow("SELECT velocity_x, velocity_y FROM attitude WHERE aircraft_id = ? AND timestamp = ?", legacyID, i*1000).Scan(&vx, &vy); err != nil {
+				t.Fatalf("failed to read attitude: %v", err)
+			}
+			wantX, wantY, _ := csvAttitudeVelocity(80, heading, 0)
+			if math.Abs(vx-wantX) > 1e-6 || math.Abs(vy-wantY) > 1e-6 {
+				t.Errorf("run %d, heading %g: velocity (%g, %g), want (%g, %g)", run, heading, vx, vy, wantX, wantY)
+			}
+		}
+
+		var vx, vy float64
+		if err := mainDB.QueryRow("SELECT velocity_x, velocity_y FROM attitude WHERE aircraft_id = ?", currentID).Scan(&vx, &vy); err != nil {
+			t.Fatalf("failed to read attitude: %v", err)
+		}
+		if vx != currentX || vy != currentY {
+			t.Errorf("run %d: velocity of a current import changed to (%g, %g)", run, vx, vy)
+		}
+	}
+}
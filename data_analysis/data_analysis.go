@@ -1,12 +1,14 @@
 package data_analysis
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"math"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -21,11 +23,6 @@ import (
 
 var (
 	tempDir = "temp_uploads"
-	
-	// Currock Hill coordinates (shared from GPS module)
-	currockHillLat = 54.9275
-	currockHillLon = -1.8342
-	targetDistanceNM = 9.0
 )
 
 func Init() {
@@ -34,28 +31,133 @@ func Init() {
 		log.Printf("Failed to create temp directory: %v", err)
 	}
 
+	initAnalysisConfig()
+
 	// Initialize the main database
 	if err := InitMainDatabase(); err != nil {
 		log.Fatalf("Failed to initialize main database: %v", err)
 	}
 
+	initDistanceMarkerSettings()
+	initLogbookSettings()
+
+	// Precompute statistics in the background so the UI never waits for them
+	go startStatisticsPrecomputation()
+
+	// Import recordings synced into the inbox by the sim PC
+	go startInboxWatcher()
+
+	// Import uploaded recordings in the background, one at a time
+	go startImportWorker()
+
+	// Purge flights archived longer than the retention window
+	go startArchivePurge()
+
 	log.Println("Data Analysis module initialized")
 }
 
 func SetupHandlers() {
-	http.HandleFunc("/data-analysis", serveDataAnalysisPage)
-	http.HandleFunc("/data-analysis/upload", handleDatabaseUpload)
-	http.HandleFunc("/data-analysis/flights", handleGetFlights)
-	http.HandleFunc("/data-analysis/flight-data", handleGetFlightData)
-	http.HandleFunc("/data-analysis/markers", handleMarkers)
-	http.HandleFunc("/data-analysis/distance-markers", handleCreateDistanceMarkers)
-	http.HandleFunc("/data-analysis/trim-markers", handleTrimMarkers)
-	http.HandleFunc("/data-analysis/duplicate-flight", handleDuplicateFlight)
-	http.HandleFunc("/data-analysis/trim-flight", handleTrimFlight)
-	http.HandleFunc("/data-analysis/delete-flight", handleDeleteFlight)
-	http.HandleFunc("/data-analysis/export-csv", handleCSVExport)
-	http.HandleFunc("/data-analysis/statistics", handleGetStatistics)
+	http.HandleFunc("GET /data-analysis", serveDataAnalysisPage)
+	http.HandleFunc("POST /data-analysis/upload", handleDatabaseUpload)
+	http.HandleFunc("POST /data-analysis/uploads", handleCreateChunkedUpload)
+	http.HandleFunc("GET /data-analysis/uploads/{uploadId}", handleGetChunkedUpload)
+	http.HandleFunc("PUT /data-analysis/uploads/{uploadId}", handleUploadChunk)
+	http.HandleFunc("DELETE /data-analysis/uploads/{uploadId}", handleCancelChunkedUpload)
+	http.HandleFunc("GET /data-analysis/import-status", handleGetImportStatus)
+	http.HandleFunc("GET /data-analysis/import-status/{jobId}", handleGetImportJob)
+	http.HandleFunc("POST /data-analysis/import-status/{jobId}/cancel", handleCancelImportJob)
+	http.HandleFunc("POST /data-analysis/import-url", handleImportURL)
+	http.HandleFunc("GET /data-analysis/logbooks", handleGetLogbooks)
+	http.HandleFunc("POST /data-analysis/logbooks/import", handleImportLogbook)
+	http.HandleFunc("GET /data-analysis/settings/logbooks", handleGetLogbookSettings)
+	http.HandleFunc("GET /data-analysis/admin/config", handleGetAnalysisConfig)
+	http.HandleFunc("PUT /data-analysis/settings/logbooks", handleUpdateLogbookSettings)
+	http.HandleFunc("GET /data-analysis/flights", handleGetFlights)
+	http.HandleFunc("GET /data-analysis/export", handleBatchExport)
+	http.HandleFunc("GET /data-analysis/export-statistics", handleExportStatistics)
+	http.HandleFunc("GET /data-analysis/export-long", handleExportLong)
+	http.HandleFunc("GET /data-analysis/backup", handleBackup)
+	http.HandleFunc("POST /data-analysis/admin/maintenance", handleMaintenance)
+	http.HandleFunc("POST /data-analysis/metrics/recompute", handleRecomputeAllMetrics)
+	http.HandleFunc("GET /data-analysis/track.geojson", handleTrackGeoJSONQuery)
+	http.HandleFunc("GET /data-analysis/variance", handleGetVarianceQuery)
+	http.HandleFunc("GET /data-analysis/aircraft", handleGetAircraftQuery)
 	http.HandleFunc("/data-analysis/api/", handleAPIRequest)
+	http.HandleFunc("GET /data-analysis/settings/distance-markers", handleGetDistanceMarkerSettings)
+	http.HandleFunc("PUT /data-analysis/settings/distance-markers", handleUpdateDistanceMarkerSettings)
+	http.HandleFunc("GET /data-analysis/settings/gps-gate", handleGetGPSGateSettings)
+	http.HandleFunc("PUT /data-analysis/settings/gps-gate", handleUpdateGPSGateSettings)
+
+	// Reference point library
+	http.HandleFunc("GET /data-analysis/reference-points", handleGetReferencePoints)
+	http.HandleFunc("POST /data-analysis/reference-points", handleCreateReferencePoint)
+	http.HandleFunc("PUT /data-analysis/reference-points/{pointId}", handleUpdateReferencePoint)
+	http.HandleFunc("DELETE /data-analysis/reference-points/{pointId}", handleDeleteReferencePoint)
+	http.HandleFunc("GET /data-analysis/reference-profiles", handleGetReferenceProfiles)
+	http.HandleFunc("POST /data-analysis/reference-profiles", handleCreateReferenceProfile)
+	http.HandleFunc("DELETE /data-analysis/reference-profiles/{profileId}", handleDeleteReferenceProfile)
+	http.HandleFunc("GET /data-analysis/runways", handleGetRunways)
+	http.HandleFunc("POST /data-analysis/runways", handleCreateRunway)
+	http.HandleFunc("DELETE /data-analysis/runways/{runwayId}", handleDeleteRunway)
+	http.HandleFunc("GET /data-analysis/participants", handleGetParticipants)
+	http.HandleFunc("POST /data-analysis/participants", handleCreateParticipant)
+	http.HandleFunc("GET /data-analysis/participants/{participantId}", handleGetParticipant)
+	http.HandleFunc("PUT /data-analysis/participants/{participantId}", handleUpdateParticipant)
+	http.HandleFunc("DELETE /data-analysis/participants/{participantId}", handleDeleteParticipant)
+	http.HandleFunc("GET /data-analysis/settings/distance-marker-waypoints", handleGetDistanceMarkerWaypoints)
+	http.HandleFunc("POST /data-analysis/settings/distance-marker-waypoints", handleCreateDistanceMarkerWaypoint)
+	http.HandleFunc("PUT /data-analysis/settings/distance-marker-waypoints/{waypointId}", handleUpdateDistanceMarkerWaypoint)
+	http.HandleFunc("DELETE /data-analysis/settings/distance-marker-waypoints/{waypointId}", handleDeleteDistanceMarkerWaypoint)
+
+	// Flight-scoped routes; withFlightID resolves and validates the {id} path parameter
+	http.HandleFunc("GET /data-analysis/flights/{id}", withFlightID(handleGetFlightData))
+	http.HandleFunc("DELETE /data-analysis/flights/{id}", withFlightID(handleDeleteFlight))
+	http.HandleFunc("POST /data-analysis/flights/{id}/restore", withFlightID(handleRestoreFlight))
+	http.HandleFunc("POST /data-analysis/flights/{id}/purge", withFlightID(handlePurgeFlight))
+	http.HandleFunc("POST /data-analysis/flights/{id}/duplicate", withFlightID(handleDuplicateFlight))
+	http.HandleFunc("POST /data-analysis/flights/{id}/trim", withFlightID(handleTrimFlight))
+	http.HandleFunc("POST /data-analysis/flights/{id}/resample", withFlightID(handleResampleFlight))
+	http.HandleFunc("POST /data-analysis/flights/{id}/align", withFlightID(handleAlignFlight))
+	http.HandleFunc("GET /data-analysis/flights/{id}/positions", withFlightID(handleGetPositionPage))
+	http.HandleFunc("GET /data-analysis/flights/{id}/positions.ndjson", withFlightID(handleStreamPositions))
+	http.HandleFunc("GET /data-analysis/flights/{id}/statistics", withFlightID(handleGetStatistics))
+	http.HandleFunc("GET /data-analysis/flights/{id}/wind-corrected-statistics", withFlightID(handleGetWindCorrectedStatistics))
+	http.HandleFunc("GET /data-analysis/flights/{id}/cross-correlation", withFlightID(handleGetCrossCorrelation))
+	http.HandleFunc("GET /data-analysis/flights/{id}/diff", withFlightID(handleGetFlightDiff))
+	http.HandleFunc("GET /data-analysis/flights/{id}/tracking-error", withFlightID(handleGetTrackingError))
+	http.HandleFunc("GET /data-analysis/flights/{id}/variance", withFlightID(handleGetVariance))
+	http.HandleFunc("GET /data-analysis/flights/{id}/live-overlay", withFlightID(handleGetLiveOverlay))
+	http.HandleFunc("GET /data-analysis/flights/{id}/response-latency", withFlightID(handleGetResponseLatency))
+	http.HandleFunc("GET /data-analysis/flights/{id}/approach-stability", withFlightID(handleGetApproachStability))
+	http.HandleFunc("GET /data-analysis/flights/{id}/glidepath", withFlightID(handleGetGlidepath))
+	http.HandleFunc("GET /data-analysis/flights/{id}/metrics", withFlightID(handleGetFlightMetrics))
+	http.HandleFunc("DELETE /data-analysis/flights/{id}/metrics", withFlightID(handleInvalidateFlightMetrics))
+	http.HandleFunc("POST /data-analysis/flights/{id}/metrics/recompute", withFlightID(handleRecomputeFlightMetrics))
+	http.HandleFunc("GET /data-analysis/flights/{id}/export", withFlightID(handleCSVExport))
+	http.HandleFunc("GET /data-analysis/flights/{id}/aircraft", withFlightID(handleGetAircraft))
+	http.HandleFunc("PATCH /data-analysis/flights/{id}/aircraft/{aircraftId}", withFlightID(handleUpdateAircraft))
+	http.HandleFunc("PUT /data-analysis/flights/{id}/target-aircraft", withFlightID(handleSetTargetAircraft))
+	http.HandleFunc("PUT /data-analysis/flights/{id}/participant", withFlightID(handleSetFlightParticipant))
+	http.HandleFunc("PUT /data-analysis/flights/{id}/review", withFlightID(handleReviewFlight))
+	http.HandleFunc("GET /data-analysis/flights/{id}/track.geojson", withFlightID(handleTrackGeoJSON))
+
+	// Marker routes
+	http.HandleFunc("GET /data-analysis/flights/{id}/markers", withFlightID(handleGetMarkers))
+	http.HandleFunc("POST /data-analysis/flights/{id}/markers", withFlightID(handleCreateMarker))
+	http.HandleFunc("DELETE /data-analysis/flights/{id}/markers/{markerId}", withFlightID(handleDeleteMarker))
+	http.HandleFunc("POST /data-analysis/flights/{id}/markers/{markerId}/nudge", withFlightID(handleNudgeMarker))
+	http.HandleFunc("POST /data-analysis/flights/{id}/markers/{markerId}/snap", withFlightID(handleSnapMarker))
+	http.HandleFunc("GET /data-analysis/flights/{id}/replay", withFlightID(handleGetReplay))
+	http.HandleFunc("PUT /data-analysis/flights/{id}/replay", withFlightID(handleUpdateReplay))
+	http.HandleFunc("DELETE /data-analysis/flights/{id}/replay", withFlightID(handleStopReplay))
+	http.HandleFunc("POST /data-analysis/flights/{id}/replay/markers", withFlightID(handleCreateReplayMarker))
+	http.HandleFunc("POST /data-analysis/flights/{id}/distance-markers", withFlightID(handleCreateDistanceMarkers))
+	http.HandleFunc("POST /data-analysis/flights/{id}/warning-markers", withFlightID(handleCreateWarningMarkers))
+	http.HandleFunc("POST /data-analysis/flights/{id}/oscillation-markers", withFlightID(handleCreateOscillationMarkers))
+	http.HandleFunc("POST /data-analysis/flights/{id}/event-markers", withFlightID(handleCreateEventMarkers))
+	http.HandleFunc("GET /data-analysis/flights/{id}/trim-markers", withFlightID(handleGetTrimMarkers))
+	http.HandleFunc("POST /data-analysis/flights/{id}/trim-markers", withFlightID(handleCreateTrimMarker))
+	http.HandleFunc("DELETE /data-analysis/flights/{id}/trim-markers", withFlightID(handleDeleteTrimMarkers))
 }
 
 func serveDataAnalysisPage(w http.ResponseWriter, r *http.Request) {
@@ -63,97 +165,222 @@ func serveDataAnalysisPage(w http.ResponseWriter, r *http.Request) {
 	DataAnalysisPage().Render(r.Context(), w)
 }
 
+// handleDatabaseUpload saves the uploaded recordings and queues their imports, answering with the
+// import jobs to follow on /data-analysis/import-status
 func handleDatabaseUpload(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// Parse multipart form
+	err := r.ParseMultipartForm(analysisConfig.UploadMemoryMB << 20) // Larger files are buffered on disk
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to parse form: %v", err), http.StatusBadRequest)
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 
-	// Parse multipart form
-	err := r.ParseMultipartForm(32 << 20) // 32 MB max
-	if err != nil {
-		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+	headers := r.MultipartForm.File["database"]
+	if len(headers) == 0 {
+		httpError(w, "No file in the database field", http.StatusBadRequest)
 		return
 	}
 
-	file, header, err := r.FormFile("database")
+	// Validate all file extensions before importing anything
+	for _, header := range headers {
+		if !isSupportedUploadFile(header.Filename) {
+			httpError(w, fmt.Sprintf("Invalid file format of %s. Please upload SQLite database files (.sdlog, .sqlite, .db) or CSV files (.csv).", header.Filename), http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	// Partial mode salvages what it can from flawed database recordings
+	options, err := parseImportOptions(r)
 	if err != nil {
-		http.Error(w, "Failed to get file", http.StatusBadRequest)
+		httpError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
 
-	// Validate file extension
-	filename := header.Filename
-	ext := strings.ToLower(filepath.Ext(filename))
-	if ext != ".sdlog" && ext != ".sqlite" && ext != ".db" && ext != ".csv" {
-		http.Error(w, "Invalid file format. Please upload a SQLite database file (.sdlog, .sqlite, .db) or CSV file (.csv).", http.StatusBadRequest)
-		return
+	// All files are saved before any is queued, so a failed upload queues nothing
+	paths := make([]string, len(headers))
+	for i, header := range headers {
+		paths[i], err = saveUploadedFile(header, i)
+		if err != nil {
+			for _, path := range paths[:i] {
+				os.Remove(path)
+			}
+			httpError(w, fmt.Sprintf("Failed to save %s: %v", header.Filename, err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Each file is imported on its own; a failing file does not stop the others
+	jobs := make([]ImportJob, 0, len(headers))
+	for i, header := range headers {
+		job, err := enqueueImport(paths[i], filepath.Base(header.Filename), options)
+		if err != nil {
+			os.Remove(paths[i])
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		jobs = append(jobs, job.ImportJob)
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "queued",
+		"message": fmt.Sprintf("Queued %d files for import", len(jobs)),
+		"jobs":    jobs,
+	})
+}
+
+// isSupportedUploadFile reports whether a file can be imported, going by its extension
+func isSupportedUploadFile(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".sdlog", ".sqlite", ".db", ".csv":
+		return true
+	}
+	return false
+}
+
+// saveUploadedFile saves an uploaded file to the temp directory, returning its path
+func saveUploadedFile(header *multipart.FileHeader, index int) (string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
 	// Create unique filename
 	timestamp := time.Now().Format("20060102_150405")
-	tempFilename := fmt.Sprintf("uploaded_%s_%s", timestamp, filename)
+	tempFilename := fmt.Sprintf("uploaded_%s_%d_%s", timestamp, index, filepath.Base(header.Filename))
 	tempPath := filepath.Join(tempDir, tempFilename)
 
 	// Save file
 	dst, err := os.Create(tempPath)
 	if err != nil {
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
-		return
+		return "", err
 	}
-	defer dst.Close()
-
 	_, err = io.Copy(dst, file)
+	dst.Close()
 	if err != nil {
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
-		return
+		os.Remove(tempPath)
+		return "", err
 	}
+	return tempPath, nil
+}
 
-	// Import flights based on file type
-	var flights []Flight
-	if ext == ".csv" {
-		// Handle CSV import
-		flight, err := importCSVFile(tempPath, filename)
+// failedImport returns the result of a file that could not be imported
+func failedImport(filename, message string) UploadFileResult {
+	return UploadFileResult{Filename: filename, Status: "failed", Message: message, Flights: []Flight{}, Errors: []ImportError{}}
+}
+
+// importFile imports the flights of a database or CSV file, going by the extension of its filename.
+// A file imported before is skipped unless the import is forced. Cancelling ctx stops the import
+// before its flights are stored.
+func importFile(ctx context.Context, path, filename string, options ImportOptions) UploadFileResult {
+	result := UploadFileResult{Filename: filename, Flights: []Flight{}, Errors: []ImportError{}}
+	skippedRows := 0
+
+	hash, err := hashRecording(path)
+	if err != nil {
+		return failedImport(filename, err.Error())
+	}
+	if !options.Force {
+		duplicates, err := findFlightsBySourceHash(hash)
 		if err != nil {
-			os.Remove(tempPath)
-			http.Error(w, fmt.Sprintf("Failed to import CSV: %v", err), http.StatusBadRequest)
-			return
+			return failedImport(filename, err.Error())
+		}
+		if len(duplicates) > 0 {
+			log.Printf("Skipping %s, it was imported before", filename)
+			return skippedImport(filename, duplicates)
 		}
-		flights = []Flight{*flight}
+	}
+
+	if strings.ToLower(filepath.Ext(filename)) == ".csv" {
+		flight, csvData, err := importCSVFile(ctx, path, filename)
+		if err != nil {
+			return failedImport(filename, fmt.Sprintf("Failed to import CSV: %v", err))
+		}
+		result.Flights = []Flight{*flight}
+		result.Warnings = csvData.Warnings
+		skippedRows = csvData.Metadata.SkippedRows
 	} else {
-		// Handle database import
-		var err error
-		flights, err = ImportFlightsFromDatabase(tempPath)
+		report, err := ImportFlightsFromDatabaseWithOptions(ctx, path, options)
 		if err != nil {
-			os.Remove(tempPath)
-			http.Error(w, fmt.Sprintf("Failed to import flights: %v", err), http.StatusBadRequest)
-			return
+			return failedImport(filename, fmt.Sprintf("Failed to import flights: %v", err))
+		}
+		result.Flights = report.Flights
+		result.Errors = report.Errors
+		result.Duplicates = report.Duplicates
+		applyTargetAircraftRule(result.Flights)
+		createImportedWarningMarkers(result.Flights)
+	}
+
+	setFlightSourceHash(result.Flights, hash)
+	// Flights imported during a session were recorded under its logged conditions
+	attachSessionConditions(result.Flights)
+	createImportedEventMarkers(result.Flights)
+	assessImportedFlights(result.Flights)
+
+	result.Status = "success"
+	result.Message = fmt.Sprintf("Successfully imported %d flights from %s", len(result.Flights), filename)
+	if len(result.Errors) > 0 {
+		result.Status = "partial"
+		result.Message = fmt.Sprintf("Imported %d flights from %s with %d errors", len(result.Flights), filename, len(result.Errors))
+	}
+	if skippedRows > 0 {
+		result.Status = "partial"
+		result.Message = fmt.Sprintf("Imported %d flights from %s, skipping %d rows", len(result.Flights), filename, skippedRows)
+	}
+	if len(result.Duplicates) > 0 {
+		if len(result.Flights) == 0 && len(result.Errors) == 0 {
+			return skippedImport(filename, result.Duplicates)
 		}
+		result.Message += fmt.Sprintf(" (skipped %d flights imported before)", len(result.Duplicates))
 	}
+	return result
+}
 
-	// Clean up temporary file
-	os.Remove(tempPath)
+// parseImportOptions reads the database import options from the upload form
+func parseImportOptions(r *http.Request) (ImportOptions, error) {
+	options := ImportOptions{
+		Partial:      r.FormValue("partial") == "true",
+		PositionOnly: r.FormValue("position_only") == "true",
+		Force:        r.FormValue("force") == "true",
+	}
 
-	response := map[string]interface{}{
-		"status":  "success",
-		"message": fmt.Sprintf("Successfully imported %d flights from %s", len(flights), filename),
-		"flights": flights,
+	if everyN := r.FormValue("thin_every_n"); everyN != "" {
+		n, err := strconv.Atoi(everyN)
+		if err != nil {
+			return options, fmt.Errorf("invalid thin_every_n value: %s", everyN)
+		}
+		options.ThinEveryN = n
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	if minDelta := r.FormValue("thin_min_delta_ms"); minDelta != "" {
+		ms, err := strconv.ParseInt(minDelta, 10, 64)
+		if err != nil {
+			return options, fmt.Errorf("invalid thin_min_delta_ms value: %s", minDelta)
+		}
+		options.ThinMinDeltaMs = ms
+	}
+
+	if skip := r.FormValue("skip_tables"); skip != "" {
+		for _, table := range strings.Split(skip, ",") {
+			options.SkipTables = append(options.SkipTables, strings.TrimSpace(table))
+		}
+	}
+
+	return options, nil
 }
 
 func handleGetFlights(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+	// ?archived=true lists the archived flights instead, e.g. to restore one
+	get := getFlightsFromMainDB
+	if r.URL.Query().Get("archived") == "true" {
+		get = getArchivedFlightsFromMainDB
 	}
-
-	flights, err := getFlightsFromMainDB()
+	flights, err := get()
 	if err != nil {
-		http.Error(w, "Failed to get flights", http.StatusInternalServerError)
+		httpError(w, "Failed to get flights", http.StatusInternalServerError)
 		return
 	}
 
@@ -161,25 +388,37 @@ func handleGetFlights(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(flights)
 }
 
-func handleGetFlightData(w http.ResponseWriter, r *http.Request) {
-	flightIdStr := r.URL.Query().Get("flightId")
-
-	if flightIdStr == "" {
-		http.Error(w, "Flight ID required", http.StatusBadRequest)
-		return
+func handleGetFlightData(w http.ResponseWriter, r *http.Request, flightId int) {
+	// Optional max_points limits every aircraft series, decimated with LTTB keeping extremes
+	maxPoints := 0
+	if value := r.URL.Query().Get("max_points"); value != "" {
+		var err error
+		maxPoints, err = strconv.Atoi(value)
+		if err != nil || maxPoints < minDownsamplePoints {
+			httpError(w, fmt.Sprintf("Invalid max_points (expected an integer of at least %d)", minDownsamplePoints), http.StatusBadRequest)
+			return
+		}
 	}
 
-	flightId, err := strconv.Atoi(flightIdStr)
+	// Optional align_marker or align_label shifts times so the marker is at t=0
+	alignment, err := parseAlignment(r, flightId)
 	if err != nil {
-		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
+		writeAlignmentError(w, err)
 		return
 	}
 
 	flightData, err := getFlightDataFromMainDB(flightId)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if maxPoints > 0 {
+		downsampleFlightData(flightData, maxPoints)
+	}
+	if alignment != nil {
+		alignFlightData(flightData, alignment)
+	}
+	configuredUnits().convertFlightData(flightData)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(flightData)
@@ -196,21 +435,40 @@ func handleAPIRequest(w http.ResponseWriter, r *http.Request) {
 	case "stats":
 		stats, err := getMainDatabaseStats()
 		if err != nil {
-			http.Error(w, "Failed to get database stats", http.StatusInternalServerError)
+			httpError(w, "Failed to get database stats", http.StatusInternalServerError)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(stats)
 	default:
-		http.Error(w, "API endpoint not found", http.StatusNotFound)
+		httpError(w, "API endpoint not found", http.StatusNotFound)
 	}
 }
 
+// noEngineDataColumn selects whether no aircraft of flight f has engine samples
+const noEngineDataColumn = `NOT EXISTS (SELECT 1 FROM engine e JOIN aircraft a ON a.id = e.aircraft_id WHERE a.flight_id = f.id)`
+
+// getFlightsFromMainDB returns the flights that are not archived
 func getFlightsFromMainDB() ([]Flight, error) {
+	return queryFlightsFromMainDB(false)
+}
+
+// getArchivedFlightsFromMainDB returns the archived flights, which can be restored or purged
+func getArchivedFlightsFromMainDB() ([]Flight, error) {
+	return queryFlightsFromMainDB(true)
+}
+
+func queryFlightsFromMainDB(archived bool) ([]Flight, error) {
+	archivedFilter := "f.archived_at IS NULL"
+	if archived {
+		archivedFilter = "f.archived_at IS NOT NULL"
+	}
 	query := `
-		SELECT id, title, flight_number, start_zulu_sim_time, end_zulu_sim_time
-		FROM flight
-		ORDER BY start_zulu_sim_time DESC
+		SELECT f.id, f.title, f.flight_number, f.start_zulu_sim_time, f.end_zulu_sim_time, f.archived_at, f.participant_id, f.participant_condition,
+		       `+noEngineDataColumn+`, `+flightWeatherColumns+`
+		FROM flight f
+		WHERE `+archivedFilter+`
+		ORDER BY f.start_zulu_sim_time DESC
 	`
 
 	rows, err := mainDB.Query(query)
@@ -222,13 +480,20 @@ func getFlightsFromMainDB() ([]Flight, error) {
 	var flights []Flight
 	for rows.Next() {
 		var f Flight
-		var title, flightNumber sql.NullString
+		var title, flightNumber, archivedAt, participantID, condition sql.NullString
 		var startTime, endTime string
+		var weather flightWeatherScan
 
-		err := rows.Scan(&f.ID, &title, &flightNumber, &startTime, &endTime)
+		dest := append([]interface{}{&f.ID, &title, &flightNumber, &startTime, &endTime, &archivedAt, &participantID, &condition, &f.NoEngineData}, weather.dest()...)
+		err := rows.Scan(dest...)
 		if err != nil {
 			return nil, err
 		}
+		f.ArchivedAt = archivedAt.String
+		f.PurgeAt = archivePurgeTime(f.ArchivedAt)
+		f.ParticipantID = participantID.String
+		f.Condition = condition.String
+		f.Weather = weather.weather()
 
 		f.Title = title.String
 		if f.Title == "" {
@@ -245,6 +510,19 @@ func getFlightsFromMainDB() ([]Flight, error) {
 
 		flights = append(flights, f)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range flights {
+		flights[i].Conditions, err = getFlightConditions(flights[i].ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get conditions of flight %d: %w", flights[i].ID, err)
+		}
+		if err := loadFlightReview(&flights[i]); err != nil {
+			return nil, fmt.Errorf("failed to get review of flight %d: %w", flights[i].ID, err)
+		}
+	}
 
 	return flights, nil
 }
@@ -266,6 +544,8 @@ func getFlightDataFromMainDB(flightID int) (*FlightData, error) {
 		Flight:       flight,
 		PositionData: make(map[string][]PositionPoint),
 		EngineData:   make(map[string][]EnginePoint),
+		AttitudeData: make(map[string][]AttitudePoint),
+		StateData:    make(map[string][]StatePoint),
 	}
 
 	// Get position and engine data for each aircraft
@@ -283,11 +563,20 @@ func getFlightDataFromMainDB(flightID int) (*FlightData, error) {
 			log.Printf("Failed to get engine data for aircraft %d: %v", ac.ID, err)
 		}
 
-		aircraftLabel := ac.Type
-		if ac.TailNumber != "" {
-			aircraftLabel += fmt.Sprintf(" (%s)", ac.TailNumber)
+		// Get attitude data
+		attitudeData, err := getAttitudeDataFromMainDB(ac.ID)
+		if err != nil {
+			log.Printf("Failed to get attitude data for aircraft %d: %v", ac.ID, err)
 		}
 
+		// Get flaps, gear, fuel, g-force and warnings
+		stateData, err := getAircraftStateFromMainDB(ac.ID)
+		if err != nil {
+			log.Printf("Failed to get aircraft state data for aircraft %d: %v", ac.ID, err)
+		}
+
+		aircraftLabel := ac.Label()
+
 		if len(positionData) > 0 {
 			flightData.PositionData[aircraftLabel] = positionData
 		}
@@ -295,6 +584,14 @@ func getFlightDataFromMainDB(flightID int) (*FlightData, error) {
 		if len(engineData) > 0 {
 			flightData.EngineData[aircraftLabel] = engineData
 		}
+
+		if len(attitudeData) > 0 {
+			flightData.AttitudeData[aircraftLabel] = attitudeData
+		}
+
+		if len(stateData) > 0 {
+			flightData.StateData[aircraftLabel] = stateData
+		}
 	}
 
 	return flightData, nil
@@ -302,19 +599,35 @@ func getFlightDataFromMainDB(flightID int) (*FlightData, error) {
 
 func getFlightByIDFromMainDB(flightID int) (*Flight, error) {
 	query := `
-		SELECT id, title, flight_number, start_zulu_sim_time, end_zulu_sim_time
-		FROM flight
-		WHERE id = ?
+		SELECT f.id, f.title, f.flight_number, f.start_zulu_sim_time, f.end_zulu_sim_time, f.archived_at, f.participant_id, f.participant_condition,
+		       `+noEngineDataColumn+`, `+flightWeatherColumns+`
+		FROM flight f
+		WHERE f.id = ?
 	`
 
 	var f Flight
-	var title, flightNumber sql.NullString
+	var title, flightNumber, archivedAt, participantID, condition sql.NullString
 	var startTime, endTime string
+	var weather flightWeatherScan
 
-	err := mainDB.QueryRow(query, flightID).Scan(&f.ID, &title, &flightNumber, &startTime, &endTime)
+	dest := append([]interface{}{&f.ID, &title, &flightNumber, &startTime, &endTime, &archivedAt, &participantID, &condition, &f.NoEngineData}, weather.dest()...)
+	err := mainDB.QueryRow(query, flightID).Scan(dest...)
 	if err != nil {
 		return nil, err
 	}
+	f.ArchivedAt = archivedAt.String
+	f.PurgeAt = archivePurgeTime(f.ArchivedAt)
+	f.ParticipantID = participantID.String
+	f.Condition = condition.String
+	f.Weather = weather.weather()
+
+	f.Conditions, err = getFlightConditions(flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight conditions: %w", err)
+	}
+	if err := loadFlightReview(&f); err != nil {
+		return nil, fmt.Errorf("failed to get flight review: %w", err)
+	}
 
 	f.Title = title.String
 	if f.Title == "" {
@@ -368,7 +681,8 @@ func getPositionDataWithAirspeedFromMainDB(aircraftID int) ([]PositionPoint, err
 	// Get position data
 	positionQuery := `
 		SELECT timestamp, altitude, latitude, longitude, 
-		       indicated_altitude, pressure_altitude, indicated_airspeed
+		       indicated_altitude, pressure_altitude, indicated_airspeed,
+		       wind_speed, wind_direction, vertical_speed, true_airspeed, ambient_temperature
 		FROM position
 		WHERE aircraft_id = ?
 		ORDER BY timestamp
@@ -381,6 +695,7 @@ func getPositionDataWithAirspeedFromMainDB(aircraftID int) ([]PositionPoint, err
 	defer rows.Close()
 
 	var positions []PositionPoint
+	var recordedVerticalSpeed []bool
 	var minTimestamp *int64
 
 	for rows.Next() {
@@ -388,9 +703,11 @@ func getPositionDataWithAirspeedFromMainDB(aircraftID int) ([]PositionPoint, err
 		var timestamp int64
 		var altitude, latitude, longitude sql.NullFloat64
 		var indicatedAltitude, pressureAltitude, indicatedAirspeed sql.NullFloat64
+		var windSpeed, windDirection, verticalSpeed, trueAirspeed, ambientTemperature sql.NullFloat64
 
 		err := rows.Scan(&timestamp, &altitude, &latitude, &longitude,
-			&indicatedAltitude, &pressureAltitude, &indicatedAirspeed)
+			&indicatedAltitude, &pressureAltitude, &indicatedAirspeed,
+			&windSpeed, &windDirection, &verticalSpeed, &trueAirspeed, &ambientTemperature)
 		if err != nil {
 			return nil, err
 		}
@@ -406,98 +723,132 @@ func getPositionDataWithAirspeedFromMainDB(aircraftID int) ([]PositionPoint, err
 		pos.Longitude = longitude.Float64
 		pos.IndicatedAltitude = indicatedAltitude.Float64
 		pos.PressureAltitude = pressureAltitude.Float64
+		pos.WindSpeed = nullFloat(windSpeed)
+		pos.WindDirection = nullFloat(windDirection)
+		pos.VerticalSpeed = verticalSpeed.Float64
+		pos.TrueAirspeed = trueAirspeed.Float64 // Derived by deriveAirspeeds unless recorded
+		pos.AmbientTemperature = nullFloat(ambientTemperature)
+		recordedVerticalSpeed = append(recordedVerticalSpeed, verticalSpeed.Valid)
 		
 		// Use stored indicated airspeed when available (CSV data)
 		if indicatedAirspeed.Valid && indicatedAirspeed.Float64 > 0 {
 			pos.Airspeed = indicatedAirspeed.Float64
 		} else {
-			pos.Airspeed = 0.0 // Will be set later from attitude data if available
+			pos.Airspeed = 0.0 // Estimated by deriveAirspeeds
 		}
 
 		positions = append(positions, pos)
 	}
+	deriveVerticalSpeed(positions, recordedVerticalSpeed)
+	deriveGroundSpeedAndTrack(positions)
 
-	// Get attitude data for airspeed calculation
-	attitudeQuery := `
-		SELECT timestamp, velocity_x, velocity_y, velocity_z
-		FROM attitude
+	weather, err := getAircraftFlightWeather(aircraftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight weather: %w", err)
+	}
+	deriveAirspeeds(positions, weather)
+
+	return positions, nil
+}
+
+func getEngineDataFromMainDB(aircraftID int) ([]EnginePoint, error) {
+	query := `
+		SELECT timestamp, 
+		       throttle_lever_position1, throttle_lever_position2, 
+		       throttle_lever_position3, throttle_lever_position4,
+		       propeller_lever_position1, propeller_lever_position2,
+		       propeller_lever_position3, propeller_lever_position4,
+		       mixture_lever_position1, mixture_lever_position2,
+		       mixture_lever_position3, mixture_lever_position4,
+		       cowl_flap_position1, cowl_flap_position2,
+		       cowl_flap_position3, cowl_flap_position4,
+		       electrical_master_battery1, electrical_master_battery2,
+		       electrical_master_battery3, electrical_master_battery4,
+		       general_engine_starter1, general_engine_starter2,
+		       general_engine_starter3, general_engine_starter4,
+		       general_engine_combustion1, general_engine_combustion2,
+		       general_engine_combustion3, general_engine_combustion4
+		FROM engine
 		WHERE aircraft_id = ?
 		ORDER BY timestamp
 	`
 
-	attitudeRows, err := mainDB.Query(attitudeQuery, aircraftID)
+	rows, err := mainDB.Query(query, aircraftID)
 	if err != nil {
-		// If attitude data is not available, return positions without airspeed
-		return positions, nil
+		return nil, err
 	}
-	defer attitudeRows.Close()
+	defer rows.Close()
 
-	type AttitudePoint struct {
-		Timestamp        int64
-		TimestampSeconds float64
-		VelocityX        float64
-		VelocityY        float64
-		VelocityZ        float64
-		Airspeed         float64
-	}
+	var engines []EnginePoint
+	var minTimestamp *int64
 
-	var attitudes []AttitudePoint
-	for attitudeRows.Next() {
-		var att AttitudePoint
+	for rows.Next() {
+		var eng EnginePoint
 		var timestamp int64
-		var velocityX, velocityY, velocityZ sql.NullFloat64
-
-		err := attitudeRows.Scan(&timestamp, &velocityX, &velocityY, &velocityZ)
+		var throttle, propeller, mixture, cowlFlap [4]sql.NullFloat64
+		var battery, starter, combustion [4]sql.NullInt64
+
+		err := rows.Scan(&timestamp,
+			&throttle[0], &throttle[1], &throttle[2], &throttle[3],
+			&propeller[0], &propeller[1], &propeller[2], &propeller[3],
+			&mixture[0], &mixture[1], &mixture[2], &mixture[3],
+			&cowlFlap[0], &cowlFlap[1], &cowlFlap[2], &cowlFlap[3],
+			&battery[0], &battery[1], &battery[2], &battery[3],
+			&starter[0], &starter[1], &starter[2], &starter[3],
+			&combustion[0], &combustion[1], &combustion[2], &combustion[3])
 		if err != nil {
-			continue
+			return nil, err
 		}
 
-		if minTimestamp != nil {
-			att.Timestamp = timestamp
-			att.TimestampSeconds = float64(timestamp-*minTimestamp) / 1000.0
-			att.VelocityX = velocityX.Float64
-			att.VelocityY = velocityY.Float64
-			att.VelocityZ = velocityZ.Float64
-
-			// Calculate airspeed from velocity components
-			att.Airspeed = calculateMagnitude(att.VelocityX, att.VelocityY, att.VelocityZ)
-
-			attitudes = append(attitudes, att)
+		if minTimestamp == nil {
+			minTimestamp = &timestamp
 		}
-	}
 
-	// Match airspeed to position data (only for positions without stored indicated airspeed)
-	for i := range positions {
-		// Skip if position already has indicated airspeed from CSV data
-		if positions[i].Airspeed > 0 {
-			continue
-		}
-		
-		// Find closest attitude data point for calculated airspeed
-		closestAirspeed := 0.0
-		minTimeDiff := float64(^uint(0) >> 1) // Max float64
-
-		for _, att := range attitudes {
-			timeDiff := abs(att.TimestampSeconds - positions[i].TimestampSeconds)
-			if timeDiff < minTimeDiff {
-				minTimeDiff = timeDiff
-				closestAirspeed = att.Airspeed
-			}
-		}
+		eng.Timestamp = timestamp
+		eng.TimestampSeconds = float64(timestamp-*minTimestamp) / 1000.0
+		eng.ThrottlePosition1 = throttle[0].Float64
+		eng.ThrottlePosition2 = throttle[1].Float64
+		eng.ThrottlePosition3 = throttle[2].Float64
+		eng.ThrottlePosition4 = throttle[3].Float64
+		eng.PropellerPosition1 = propeller[0].Float64
+		eng.PropellerPosition2 = propeller[1].Float64
+		eng.PropellerPosition3 = propeller[2].Float64
+		eng.PropellerPosition4 = propeller[3].Float64
+		eng.MixturePosition1 = mixture[0].Float64
+		eng.MixturePosition2 = mixture[1].Float64
+		eng.MixturePosition3 = mixture[2].Float64
+		eng.MixturePosition4 = mixture[3].Float64
+		eng.CowlFlapPosition1 = cowlFlap[0].Float64
+		eng.CowlFlapPosition2 = cowlFlap[1].Float64
+		eng.CowlFlapPosition3 = cowlFlap[2].Float64
+		eng.CowlFlapPosition4 = cowlFlap[3].Float64
+		eng.ElectricalMasterBattery1 = battery[0].Int64 != 0
+		eng.ElectricalMasterBattery2 = battery[1].Int64 != 0
+		eng.ElectricalMasterBattery3 = battery[2].Int64 != 0
+		eng.ElectricalMasterBattery4 = battery[3].Int64 != 0
+		eng.Starter1 = starter[0].Int64 != 0
+		eng.Starter2 = starter[1].Int64 != 0
+		eng.Starter3 = starter[2].Int64 != 0
+		eng.Starter4 = starter[3].Int64 != 0
+		eng.Combustion1 = combustion[0].Int64 != 0
+		eng.Combustion2 = combustion[1].Int64 != 0
+		eng.Combustion3 = combustion[2].Int64 != 0
+		eng.Combustion4 = combustion[3].Int64 != 0
 
-		positions[i].Airspeed = closestAirspeed
+		engines = append(engines, eng)
 	}
 
-	return positions, nil
+	return engines, nil
 }
 
-func getEngineDataFromMainDB(aircraftID int) ([]EnginePoint, error) {
+// getAttitudeDataFromMainDB returns the pitch, bank and heading samples of an aircraft, whether recorded
+// by Sky Dolly or imported from CSV. Samples without pitch or bank are left out so they do not count as
+// level flight.
+func getAttitudeDataFromMainDB(aircraftID int) ([]AttitudePoint, error) {
 	query := `
-		SELECT timestamp, 
-		       throttle_lever_position1, throttle_lever_position2, 
-		       throttle_lever_position3, throttle_lever_position4
-		FROM engine
-		WHERE aircraft_id = ?
+		SELECT timestamp, pitch, bank, true_heading, on_ground
+		FROM attitude
+		WHERE aircraft_id = ? AND pitch IS NOT NULL AND bank IS NOT NULL
 		ORDER BY timestamp
 	`
 
@@ -507,15 +858,16 @@ func getEngineDataFromMainDB(aircraftID int) ([]EnginePoint, error) {
 	}
 	defer rows.Close()
 
-	var engines []EnginePoint
+	var attitudes []AttitudePoint
 	var minTimestamp *int64
 
 	for rows.Next() {
-		var eng EnginePoint
+		var att AttitudePoint
 		var timestamp int64
-		var throttle1, throttle2, throttle3, throttle4 sql.NullFloat64
+		var pitch, bank, trueHeading sql.NullFloat64
+		var onGround sql.NullInt64
 
-		err := rows.Scan(&timestamp, &throttle1, &throttle2, &throttle3, &throttle4)
+		err := rows.Scan(&timestamp, &pitch, &bank, &trueHeading, &onGround)
 		if err != nil {
 			return nil, err
 		}
@@ -524,17 +876,17 @@ func getEngineDataFromMainDB(aircraftID int) ([]EnginePoint, error) {
 			minTimestamp = &timestamp
 		}
 
-		eng.Timestamp = timestamp
-		eng.TimestampSeconds = float64(timestamp-*minTimestamp) / 1000.0
-		eng.ThrottlePosition1 = throttle1.Float64
-		eng.ThrottlePosition2 = throttle2.Float64
-		eng.ThrottlePosition3 = throttle3.Float64
-		eng.ThrottlePosition4 = throttle4.Float64
+		att.Timestamp = timestamp
+		att.TimestampSeconds = float64(timestamp-*minTimestamp) / 1000.0
+		att.Pitch = pitch.Float64
+		att.Bank = bank.Float64
+		att.TrueHeading = trueHeading.Float64
+		att.OnGround = onGround.Int64 != 0
 
-		engines = append(engines, eng)
+		attitudes = append(attitudes, att)
 	}
 
-	return engines, nil
+	return attitudes, rows.Err()
 }
 
 func getMainDatabaseStats() (map[string]interface{}, error) {
@@ -570,36 +922,25 @@ func getMainDatabaseStats() (map[string]interface{}, error) {
 		stats["database_size_mb"] = float64(fileInfo.Size()) / (1024 * 1024)
 	}
 
-	return stats, nil
-}
-
-// Helper functions
-func calculateMagnitude(x, y, z float64) float64 {
-	return sqrt(x*x + y*y + z*z)
-}
-
-func sqrt(x float64) float64 {
-	if x == 0 {
-		return 0
+	// Space of deleted data that maintenance would reclaim
+	var freePages, pageSize int64
+	if err := mainDB.QueryRow("PRAGMA freelist_count").Scan(&freePages); err != nil {
+		return nil, err
 	}
-	z := x
-	for i := 0; i < 10; i++ {
-		z = (z + x/z) / 2
+	if err := mainDB.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return nil, err
 	}
-	return z
-}
+	stats["free_bytes"] = freePages * pageSize
+	stats["last_maintenance"] = getLastMaintenance()
 
-func abs(x float64) float64 {
-	if x < 0 {
-		return -x
-	}
-	return x
+	return stats, nil
 }
 
+
 // Marker database functions
 func getMarkersForFlight(flightID int) ([]Marker, error) {
 	query := `
-		SELECT id, flight_id, time_seconds, label, COALESCE(type, 'regular'), created_at
+		SELECT id, flight_id, time_seconds, label, COALESCE(type, 'regular'), category, color, created_at
 		FROM markers
 		WHERE flight_id = ?
 		ORDER BY time_seconds
@@ -614,10 +955,11 @@ func getMarkersForFlight(flightID int) ([]Marker, error) {
 	var markers []Marker
 	for rows.Next() {
 		var m Marker
-		err := rows.Scan(&m.ID, &m.FlightID, &m.Time, &m.Label, &m.Type, &m.CreatedAt)
+		err := rows.Scan(&m.ID, &m.FlightID, &m.Time, &m.Label, &m.Type, &m.Category, &m.Color, &m.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
+		fillMarkerCategory(&m)
 		markers = append(markers, m)
 	}
 
@@ -629,13 +971,16 @@ func createMarker(marker Marker) (*Marker, error) {
 	if marker.Type == "" {
 		marker.Type = "regular"
 	}
+	if err := normalizeMarkerCategory(&marker); err != nil {
+		return nil, err
+	}
 
 	query := `
-		INSERT INTO markers (flight_id, time_seconds, label, type)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO markers (flight_id, time_seconds, label, type, category, color)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := mainDB.Exec(query, marker.FlightID, marker.Time, marker.Label, marker.Type)
+	result, err := mainDB.Exec(query, marker.FlightID, marker.Time, marker.Label, marker.Type, marker.Category, marker.Color)
 	if err != nil {
 		return nil, err
 	}
@@ -652,69 +997,82 @@ func createMarker(marker Marker) (*Marker, error) {
 		Time:     marker.Time,
 		Label:    marker.Label,
 		Type:     marker.Type,
+		Category: marker.Category,
+		Color:    marker.Color,
 	}
 
 	return createdMarker, nil
 }
 
-func deleteMarker(markerID int) error {
-	query := `DELETE FROM markers WHERE id = ?`
-	_, err := mainDB.Exec(query, markerID)
-	return err
-}
+// deleteMarker deletes a marker belonging to the given flight, returning sql.ErrNoRows if there is none
+func deleteMarker(flightID, markerID int) error {
+	query := `DELETE FROM markers WHERE id = ? AND flight_id = ?`
+	result, err := mainDB.Exec(query, markerID, flightID)
+	if err != nil {
+		return err
+	}
 
-// Marker HTTP handlers
-func handleMarkers(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		handleGetMarkers(w, r)
-	case http.MethodPost:
-		handleCreateMarker(w, r)
-	case http.MethodDelete:
-		handleDeleteMarker(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
 	}
+	return nil
 }
 
-func handleGetMarkers(w http.ResponseWriter, r *http.Request) {
-	flightIdStr := r.URL.Query().Get("flightId")
-	if flightIdStr == "" {
-		http.Error(w, "Flight ID required", http.StatusBadRequest)
+// Marker HTTP handlers
+
+func handleGetMarkers(w http.ResponseWriter, r *http.Request, flightId int) {
+	categories, err := parseMarkerCategoryFilter(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	flightId, err := strconv.Atoi(flightIdStr)
+	alignment, err := parseAlignment(r, flightId)
 	if err != nil {
-		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
+		writeAlignmentError(w, err)
 		return
 	}
 
 	markers, err := getMarkersForFlight(flightId)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get markers: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to get markers: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if categories != nil {
+		markers = filterMarkersByCategory(markers, categories)
+	}
+	if alignment != nil {
+		alignMarkers(markers, alignment)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(markers)
 }
 
-func handleCreateMarker(w http.ResponseWriter, r *http.Request) {
+func handleCreateMarker(w http.ResponseWriter, r *http.Request, flightId int) {
 	var marker Marker
 	if err := json.NewDecoder(r.Body).Decode(&marker); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	if marker.FlightID == 0 || marker.Label == "" {
-		http.Error(w, "Flight ID and label are required", http.StatusBadRequest)
+	if marker.Label == "" {
+		httpError(w, "Label is required", http.StatusBadRequest)
 		return
 	}
+	if err := normalizeMarkerCategory(&marker); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	marker.FlightID = flightId
 
 	createdMarker, err := createMarker(marker)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create marker: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to create marker: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -722,21 +1080,18 @@ func handleCreateMarker(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(createdMarker)
 }
 
-func handleDeleteMarker(w http.ResponseWriter, r *http.Request) {
-	markerIdStr := r.URL.Query().Get("id")
-	if markerIdStr == "" {
-		http.Error(w, "Marker ID required", http.StatusBadRequest)
-		return
-	}
-
-	markerId, err := strconv.Atoi(markerIdStr)
+func handleDeleteMarker(w http.ResponseWriter, r *http.Request, flightId int) {
+	markerId, err := strconv.Atoi(r.PathValue("markerId"))
 	if err != nil {
-		http.Error(w, "Invalid marker ID", http.StatusBadRequest)
+		httpError(w, "Invalid marker ID", http.StatusBadRequest)
 		return
 	}
 
-	if err := deleteMarker(markerId); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete marker: %v", err), http.StatusInternalServerError)
+	if err := deleteMarker(flightId, markerId); err == sql.ErrNoRows {
+		httpError(w, "Marker not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, fmt.Sprintf("Failed to delete marker: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -783,16 +1138,17 @@ func createOrUpdateTrimMarker(flightID int, markerType string, time float64, lab
 // getTrimMarker retrieves a specific trim marker (trim_start or trim_end) for a flight
 func getTrimMarker(flightID int, markerType string) (*Marker, error) {
 	query := `
-		SELECT id, flight_id, time_seconds, label, type, created_at
+		SELECT id, flight_id, time_seconds, label, type, category, color, created_at
 		FROM markers
 		WHERE flight_id = ? AND type = ?
 	`
 
 	var m Marker
-	err := mainDB.QueryRow(query, flightID, markerType).Scan(&m.ID, &m.FlightID, &m.Time, &m.Label, &m.Type, &m.CreatedAt)
+	err := mainDB.QueryRow(query, flightID, markerType).Scan(&m.ID, &m.FlightID, &m.Time, &m.Label, &m.Type, &m.Category, &m.Color, &m.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
+	fillMarkerCategory(&m)
 
 	return &m, nil
 }
@@ -811,36 +1167,12 @@ func deleteTrimMarkers(flightID int) error {
 	return err
 }
 
-// HTTP handler for trim markers
-func handleTrimMarkers(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		handleGetTrimMarkers(w, r)
-	case http.MethodPost:
-		handleCreateTrimMarker(w, r)
-	case http.MethodDelete:
-		handleDeleteTrimMarkers(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-func handleGetTrimMarkers(w http.ResponseWriter, r *http.Request) {
-	flightIdStr := r.URL.Query().Get("flightId")
-	if flightIdStr == "" {
-		http.Error(w, "Flight ID required", http.StatusBadRequest)
-		return
-	}
-
-	flightId, err := strconv.Atoi(flightIdStr)
-	if err != nil {
-		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
-		return
-	}
+// HTTP handlers for trim markers
 
+func handleGetTrimMarkers(w http.ResponseWriter, r *http.Request, flightId int) {
 	trimStart, trimEnd, err := getTrimMarkers(flightId)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get trim markers: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to get trim markers: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -853,27 +1185,26 @@ func handleGetTrimMarkers(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func handleCreateTrimMarker(w http.ResponseWriter, r *http.Request) {
+func handleCreateTrimMarker(w http.ResponseWriter, r *http.Request, flightId int) {
 	var request struct {
-		FlightID int     `json:"flight_id"`
-		Type     string  `json:"type"`      // "trim_start" or "trim_end"
-		Time     float64 `json:"time"`
-		Label    string  `json:"label"`
+		Type  string  `json:"type"` // "trim_start" or "trim_end"
+		Time  float64 `json:"time"`
+		Label string  `json:"label"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	if request.FlightID == 0 || request.Type == "" {
-		http.Error(w, "Flight ID and type are required", http.StatusBadRequest)
+	if request.Type == "" {
+		httpError(w, "Type is required", http.StatusBadRequest)
 		return
 	}
 
-	marker, err := createOrUpdateTrimMarker(request.FlightID, request.Type, request.Time, request.Label)
+	marker, err := createOrUpdateTrimMarker(flightId, request.Type, request.Time, request.Label)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create trim marker: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to create trim marker: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -881,21 +1212,9 @@ func handleCreateTrimMarker(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(marker)
 }
 
-func handleDeleteTrimMarkers(w http.ResponseWriter, r *http.Request) {
-	flightIdStr := r.URL.Query().Get("flightId")
-	if flightIdStr == "" {
-		http.Error(w, "Flight ID required", http.StatusBadRequest)
-		return
-	}
-
-	flightId, err := strconv.Atoi(flightIdStr)
-	if err != nil {
-		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
-		return
-	}
-
+func handleDeleteTrimMarkers(w http.ResponseWriter, r *http.Request, flightId int) {
 	if err := deleteTrimMarkers(flightId); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete trim markers: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to delete trim markers: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -922,20 +1241,21 @@ func calculateDistanceNM(lat1, lon1, lat2, lon2 float64) float64 {
 	return R * c
 }
 
-// findDistanceMarkers analyzes position data to find the first point where aircraft reaches exactly targetDistanceNM from Currock Hill
-func findDistanceMarkers(positionData []PositionPoint) []float64 {
+// findDistanceMarkers analyzes position data to find the first point where aircraft reaches exactly the configured distance from the reference point
+func findDistanceMarkers(positionData []PositionPoint, settings DistanceMarkerSettings) []float64 {
 	var markerTimes []float64
 	var prevDistance float64
 	var prevTime float64
 	markerFound := false
-	const tolerance = 0.05 // 0.05 nm tolerance for "exactly" 9nm
+	tolerance := analysisConfig.DistanceToleranceNM // Tolerance for "exactly" the target distance
+	targetDistanceNM := settings.DistanceNM
 
 	for i, pos := range positionData {
 		if pos.Latitude == 0 && pos.Longitude == 0 {
 			continue // Skip invalid coordinates
 		}
 
-		distance := calculateDistanceNM(pos.Latitude, pos.Longitude, currockHillLat, currockHillLon)
+		distance := calculateDistanceNM(pos.Latitude, pos.Longitude, settings.Latitude, settings.Longitude)
 		
 		if i > 0 && !markerFound {
 			// Check if we crossed the target distance (from either direction)
@@ -963,62 +1283,61 @@ func findDistanceMarkers(positionData []PositionPoint) []float64 {
 	return markerTimes
 }
 
-// createDistanceMarkersForFlight automatically creates distance markers for a flight
-func createDistanceMarkersForFlight(flightID int) error {
+// createDistanceMarkersForFlight automatically creates distance markers for a flight, for the
+// distance marker reference and every distance marker waypoint. Only the target aircraft is
+// gated unless allAircraft is set.
+func createDistanceMarkersForFlight(flightID int, allAircraft bool) error {
 	// Get flight data
 	flightData, err := getFlightDataFromMainDB(flightID)
 	if err != nil {
 		return fmt.Errorf("failed to get flight data: %v", err)
 	}
+	positionData := flightData.PositionData
+	if !allAircraft {
+		if positionData, err = targetAircraftOnly(flightID, positionData); err != nil {
+			return err
+		}
+	}
+
+	targets, err := distanceMarkerTargets(GetDistanceMarkerSettings())
+	if err != nil {
+		return fmt.Errorf("failed to get distance marker waypoints: %w", err)
+	}
 
 	// Process each aircraft's position data
-	for aircraftLabel, positionData := range flightData.PositionData {
-		markerTimes := findDistanceMarkers(positionData)
-		
-		for _, markerTime := range markerTimes {
-			label := fmt.Sprintf("9nm from Currock Hill - %s", aircraftLabel)
-			
-			marker := Marker{
-				FlightID: flightID,
-				Time:     markerTime,
-				Label:    label,
-			}
-			
-			_, err := createMarker(marker)
-			if err != nil {
-				log.Printf("Failed to create distance marker: %v", err)
-				continue
+	for aircraftLabel, positionData := range positionData {
+		for _, settings := range targets {
+			markerTimes := findDistanceMarkers(positionData, settings)
+
+			for _, markerTime := range markerTimes {
+				label := settings.Label(aircraftLabel)
+
+				marker := Marker{
+					FlightID: flightID,
+					Time:     markerTime,
+					Label:    label,
+					Category: MarkerCategoryDistance,
+				}
+
+				_, err := createMarker(marker)
+				if err != nil {
+					log.Printf("Failed to create distance marker: %v", err)
+					continue
+				}
+
+				log.Printf("Created distance marker at %.2fs for flight %d: %s", markerTime, flightID, label)
 			}
-			
-			log.Printf("Created distance marker at %.2fs for flight %d: %s", markerTime, flightID, label)
 		}
 	}
 
 	return nil
 }
 
-// HTTP handler for creating distance markers
-func handleCreateDistanceMarkers(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	flightIdStr := r.URL.Query().Get("flightId")
-	if flightIdStr == "" {
-		http.Error(w, "Flight ID required", http.StatusBadRequest)
-		return
-	}
-
-	flightId, err := strconv.Atoi(flightIdStr)
+// HTTP handler for creating distance markers; aircraft=all creates them for every aircraft
+func handleCreateDistanceMarkers(w http.ResponseWriter, r *http.Request, flightId int) {
+	err := createDistanceMarkersForFlight(flightId, allAircraftRequested(r))
 	if err != nil {
-		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
-		return
-	}
-
-	err = createDistanceMarkersForFlight(flightId)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create distance markers: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to create distance markers: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -1030,45 +1349,42 @@ func handleCreateDistanceMarkers(w http.ResponseWriter, r *http.Request) {
 }
 
 // HTTP handler for duplicating flights
-func handleDuplicateFlight(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
+func handleDuplicateFlight(w http.ResponseWriter, r *http.Request, flightId int) {
 	// Parse request body
 	var request struct {
-		FlightID int    `json:"flight_id"`
 		NewTitle string `json:"new_title"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		httpError(w, "Invalid JSON request", http.StatusBadRequest)
 		return
 	}
 
-	if request.FlightID == 0 || request.NewTitle == "" {
-		http.Error(w, "Flight ID and new title are required", http.StatusBadRequest)
+	if request.NewTitle == "" {
+		httpError(w, "New title is required", http.StatusBadRequest)
 		return
 	}
 
 	// Check if title already exists
 	exists, err := flightTitleExists(request.NewTitle)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to check title uniqueness: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to check title uniqueness: %v", err), http.StatusInternalServerError)
 		return
 	}
 	if exists {
-		http.Error(w, "A flight with this title already exists", http.StatusConflict)
+		httpError(w, "A flight with this title already exists", http.StatusConflict)
 		return
 	}
 
 	// Duplicate the flight
-	newFlightID, err := duplicateFlight(request.FlightID, request.NewTitle)
+	newFlightID, err := duplicateFlight(flightId, request.NewTitle)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to duplicate flight: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to duplicate flight: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if _, err := assessFlightQuality(newFlightID); err != nil {
+		log.Printf("Failed to assess quality of flight %d: %v", newFlightID, err)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1129,6 +1445,10 @@ func duplicateFlight(originalFlightID int, newTitle string) (int, error) {
 		if err := duplicateEngineData(tx, ac.ID, newAircraftID); err != nil {
 			return 0, fmt.Errorf("failed to duplicate engine data for aircraft %d: %w", ac.ID, err)
 		}
+
+		if err := duplicateRecordedTables(tx, ac.ID, newAircraftID); err != nil {
+			return 0, fmt.Errorf("failed to duplicate recorded data for aircraft %d: %w", ac.ID, err)
+		}
 	}
 
 	// Step 4: Duplicate markers
@@ -1260,7 +1580,8 @@ func duplicateAircraftRecord(tx *sql.Tx, aircraft Aircraft, newFlightID int) (in
 func duplicatePositionData(tx *sql.Tx, originalAircraftID, newAircraftID int) error {
 	query := `
 		SELECT timestamp, latitude, longitude, altitude, indicated_altitude,
-		       calibrated_indicated_altitude, pressure_altitude, indicated_airspeed
+		       calibrated_indicated_altitude, pressure_altitude, indicated_airspeed,
+		       wind_speed, wind_direction, vertical_speed, true_airspeed, ambient_temperature
 		FROM position WHERE aircraft_id = ? ORDER BY timestamp
 	`
 
@@ -1273,8 +1594,9 @@ func duplicatePositionData(tx *sql.Tx, originalAircraftID, newAircraftID int) er
 	insertQuery := `
 		INSERT INTO position (
 			aircraft_id, timestamp, latitude, longitude, altitude,
-			indicated_altitude, calibrated_indicated_altitude, pressure_altitude, indicated_airspeed
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			indicated_altitude, calibrated_indicated_altitude, pressure_altitude, indicated_airspeed,
+			wind_speed, wind_direction, vertical_speed, true_airspeed, ambient_temperature
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	stmt, err := tx.Prepare(insertQuery)
@@ -1287,10 +1609,12 @@ func duplicatePositionData(tx *sql.Tx, originalAircraftID, newAircraftID int) er
 		var timestamp int64
 		var latitude, longitude, altitude sql.NullFloat64
 		var indicatedAltitude, calibratedIndicatedAltitude, pressureAltitude, indicatedAirspeed sql.NullFloat64
+		var windSpeed, windDirection, verticalSpeed, trueAirspeed, ambientTemperature sql.NullFloat64
 
 		err := rows.Scan(
 			&timestamp, &latitude, &longitude, &altitude,
 			&indicatedAltitude, &calibratedIndicatedAltitude, &pressureAltitude, &indicatedAirspeed,
+			&windSpeed, &windDirection, &verticalSpeed, &trueAirspeed, &ambientTemperature,
 		)
 		if err != nil {
 			return err
@@ -1299,6 +1623,7 @@ func duplicatePositionData(tx *sql.Tx, originalAircraftID, newAircraftID int) er
 		_, err = stmt.Exec(
 			newAircraftID, timestamp, latitude, longitude, altitude,
 			indicatedAltitude, calibratedIndicatedAltitude, pressureAltitude, indicatedAirspeed,
+			windSpeed, windDirection, verticalSpeed, trueAirspeed, ambientTemperature,
 		)
 		if err != nil {
 			return err
@@ -1454,7 +1779,7 @@ func duplicateEngineData(tx *sql.Tx, originalAircraftID, newAircraftID int) erro
 // duplicateMarkers copies all markers for a flight
 func duplicateMarkers(tx *sql.Tx, originalFlightID, newFlightID int) error {
 	query := `
-		SELECT time_seconds, label
+		SELECT time_seconds, label, category, color
 		FROM markers WHERE flight_id = ?
 		ORDER BY time_seconds
 	`
@@ -1466,8 +1791,8 @@ func duplicateMarkers(tx *sql.Tx, originalFlightID, newFlightID int) error {
 	defer rows.Close()
 
 	insertQuery := `
-		INSERT INTO markers (flight_id, time_seconds, label)
-		VALUES (?, ?, ?)
+		INSERT INTO markers (flight_id, time_seconds, label, category, color)
+		VALUES (?, ?, ?, ?, ?)
 	`
 
 	stmt, err := tx.Prepare(insertQuery)
@@ -1478,14 +1803,14 @@ func duplicateMarkers(tx *sql.Tx, originalFlightID, newFlightID int) error {
 
 	for rows.Next() {
 		var timeSeconds float64
-		var label string
+		var label, category, color string
 
-		err := rows.Scan(&timeSeconds, &label)
+		err := rows.Scan(&timeSeconds, &label, &category, &color)
 		if err != nil {
 			return err
 		}
 
-		_, err = stmt.Exec(newFlightID, timeSeconds, label)
+		_, err = stmt.Exec(newFlightID, timeSeconds, label, category, color)
 		if err != nil {
 			return err
 		}
@@ -1495,57 +1820,54 @@ func duplicateMarkers(tx *sql.Tx, originalFlightID, newFlightID int) error {
 }
 
 // HTTP handler for trimming flights
-func handleTrimFlight(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
+func handleTrimFlight(w http.ResponseWriter, r *http.Request, flightId int) {
 	// Parse request body
 	var request struct {
-		FlightID  int     `json:"flight_id"`
 		NewTitle  string  `json:"new_title"`
 		StartTime float64 `json:"start_time"`
 		EndTime   float64 `json:"end_time"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		httpError(w, "Invalid JSON request", http.StatusBadRequest)
 		return
 	}
 
-	if request.FlightID == 0 || request.NewTitle == "" {
-		http.Error(w, "Flight ID and new title are required", http.StatusBadRequest)
+	if request.NewTitle == "" {
+		httpError(w, "New title is required", http.StatusBadRequest)
 		return
 	}
 
 	if request.EndTime <= request.StartTime {
-		http.Error(w, "End time must be greater than start time", http.StatusBadRequest)
+		httpError(w, "End time must be greater than start time", http.StatusBadRequest)
 		return
 	}
 
-	if request.EndTime-request.StartTime < 1.0 {
-		http.Error(w, "Trim range too small (minimum 1 second)", http.StatusBadRequest)
+	if request.EndTime-request.StartTime < analysisConfig.MinTrimSeconds {
+		httpError(w, fmt.Sprintf("Trim range too small (minimum %g seconds)", analysisConfig.MinTrimSeconds), http.StatusBadRequest)
 		return
 	}
 
 	// Check if title already exists
 	exists, err := flightTitleExists(request.NewTitle)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to check title uniqueness: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to check title uniqueness: %v", err), http.StatusInternalServerError)
 		return
 	}
 	if exists {
-		http.Error(w, "A flight with this title already exists", http.StatusConflict)
+		httpError(w, "A flight with this title already exists", http.StatusConflict)
 		return
 	}
 
 	// Trim the flight
-	newFlightID, err := trimFlight(request.FlightID, request.NewTitle, request.StartTime, request.EndTime)
+	newFlightID, err := trimFlight(flightId, request.NewTitle, request.StartTime, request.EndTime)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to trim flight: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to trim flight: %v", err), http.StatusInternalServerError)
 		return
 	}
+	if _, err := assessFlightQuality(newFlightID); err != nil {
+		log.Printf("Failed to assess quality of flight %d: %v", newFlightID, err)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1595,6 +1917,10 @@ func trimFlight(originalFlightID int, newTitle string, startTime, endTime float6
 		if err := duplicateEngineDataTrimmed(tx, ac.ID, newAircraftID, startTime, endTime); err != nil {
 			return 0, fmt.Errorf("failed to duplicate engine data for aircraft %d: %w", ac.ID, err)
 		}
+
+		if err := duplicateRecordedTablesTrimmed(tx, ac.ID, newAircraftID, startTime, endTime); err != nil {
+			return 0, fmt.Errorf("failed to duplicate recorded data for aircraft %d: %w", ac.ID, err)
+		}
 	}
 
 	// Step 4: Duplicate markers within the trim range
@@ -1626,7 +1952,8 @@ func duplicatePositionDataTrimmed(tx *sql.Tx, originalAircraftID, newAircraftID
 
 	query := `
 		SELECT timestamp, latitude, longitude, altitude, indicated_altitude,
-		       calibrated_indicated_altitude, pressure_altitude, indicated_airspeed
+		       calibrated_indicated_altitude, pressure_altitude, indicated_airspeed,
+		       wind_speed, wind_direction, vertical_speed, true_airspeed, ambient_temperature
 		FROM position 
 		WHERE aircraft_id = ? AND timestamp >= ? AND timestamp <= ?
 		ORDER BY timestamp
@@ -1641,8 +1968,9 @@ func duplicatePositionDataTrimmed(tx *sql.Tx, originalAircraftID, newAircraftID
 	insertQuery := `
 		INSERT INTO position (
 			aircraft_id, timestamp, latitude, longitude, altitude,
-			indicated_altitude, calibrated_indicated_altitude, pressure_altitude, indicated_airspeed
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			indicated_altitude, calibrated_indicated_altitude, pressure_altitude, indicated_airspeed,
+			wind_speed, wind_direction, vertical_speed, true_airspeed, ambient_temperature
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	stmt, err := tx.Prepare(insertQuery)
@@ -1655,10 +1983,12 @@ func duplicatePositionDataTrimmed(tx *sql.Tx, originalAircraftID, newAircraftID
 		var timestamp int64
 		var latitude, longitude, altitude sql.NullFloat64
 		var indicatedAltitude, calibratedIndicatedAltitude, pressureAltitude, indicatedAirspeed sql.NullFloat64
+		var windSpeed, windDirection, verticalSpeed, trueAirspeed, ambientTemperature sql.NullFloat64
 
 		err := rows.Scan(
 			&timestamp, &latitude, &longitude, &altitude,
 			&indicatedAltitude, &calibratedIndicatedAltitude, &pressureAltitude, &indicatedAirspeed,
+			&windSpeed, &windDirection, &verticalSpeed, &trueAirspeed, &ambientTemperature,
 		)
 		if err != nil {
 			return err
@@ -1670,6 +2000,7 @@ func duplicatePositionDataTrimmed(tx *sql.Tx, originalAircraftID, newAircraftID
 		_, err = stmt.Exec(
 			newAircraftID, adjustedTimestamp, latitude, longitude, altitude,
 			indicatedAltitude, calibratedIndicatedAltitude, pressureAltitude, indicatedAirspeed,
+			windSpeed, windDirection, verticalSpeed, trueAirspeed, ambientTemperature,
 		)
 		if err != nil {
 			return err
@@ -1750,15 +2081,19 @@ func duplicateAttitudeDataTrimmed(tx *sql.Tx, originalAircraftID, newAircraftID
 // duplicateEngineDataTrimmed copies engine data within a specific time range, adjusting timestamps to start from 0
 func duplicateEngineDataTrimmed(tx *sql.Tx, originalAircraftID, newAircraftID int, startTime, endTime float64) error {
 	// Calculate the minimum timestamp to normalize timestamps to start from 0
-	var minTimestamp int64
+	var minTimestamp sql.NullInt64
 	err := tx.QueryRow("SELECT MIN(timestamp) FROM engine WHERE aircraft_id = ?", originalAircraftID).Scan(&minTimestamp)
 	if err != nil && err != sql.ErrNoRows {
 		return err
 	}
+	// CSV recordings without engine columns have no engine samples
+	if !minTimestamp.Valid {
+		return nil
+	}
 
 	// Convert time range to milliseconds and add to base timestamp
-	startTimestamp := minTimestamp + int64(startTime*1000)
-	endTimestamp := minTimestamp + int64(endTime*1000)
+	startTimestamp := minTimestamp.Int64 + int64(startTime*1000)
+	endTimestamp := minTimestamp.Int64 + int64(endTime*1000)
 
 	query := `
 		SELECT timestamp, throttle_lever_position1, throttle_lever_position2,
@@ -1835,7 +2170,7 @@ func duplicateEngineDataTrimmed(tx *sql.Tx, originalAircraftID, newAircraftID in
 		}
 
 		// Adjust timestamp to start from the new base
-		adjustedTimestamp := minTimestamp + (timestamp - startTimestamp)
+		adjustedTimestamp := minTimestamp.Int64 + (timestamp - startTimestamp)
 
 		_, err = stmt.Exec(
 			newAircraftID, adjustedTimestamp, throttle1, throttle2, throttle3, throttle4,
@@ -1857,7 +2192,7 @@ func duplicateEngineDataTrimmed(tx *sql.Tx, originalAircraftID, newAircraftID in
 // duplicateMarkersTrimmed copies markers within a specific time range, adjusting time to start from 0
 func duplicateMarkersTrimmed(tx *sql.Tx, originalFlightID, newFlightID int, startTime, endTime float64) error {
 	query := `
-		SELECT time_seconds, label, COALESCE(type, 'regular')
+		SELECT time_seconds, label, COALESCE(type, 'regular'), category, color
 		FROM markers 
 		WHERE flight_id = ? AND time_seconds >= ? AND time_seconds <= ?
 		ORDER BY time_seconds
@@ -1870,8 +2205,8 @@ func duplicateMarkersTrimmed(tx *sql.Tx, originalFlightID, newFlightID int, star
 	defer rows.Close()
 
 	insertQuery := `
-		INSERT INTO markers (flight_id, time_seconds, label, type)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO markers (flight_id, time_seconds, label, type, category, color)
+		VALUES (?, ?, ?, ?, ?, ?)
 	`
 
 	stmt, err := tx.Prepare(insertQuery)
@@ -1882,9 +2217,9 @@ func duplicateMarkersTrimmed(tx *sql.Tx, originalFlightID, newFlightID int, star
 
 	for rows.Next() {
 		var timeSeconds float64
-		var label, markerType string
+		var label, markerType, category, color string
 
-		err := rows.Scan(&timeSeconds, &label, &markerType)
+		err := rows.Scan(&timeSeconds, &label, &markerType, &category, &color)
 		if err != nil {
 			return err
 		}
@@ -1892,7 +2227,7 @@ func duplicateMarkersTrimmed(tx *sql.Tx, originalFlightID, newFlightID int, star
 		// Adjust time to start from 0
 		adjustedTime := timeSeconds - startTime
 
-		_, err = stmt.Exec(newFlightID, adjustedTime, label, markerType)
+		_, err = stmt.Exec(newFlightID, adjustedTime, label, markerType, category, color)
 		if err != nil {
 			return err
 		}
@@ -1902,50 +2237,48 @@ func duplicateMarkersTrimmed(tx *sql.Tx, originalFlightID, newFlightID int, star
 }
 
 // handleGetStatistics handles requests for flight data statistics
-func handleGetStatistics(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+func handleGetStatistics(w http.ResponseWriter, r *http.Request, flightId int) {
+	// Get cached statistics, calculating them if they have not been precomputed yet
+	statistics, err := getFlightStatistics(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight statistics: %v", err), http.StatusInternalServerError)
 		return
 	}
-
-	flightIdStr := r.URL.Query().Get("flightId")
-	if flightIdStr == "" {
-		http.Error(w, "Flight ID required", http.StatusBadRequest)
-		return
+	// Only the target aircraft is reported unless aircraft=all
+	if !allAircraftRequested(r) {
+		if statistics, err = targetAircraftOnly(flightId, statistics); err != nil {
+			httpError(w, fmt.Sprintf("Failed to get flight statistics: %v", err), http.StatusInternalServerError)
+			return
+		}
 	}
-
-	flightId, err := strconv.Atoi(flightIdStr)
-	if err != nil {
-		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
+	// The glidepath deviation depends on the runway library, so it is computed for each response
+	rw, ok := statisticsRunway(w, r)
+	if !ok {
 		return
 	}
-
-	// Get flight data
-	flightData, err := getFlightDataFromMainDB(flightId)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
-		return
+	if rw != nil {
+		if err := addGlidepathDeviation(flightId, statistics, rw); err != nil {
+			httpError(w, fmt.Sprintf("Failed to get glidepath deviation: %v", err), http.StatusInternalServerError)
+			return
+		}
 	}
 
-	// Calculate statistics
-	statistics := CalculateFlightStatistics(flightData)
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(statistics)
+	json.NewEncoder(w).Encode(configuredUnits().convertStatistics(statistics))
 }
 
-// importCSVFile imports flight data from a CSV file
-func importCSVFile(filePath, filename string) (*Flight, error) {
+// importCSVFile imports flight data from a CSV file, also returning the parsed data with its warnings
+func importCSVFile(ctx context.Context, filePath, filename string) (*Flight, *CSVFlightData, error) {
 	// Open the CSV file
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+		return nil, nil, fmt.Errorf("failed to open CSV file: %w", err)
 	}
 	defer file.Close()
 
 	// Validate CSV structure first
 	if err := ValidateCSVStructure(file); err != nil {
-		return nil, fmt.Errorf("invalid CSV structure: %w", err)
+		return nil, nil, fmt.Errorf("invalid CSV structure: %w", err)
 	}
 
 	// Reset file pointer
@@ -1958,18 +2291,14 @@ func importCSVFile(filePath, filename string) (*Flight, error) {
 		SkipRows:     2, // Skip separator and comment rows
 	}
 
-	csvData, err := ParseCSVFlightData(file, options)
+	// Parse the records and insert them into the database as they are read
+	flight, csvData, err := ImportFlightFromCSV(ctx, file, options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse CSV data: %w", err)
+		return nil, nil, err
 	}
+	createCSVWarningMarkers(flight.ID, csvData)
 
-	// Import into database
-	flight, err := ImportFlightFromCSV(csvData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to import CSV to database: %w", err)
-	}
-
-	return flight, nil
+	return flight, csvData, nil
 }
 
 // extractFlightTitle extracts a meaningful flight title from filename
@@ -1993,41 +2322,26 @@ func extractFlightTitle(filename string) string {
 	return name
 }
 
-// handleDeleteFlight handles flight deletion requests
-func handleDeleteFlight(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	flightIdStr := r.URL.Query().Get("id")
-	if flightIdStr == "" {
-		http.Error(w, "Flight ID required", http.StatusBadRequest)
-		return
-	}
-
-	flightId, err := strconv.Atoi(flightIdStr)
+// handleDeleteFlight archives a flight; it is hidden from the flight list until restored or purged
+func handleDeleteFlight(w http.ResponseWriter, r *http.Request, flightId int) {
+	flight, err := getFlightByIDFromMainDB(flightId)
 	if err != nil {
-		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
+		httpError(w, fmt.Sprintf("Failed to get flight: %v", err), http.StatusInternalServerError)
 		return
 	}
-
-	// Get flight title for logging before deletion
-	flight, err := getFlightByIDFromMainDB(flightId)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Flight not found: %v", err), http.StatusNotFound)
+	if flight.ArchivedAt != "" {
+		httpError(w, fmt.Sprintf("Flight %d is already archived (purge it to remove it permanently)", flightId), http.StatusConflict)
 		return
 	}
 
-	// Delete the flight
-	if err := DeleteFlight(flightId); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete flight: %v", err), http.StatusInternalServerError)
+	if err := archiveFlight(flightId); err != nil {
+		httpError(w, fmt.Sprintf("Failed to archive flight: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":  "success",
-		"message": fmt.Sprintf("Flight '%s' (ID: %d) deleted successfully", flight.Title, flightId),
+		"message": fmt.Sprintf("Flight '%s' (ID: %d) archived, restore it from the archived flights", flight.Title, flightId),
 	})
 }
@@ -1,23 +1,45 @@
 package data_analysis
 
 import (
+	"context"
 	"database/sql"
+	_ "embed"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"path/filepath"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 const (
 	mainDatabasePath = "data/data_analysis.db"
+	// mainDatabaseBusyTimeoutMs is how long a statement waits for the write lock held by another
+	// connection, e.g. a large import, before failing with "database is locked"
+	mainDatabaseBusyTimeoutMs = 30000
+	// mainDatabaseMaxOpenConns limits the connections to the main database. With the WAL journal they
+	// read concurrently while one of them writes.
+	mainDatabaseMaxOpenConns = 8
 )
 
+// mainDatabaseDSN opens the main database with a write-ahead log, so imports do not block reads.
+// Transactions take the write lock when they begin, as a read transaction that later writes cannot wait
+// for a concurrent writer and would fail at once.
+var mainDatabaseDSN = fmt.Sprintf("file:%s?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=%d&_txlock=immediate",
+	mainDatabasePath, mainDatabaseBusyTimeoutMs)
+
 var (
 	mainDB *sql.DB
 )
 
+// schemaSQL is the Sky Dolly schema the main database is created with, embedded so a deployment needs
+// only the binary
+//
+//go:embed structure.sql
+var schemaSQL string
+
 // InitMainDatabase initializes the main data analysis database
 func InitMainDatabase() error {
 	// Ensure data directory exists
@@ -26,16 +48,27 @@ func InitMainDatabase() error {
 	}
 
 	var err error
-	mainDB, err = sql.Open("sqlite3", mainDatabasePath)
+	mainDB, err = sql.Open("sqlite3", mainDatabaseDSN)
 	if err != nil {
 		return fmt.Errorf("failed to open main database: %w", err)
 	}
+	mainDB.SetMaxOpenConns(mainDatabaseMaxOpenConns)
+	mainDB.SetMaxIdleConns(mainDatabaseMaxOpenConns)
 
 	// Test connection
 	if err := mainDB.Ping(); err != nil {
 		return fmt.Errorf("failed to ping main database: %w", err)
 	}
 
+	// Some file systems, e.g. network shares, cannot hold a write-ahead log
+	var journalMode string
+	if err := mainDB.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		return fmt.Errorf("failed to read journal mode of main database: %w", err)
+	}
+	if !strings.EqualFold(journalMode, "wal") {
+		log.Printf("Main database uses journal mode %s instead of WAL; imports will block reads", journalMode)
+	}
+
 	// Create schema if it doesn't exist
 	if err := createMainDatabaseSchema(); err != nil {
 		return fmt.Errorf("failed to create main database schema: %w", err)
@@ -53,23 +86,13 @@ func createMainDatabaseSchema() error {
 	if err == nil && count > 0 {
 		// Database already initialized, but check if markers table exists
 		log.Println("Main database schema already exists, checking for markers table...")
-		if err := ensureMarkersTable(); err != nil {
-			return err
-		}
-		return ensurePositionTableColumns()
+		return ensureSchemaExtensions()
 	}
 
 	log.Println("Initializing main database schema...")
 
-	// Read the schema from structure.sql
-	schemaPath := filepath.Join("data", "structure.sql")
-	schemaBytes, err := os.ReadFile(schemaPath)
-	if err != nil {
-		return fmt.Errorf("failed to read schema file: %w", err)
-	}
-
-	// Execute the schema
-	_, err = mainDB.Exec(string(schemaBytes))
+	// Execute the schema embedded from structure.sql
+	_, err = mainDB.Exec(schemaSQL)
 	if err != nil {
 		// If there's an error, it might be because tables already exist
 		// Let's check if the essential tables exist
@@ -81,22 +104,85 @@ func createMainDatabaseSchema() error {
 		if flightCount > 0 && aircraftCount > 0 && positionCount > 0 {
 			// Essential tables exist, schema is probably fine
 			log.Println("Essential database tables already exist, continuing...")
-			// Still need to ensure markers table exists
-			if err := ensureMarkersTable(); err != nil {
-				return err
-			}
-			return ensurePositionTableColumns()
+			// Still need to ensure the tables and columns not in structure.sql exist
+			return ensureSchemaExtensions()
 		}
 
 		return fmt.Errorf("failed to execute schema: %w", err)
 	}
 
 	log.Println("Main database schema created successfully")
-	// Create markers table
+	// Add the tables and columns not in structure.sql
+	return ensureSchemaExtensions()
+}
+
+// ensureSchemaExtensions creates the tables and columns that are not part of the
+// Sky Dolly schema in structure.sql
+func ensureSchemaExtensions() error {
 	if err := ensureMarkersTable(); err != nil {
 		return err
 	}
-	return ensurePositionTableColumns()
+	if err := ensureMarkerCategoryColumns(); err != nil {
+		return err
+	}
+	if err := ensurePositionTableColumns(); err != nil {
+		return err
+	}
+	if err := ensurePositionWindColumns(); err != nil {
+		return err
+	}
+	if err := ensurePositionVerticalSpeedColumn(); err != nil {
+		return err
+	}
+	if err := ensurePositionAirDataColumns(); err != nil {
+		return err
+	}
+	if err := ensurePositionAltitudeInFeet(); err != nil {
+		return err
+	}
+	if err := ensureFlightMetricsTable(); err != nil {
+		return err
+	}
+	if err := ensurePositionProvenanceTable(); err != nil {
+		return err
+	}
+	if err := ensureParticipantTable(); err != nil {
+		return err
+	}
+	if err := ensureFlightParticipantColumns(); err != nil {
+		return err
+	}
+	if err := ensureFlightConditionsTable(); err != nil {
+		return err
+	}
+	if err := ensureFlightReviewTable(); err != nil {
+		return err
+	}
+	if err := ensureReferencePointsTable(); err != nil {
+		return err
+	}
+	if err := ensureDistanceMarkerWaypointsTable(); err != nil {
+		return err
+	}
+	if err := ensureAircraftStateTable(); err != nil {
+		return err
+	}
+	if err := ensureFlightSourceHashColumn(); err != nil {
+		return err
+	}
+	if err := ensureFlightArchivedColumn(); err != nil {
+		return err
+	}
+	if err := ensureReferenceProfilesTable(); err != nil {
+		return err
+	}
+	if err := ensureRunwaysTable(); err != nil {
+		return err
+	}
+	if err := ensureRunwayGlidepathColumn(); err != nil {
+		return err
+	}
+	return ensureCSVAttitudeVelocities()
 }
 
 // ensureMarkersTable creates the markers table if it doesn't exist
@@ -120,6 +206,8 @@ func ensureMarkersTable() error {
 			time_seconds REAL NOT NULL,
 			label TEXT NOT NULL,
 			type TEXT NOT NULL DEFAULT 'regular',
+			category TEXT NOT NULL DEFAULT '',
+			color TEXT NOT NULL DEFAULT '',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY(flight_id) REFERENCES flight(id) ON DELETE CASCADE
 		);
@@ -232,6 +320,26 @@ func ensurePositionTableColumns() error {
 	return nil
 }
 
+// ensurePositionProvenanceTable creates the table recording the source sample rate of imported positions
+func ensurePositionProvenanceTable() error {
+	provenanceSchema := `
+		CREATE TABLE IF NOT EXISTS position_provenance (
+			aircraft_id INTEGER PRIMARY KEY,
+			source_sample_count INTEGER NOT NULL,
+			source_sample_rate_hz REAL NOT NULL,
+			imported_sample_count INTEGER NOT NULL,
+			thin_every_n INTEGER NOT NULL DEFAULT 0,
+			thin_min_delta_ms INTEGER NOT NULL DEFAULT 0,
+			FOREIGN KEY(aircraft_id) REFERENCES aircraft(id) ON DELETE CASCADE
+		);
+	`
+
+	if _, err := mainDB.Exec(provenanceSchema); err != nil {
+		return fmt.Errorf("failed to create position_provenance table: %w", err)
+	}
+	return nil
+}
+
 // GetMainDatabase returns the main database connection
 func GetMainDatabase() *sql.DB {
 	return mainDB
@@ -247,6 +355,21 @@ func CloseMainDatabase() error {
 
 // ImportFlightsFromDatabase imports all flights and related data from an uploaded database
 func ImportFlightsFromDatabase(sourceDBPath string) ([]Flight, error) {
+	report, err := ImportFlightsFromDatabaseWithOptions(context.Background(), sourceDBPath, ImportOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return report.Flights, nil
+}
+
+// ImportFlightsFromDatabaseWithOptions imports all flights from an uploaded database.
+// In partial mode, failures of individual aircraft or tables are recorded in the
+// returned report instead of rolling back the whole import. Cancelling ctx rolls the import back.
+func ImportFlightsFromDatabaseWithOptions(ctx context.Context, sourceDBPath string, options ImportOptions) (*ImportReport, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
 	// Open the source database
 	sourceDB, err := sql.Open("sqlite3", sourceDBPath)
 	if err != nil {
@@ -255,42 +378,91 @@ func ImportFlightsFromDatabase(sourceDBPath string) ([]Flight, error) {
 	defer sourceDB.Close()
 
 	// Verify source database has required tables
-	if err := verifyDatabaseSchema(sourceDB); err != nil {
+	if err := verifyDatabaseSchema(sourceDB, options); err != nil {
 		return nil, fmt.Errorf("invalid source database: %w", err)
 	}
 
-	// Start transaction
-	tx, err := mainDB.Begin()
+	// Start transaction; it is rolled back as soon as ctx is cancelled
+	tx, err := mainDB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Import flights
-	flights, err := importFlights(sourceDB, tx)
+	report := &ImportReport{Errors: []ImportError{}}
+
+	// Import flights, skipping those imported before unless forced
+	flights, err := importFlights(sourceDB, tx, options, report)
 	if err != nil {
 		return nil, fmt.Errorf("failed to import flights: %w", err)
 	}
+	report.Flights = flights
 
 	// Import aircraft for each flight
 	for _, flight := range flights {
-		if err := importAircraftForFlight(sourceDB, tx, flight.SourceID, flight.ID); err != nil {
+		if err := importAircraftForFlight(sourceDB, tx, flight.SourceID, flight.ID, options, report); err != nil {
+			// Statements fail once the transaction was rolled back by a cancellation
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			return nil, fmt.Errorf("failed to import aircraft for flight %d: %w", flight.SourceID, err)
 		}
 	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	log.Printf("Successfully imported %d flights from %s", len(flights), sourceDBPath)
-	return flights, nil
+	if len(report.Errors) > 0 {
+		log.Printf("Imported %d flights from %s with %d errors", len(flights), sourceDBPath, len(report.Errors))
+	} else {
+		log.Printf("Successfully imported %d flights from %s", len(flights), sourceDBPath)
+	}
+	return report, nil
 }
 
-// verifyDatabaseSchema verifies that the source database has the required schema
-func verifyDatabaseSchema(db *sql.DB) error {
-	requiredTables := []string{"flight", "aircraft", "position", "attitude", "engine"}
+// importStep runs one step of an import. In partial mode the step runs inside a
+// savepoint, and a failure only rolls back that step and is recorded in the report.
+func importStep(tx *sql.Tx, options ImportOptions, report *ImportReport, importErr ImportError, step func() error) error {
+	if !options.Partial {
+		return step()
+	}
+
+	if _, err := tx.Exec("SAVEPOINT import_step"); err != nil {
+		return err
+	}
+
+	if err := step(); err != nil {
+		if _, rbErr := tx.Exec("ROLLBACK TO import_step"); rbErr != nil {
+			return fmt.Errorf("%v (rollback failed: %v)", err, rbErr)
+		}
+		if _, relErr := tx.Exec("RELEASE import_step"); relErr != nil {
+			return relErr
+		}
+
+		importErr.Error = err.Error()
+		report.Errors = append(report.Errors, importErr)
+		log.Printf("Skipped %s data for source flight %d, aircraft %d: %v",
+			importErr.Table, importErr.SourceFlightID, importErr.SourceAircraftID, err)
+		return nil
+	}
+
+	_, err := tx.Exec("RELEASE import_step")
+	return err
+}
+
+// verifyDatabaseSchema verifies that the source database has the tables required by the import
+func verifyDatabaseSchema(db *sql.DB, options ImportOptions) error {
+	requiredTables := []string{"flight", "aircraft", "position"}
+	for _, table := range optionalImportTables {
+		if options.importsTable(table) {
+			requiredTables = append(requiredTables, table)
+		}
+	}
 
 	for _, table := range requiredTables {
 		var tableName string
@@ -303,8 +475,9 @@ func verifyDatabaseSchema(db *sql.DB) error {
 	return nil
 }
 
-// importFlights imports flight records from source database to main database
-func importFlights(sourceDB *sql.DB, tx *sql.Tx) ([]Flight, error) {
+// importFlights imports flight records from source database to main database. Flights imported before
+// are added to the report's duplicates instead, unless the import is forced.
+func importFlights(sourceDB *sql.DB, tx *sql.Tx, options ImportOptions, report *ImportReport) ([]Flight, error) {
 	query := `
 		SELECT id, title, flight_number, start_zulu_sim_time, end_zulu_sim_time,
 		       description, user_aircraft_seq_nr, surface_type, surface_condition,
@@ -356,6 +529,24 @@ func importFlights(sourceDB *sql.DB, tx *sql.Tx) ([]Flight, error) {
 			return nil, err
 		}
 
+		if !options.Force {
+			existingID, err := findDuplicateFlight(tx, title, startZulu, endZulu)
+			if err != nil {
+				return nil, err
+			}
+			if existingID != 0 {
+				report.Duplicates = append(report.Duplicates, DuplicateFlight{
+					SourceFlightID:   sourceID,
+					Title:            title.String,
+					StartTime:        startZulu,
+					EndTime:          endZulu,
+					ExistingFlightID: existingID,
+				})
+				log.Printf("Skipping source flight %d, already imported as flight %d", sourceID, existingID)
+				continue
+			}
+		}
+
 		result, err := tx.Exec(insertQuery,
 			title, flightNumber, startZulu, endZulu,
 			description, userAircraftSeqNr, surfaceType, surfaceCondition,
@@ -396,7 +587,7 @@ func importFlights(sourceDB *sql.DB, tx *sql.Tx) ([]Flight, error) {
 }
 
 // importAircraftForFlight imports aircraft and all related data for a specific flight
-func importAircraftForFlight(sourceDB *sql.DB, tx *sql.Tx, sourceFlightID, newFlightID int) error {
+func importAircraftForFlight(sourceDB *sql.DB, tx *sql.Tx, sourceFlightID, newFlightID int, options ImportOptions, report *ImportReport) error {
 	// Get aircraft for this flight
 	aircraftQuery := `
 		SELECT id, seq_nr, type, time_offset, tail_number, airline,
@@ -435,40 +626,83 @@ func importAircraftForFlight(sourceDB *sql.DB, tx *sql.Tx, sourceFlightID, newFl
 			return err
 		}
 
-		result, err := tx.Exec(insertAircraftQuery,
-			newFlightID, seqNr, aircraftType, timeOffset,
-			tailNumber, airline, initialAirspeed, altitudeAboveGround, startOnGround,
-		)
-		if err != nil {
-			return err
+		srcAircraftID := int(sourceAircraftID.Int64)
+		stepError := func(table string) ImportError {
+			return ImportError{SourceFlightID: sourceFlightID, SourceAircraftID: srcAircraftID, Table: table}
 		}
 
-		newAircraftID, err := result.LastInsertId()
+		var newAircraftID int64
+		aircraftImported := false
+		err = importStep(tx, options, report, stepError("aircraft"), func() error {
+			result, err := tx.Exec(insertAircraftQuery,
+				newFlightID, seqNr, aircraftType, timeOffset,
+				tailNumber, airline, initialAirspeed, altitudeAboveGround, startOnGround,
+			)
+			if err != nil {
+				return err
+			}
+
+			newAircraftID, err = result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			aircraftImported = true
+			return nil
+		})
 		if err != nil {
 			return err
 		}
+		if !aircraftImported {
+			continue
+		}
 
 		// Import position data
-		if err := importPositionData(sourceDB, tx, int(sourceAircraftID.Int64), int(newAircraftID)); err != nil {
+		err = importStep(tx, options, report, stepError("position"), func() error {
+			return importPositionData(sourceDB, tx, srcAircraftID, int(newAircraftID), options)
+		})
+		if err != nil {
 			return fmt.Errorf("failed to import position data: %w", err)
 		}
 
 		// Import attitude data
-		if err := importAttitudeData(sourceDB, tx, int(sourceAircraftID.Int64), int(newAircraftID)); err != nil {
-			return fmt.Errorf("failed to import attitude data: %w", err)
+		if options.importsTable("attitude") {
+			err = importStep(tx, options, report, stepError("attitude"), func() error {
+				return importAttitudeData(sourceDB, tx, srcAircraftID, int(newAircraftID))
+			})
+			if err != nil {
+				return fmt.Errorf("failed to import attitude data: %w", err)
+			}
 		}
 
 		// Import engine data
-		if err := importEngineData(sourceDB, tx, int(sourceAircraftID.Int64), int(newAircraftID)); err != nil {
-			return fmt.Errorf("failed to import engine data: %w", err)
+		if options.importsTable("engine") {
+			err = importStep(tx, options, report, stepError("engine"), func() error {
+				return importEngineData(sourceDB, tx, srcAircraftID, int(newAircraftID))
+			})
+			if err != nil {
+				return fmt.Errorf("failed to import engine data: %w", err)
+			}
+		}
+
+		// Import handle, light, control surface and waypoint data as recorded
+		for _, table := range recordedAircraftTables {
+			if !options.importsTable(table) {
+				continue
+			}
+			err = importStep(tx, options, report, stepError(table), func() error {
+				return importRecordedTable(sourceDB, tx, table, srcAircraftID, int(newAircraftID))
+			})
+			if err != nil {
+				return fmt.Errorf("failed to import %s data: %w", table, err)
+			}
 		}
 	}
 
 	return nil
 }
 
-// importPositionData imports position data for an aircraft
-func importPositionData(sourceDB *sql.DB, tx *sql.Tx, sourceAircraftID, newAircraftID int) error {
+// importPositionData imports position data for an aircraft, thinning the samples if requested
+func importPositionData(sourceDB *sql.DB, tx *sql.Tx, sourceAircraftID, newAircraftID int, options ImportOptions) error {
 	query := `
 		SELECT timestamp, latitude, longitude, altitude, indicated_altitude,
 		       calibrated_indicated_altitude, pressure_altitude
@@ -496,6 +730,13 @@ func importPositionData(sourceDB *sql.DB, tx *sql.Tx, sourceAircraftID, newAircr
 	}
 	defer stmt.Close()
 
+	provenance := PositionProvenance{
+		AircraftID:     newAircraftID,
+		ThinEveryN:     options.ThinEveryN,
+		ThinMinDeltaMs: options.ThinMinDeltaMs,
+	}
+	var firstTimestamp, lastTimestamp, lastKeptTimestamp int64
+
 	for rows.Next() {
 		var timestamp int64
 		var latitude, longitude, altitude sql.NullFloat64
@@ -509,6 +750,25 @@ func importPositionData(sourceDB *sql.DB, tx *sql.Tx, sourceAircraftID, newAircr
 			return err
 		}
 
+		index := provenance.SourceSampleCount
+		if index == 0 {
+			firstTimestamp = timestamp
+		}
+		lastTimestamp = timestamp
+		provenance.SourceSampleCount++
+
+		// The first sample is always kept so the track starts where the recording does
+		if index > 0 {
+			if options.ThinEveryN > 1 && index%options.ThinEveryN != 0 {
+				continue
+			}
+			if options.ThinMinDeltaMs > 0 && timestamp-lastKeptTimestamp < options.ThinMinDeltaMs {
+				continue
+			}
+		}
+		lastKeptTimestamp = timestamp
+		provenance.ImportedSampleCount++
+
 		_, err = stmt.Exec(
 			newAircraftID, timestamp, latitude, longitude, altitude,
 			indicatedAltitude, calibratedIndicatedAltitude, pressureAltitude,
@@ -517,7 +777,39 @@ func importPositionData(sourceDB *sql.DB, tx *sql.Tx, sourceAircraftID, newAircr
 			return err
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if provenance.SourceSampleCount > 1 && lastTimestamp > firstTimestamp {
+		durationSeconds := float64(lastTimestamp-firstTimestamp) / 1000.0
+		provenance.SourceSampleRateHz = float64(provenance.SourceSampleCount-1) / durationSeconds
+	}
 
+	if options.thinsPositions() {
+		log.Printf("Thinned position data of aircraft %d from %d to %d samples (source rate %.1f Hz)",
+			newAircraftID, provenance.SourceSampleCount, provenance.ImportedSampleCount, provenance.SourceSampleRateHz)
+	}
+
+	return savePositionProvenance(tx, provenance)
+}
+
+// savePositionProvenance records how the stored position samples relate to the source recording
+func savePositionProvenance(tx *sql.Tx, provenance PositionProvenance) error {
+	query := `
+		INSERT OR REPLACE INTO position_provenance (
+			aircraft_id, source_sample_count, source_sample_rate_hz,
+			imported_sample_count, thin_every_n, thin_min_delta_ms
+		) VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := tx.Exec(query,
+		provenance.AircraftID, provenance.SourceSampleCount, provenance.SourceSampleRateHz,
+		provenance.ImportedSampleCount, provenance.ThinEveryN, provenance.ThinMinDeltaMs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save position provenance: %w", err)
+	}
 	return nil
 }
 
@@ -668,49 +960,74 @@ func importEngineData(sourceDB *sql.DB, tx *sql.Tx, sourceAircraftID, newAircraf
 	return nil
 }
 
-// ImportFlightFromCSV imports flight data from parsed CSV data
-func ImportFlightFromCSV(csvData *CSVFlightData) (*Flight, error) {
-	if len(csvData.Records) == 0 {
-		return nil, fmt.Errorf("no flight data records to import")
+// csvImportBatchSize is how many records of a CSV recording are parsed before they are inserted
+const csvImportBatchSize = 1000
+
+// ImportFlightFromCSV streams a CSV recording into the database, parsing and inserting its records in
+// batches so large files never have to fit into memory. It returns the flight and the parsed data
+// without its records. Cancelling ctx rolls the import back.
+func ImportFlightFromCSV(ctx context.Context, reader io.Reader, options CSVImportOptions) (*Flight, *CSVFlightData, error) {
+	stream, err := newCSVRecordStream(reader, options)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CSV data: %w", err)
 	}
 
-	// Start transaction
-	tx, err := mainDB.Begin()
+	// Start transaction; it is rolled back as soon as ctx is cancelled
+	tx, err := mainDB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Create flight record
-	flightID, err := createFlightFromCSV(tx, csvData)
+	// Create flight record; its times are known once all records were read
+	flightID, err := createFlightFromCSV(tx, stream.metadata)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create flight: %w", err)
+		return nil, nil, fmt.Errorf("failed to create flight: %w", err)
 	}
 
 	// Create aircraft record
-	aircraftID, err := createAircraftFromCSV(tx, flightID, csvData)
+	aircraftID, err := createAircraftFromCSV(tx, flightID, stream.data())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create aircraft: %w", err)
+		return nil, nil, fmt.Errorf("failed to create aircraft: %w", err)
 	}
 
-	// Import position data
-	if err := importPositionDataFromCSV(tx, aircraftID, csvData); err != nil {
-		return nil, fmt.Errorf("failed to import position data: %w", err)
+	batch := make([]CSVFlightRecord, 0, csvImportBatchSize)
+	for {
+		record, err := stream.next()
+		if err != nil && err != io.EOF {
+			return nil, nil, fmt.Errorf("failed to parse CSV data: %w", err)
+		}
+		if record != nil {
+			batch = append(batch, *record)
+		}
+		if len(batch) == csvImportBatchSize || (err == io.EOF && len(batch) > 0) {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			// Record times are stored from the start time, known since the first record
+			csvData := stream.data()
+			csvData.Records = batch
+			if err := importCSVRecords(tx, aircraftID, csvData); err != nil {
+				return nil, nil, err
+			}
+			batch = batch[:0]
+		}
+		if err == io.EOF {
+			break
+		}
 	}
 
-	// Import attitude data
-	if err := importAttitudeDataFromCSV(tx, aircraftID, csvData); err != nil {
-		return nil, fmt.Errorf("failed to import attitude data: %w", err)
+	csvData, err := stream.finish()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CSV data: %w", err)
 	}
-
-	// Import engine data (limited data available from CSV)
-	if err := importEngineDataFromCSV(tx, aircraftID, csvData); err != nil {
-		return nil, fmt.Errorf("failed to import engine data: %w", err)
+	if err := completeFlightFromCSV(tx, flightID, csvData); err != nil {
+		return nil, nil, fmt.Errorf("failed to update flight: %w", err)
 	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	// Return the created flight
@@ -718,39 +1035,63 @@ func ImportFlightFromCSV(csvData *CSVFlightData) (*Flight, error) {
 		ID:          flightID,
 		Title:       csvData.Metadata.FlightTitle,
 		FlightNumber: "CSV Import",
-		StartTime:   csvData.Metadata.RecordedAt,
-		EndTime:     csvData.Metadata.RecordedAt,
+		StartTime:   formatZuluTime(csvData.Metadata.StartTime),
+		EndTime:     formatZuluTime(csvData.Metadata.EndTime),
 	}
 
-	log.Printf("Successfully imported CSV flight: %s (%d records)", flight.Title, len(csvData.Records))
-	return flight, nil
+	log.Printf("Successfully imported CSV flight: %s (%d records)", flight.Title, csvData.Metadata.TotalRecords)
+	return flight, csvData, nil
 }
 
-// createFlightFromCSV creates a flight record from CSV metadata
-func createFlightFromCSV(tx *sql.Tx, csvData *CSVFlightData) (int, error) {
-	// Create flight times from first and last records
-	var startTime, endTime string
-	if len(csvData.Records) > 0 {
-		startTime = csvData.Records[0].Time
-		endTime = csvData.Records[len(csvData.Records)-1].Time
+// importCSVRecords inserts a batch of CSV records into the tables of an aircraft
+func importCSVRecords(tx *sql.Tx, aircraftID int, csvData *CSVFlightData) error {
+	// Import position data
+	if err := importPositionDataFromCSV(tx, aircraftID, csvData); err != nil {
+		return fmt.Errorf("failed to import position data: %w", err)
 	}
 
+	// Import attitude data
+	if err := importAttitudeDataFromCSV(tx, aircraftID, csvData); err != nil {
+		return fmt.Errorf("failed to import attitude data: %w", err)
+	}
+
+	// Import engine data, if the CSV has any
+	if err := importEngineDataFromCSV(tx, aircraftID, csvData); err != nil {
+		return fmt.Errorf("failed to import engine data: %w", err)
+	}
+
+	// Import flaps, gear, fuel, g-force and warnings, if the CSV has any
+	if err := importAircraftStateFromCSV(tx, aircraftID, csvData); err != nil {
+		return fmt.Errorf("failed to import aircraft state data: %w", err)
+	}
+	return nil
+}
+
+// csvBaseTimestamp returns the epoch milliseconds the relative record times of a CSV recording are
+// stored from: its start time, or the configured base when the recording has no usable timestamps
+func csvBaseTimestamp(csvData *CSVFlightData) int64 {
+	if !csvData.Metadata.StartTime.IsZero() {
+		return csvData.Metadata.StartTime.UnixMilli()
+	}
+	return analysisConfig.CSVBaseTimestampMs
+}
+
+// formatZuluTime formats a time as Sky Dolly stores its zulu sim times
+func formatZuluTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// createFlightFromCSV creates a flight record from CSV metadata
+func createFlightFromCSV(tx *sql.Tx, metadata *CSVMetadata) (int, error) {
 	query := `
 		INSERT INTO flight (
-			title, flight_number, start_zulu_sim_time, end_zulu_sim_time,
-			description, user_aircraft_seq_nr
-		) VALUES (?, ?, ?, ?, ?, ?)
+			title, flight_number, user_aircraft_seq_nr
+		) VALUES (?, ?, ?)
 	`
 
-	description := fmt.Sprintf("Imported from CSV (%s) - %d data points", 
-		csvData.Metadata.Source, csvData.Metadata.TotalRecords)
-
 	result, err := tx.Exec(query,
-		csvData.Metadata.FlightTitle,
+		metadata.FlightTitle,
 		"CSV Import",
-		startTime,
-		endTime,
-		description,
 		1, // user_aircraft_seq_nr - default to 1 for CSV data
 	)
 	if err != nil {
@@ -765,6 +1106,27 @@ func createFlightFromCSV(tx *sql.Tx, csvData *CSVFlightData) (int, error) {
 	return int(flightID), nil
 }
 
+// completeFlightFromCSV sets the times and description of a flight once all its records were imported
+func completeFlightFromCSV(tx *sql.Tx, flightID int, csvData *CSVFlightData) error {
+	// Flight times come from the first and last records, in UTC like Sky Dolly's zulu times
+	query := `
+		UPDATE flight
+		SET start_zulu_sim_time = ?, end_zulu_sim_time = ?, description = ?
+		WHERE id = ?
+	`
+
+	description := fmt.Sprintf("Imported from CSV (%s) - %d data points",
+		csvData.Metadata.Source, csvData.Metadata.TotalRecords)
+
+	_, err := tx.Exec(query,
+		formatZuluTime(csvData.Metadata.StartTime),
+		formatZuluTime(csvData.Metadata.EndTime),
+		description,
+		flightID,
+	)
+	return err
+}
+
 // createAircraftFromCSV creates an aircraft record from CSV data
 func createAircraftFromCSV(tx *sql.Tx, flightID int, csvData *CSVFlightData) (int, error) {
 	query := `
@@ -796,8 +1158,9 @@ func importPositionDataFromCSV(tx *sql.Tx, aircraftID int, csvData *CSVFlightDat
 	query := `
 		INSERT INTO position (
 			aircraft_id, timestamp, latitude, longitude, altitude,
-			indicated_altitude, pressure_altitude, indicated_airspeed
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			indicated_altitude, pressure_altitude, indicated_airspeed,
+			wind_speed, wind_direction, vertical_speed, true_airspeed, ambient_temperature
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	stmt, err := tx.Prepare(query)
@@ -806,29 +1169,32 @@ func importPositionDataFromCSV(tx *sql.Tx, aircraftID int, csvData *CSVFlightDat
 	}
 	defer stmt.Close()
 
-	// Calculate base timestamp from first record
-	var baseTimestamp int64
-	if len(csvData.Records) > 0 {
-		// Use milliseconds since epoch, with relative timing
-		baseTimestamp = 1690000000000 // Arbitrary base timestamp
-	}
+	// Files without ambient wind columns store NULL rather than a calm wind
+	hasWind := csvHasWindColumns(csvData.Headers)
+	hasVerticalSpeed := csvHasVerticalSpeedColumn(csvData.Headers)
+	hasTrueAirspeed := csvHasTrueAirspeedColumn(csvData.Headers)
+	hasTemperature := csvHasTemperatureColumn(csvData.Headers)
+
+	baseTimestamp := csvBaseTimestamp(csvData)
 
 	for _, record := range csvData.Records {
 		// Convert timestamp to milliseconds
 		timestamp := baseTimestamp + int64(record.TimestampSeconds*1000)
 		
-		// Convert altitude from feet to meters for consistency
-		altitudeMeters := record.Altitude * 0.3048
-		
 		_, err = stmt.Exec(
 			aircraftID,
 			timestamp,
 			record.Latitude,
 			record.Longitude,
-			altitudeMeters,
+			record.Altitude, // Feet, like Sky Dolly
 			record.Altitude, // Keep indicated altitude in feet
 			record.Altitude, // Use same for pressure altitude
 			record.AirspeedIndicated, // Store indicated airspeed in knots
+			optionalValue(hasWind, record.AmbientWindVelocity), // Ambient wind in knots
+			optionalValue(hasWind, record.AmbientWindDirection),
+			optionalValue(hasVerticalSpeed, record.VerticalSpeed), // Feet per minute
+			optionalValue(hasTrueAirspeed, record.AirspeedTrue), // Knots
+			optionalValue(hasTemperature, record.AmbientTemperature), // Celsius
 		)
 		if err != nil {
 			return err
@@ -853,22 +1219,16 @@ func importAttitudeDataFromCSV(tx *sql.Tx, aircraftID int, csvData *CSVFlightDat
 	}
 	defer stmt.Close()
 
-	// Calculate base timestamp from first record
-	var baseTimestamp int64
-	if len(csvData.Records) > 0 {
-		baseTimestamp = 1690000000000 // Arbitrary base timestamp
-	}
+	baseTimestamp := csvBaseTimestamp(csvData)
 
 	for _, record := range csvData.Records {
+		// Records without pitch or bank have no attitude sample rather than a level one
+		if !record.HasPitch || !record.HasBank {
+			continue
+		}
 		timestamp := baseTimestamp + int64(record.TimestampSeconds*1000)
 		
-		// Calculate velocity components from ground speed and heading
-		groundSpeedMS := record.GroundSpeed * 0.514444 // knots to m/s
-		headingRad := record.HeadingTrue * 3.14159 / 180.0
-		
-		velocityX := groundSpeedMS * sin(headingRad)
-		velocityY := groundSpeedMS * cos(headingRad)
-		velocityZ := record.VerticalSpeed * 0.00508 // ft/min to m/s
+		velocityX, velocityY, velocityZ := csvAttitudeVelocity(record.GroundSpeed, record.HeadingTrue, record.VerticalSpeed)
 		
 		onGround := 0
 		if record.OnGround {
@@ -880,7 +1240,7 @@ func importAttitudeDataFromCSV(tx *sql.Tx, aircraftID int, csvData *CSVFlightDat
 			timestamp,
 			record.PitchAngle,
 			record.BankAngle,
-			record.HeadingTrue,
+			sql.NullFloat64{Float64: record.HeadingTrue, Valid: record.HasHeadingTrue},
 			velocityX,
 			velocityY,
 			velocityZ,
@@ -894,13 +1254,15 @@ func importAttitudeDataFromCSV(tx *sql.Tx, aircraftID int, csvData *CSVFlightDat
 	return nil
 }
 
-// importEngineDataFromCSV imports limited engine data from CSV records
+// importEngineDataFromCSV imports the engine columns the CSV has. Without engine columns no engine rows
+// are written, so the flight is reported as having no engine data.
 func importEngineDataFromCSV(tx *sql.Tx, aircraftID int, csvData *CSVFlightData) error {
-	query := `
-		INSERT INTO engine (
-			aircraft_id, timestamp, throttle_lever_position1
-		) VALUES (?, ?, ?)
-	`
+	if len(csvData.EngineColumns) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf("INSERT INTO engine (aircraft_id, timestamp, %s) VALUES (?, ?%s)",
+		strings.Join(csvData.EngineColumns, ", "), strings.Repeat(", ?", len(csvData.EngineColumns)))
 
 	stmt, err := tx.Prepare(query)
 	if err != nil {
@@ -908,24 +1270,18 @@ func importEngineDataFromCSV(tx *sql.Tx, aircraftID int, csvData *CSVFlightData)
 	}
 	defer stmt.Close()
 
-	// Calculate base timestamp from first record
-	var baseTimestamp int64
-	if len(csvData.Records) > 0 {
-		baseTimestamp = 1690000000000 // Arbitrary base timestamp
-	}
+	baseTimestamp := csvBaseTimestamp(csvData)
 
+	args := make([]interface{}, len(csvData.EngineColumns)+2)
 	for _, record := range csvData.Records {
-		timestamp := baseTimestamp + int64(record.TimestampSeconds*1000)
-		
-		// Use flaps position as a proxy for throttle data (limited CSV data)
-		throttlePosition := record.FlapsHandlePosition / 100.0 // Normalize to 0-1
-		
-		_, err = stmt.Exec(
-			aircraftID,
-			timestamp,
-			throttlePosition,
-		)
-		if err != nil {
+		args[0] = aircraftID
+		args[1] = baseTimestamp + int64(record.TimestampSeconds*1000)
+		for i, column := range csvData.EngineColumns {
+			value, ok := record.Engine[column]
+			args[i+2] = sql.NullFloat64{Float64: value, Valid: ok}
+		}
+
+		if _, err := stmt.Exec(args...); err != nil {
 			return err
 		}
 	}
@@ -933,18 +1289,6 @@ func importEngineDataFromCSV(tx *sql.Tx, aircraftID int, csvData *CSVFlightData)
 	return nil
 }
 
-// Simple sin function implementation for velocity calculations
-func sin(x float64) float64 {
-	// Simple approximation using Taylor series
-	x = x - 2*3.14159*float64(int(x/(2*3.14159))) // Normalize to [-2π, 2π]
-	return x - (x*x*x)/6 + (x*x*x*x*x)/120
-}
-
-// Simple cos function implementation for velocity calculations  
-func cos(x float64) float64 {
-	return sin(x + 3.14159/2)
-}
-
 // DeleteFlight deletes a flight and all associated data
 func DeleteFlight(flightID int) error {
 	if flightID <= 0 {
@@ -1001,6 +1345,14 @@ func DeleteFlight(flightID int) error {
 		if _, err := tx.Exec("DELETE FROM waypoint WHERE aircraft_id = ?", aircraftID); err != nil {
 			return fmt.Errorf("failed to delete waypoint data for aircraft %d: %w", aircraftID, err)
 		}
+
+		if _, err := tx.Exec("DELETE FROM aircraft_state WHERE aircraft_id = ?", aircraftID); err != nil {
+			return fmt.Errorf("failed to delete aircraft state data for aircraft %d: %w", aircraftID, err)
+		}
+
+		if _, err := tx.Exec("DELETE FROM position_provenance WHERE aircraft_id = ?", aircraftID); err != nil {
+			return fmt.Errorf("failed to delete position provenance for aircraft %d: %w", aircraftID, err)
+		}
 	}
 
 	// Delete markers for this flight
@@ -1008,6 +1360,21 @@ func DeleteFlight(flightID int) error {
 		return fmt.Errorf("failed to delete markers for flight %d: %w", flightID, err)
 	}
 
+	// Delete stored metrics for this flight
+	if _, err := tx.Exec("DELETE FROM flight_metrics WHERE flight_id = ?", flightID); err != nil {
+		return fmt.Errorf("failed to delete stored metrics for flight %d: %w", flightID, err)
+	}
+
+	// Delete the quality assessment and review of this flight
+	if _, err := tx.Exec("DELETE FROM flight_review WHERE flight_id = ?", flightID); err != nil {
+		return fmt.Errorf("failed to delete review for flight %d: %w", flightID, err)
+	}
+
+	// Delete the session conditions attached to this flight
+	if _, err := tx.Exec("DELETE FROM flight_conditions WHERE flight_id = ?", flightID); err != nil {
+		return fmt.Errorf("failed to delete conditions for flight %d: %w", flightID, err)
+	}
+
 	// Delete aircraft records
 	if _, err := tx.Exec("DELETE FROM aircraft WHERE flight_id = ?", flightID); err != nil {
 		return fmt.Errorf("failed to delete aircraft for flight %d: %w", flightID, err)
@@ -1033,6 +1400,9 @@ func DeleteFlight(flightID int) error {
 		return fmt.Errorf("failed to commit deletion transaction: %w", err)
 	}
 
+	invalidateFlightColumns(flightID)
+	stopReplay(flightID)
+
 	log.Printf("Successfully deleted flight %d with all associated data", flightID)
 	return nil
 }
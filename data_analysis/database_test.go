@@ -0,0 +1,49 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// openTestDatabase replaces the main database with an empty one holding the full schema for the
+// duration of a test
+func openTestDatabase(t *testing.T) {
+	t.Helper()
+
+	previous := mainDB
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_txlock=immediate", filepath.Join(t.TempDir(), "test.db"))
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	mainDB = db
+	t.Cleanup(func() {
+		db.Close()
+		mainDB = previous
+	})
+
+	if err := createMainDatabaseSchema(); err != nil {
+		t.Fatalf("failed to create test database schema: %v", err)
+	}
+}
+
+// insertTestFlight adds a flight with one aircraft, returning their IDs
+func insertTestFlight(t *testing.T, title, description, tailNumber string) (flightID, aircraftID int) {
+	t.Helper()
+
+	result, err := mainDB.Exec("INSERT INTO flight (title, description, user_aircraft_seq_nr) VALUES (?, ?, 1)", title, description)
+	if err != nil {
+		t.Fatalf("failed to insert flight: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	flightID = int(id)
+
+	result, err = mainDB.Exec("INSERT INTO aircraft (flight_id, seq_nr, type, tail_number) VALUES (?, 1, 'C172', ?)", flightID, tailNumber)
+	if err != nil {
+		t.Fatalf("failed to insert aircraft: %v", err)
+	}
+	id, _ = result.LastInsertId()
+	return flightID, int(id)
+}
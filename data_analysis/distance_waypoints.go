@@ -0,0 +1,196 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// DistanceMarkerWaypoint is a library point distance markers are created for in addition to the
+// distance marker reference, each with its own target distance
+type DistanceMarkerWaypoint struct {
+	ID               int     `json:"id"`
+	ReferencePointID int     `json:"reference_point_id"`
+	Name             string  `json:"name"` // Name of the library point
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	DistanceNM       float64 `json:"distance_nm"`
+}
+
+// ensureDistanceMarkerWaypointsTable creates the table of additional distance marker waypoints
+func ensureDistanceMarkerWaypointsTable() error {
+	waypointsSchema := `
+		CREATE TABLE IF NOT EXISTS distance_marker_waypoint (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			reference_point_id INTEGER NOT NULL,
+			distance_nm REAL NOT NULL,
+			FOREIGN KEY(reference_point_id) REFERENCES reference_point(id) ON DELETE CASCADE
+		);
+	`
+	if _, err := mainDB.Exec(waypointsSchema); err != nil {
+		return fmt.Errorf("failed to create distance_marker_waypoint table: %w", err)
+	}
+	return nil
+}
+
+// getDistanceMarkerWaypoints returns the waypoints with the name and coordinates of their library points
+func getDistanceMarkerWaypoints() ([]DistanceMarkerWaypoint, error) {
+	query := `
+		SELECT w.id, w.reference_point_id, p.name, p.latitude, p.longitude, w.distance_nm
+		FROM distance_marker_waypoint w
+		JOIN reference_point p ON p.id = w.reference_point_id
+		ORDER BY p.name, w.distance_nm
+	`
+	rows, err := mainDB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	waypoints := []DistanceMarkerWaypoint{}
+	for rows.Next() {
+		var w DistanceMarkerWaypoint
+		if err := rows.Scan(&w.ID, &w.ReferencePointID, &w.Name, &w.Latitude, &w.Longitude, &w.DistanceNM); err != nil {
+			return nil, err
+		}
+		waypoints = append(waypoints, w)
+	}
+	return waypoints, rows.Err()
+}
+
+// distanceMarkerTargets returns the settings markers are created for: the distance marker reference
+// followed by every waypoint, each labelled with its own name and distance
+func distanceMarkerTargets(settings DistanceMarkerSettings) ([]DistanceMarkerSettings, error) {
+	waypoints, err := getDistanceMarkerWaypoints()
+	if err != nil {
+		return nil, err
+	}
+
+	targets := []DistanceMarkerSettings{settings}
+	for _, w := range waypoints {
+		target := settings
+		target.ReferencePointID = w.ReferencePointID
+		target.ReferenceName = w.Name
+		target.Latitude = w.Latitude
+		target.Longitude = w.Longitude
+		target.DistanceNM = w.DistanceNM
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// distanceMarkerWaypointFromRequest decodes and validates a waypoint from a request body
+func distanceMarkerWaypointFromRequest(w http.ResponseWriter, r *http.Request) (DistanceMarkerWaypoint, bool) {
+	var waypoint DistanceMarkerWaypoint
+	if err := json.NewDecoder(r.Body).Decode(&waypoint); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return waypoint, false
+	}
+	if waypoint.DistanceNM <= 0 {
+		httpError(w, "distance must be positive", http.StatusBadRequest)
+		return waypoint, false
+	}
+
+	point, err := getReferencePoint(waypoint.ReferencePointID)
+	if err == sql.ErrNoRows {
+		httpError(w, "Reference point not found", http.StatusNotFound)
+		return waypoint, false
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get reference point: %v", err), http.StatusInternalServerError)
+		return waypoint, false
+	}
+	waypoint.Name = point.Name
+	waypoint.Latitude = point.Latitude
+	waypoint.Longitude = point.Longitude
+	return waypoint, true
+}
+
+// handleGetDistanceMarkerWaypoints lists the distance marker waypoints
+func handleGetDistanceMarkerWaypoints(w http.ResponseWriter, r *http.Request) {
+	waypoints, err := getDistanceMarkerWaypoints()
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get distance marker waypoints: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(waypoints)
+}
+
+// handleCreateDistanceMarkerWaypoint adds a library point with a target distance to the waypoints
+func handleCreateDistanceMarkerWaypoint(w http.ResponseWriter, r *http.Request) {
+	waypoint, ok := distanceMarkerWaypointFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := mainDB.Exec("INSERT INTO distance_marker_waypoint (reference_point_id, distance_nm) VALUES (?, ?)",
+		waypoint.ReferencePointID, waypoint.DistanceNM)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create distance marker waypoint: %v", err), http.StatusInternalServerError)
+		return
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create distance marker waypoint: %v", err), http.StatusInternalServerError)
+		return
+	}
+	waypoint.ID = int(id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(waypoint)
+}
+
+// handleUpdateDistanceMarkerWaypoint replaces the library point and target distance of a waypoint
+func handleUpdateDistanceMarkerWaypoint(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("waypointId"))
+	if err != nil {
+		httpError(w, "Invalid waypoint ID", http.StatusBadRequest)
+		return
+	}
+	waypoint, ok := distanceMarkerWaypointFromRequest(w, r)
+	if !ok {
+		return
+	}
+	waypoint.ID = id
+
+	result, err := mainDB.Exec("UPDATE distance_marker_waypoint SET reference_point_id = ?, distance_nm = ? WHERE id = ?",
+		waypoint.ReferencePointID, waypoint.DistanceNM, id)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to update distance marker waypoint: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		httpError(w, "Waypoint not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(waypoint)
+}
+
+// handleDeleteDistanceMarkerWaypoint removes a waypoint; its library point is kept
+func handleDeleteDistanceMarkerWaypoint(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("waypointId"))
+	if err != nil {
+		httpError(w, "Invalid waypoint ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := mainDB.Exec("DELETE FROM distance_marker_waypoint WHERE id = ?", id)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to delete distance marker waypoint: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		httpError(w, "Waypoint not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
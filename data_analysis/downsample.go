@@ -0,0 +1,225 @@
+package data_analysis
+
+import (
+	"math"
+)
+
+// minDownsamplePoints is the smallest max_points value; LTTB always keeps the first and last sample
+const minDownsamplePoints = 3
+
+// normalizeChannel scales a channel to [0, 1] so channels with large units do not dominate the
+// triangle areas; constant channels become all zero
+func normalizeChannel(values []float64) []float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+
+	normalized := make([]float64, len(values))
+	if hi-lo == 0 {
+		return normalized
+	}
+	for i, v := range values {
+		normalized[i] = (v - lo) / (hi - lo)
+	}
+	return normalized
+}
+
+// extremeIndices returns the indices of the minimum and maximum of every channel
+func extremeIndices(channels [][]float64) map[int]bool {
+	extremes := map[int]bool{}
+	for _, values := range channels {
+		if len(values) == 0 {
+			continue
+		}
+		minIndex, maxIndex := 0, 0
+		for i, v := range values {
+			if v < values[minIndex] {
+				minIndex = i
+			}
+			if v > values[maxIndex] {
+				maxIndex = i
+			}
+		}
+		extremes[minIndex] = true
+		extremes[maxIndex] = true
+	}
+	return extremes
+}
+
+// lttbIndices selects at most threshold samples with Largest-Triangle-Three-Buckets over several
+// channels. The triangle area of a candidate is summed over the normalized channels, and a bucket
+// containing the minimum or maximum of a channel keeps that sample so peaks survive decimation.
+func lttbIndices(x []float64, channels [][]float64, threshold int) []int {
+	n := len(x)
+	if threshold >= n || threshold < minDownsamplePoints {
+		indices := make([]int, n)
+		for i := range indices {
+			indices[i] = i
+		}
+		return indices
+	}
+
+	normalized := make([][]float64, len(channels))
+	for c, values := range channels {
+		normalized[c] = normalizeChannel(values)
+	}
+	extremes := extremeIndices(channels)
+
+	indices := make([]int, 0, threshold)
+	indices = append(indices, 0)
+
+	// Buckets between the fixed first and last sample
+	every := float64(n-2) / float64(threshold-2)
+	selected := 0
+	for bucket := 0; bucket < threshold-2; bucket++ {
+		start := int(float64(bucket)*every) + 1
+		end := int(float64(bucket+1)*every) + 1
+
+		// Average of the next bucket is the third triangle corner
+		nextStart, nextEnd := end, min(int(float64(bucket+2)*every)+1, n)
+		if nextStart >= nextEnd {
+			nextStart, nextEnd = n-1, n
+		}
+		avgX := 0.0
+		avgY := make([]float64, len(normalized))
+		for i := nextStart; i < nextEnd; i++ {
+			avgX += x[i]
+			for c := range normalized {
+				avgY[c] += normalized[c][i]
+			}
+		}
+		count := float64(nextEnd - nextStart)
+		avgX /= count
+		for c := range avgY {
+			avgY[c] /= count
+		}
+
+		best, bestArea := start, -1.0
+		for i := start; i < end; i++ {
+			if extremes[i] {
+				best = i
+				break
+			}
+			area := 0.0
+			for c := range normalized {
+				area += math.Abs((x[selected]-avgX)*(normalized[c][i]-normalized[c][selected]) -
+					(x[selected]-x[i])*(avgY[c]-normalized[c][selected]))
+			}
+			if area > bestArea {
+				best, bestArea = i, area
+			}
+		}
+
+		indices = append(indices, best)
+		selected = best
+	}
+
+	return append(indices, n-1)
+}
+
+// downsamplePositions reduces a position series to at most maxPoints samples, preserving the shape
+// and extremes of altitude and airspeed
+func downsamplePositions(points []PositionPoint, maxPoints int) []PositionPoint {
+	if len(points) <= maxPoints {
+		return points
+	}
+
+	x := make([]float64, len(points))
+	altitude := make([]float64, len(points))
+	indicatedAltitude := make([]float64, len(points))
+	airspeed := make([]float64, len(points))
+	for i, p := range points {
+		x[i] = p.TimestampSeconds
+		altitude[i] = p.Altitude
+		indicatedAltitude[i] = p.IndicatedAltitude
+		airspeed[i] = p.Airspeed
+	}
+
+	indices := lttbIndices(x, [][]float64{altitude, indicatedAltitude, airspeed}, maxPoints)
+	result := make([]PositionPoint, len(indices))
+	for i, index := range indices {
+		result[i] = points[index]
+	}
+	return result
+}
+
+// downsampleEngine reduces an engine series to at most maxPoints samples, preserving the shape
+// and extremes of the throttle, propeller and mixture levers
+func downsampleEngine(points []EnginePoint, maxPoints int) []EnginePoint {
+	if len(points) <= maxPoints {
+		return points
+	}
+
+	x := make([]float64, len(points))
+	levers := make([][]float64, 12)
+	for c := range levers {
+		levers[c] = make([]float64, len(points))
+	}
+	for i, p := range points {
+		x[i] = p.TimestampSeconds
+		for c, v := range []float64{
+			p.ThrottlePosition1, p.ThrottlePosition2, p.ThrottlePosition3, p.ThrottlePosition4,
+			p.PropellerPosition1, p.PropellerPosition2, p.PropellerPosition3, p.PropellerPosition4,
+			p.MixturePosition1, p.MixturePosition2, p.MixturePosition3, p.MixturePosition4,
+		} {
+			levers[c][i] = v
+		}
+	}
+
+	indices := lttbIndices(x, levers, maxPoints)
+	result := make([]EnginePoint, len(indices))
+	for i, index := range indices {
+		result[i] = points[index]
+	}
+	return result
+}
+
+// downsampleAttitude reduces an attitude series to at most maxPoints samples, preserving the shape
+// and extremes of pitch and bank
+func downsampleAttitude(points []AttitudePoint, maxPoints int) []AttitudePoint {
+	if len(points) <= maxPoints {
+		return points
+	}
+
+	x := make([]float64, len(points))
+	pitch := make([]float64, len(points))
+	bank := make([]float64, len(points))
+	for i, p := range points {
+		x[i] = p.TimestampSeconds
+		pitch[i] = p.Pitch
+		bank[i] = p.Bank
+	}
+
+	indices := lttbIndices(x, [][]float64{pitch, bank}, maxPoints)
+	result := make([]AttitudePoint, len(indices))
+	for i, index := range indices {
+		result[i] = points[index]
+	}
+	return result
+}
+
+// downsampleFlightData reduces every aircraft series to at most maxPoints samples and records the
+// original position sample counts of the aircraft that were reduced
+func downsampleFlightData(flightData *FlightData, maxPoints int) {
+	for label, points := range flightData.PositionData {
+		if len(points) <= maxPoints {
+			continue
+		}
+		if flightData.OriginalPointCounts == nil {
+			flightData.OriginalPointCounts = map[string]int{}
+		}
+		flightData.OriginalPointCounts[label] = len(points)
+		flightData.PositionData[label] = downsamplePositions(points, maxPoints)
+	}
+	for label, points := range flightData.EngineData {
+		flightData.EngineData[label] = downsampleEngine(points, maxPoints)
+	}
+	for label, points := range flightData.AttitudeData {
+		flightData.AttitudeData[label] = downsampleAttitude(points, maxPoints)
+	}
+	for label, points := range flightData.StateData {
+		flightData.StateData[label] = downsampleState(points, maxPoints)
+	}
+}
@@ -0,0 +1,118 @@
+package data_analysis
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// ensureFlightSourceHashColumn adds the content hash of the recording a flight was imported from, so
+// importing the same file again is detected
+func ensureFlightSourceHashColumn() error {
+	var exists bool
+	err := mainDB.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('flight') WHERE name = 'source_hash'").Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get flight table info: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	log.Println("Adding source_hash column to flight table...")
+	if _, err := mainDB.Exec("ALTER TABLE flight ADD COLUMN source_hash TEXT"); err != nil {
+		return fmt.Errorf("failed to add source_hash column: %w", err)
+	}
+	if _, err := mainDB.Exec("CREATE INDEX IF NOT EXISTS flight_idx_source_hash ON flight (source_hash)"); err != nil {
+		return fmt.Errorf("failed to create source_hash index: %w", err)
+	}
+	return nil
+}
+
+// hashRecording returns the SHA-256 of a recording file
+func hashRecording(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read recording: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// findFlightsBySourceHash returns the flights imported from a recording with the given content hash
+func findFlightsBySourceHash(hash string) ([]DuplicateFlight, error) {
+	rows, err := mainDB.Query(`
+		SELECT id, COALESCE(title, ''), COALESCE(start_zulu_sim_time, ''), COALESCE(end_zulu_sim_time, '')
+		FROM flight
+		WHERE source_hash = ?
+		ORDER BY id
+	`, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flights by source hash: %w", err)
+	}
+	defer rows.Close()
+
+	var duplicates []DuplicateFlight
+	for rows.Next() {
+		var d DuplicateFlight
+		if err := rows.Scan(&d.ExistingFlightID, &d.Title, &d.StartTime, &d.EndTime); err != nil {
+			return nil, fmt.Errorf("failed to scan flight: %w", err)
+		}
+		duplicates = append(duplicates, d)
+	}
+	return duplicates, rows.Err()
+}
+
+// findDuplicateFlight returns the ID of a stored flight with the same title and start and end times,
+// 0 if there is none. Sky Dolly logbooks keep their earlier flights, so importing a logbook again
+// would otherwise copy every flight recorded before.
+func findDuplicateFlight(tx *sql.Tx, title sql.NullString, startZulu, endZulu string) (int, error) {
+	var id int
+	err := tx.QueryRow(`
+		SELECT id FROM flight
+		WHERE title IS ? AND start_zulu_sim_time = ? AND end_zulu_sim_time = ?
+		ORDER BY id
+		LIMIT 1
+	`, title, startZulu, endZulu).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up duplicate flight: %w", err)
+	}
+	return id, nil
+}
+
+// setFlightSourceHash records the content hash of the recording the flights were imported from
+func setFlightSourceHash(flights []Flight, hash string) {
+	for _, flight := range flights {
+		if _, err := mainDB.Exec("UPDATE flight SET source_hash = ? WHERE id = ?", hash, flight.ID); err != nil {
+			log.Printf("Failed to set source hash of flight %d: %v", flight.ID, err)
+		}
+	}
+}
+
+// skippedImport is the result of a recording whose flights were all imported before
+func skippedImport(filename string, duplicates []DuplicateFlight) UploadFileResult {
+	ids := make([]string, len(duplicates))
+	for i, d := range duplicates {
+		ids[i] = fmt.Sprint(d.ExistingFlightID)
+	}
+	return UploadFileResult{
+		Filename:   filename,
+		Status:     "skipped",
+		Message:    fmt.Sprintf("Skipped %s, already imported as flight %s (import with force to import it again)", filename, strings.Join(ids, ", ")),
+		Flights:    []Flight{},
+		Errors:     []ImportError{},
+		Duplicates: duplicates,
+	}
+}
@@ -0,0 +1,45 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// APIError is the body of every error response of the data analysis endpoints, so the frontend and
+// scripts can handle failures uniformly
+type APIError struct {
+	Code    string      `json:"code"` // The status as snake_case text, e.g. "not_found" or "conflict"
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"` // Structured context, e.g. the state of a chunked upload
+}
+
+// errorCode returns the code of an HTTP status, e.g. "not_found" for 404
+func errorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
+
+// httpError replies with a JSON error envelope; it takes the arguments of http.Error
+func httpError(w http.ResponseWriter, message string, status int) {
+	httpErrorDetails(w, message, status, nil)
+}
+
+// httpErrorDetails replies with a JSON error envelope carrying structured details
+func httpErrorDetails(w http.ResponseWriter, message string, status int, details interface{}) {
+	// Headers set for the intended response, e.g. of a download, do not apply to the error
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Del("Content-Disposition")
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIError{
+		Code:    errorCode(status),
+		Message: message,
+		Details: details,
+	})
+}
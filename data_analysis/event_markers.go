@@ -0,0 +1,126 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+)
+
+// EventMarkerType is the marker type of markers created from the operator's event log
+const EventMarkerType = "event"
+
+// eventMarkerCategories are the marker categories of the operator events placed on the timeline.
+// Markers are labelled with the event type, so flights can be aligned on e.g. failure_started.
+var eventMarkerCategories = map[string]string{
+	"flight_started":     MarkerCategoryPhase,
+	"flight_ended":       MarkerCategoryPhase,
+	"completion":         MarkerCategoryPhase,
+	"failure_started":    MarkerCategoryFailure,
+	"failure_recognised": MarkerCategoryFailure,
+	"back_on_track":      MarkerCategoryFailure,
+	"confused":           MarkerCategoryObservation,
+}
+
+// flightWallClockStart returns the UTC time of t=0 of a flight, its start zulu sim time. This matches
+// the operator's clock as long as the simulator runs on real time.
+func flightWallClockStart(flightID int) (time.Time, error) {
+	var startZulu string
+	if err := mainDB.QueryRow("SELECT start_zulu_sim_time FROM flight WHERE id = ?", flightID).Scan(&startZulu); err != nil {
+		return time.Time{}, fmt.Errorf("failed to get flight start: %w", err)
+	}
+	start, err := time.Parse(time.RFC3339Nano, startZulu)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid flight start '%s': %w", startZulu, err)
+	}
+	return start, nil
+}
+
+// createEventMarkersForFlight replaces the event markers of a flight with markers for the operator
+// events logged while it was recorded. clockOffset is added to the flight's start time for simulators
+// not running on real time. It returns the number of markers created.
+func createEventMarkersForFlight(flightID int, clockOffset time.Duration) (int, error) {
+	start, err := flightWallClockStart(flightID)
+	if err != nil {
+		return 0, err
+	}
+	start = start.Add(clockOffset)
+	duration, err := flightDurationSeconds(flightID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get flight duration: %w", err)
+	}
+
+	logged, err := events.Between(start, start.Add(time.Duration(duration*float64(time.Second))))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	if _, err := mainDB.Exec("DELETE FROM markers WHERE flight_id = ? AND type = ?", flightID, EventMarkerType); err != nil {
+		return 0, fmt.Errorf("failed to delete event markers: %w", err)
+	}
+
+	created := 0
+	for _, event := range logged {
+		category, ok := eventMarkerCategories[event.Type]
+		if !ok {
+			continue
+		}
+		marker := Marker{
+			FlightID: flightID,
+			Time:     event.Timestamp.Sub(start).Seconds(),
+			Label:    event.Type,
+			Type:     EventMarkerType,
+			Category: category,
+		}
+		if _, err := createMarker(marker); err != nil {
+			return created, fmt.Errorf("failed to create event marker: %w", err)
+		}
+		created++
+	}
+	return created, nil
+}
+
+// createImportedEventMarkers places the operator events on the timelines of imported flights
+func createImportedEventMarkers(flights []Flight) {
+	for _, flight := range flights {
+		created, err := createEventMarkersForFlight(flight.ID, 0)
+		if err != nil {
+			log.Printf("Failed to create event markers for flight %d: %v", flight.ID, err)
+			continue
+		}
+		if created > 0 {
+			log.Printf("Created %d event markers for flight %d", created, flight.ID)
+		}
+	}
+}
+
+// handleCreateEventMarkers matches the operator events to a flight again, replacing its event markers.
+// The optional clock_offset parameter (seconds) corrects for a simulator clock off real time.
+func handleCreateEventMarkers(w http.ResponseWriter, r *http.Request, flightId int) {
+	var clockOffset time.Duration
+	if value := r.URL.Query().Get("clock_offset"); value != "" {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			httpError(w, fmt.Sprintf("Invalid clock_offset '%s'", value), http.StatusBadRequest)
+			return
+		}
+		clockOffset = time.Duration(seconds * float64(time.Second))
+	}
+
+	created, err := createEventMarkersForFlight(flightId, clockOffset)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create event markers: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Created %d event markers", created),
+		"created": created,
+	})
+}
@@ -5,80 +5,210 @@ import (
 	"bytes"
 	"encoding/csv"
 	"fmt"
+	"io"
 	"net/http"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // CSVExportOptions defines options for CSV export
 type CSVExportOptions struct {
 	FlightID int
-	Format   string // "airspeed-altitude", "full"
+	Format   string   // "airspeed-altitude", "full"
+	Flight   *Flight  // Flight metadata written to flight_metadata.csv, omitted if nil
+	Markers  []Marker // Markers written to markers.csv, omitted if nil
 }
 
 // ExportFlightDataToCSV exports flight data to ZIP file containing two CSV files
 func ExportFlightDataToCSV(flightData *FlightData, options CSVExportOptions) (*bytes.Buffer, error) {
+	if options.Flight == nil {
+		options.Flight = flightData.Flight
+	}
+	return exportFlightColumnsToCSV(flightDataToColumns(flightData), options)
+}
+
+// exportFlightColumnsToCSV exports columnar flight series to ZIP file containing the CSV files
+func exportFlightColumnsToCSV(columns map[string]*SeriesColumns, options CSVExportOptions) (*bytes.Buffer, error) {
 	// Create a buffer to write our zip to
 	buf := new(bytes.Buffer)
 
 	// Create a new zip archive
 	w := zip.NewWriter(buf)
 
+	if err := writeFlightCSVFiles(w, "", columns, options); err != nil {
+		return nil, err
+	}
+
+	// Close the zip writer
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close zip writer: %w", err)
+	}
+
+	return buf, nil
+}
+
+// FlightFolderFiles describes the files writeFlightCSVFiles writes into the folder of a flight; the units of
+// altitudes and speeds follow the units setting and are given in the column headers
+var FlightFolderFiles = []struct {
+	Name        string
+	Description string
+}{
+	{"airspeed_data.csv", "Airspeed of all aircraft: Timestamp in seconds from the flight start, IAS and TAS, IAS_Source recorded or estimated from ground speed, wind and air density"},
+	{"altitude_data.csv", "Altitude of all aircraft: Timestamp in seconds from the flight start, Altitude above mean sea level"},
+	{"flight_metadata.csv", "Flight details, participant and condition, recorded weather and the simulator conditions logged on the session, as field/value rows"},
+	{"markers.csv", "Markers: time_seconds in seconds from the flight start"},
+}
+
+// writeFlightCSVFiles adds the CSV files of one flight to a ZIP archive, inside the given folder, in the
+// configured units
+func writeFlightCSVFiles(w *zip.Writer, folder string, columns map[string]*SeriesColumns, options CSVExportOptions) error {
+	units := configuredUnits()
+	columns = units.convertColumns(columns)
+
 	// Generate airspeed CSV
-	airspeedData, err := generateAirspeedCSV(flightData)
+	airspeedData, err := generateAirspeedCSV(columns, units)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate airspeed CSV: %w", err)
+		return fmt.Errorf("failed to generate airspeed CSV: %w", err)
 	}
 
 	// Generate altitude CSV
-	altitudeData, err := generateAltitudeCSV(flightData)
+	altitudeData, err := generateAltitudeCSV(columns, units)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate altitude CSV: %w", err)
+		return fmt.Errorf("failed to generate altitude CSV: %w", err)
 	}
 
 	// Add airspeed CSV to zip
-	airspeedFile, err := w.Create("airspeed_data.csv")
+	airspeedFile, err := w.Create(path.Join(folder, "airspeed_data.csv"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create airspeed CSV file in zip: %w", err)
+		return fmt.Errorf("failed to create airspeed CSV file in zip: %w", err)
 	}
 	if _, err := airspeedFile.Write(airspeedData); err != nil {
-		return nil, fmt.Errorf("failed to write airspeed CSV data: %w", err)
+		return fmt.Errorf("failed to write airspeed CSV data: %w", err)
 	}
 
 	// Add altitude CSV to zip
-	altitudeFile, err := w.Create("altitude_data.csv")
+	altitudeFile, err := w.Create(path.Join(folder, "altitude_data.csv"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create altitude CSV file in zip: %w", err)
+		return fmt.Errorf("failed to create altitude CSV file in zip: %w", err)
 	}
 	if _, err := altitudeFile.Write(altitudeData); err != nil {
-		return nil, fmt.Errorf("failed to write altitude CSV data: %w", err)
+		return fmt.Errorf("failed to write altitude CSV data: %w", err)
 	}
 
-	// Close the zip writer
-	if err := w.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close zip writer: %w", err)
+	// Add flight metadata including weather, a covariate in the analysis
+	if options.Flight != nil {
+		metadataFile, err := w.Create(path.Join(folder, "flight_metadata.csv"))
+		if err != nil {
+			return fmt.Errorf("failed to create flight metadata CSV file in zip: %w", err)
+		}
+		if err := writeFlightMetadataCSV(metadataFile, options.Flight, units); err != nil {
+			return fmt.Errorf("failed to write flight metadata CSV data: %w", err)
+		}
 	}
 
-	return buf, nil
+	// Add markers
+	if options.Markers != nil {
+		markersFile, err := w.Create(path.Join(folder, "markers.csv"))
+		if err != nil {
+			return fmt.Errorf("failed to create markers CSV file in zip: %w", err)
+		}
+		if err := writeMarkersCSV(markersFile, options.Markers); err != nil {
+			return fmt.Errorf("failed to write markers CSV data: %w", err)
+		}
+	}
+
+	return nil
 }
 
-// generateAirspeedCSV generates CSV data for airspeed information (IAS only)
-func generateAirspeedCSV(flightData *FlightData) ([]byte, error) {
+// getExportMarkers returns the markers of a flight for export; flights without markers export an empty markers.csv
+func getExportMarkers(flightID int) ([]Marker, error) {
+	markers, err := getMarkersForFlight(flightID)
+	if markers == nil && err == nil {
+		markers = []Marker{}
+	}
+	return markers, err
+}
+
+// writeMarkersCSV writes the markers of a flight, times in seconds from the flight start
+func writeMarkersCSV(out io.Writer, markers []Marker) error {
+	writer := csv.NewWriter(out)
+
+	records := [][]string{{"id", "time_seconds", "label", "type", "category", "color"}}
+	for _, m := range markers {
+		records = append(records, []string{
+			strconv.Itoa(m.ID),
+			strconv.FormatFloat(m.Time, 'f', 3, 64),
+			m.Label,
+			m.Type,
+			m.Category,
+			m.Color,
+		})
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// writeFlightMetadataCSV writes the flight details and weather as name/value rows
+func writeFlightMetadataCSV(out io.Writer, flight *Flight, units UnitSystem) error {
+	writer := csv.NewWriter(out)
+
+	records := [][]string{
+		{"field", "value"},
+		{"flight_id", strconv.Itoa(flight.ID)},
+		{"title", flight.Title},
+		{"flight_number", flight.FlightNumber},
+		{"start_time", flight.StartTime},
+		{"end_time", flight.EndTime},
+		{"participant_id", flight.ParticipantID},
+		{"condition", flight.Condition},
+	}
+	records = append(records, weatherRecords(flight.Weather, units)...)
+
+	conditions := flight.Conditions
+	if conditions == nil {
+		conditions = &FlightConditions{}
+	}
+	records = append(records,
+		[]string{"weather_preset", conditions.WeatherPreset},
+		[]string{"failures", strings.Join(conditions.Failures, "; ")},
+		[]string{"conditions_notes", conditions.Notes},
+	)
+
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// generateAirspeedCSV generates CSV data for airspeed information, labelling estimated indicated airspeeds
+func generateAirspeedCSV(columns map[string]*SeriesColumns, units UnitSystem) ([]byte, error) {
 	buf := new(bytes.Buffer)
 	writer := csv.NewWriter(buf)
 
 	// Write header
-	header := []string{"Timestamp", "IAS"}
+	header := []string{units.header("Timestamp", "s"), units.header("IAS", units.Speed), units.header("TAS", units.Speed), "IAS_Source"}
 	if err := writer.Write(header); err != nil {
 		return nil, fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
 	// Write data rows - combine all aircraft data
-	for _, positionData := range flightData.PositionData {
-		for _, point := range positionData {
+	for _, aircraftLabel := range sortedAircraftLabels(columns) {
+		series := columns[aircraftLabel]
+		for i := 0; i < series.Len(); i++ {
+			source := AirspeedRecorded
+			if series.AirspeedEstimated[i] {
+				source = AirspeedEstimated
+			}
 			row := []string{
-				fmt.Sprintf("%.1f", point.TimestampSeconds),
-				fmt.Sprintf("%.2f", point.Airspeed),
+				fmt.Sprintf("%.1f", series.Time[i]),
+				fmt.Sprintf("%.2f", series.Airspeed[i]),
+				fmt.Sprintf("%.2f", series.TrueAirspeed[i]),
+				source,
 			}
 			if err := writer.Write(row); err != nil {
 				return nil, fmt.Errorf("failed to write CSV row: %w", err)
@@ -95,22 +225,23 @@ func generateAirspeedCSV(flightData *FlightData) ([]byte, error) {
 }
 
 // generateAltitudeCSV generates CSV data for altitude information (essential data only)
-func generateAltitudeCSV(flightData *FlightData) ([]byte, error) {
+func generateAltitudeCSV(columns map[string]*SeriesColumns, units UnitSystem) ([]byte, error) {
 	buf := new(bytes.Buffer)
 	writer := csv.NewWriter(buf)
 
 	// Write header
-	header := []string{"Timestamp", "Altitude"}
+	header := []string{units.header("Timestamp", "s"), units.header("Altitude", units.Altitude)}
 	if err := writer.Write(header); err != nil {
 		return nil, fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
 	// Write data rows - combine all aircraft data, use MSL altitude as primary
-	for _, positionData := range flightData.PositionData {
-		for _, point := range positionData {
+	for _, aircraftLabel := range sortedAircraftLabels(columns) {
+		series := columns[aircraftLabel]
+		for i := 0; i < series.Len(); i++ {
 			row := []string{
-				fmt.Sprintf("%.1f", point.TimestampSeconds),
-				fmt.Sprintf("%.2f", point.Altitude),
+				fmt.Sprintf("%.1f", series.Time[i]),
+				fmt.Sprintf("%.2f", series.Altitude[i]),
 			}
 			if err := writer.Write(row); err != nil {
 				return nil, fmt.Errorf("failed to write CSV row: %w", err)
@@ -145,27 +276,10 @@ func GenerateCSVFilename(flight *Flight, format string) string {
 }
 
 // handleCSVExport handles HTTP requests for CSV export
-func handleCSVExport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
+func handleCSVExport(w http.ResponseWriter, r *http.Request, flightId int) {
 	// Get parameters
-	flightIdStr := r.URL.Query().Get("flightId")
 	format := r.URL.Query().Get("format")
 
-	if flightIdStr == "" {
-		http.Error(w, "Flight ID required", http.StatusBadRequest)
-		return
-	}
-
-	flightId, err := strconv.Atoi(flightIdStr)
-	if err != nil {
-		http.Error(w, "Invalid flight ID", http.StatusBadRequest)
-		return
-	}
-
 	// Default format if not specified
 	if format == "" {
 		format = "airspeed-altitude"
@@ -173,14 +287,26 @@ func handleCSVExport(w http.ResponseWriter, r *http.Request) {
 
 	// Validate format
 	if format != "airspeed-altitude" && format != "full" {
-		http.Error(w, "Invalid format. Use 'airspeed-altitude' or 'full'", http.StatusBadRequest)
+		httpError(w, "Invalid format. Use 'airspeed-altitude' or 'full'", http.StatusBadRequest)
 		return
 	}
 
 	// Get flight data
-	flightData, err := getFlightDataFromMainDB(flightId)
+	flight, err := getFlightByIDFromMainDB(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	columns, err := getFlightColumns(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	markers, err := getExportMarkers(flightId)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to get markers: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -188,16 +314,18 @@ func handleCSVExport(w http.ResponseWriter, r *http.Request) {
 	options := CSVExportOptions{
 		FlightID: flightId,
 		Format:   format,
+		Flight:   flight,
+		Markers:  markers,
 	}
 
-	csvBuffer, err := ExportFlightDataToCSV(flightData, options)
+	csvBuffer, err := exportFlightColumnsToCSV(columns, options)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to generate CSV files: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to generate CSV files: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Generate filename
-	filename := GenerateCSVFilename(flightData.Flight, format)
+	filename := GenerateCSVFilename(flight, format)
 
 	// Set headers for file download
 	w.Header().Set("Content-Type", "application/zip")
@@ -207,7 +335,7 @@ func handleCSVExport(w http.ResponseWriter, r *http.Request) {
 	// Write the ZIP file to response
 	_, err = w.Write(csvBuffer.Bytes())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to write CSV file: %v", err), http.StatusInternalServerError)
+		httpError(w, fmt.Sprintf("Failed to write CSV file: %v", err), http.StatusInternalServerError)
 		return
 	}
 }
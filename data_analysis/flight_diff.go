@@ -0,0 +1,421 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// Defaults of the flight diff tolerances and the number of differing samples listed per table
+const (
+	defaultDiffTimeTolerance     = 0.001  // Seconds
+	defaultDiffValueTolerance    = 0.0001 // In the unit of each channel
+	defaultDiffDistanceTolerance = 0.1    // Meters between the positions of paired samples
+	maxListedDifferences         = 20
+
+	metersPerNauticalMile = 1852.0
+)
+
+// DiffTolerances are the largest differences between paired samples that are not reported
+type DiffTolerances struct {
+	TimeSeconds    float64 `json:"time_seconds"`
+	Value          float64 `json:"value"`
+	DistanceMeters float64 `json:"distance_meters"`
+}
+
+// ChannelDiff summarizes the differences of one channel over the paired samples of a table
+type ChannelDiff struct {
+	MaxDifference float64 `json:"max_difference"`
+	Differing     int     `json:"differing"` // Paired samples differing beyond the tolerance
+}
+
+// SampleDifference is a channel of a sample pair differing beyond the tolerance. Values are null
+// where a flight did not record the channel.
+type SampleDifference struct {
+	Index       int      `json:"index"`        // Sample index within the compared range
+	TimeSeconds float64  `json:"time_seconds"` // Time of the first flight's sample from the start of the range
+	Channel     string   `json:"channel"`
+	Value       *float64 `json:"value"`
+	OtherValue  *float64 `json:"other_value"`
+}
+
+// TableDiff compares the samples of one table of an aircraft pair, paired in time order
+type TableDiff struct {
+	Samples      int                    `json:"samples"`       // Samples of the first flight within the compared range
+	OtherSamples int                    `json:"other_samples"` // Samples of the other flight
+	Compared     int                    `json:"compared"`      // Sample pairs
+	Channels     map[string]ChannelDiff `json:"channels"`      // Includes "time" and, for positions, "distance" in meters
+	Differences  []SampleDifference     `json:"differences"`   // The first differing channels, up to 20
+	Identical    bool                   `json:"identical"`     // Same sample counts and no differences beyond the tolerances
+}
+
+// AircraftDiff compares the tables of the aircraft at the same position in both flights
+type AircraftDiff struct {
+	Aircraft      string               `json:"aircraft"`
+	OtherAircraft string               `json:"other_aircraft"`
+	Tables        map[string]TableDiff `json:"tables"` // position, attitude, engine and state
+}
+
+// FlightDiff is the sample-by-sample comparison of a flight, or a time range of it, with another flight
+type FlightDiff struct {
+	FlightID      int            `json:"flight_id"`
+	OtherFlightID int            `json:"other_flight_id"`
+	StartTime     float64        `json:"start_time"`
+	EndTime       *float64       `json:"end_time,omitempty"`
+	Tolerances    DiffTolerances `json:"tolerances"`
+	Aircraft      []AircraftDiff `json:"aircraft"`
+	// Aircraft only one of the flights has, compared with nothing
+	UnmatchedAircraft []string `json:"unmatched_aircraft"`
+	Identical         bool     `json:"identical"`
+}
+
+// diffChannel is a channel of a table; missing values are NaN
+type diffChannel struct {
+	name   string
+	values []float64
+}
+
+// diffSeries is one table of an aircraft as times relative to its first sample and channel values
+type diffSeries struct {
+	times    []float64
+	channels []diffChannel
+	// Latitude and longitude, compared as the distance between positions instead of per channel
+	latitudes, longitudes []float64
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func optionalFloat(f *float64) float64 {
+	if f == nil {
+		return math.NaN()
+	}
+	return *f
+}
+
+func optionalBool(b *bool) float64 {
+	if b == nil {
+		return math.NaN()
+	}
+	return boolValue(*b)
+}
+
+// seriesOf builds a diffSeries from samples with a time and named channel values
+func seriesOf[T any](samples []T, time func(T) float64, names []string, values func(T) []float64) diffSeries {
+	series := diffSeries{times: make([]float64, len(samples))}
+	for _, name := range names {
+		series.channels = append(series.channels, diffChannel{name: name, values: make([]float64, len(samples))})
+	}
+	for i, sample := range samples {
+		series.times[i] = time(sample)
+		for c, value := range values(sample) {
+			series.channels[c].values[i] = value
+		}
+	}
+	return series
+}
+
+func positionSeries(points []PositionPoint) diffSeries {
+	series := seriesOf(points, func(p PositionPoint) float64 { return p.TimestampSeconds },
+		[]string{"altitude", "indicated_altitude", "pressure_altitude", "airspeed", "vertical_speed", "wind_speed", "wind_direction"},
+		func(p PositionPoint) []float64 {
+			return []float64{p.Altitude, p.IndicatedAltitude, p.PressureAltitude, p.Airspeed, p.VerticalSpeed,
+				optionalFloat(p.WindSpeed), optionalFloat(p.WindDirection)}
+		})
+	for _, p := range points {
+		series.latitudes = append(series.latitudes, p.Latitude)
+		series.longitudes = append(series.longitudes, p.Longitude)
+	}
+	return series
+}
+
+func attitudeSeries(points []AttitudePoint) diffSeries {
+	return seriesOf(points, func(p AttitudePoint) float64 { return p.TimestampSeconds },
+		[]string{"pitch", "bank", "true_heading", "on_ground"},
+		func(p AttitudePoint) []float64 {
+			return []float64{p.Pitch, p.Bank, p.TrueHeading, boolValue(p.OnGround)}
+		})
+}
+
+func engineSeries(points []EnginePoint) diffSeries {
+	var names []string
+	for _, channel := range []string{"throttle_position", "propeller_position", "mixture_position", "cowl_flap_position",
+		"electrical_master_battery", "starter", "combustion"} {
+		for engine := 1; engine <= 4; engine++ {
+			names = append(names, fmt.Sprintf("%s%d", channel, engine))
+		}
+	}
+	return seriesOf(points, func(p EnginePoint) float64 { return p.TimestampSeconds }, names,
+		func(p EnginePoint) []float64 {
+			return []float64{
+				p.ThrottlePosition1, p.ThrottlePosition2, p.ThrottlePosition3, p.ThrottlePosition4,
+				p.PropellerPosition1, p.PropellerPosition2, p.PropellerPosition3, p.PropellerPosition4,
+				p.MixturePosition1, p.MixturePosition2, p.MixturePosition3, p.MixturePosition4,
+				p.CowlFlapPosition1, p.CowlFlapPosition2, p.CowlFlapPosition3, p.CowlFlapPosition4,
+				boolValue(p.ElectricalMasterBattery1), boolValue(p.ElectricalMasterBattery2),
+				boolValue(p.ElectricalMasterBattery3), boolValue(p.ElectricalMasterBattery4),
+				boolValue(p.Starter1), boolValue(p.Starter2), boolValue(p.Starter3), boolValue(p.Starter4),
+				boolValue(p.Combustion1), boolValue(p.Combustion2), boolValue(p.Combustion3), boolValue(p.Combustion4),
+			}
+		})
+}
+
+func stateSeries(points []StatePoint) diffSeries {
+	return seriesOf(points, func(p StatePoint) float64 { return p.TimestampSeconds },
+		[]string{"flaps_handle_position", "gear_down", "fuel_total_gallons", "g_force", "stall_warning", "overspeed_warning"},
+		func(p StatePoint) []float64 {
+			return []float64{optionalFloat(p.FlapsHandlePosition), optionalBool(p.GearDown), optionalFloat(p.FuelTotalGallons),
+				optionalFloat(p.GForce), optionalBool(p.StallWarning), optionalBool(p.OverspeedWarning)}
+		})
+}
+
+// within returns the samples of a series from start to end seconds (end < 0 for no end), with times
+// relative to the first of them like those of a flight trimmed to that range
+func (s diffSeries) within(start, end float64) diffSeries {
+	from, to := len(s.times), len(s.times)
+	for i, t := range s.times {
+		if t >= start && from == len(s.times) {
+			from = i
+		}
+		if end >= 0 && t > end {
+			to = i
+			break
+		}
+	}
+	to = max(from, to)
+
+	result := diffSeries{times: make([]float64, to-from)}
+	for i := range result.times {
+		result.times[i] = s.times[from+i] - s.times[from]
+	}
+	for _, channel := range s.channels {
+		result.channels = append(result.channels, diffChannel{name: channel.name, values: channel.values[from:to]})
+	}
+	if s.latitudes != nil {
+		result.latitudes, result.longitudes = s.latitudes[from:to], s.longitudes[from:to]
+	}
+	return result
+}
+
+// diffValue returns the difference of two values of a channel; a value missing in only one of them
+// differs infinitely
+func diffValue(a, b float64) float64 {
+	switch {
+	case math.IsNaN(a) && math.IsNaN(b):
+		return 0
+	case math.IsNaN(a) || math.IsNaN(b):
+		return math.Inf(1)
+	}
+	return math.Abs(a - b)
+}
+
+func valuePointer(f float64) *float64 {
+	if math.IsNaN(f) {
+		return nil
+	}
+	return &f
+}
+
+// compareSeries pairs the samples of two series in order and reports the channels differing beyond
+// the tolerances
+func compareSeries(a, b diffSeries, tolerances DiffTolerances) TableDiff {
+	diff := TableDiff{
+		Samples:      len(a.times),
+		OtherSamples: len(b.times),
+		Compared:     min(len(a.times), len(b.times)),
+		Channels:     map[string]ChannelDiff{},
+		Differences:  []SampleDifference{},
+	}
+
+	differing := 0
+	record := func(i int, name string, difference, tolerance, value, otherValue float64) {
+		channel := diff.Channels[name]
+		if difference > tolerance {
+			channel.Differing++
+			differing++
+			if len(diff.Differences) < maxListedDifferences {
+				diff.Differences = append(diff.Differences, SampleDifference{
+					Index: i, TimeSeconds: a.times[i], Channel: name,
+					Value: valuePointer(value), OtherValue: valuePointer(otherValue),
+				})
+			}
+		}
+		// Infinite differences of missing values are reported as differing samples only, as JSON
+		// cannot encode them
+		if !math.IsInf(difference, 1) {
+			channel.MaxDifference = math.Max(channel.MaxDifference, difference)
+		}
+		diff.Channels[name] = channel
+	}
+
+	for i := 0; i < diff.Compared; i++ {
+		record(i, "time", math.Abs(a.times[i]-b.times[i]), tolerances.TimeSeconds, a.times[i], b.times[i])
+		if a.latitudes != nil {
+			distance := calculateDistanceNM(a.latitudes[i], a.longitudes[i], b.latitudes[i], b.longitudes[i]) * metersPerNauticalMile
+			record(i, "distance", distance, tolerances.DistanceMeters, math.NaN(), math.NaN())
+		}
+		for c, channel := range a.channels {
+			value, otherValue := channel.values[i], b.channels[c].values[i]
+			record(i, channel.name, diffValue(value, otherValue), tolerances.Value, value, otherValue)
+		}
+	}
+
+	diff.Identical = differing == 0 && diff.Samples == diff.OtherSamples
+	return diff
+}
+
+// aircraftSeries loads the tables of an aircraft for comparison
+func aircraftSeries(aircraftID int) (map[string]diffSeries, error) {
+	positions, err := getPositionDataWithAirspeedFromMainDB(aircraftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get position data for aircraft %d: %w", aircraftID, err)
+	}
+	attitudes, err := getAttitudeDataFromMainDB(aircraftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attitude data for aircraft %d: %w", aircraftID, err)
+	}
+	engines, err := getEngineDataFromMainDB(aircraftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get engine data for aircraft %d: %w", aircraftID, err)
+	}
+	states, err := getAircraftStateFromMainDB(aircraftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aircraft state data for aircraft %d: %w", aircraftID, err)
+	}
+
+	return map[string]diffSeries{
+		"position": positionSeries(positions),
+		"attitude": attitudeSeries(attitudes),
+		"engine":   engineSeries(engines),
+		"state":    stateSeries(states),
+	}, nil
+}
+
+// diffFlights compares the samples of a flight from start to end seconds (end < 0 for no end) with
+// those of another flight. Aircraft are paired in order and each table's times are relative to its
+// first sample, so a trimmed copy compared with the trim range of its source is identical.
+func diffFlights(flightID, otherFlightID int, start, end float64, tolerances DiffTolerances) (*FlightDiff, error) {
+	aircraft, err := getAircraftByFlightIDFromMainDB(flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aircraft of flight %d: %w", flightID, err)
+	}
+	otherAircraft, err := getAircraftByFlightIDFromMainDB(otherFlightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aircraft of flight %d: %w", otherFlightID, err)
+	}
+
+	diff := &FlightDiff{
+		FlightID:          flightID,
+		OtherFlightID:     otherFlightID,
+		StartTime:         start,
+		Tolerances:        tolerances,
+		Aircraft:          []AircraftDiff{},
+		UnmatchedAircraft: []string{},
+		Identical:         len(aircraft) == len(otherAircraft),
+	}
+	if end >= 0 {
+		diff.EndTime = &end
+	}
+
+	for i := 0; i < min(len(aircraft), len(otherAircraft)); i++ {
+		series, err := aircraftSeries(aircraft[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		otherSeries, err := aircraftSeries(otherAircraft[i].ID)
+		if err != nil {
+			return nil, err
+		}
+
+		aircraftDiff := AircraftDiff{
+			Aircraft:      aircraft[i].Label(),
+			OtherAircraft: otherAircraft[i].Label(),
+			Tables:        map[string]TableDiff{},
+		}
+		for table, s := range series {
+			tableDiff := compareSeries(s.within(start, end), otherSeries[table], tolerances)
+			aircraftDiff.Tables[table] = tableDiff
+			diff.Identical = diff.Identical && tableDiff.Identical
+		}
+		diff.Aircraft = append(diff.Aircraft, aircraftDiff)
+	}
+	for _, ac := range aircraft[min(len(aircraft), len(otherAircraft)):] {
+		diff.UnmatchedAircraft = append(diff.UnmatchedAircraft, ac.Label())
+	}
+	for _, ac := range otherAircraft[min(len(aircraft), len(otherAircraft)):] {
+		diff.UnmatchedAircraft = append(diff.UnmatchedAircraft, ac.Label())
+	}
+	return diff, nil
+}
+
+// parseDiffParameter reads a non-negative number from the query, or returns the default
+func parseDiffParameter(r *http.Request, name string, def float64) (float64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(f >= 0) || math.IsInf(f, 1) {
+		return 0, fmt.Errorf("invalid %s '%s' (expected a number of 0 or more)", name, value)
+	}
+	return f, nil
+}
+
+// handleGetFlightDiff compares a flight sample by sample with the flight given by other, e.g. to
+// check a trimmed or re-imported copy against its source before using it
+func handleGetFlightDiff(w http.ResponseWriter, r *http.Request, flightId int) {
+	otherFlightID, err := strconv.Atoi(r.URL.Query().Get("other"))
+	if err != nil || otherFlightID <= 0 {
+		httpError(w, "Invalid or missing other flight ID", http.StatusBadRequest)
+		return
+	}
+	var exists int
+	err = mainDB.QueryRow("SELECT 1 FROM flight WHERE id = ?", otherFlightID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		httpError(w, fmt.Sprintf("Flight %d not found", otherFlightID), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to look up flight: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var tolerances DiffTolerances
+	start, err := parseDiffParameter(r, "start_time", 0)
+	if err == nil {
+		tolerances.TimeSeconds, err = parseDiffParameter(r, "time_tolerance", defaultDiffTimeTolerance)
+	}
+	if err == nil {
+		tolerances.Value, err = parseDiffParameter(r, "value_tolerance", defaultDiffValueTolerance)
+	}
+	if err == nil {
+		tolerances.DistanceMeters, err = parseDiffParameter(r, "distance_tolerance", defaultDiffDistanceTolerance)
+	}
+	end := -1.0
+	if err == nil && r.URL.Query().Get("end_time") != "" {
+		end, err = parseDiffParameter(r, "end_time", 0)
+		if err == nil && end <= start {
+			err = fmt.Errorf("end_time must be after start_time")
+		}
+	}
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	diff, err := diffFlights(flightId, otherFlightID, start, end, tolerances)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to compare flights: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
@@ -0,0 +1,170 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// GeoJSONFeatureCollection is a GeoJSON (RFC 7946) feature collection
+type GeoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature is a GeoJSON feature with a point or line string geometry
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   GeoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONGeometry holds the coordinates of a point ([lon, lat, alt]) or line string ([][lon, lat, alt])
+type GeoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// buildTrackGeoJSON returns one LineString per aircraft and one Point per marker, placed on the
+// track of the marker aircraft at the marker time. Altitudes are in the units of the flight data.
+func buildTrackGeoJSON(flightData *FlightData, markers []Marker, markerAircraft string) GeoJSONFeatureCollection {
+	collection := GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []GeoJSONFeature{}}
+
+	labels := make([]string, 0, len(flightData.PositionData))
+	for label := range flightData.PositionData {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
+		var coordinates [][]float64
+		var start, end float64
+		for _, pos := range flightData.PositionData[label] {
+			if pos.Latitude == 0 && pos.Longitude == 0 {
+				continue // Skip invalid coordinates
+			}
+			if coordinates == nil {
+				start = pos.TimestampSeconds
+			}
+			end = pos.TimestampSeconds
+			coordinates = append(coordinates, []float64{pos.Longitude, pos.Latitude, pos.Altitude})
+		}
+		// A line string needs at least two positions
+		if len(coordinates) < 2 {
+			continue
+		}
+
+		collection.Features = append(collection.Features, GeoJSONFeature{
+			Type:     "Feature",
+			Geometry: GeoJSONGeometry{Type: "LineString", Coordinates: coordinates},
+			Properties: map[string]interface{}{
+				"kind":          "track",
+				"flight_id":     flightData.Flight.ID,
+				"aircraft":      label,
+				"start_time":    start,
+				"end_time":      end,
+				"altitude_unit": flightData.Units.Altitude,
+			},
+		})
+	}
+
+	positions := flightData.PositionData[markerAircraft]
+	for _, marker := range markers {
+		pos, ok := interpolatePosition(positions, marker.Time)
+		if !ok {
+			continue
+		}
+		collection.Features = append(collection.Features, GeoJSONFeature{
+			Type:     "Feature",
+			Geometry: GeoJSONGeometry{Type: "Point", Coordinates: []float64{pos.Longitude, pos.Latitude, pos.Altitude}},
+			Properties: map[string]interface{}{
+				"kind":            "marker",
+				"flight_id":       flightData.Flight.ID,
+				"marker_id":       marker.ID,
+				"label":           marker.Label,
+				"marker_type":     marker.Type,
+				"marker_category": marker.Category,
+				"color":           marker.Color,
+				"time":            marker.Time,
+				"aircraft":        markerAircraft,
+			},
+		})
+	}
+
+	return collection
+}
+
+// interpolatePosition linearly interpolates the position at the given time (seconds since the first
+// sample); times outside the recorded range have no position
+func interpolatePosition(positions []PositionPoint, t float64) (PositionPoint, bool) {
+	var valid []PositionPoint
+	for _, pos := range positions {
+		if pos.Latitude != 0 || pos.Longitude != 0 {
+			valid = append(valid, pos)
+		}
+	}
+	if len(valid) == 0 || t < valid[0].TimestampSeconds || t > valid[len(valid)-1].TimestampSeconds {
+		return PositionPoint{}, false
+	}
+
+	i := sort.Search(len(valid), func(i int) bool { return valid[i].TimestampSeconds >= t })
+	if valid[i].TimestampSeconds == t || i == 0 {
+		return valid[i], true
+	}
+
+	prev, next := valid[i-1], valid[i]
+	ratio := (t - prev.TimestampSeconds) / (next.TimestampSeconds - prev.TimestampSeconds)
+	return PositionPoint{
+		TimestampSeconds: t,
+		Latitude:         prev.Latitude + ratio*(next.Latitude-prev.Latitude),
+		Longitude:        prev.Longitude + ratio*(next.Longitude-prev.Longitude),
+		Altitude:         prev.Altitude + ratio*(next.Altitude-prev.Altitude),
+	}, true
+}
+
+// handleTrackGeoJSON returns the flight's position data as GeoJSON for map rendering. Markers are placed on
+// the track of the aircraft given by the aircraft query parameter, defaulting to the user aircraft.
+func handleTrackGeoJSON(w http.ResponseWriter, r *http.Request, flightId int) {
+	flightData, err := getFlightDataFromMainDB(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	markers, err := getMarkersForFlight(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get markers: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	markerAircraft := r.URL.Query().Get("aircraft")
+	if markerAircraft == "" {
+		markerAircraft, err = getUserAircraftLabel(flightId)
+		if err != nil && err != sql.ErrNoRows {
+			httpError(w, fmt.Sprintf("Failed to get user aircraft: %v", err), http.StatusInternalServerError)
+			return
+		}
+	} else if _, ok := flightData.PositionData[markerAircraft]; !ok {
+		httpError(w, fmt.Sprintf("Aircraft '%s' has no position data in flight %d", markerAircraft, flightId), http.StatusNotFound)
+		return
+	}
+
+	configuredUnits().convertFlightData(flightData)
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(buildTrackGeoJSON(flightData, markers, markerAircraft))
+}
+
+// handleTrackGeoJSONQuery serves the track for the flight given by the flightId query parameter, for
+// map tools that cannot build path-based URLs
+func handleTrackGeoJSONQuery(w http.ResponseWriter, r *http.Request) {
+	r.SetPathValue("id", r.URL.Query().Get("flightId"))
+	if _, err := strconv.Atoi(r.PathValue("id")); err != nil {
+		httpError(w, "Missing or invalid flightId parameter", http.StatusBadRequest)
+		return
+	}
+	withFlightID(handleTrackGeoJSON)(w, r)
+}
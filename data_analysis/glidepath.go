@@ -0,0 +1,181 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+)
+
+// GlidepathPoint is the height of one approach sample against the glidepath of a runway
+type GlidepathPoint struct {
+	Time              float64 `json:"time"`
+	DistanceNM        float64 `json:"distance_nm"` // From the threshold along the extended centerline
+	HeightFt          float64 `json:"height_ft"`   // Above the threshold
+	GlidepathHeightFt float64 `json:"glidepath_height_ft"`
+	DeviationFt       float64 `json:"deviation_ft"` // Positive above the glidepath
+}
+
+// GlidepathApproach holds the deviation from the glidepath over one approach
+type GlidepathApproach struct {
+	StartTime        float64          `json:"start_time"`
+	EndTime          float64          `json:"end_time"`
+	ReachedThreshold bool             `json:"reached_threshold"`
+	DeviationStats   *DataStatistics  `json:"deviation_stats"` // Of deviation_ft, nil without altitudes
+	Points           []GlidepathPoint `json:"points"`
+}
+
+// AircraftGlidepath holds the deviation from the glidepath of one aircraft over all its approaches
+// and per approach
+type AircraftGlidepath struct {
+	DeviationStats *DataStatistics     `json:"deviation_stats"` // nil without approaches
+	Approaches     []GlidepathApproach `json:"approaches"`
+}
+
+// GlidepathReport holds the glidepath deviation of a flight's aircraft on the final approach to a runway
+type GlidepathReport struct {
+	FlightID int                           `json:"flight_id"`
+	Runway   Runway                        `json:"runway"`
+	GateNM   float64                       `json:"gate_nm"` // Distance from the threshold the final approach starts at
+	Aircraft map[string]*AircraftGlidepath `json:"aircraft"`
+}
+
+// GlidepathDeviation summarizes the deviation from the glidepath of a runway in the flight statistics
+type GlidepathDeviation struct {
+	RunwayID       int             `json:"runway_id"`
+	Runway         string          `json:"runway"`
+	GlidepathDeg   float64         `json:"glidepath_deg"`
+	Approaches     int             `json:"approaches"`
+	DeviationStats *DataStatistics `json:"deviation_stats"` // Height above the glidepath, nil without approaches
+}
+
+// glidepathHeightFt returns the height of the glidepath of a runway above its threshold at a distance
+// before it; the glidepath reaches the threshold at its elevation
+func (rw *Runway) glidepathHeightFt(distanceNM float64) float64 {
+	return distanceNM * feetPerNauticalMile * math.Tan(rw.GlidepathDeg*math.Pi/180)
+}
+
+// calculateGlidepath compares the altitude of one aircraft with the glidepath of a runway over its
+// approaches within gateNM of the threshold
+func calculateGlidepath(series *SeriesColumns, rw *Runway, gateNM float64) *AircraftGlidepath {
+	result := &AircraftGlidepath{Approaches: []GlidepathApproach{}}
+
+	var all []float64
+	for _, segment := range findApproaches(series, rw, gateNM) {
+		approach := GlidepathApproach{
+			StartTime:        series.Time[segment.start],
+			EndTime:          series.Time[segment.end],
+			ReachedThreshold: segment.reachedThreshold,
+			Points:           []GlidepathPoint{},
+		}
+		var deviations []float64
+		for i := segment.start; i <= segment.end; i++ {
+			if math.IsNaN(series.Altitude[i]) {
+				continue
+			}
+			point := GlidepathPoint{
+				Time:              series.Time[i],
+				DistanceNM:        segment.distances[i],
+				HeightFt:          series.Altitude[i] - rw.ElevationFt,
+				GlidepathHeightFt: rw.glidepathHeightFt(segment.distances[i]),
+			}
+			point.DeviationFt = point.HeightFt - point.GlidepathHeightFt
+			approach.Points = append(approach.Points, point)
+			deviations = append(deviations, point.DeviationFt)
+		}
+		approach.DeviationStats = calculateDataStatistics(deviations)
+		result.Approaches = append(result.Approaches, approach)
+		all = append(all, deviations...)
+	}
+	result.DeviationStats = calculateDataStatistics(all)
+	return result
+}
+
+// statisticsRunway returns the runway of the glidepath deviation in the flight statistics: that of the
+// runway parameter, else the configured target runway, nil if neither is set. On failure it writes the
+// error response and returns false.
+func statisticsRunway(w http.ResponseWriter, r *http.Request) (*Runway, bool) {
+	if value := r.URL.Query().Get("runway"); value != "" {
+		return lookupRunway(w, value)
+	}
+	if analysisConfig.TargetRunway == "" {
+		return nil, true
+	}
+
+	rw, err := getRunwayByName(analysisConfig.TargetRunway)
+	if err == sql.ErrNoRows {
+		// A configured runway that is not in the library leaves the glidepath out rather than failing
+		log.Printf("Target runway '%s' is not in the runway library", analysisConfig.TargetRunway)
+		return nil, true
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get target runway: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return rw, true
+}
+
+// addGlidepathDeviation sets the glidepath deviation of each aircraft's statistics on the final approach
+// to a runway
+func addGlidepathDeviation(flightID int, statistics map[string]*FlightStatistics, rw *Runway) error {
+	columns, err := getFlightColumns(flightID)
+	if err != nil {
+		return fmt.Errorf("failed to get flight data: %w", err)
+	}
+
+	for label, stats := range statistics {
+		series, exists := columns[label]
+		if stats == nil || !exists {
+			continue
+		}
+		glidepath := calculateGlidepath(series, rw, analysisConfig.ApproachGateNM)
+		stats.Glidepath = &GlidepathDeviation{
+			RunwayID:       rw.ID,
+			Runway:         rw.Name,
+			GlidepathDeg:   rw.GlidepathDeg,
+			Approaches:     len(glidepath.Approaches),
+			DeviationStats: glidepath.DeviationStats,
+		}
+	}
+	return nil
+}
+
+// handleGetGlidepath computes the vertical deviation of the target aircraft from the glidepath of a
+// runway over its final approaches, or of every aircraft with aircraft=all
+func handleGetGlidepath(w http.ResponseWriter, r *http.Request, flightId int) {
+	if r.URL.Query().Get("runway") == "" {
+		httpError(w, "runway is required", http.StatusBadRequest)
+		return
+	}
+	rw, ok := lookupRunway(w, r.URL.Query().Get("runway"))
+	if !ok {
+		return
+	}
+
+	columns, err := getFlightColumns(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	aircraft := map[string]*AircraftGlidepath{}
+	for label, series := range columns {
+		aircraft[label] = calculateGlidepath(series, rw, analysisConfig.ApproachGateNM)
+	}
+	if !allAircraftRequested(r) {
+		if aircraft, err = targetAircraftOnly(flightId, aircraft); err != nil {
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GlidepathReport{
+		FlightID: flightId,
+		Runway:   *rw,
+		GateNM:   analysisConfig.ApproachGateNM,
+		Aircraft: aircraft,
+	})
+}
@@ -0,0 +1,50 @@
+package data_analysis
+
+import (
+	"math"
+)
+
+// minTrackGroundSpeed is the ground speed in knots below which the ground track is not updated, since
+// the direction of a near-stationary aircraft is dominated by position noise
+const minTrackGroundSpeed = 2.0
+
+// deriveGroundSpeedAndTrack fills the ground speed and track of every sample from the position change
+// across a window of at least minGroundVelocityInterval around it. The stored velocity components are
+// not used, as Sky Dolly records them in the aircraft body frame.
+func deriveGroundSpeedAndTrack(positions []PositionPoint) {
+	n := len(positions)
+	halfWindow := minGroundVelocityInterval / 2
+	track := 0.0
+
+	lo, hi := 0, 0
+	for i := range positions {
+		if positions[i].Latitude == 0 && positions[i].Longitude == 0 {
+			continue
+		}
+
+		// Widen the window to at least half the interval on each side of the sample
+		for lo < i && positions[i].TimestampSeconds-positions[lo+1].TimestampSeconds >= halfWindow {
+			lo++
+		}
+		if hi < i {
+			hi = i
+		}
+		for hi < n-1 && positions[hi].TimestampSeconds-positions[i].TimestampSeconds < halfWindow {
+			hi++
+		}
+
+		from, to := positions[lo], positions[hi]
+		if to.TimestampSeconds-from.TimestampSeconds <= 0 ||
+			(from.Latitude == 0 && from.Longitude == 0) || (to.Latitude == 0 && to.Longitude == 0) {
+			positions[i].GroundTrack = track
+			continue
+		}
+
+		east, north := groundVelocity(from, to)
+		positions[i].GroundSpeed = math.Hypot(east, north)
+		if positions[i].GroundSpeed >= minTrackGroundSpeed {
+			track = math.Mod(math.Atan2(east, north)*180/math.Pi+360, 360)
+		}
+		positions[i].GroundTrack = track
+	}
+}
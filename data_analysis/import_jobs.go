@@ -0,0 +1,313 @@
+package data_analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// importJobExpiry is how long finished import jobs are kept for their status to be read
+const importJobExpiry = 24 * time.Hour
+
+// Statuses of an import job
+const (
+	importJobQueued    = "queued"
+	importJobRunning   = "running"
+	importJobCompleted = "completed"
+	importJobFailed    = "failed"
+	importJobCancelled = "cancelled"
+)
+
+// ImportJob is a recording waiting for or being imported by the background import worker
+type ImportJob struct {
+	ID         string            `json:"id"`
+	Filename   string            `json:"filename"`
+	Status     string            `json:"status"`           // "queued", "running", "completed", "failed" or "cancelled"
+	Result     *UploadFileResult `json:"result,omitempty"` // Set once the job finished
+	CreatedAt  time.Time         `json:"created_at"`
+	StartedAt  *time.Time        `json:"started_at,omitempty"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// ImportSummary combines the results of the files imported together
+type ImportSummary struct {
+	Status  string             `json:"status"` // "success", "partial" when some records or files failed to import, "skipped" when every file was imported before, or "failed"
+	Message string             `json:"message"`
+	Flights []Flight           `json:"flights"`
+	Errors  []ImportError      `json:"errors"`
+	Files   []UploadFileResult `json:"files"` // Outcome per file
+}
+
+// ImportStatus reports import jobs; the summary is given for the jobs selected by ID once none is pending
+type ImportStatus struct {
+	Jobs    []ImportJob    `json:"jobs"`
+	Pending int            `json:"pending"` // Jobs queued or running
+	Summary *ImportSummary `json:"summary,omitempty"`
+}
+
+// importJob is an import job with the recording it imports, which it removes once finished
+type importJob struct {
+	ImportJob
+	path    string
+	options ImportOptions
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{} // Closed when the job finished
+}
+
+var (
+	importJobs     = map[string]*importJob{}
+	importQueue    []*importJob // Queued jobs in the order they are imported
+	importJobMutex = &sync.Mutex{}
+	// importJobAdded wakes the worker when a job is queued
+	importJobAdded = make(chan struct{}, 1)
+)
+
+// pending reports whether the job is yet to finish. Must be called with importJobMutex held.
+func (job *importJob) pending() bool {
+	return job.Status == importJobQueued || job.Status == importJobRunning
+}
+
+// finish records the outcome of the job and removes its recording. Must be called with importJobMutex held.
+func (job *importJob) finish(status string, result UploadFileResult) {
+	os.Remove(job.path)
+	now := time.Now()
+	job.Status = status
+	job.Result = &result
+	job.FinishedAt = &now
+	job.cancel()
+	close(job.done)
+}
+
+// SummarizeImport combines the results of files imported together into one status and message
+func SummarizeImport(results []UploadFileResult) ImportSummary {
+	summary := ImportSummary{Status: "success", Flights: []Flight{}, Errors: []ImportError{}, Files: results}
+	if len(results) == 0 {
+		summary.Message = "No files imported"
+		return summary
+	}
+
+	failedFiles := 0
+	partialFiles := 0
+	skippedFiles := 0
+	for _, result := range results {
+		summary.Flights = append(summary.Flights, result.Flights...)
+		summary.Errors = append(summary.Errors, result.Errors...)
+		switch result.Status {
+		case "failed":
+			failedFiles++
+		case "partial":
+			partialFiles++
+		case "skipped":
+			skippedFiles++
+		}
+	}
+
+	summary.Message = results[0].Message
+	if len(results) > 1 {
+		summary.Message = fmt.Sprintf("Imported %d flights from %d files", len(summary.Flights), len(results)-failedFiles-skippedFiles)
+		if skippedFiles > 0 {
+			summary.Message += fmt.Sprintf(", %d files were imported before", skippedFiles)
+		}
+		if failedFiles > 0 {
+			summary.Message += fmt.Sprintf(", %d files failed", failedFiles)
+		}
+	}
+	if failedFiles == len(results) {
+		summary.Status = "failed"
+	} else if skippedFiles == len(results) {
+		summary.Status = "skipped"
+	} else if failedFiles > 0 || partialFiles > 0 {
+		summary.Status = "partial"
+	}
+	return summary
+}
+
+// enqueueImport queues the import of a recording saved at path; the job removes the file once finished
+func enqueueImport(path, filename string, options ImportOptions) (*importJob, error) {
+	id, err := newRandomID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &importJob{
+		ImportJob: ImportJob{ID: id, Filename: filename, Status: importJobQueued, CreatedAt: time.Now()},
+		path:      path,
+		options:   options,
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	importJobMutex.Lock()
+	removeFinishedImportJobs(job.CreatedAt)
+	importJobs[id] = job
+	importQueue = append(importQueue, job)
+	importJobMutex.Unlock()
+
+	select {
+	case importJobAdded <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// removeFinishedImportJobs forgets jobs that finished more than importJobExpiry ago.
+// Must be called with importJobMutex held.
+func removeFinishedImportJobs(now time.Time) {
+	for id, job := range importJobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > importJobExpiry {
+			delete(importJobs, id)
+		}
+	}
+}
+
+// startImportWorker imports queued recordings one at a time, so uploads return at once and imports do
+// not compete for the database
+func startImportWorker() {
+	for range importJobAdded {
+		for job := nextImportJob(); job != nil; job = nextImportJob() {
+			runImportJob(job)
+		}
+	}
+}
+
+// nextImportJob takes the first queued job and marks it running, nil if none is queued
+func nextImportJob() *importJob {
+	importJobMutex.Lock()
+	defer importJobMutex.Unlock()
+
+	if len(importQueue) == 0 {
+		return nil
+	}
+	job := importQueue[0]
+	importQueue = importQueue[1:]
+	now := time.Now()
+	job.Status = importJobRunning
+	job.StartedAt = &now
+	return job
+}
+
+// runImportJob imports the recording of a job; a job cancelled while running is rolled back
+func runImportJob(job *importJob) {
+	log.Printf("Import job %s: importing %s", job.ID, job.Filename)
+	result := importFile(job.ctx, job.path, job.Filename, job.options)
+
+	status := importJobCompleted
+	switch {
+	case result.Status == "failed" && job.ctx.Err() != nil:
+		status = importJobCancelled
+		result = failedImport(job.Filename, "Import cancelled")
+	case result.Status == "failed":
+		status = importJobFailed
+	}
+	log.Printf("Import job %s: %s", job.ID, result.Message)
+
+	importJobMutex.Lock()
+	job.finish(status, result)
+	importJobMutex.Unlock()
+}
+
+// waitForImport blocks until a job finished and returns its result
+func waitForImport(job *importJob) UploadFileResult {
+	<-job.done
+	importJobMutex.Lock()
+	defer importJobMutex.Unlock()
+	return *job.Result
+}
+
+// handleGetImportStatus reports all import jobs, or those listed in the ids parameter together with their
+// combined result once they all finished
+func handleGetImportStatus(w http.ResponseWriter, r *http.Request) {
+	importJobMutex.Lock()
+	var jobs []*importJob
+	if value := r.URL.Query().Get("ids"); value != "" {
+		for _, id := range strings.Split(value, ",") {
+			job, exists := importJobs[strings.TrimSpace(id)]
+			if !exists {
+				importJobMutex.Unlock()
+				httpError(w, fmt.Sprintf("Import job %s not found", id), http.StatusNotFound)
+				return
+			}
+			jobs = append(jobs, job)
+		}
+	} else {
+		for _, job := range importJobs {
+			jobs = append(jobs, job)
+		}
+		slices.SortFunc(jobs, func(a, b *importJob) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	}
+
+	status := ImportStatus{Jobs: make([]ImportJob, 0, len(jobs))}
+	results := make([]UploadFileResult, 0, len(jobs))
+	for _, job := range jobs {
+		status.Jobs = append(status.Jobs, job.ImportJob)
+		if job.pending() {
+			status.Pending++
+		} else {
+			results = append(results, *job.Result)
+		}
+	}
+	importJobMutex.Unlock()
+
+	if r.URL.Query().Has("ids") && status.Pending == 0 {
+		summary := SummarizeImport(results)
+		status.Summary = &summary
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleGetImportJob reports one import job
+func handleGetImportJob(w http.ResponseWriter, r *http.Request) {
+	importJobMutex.Lock()
+	job, exists := importJobs[r.PathValue("jobId")]
+	if !exists {
+		importJobMutex.Unlock()
+		httpError(w, "Import job not found", http.StatusNotFound)
+		return
+	}
+	state := job.ImportJob
+	importJobMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// handleCancelImportJob cancels an import job. A queued job is dropped; a running job is rolled back,
+// which it reports as cancelled once it stopped, unless its flights were already stored.
+func handleCancelImportJob(w http.ResponseWriter, r *http.Request) {
+	importJobMutex.Lock()
+	job, exists := importJobs[r.PathValue("jobId")]
+	if !exists {
+		importJobMutex.Unlock()
+		httpError(w, "Import job not found", http.StatusNotFound)
+		return
+	}
+	switch job.Status {
+	case importJobQueued:
+		importQueue = slices.DeleteFunc(importQueue, func(queued *importJob) bool { return queued == job })
+		job.finish(importJobCancelled, failedImport(job.Filename, "Import cancelled"))
+	case importJobRunning:
+		job.cancel()
+	default:
+		status := job.Status
+		importJobMutex.Unlock()
+		httpError(w, fmt.Sprintf("Import job is %s", status), http.StatusConflict)
+		return
+	}
+	state := job.ImportJob
+	importJobMutex.Unlock()
+	log.Printf("Import job %s: cancelled", state.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
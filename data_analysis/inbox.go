@@ -0,0 +1,195 @@
+package data_analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+)
+
+// inboxDir is watched for recordings placed there by the sim PC's sync tool. Imported files are moved
+// to its imported folder, files that fail to import to its failed folder.
+var inboxDir = filepath.Join("data", "inbox")
+
+const (
+	inboxPollInterval = 5 * time.Second
+	urlImportTimeout  = 10 * time.Minute
+)
+
+// inboxFileState is the size and modification time of an inbox file at the last poll
+type inboxFileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// startInboxWatcher imports new recordings from the inbox until the process exits
+func startInboxWatcher() {
+	for _, dir := range []string{inboxDir, filepath.Join(inboxDir, "imported"), filepath.Join(inboxDir, "failed")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Printf("Failed to create inbox directory %s: %v", dir, err)
+			return
+		}
+	}
+	log.Printf("Watching %s for recordings to import", inboxDir)
+
+	seen := map[string]inboxFileState{}
+	ticker := time.NewTicker(inboxPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		// Recordings arriving while the dataset is frozen wait in the inbox
+		if readOnly.Load() {
+			continue
+		}
+		scanInbox(seen)
+	}
+}
+
+// scanInbox imports the recordings in the inbox whose size and modification time did not change since
+// the last poll, so files still being synced are left alone
+func scanInbox(seen map[string]inboxFileState) {
+	entries, err := os.ReadDir(inboxDir)
+	if err != nil {
+		log.Printf("Failed to read inbox: %v", err)
+		return
+	}
+
+	present := map[string]bool{}
+	for _, entry := range entries {
+		name := entry.Name()
+		// Sync tools write partial files under hidden names
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !isSupportedUploadFile(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		present[name] = true
+
+		state := inboxFileState{size: info.Size(), modTime: info.ModTime()}
+		if previous, ok := seen[name]; !ok || previous != state {
+			seen[name] = state
+			continue
+		}
+
+		delete(seen, name)
+		importInboxFile(name)
+	}
+
+	for name := range seen {
+		if !present[name] {
+			delete(seen, name)
+		}
+	}
+}
+
+// importInboxFile imports a recording from the inbox and moves it out of the way
+func importInboxFile(name string) {
+	source := filepath.Join(inboxDir, name)
+	result := importFile(context.Background(), source, name, ImportOptions{})
+	logImportEvent(result)
+
+	folder := "imported"
+	if result.Status == "failed" {
+		folder = "failed"
+		log.Printf("Failed to import %s from inbox: %s", name, result.Message)
+	} else {
+		log.Printf("Inbox: %s", result.Message)
+	}
+
+	// Prefix the time so a recording synced again under the same name does not overwrite the earlier one
+	target := filepath.Join(inboxDir, folder, time.Now().Format("20060102_150405")+"_"+name)
+	if err := os.Rename(source, target); err != nil {
+		log.Printf("Failed to move %s out of the inbox: %v", name, err)
+	}
+}
+
+// logImportEvent records the outcome of an automatic import in the event log
+func logImportEvent(result UploadFileResult) {
+	event := events.Event{
+		Type:      "flight_imported",
+		Program:   fmt.Sprintf("%s - %d flights", result.Filename, len(result.Flights)),
+		Timestamp: time.Now(),
+	}
+	if result.Status == "failed" {
+		event.Type = "flight_import_failed"
+		event.Program = result.Filename
+	}
+	events.LogEvent(event)
+}
+
+// downloadRecording downloads a recording to the temp directory, returning its path and filename
+func downloadRecording(rawURL string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", fmt.Errorf("invalid URL '%s', expected an http or https URL", rawURL)
+	}
+	filename := path.Base(u.Path)
+	if !isSupportedUploadFile(filename) {
+		return "", "", fmt.Errorf("URL does not point to a SQLite database (.sdlog, .sqlite, .db) or CSV file (.csv)")
+	}
+
+	client := &http.Client{Timeout: urlImportTimeout}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return "", "", fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to download %s: %s", rawURL, resp.Status)
+	}
+
+	tempPath := filepath.Join(tempDir, fmt.Sprintf("downloaded_%s_%s", time.Now().Format("20060102_150405"), filename))
+	dst, err := os.Create(tempPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to save download: %w", err)
+	}
+	_, err = io.Copy(dst, resp.Body)
+	dst.Close()
+	if err != nil {
+		os.Remove(tempPath)
+		return "", "", fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	return tempPath, filename, nil
+}
+
+// handleImportURL downloads a recording from a URL and imports it
+func handleImportURL(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		URL     string `json:"url"`
+		Partial bool   `json:"partial"`
+		Force   bool   `json:"force"` // Import flights that were imported before
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	tempPath, filename, err := downloadRecording(strings.TrimSpace(request.URL))
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer os.Remove(tempPath)
+
+	result := importFile(context.Background(), tempPath, filename, ImportOptions{Partial: request.Partial, Force: request.Force})
+	logImportEvent(result)
+	if result.Status == "failed" {
+		httpError(w, result.Message, http.StatusBadRequest)
+		return
+	}
+	log.Printf("Imported %d flights from %s", len(result.Flights), request.URL)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
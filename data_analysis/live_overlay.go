@@ -0,0 +1,222 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/kaireichart/master-thesis-operator-station/gps"
+)
+
+// maxOverlayAheadSeconds limits how far past the latest live position the reference flight is served
+const maxOverlayAheadSeconds = 600
+
+// OverlaySample is a sample of the live recording or of the reference flight on the live timeline
+type OverlaySample struct {
+	Time          float64  `json:"time"` // Seconds since the first recorded position, without session pauses
+	Latitude      float64  `json:"latitude"`
+	Longitude     float64  `json:"longitude"`
+	AltitudeFt    float64  `json:"altitude_ft"`
+	GroundSpeedKt float64  `json:"ground_speed_kt"`
+	AirspeedKt    *float64 `json:"airspeed_kt,omitempty"` // Reference only; fs2ff sends no airspeed
+}
+
+// OverlayDeviation is the latest live sample compared with the reference flight at the same time
+type OverlayDeviation struct {
+	Time          float64 `json:"time"`
+	AltitudeFt    float64 `json:"altitude_ft"`     // Live minus reference
+	GroundSpeedKt float64 `json:"ground_speed_kt"` // Live minus reference
+	DistanceNM    float64 `json:"distance_nm"`     // Between the live and the reference position
+}
+
+// LiveOverlay is a reference flight resampled to the timeline of the session being recorded, so the
+// participant's current performance can be overlaid on a baseline run
+type LiveOverlay struct {
+	FlightID      int               `json:"flight_id"`
+	Aircraft      string            `json:"aircraft"` // Target aircraft of the reference flight
+	Alignment     *FlightAlignment  `json:"alignment"`
+	OffsetSeconds float64           `json:"offset_seconds"` // Reference flight time at live time 0
+	SessionID     int               `json:"session_id"`
+	ParticipantID string            `json:"participant_id"`
+	Active        bool              `json:"active"`       // The session is still recording
+	LiveSeconds   float64           `json:"live_seconds"` // Live time of the latest position
+	Live          []OverlaySample   `json:"live"`
+	Reference     []OverlaySample   `json:"reference"` // At the times of the live samples, then every second up to ahead
+	Deviation     *OverlayDeviation `json:"deviation,omitempty"`
+}
+
+// getUserAircraftID returns the ID of the aircraft flown by the participant, falling back to the first
+// aircraft like getUserAircraftLabel
+func getUserAircraftID(flightID int) (int, string, error) {
+	query := `
+		SELECT a.id, a.type, a.tail_number
+		FROM aircraft a
+		JOIN flight f ON f.id = a.flight_id
+		WHERE a.flight_id = ?
+		ORDER BY (a.seq_nr = f.user_aircraft_seq_nr) DESC, a.seq_nr
+		LIMIT 1
+	`
+
+	var id int
+	var ac Aircraft
+	var tailNumber sql.NullString
+	if err := mainDB.QueryRow(query, flightID).Scan(&id, &ac.Type, &tailNumber); err != nil {
+		return 0, "", err
+	}
+	ac.TailNumber = tailNumber.String
+	return id, ac.Label(), nil
+}
+
+// referenceSampleAt interpolates the reference positions at a flight time, false outside the flight
+func referenceSampleAt(positions []PositionPoint, t float64) (OverlaySample, bool) {
+	i := sort.Search(len(positions), func(i int) bool { return positions[i].TimestampSeconds >= t })
+	switch {
+	case i == len(positions):
+		return OverlaySample{}, false
+	case positions[i].TimestampSeconds == t:
+		return overlaySampleOf(positions[i]), true
+	case i == 0:
+		return OverlaySample{}, false
+	}
+	before, after := positions[i-1], positions[i]
+	ratio := (t - before.TimestampSeconds) / (after.TimestampSeconds - before.TimestampSeconds)
+	lerp := func(a, b float64) float64 { return a + (b-a)*ratio }
+	airspeed := lerp(before.Airspeed, after.Airspeed)
+	return OverlaySample{
+		Latitude:      lerp(before.Latitude, after.Latitude),
+		Longitude:     lerp(before.Longitude, after.Longitude),
+		AltitudeFt:    lerp(before.Altitude, after.Altitude),
+		GroundSpeedKt: lerp(before.GroundSpeed, after.GroundSpeed),
+		AirspeedKt:    &airspeed,
+	}, true
+}
+
+// overlaySampleOf converts a stored position sample
+func overlaySampleOf(p PositionPoint) OverlaySample {
+	airspeed := p.Airspeed
+	return OverlaySample{
+		Latitude:      p.Latitude,
+		Longitude:     p.Longitude,
+		AltitudeFt:    p.Altitude,
+		GroundSpeedKt: p.GroundSpeed,
+		AirspeedKt:    &airspeed,
+	}
+}
+
+// parseOverlaySeconds reads an optional seconds parameter, fallback when it is not given
+func parseOverlaySeconds(r *http.Request, name string, fallback float64) (float64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0, fmt.Errorf("invalid %s '%s' (expected seconds)", name, value)
+	}
+	return seconds, nil
+}
+
+// handleGetLiveOverlay serves a reference flight resampled to the timeline of the session being recorded.
+// The reference starts at live time 0, at its alignment marker if one is given, shifted by offset seconds.
+func handleGetLiveOverlay(w http.ResponseWriter, r *http.Request, flightId int) {
+	recording, ok := gps.GetRecording()
+	if !ok {
+		httpError(w, "No recording available (start a session to record)", http.StatusNotFound)
+		return
+	}
+
+	offset, err := parseOverlaySeconds(r, "offset", 0)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	since, err := parseOverlaySeconds(r, "since", math.Inf(-1))
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ahead, err := parseOverlaySeconds(r, "ahead", 0)
+	if err != nil || ahead < 0 || ahead > maxOverlayAheadSeconds {
+		httpError(w, fmt.Sprintf("Invalid ahead '%s' (expected 0 to %d seconds)", r.URL.Query().Get("ahead"), maxOverlayAheadSeconds), http.StatusBadRequest)
+		return
+	}
+	alignment, err := parseAlignment(r, flightId)
+	if err != nil {
+		writeAlignmentError(w, err)
+		return
+	}
+	if alignment != nil {
+		offset += alignment.OffsetSeconds
+	}
+
+	aircraftID, label, err := getUserAircraftID(flightId)
+	if err == sql.ErrNoRows {
+		httpError(w, "Flight has no aircraft", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get aircraft: %v", err), http.StatusInternalServerError)
+		return
+	}
+	positions, err := getPositionDataWithAirspeedFromMainDB(aircraftID)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get position data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	overlay := LiveOverlay{
+		FlightID:      flightId,
+		Aircraft:      label,
+		Alignment:     alignment,
+		OffsetSeconds: offset,
+		SessionID:     recording.SessionID,
+		ParticipantID: recording.ParticipantID,
+		Active:        recording.Active,
+		Live:          []OverlaySample{},
+		Reference:     []OverlaySample{},
+	}
+	for _, p := range recording.Positions {
+		t := recording.Seconds(p.Timestamp)
+		overlay.LiveSeconds = t
+		if t <= since {
+			continue
+		}
+		live := OverlaySample{
+			Time:          t,
+			Latitude:      p.Latitude,
+			Longitude:     p.Longitude,
+			AltitudeFt:    p.Altitude / 0.3048,
+			GroundSpeedKt: p.GroundSpeed,
+		}
+		overlay.Live = append(overlay.Live, live)
+		if reference, ok := referenceSampleAt(positions, t+offset); ok {
+			reference.Time = t
+			overlay.Reference = append(overlay.Reference, reference)
+		}
+	}
+	for t := math.Floor(overlay.LiveSeconds) + 1; t <= overlay.LiveSeconds+ahead; t++ {
+		if reference, ok := referenceSampleAt(positions, t+offset); ok {
+			reference.Time = t
+			overlay.Reference = append(overlay.Reference, reference)
+		}
+	}
+
+	if n := len(overlay.Live); n > 0 {
+		latest := overlay.Live[n-1]
+		if reference, ok := referenceSampleAt(positions, latest.Time+offset); ok {
+			overlay.Deviation = &OverlayDeviation{
+				Time:          latest.Time,
+				AltitudeFt:    latest.AltitudeFt - reference.AltitudeFt,
+				GroundSpeedKt: latest.GroundSpeedKt - reference.GroundSpeedKt,
+				DistanceNM:    calculateDistanceNM(latest.Latitude, latest.Longitude, reference.Latitude, reference.Longitude),
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overlay)
+}
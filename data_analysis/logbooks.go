@@ -0,0 +1,268 @@
+package data_analysis
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogbookSettings is where Sky Dolly keeps its logbooks on the sim machine
+type LogbookSettings struct {
+	Directory string `json:"directory"`
+}
+
+// Logbook is a Sky Dolly logbook found in the logbook directory
+type Logbook struct {
+	Path        string `json:"path"`
+	Name        string `json:"name"`
+	SizeBytes   int64  `json:"size_bytes"`
+	ModifiedAt  string `json:"modified_at"`
+	FlightCount int    `json:"flight_count"`
+	Error       string `json:"error,omitempty"` // Why the flights could not be counted
+}
+
+var (
+	logbookSettingsFile = filepath.Join("data", "skydolly_logbooks.json")
+	logbookSettings     LogbookSettings
+	logbookSettingsMux  = &sync.Mutex{}
+)
+
+// defaultLogbookDirectory is the folder Sky Dolly creates its logbook in
+func defaultLogbookDirectory() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "Documents", "Sky Dolly")
+}
+
+// initLogbookSettings loads the logbook directory, defaulting to Sky Dolly's own
+func initLogbookSettings() {
+	logbookSettings = LogbookSettings{Directory: defaultLogbookDirectory()}
+
+	data, err := os.ReadFile(logbookSettingsFile)
+	if err != nil {
+		return
+	}
+	var settings LogbookSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		log.Printf("Failed to load logbook settings: %v", err)
+		return
+	}
+	logbookSettings = settings
+}
+
+// getLogbookSettings returns the logbook directory setting
+func getLogbookSettings() LogbookSettings {
+	logbookSettingsMux.Lock()
+	defer logbookSettingsMux.Unlock()
+	return logbookSettings
+}
+
+// saveLogbookSettings writes the logbook settings to their file
+func saveLogbookSettings(settings LogbookSettings) error {
+	if err := os.MkdirAll(filepath.Dir(logbookSettingsFile), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := logbookSettingsFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, logbookSettingsFile)
+}
+
+// findLogbooks lists the .sdlog files in a directory and its subdirectories, newest first
+func findLogbooks(directory string) ([]Logbook, error) {
+	logbooks := []Logbook{}
+	err := filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == directory {
+				return err
+			}
+			return nil // Skip unreadable subdirectories
+		}
+		if entry.IsDir() || strings.ToLower(filepath.Ext(path)) != ".sdlog" {
+			return nil
+		}
+
+		logbook := Logbook{Path: path, Name: entry.Name()}
+		if info, err := entry.Info(); err == nil {
+			logbook.SizeBytes = info.Size()
+			logbook.ModifiedAt = info.ModTime().Format(time.RFC3339)
+		}
+		count, err := countLogbookFlights(path)
+		if err != nil {
+			logbook.Error = err.Error()
+		}
+		logbook.FlightCount = count
+		logbooks = append(logbooks, logbook)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(logbooks, func(i, j int) bool { return logbooks[i].ModifiedAt > logbooks[j].ModifiedAt })
+	return logbooks, nil
+}
+
+// countLogbookFlights counts the flights of a logbook, opened read-only so Sky Dolly can keep using it
+func countLogbookFlights(path string) (int, error) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.ToSlash(path)+"?mode=ro")
+	if err != nil {
+		return 0, fmt.Errorf("failed to open logbook: %w", err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM flight").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count flights: %w", err)
+	}
+	return count, nil
+}
+
+// copyLogbook copies a logbook and its write-ahead log to the temp directory, so importing does not
+// touch the logbook Sky Dolly works with
+func copyLogbook(path string) (string, error) {
+	tempPath := filepath.Join(tempDir, fmt.Sprintf("logbook_%s_%s", time.Now().Format("20060102_150405"), filepath.Base(path)))
+	if err := copyFile(path, tempPath); err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path + "-wal"); err == nil {
+		if err := copyFile(path+"-wal", tempPath+"-wal"); err != nil {
+			os.Remove(tempPath)
+			return "", err
+		}
+	}
+	return tempPath, nil
+}
+
+// copyFile copies the file at source to target
+func copyFile(source, target string) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", source, err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(target)
+		return fmt.Errorf("failed to copy %s: %w", source, err)
+	}
+	return dst.Close()
+}
+
+// isInDirectory reports whether path lies within directory
+func isInDirectory(path, directory string) bool {
+	rel, err := filepath.Rel(directory, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// handleGetLogbookSettings returns the logbook directory
+func handleGetLogbookSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(getLogbookSettings())
+}
+
+// handleUpdateLogbookSettings changes the logbook directory and persists it
+func handleUpdateLogbookSettings(w http.ResponseWriter, r *http.Request) {
+	var settings LogbookSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	settings.Directory = strings.TrimSpace(settings.Directory)
+	if info, err := os.Stat(settings.Directory); err != nil || !info.IsDir() {
+		httpError(w, fmt.Sprintf("Directory '%s' does not exist", settings.Directory), http.StatusBadRequest)
+		return
+	}
+
+	logbookSettingsMux.Lock()
+	logbookSettings = settings
+	logbookSettingsMux.Unlock()
+	if err := saveLogbookSettings(settings); err != nil {
+		log.Printf("Failed to save logbook settings: %v", err)
+	}
+	log.Printf("Logbook directory set to %s", settings.Directory)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+// handleGetLogbooks lists the logbooks in the logbook directory with their flight counts
+func handleGetLogbooks(w http.ResponseWriter, r *http.Request) {
+	directory := getLogbookSettings().Directory
+	logbooks, err := findLogbooks(directory)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to scan logbook directory '%s': %v", directory, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logbooks)
+}
+
+// handleImportLogbook imports the flights of a logbook in the logbook directory
+func handleImportLogbook(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Path    string `json:"path"`
+		Partial bool   `json:"partial"`
+		Force   bool   `json:"force"` // Import flights that were imported before
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Only logbooks found by the scan can be imported
+	path := filepath.Clean(request.Path)
+	if !isInDirectory(path, getLogbookSettings().Directory) || strings.ToLower(filepath.Ext(path)) != ".sdlog" {
+		httpError(w, "Path is not a logbook in the logbook directory", http.StatusBadRequest)
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		httpError(w, "Logbook not found", http.StatusNotFound)
+		return
+	}
+
+	tempPath, err := copyLogbook(path)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to copy logbook: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tempPath + "-wal")
+	defer os.Remove(tempPath)
+
+	result := importFile(context.Background(), tempPath, filepath.Base(path), ImportOptions{Partial: request.Partial, Force: request.Force})
+	logImportEvent(result)
+	if result.Status == "failed" {
+		httpError(w, result.Message, http.StatusBadRequest)
+		return
+	}
+	log.Printf("Imported %d flights from logbook %s", len(result.Flights), path)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,184 @@
+package data_analysis
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// longCSVHeader is the header of the long-format export, one row per sample of a variable
+var longCSVHeader = []string{"flight_id", "aircraft", "timestamp_s", "variable", "value"}
+
+// longVariable is a variable of the long-format export and the series it is read from; attitude and
+// control inputs have their own time bases
+type longVariable struct {
+	name   string
+	series func(*SeriesColumns) (times, values []float64) // nil values if the aircraft has no such data
+}
+
+// longVariables are the variables of the long-format export in the order they are written
+var longVariables = []longVariable{
+	{"latitude", func(c *SeriesColumns) ([]float64, []float64) { return c.Time, c.Latitude }},
+	{"longitude", func(c *SeriesColumns) ([]float64, []float64) { return c.Time, c.Longitude }},
+	{"altitude", func(c *SeriesColumns) ([]float64, []float64) { return c.Time, c.Altitude }},
+	{"indicated_altitude", func(c *SeriesColumns) ([]float64, []float64) { return c.Time, c.IndicatedAltitude }},
+	{"pressure_altitude", func(c *SeriesColumns) ([]float64, []float64) { return c.Time, c.PressureAltitude }},
+	{"airspeed", func(c *SeriesColumns) ([]float64, []float64) { return c.Time, c.Airspeed }},
+	{"true_airspeed", func(c *SeriesColumns) ([]float64, []float64) { return c.Time, c.TrueAirspeed }},
+	{"vertical_speed", func(c *SeriesColumns) ([]float64, []float64) { return c.Time, c.VerticalSpeed }},
+	{"pitch", func(c *SeriesColumns) ([]float64, []float64) {
+		if c.Attitude == nil {
+			return nil, nil
+		}
+		return c.Attitude.Time, c.Attitude.Pitch
+	}},
+	{"bank", func(c *SeriesColumns) ([]float64, []float64) {
+		if c.Attitude == nil {
+			return nil, nil
+		}
+		return c.Attitude.Time, c.Attitude.Bank
+	}},
+	{"true_heading", func(c *SeriesColumns) ([]float64, []float64) {
+		if c.Attitude == nil {
+			return nil, nil
+		}
+		return c.Attitude.Time, c.Attitude.TrueHeading
+	}},
+	{"throttle", func(c *SeriesColumns) ([]float64, []float64) {
+		if c.Controls == nil {
+			return nil, nil
+		}
+		return c.Controls.ThrottleTime, c.Controls.Throttle
+	}},
+	{"elevator", func(c *SeriesColumns) ([]float64, []float64) {
+		if c.Controls == nil {
+			return nil, nil
+		}
+		return c.Controls.SurfaceTime, c.Controls.Elevator
+	}},
+	{"aileron", func(c *SeriesColumns) ([]float64, []float64) {
+		if c.Controls == nil {
+			return nil, nil
+		}
+		return c.Controls.SurfaceTime, c.Controls.Aileron
+	}},
+	{"rudder", func(c *SeriesColumns) ([]float64, []float64) {
+		if c.Controls == nil {
+			return nil, nil
+		}
+		return c.Controls.SurfaceTime, c.Controls.Rudder
+	}},
+}
+
+// parseLongVariables returns the variables listed in the variables parameter, all if it is empty
+func parseLongVariables(value string) ([]longVariable, error) {
+	if value == "" {
+		return longVariables, nil
+	}
+
+	var selected []longVariable
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, v := range longVariables {
+			if v.name == name {
+				selected = append(selected, v)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, len(longVariables))
+			for i, v := range longVariables {
+				names[i] = v.name
+			}
+			return nil, fmt.Errorf("unknown variable '%s' (available: %s)", name, strings.Join(names, ", "))
+		}
+	}
+	return selected, nil
+}
+
+// handleExportLong exports the samples of all flights, or those listed in the flight_ids parameter, as one
+// long-format CSV with a row per sample of each variable of the target aircraft (of every aircraft with
+// aircraft=all)
+func handleExportLong(w http.ResponseWriter, r *http.Request) {
+	variables, err := parseLongVariables(r.URL.Query().Get("variables"))
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flights, ok := selectExportFlights(w, r)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("flights_long_%s.csv", time.Now().Format("20060102_150405"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	// The CSV is streamed, so errors after the first flight can only be logged
+	if err := writeLongCSV(w, flights, variables, allAircraftRequested(r)); err != nil {
+		log.Printf("Long-format export: %v", err)
+		return
+	}
+	log.Printf("Long-format export: exported %d flights", len(flights))
+}
+
+// writeLongCSV writes the samples of the flights in long format, converted to the configured units.
+// Samples without a value are left out.
+func writeLongCSV(w io.Writer, flights []*Flight, variables []longVariable, allAircraft bool) error {
+	units := configuredUnits()
+	writer := csv.NewWriter(w)
+	if err := writer.Write(longCSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	formatFloat := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, flight := range flights {
+		columns, err := getFlightColumns(flight.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get data of flight %d: %w", flight.ID, err)
+		}
+		if !allAircraft {
+			if columns, err = targetAircraftOnly(flight.ID, columns); err != nil {
+				return fmt.Errorf("failed to get data of flight %d: %w", flight.ID, err)
+			}
+		}
+		columns = units.convertColumns(columns)
+
+		labels := make([]string, 0, len(columns))
+		for label := range columns {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+
+		flightID := strconv.Itoa(flight.ID)
+		for _, label := range labels {
+			for _, variable := range variables {
+				times, values := variable.series(columns[label])
+				for i, value := range values {
+					if math.IsNaN(value) {
+						continue
+					}
+					if err := writer.Write([]string{flightID, label, formatFloat(times[i]), variable.name, formatFloat(value)}); err != nil {
+						return fmt.Errorf("failed to write CSV row: %w", err)
+					}
+				}
+			}
+		}
+		writer.Flush()
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("CSV writer error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// MaintenanceResult reports a VACUUM and ANALYZE of the analysis database
+type MaintenanceResult struct {
+	StartedAt       time.Time `json:"started_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	SizeBeforeBytes int64     `json:"size_before_bytes"` // Database and write-ahead log
+	SizeAfterBytes  int64     `json:"size_after_bytes"`
+	ReclaimedBytes  int64     `json:"reclaimed_bytes"`
+}
+
+var (
+	// maintenanceMutex keeps maintenance runs from overlapping and guards lastMaintenance
+	maintenanceMutex = &sync.Mutex{}
+	// lastMaintenance is the result of the latest maintenance run since startup, nil before the first
+	lastMaintenance *MaintenanceResult
+)
+
+// databaseFilesSize returns the size of the analysis database including its write-ahead log
+func databaseFilesSize() int64 {
+	var size int64
+	for _, path := range []string{mainDatabasePath, mainDatabasePath + "-wal"} {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// runMaintenance rebuilds the analysis database to reclaim the space of deleted data, refreshes the
+// query planner statistics and truncates the write-ahead log
+func runMaintenance() (MaintenanceResult, error) {
+	maintenanceMutex.Lock()
+	defer maintenanceMutex.Unlock()
+
+	result := MaintenanceResult{StartedAt: time.Now(), SizeBeforeBytes: databaseFilesSize()}
+	for _, statement := range []string{"VACUUM", "ANALYZE", "PRAGMA wal_checkpoint(TRUNCATE)"} {
+		if _, err := mainDB.Exec(statement); err != nil {
+			return result, fmt.Errorf("failed to run %s: %w", statement, err)
+		}
+	}
+	result.DurationSeconds = time.Since(result.StartedAt).Seconds()
+	result.SizeAfterBytes = databaseFilesSize()
+	result.ReclaimedBytes = result.SizeBeforeBytes - result.SizeAfterBytes
+
+	lastMaintenance = &result
+	log.Printf("Database maintenance reclaimed %d bytes in %.1fs", result.ReclaimedBytes, result.DurationSeconds)
+	return result, nil
+}
+
+// getLastMaintenance returns the result of the latest maintenance run, nil if none ran since startup
+func getLastMaintenance() *MaintenanceResult {
+	maintenanceMutex.Lock()
+	defer maintenanceMutex.Unlock()
+	return lastMaintenance
+}
+
+// handleMaintenance runs VACUUM and ANALYZE on the analysis database and reports the reclaimed space
+func handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	result, err := runMaintenance()
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
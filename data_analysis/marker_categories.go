@@ -0,0 +1,119 @@
+package data_analysis
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Marker categories, distinguishing marker kinds on the timeline
+const (
+	MarkerCategoryObservation = "observation"
+	MarkerCategoryFailure     = "failure"
+	MarkerCategoryPhase       = "phase"
+	MarkerCategoryDistance    = "distance"    // Created by the distance marker detection
+	MarkerCategoryWarning     = "warning"     // Created for stall and overspeed warnings
+	MarkerCategoryOscillation = "oscillation" // Created by the pilot-induced oscillation detection
+)
+
+var markerCategories = []string{MarkerCategoryObservation, MarkerCategoryFailure, MarkerCategoryPhase, MarkerCategoryDistance, MarkerCategoryWarning, MarkerCategoryOscillation}
+
+// markerCategoryColors are the colors of markers created without an explicit color
+var markerCategoryColors = map[string]string{
+	MarkerCategoryObservation: "#0d6efd",
+	MarkerCategoryFailure:     "#dc3545",
+	MarkerCategoryPhase:       "#6f42c1",
+	MarkerCategoryDistance:    "#fd7e14",
+	MarkerCategoryWarning:     "#ffc107",
+	MarkerCategoryOscillation: "#20c997",
+}
+
+var markerColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ensureMarkerCategoryColumns adds the category and color columns to markers tables created before them
+func ensureMarkerCategoryColumns() error {
+	rows, err := mainDB.Query("SELECT name FROM pragma_table_info('markers')")
+	if err != nil {
+		return fmt.Errorf("failed to get markers table info: %w", err)
+	}
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan markers table info: %w", err)
+		}
+		columns[name] = true
+	}
+	rows.Close()
+
+	for _, column := range []string{"category", "color"} {
+		if columns[column] {
+			continue
+		}
+		log.Printf("Adding %s column to markers table...", column)
+		if _, err := mainDB.Exec(fmt.Sprintf("ALTER TABLE markers ADD COLUMN %s TEXT NOT NULL DEFAULT ''", column)); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// fillMarkerCategory gives markers without a category, e.g. those created before categories existed,
+// the observation category, and markers without a color the color of their category
+func fillMarkerCategory(marker *Marker) {
+	if marker.Category == "" {
+		marker.Category = MarkerCategoryObservation
+	}
+	if marker.Color == "" {
+		marker.Color = markerCategoryColors[marker.Category]
+	}
+}
+
+// normalizeMarkerCategory validates the category and color of a new marker and fills the defaults
+func normalizeMarkerCategory(marker *Marker) error {
+	marker.Category = strings.TrimSpace(marker.Category)
+	marker.Color = strings.TrimSpace(marker.Color)
+	fillMarkerCategory(marker)
+
+	if !slices.Contains(markerCategories, marker.Category) {
+		return fmt.Errorf("unknown marker category '%s' (available: %s)", marker.Category, strings.Join(markerCategories, ", "))
+	}
+	if !markerColorPattern.MatchString(marker.Color) {
+		return fmt.Errorf("invalid marker color '%s', expected #rrggbb", marker.Color)
+	}
+	return nil
+}
+
+// parseMarkerCategoryFilter reads the category parameter: a comma-separated list of categories.
+// Without it all markers are included.
+func parseMarkerCategoryFilter(r *http.Request) ([]string, error) {
+	value := r.URL.Query().Get("category")
+	if value == "" {
+		return nil, nil
+	}
+
+	var categories []string
+	for _, category := range strings.Split(value, ",") {
+		category = strings.TrimSpace(category)
+		if !slices.Contains(markerCategories, category) {
+			return nil, fmt.Errorf("unknown marker category '%s' (available: %s)", category, strings.Join(markerCategories, ", "))
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// filterMarkersByCategory returns the markers in one of the categories
+func filterMarkersByCategory(markers []Marker, categories []string) []Marker {
+	filtered := []Marker{}
+	for _, m := range markers {
+		if slices.Contains(categories, m.Category) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
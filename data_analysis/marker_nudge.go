@@ -0,0 +1,242 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Defaults and limits of snapping a marker to a feature
+const (
+	defaultSnapWindowSeconds = 10.0
+	maxSnapWindowSeconds     = 300.0
+	// defaultSnapNeighborhoodSeconds is how far around a sample no lower (or higher) value may lie for it
+	// to count as a local minimum (or maximum), so noise does not make every wiggle a feature
+	defaultSnapNeighborhoodSeconds = 2.0
+)
+
+// snapChannels are the position series a marker can be snapped to a local extreme of
+var snapChannels = map[string]func(*SeriesColumns) []float64{
+	"airspeed":           func(c *SeriesColumns) []float64 { return c.Airspeed },
+	"altitude":           func(c *SeriesColumns) []float64 { return c.Altitude },
+	"indicated_altitude": func(c *SeriesColumns) []float64 { return c.IndicatedAltitude },
+	"vertical_speed":     func(c *SeriesColumns) []float64 { return c.VerticalSpeed },
+}
+
+// MarkerMove is a marker after it was nudged or snapped, with where it was before
+type MarkerMove struct {
+	Marker       *Marker  `json:"marker"`
+	PreviousTime float64  `json:"previous_time"`
+	Aircraft     string   `json:"aircraft,omitempty"` // Aircraft whose series the marker was snapped to
+	Value        *float64 `json:"value,omitempty"`    // Value of the feature the marker was snapped to
+}
+
+// NudgeMarkerRequest shifts a marker by Seconds, negative to move it earlier
+type NudgeMarkerRequest struct {
+	Seconds float64 `json:"seconds"`
+}
+
+// SnapMarkerRequest moves a marker to the nearest local minimum or maximum of a channel
+type SnapMarkerRequest struct {
+	Channel             string  `json:"channel"`  // airspeed, altitude, indicated_altitude or vertical_speed
+	Feature             string  `json:"feature"`  // min or max
+	Aircraft            string  `json:"aircraft"` // Optional, defaults to the user aircraft
+	WindowSeconds       float64 `json:"window_seconds"`
+	NeighborhoodSeconds float64 `json:"neighborhood_seconds"`
+}
+
+// getMarker returns one marker of a flight, or sql.ErrNoRows
+func getMarker(flightID, markerID int) (*Marker, error) {
+	query := `
+		SELECT id, flight_id, time_seconds, label, COALESCE(type, 'regular'), category, color, created_at
+		FROM markers
+		WHERE id = ? AND flight_id = ?
+	`
+
+	var m Marker
+	err := mainDB.QueryRow(query, markerID, flightID).Scan(&m.ID, &m.FlightID, &m.Time, &m.Label, &m.Type, &m.Category, &m.Color, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	fillMarkerCategory(&m)
+	return &m, nil
+}
+
+// moveMarker sets the time of a marker
+func moveMarker(marker *Marker, time float64) error {
+	if _, err := mainDB.Exec("UPDATE markers SET time_seconds = ? WHERE id = ?", time, marker.ID); err != nil {
+		return err
+	}
+	marker.Time = time
+	return nil
+}
+
+// lookupMarker resolves the markerId path value; on failure it writes the error response
+func lookupMarker(w http.ResponseWriter, r *http.Request, flightId int) (*Marker, bool) {
+	markerId, err := strconv.Atoi(r.PathValue("markerId"))
+	if err != nil {
+		httpError(w, "Invalid marker ID", http.StatusBadRequest)
+		return nil, false
+	}
+	marker, err := getMarker(flightId, markerId)
+	if err == sql.ErrNoRows {
+		httpError(w, "Marker not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get marker: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return marker, true
+}
+
+// handleNudgeMarker shifts a marker by some seconds, keeping it within the flight
+func handleNudgeMarker(w http.ResponseWriter, r *http.Request, flightId int) {
+	var req NudgeMarkerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if req.Seconds == 0 || math.IsNaN(req.Seconds) || math.IsInf(req.Seconds, 0) {
+		httpError(w, "seconds must be a non-zero number", http.StatusBadRequest)
+		return
+	}
+
+	marker, ok := lookupMarker(w, r, flightId)
+	if !ok {
+		return
+	}
+	duration, err := flightDurationSeconds(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight duration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	move := MarkerMove{Marker: marker, PreviousTime: marker.Time}
+	if err := moveMarker(marker, math.Min(math.Max(marker.Time+req.Seconds, 0), duration)); err != nil {
+		httpError(w, fmt.Sprintf("Failed to move marker: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(move)
+}
+
+// handleSnapMarker moves a marker to the nearest local minimum or maximum of a channel within a window
+// around it, e.g. the airspeed low point after a failure
+func handleSnapMarker(w http.ResponseWriter, r *http.Request, flightId int) {
+	req := SnapMarkerRequest{WindowSeconds: defaultSnapWindowSeconds, NeighborhoodSeconds: defaultSnapNeighborhoodSeconds}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	channel, ok := snapChannels[req.Channel]
+	if !ok {
+		names := make([]string, 0, len(snapChannels))
+		for name := range snapChannels {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		httpError(w, fmt.Sprintf("Invalid channel '%s' (expected %s)", req.Channel, strings.Join(names, ", ")), http.StatusBadRequest)
+		return
+	}
+	if req.Feature != "min" && req.Feature != "max" {
+		httpError(w, fmt.Sprintf("Invalid feature '%s' (expected min or max)", req.Feature), http.StatusBadRequest)
+		return
+	}
+	if !(req.WindowSeconds > 0) || req.WindowSeconds > maxSnapWindowSeconds {
+		httpError(w, fmt.Sprintf("window_seconds must be above 0 and at most %g", maxSnapWindowSeconds), http.StatusBadRequest)
+		return
+	}
+	if !(req.NeighborhoodSeconds >= 0) || req.NeighborhoodSeconds > req.WindowSeconds {
+		httpError(w, "neighborhood_seconds must be between 0 and window_seconds", http.StatusBadRequest)
+		return
+	}
+
+	marker, ok := lookupMarker(w, r, flightId)
+	if !ok {
+		return
+	}
+
+	if req.Aircraft == "" {
+		label, err := getUserAircraftLabel(flightId)
+		if err != nil && err != sql.ErrNoRows {
+			httpError(w, fmt.Sprintf("Failed to get user aircraft: %v", err), http.StatusInternalServerError)
+			return
+		}
+		req.Aircraft = label
+	}
+	columns, err := getFlightColumns(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		return
+	}
+	series, exists := columns[req.Aircraft]
+	if !exists {
+		httpError(w, fmt.Sprintf("Aircraft '%s' has no position data in flight %d", req.Aircraft, flightId), http.StatusNotFound)
+		return
+	}
+
+	values := channel(series)
+	index := nearestLocalExtreme(series.Time, values, marker.Time, req.WindowSeconds, req.NeighborhoodSeconds, req.Feature == "max")
+	if index < 0 {
+		httpError(w, fmt.Sprintf("No local %s of %s within %gs of the marker", req.Feature, req.Channel, req.WindowSeconds), http.StatusNotFound)
+		return
+	}
+
+	value := values[index]
+	move := MarkerMove{Marker: marker, PreviousTime: marker.Time, Aircraft: req.Aircraft, Value: &value}
+	if err := moveMarker(marker, series.Time[index]); err != nil {
+		httpError(w, fmt.Sprintf("Failed to move marker: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(move)
+}
+
+// nearestLocalExtreme returns the index of the local minimum (or maximum) nearest to t within window
+// seconds, or -1. A sample is a local extreme when no sample within neighborhood seconds of it is lower
+// (or higher); of a plateau, the sample nearest to t is taken.
+func nearestLocalExtreme(times, values []float64, t, window, neighborhood float64, maximum bool) int {
+	beyond := func(a, b float64) bool {
+		if maximum {
+			return a > b
+		}
+		return a < b
+	}
+
+	best := -1
+	start := 0
+	for i, ti := range times {
+		if ti < t-window {
+			continue
+		}
+		if ti > t+window {
+			break
+		}
+		if math.IsNaN(values[i]) {
+			continue
+		}
+
+		for start < len(times) && times[start] < ti-neighborhood {
+			start++
+		}
+		extreme := true
+		for j := start; j < len(times) && times[j] <= ti+neighborhood; j++ {
+			if beyond(values[j], values[i]) {
+				extreme = false
+				break
+			}
+		}
+		if extreme && (best < 0 || math.Abs(ti-t) < math.Abs(times[best]-t)) {
+			best = i
+		}
+	}
+	return best
+}
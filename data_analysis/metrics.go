@@ -0,0 +1,211 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// Derived metrics stored per flight
+const (
+	metricStatistics    = "statistics"     // map[string]*FlightStatistics
+	metricWindCorrected = "wind_corrected" // map[string]*WindCorrectedStatistics
+)
+
+// metricVersions are stored with each metric; bump a version when the definition of its metric changes
+// so stored values are recomputed
+var metricVersions = map[string]int{
+	metricStatistics:    statisticsVersion,
+	metricWindCorrected: 4,
+}
+
+// FlightMetric describes a metric stored for a flight
+type FlightMetric struct {
+	Metric         string `json:"metric"`
+	Version        int    `json:"version"`         // Definition version the value was computed with
+	CurrentVersion int    `json:"current_version"` // Definition version of this build
+	ComputedAt     string `json:"computed_at"`
+	Outdated       bool   `json:"outdated"` // Recomputed on next use
+}
+
+// ensureFlightMetricsTable creates the table storing derived metrics per flight
+func ensureFlightMetricsTable() error {
+	metricsSchema := `
+		CREATE TABLE IF NOT EXISTS flight_metrics (
+			flight_id INTEGER NOT NULL,
+			metric TEXT NOT NULL,
+			value TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			computed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (flight_id, metric),
+			FOREIGN KEY(flight_id) REFERENCES flight(id) ON DELETE CASCADE
+		);
+	`
+	if _, err := mainDB.Exec(metricsSchema); err != nil {
+		return fmt.Errorf("failed to create flight_metrics table: %w", err)
+	}
+
+	// The statistics cache predating flight_metrics is dropped; its flights are recomputed in the background
+	if _, err := mainDB.Exec("DROP TABLE IF EXISTS flight_statistics"); err != nil {
+		return fmt.Errorf("failed to drop flight_statistics table: %w", err)
+	}
+	return nil
+}
+
+// getStoredMetric decodes the stored value of a metric into dest. It returns sql.ErrNoRows when the
+// metric was not computed with its current version.
+func getStoredMetric(flightID int, metric string, dest interface{}) error {
+	var raw string
+	err := mainDB.QueryRow("SELECT value FROM flight_metrics WHERE flight_id = ? AND metric = ? AND version = ?",
+		flightID, metric, metricVersions[metric]).Scan(&raw)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), dest); err != nil {
+		return fmt.Errorf("failed to decode stored %s: %w", metric, err)
+	}
+	return nil
+}
+
+// storeMetric stores the value of a metric with its current version. While the dataset is frozen nothing
+// is stored, so computed values are served without changing the database.
+func storeMetric(flightID int, metric string, value interface{}) error {
+	if readOnly.Load() {
+		return nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", metric, err)
+	}
+
+	query := `
+		INSERT INTO flight_metrics (flight_id, metric, value, version, computed_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(flight_id, metric) DO UPDATE SET value = excluded.value, version = excluded.version,
+			computed_at = excluded.computed_at
+	`
+	if _, err := mainDB.Exec(query, flightID, metric, string(raw), metricVersions[metric]); err != nil {
+		return fmt.Errorf("failed to store %s: %w", metric, err)
+	}
+	return nil
+}
+
+// getFlightMetrics lists the metrics stored for a flight, including metrics not computed yet
+func getFlightMetrics(flightID int) ([]FlightMetric, error) {
+	rows, err := mainDB.Query("SELECT metric, version, computed_at FROM flight_metrics WHERE flight_id = ?", flightID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := map[string]FlightMetric{}
+	for rows.Next() {
+		var m FlightMetric
+		var computedAt sql.NullString
+		if err := rows.Scan(&m.Metric, &m.Version, &computedAt); err != nil {
+			return nil, err
+		}
+		m.ComputedAt = computedAt.String
+		stored[m.Metric] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	metrics := []FlightMetric{}
+	for metric, version := range metricVersions {
+		m, ok := stored[metric]
+		if !ok {
+			m = FlightMetric{Metric: metric}
+		}
+		m.CurrentVersion = version
+		m.Outdated = m.Version != version
+		metrics = append(metrics, m)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Metric < metrics[j].Metric })
+	return metrics, nil
+}
+
+// invalidateFlightMetrics removes the stored metrics and cached series of a flight
+func invalidateFlightMetrics(flightID int) error {
+	if _, err := mainDB.Exec("DELETE FROM flight_metrics WHERE flight_id = ?", flightID); err != nil {
+		return err
+	}
+	invalidateFlightColumns(flightID)
+	return nil
+}
+
+// recomputeFlightMetrics computes and stores every metric of a flight from freshly loaded data
+func recomputeFlightMetrics(flightID int) error {
+	// Bypass the series cache so background work does not evict flights being analyzed
+	flightData, err := getFlightDataFromMainDB(flightID)
+	if err != nil {
+		return fmt.Errorf("failed to get flight data: %w", err)
+	}
+
+	columns := flightDataToColumns(flightData)
+	if err := addControlColumns(flightData, columns); err != nil {
+		return err
+	}
+	if _, err := cacheFlightStatistics(flightID, columns); err != nil {
+		return err
+	}
+	return storeMetric(flightID, metricWindCorrected, calculateFlightWindCorrectedStatistics(flightData))
+}
+
+// handleGetFlightMetrics lists the metrics stored for a flight with their versions
+func handleGetFlightMetrics(w http.ResponseWriter, r *http.Request, flightId int) {
+	metrics, err := getFlightMetrics(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight metrics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metrics)
+}
+
+// handleInvalidateFlightMetrics removes the stored metrics of a flight; they are recomputed on next use
+func handleInvalidateFlightMetrics(w http.ResponseWriter, r *http.Request, flightId int) {
+	if err := invalidateFlightMetrics(flightId); err != nil {
+		httpError(w, fmt.Sprintf("Failed to invalidate flight metrics: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Invalidated metrics of flight %d", flightId)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "flight_id": flightId})
+}
+
+// handleRecomputeFlightMetrics recomputes the metrics of a flight and returns their new state
+func handleRecomputeFlightMetrics(w http.ResponseWriter, r *http.Request, flightId int) {
+	invalidateFlightColumns(flightId)
+	if err := recomputeFlightMetrics(flightId); err != nil {
+		httpError(w, fmt.Sprintf("Failed to recompute flight metrics: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Recomputed metrics of flight %d", flightId)
+
+	handleGetFlightMetrics(w, r, flightId)
+}
+
+// handleRecomputeAllMetrics invalidates the metrics of every flight and has the background
+// precomputation recompute them
+func handleRecomputeAllMetrics(w http.ResponseWriter, r *http.Request) {
+	result, err := mainDB.Exec("DELETE FROM flight_metrics")
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to invalidate metrics: %v", err), http.StatusInternalServerError)
+		return
+	}
+	invalidated, _ := result.RowsAffected()
+	log.Printf("Invalidated %d stored metrics, recomputing in the background", invalidated)
+	triggerPrecomputation()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "recomputing", "invalidated": invalidated})
+}
@@ -0,0 +1,33 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestStoreMetricReadOnly(t *testing.T) {
+	openTestDatabase(t)
+	flightID, _ := insertTestFlight(t, "Flight", "", "D-TEST")
+	t.Cleanup(func() { readOnly.Store(false) })
+
+	readOnly.Store(true)
+	statistics, err := getFlightStatistics(flightID)
+	if err != nil {
+		t.Fatalf("getFlightStatistics() while frozen error: %v", err)
+	}
+	if statistics == nil {
+		t.Fatalf("getFlightStatistics() while frozen returned no statistics")
+	}
+	var stored map[string]*FlightStatistics
+	if err := getStoredMetric(flightID, metricStatistics, &stored); err != sql.ErrNoRows {
+		t.Errorf("statistics were stored while frozen (%v)", err)
+	}
+
+	readOnly.Store(false)
+	if _, err := getFlightStatistics(flightID); err != nil {
+		t.Fatalf("getFlightStatistics() error: %v", err)
+	}
+	if err := getStoredMetric(flightID, metricStatistics, &stored); err != nil {
+		t.Errorf("statistics were not stored after unfreezing: %v", err)
+	}
+}
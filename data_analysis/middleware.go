@@ -0,0 +1,36 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// flightHandlerFunc is an HTTP handler scoped to a single, existing flight
+type flightHandlerFunc func(w http.ResponseWriter, r *http.Request, flightID int)
+
+// withFlightID resolves the {id} path parameter of flight-scoped routes, rejecting
+// malformed IDs and unknown flights before the wrapped handler runs
+func withFlightID(next flightHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flightID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || flightID <= 0 {
+			httpError(w, "Invalid flight ID", http.StatusBadRequest)
+			return
+		}
+
+		var exists int
+		err = mainDB.QueryRow("SELECT 1 FROM flight WHERE id = ?", flightID).Scan(&exists)
+		if err == sql.ErrNoRows {
+			httpError(w, fmt.Sprintf("Flight %d not found", flightID), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			httpError(w, fmt.Sprintf("Failed to look up flight: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		next(w, r, flightID)
+	}
+}
@@ -0,0 +1,250 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// Marker types of the start and end of a detected oscillation
+const (
+	OscillationStartMarkerType = "oscillation_start"
+	OscillationEndMarkerType   = "oscillation_end"
+)
+
+// Oscillation is an interval in which the attitude of an aircraft oscillated about one axis within the
+// configured frequency band and above the configured amplitude, a sign of pilot-induced oscillation
+type Oscillation struct {
+	Axis         string  `json:"axis"` // "pitch" or "roll"
+	StartTime    float64 `json:"start_time"`
+	EndTime      float64 `json:"end_time"`
+	Cycles       float64 `json:"cycles"`
+	FrequencyHz  float64 `json:"frequency_hz"`
+	AmplitudeDeg float64 `json:"amplitude_deg"` // Largest deviation from the moving mean of the attitude
+	// Peak-to-peak travel of the elevator or aileron over the interval as a fraction of full travel; nil
+	// without control data, in which case the oscillation is detected from the attitude alone
+	ControlTravel *float64 `json:"control_travel"`
+}
+
+// oscillationAxes are the attitude angles searched for oscillations and the control moving each
+var oscillationAxes = []struct {
+	name     string
+	attitude func(*AttitudeColumns) []float64
+	control  func(*ControlColumns) ([]float64, []float64)
+}{
+	{"pitch",
+		func(a *AttitudeColumns) []float64 { return a.Pitch },
+		func(c *ControlColumns) ([]float64, []float64) { return c.SurfaceTime, c.Elevator }},
+	{"roll",
+		func(a *AttitudeColumns) []float64 { return a.Bank },
+		func(c *ControlColumns) ([]float64, []float64) { return c.SurfaceTime, c.Aileron }},
+}
+
+// halfCycle is the stretch of a detrended signal between two zero crossings
+type halfCycle struct {
+	start, end float64 // Crossing times
+	amplitude  float64 // Largest absolute value in between
+}
+
+// detrend subtracts from each sample the mean of the samples within windowSeconds centered on it,
+// leaving the oscillations faster than the window
+func detrend(times, values []float64, windowSeconds float64) []float64 {
+	detrended := make([]float64, len(values))
+	var sum float64
+	lo, hi := 0, 0 // Samples lo to hi-1 are within the window
+	for i, t := range times {
+		for hi < len(times) && times[hi] <= t+windowSeconds/2 {
+			sum += values[hi]
+			hi++
+		}
+		for times[lo] < t-windowSeconds/2 {
+			sum -= values[lo]
+			lo++
+		}
+		detrended[i] = values[i] - sum/float64(hi-lo)
+	}
+	return detrended
+}
+
+// halfCycles splits a detrended signal at its zero crossings, interpolating the crossing times
+func halfCycles(times, detrended []float64) []halfCycle {
+	var cycles []halfCycle
+	var current *halfCycle
+	for i := 1; i < len(detrended); i++ {
+		prev, cur := detrended[i-1], detrended[i]
+		if (prev < 0) != (cur < 0) {
+			crossing := times[i-1] + (times[i]-times[i-1])*prev/(prev-cur)
+			if current != nil {
+				current.end = crossing
+				cycles = append(cycles, *current)
+			}
+			current = &halfCycle{start: crossing}
+		}
+		if current != nil {
+			current.amplitude = math.Max(current.amplitude, math.Abs(cur))
+		}
+	}
+	return cycles
+}
+
+// detectOscillations finds the intervals in which a signal swings about its moving mean with half periods
+// within the configured frequency band and at least the configured amplitude, for at least the
+// configured number of consecutive cycles. Every sample counts, as a level attitude or centered control
+// is a valid zero.
+func detectOscillations(times, values []float64) []Oscillation {
+	if len(times) < 3 {
+		return nil
+	}
+
+	// The moving mean spans a period of the slowest oscillation, so slower maneuvers are not counted
+	minHalfPeriod := 1 / (2 * analysisConfig.PIOMaxFrequencyHz)
+	maxHalfPeriod := 1 / (2 * analysisConfig.PIOMinFrequencyHz)
+	cycles := halfCycles(times, detrend(times, values, 1/analysisConfig.PIOMinFrequencyHz))
+
+	var oscillations []Oscillation
+	qualifies := func(c halfCycle) bool {
+		duration := c.end - c.start
+		return duration >= minHalfPeriod && duration <= maxHalfPeriod && c.amplitude >= analysisConfig.PIOMinAmplitudeDeg
+	}
+	for start := 0; start < len(cycles); start++ {
+		if !qualifies(cycles[start]) {
+			continue
+		}
+		end := start
+		for end+1 < len(cycles) && qualifies(cycles[end+1]) {
+			end++
+		}
+
+		count := end - start + 1
+		if float64(count)/2 >= float64(analysisConfig.PIOMinCycles) {
+			oscillation := Oscillation{
+				StartTime: cycles[start].start,
+				EndTime:   cycles[end].end,
+				Cycles:    float64(count) / 2,
+			}
+			oscillation.FrequencyHz = oscillation.Cycles / (oscillation.EndTime - oscillation.StartTime)
+			for _, c := range cycles[start : end+1] {
+				oscillation.AmplitudeDeg = math.Max(oscillation.AmplitudeDeg, c.amplitude)
+			}
+			oscillations = append(oscillations, oscillation)
+		}
+		start = end
+	}
+	return oscillations
+}
+
+// controlTravel returns the peak-to-peak travel of a control input between two times, nil without samples
+func controlTravel(times, values []float64, start, end float64) *float64 {
+	low, high := math.Inf(1), math.Inf(-1)
+	for i, t := range times {
+		if t < start || t > end {
+			continue
+		}
+		low = math.Min(low, values[i])
+		high = math.Max(high, values[i])
+	}
+	if math.IsInf(low, 1) {
+		return nil
+	}
+	travel := high - low
+	return &travel
+}
+
+// findAircraftOscillations detects the pitch and roll oscillations of one aircraft. With control data an
+// oscillation only counts if the pilot moved the elevator or aileron by at least the configured travel
+// during it, telling pilot-induced oscillations from e.g. turbulence.
+func findAircraftOscillations(series *SeriesColumns) []Oscillation {
+	oscillations := []Oscillation{}
+	if series.Attitude == nil {
+		return oscillations
+	}
+
+	for _, axis := range oscillationAxes {
+		for _, oscillation := range detectOscillations(series.Attitude.Time, axis.attitude(series.Attitude)) {
+			oscillation.Axis = axis.name
+			if series.Controls != nil {
+				times, values := axis.control(series.Controls)
+				oscillation.ControlTravel = controlTravel(times, values, oscillation.StartTime, oscillation.EndTime)
+				if oscillation.ControlTravel != nil && *oscillation.ControlTravel < analysisConfig.PIOMinControlTravel {
+					continue
+				}
+			}
+			oscillations = append(oscillations, oscillation)
+		}
+	}
+	sort.Slice(oscillations, func(i, j int) bool { return oscillations[i].StartTime < oscillations[j].StartTime })
+	return oscillations
+}
+
+// createOscillationMarkersForFlight detects the oscillations of the target aircraft of a flight, or of
+// each aircraft if allAircraft is set, and replaces the oscillation markers with a start and end marker
+// per oscillation; with several aircraft the labels name the aircraft
+func createOscillationMarkersForFlight(flightID int, allAircraft bool) (map[string][]Oscillation, int, error) {
+	columns, err := getFlightColumns(flightID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get flight data: %w", err)
+	}
+
+	aircraft := map[string][]Oscillation{}
+	for label, series := range columns {
+		aircraft[label] = findAircraftOscillations(series)
+	}
+	if !allAircraft {
+		if aircraft, err = targetAircraftOnly(flightID, aircraft); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if _, err := mainDB.Exec("DELETE FROM markers WHERE flight_id = ? AND category = ?", flightID, MarkerCategoryOscillation); err != nil {
+		return nil, 0, fmt.Errorf("failed to delete oscillation markers: %w", err)
+	}
+
+	labels := make([]string, 0, len(aircraft))
+	for label := range aircraft {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	created := 0
+	for _, aircraftLabel := range labels {
+		for _, oscillation := range aircraft[aircraftLabel] {
+			label := "Pitch oscillation"
+			if oscillation.Axis == "roll" {
+				label = "Roll oscillation"
+			}
+			if len(columns) > 1 {
+				label = fmt.Sprintf("%s (%s)", label, aircraftLabel)
+			}
+			for _, marker := range []Marker{
+				{FlightID: flightID, Time: oscillation.StartTime, Label: label, Type: OscillationStartMarkerType, Category: MarkerCategoryOscillation},
+				{FlightID: flightID, Time: oscillation.EndTime, Label: label, Type: OscillationEndMarkerType, Category: MarkerCategoryOscillation},
+			} {
+				if _, err := createMarker(marker); err != nil {
+					return nil, created, fmt.Errorf("failed to create oscillation marker: %w", err)
+				}
+				created++
+			}
+		}
+	}
+	return aircraft, created, nil
+}
+
+// handleCreateOscillationMarkers detects the pitch and roll oscillations of a flight, replacing its
+// oscillation markers; aircraft=all detects them for every aircraft
+func handleCreateOscillationMarkers(w http.ResponseWriter, r *http.Request, flightId int) {
+	aircraft, created, err := createOscillationMarkersForFlight(flightId, allAircraftRequested(r))
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create oscillation markers: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"message":  fmt.Sprintf("Created %d oscillation markers", created),
+		"created":  created,
+		"aircraft": aircraft,
+	})
+}
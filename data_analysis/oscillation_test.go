@@ -0,0 +1,48 @@
+package data_analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDetectOscillations(t *testing.T) {
+	// 20 seconds of level flight, 10 seconds of a 0.5 Hz pitch oscillation of 5 degrees, then level again;
+	// the level stretches hold zeros, which count like any other pitch
+	var times, pitch []float64
+	for i := 0; i <= 400; i++ {
+		time := float64(i) / 10
+		value := 0.0
+		if time >= 20 && time < 30 {
+			value = 5 * math.Sin(math.Pi*(time-20))
+		}
+		times = append(times, time)
+		pitch = append(pitch, value)
+	}
+
+	oscillations := detectOscillations(times, pitch)
+	if len(oscillations) != 1 {
+		t.Fatalf("got %d oscillations %+v, want 1", len(oscillations), oscillations)
+	}
+	o := oscillations[0]
+	if o.StartTime < 19 || o.EndTime > 31 || o.Cycles < float64(analysisConfig.PIOMinCycles) {
+		t.Errorf("oscillation = %+v, want at least %d cycles within 19 to 31 s", o, analysisConfig.PIOMinCycles)
+	}
+	if math.Abs(o.FrequencyHz-0.5) > 0.1 {
+		t.Errorf("frequency = %v Hz, want about 0.5 Hz", o.FrequencyHz)
+	}
+
+	if got := detectOscillations(times[:2], pitch[:2]); got != nil {
+		t.Errorf("two samples gave oscillations %+v", got)
+	}
+}
+
+func TestControlTravel(t *testing.T) {
+	times := []float64{0, 1, 2, 3, 4}
+	values := []float64{0.5, -0.2, 0, 0.3, 0.9}
+	if travel := controlTravel(times, values, 1, 3); travel == nil || math.Abs(*travel-0.5) > 1e-12 {
+		t.Errorf("controlTravel(1, 3) = %v, want 0.5", travel)
+	}
+	if travel := controlTravel(times, values, 5, 6); travel != nil {
+		t.Errorf("controlTravel(5, 6) = %v, want nil", *travel)
+	}
+}
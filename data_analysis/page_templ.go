@@ -29,7 +29,7 @@ func DataAnalysisPage() templ.Component {
 			templ_7745c5c3_Var1 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!doctype html><html lang=\"en\"><head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><title>Flight Data Visualizer</title><script src=\"https://cdn.plot.ly/plotly-latest.min.js\"></script><style>\n\t\t\tbody {\n\t\t\t\tfont-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;\n\t\t\t\tmargin: 0;\n\t\t\t\tpadding: 20px;\n\t\t\t\tbackground-color: #f5f5f5;\n\t\t\t}\n\t\t\t\n\t\t\t.container {\n\t\t\t\tmax-width: 1200px;\n\t\t\t\tmargin: 0 auto;\n\t\t\t\tbackground: white;\n\t\t\t\tpadding: 20px;\n\t\t\t\tborder-radius: 8px;\n\t\t\t\tbox-shadow: 0 2px 10px rgba(0,0,0,0.1);\n\t\t\t}\n\t\t\t\n\t\t\th1 {\n\t\t\t\ttext-align: center;\n\t\t\t\tcolor: #333;\n\t\t\t\tmargin-bottom: 30px;\n\t\t\t}\n\t\t\t\n\t\t\t.section {\n\t\t\t\tmargin-bottom: 30px;\n\t\t\t\tpadding: 20px;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 5px;\n\t\t\t\tbackground: #fafafa;\n\t\t\t}\n\t\t\t\n\t\t\t.section h3 {\n\t\t\t\tmargin-top: 0;\n\t\t\t\tcolor: #444;\n\t\t\t}\n\t\t\t\n\t\t\tinput[type=\"file\"] {\n\t\t\t\tdisplay: none;\n\t\t\t}\n\t\t\t\n\t\t\tbutton {\n\t\t\t\tbackground-color: #007cba;\n\t\t\t\tcolor: white;\n\t\t\t\tborder: none;\n\t\t\t\tpadding: 10px 20px;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\tcursor: pointer;\n\t\t\t\tfont-size: 14px;\n\t\t\t}\n\t\t\t\n\t\t\tbutton:hover {\n\t\t\t\tbackground-color: #005a8b;\n\t\t\t}\n\t\t\t\n\t\t\tbutton:disabled {\n\t\t\t\tbackground-color: #ccc;\n\t\t\t\tcursor: not-allowed;\n\t\t\t}\n\t\t\t\n\t\t\tselect {\n\t\t\t\twidth: 100%;\n\t\t\t\tpadding: 8px;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\tbackground: white;\n\t\t\t}\n\t\t\t\n\t\t\t.status {\n\t\t\t\tpadding: 10px;\n\t\t\t\tmargin: 10px 0;\n\t\t\t\tborder-radius: 4px;\n\t\t\t}\n\t\t\t\n\t\t\t.status.success {\n\t\t\t\tbackground-color: #d4edda;\n\t\t\t\tcolor: #155724;\n\t\t\t\tborder: 1px solid #c3e6cb;\n\t\t\t}\n\t\t\t\n\t\t\t.status.error {\n\t\t\t\tbackground-color: #f8d7da;\n\t\t\t\tcolor: #721c24;\n\t\t\t\tborder: 1px solid #f5c6cb;\n\t\t\t}\n\t\t\t\n\t\t\t.status.info {\n\t\t\t\tbackground-color: #cce7ff;\n\t\t\t\tcolor: #004085;\n\t\t\t\tborder: 1px solid #99d3ff;\n\t\t\t}\n\t\t\t\n\t\t\t.slider-container {\n\t\t\t\tmargin: 20px 0;\n\t\t\t}\n\t\t\t\n\t\t\t.slider {\n\t\t\t\twidth: 100%;\n\t\t\t\tmargin: 10px 0;\n\t\t\t}\n\t\t\t\n\t\t\t.time-display {\n\t\t\t\tcolor: #007cba;\n\t\t\t\tfont-weight: bold;\n\t\t\t\tmargin: 5px 0;\n\t\t\t}\n\t\t\t\n\t\t\t.controls {\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tgap: 10px;\n\t\t\t\tmargin-bottom: 10px;\n\t\t\t}\n\t\t\t\n\t\t\t.controls input[type=\"text\"] {\n\t\t\t\tpadding: 6px;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 4px;\n\t\t\t}\n\t\t\t\n\t\t\t.markers-table {\n\t\t\t\twidth: 100%;\n\t\t\t\tborder-collapse: collapse;\n\t\t\t\tmargin-top: 10px;\n\t\t\t}\n\t\t\t\n\t\t\t.markers-table th,\n\t\t\t.markers-table td {\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tpadding: 8px;\n\t\t\t\ttext-align: left;\n\t\t\t}\n\t\t\t\n\t\t\t.markers-table th {\n\t\t\t\tbackground-color: #f2f2f2;\n\t\t\t}\n\t\t\t\n\t\t\t.tabs {\n\t\t\t\tdisplay: flex;\n\t\t\t\tborder-bottom: 1px solid #ddd;\n\t\t\t\tmargin-bottom: 20px;\n\t\t\t}\n\t\t\t\n\t\t\t.tab {\n\t\t\t\tpadding: 10px 20px;\n\t\t\t\tcursor: pointer;\n\t\t\t\tborder: none;\n\t\t\t\tbackground: none;\n\t\t\t\tborder-bottom: 2px solid transparent;\n\t\t\t}\n\t\t\t\n\t\t\t.tab.active {\n\t\t\t\tborder-bottom-color: #007cba;\n\t\t\t\tcolor: #007cba;\n\t\t\t}\n\t\t\t\n\t\t\t.tab-content {\n\t\t\t\tdisplay: none;\n\t\t\t}\n\t\t\t\n\t\t\t.tab-content.active {\n\t\t\t\tdisplay: block;\n\t\t\t}\n\t\t\t\n\t\t\t.graph-container {\n\t\t\t\theight: 400px;\n\t\t\t\tmargin-bottom: 20px;\n\t\t\t}\n\t\t\t\n\t\t\t.map-container {\n\t\t\t\theight: 600px;\n\t\t\t\twidth: 100%;\n\t\t\t\tmargin-bottom: 20px;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\toverflow: hidden;\n\t\t\t}\n\t\t\t\n\t\t\t.subsection {\n\t\t\t\tmargin-bottom: 20px;\n\t\t\t\tpadding: 15px;\n\t\t\t\tborder: 1px solid #eee;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\tbackground: white;\n\t\t\t}\n\t\t\t\n\t\t\t.subsection h4 {\n\t\t\t\tmargin-top: 0;\n\t\t\t\tmargin-bottom: 15px;\n\t\t\t\tcolor: #555;\n\t\t\t\tfont-size: 16px;\n\t\t\t}\n\t\t\t\n\t\t\t.controls {\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tgap: 10px;\n\t\t\t\tflex-wrap: wrap;\n\t\t\t}\n\t\t\t\n\t\t\t.flight-controls {\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tgap: 10px;\n\t\t\t\tmargin-bottom: 10px;\n\t\t\t}\n\t\t\t\n\t\t\t.flight-controls select {\n\t\t\t\tflex-grow: 1;\n\t\t\t}\n\t\t\t\n\t\t\t.statistics-container {\n\t\t\t\tdisplay: grid;\n\t\t\t\tgrid-template-columns: repeat(auto-fit, minmax(300px, 1fr));\n\t\t\t\tgap: 20px;\n\t\t\t\tmargin-top: 20px;\n\t\t\t}\n\t\t\t\n\t\t\t.aircraft-stats {\n\t\t\t\tbackground: white;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 5px;\n\t\t\t\tpadding: 15px;\n\t\t\t}\n\t\t\t\n\t\t\t.aircraft-stats h4 {\n\t\t\t\tmargin-top: 0;\n\t\t\t\tmargin-bottom: 15px;\n\t\t\t\tcolor: #007cba;\n\t\t\t\tborder-bottom: 1px solid #eee;\n\t\t\t\tpadding-bottom: 5px;\n\t\t\t}\n\t\t\t\n\t\t\t.stats-table {\n\t\t\t\twidth: 100%;\n\t\t\t\tborder-collapse: collapse;\n\t\t\t\tfont-size: 14px;\n\t\t\t}\n\t\t\t\n\t\t\t.stats-table th,\n\t\t\t.stats-table td {\n\t\t\t\ttext-align: left;\n\t\t\t\tpadding: 8px 5px;\n\t\t\t\tborder-bottom: 1px solid #f0f0f0;\n\t\t\t}\n\t\t\t\n\t\t\t.stats-table th {\n\t\t\t\tbackground-color: #f8f9fa;\n\t\t\t\tfont-weight: bold;\n\t\t\t}\n\t\t\t\n\t\t\t.stats-table .metric-name {\n\t\t\t\twidth: 40%;\n\t\t\t}\n\t\t\t\n\t\t\t.stats-table .metric-value {\n\t\t\t\twidth: 30%;\n\t\t\t\ttext-align: right;\n\t\t\t}\n\t\t\t\n\t\t\t.variance-highlight {\n\t\t\t\tbackground-color: #fff3cd;\n\t\t\t\tfont-weight: bold;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion {\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 5px;\n\t\t\t\tbackground: white;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-header {\n\t\t\t\tdisplay: flex;\n\t\t\t\tjustify-content: space-between;\n\t\t\t\talign-items: center;\n\t\t\t\tpadding: 15px 20px;\n\t\t\t\tcursor: pointer;\n\t\t\t\tbackground: #f8f9fa;\n\t\t\t\tborder-bottom: 1px solid #ddd;\n\t\t\t\ttransition: background-color 0.2s;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-header:hover {\n\t\t\t\tbackground: #e9ecef;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-header h3 {\n\t\t\t\tmargin: 0;\n\t\t\t\tcolor: #333;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-icon {\n\t\t\t\tfont-size: 16px;\n\t\t\t\ttransition: transform 0.2s;\n\t\t\t\tcolor: #007cba;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-icon.rotated {\n\t\t\t\ttransform: rotate(180deg);\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-content {\n\t\t\t\tmax-height: 0;\n\t\t\t\toverflow: hidden;\n\t\t\t\ttransition: max-height 0.3s ease-out;\n\t\t\t\tpadding: 0 20px;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-content.open {\n\t\t\t\tmax-height: 2000px;\n\t\t\t\tpadding: 20px;\n\t\t\t\ttransition: max-height 0.3s ease-in;\n\t\t\t}\n\t\t</style></head><body><div class=\"container\"><h1>Flight Data Visualizer</h1><!-- Flight Selection --><div class=\"section\"><h3>Flight Selection</h3><div class=\"flight-controls\"><select id=\"flightDropdown\" disabled><option value=\"\">Loading flights...</option></select> <button id=\"loadDataButton\" disabled>Load Flight Data</button> <input type=\"text\" id=\"duplicateFlightTitle\" placeholder=\"New flight name\" disabled style=\"width: 200px;\"> <button id=\"duplicateFlightButton\" disabled>Duplicate Flight</button> <button id=\"deleteFlightButton\" disabled style=\"background-color: #dc3545;\">Delete Flight</button> <button id=\"refreshFlightsButton\">Refresh Flights</button> <button id=\"uploadButton\" type=\"button\" title=\"Import .sdlog, .sqlite, .db, or .csv files\">Import Data</button></div><div class=\"flight-controls\" style=\"margin-top: 10px;\"><button id=\"exportAirspeedAltitudeButton\" disabled style=\"background-color: #28a745;\">Export Airspeed & Altitude</button> <button id=\"exportFullDataButton\" disabled style=\"background-color: #6f42c1;\">Export Full Flight Data</button></div><div id=\"flightStatus\"></div><input type=\"file\" id=\"fileInput\" accept=\".sdlog,.sqlite,.db,.csv\" style=\"display: none;\"></div><!-- Markers --><div class=\"section\" id=\"controlsSection\" style=\"display: none;\"><h3>Markers</h3><div class=\"controls\"><input type=\"range\" id=\"markerTimeSlider\" class=\"slider\" min=\"0\" max=\"100\" value=\"0\" step=\"0.1\" disabled style=\"flex-grow: 1;\"> <label><input type=\"checkbox\" id=\"previewToggle\"> Show Preview</label> <input type=\"text\" id=\"markerLabelInput\" placeholder=\"Marker label\" disabled> <button id=\"addMarkerButton\" disabled>Add Marker</button> <button id=\"setTrimStartButton\" disabled style=\"background-color: #28a745;\">Set Trim Start</button> <button id=\"setTrimEndButton\" disabled style=\"background-color: #dc3545;\">Set Trim End</button> <button id=\"createDistanceMarkersButton\" disabled>Create 9nm Distance Markers</button> <button id=\"clearMarkersButton\" disabled>Clear All Markers</button></div><div class=\"controls\" style=\"margin-top: 10px;\"><input type=\"text\" id=\"trimmedFlightTitle\" placeholder=\"Trimmed flight name\" disabled style=\"width: 200px;\"> <button id=\"createTrimmedFlightButton\" disabled>Create Trimmed Flight</button></div><div class=\"time-display\" id=\"markerTimeDisplay\">Time: 0.0s</div><table class=\"markers-table\" id=\"markersTable\" style=\"display: none;\"><thead><tr><th>Time (s)</th><th>Label</th><th>Action</th></tr></thead> <tbody id=\"markersTableBody\"></tbody></table></div><!-- Statistics --><div class=\"section\" id=\"statisticsSection\" style=\"display: none;\"><div class=\"accordion\"><div class=\"accordion-header\" onclick=\"toggleAccordion('statisticsAccordion')\"><h3>Flight Data Statistics</h3><span class=\"accordion-icon\" id=\"statisticsAccordionIcon\">▼</span></div><div class=\"accordion-content\" id=\"statisticsAccordion\"><div id=\"statisticsContent\"><p>No statistics calculated yet. Load flight data to see variance and other statistics.</p></div></div></div></div><!-- Visualizations --><div class=\"section\" id=\"visualizationSection\" style=\"display: none;\"><div class=\"tabs\"><button class=\"tab active\" onclick=\"showTab('altitude')\">Altitude</button> <button class=\"tab\" onclick=\"showTab('map')\">GPS Position</button> <button class=\"tab\" onclick=\"showTab('airspeed')\">Airspeed</button></div><div id=\"altitude-tab\" class=\"tab-content active\"><div id=\"altitudeGraph\" class=\"graph-container\"></div></div><div id=\"map-tab\" class=\"tab-content\"><div id=\"mapGraph\" class=\"map-container\"></div></div><div id=\"airspeed-tab\" class=\"tab-content\"><div id=\"airspeedGraph\" class=\"graph-container\"></div></div></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<!doctype html><html lang=\"en\"><head><meta charset=\"UTF-8\"><meta name=\"viewport\" content=\"width=device-width, initial-scale=1.0\"><title>Flight Data Visualizer</title><script src=\"https://cdn.plot.ly/plotly-latest.min.js\"></script><style>\n\t\t\tbody {\n\t\t\t\tfont-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif;\n\t\t\t\tmargin: 0;\n\t\t\t\tpadding: 20px;\n\t\t\t\tbackground-color: #f5f5f5;\n\t\t\t}\n\t\t\t\n\t\t\t.container {\n\t\t\t\tmax-width: 1200px;\n\t\t\t\tmargin: 0 auto;\n\t\t\t\tbackground: white;\n\t\t\t\tpadding: 20px;\n\t\t\t\tborder-radius: 8px;\n\t\t\t\tbox-shadow: 0 2px 10px rgba(0,0,0,0.1);\n\t\t\t}\n\t\t\t\n\t\t\th1 {\n\t\t\t\ttext-align: center;\n\t\t\t\tcolor: #333;\n\t\t\t\tmargin-bottom: 30px;\n\t\t\t}\n\t\t\t\n\t\t\t.section {\n\t\t\t\tmargin-bottom: 30px;\n\t\t\t\tpadding: 20px;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 5px;\n\t\t\t\tbackground: #fafafa;\n\t\t\t}\n\t\t\t\n\t\t\t.section h3 {\n\t\t\t\tmargin-top: 0;\n\t\t\t\tcolor: #444;\n\t\t\t}\n\t\t\t\n\t\t\tinput[type=\"file\"] {\n\t\t\t\tdisplay: none;\n\t\t\t}\n\t\t\t\n\t\t\tbutton {\n\t\t\t\tbackground-color: #007cba;\n\t\t\t\tcolor: white;\n\t\t\t\tborder: none;\n\t\t\t\tpadding: 10px 20px;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\tcursor: pointer;\n\t\t\t\tfont-size: 14px;\n\t\t\t}\n\t\t\t\n\t\t\tbutton:hover {\n\t\t\t\tbackground-color: #005a8b;\n\t\t\t}\n\t\t\t\n\t\t\tbutton:disabled {\n\t\t\t\tbackground-color: #ccc;\n\t\t\t\tcursor: not-allowed;\n\t\t\t}\n\t\t\t\n\t\t\tselect {\n\t\t\t\twidth: 100%;\n\t\t\t\tpadding: 8px;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\tbackground: white;\n\t\t\t}\n\t\t\t\n\t\t\t.status {\n\t\t\t\tpadding: 10px;\n\t\t\t\tmargin: 10px 0;\n\t\t\t\tborder-radius: 4px;\n\t\t\t}\n\t\t\t\n\t\t\t.status.success {\n\t\t\t\tbackground-color: #d4edda;\n\t\t\t\tcolor: #155724;\n\t\t\t\tborder: 1px solid #c3e6cb;\n\t\t\t}\n\t\t\t\n\t\t\t.status.error {\n\t\t\t\tbackground-color: #f8d7da;\n\t\t\t\tcolor: #721c24;\n\t\t\t\tborder: 1px solid #f5c6cb;\n\t\t\t}\n\t\t\t\n\t\t\t.status.info {\n\t\t\t\tbackground-color: #cce7ff;\n\t\t\t\tcolor: #004085;\n\t\t\t\tborder: 1px solid #99d3ff;\n\t\t\t}\n\t\t\t\n\t\t\t.slider-container {\n\t\t\t\tmargin: 20px 0;\n\t\t\t}\n\t\t\t\n\t\t\t.slider {\n\t\t\t\twidth: 100%;\n\t\t\t\tmargin: 10px 0;\n\t\t\t}\n\t\t\t\n\t\t\t.time-display {\n\t\t\t\tcolor: #007cba;\n\t\t\t\tfont-weight: bold;\n\t\t\t\tmargin: 5px 0;\n\t\t\t}\n\t\t\t\n\t\t\t.controls {\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tgap: 10px;\n\t\t\t\tmargin-bottom: 10px;\n\t\t\t}\n\t\t\t\n\t\t\t.controls input[type=\"text\"] {\n\t\t\t\tpadding: 6px;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 4px;\n\t\t\t}\n\t\t\t\n\t\t\t.markers-table {\n\t\t\t\twidth: 100%;\n\t\t\t\tborder-collapse: collapse;\n\t\t\t\tmargin-top: 10px;\n\t\t\t}\n\t\t\t\n\t\t\t.markers-table th,\n\t\t\t.markers-table td {\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tpadding: 8px;\n\t\t\t\ttext-align: left;\n\t\t\t}\n\t\t\t\n\t\t\t.markers-table th {\n\t\t\t\tbackground-color: #f2f2f2;\n\t\t\t}\n\t\t\t\n\t\t\t.tabs {\n\t\t\t\tdisplay: flex;\n\t\t\t\tborder-bottom: 1px solid #ddd;\n\t\t\t\tmargin-bottom: 20px;\n\t\t\t}\n\t\t\t\n\t\t\t.tab {\n\t\t\t\tpadding: 10px 20px;\n\t\t\t\tcursor: pointer;\n\t\t\t\tborder: none;\n\t\t\t\tbackground: none;\n\t\t\t\tborder-bottom: 2px solid transparent;\n\t\t\t}\n\t\t\t\n\t\t\t.tab.active {\n\t\t\t\tborder-bottom-color: #007cba;\n\t\t\t\tcolor: #007cba;\n\t\t\t}\n\t\t\t\n\t\t\t.tab-content {\n\t\t\t\tdisplay: none;\n\t\t\t}\n\t\t\t\n\t\t\t.tab-content.active {\n\t\t\t\tdisplay: block;\n\t\t\t}\n\t\t\t\n\t\t\t.graph-container {\n\t\t\t\theight: 400px;\n\t\t\t\tmargin-bottom: 20px;\n\t\t\t}\n\t\t\t\n\t\t\t.map-container {\n\t\t\t\theight: 600px;\n\t\t\t\twidth: 100%;\n\t\t\t\tmargin-bottom: 20px;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\toverflow: hidden;\n\t\t\t}\n\t\t\t\n\t\t\t.subsection {\n\t\t\t\tmargin-bottom: 20px;\n\t\t\t\tpadding: 15px;\n\t\t\t\tborder: 1px solid #eee;\n\t\t\t\tborder-radius: 4px;\n\t\t\t\tbackground: white;\n\t\t\t}\n\t\t\t\n\t\t\t.subsection h4 {\n\t\t\t\tmargin-top: 0;\n\t\t\t\tmargin-bottom: 15px;\n\t\t\t\tcolor: #555;\n\t\t\t\tfont-size: 16px;\n\t\t\t}\n\t\t\t\n\t\t\t.controls {\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tgap: 10px;\n\t\t\t\tflex-wrap: wrap;\n\t\t\t}\n\t\t\t\n\t\t\t.flight-controls {\n\t\t\t\tdisplay: flex;\n\t\t\t\talign-items: center;\n\t\t\t\tgap: 10px;\n\t\t\t\tmargin-bottom: 10px;\n\t\t\t}\n\t\t\t\n\t\t\t.flight-controls select {\n\t\t\t\tflex-grow: 1;\n\t\t\t}\n\t\t\t\n\t\t\t.statistics-container {\n\t\t\t\tdisplay: grid;\n\t\t\t\tgrid-template-columns: repeat(auto-fit, minmax(300px, 1fr));\n\t\t\t\tgap: 20px;\n\t\t\t\tmargin-top: 20px;\n\t\t\t}\n\t\t\t\n\t\t\t.aircraft-stats {\n\t\t\t\tbackground: white;\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 5px;\n\t\t\t\tpadding: 15px;\n\t\t\t}\n\t\t\t\n\t\t\t.aircraft-stats h4 {\n\t\t\t\tmargin-top: 0;\n\t\t\t\tmargin-bottom: 15px;\n\t\t\t\tcolor: #007cba;\n\t\t\t\tborder-bottom: 1px solid #eee;\n\t\t\t\tpadding-bottom: 5px;\n\t\t\t}\n\t\t\t\n\t\t\t.stats-table {\n\t\t\t\twidth: 100%;\n\t\t\t\tborder-collapse: collapse;\n\t\t\t\tfont-size: 14px;\n\t\t\t}\n\t\t\t\n\t\t\t.stats-table th,\n\t\t\t.stats-table td {\n\t\t\t\ttext-align: left;\n\t\t\t\tpadding: 8px 5px;\n\t\t\t\tborder-bottom: 1px solid #f0f0f0;\n\t\t\t}\n\t\t\t\n\t\t\t.stats-table th {\n\t\t\t\tbackground-color: #f8f9fa;\n\t\t\t\tfont-weight: bold;\n\t\t\t}\n\t\t\t\n\t\t\t.stats-table .metric-name {\n\t\t\t\twidth: 40%;\n\t\t\t}\n\t\t\t\n\t\t\t.stats-table .metric-value {\n\t\t\t\twidth: 30%;\n\t\t\t\ttext-align: right;\n\t\t\t}\n\t\t\t\n\t\t\t.variance-highlight {\n\t\t\t\tbackground-color: #fff3cd;\n\t\t\t\tfont-weight: bold;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion {\n\t\t\t\tborder: 1px solid #ddd;\n\t\t\t\tborder-radius: 5px;\n\t\t\t\tbackground: white;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-header {\n\t\t\t\tdisplay: flex;\n\t\t\t\tjustify-content: space-between;\n\t\t\t\talign-items: center;\n\t\t\t\tpadding: 15px 20px;\n\t\t\t\tcursor: pointer;\n\t\t\t\tbackground: #f8f9fa;\n\t\t\t\tborder-bottom: 1px solid #ddd;\n\t\t\t\ttransition: background-color 0.2s;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-header:hover {\n\t\t\t\tbackground: #e9ecef;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-header h3 {\n\t\t\t\tmargin: 0;\n\t\t\t\tcolor: #333;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-icon {\n\t\t\t\tfont-size: 16px;\n\t\t\t\ttransition: transform 0.2s;\n\t\t\t\tcolor: #007cba;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-icon.rotated {\n\t\t\t\ttransform: rotate(180deg);\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-content {\n\t\t\t\tmax-height: 0;\n\t\t\t\toverflow: hidden;\n\t\t\t\ttransition: max-height 0.3s ease-out;\n\t\t\t\tpadding: 0 20px;\n\t\t\t}\n\t\t\t\n\t\t\t.accordion-content.open {\n\t\t\t\tmax-height: 2000px;\n\t\t\t\tpadding: 20px;\n\t\t\t\ttransition: max-height 0.3s ease-in;\n\t\t\t}\n\t\t</style></head><body><div class=\"container\"><h1>Flight Data Visualizer</h1><!-- Flight Selection --><div class=\"section\"><h3>Flight Selection</h3><div class=\"flight-controls\"><select id=\"flightDropdown\" disabled><option value=\"\">Loading flights...</option></select> <button id=\"loadDataButton\" disabled>Load Flight Data</button> <input type=\"text\" id=\"duplicateFlightTitle\" placeholder=\"New flight name\" disabled style=\"width: 200px;\"> <button id=\"duplicateFlightButton\" disabled>Duplicate Flight</button> <input type=\"number\" id=\"resampleRateInput\" value=\"1\" min=\"0.1\" max=\"100\" step=\"0.1\" disabled style=\"width: 70px;\" title=\"Sample rate in Hz\"> <button id=\"resampleFlightButton\" disabled title=\"Create a copy of the flight resampled to the given rate\">Resample (Hz)</button> <button id=\"deleteFlightButton\" disabled style=\"background-color: #dc3545;\" title=\"Hide the flight from the list; it can be restored from the archived flights\">Archive Flight</button> <button id=\"refreshFlightsButton\">Refresh Flights</button> <button id=\"uploadButton\" type=\"button\" title=\"Import .sdlog, .sqlite, .db, or .csv files\">Import Data</button> <button id=\"findLogbooksButton\" type=\"button\" title=\"List the Sky Dolly logbooks on this machine\">Find Logbooks</button> <button id=\"archivedFlightsButton\" type=\"button\" title=\"List the archived flights to restore or purge them\">Archived Flights</button> <label title=\"Import what can be salvaged when individual tables of a recording fail\"><input type=\"checkbox\" id=\"partialImportToggle\"> Partial import</label> <label title=\"Import recordings and flights again that were imported before, instead of skipping them\"><input type=\"checkbox\" id=\"forceImportToggle\"> Import duplicates</label> <button id=\"cancelImportButton\" type=\"button\" style=\"display: none; background-color: #dc3545;\" title=\"Cancel the imports that are still queued or running\">Cancel Import</button></div><div id=\"logbookList\" style=\"display: none; margin-top: 10px;\"></div><div id=\"archivedFlightList\" style=\"display: none; margin-top: 10px;\"></div><div class=\"flight-controls\" style=\"margin-top: 10px;\"><button id=\"exportAirspeedAltitudeButton\" disabled style=\"background-color: #28a745;\">Export Airspeed & Altitude</button> <button id=\"exportFullDataButton\" disabled style=\"background-color: #6f42c1;\">Export Full Flight Data</button> <button id=\"exportAllFlightsButton\" title=\"Export every flight into one ZIP with a folder per flight\">Export All Flights</button> <button id=\"exportStatisticsButton\" title=\"Download the statistics of every flight as CSV, one row per aircraft per metric\">Export Statistics</button> <button id=\"backupButton\" title=\"Download a consistent copy of the analysis database\">Backup Database</button></div><div id=\"flightStatus\"></div><input type=\"file\" id=\"fileInput\" accept=\".sdlog,.sqlite,.db,.csv\" multiple style=\"display: none;\"></div><!-- Markers --><div class=\"section\" id=\"controlsSection\" style=\"display: none;\"><h3>Markers</h3><div class=\"controls\"><input type=\"range\" id=\"markerTimeSlider\" class=\"slider\" min=\"0\" max=\"100\" value=\"0\" step=\"0.1\" disabled style=\"flex-grow: 1;\"> <label><input type=\"checkbox\" id=\"previewToggle\"> Show Preview</label> <button id=\"replayButton\" disabled title=\"Replay the flight on the preview. Space plays or pauses, M adds a marker at the replay cursor.\">Play</button> <select id=\"replaySpeedSelect\" disabled title=\"Replay speed\"><option value=\"1\">1×</option> <option value=\"2\">2×</option> <option value=\"4\">4×</option> <option value=\"8\">8×</option></select> <input type=\"text\" id=\"markerLabelInput\" placeholder=\"Marker label\" disabled> <select id=\"markerCategorySelect\" disabled><option value=\"observation\">Observation</option> <option value=\"failure\">Failure</option> <option value=\"phase\">Phase</option></select> <button id=\"addMarkerButton\" disabled>Add Marker</button> <button id=\"setTrimStartButton\" disabled style=\"background-color: #28a745;\">Set Trim Start</button> <button id=\"setTrimEndButton\" disabled style=\"background-color: #dc3545;\">Set Trim End</button> <button id=\"createDistanceMarkersButton\" disabled>Create Distance Markers</button> <button id=\"createWarningMarkersButton\" disabled>Detect Warnings</button> <button id=\"createOscillationMarkersButton\" disabled>Detect Oscillations</button> <button id=\"createEventMarkersButton\" disabled>Overlay Events</button> <button id=\"clearMarkersButton\" disabled>Clear All Markers</button></div><div class=\"controls\" style=\"margin-top: 10px;\"><input type=\"text\" id=\"trimmedFlightTitle\" placeholder=\"Trimmed flight name\" disabled style=\"width: 200px;\"> <button id=\"createTrimmedFlightButton\" disabled>Create Trimmed Flight</button></div><div class=\"time-display\" id=\"markerTimeDisplay\">Time: 0.0s</div><table class=\"markers-table\" id=\"markersTable\" style=\"display: none;\"><thead><tr><th>Time (s)</th><th>Label</th><th>Category</th><th>Action</th></tr></thead> <tbody id=\"markersTableBody\"></tbody></table></div><!-- Statistics --><div class=\"section\" id=\"statisticsSection\" style=\"display: none;\"><div class=\"accordion\"><div class=\"accordion-header\" onclick=\"toggleAccordion('statisticsAccordion')\"><h3>Flight Data Statistics</h3><span class=\"accordion-icon\" id=\"statisticsAccordionIcon\">▼</span></div><div class=\"accordion-content\" id=\"statisticsAccordion\"><div id=\"statisticsContent\"><p>No statistics calculated yet. Load flight data to see variance and other statistics.</p></div></div></div></div><!-- Visualizations --><div class=\"section\" id=\"visualizationSection\" style=\"display: none;\"><div class=\"tabs\"><button class=\"tab active\" onclick=\"showTab('altitude')\">Altitude</button> <button class=\"tab\" onclick=\"showTab('map')\">GPS Position</button> <button class=\"tab\" onclick=\"showTab('airspeed')\">Airspeed</button></div><div id=\"altitude-tab\" class=\"tab-content active\"><div id=\"altitudeGraph\" class=\"graph-container\"></div></div><div id=\"map-tab\" class=\"tab-content\"><div id=\"mapGraph\" class=\"map-container\"></div></div><div id=\"airspeed-tab\" class=\"tab-content\"><div id=\"airspeedGraph\" class=\"graph-container\"></div></div></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -0,0 +1,166 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Page sizes of the paged position endpoint
+const (
+	defaultPositionPageLimit = 1000
+	maxPositionPageLimit     = 10000
+)
+
+// ndjsonFlushInterval is the number of lines written between flushes of the NDJSON stream
+const ndjsonFlushInterval = 1000
+
+// PositionPage is one window of the position samples of an aircraft
+type PositionPage struct {
+	Aircraft   string          `json:"aircraft"`
+	Offset     int             `json:"offset"`
+	Limit      int             `json:"limit"`
+	Total      int             `json:"total"`
+	NextOffset *int            `json:"next_offset"` // nil on the last page
+	Positions  []PositionPoint `json:"positions"`
+	Units      UnitSystem      `json:"units"`
+}
+
+// positionLine is one line of the NDJSON position stream
+type positionLine struct {
+	Aircraft string `json:"aircraft"`
+	PositionPoint
+}
+
+// getAircraftByLabel returns the aircraft of a flight with the given label, or sql.ErrNoRows
+func getAircraftByLabel(flightID int, label string) (*Aircraft, error) {
+	aircraft, err := getAircraftByFlightIDFromMainDB(flightID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range aircraft {
+		if aircraft[i].Label() == label {
+			return &aircraft[i], nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// parsePageParameter parses a non-negative integer query parameter, returning def if absent
+func parsePageParameter(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s '%s'", name, value)
+	}
+	return n, nil
+}
+
+// handleGetPositionPage returns a window of the position samples of one aircraft, the user aircraft
+// unless the aircraft parameter names another
+func handleGetPositionPage(w http.ResponseWriter, r *http.Request, flightId int) {
+	offset, err := parsePageParameter(r, "offset", 0)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := parsePageParameter(r, "limit", defaultPositionPageLimit)
+	if err != nil || limit == 0 || limit > maxPositionPageLimit {
+		httpError(w, fmt.Sprintf("Invalid limit (expected 1 to %d)", maxPositionPageLimit), http.StatusBadRequest)
+		return
+	}
+
+	label := r.URL.Query().Get("aircraft")
+	if label == "" {
+		label, err = getUserAircraftLabel(flightId)
+		if err == sql.ErrNoRows {
+			httpError(w, "Flight has no aircraft", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			httpError(w, fmt.Sprintf("Failed to get user aircraft: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	aircraft, err := getAircraftByLabel(flightId, label)
+	if err == sql.ErrNoRows {
+		httpError(w, fmt.Sprintf("Aircraft '%s' not found", label), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get aircraft: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	positions, err := getPositionDataWithAirspeedFromMainDB(aircraft.ID)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get position data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	page := PositionPage{
+		Aircraft:  label,
+		Offset:    offset,
+		Limit:     limit,
+		Total:     len(positions),
+		Positions: []PositionPoint{},
+		Units:     configuredUnits(),
+	}
+	if offset < len(positions) {
+		end := min(offset+limit, len(positions))
+		page.Positions = positions[offset:end]
+		if end < len(positions) {
+			page.NextOffset = &end
+		}
+	}
+	page.Units.convertPositions(page.Positions)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// handleStreamPositions streams the position samples of all aircraft as newline-delimited JSON, one
+// sample per line in the configured units, loading one aircraft at a time
+func handleStreamPositions(w http.ResponseWriter, r *http.Request, flightId int) {
+	units := configuredUnits()
+
+	aircraft, err := getAircraftByFlightIDFromMainDB(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get aircraft: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	lines := 0
+	for _, ac := range aircraft {
+		positions, err := getPositionDataWithAirspeedFromMainDB(ac.ID)
+		if err != nil {
+			// Headers are sent, so the stream can only end early
+			log.Printf("Position stream: failed to get position data of aircraft %d: %v", ac.ID, err)
+			return
+		}
+
+		units.convertPositions(positions)
+
+		label := ac.Label()
+		for _, p := range positions {
+			if err := encoder.Encode(positionLine{Aircraft: label, PositionPoint: p}); err != nil {
+				return
+			}
+			lines++
+			if flusher != nil && lines%ndjsonFlushInterval == 0 {
+				flusher.Flush()
+			}
+		}
+	}
+}
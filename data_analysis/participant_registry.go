@@ -0,0 +1,296 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Participant is a study participant of the registry, which flights are attributed to
+type Participant struct {
+	ID        string `json:"id"` // e.g. "P001", as used in flight assignments and sessions
+	Pseudonym string `json:"pseudonym"`
+	Group     string `json:"group"` // Study group or condition order the participant was assigned to
+	Notes     string `json:"notes"`
+	CreatedAt string `json:"created_at,omitempty"`
+	FlightIDs []int  `json:"flight_ids"` // Flights assigned to the participant, read-only
+}
+
+// errDuplicateParticipant is returned when a participant ID is already registered
+var errDuplicateParticipant = errors.New("a participant with this ID already exists")
+
+// errParticipantHasFlights is returned when deleting a participant flights are still assigned to
+var errParticipantHasFlights = errors.New("flights are assigned to the participant; unassign them first")
+
+// ensureParticipantTable creates the participant registry
+func ensureParticipantTable() error {
+	participantSchema := `
+		CREATE TABLE IF NOT EXISTS participant (
+			id TEXT PRIMARY KEY,
+			pseudonym TEXT NOT NULL DEFAULT '',
+			group_name TEXT NOT NULL DEFAULT '',
+			notes TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`
+	if _, err := mainDB.Exec(participantSchema); err != nil {
+		return fmt.Errorf("failed to create participant table: %w", err)
+	}
+	return nil
+}
+
+// validate checks the ID of a participant and trims its fields
+func (p *Participant) validate() error {
+	p.ID = strings.TrimSpace(p.ID)
+	p.Pseudonym = strings.TrimSpace(p.Pseudonym)
+	p.Group = strings.TrimSpace(p.Group)
+	if p.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if strings.ContainsAny(p.ID, "/?#") {
+		return fmt.Errorf("id must not contain '/', '?' or '#'")
+	}
+	return nil
+}
+
+// participantFlightIDs returns the flights assigned to each participant
+func participantFlightIDs() (map[string][]int, error) {
+	rows, err := mainDB.Query("SELECT participant_id, id FROM flight WHERE participant_id IS NOT NULL ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flightIDs := map[string][]int{}
+	for rows.Next() {
+		var participantID string
+		var flightID int
+		if err := rows.Scan(&participantID, &flightID); err != nil {
+			return nil, err
+		}
+		flightIDs[participantID] = append(flightIDs[participantID], flightID)
+	}
+	return flightIDs, rows.Err()
+}
+
+// getParticipants returns the registry ordered by ID
+func getParticipants() ([]Participant, error) {
+	flightIDs, err := participantFlightIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := mainDB.Query("SELECT id, pseudonym, group_name, notes, created_at FROM participant ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	participants := []Participant{}
+	for rows.Next() {
+		var p Participant
+		var createdAt sql.NullString
+		if err := rows.Scan(&p.ID, &p.Pseudonym, &p.Group, &p.Notes, &createdAt); err != nil {
+			return nil, err
+		}
+		p.CreatedAt = createdAt.String
+		p.FlightIDs = flightIDs[p.ID]
+		if p.FlightIDs == nil {
+			p.FlightIDs = []int{}
+		}
+		participants = append(participants, p)
+	}
+	return participants, rows.Err()
+}
+
+// getParticipant returns one participant, or sql.ErrNoRows
+func getParticipant(id string) (*Participant, error) {
+	var p Participant
+	var createdAt sql.NullString
+	err := mainDB.QueryRow("SELECT id, pseudonym, group_name, notes, created_at FROM participant WHERE id = ?", id).
+		Scan(&p.ID, &p.Pseudonym, &p.Group, &p.Notes, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	p.CreatedAt = createdAt.String
+
+	flightIDs, err := participantFlightIDs()
+	if err != nil {
+		return nil, err
+	}
+	p.FlightIDs = flightIDs[p.ID]
+	if p.FlightIDs == nil {
+		p.FlightIDs = []int{}
+	}
+	return &p, nil
+}
+
+// createParticipant registers a validated participant
+func createParticipant(p Participant) error {
+	_, err := mainDB.Exec("INSERT INTO participant (id, pseudonym, group_name, notes) VALUES (?, ?, ?, ?)",
+		p.ID, p.Pseudonym, p.Group, p.Notes)
+	if isUniqueViolation(err) {
+		return errDuplicateParticipant
+	}
+	return err
+}
+
+// updateParticipant changes the pseudonym, group and notes of a participant
+func updateParticipant(p Participant) error {
+	result, err := mainDB.Exec("UPDATE participant SET pseudonym = ?, group_name = ?, notes = ? WHERE id = ?",
+		p.Pseudonym, p.Group, p.Notes, p.ID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// deleteParticipant removes a participant without assigned flights from the registry
+func deleteParticipant(id string) error {
+	var assigned int
+	if err := mainDB.QueryRow("SELECT COUNT(*) FROM flight WHERE participant_id = ?", id).Scan(&assigned); err != nil {
+		return err
+	}
+	if assigned > 0 {
+		return errParticipantHasFlights
+	}
+
+	result, err := mainDB.Exec("DELETE FROM participant WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// participantFromRequest decodes and validates the participant of a request body; on failure it writes
+// the error response and returns false
+func participantFromRequest(w http.ResponseWriter, r *http.Request) (Participant, bool) {
+	var p Participant
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return p, false
+	}
+	if err := p.validate(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return p, false
+	}
+	return p, true
+}
+
+// handleGetParticipants lists the participant registry
+func handleGetParticipants(w http.ResponseWriter, r *http.Request) {
+	participants, err := getParticipants()
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get participants: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(participants)
+}
+
+// handleGetParticipant returns one participant with its flights
+func handleGetParticipant(w http.ResponseWriter, r *http.Request) {
+	p, err := getParticipant(r.PathValue("participantId"))
+	if err == sql.ErrNoRows {
+		httpError(w, "Participant not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get participant: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// handleCreateParticipant registers a participant
+func handleCreateParticipant(w http.ResponseWriter, r *http.Request) {
+	p, ok := participantFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := createParticipant(p)
+	if err == errDuplicateParticipant {
+		httpError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create participant: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Registered participant '%s'", p.ID)
+
+	created, err := getParticipant(p.ID)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get participant: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleUpdateParticipant changes the pseudonym, group and notes of a participant; its ID is fixed
+func handleUpdateParticipant(w http.ResponseWriter, r *http.Request) {
+	var p Participant
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	p.ID = r.PathValue("participantId")
+	if err := p.validate(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := updateParticipant(p)
+	if err == sql.ErrNoRows {
+		httpError(w, "Participant not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to update participant: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := getParticipant(p.ID)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get participant: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// handleDeleteParticipant removes a participant without assigned flights from the registry
+func handleDeleteParticipant(w http.ResponseWriter, r *http.Request) {
+	err := deleteParticipant(r.PathValue("participantId"))
+	if err == sql.ErrNoRows {
+		httpError(w, "Participant not found", http.StatusNotFound)
+		return
+	}
+	if err == errParticipantHasFlights {
+		httpError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to delete participant: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
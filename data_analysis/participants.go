@@ -0,0 +1,312 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ParticipantFlightStatistics holds the statistics of the participant-flown aircraft of one flight
+type ParticipantFlightStatistics struct {
+	Flight          Flight            `json:"flight"`
+	AircraftLabel   string            `json:"aircraft_label"`
+	Statistics      *FlightStatistics `json:"statistics"`
+	ResponseLatency *ResponseLatency  `json:"response_latency"` // nil without aircraft
+	// Root mean square error of the altitude against the configured altitude reference profile, nil
+	// without one or without samples within the profile
+	AltitudeRMSE *float64 `json:"altitude_rmse"`
+}
+
+// flightConditions are the study conditions a participant flight can be recorded under
+var flightConditions = []string{"baseline", "failure"}
+
+// errUnregisteredParticipant is returned when assigning a flight to a participant not in the registry
+var errUnregisteredParticipant = errors.New("participant is not registered")
+
+// ensureFlightParticipantColumns adds the participant a flight is assigned to, a reference into the
+// participant registry, and the condition it was flown under to the flight table. Assignments kept in the
+// former flight_participant table are moved over and their participants registered.
+func ensureFlightParticipantColumns() error {
+	for _, column := range []string{
+		"participant_id TEXT REFERENCES participant(id)",
+		"participant_condition TEXT NOT NULL DEFAULT ''",
+	} {
+		name := strings.Fields(column)[0]
+		var exists bool
+		err := mainDB.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('flight') WHERE name = ?", name).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to get flight table info: %w", err)
+		}
+		if exists {
+			continue
+		}
+		if _, err := mainDB.Exec("ALTER TABLE flight ADD COLUMN " + column); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", name, err)
+		}
+	}
+	if _, err := mainDB.Exec("CREATE INDEX IF NOT EXISTS idx_flight_participant_id ON flight(participant_id)"); err != nil {
+		return fmt.Errorf("failed to create participant index: %w", err)
+	}
+
+	var legacyTable bool
+	err := mainDB.QueryRow("SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'flight_participant'").Scan(&legacyTable)
+	if err != nil {
+		return fmt.Errorf("failed to look up flight_participant table: %w", err)
+	}
+	if !legacyTable {
+		return nil
+	}
+
+	// Tables created before conditions were recorded lack the condition column
+	condition := "''"
+	var conditionExists bool
+	err = mainDB.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('flight_participant') WHERE name = 'condition'").Scan(&conditionExists)
+	if err != nil {
+		return fmt.Errorf("failed to get flight_participant table info: %w", err)
+	}
+	if conditionExists {
+		condition = "p.condition"
+	}
+
+	tx, err := mainDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT OR IGNORE INTO participant (id) SELECT DISTINCT participant_id FROM flight_participant"); err != nil {
+		return fmt.Errorf("failed to register assigned participants: %w", err)
+	}
+	result, err := tx.Exec(`
+		UPDATE flight
+		SET participant_id = (SELECT p.participant_id FROM flight_participant p WHERE p.flight_id = flight.id),
+		    participant_condition = (SELECT ` + condition + ` FROM flight_participant p WHERE p.flight_id = flight.id)
+		WHERE id IN (SELECT flight_id FROM flight_participant)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to move participant assignments: %w", err)
+	}
+	if _, err := tx.Exec("DROP TABLE flight_participant"); err != nil {
+		return fmt.Errorf("failed to drop flight_participant table: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if moved, err := result.RowsAffected(); err == nil {
+		log.Printf("Moved %d participant assignments to the flight table", moved)
+	}
+	return nil
+}
+
+// setFlightParticipant assigns a flight flown under a condition to a registered participant, or
+// errUnregisteredParticipant; an empty participant ID removes the assignment
+func setFlightParticipant(flightID int, participantID, condition string) error {
+	if participantID == "" {
+		_, err := mainDB.Exec("UPDATE flight SET participant_id = NULL, participant_condition = '' WHERE id = ?", flightID)
+		return err
+	}
+
+	// Checked in the same statement, so a participant deleted meanwhile is not referenced
+	result, err := mainDB.Exec(`
+		UPDATE flight SET participant_id = ?, participant_condition = ?
+		WHERE id = ? AND EXISTS (SELECT 1 FROM participant WHERE id = ?)
+	`, participantID, condition, flightID, participantID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errUnregisteredParticipant
+	}
+	return nil
+}
+
+// handleSetFlightParticipant assigns a flight to a study participant
+func handleSetFlightParticipant(w http.ResponseWriter, r *http.Request, flightId int) {
+	var request struct {
+		ParticipantID string `json:"participant_id"`
+		Condition     string `json:"condition"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	participantID := strings.TrimSpace(request.ParticipantID)
+	if participantID != "" {
+		if err := (&Participant{ID: participantID}).validate(); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if request.Condition != "" && !slices.Contains(flightConditions, request.Condition) {
+		httpError(w, fmt.Sprintf("Unknown condition '%s' (available: %s)", request.Condition, strings.Join(flightConditions, ", ")), http.StatusBadRequest)
+		return
+	}
+	if participantID == "" && request.Condition != "" {
+		httpError(w, "A condition requires a participant", http.StatusBadRequest)
+		return
+	}
+
+	err := setFlightParticipant(flightId, participantID, request.Condition)
+	if err == errUnregisteredParticipant {
+		httpError(w, fmt.Sprintf("Participant '%s' is not registered; register it first", participantID), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to assign participant: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Assigned flight %d to participant '%s' (condition '%s')", flightId, participantID, request.Condition)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":         "success",
+		"flight_id":      flightId,
+		"participant_id": participantID,
+		"condition":      request.Condition,
+	})
+}
+
+// getUserAircraftLabel returns the label of the aircraft flown by the participant, i.e. the
+// flight's user aircraft, falling back to the first aircraft
+func getUserAircraftLabel(flightID int) (string, error) {
+	query := `
+		SELECT a.type, a.tail_number
+		FROM aircraft a
+		JOIN flight f ON f.id = a.flight_id
+		WHERE a.flight_id = ?
+		ORDER BY (a.seq_nr = f.user_aircraft_seq_nr) DESC, a.seq_nr
+		LIMIT 1
+	`
+
+	var ac Aircraft
+	var tailNumber sql.NullString
+	if err := mainDB.QueryRow(query, flightID).Scan(&ac.Type, &tailNumber); err != nil {
+		return "", err
+	}
+	ac.TailNumber = tailNumber.String
+	return ac.Label(), nil
+}
+
+// altitudeReferenceProfile returns the reference profile configured for the altitude RMSE of the study
+// metrics, nil if none is configured or it is not stored or targets no altitude
+func altitudeReferenceProfile() (*ReferenceProfile, error) {
+	name := analysisConfig.AltitudeReferenceProfile
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, err := getReferenceProfileByName(name)
+	if err == sql.ErrNoRows {
+		log.Printf("Altitude reference profile '%s' is not stored", name)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get altitude reference profile: %w", err)
+	}
+	if profile.Channel != "altitude" && profile.Channel != "indicated_altitude" {
+		log.Printf("Altitude reference profile '%s' targets %s rather than an altitude", name, profile.Channel)
+		return nil, nil
+	}
+	return profile, nil
+}
+
+// altitudeRMSE returns the root mean square error of the altitude of one aircraft of a flight against a
+// reference profile, nil if the aircraft has no samples within the profile
+func altitudeRMSE(flightID int, label string, profile *ReferenceProfile) (*float64, error) {
+	columns, err := getFlightColumns(flightID)
+	if err != nil {
+		return nil, err
+	}
+	series, exists := columns[label]
+	if !exists {
+		return nil, nil
+	}
+
+	trackingError := calculateTrackingError(series.Time, trackingChannels[profile.Channel](series), 0, profile.value, 0)
+	if trackingError.Overall == nil {
+		return nil, nil
+	}
+	rmse := trackingError.Overall.RMSE
+	return &rmse, nil
+}
+
+// GetParticipantFlightStatistics returns the statistics of all flights assigned to participants, keyed by
+// participant ID; rejected and archived flights are left out
+func GetParticipantFlightStatistics() (map[string][]ParticipantFlightStatistics, error) {
+	rows, err := mainDB.Query("SELECT id, participant_id FROM flight WHERE participant_id IS NOT NULL ORDER BY participant_id, id")
+	if err != nil {
+		return nil, err
+	}
+
+	type assignment struct {
+		flightID      int
+		participantID string
+	}
+	var assignments []assignment
+	for rows.Next() {
+		var a assignment
+		if err := rows.Scan(&a.flightID, &a.participantID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		assignments = append(assignments, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	profile, err := altitudeReferenceProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string][]ParticipantFlightStatistics{}
+	for _, a := range assignments {
+		flight, err := getFlightByIDFromMainDB(a.flightID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get flight %d: %w", a.flightID, err)
+		}
+		if flight.ReviewStatus == ReviewRejected || flight.ArchivedAt != "" {
+			continue
+		}
+
+		label, err := getUserAircraftLabel(a.flightID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get user aircraft of flight %d: %w", a.flightID, err)
+		}
+
+		statistics, err := getFlightStatistics(a.flightID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statistics of flight %d: %w", a.flightID, err)
+		}
+
+		latency, err := calculateResponseLatency(a.flightID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to get response latency of flight %d: %w", a.flightID, err)
+		}
+
+		var rmse *float64
+		if profile != nil && label != "" {
+			if rmse, err = altitudeRMSE(a.flightID, label, profile); err != nil {
+				return nil, fmt.Errorf("failed to get altitude RMSE of flight %d: %w", a.flightID, err)
+			}
+		}
+
+		result[a.participantID] = append(result[a.participantID], ParticipantFlightStatistics{
+			Flight:          *flight,
+			AircraftLabel:   label,
+			Statistics:      statistics[label],
+			ResponseLatency: latency,
+			AltitudeRMSE:    rmse,
+		})
+	}
+
+	return result, nil
+}
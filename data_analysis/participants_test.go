@@ -0,0 +1,131 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestAltitudeReferenceProfile(t *testing.T) {
+	openTestDatabase(t)
+	previous := analysisConfig
+	t.Cleanup(func() { analysisConfig = previous })
+
+	points := []ProfilePoint{{Time: 0, Value: 1500}, {Time: 600, Value: 3000}}
+	for _, p := range []ReferenceProfile{
+		{Name: "Climb", Channel: "altitude", Points: points},
+		{Name: "Speed", Channel: "airspeed", Points: points},
+	} {
+		if _, err := createReferenceProfile(p); err != nil {
+			t.Fatalf("failed to create reference profile: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		want    string
+	}{
+		{"not configured", "", ""},
+		{"altitude profile", "Climb", "Climb"},
+		{"not stored", "Descent", ""},
+		{"airspeed profile", "Speed", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysisConfig.AltitudeReferenceProfile = tt.profile
+			profile, err := altitudeReferenceProfile()
+			if err != nil {
+				t.Fatalf("altitudeReferenceProfile() error: %v", err)
+			}
+			got := ""
+			if profile != nil {
+				got = profile.Name
+			}
+			if got != tt.want {
+				t.Errorf("altitudeReferenceProfile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetFlightParticipant(t *testing.T) {
+	openTestDatabase(t)
+	flightID, _ := insertTestFlight(t, "Flight", "", "D-TEST")
+	if err := createParticipant(Participant{ID: "P001"}); err != nil {
+		t.Fatalf("failed to register participant: %v", err)
+	}
+
+	if err := setFlightParticipant(flightID, "P01", "baseline"); err != errUnregisteredParticipant {
+		t.Errorf("assigning an unregistered participant returned %v, want errUnregisteredParticipant", err)
+	}
+	if _, err := getParticipant("P01"); err != sql.ErrNoRows {
+		t.Errorf("unregistered participant was registered (%v)", err)
+	}
+
+	if err := setFlightParticipant(flightID, "P001", "failure"); err != nil {
+		t.Fatalf("failed to assign participant: %v", err)
+	}
+	flight, err := getFlightByIDFromMainDB(flightID)
+	if err != nil {
+		t.Fatalf("failed to get flight: %v", err)
+	}
+	if flight.ParticipantID != "P001" || flight.Condition != "failure" {
+		t.Errorf("flight participant = %q (%q), want P001 (failure)", flight.ParticipantID, flight.Condition)
+	}
+	if err := deleteParticipant("P001"); err != errParticipantHasFlights {
+		t.Errorf("deleting an assigned participant returned %v, want errParticipantHasFlights", err)
+	}
+
+	if err := setFlightParticipant(flightID, "", ""); err != nil {
+		t.Fatalf("failed to remove assignment: %v", err)
+	}
+	if flight, _ := getFlightByIDFromMainDB(flightID); flight.ParticipantID != "" || flight.Condition != "" {
+		t.Errorf("flight participant = %q (%q) after removing the assignment", flight.ParticipantID, flight.Condition)
+	}
+}
+
+func TestEnsureFlightParticipantColumnsMigratesAssignments(t *testing.T) {
+	openTestDatabase(t)
+	assigned, _ := insertTestFlight(t, "Assigned", "", "D-TEST")
+	unassigned, _ := insertTestFlight(t, "Unassigned", "", "D-TEST")
+
+	legacy := `
+		CREATE TABLE flight_participant (
+			flight_id INTEGER PRIMARY KEY,
+			participant_id TEXT NOT NULL,
+			condition TEXT NOT NULL DEFAULT ''
+		);
+	`
+	if _, err := mainDB.Exec(legacy); err != nil {
+		t.Fatalf("failed to create flight_participant table: %v", err)
+	}
+	if _, err := mainDB.Exec("INSERT INTO flight_participant VALUES (?, 'P007', 'baseline')", assigned); err != nil {
+		t.Fatalf("failed to insert assignment: %v", err)
+	}
+
+	// Runs twice, as at every startup
+	for i := 0; i < 2; i++ {
+		if err := ensureFlightParticipantColumns(); err != nil {
+			t.Fatalf("ensureFlightParticipantColumns() error: %v", err)
+		}
+	}
+
+	flight, err := getFlightByIDFromMainDB(assigned)
+	if err != nil {
+		t.Fatalf("failed to get flight: %v", err)
+	}
+	if flight.ParticipantID != "P007" || flight.Condition != "baseline" {
+		t.Errorf("migrated participant = %q (%q), want P007 (baseline)", flight.ParticipantID, flight.Condition)
+	}
+	if flight, _ := getFlightByIDFromMainDB(unassigned); flight.ParticipantID != "" {
+		t.Errorf("unassigned flight got participant %q", flight.ParticipantID)
+	}
+	if _, err := getParticipant("P007"); err != nil {
+		t.Errorf("migrated participant is not registered: %v", err)
+	}
+	var tables int
+	mainDB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'flight_participant'").Scan(&tables)
+	if tables != 0 {
+		t.Errorf("flight_participant table was not dropped")
+	}
+}
@@ -0,0 +1,150 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// precomputeInterval is how often the background task looks for flights without current metrics
+	precomputeInterval = 1 * time.Hour
+	// precomputeFlightPause keeps the background task from saturating the database between flights
+	precomputeFlightPause = 2 * time.Second
+	// statisticsVersion is the metric version of FlightStatistics; bump it when FlightStatistics gains
+	// metrics or their computation changes so stored statistics are recomputed
+	statisticsVersion = 8
+)
+
+// precomputeTrigger wakes the background precomputation before its next interval
+var precomputeTrigger = make(chan struct{}, 1)
+
+// startStatisticsPrecomputation runs the background metrics precomputation loop
+func startStatisticsPrecomputation() {
+	for {
+		// A frozen database stays as it was snapshotted
+		if !readOnly.Load() {
+			precomputeMissingMetrics()
+			assessMissingFlightQuality()
+		}
+		select {
+		case <-time.After(precomputeInterval):
+		case <-precomputeTrigger:
+		}
+	}
+}
+
+// triggerPrecomputation has the background task look for flights without current metrics now
+func triggerPrecomputation() {
+	select {
+	case precomputeTrigger <- struct{}{}:
+	default: // Already triggered
+	}
+}
+
+// precomputeMissingMetrics computes and stores the metrics of every flight lacking a current one
+func precomputeMissingMetrics() {
+	flightIDs, err := getFlightIDsWithoutCurrentMetrics()
+	if err != nil {
+		log.Printf("Failed to find flights without current metrics: %v", err)
+		return
+	}
+
+	if len(flightIDs) == 0 {
+		return
+	}
+
+	log.Printf("Precomputing metrics for %d flights...", len(flightIDs))
+	for _, flightID := range flightIDs {
+		if readOnly.Load() {
+			log.Printf("Stopped precomputing metrics as the dataset was frozen")
+			return
+		}
+		if err := recomputeFlightMetrics(flightID); err != nil {
+			log.Printf("Failed to precompute metrics for flight %d: %v", flightID, err)
+		}
+		time.Sleep(precomputeFlightPause)
+	}
+	log.Printf("Finished precomputing metrics for %d flights", len(flightIDs))
+}
+
+// getFlightIDsWithoutCurrentMetrics returns the IDs of all flights lacking a metric computed with its
+// current version
+func getFlightIDsWithoutCurrentMetrics() ([]int, error) {
+	query := `
+		SELECT f.id, m.metric, m.version
+		FROM flight f
+		LEFT JOIN flight_metrics m ON m.flight_id = f.id
+		ORDER BY f.id
+	`
+
+	rows, err := mainDB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flightIDs []int
+	current := map[int]int{}
+	for rows.Next() {
+		var id int
+		var metric sql.NullString
+		var version sql.NullInt64
+		if err := rows.Scan(&id, &metric, &version); err != nil {
+			return nil, err
+		}
+		if _, seen := current[id]; !seen {
+			flightIDs = append(flightIDs, id)
+			current[id] = 0
+		}
+		if v, ok := metricVersions[metric.String]; ok && version.Valid && int(version.Int64) == v {
+			current[id]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var missing []int
+	for _, id := range flightIDs {
+		if current[id] < len(metricVersions) {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// getFlightStatistics returns the stored statistics for a flight, computing them if they are missing
+// or outdated
+func getFlightStatistics(flightID int) (map[string]*FlightStatistics, error) {
+	var statistics map[string]*FlightStatistics
+	err := getStoredMetric(flightID, metricStatistics, &statistics)
+	if err == nil {
+		return statistics, nil
+	}
+	if err != sql.ErrNoRows {
+		log.Printf("Failed to read stored statistics for flight %d: %v", flightID, err)
+	}
+
+	return computeAndCacheFlightStatistics(flightID)
+}
+
+// computeAndCacheFlightStatistics calculates statistics for a flight and stores them
+func computeAndCacheFlightStatistics(flightID int) (map[string]*FlightStatistics, error) {
+	columns, err := getFlightColumns(flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight data: %w", err)
+	}
+
+	return cacheFlightStatistics(flightID, columns)
+}
+
+// cacheFlightStatistics calculates statistics from columnar series and stores them
+func cacheFlightStatistics(flightID int, columns map[string]*SeriesColumns) (map[string]*FlightStatistics, error) {
+	statistics := calculateColumnStatistics(columns)
+	if err := storeMetric(flightID, metricStatistics, statistics); err != nil {
+		return nil, err
+	}
+	return statistics, nil
+}
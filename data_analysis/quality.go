@@ -0,0 +1,287 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Review statuses of a flight
+const (
+	ReviewUnreviewed = "unreviewed"
+	ReviewAccepted   = "accepted"
+	ReviewRejected   = "rejected"
+)
+
+var reviewStatuses = []string{ReviewUnreviewed, ReviewAccepted, ReviewRejected}
+
+// defaultReviewFilter are the review statuses analysis endpoints include unless asked otherwise
+var defaultReviewFilter = []string{ReviewUnreviewed, ReviewAccepted}
+
+// Thresholds of the flight quality assessment
+const (
+	qualityMaxSampleInterval = 5.0     // Seconds between samples beyond which the recording has a gap
+	maxPlausibleGroundSpeed  = 1000.0  // Knots
+	maxPlausibleVerticalRate = 20000.0 // Feet per minute
+)
+
+// FlightQuality summarizes the data quality of a flight's position recording
+type FlightQuality struct {
+	Score        float64 `json:"score"`         // 0 to 100, the product of completeness, gap-free coverage and anomaly-free samples
+	Completeness float64 `json:"completeness"`  // Fraction of samples with coordinates
+	GapCount     int     `json:"gap_count"`     // Sample intervals longer than five seconds
+	GapSeconds   float64 `json:"gap_seconds"`   // Total duration of the gaps
+	AnomalyCount int     `json:"anomaly_count"` // Samples with non-increasing timestamps or implausible speeds
+	AssessedAt   string  `json:"assessed_at"`
+}
+
+// ensureFlightReviewTable creates the table holding the quality assessment and review status of flights
+func ensureFlightReviewTable() error {
+	reviewSchema := `
+		CREATE TABLE IF NOT EXISTS flight_review (
+			flight_id INTEGER PRIMARY KEY,
+			quality_score REAL,
+			completeness REAL,
+			gap_count INTEGER,
+			gap_seconds REAL,
+			anomaly_count INTEGER,
+			assessed_at TEXT,
+			review_status TEXT NOT NULL DEFAULT 'unreviewed',
+			review_reason TEXT NOT NULL DEFAULT '',
+			reviewed_at TEXT,
+			FOREIGN KEY(flight_id) REFERENCES flight(id) ON DELETE CASCADE
+		);
+	`
+
+	if _, err := mainDB.Exec(reviewSchema); err != nil {
+		return fmt.Errorf("failed to create flight_review table: %w", err)
+	}
+	return nil
+}
+
+// computeFlightQuality assesses the position data of all aircraft of a flight
+func computeFlightQuality(flightData *FlightData) *FlightQuality {
+	quality := &FlightQuality{AssessedAt: time.Now().Format(time.RFC3339)}
+
+	samples, valid := 0, 0
+	duration := 0.0
+	for _, positions := range flightData.PositionData {
+		for i, p := range positions {
+			samples++
+			if p.Latitude != 0 || p.Longitude != 0 {
+				valid++
+			}
+
+			anomalous := p.GroundSpeed > maxPlausibleGroundSpeed || math.Abs(p.VerticalSpeed) > maxPlausibleVerticalRate
+			if i > 0 {
+				dt := p.TimestampSeconds - positions[i-1].TimestampSeconds
+				if dt <= 0 {
+					anomalous = true
+				} else if dt > qualityMaxSampleInterval {
+					quality.GapCount++
+					quality.GapSeconds += dt
+				}
+			}
+			if anomalous {
+				quality.AnomalyCount++
+			}
+		}
+		if len(positions) > 1 {
+			duration += positions[len(positions)-1].TimestampSeconds - positions[0].TimestampSeconds
+		}
+	}
+
+	if samples == 0 {
+		return quality
+	}
+
+	quality.Completeness = float64(valid) / float64(samples)
+	coverage := 1.0
+	if duration > 0 {
+		coverage = math.Max(0, 1-quality.GapSeconds/duration)
+	}
+	anomalyFree := 1 - float64(quality.AnomalyCount)/float64(samples)
+	quality.Score = math.Round(quality.Completeness*coverage*anomalyFree*1000) / 10
+	return quality
+}
+
+// assessFlightQuality computes and stores the quality of a flight, keeping its review status
+func assessFlightQuality(flightID int) (*FlightQuality, error) {
+	flightData, err := getFlightDataFromMainDB(flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight data: %w", err)
+	}
+	quality := computeFlightQuality(flightData)
+
+	query := `
+		INSERT INTO flight_review (flight_id, quality_score, completeness, gap_count, gap_seconds, anomaly_count, assessed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(flight_id) DO UPDATE SET quality_score = excluded.quality_score,
+			completeness = excluded.completeness, gap_count = excluded.gap_count, gap_seconds = excluded.gap_seconds,
+			anomaly_count = excluded.anomaly_count, assessed_at = excluded.assessed_at
+	`
+	_, err = mainDB.Exec(query, flightID, quality.Score, quality.Completeness, quality.GapCount,
+		quality.GapSeconds, quality.AnomalyCount, quality.AssessedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store flight quality: %w", err)
+	}
+	return quality, nil
+}
+
+// assessImportedFlights assesses the quality of newly imported flights
+func assessImportedFlights(flights []Flight) {
+	for i := range flights {
+		quality, err := assessFlightQuality(flights[i].ID)
+		if err != nil {
+			log.Printf("Failed to assess quality of flight %d: %v", flights[i].ID, err)
+			continue
+		}
+		flights[i].Quality = quality
+		flights[i].ReviewStatus = ReviewUnreviewed
+	}
+}
+
+// assessMissingFlightQuality assesses every flight without a quality score, e.g. flights imported
+// before scores existed or created by trimming
+func assessMissingFlightQuality() {
+	query := `
+		SELECT f.id
+		FROM flight f
+		LEFT JOIN flight_review r ON r.flight_id = f.id
+		WHERE r.quality_score IS NULL
+		ORDER BY f.id
+	`
+	rows, err := mainDB.Query(query)
+	if err != nil {
+		log.Printf("Failed to find flights without quality score: %v", err)
+		return
+	}
+	var flightIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			log.Printf("Failed to find flights without quality score: %v", err)
+			return
+		}
+		flightIDs = append(flightIDs, id)
+	}
+	rows.Close()
+
+	for _, flightID := range flightIDs {
+		if readOnly.Load() {
+			return
+		}
+		if _, err := assessFlightQuality(flightID); err != nil {
+			log.Printf("Failed to assess quality of flight %d: %v", flightID, err)
+		}
+		time.Sleep(precomputeFlightPause)
+	}
+}
+
+// loadFlightReview fills the quality and review status of a flight; flights never assessed or
+// reviewed are unreviewed without a quality
+func loadFlightReview(flight *Flight) error {
+	query := `
+		SELECT quality_score, completeness, gap_count, gap_seconds, anomaly_count, assessed_at, review_status, review_reason
+		FROM flight_review
+		WHERE flight_id = ?
+	`
+
+	var score, completeness, gapSeconds sql.NullFloat64
+	var gapCount, anomalyCount sql.NullInt64
+	var assessedAt sql.NullString
+	var status, reason string
+	err := mainDB.QueryRow(query, flight.ID).Scan(&score, &completeness, &gapCount, &gapSeconds, &anomalyCount,
+		&assessedAt, &status, &reason)
+	if err == sql.ErrNoRows {
+		flight.ReviewStatus = ReviewUnreviewed
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	flight.ReviewStatus = status
+	flight.ReviewReason = reason
+	if score.Valid {
+		flight.Quality = &FlightQuality{
+			Score:        score.Float64,
+			Completeness: completeness.Float64,
+			GapCount:     int(gapCount.Int64),
+			GapSeconds:   gapSeconds.Float64,
+			AnomalyCount: int(anomalyCount.Int64),
+			AssessedAt:   assessedAt.String,
+		}
+	}
+	return nil
+}
+
+// parseReviewFilter reads the review_status parameter: a comma-separated list of statuses, or "all".
+// Without it rejected flights are left out.
+func parseReviewFilter(r *http.Request) ([]string, error) {
+	value := r.URL.Query().Get("review_status")
+	if value == "" {
+		return defaultReviewFilter, nil
+	}
+	if value == "all" {
+		return reviewStatuses, nil
+	}
+
+	var statuses []string
+	for _, status := range strings.Split(value, ",") {
+		status = strings.TrimSpace(status)
+		if !slices.Contains(reviewStatuses, status) {
+			return nil, fmt.Errorf("unknown review status '%s' (available: %s, all)", status, strings.Join(reviewStatuses, ", "))
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// handleReviewFlight sets the review status of a flight; rejecting requires a reason
+func handleReviewFlight(w http.ResponseWriter, r *http.Request, flightId int) {
+	var request struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	reason := strings.TrimSpace(request.Reason)
+	if !slices.Contains(reviewStatuses, request.Status) {
+		httpError(w, fmt.Sprintf("Unknown review status '%s' (available: %s)", request.Status, strings.Join(reviewStatuses, ", ")), http.StatusBadRequest)
+		return
+	}
+	if request.Status == ReviewRejected && reason == "" {
+		httpError(w, "Rejecting a flight requires a reason", http.StatusBadRequest)
+		return
+	}
+
+	query := `
+		INSERT INTO flight_review (flight_id, review_status, review_reason, reviewed_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(flight_id) DO UPDATE SET review_status = excluded.review_status,
+			review_reason = excluded.review_reason, reviewed_at = excluded.reviewed_at
+	`
+	if _, err := mainDB.Exec(query, flightId, request.Status, reason, time.Now().Format(time.RFC3339)); err != nil {
+		httpError(w, fmt.Sprintf("Failed to set review status: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Set review status of flight %d to '%s'", flightId, request.Status)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "success",
+		"flight_id":     flightId,
+		"review_status": request.Status,
+		"review_reason": reason,
+	})
+}
@@ -0,0 +1,154 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// recordedAircraftTables are the per-aircraft Sky Dolly tables that are stored as recorded: handles
+// and gear, lights, control surfaces, flaps and spoilers, and the flight plan waypoints
+var recordedAircraftTables = []string{"handle", "light", "primary_flight_control", "secondary_flight_control", "waypoint"}
+
+// duplicatedAircraftTables are the recorded tables copied when duplicating or trimming a flight,
+// including the state channels of CSV recordings that logbooks do not have
+var duplicatedAircraftTables = append(slices.Clone(recordedAircraftTables), "aircraft_state")
+
+// tableColumns returns the column names of a table
+func tableColumns(query func(string, ...interface{}) (*sql.Rows, error), table string) ([]string, error) {
+	rows, err := query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+// recordedValueColumns returns the columns of a recorded table besides aircraft_id and timestamp
+// that both the source and the main database have, so recordings of older and newer Sky Dolly
+// versions import alike
+func recordedValueColumns(sourceColumns, mainColumns []string) []string {
+	var columns []string
+	for _, column := range sourceColumns {
+		if column != "aircraft_id" && column != "timestamp" && slices.Contains(mainColumns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// insertRecordedRows writes rows of timestamp and value columns to a recorded table for an aircraft,
+// shifting the timestamps by shiftMs
+func insertRecordedRows(tx *sql.Tx, table string, columns []string, rows *sql.Rows, newAircraftID int, shiftMs int64) error {
+	names := append([]string{"aircraft_id", "timestamp"}, columns...)
+	insertQuery := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?%s)",
+		table, strings.Join(names, ", "), strings.Repeat(", ?", len(names)-1))
+	stmt, err := tx.Prepare(insertQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var timestamp int64
+	values := make([]interface{}, len(columns))
+	dest := []interface{}{&timestamp}
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		args := append([]interface{}{newAircraftID, timestamp + shiftMs}, values...)
+		if _, err := stmt.Exec(args...); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// importRecordedTable copies the samples of an aircraft in a recorded table from the source database
+func importRecordedTable(sourceDB *sql.DB, tx *sql.Tx, table string, sourceAircraftID, newAircraftID int) error {
+	sourceColumns, err := tableColumns(sourceDB.Query, table)
+	if err != nil {
+		return err
+	}
+	mainColumns, err := tableColumns(tx.Query, table)
+	if err != nil {
+		return err
+	}
+	columns := recordedValueColumns(sourceColumns, mainColumns)
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE aircraft_id = ? ORDER BY timestamp",
+		strings.Join(append([]string{"timestamp"}, columns...), ", "), table)
+	rows, err := sourceDB.Query(query, sourceAircraftID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return insertRecordedRows(tx, table, columns, rows, newAircraftID, 0)
+}
+
+// duplicateRecordedTable copies the samples of an aircraft in a recorded table between fromMs and
+// toMs to the new aircraft, shifting the timestamps by shiftMs
+func duplicateRecordedTable(tx *sql.Tx, table string, originalAircraftID, newAircraftID int, fromMs, toMs, shiftMs int64) error {
+	mainColumns, err := tableColumns(tx.Query, table)
+	if err != nil {
+		return err
+	}
+	columns := recordedValueColumns(mainColumns, mainColumns)
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE aircraft_id = ? AND timestamp >= ? AND timestamp <= ? ORDER BY timestamp",
+		strings.Join(append([]string{"timestamp"}, columns...), ", "), table)
+	rows, err := tx.Query(query, originalAircraftID, fromMs, toMs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	return insertRecordedRows(tx, table, columns, rows, newAircraftID, shiftMs)
+}
+
+// duplicateRecordedTables copies all recorded table samples of an aircraft to the new aircraft
+func duplicateRecordedTables(tx *sql.Tx, originalAircraftID, newAircraftID int) error {
+	for _, table := range duplicatedAircraftTables {
+		if err := duplicateRecordedTable(tx, table, originalAircraftID, newAircraftID, math.MinInt64, math.MaxInt64, 0); err != nil {
+			return fmt.Errorf("failed to duplicate %s data: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// duplicateRecordedTablesTrimmed copies the recorded table samples of an aircraft within a time range
+// to the new aircraft. Like the other trimmed tables, each table's range is relative to its first
+// sample and its timestamps are shifted so the range starts there.
+func duplicateRecordedTablesTrimmed(tx *sql.Tx, originalAircraftID, newAircraftID int, startTime, endTime float64) error {
+	for _, table := range duplicatedAircraftTables {
+		var minTimestamp sql.NullInt64
+		if err := tx.QueryRow(fmt.Sprintf("SELECT MIN(timestamp) FROM %s WHERE aircraft_id = ?", table), originalAircraftID).Scan(&minTimestamp); err != nil {
+			return fmt.Errorf("failed to get start of %s data: %w", table, err)
+		}
+		if !minTimestamp.Valid {
+			continue
+		}
+
+		fromMs := minTimestamp.Int64 + int64(startTime*1000)
+		toMs := minTimestamp.Int64 + int64(endTime*1000)
+		if err := duplicateRecordedTable(tx, table, originalAircraftID, newAircraftID, fromMs, toMs, -int64(startTime*1000)); err != nil {
+			return fmt.Errorf("failed to duplicate %s data: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,350 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kaireichart/master-thesis-operator-station/gps"
+)
+
+// Reference point kinds
+var referencePointKinds = []string{"airfield", "vrp", "other"}
+
+// ReferencePoint is a named location of the reference point library, e.g. an airfield or visual reporting point
+type ReferencePoint struct {
+	ID        int     `json:"id"`
+	Name      string  `json:"name"`
+	Kind      string  `json:"kind"` // "airfield", "vrp" (visual reporting point) or "other"
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// GPSGateSettings selects the library point the GPS distance gate is centered on
+type GPSGateSettings struct {
+	ReferencePointID int                `json:"reference_point_id"` // 0 while the gate uses the configured site reference point
+	Reference        gps.ReferencePoint `json:"reference"`
+}
+
+// errDuplicateReferencePoint is returned when a reference point name is already taken
+var errDuplicateReferencePoint = errors.New("a reference point with this name already exists")
+
+var (
+	gpsGateReferencePointID int
+	gpsGateSettingsMutex    = &sync.Mutex{}
+)
+
+// ensureReferencePointsTable creates the reference point library, seeded with the site reference point
+func ensureReferencePointsTable() error {
+	referencePointsSchema := `
+		CREATE TABLE IF NOT EXISTS reference_point (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			kind TEXT NOT NULL DEFAULT 'other',
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL
+		);
+	`
+	if _, err := mainDB.Exec(referencePointsSchema); err != nil {
+		return fmt.Errorf("failed to create reference_point table: %w", err)
+	}
+
+	seed := `
+		INSERT INTO reference_point (name, kind, latitude, longitude)
+		SELECT ?, 'vrp', ?, ?
+		WHERE NOT EXISTS (SELECT 1 FROM reference_point)
+	`
+	site := gps.GetReferenceConfig()
+	if _, err := mainDB.Exec(seed, site.Name, site.Latitude, site.Longitude); err != nil {
+		return fmt.Errorf("failed to seed reference_point table: %w", err)
+	}
+	return nil
+}
+
+// validate checks the name, kind and coordinates of a reference point
+func (p *ReferencePoint) validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if p.Kind == "" {
+		p.Kind = "other"
+	}
+	validKind := false
+	for _, kind := range referencePointKinds {
+		validKind = validKind || p.Kind == kind
+	}
+	if !validKind {
+		return fmt.Errorf("invalid kind '%s' (expected %s)", p.Kind, strings.Join(referencePointKinds, ", "))
+	}
+	if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+		return fmt.Errorf("invalid coordinates: %f, %f", p.Latitude, p.Longitude)
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether an insert or update failed on a UNIQUE constraint
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// getReferencePoints returns the library ordered by name
+func getReferencePoints() ([]ReferencePoint, error) {
+	rows, err := mainDB.Query("SELECT id, name, kind, latitude, longitude FROM reference_point ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []ReferencePoint{}
+	for rows.Next() {
+		var p ReferencePoint
+		if err := rows.Scan(&p.ID, &p.Name, &p.Kind, &p.Latitude, &p.Longitude); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// getReferencePoint returns one library point, or sql.ErrNoRows
+func getReferencePoint(id int) (*ReferencePoint, error) {
+	var p ReferencePoint
+	err := mainDB.QueryRow("SELECT id, name, kind, latitude, longitude FROM reference_point WHERE id = ?", id).
+		Scan(&p.ID, &p.Name, &p.Kind, &p.Latitude, &p.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// createReferencePoint adds a point to the library
+func createReferencePoint(p ReferencePoint) (*ReferencePoint, error) {
+	result, err := mainDB.Exec("INSERT INTO reference_point (name, kind, latitude, longitude) VALUES (?, ?, ?, ?)",
+		p.Name, p.Kind, p.Latitude, p.Longitude)
+	if isUniqueViolation(err) {
+		return nil, errDuplicateReferencePoint
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	p.ID = int(id)
+	return &p, nil
+}
+
+// updateReferencePoint replaces a library point and refreshes the selections using it
+func updateReferencePoint(p ReferencePoint) error {
+	result, err := mainDB.Exec("UPDATE reference_point SET name = ?, kind = ?, latitude = ?, longitude = ? WHERE id = ?",
+		p.Name, p.Kind, p.Latitude, p.Longitude, p.ID)
+	if isUniqueViolation(err) {
+		return errDuplicateReferencePoint
+	}
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	syncReferencePointSelections(p.ID, &p)
+	return nil
+}
+
+// deleteReferencePoint removes a library point and the distance marker waypoints at it. Selections
+// using it keep its coordinates but are no longer linked to the library.
+func deleteReferencePoint(id int) error {
+	if _, err := mainDB.Exec("DELETE FROM distance_marker_waypoint WHERE reference_point_id = ?", id); err != nil {
+		return err
+	}
+	result, err := mainDB.Exec("DELETE FROM reference_point WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	syncReferencePointSelections(id, nil)
+	return nil
+}
+
+// syncReferencePointSelections applies a changed library point to the distance markers and GPS gate
+// selecting it, or unlinks them when the point was deleted (point is nil)
+func syncReferencePointSelections(id int, point *ReferencePoint) {
+	distanceMarkerSettingsMutex.Lock()
+	if distanceMarkerSettings.ReferencePointID == id {
+		if point == nil {
+			distanceMarkerSettings.ReferencePointID = 0
+		} else {
+			distanceMarkerSettings.applyReferencePoint(point)
+		}
+	}
+	distanceMarkerSettingsMutex.Unlock()
+
+	gpsGateSettingsMutex.Lock()
+	defer gpsGateSettingsMutex.Unlock()
+	if gpsGateReferencePointID == id {
+		if point == nil {
+			gpsGateReferencePointID = 0
+		} else {
+			gps.SetGateReference(gps.ReferencePoint{Name: point.Name, Latitude: point.Latitude, Longitude: point.Longitude})
+		}
+	}
+}
+
+// referencePointFromRequest decodes and validates a reference point from a request body
+func referencePointFromRequest(w http.ResponseWriter, r *http.Request) (ReferencePoint, bool) {
+	var point ReferencePoint
+	if err := json.NewDecoder(r.Body).Decode(&point); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return point, false
+	}
+	if err := point.validate(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return point, false
+	}
+	return point, true
+}
+
+// handleGetReferencePoints lists the reference point library
+func handleGetReferencePoints(w http.ResponseWriter, r *http.Request) {
+	points, err := getReferencePoints()
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get reference points: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(points)
+}
+
+// handleCreateReferencePoint adds a point to the library
+func handleCreateReferencePoint(w http.ResponseWriter, r *http.Request) {
+	point, ok := referencePointFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := createReferencePoint(point)
+	if err == errDuplicateReferencePoint {
+		httpError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create reference point: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleUpdateReferencePoint replaces a library point
+func handleUpdateReferencePoint(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("pointId"))
+	if err != nil {
+		httpError(w, "Invalid reference point ID", http.StatusBadRequest)
+		return
+	}
+	point, ok := referencePointFromRequest(w, r)
+	if !ok {
+		return
+	}
+	point.ID = id
+
+	err = updateReferencePoint(point)
+	if err == sql.ErrNoRows {
+		httpError(w, "Reference point not found", http.StatusNotFound)
+		return
+	}
+	if err == errDuplicateReferencePoint {
+		httpError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to update reference point: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(point)
+}
+
+// handleDeleteReferencePoint removes a library point
+func handleDeleteReferencePoint(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("pointId"))
+	if err != nil {
+		httpError(w, "Invalid reference point ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := deleteReferencePoint(id); err == sql.ErrNoRows {
+		httpError(w, "Reference point not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpError(w, fmt.Sprintf("Failed to delete reference point: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// getGPSGateSettings returns the GPS gate center and the library point it was selected from
+func getGPSGateSettings() GPSGateSettings {
+	gpsGateSettingsMutex.Lock()
+	defer gpsGateSettingsMutex.Unlock()
+	return GPSGateSettings{ReferencePointID: gpsGateReferencePointID, Reference: gps.GetGateReference()}
+}
+
+// handleGetGPSGateSettings returns the GPS gate center
+func handleGetGPSGateSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(getGPSGateSettings())
+}
+
+// handleUpdateGPSGateSettings centers the GPS gate on a library point
+func handleUpdateGPSGateSettings(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ReferencePointID int `json:"reference_point_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	point, err := getReferencePoint(request.ReferencePointID)
+	if err == sql.ErrNoRows {
+		httpError(w, "Reference point not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get reference point: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	gpsGateSettingsMutex.Lock()
+	err = gps.SetGateReference(gps.ReferencePoint{Name: point.Name, Latitude: point.Latitude, Longitude: point.Longitude})
+	if err == nil {
+		gpsGateReferencePointID = point.ID
+	}
+	gpsGateSettingsMutex.Unlock()
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(getGPSGateSettings())
+}
@@ -0,0 +1,238 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProfilePoint is a sample of a reference profile
+type ProfilePoint struct {
+	Time  float64 `json:"time"` // Seconds from the start of the flight, or from the alignment marker
+	Value float64 `json:"value"`
+}
+
+// ReferenceProfile is a target series a flight is flown against, e.g. the altitude profile of the study
+// scenario. Between its samples the target is interpolated linearly.
+type ReferenceProfile struct {
+	ID      int            `json:"id"`
+	Name    string         `json:"name"`
+	Channel string         `json:"channel"` // altitude, indicated_altitude or airspeed
+	Points  []ProfilePoint `json:"points"`
+}
+
+// errDuplicateReferenceProfile is returned when a reference profile name is already taken
+var errDuplicateReferenceProfile = errors.New("a reference profile with this name already exists")
+
+// ensureReferenceProfilesTable creates the table of the uploaded reference profiles
+func ensureReferenceProfilesTable() error {
+	referenceProfilesSchema := `
+		CREATE TABLE IF NOT EXISTS reference_profile (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			channel TEXT NOT NULL,
+			points TEXT NOT NULL
+		);
+	`
+	if _, err := mainDB.Exec(referenceProfilesSchema); err != nil {
+		return fmt.Errorf("failed to create reference_profile table: %w", err)
+	}
+	return nil
+}
+
+// validate checks the name and channel of a profile and sorts its points by time
+func (p *ReferenceProfile) validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, ok := trackingChannels[p.Channel]; !ok {
+		return fmt.Errorf("invalid channel '%s' (expected %s)", p.Channel, strings.Join(trackingChannelNames(), ", "))
+	}
+	if len(p.Points) < 2 {
+		return fmt.Errorf("at least two points are required")
+	}
+
+	sort.SliceStable(p.Points, func(i, j int) bool { return p.Points[i].Time < p.Points[j].Time })
+	for i := 1; i < len(p.Points); i++ {
+		if p.Points[i].Time == p.Points[i-1].Time {
+			return fmt.Errorf("more than one point at %gs", p.Points[i].Time)
+		}
+	}
+	return nil
+}
+
+// value interpolates the profile at t; times outside the profile have no target
+func (p *ReferenceProfile) value(t float64) (float64, bool) {
+	i := sort.Search(len(p.Points), func(i int) bool { return p.Points[i].Time >= t })
+	switch {
+	case i == len(p.Points):
+		return 0, false
+	case p.Points[i].Time == t:
+		return p.Points[i].Value, true
+	case i == 0:
+		return 0, false
+	}
+	before, after := p.Points[i-1], p.Points[i]
+	ratio := (t - before.Time) / (after.Time - before.Time)
+	return before.Value + (after.Value-before.Value)*ratio, true
+}
+
+// getReferenceProfiles returns all profiles ordered by name
+func getReferenceProfiles() ([]ReferenceProfile, error) {
+	rows, err := mainDB.Query("SELECT id, name, channel, points FROM reference_profile ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := []ReferenceProfile{}
+	for rows.Next() {
+		profile, err := scanReferenceProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, *profile)
+	}
+	return profiles, rows.Err()
+}
+
+// getReferenceProfile returns one profile, or sql.ErrNoRows
+func getReferenceProfile(id int) (*ReferenceProfile, error) {
+	return scanReferenceProfile(mainDB.QueryRow("SELECT id, name, channel, points FROM reference_profile WHERE id = ?", id))
+}
+
+// getReferenceProfileByName returns the profile of a name, or sql.ErrNoRows
+func getReferenceProfileByName(name string) (*ReferenceProfile, error) {
+	return scanReferenceProfile(mainDB.QueryRow("SELECT id, name, channel, points FROM reference_profile WHERE name = ?", name))
+}
+
+func scanReferenceProfile(row interface{ Scan(...interface{}) error }) (*ReferenceProfile, error) {
+	var p ReferenceProfile
+	var points string
+	if err := row.Scan(&p.ID, &p.Name, &p.Channel, &points); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(points), &p.Points); err != nil {
+		return nil, fmt.Errorf("failed to parse points of reference profile %d: %w", p.ID, err)
+	}
+	return &p, nil
+}
+
+// createReferenceProfile stores a validated profile
+func createReferenceProfile(p ReferenceProfile) (*ReferenceProfile, error) {
+	points, err := json.Marshal(p.Points)
+	if err != nil {
+		return nil, err
+	}
+	result, err := mainDB.Exec("INSERT INTO reference_profile (name, channel, points) VALUES (?, ?, ?)", p.Name, p.Channel, string(points))
+	if isUniqueViolation(err) {
+		return nil, errDuplicateReferenceProfile
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	p.ID = int(id)
+	return &p, nil
+}
+
+// handleGetReferenceProfiles lists the reference profiles
+func handleGetReferenceProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := getReferenceProfiles()
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get reference profiles: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// handleCreateReferenceProfile stores an uploaded reference profile
+func handleCreateReferenceProfile(w http.ResponseWriter, r *http.Request) {
+	var profile ReferenceProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := profile.validate(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := createReferenceProfile(profile)
+	if err == errDuplicateReferenceProfile {
+		httpError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create reference profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleDeleteReferenceProfile removes a reference profile
+func handleDeleteReferenceProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("profileId"))
+	if err != nil {
+		httpError(w, "Invalid reference profile ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := mainDB.Exec("DELETE FROM reference_profile WHERE id = ?", id)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to delete reference profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		httpError(w, "Reference profile not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// trackingChannelNames returns the channels profiles can target, sorted
+func trackingChannelNames() []string {
+	names := make([]string, 0, len(trackingChannels))
+	for name := range trackingChannels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// lookupReferenceProfile resolves the profile query parameter; on failure it writes the error response
+func lookupReferenceProfile(w http.ResponseWriter, value string) (*ReferenceProfile, bool) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Invalid reference profile ID '%s'", value), http.StatusBadRequest)
+		return nil, false
+	}
+	profile, err := getReferenceProfile(id)
+	if err == sql.ErrNoRows {
+		httpError(w, fmt.Sprintf("Reference profile %d not found", id), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get reference profile: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return profile, true
+}
@@ -0,0 +1,201 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limits of the replay speed
+const (
+	minReplaySpeed = 0.1
+	maxReplaySpeed = 16.0
+)
+
+// ReplaySession is the state of a flight's replay; Cursor is the flight time being replayed in seconds
+type ReplaySession struct {
+	FlightID int     `json:"flight_id"`
+	Cursor   float64 `json:"cursor"`
+	Duration float64 `json:"duration"`
+	Speed    float64 `json:"speed"` // Flight seconds per wall-clock second
+	Playing  bool    `json:"playing"`
+}
+
+// ReplayUpdate starts or changes a replay; omitted fields keep their value, or their default when the
+// replay starts (cursor 0, speed 1, playing)
+type ReplayUpdate struct {
+	Cursor  *float64 `json:"cursor"`
+	Speed   *float64 `json:"speed"`
+	Playing *bool    `json:"playing"`
+}
+
+// replaySession advances its cursor from the time it was last changed while playing
+type replaySession struct {
+	cursor    float64 // Cursor at updatedAt
+	updatedAt time.Time
+	duration  float64
+	speed     float64
+	playing   bool
+}
+
+var (
+	// replaySessions are the running replays by flight ID, held in memory
+	replaySessions = map[int]*replaySession{}
+	replayMutex    = &sync.Mutex{}
+)
+
+// advance moves the cursor to now; a replay reaching the end of the flight pauses there.
+// Must be called with replayMutex held.
+func (s *replaySession) advance(now time.Time) {
+	if s.playing {
+		s.cursor += now.Sub(s.updatedAt).Seconds() * s.speed
+		if s.cursor >= s.duration {
+			s.cursor = s.duration
+			s.playing = false
+		}
+	}
+	s.updatedAt = now
+}
+
+func (s *replaySession) state(flightID int) ReplaySession {
+	return ReplaySession{FlightID: flightID, Cursor: s.cursor, Duration: s.duration, Speed: s.speed, Playing: s.playing}
+}
+
+// replayCursor returns the current replay state of a flight, false without a replay
+func replayCursor(flightID int) (ReplaySession, bool) {
+	replayMutex.Lock()
+	defer replayMutex.Unlock()
+
+	session, exists := replaySessions[flightID]
+	if !exists {
+		return ReplaySession{}, false
+	}
+	session.advance(time.Now())
+	return session.state(flightID), true
+}
+
+// stopReplay ends the replay of a flight, if one is running
+func stopReplay(flightID int) bool {
+	replayMutex.Lock()
+	defer replayMutex.Unlock()
+
+	_, exists := replaySessions[flightID]
+	delete(replaySessions, flightID)
+	return exists
+}
+
+// handleGetReplay returns the replay state of a flight with its current cursor
+func handleGetReplay(w http.ResponseWriter, r *http.Request, flightId int) {
+	state, exists := replayCursor(flightId)
+	if !exists {
+		httpError(w, "No replay running for this flight", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// handleUpdateReplay starts the replay of a flight, or seeks, changes the speed of, pauses or resumes it
+func handleUpdateReplay(w http.ResponseWriter, r *http.Request, flightId int) {
+	var update ReplayUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if update.Speed != nil && !(*update.Speed >= minReplaySpeed && *update.Speed <= maxReplaySpeed) {
+		httpError(w, fmt.Sprintf("speed must be between %g and %g", minReplaySpeed, maxReplaySpeed), http.StatusBadRequest)
+		return
+	}
+	if update.Cursor != nil && (math.IsNaN(*update.Cursor) || math.IsInf(*update.Cursor, 0)) {
+		httpError(w, "cursor must be a number", http.StatusBadRequest)
+		return
+	}
+
+	// The cursor is kept within the recorded time of the flight
+	duration, err := flightDurationSeconds(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight duration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	replayMutex.Lock()
+	now := time.Now()
+	session, exists := replaySessions[flightId]
+	if exists {
+		session.advance(now)
+	} else {
+		session = &replaySession{updatedAt: now, speed: 1, playing: true}
+		replaySessions[flightId] = session
+	}
+	session.duration = duration
+	if update.Cursor != nil {
+		session.cursor = *update.Cursor
+	}
+	session.cursor = math.Min(math.Max(session.cursor, 0), duration)
+	if update.Speed != nil {
+		session.speed = *update.Speed
+	}
+	if update.Playing != nil {
+		session.playing = *update.Playing
+		// Playing from the end starts over
+		if session.playing && session.cursor >= duration {
+			session.cursor = 0
+		}
+	}
+	state := session.state(flightId)
+	replayMutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// handleStopReplay ends the replay of a flight
+func handleStopReplay(w http.ResponseWriter, r *http.Request, flightId int) {
+	if !stopReplay(flightId) {
+		httpError(w, "No replay running for this flight", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// handleCreateReplayMarker creates a marker at the replay cursor, so flights can be annotated while
+// watching the replay. The request takes the fields of a marker except its time.
+func handleCreateReplayMarker(w http.ResponseWriter, r *http.Request, flightId int) {
+	var marker Marker
+	if err := json.NewDecoder(r.Body).Decode(&marker); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if marker.Label == "" {
+		httpError(w, "Label is required", http.StatusBadRequest)
+		return
+	}
+	if err := normalizeMarkerCategory(&marker); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The cursor is read after validating, as close to the key press as the request allows
+	state, exists := replayCursor(flightId)
+	if !exists {
+		httpError(w, "No replay running for this flight", http.StatusConflict)
+		return
+	}
+	marker.FlightID = flightId
+	marker.Time = state.Cursor
+
+	createdMarker, err := createMarker(marker)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create marker: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(createdMarker)
+}
@@ -0,0 +1,283 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// Accepted range of resampling rates
+const (
+	minResampleRateHz = 0.1
+	maxResampleRateHz = 100.0
+)
+
+// How a column is interpolated between two samples
+type resampleMode int
+
+const (
+	resampleLinear resampleMode = iota
+	resampleAngle               // Degrees, interpolated along the shorter way around the circle
+	resampleStep                // Switches and states, holding the earlier sample's value
+)
+
+type resampleColumn struct {
+	name string
+	mode resampleMode
+}
+
+// Columns resampled per table, the same columns trimming copies
+var (
+	positionResampleColumns = []resampleColumn{
+		{"latitude", resampleLinear}, {"longitude", resampleLinear}, {"altitude", resampleLinear},
+		{"indicated_altitude", resampleLinear}, {"calibrated_indicated_altitude", resampleLinear},
+		{"pressure_altitude", resampleLinear}, {"indicated_airspeed", resampleLinear},
+		{"wind_speed", resampleLinear}, {"wind_direction", resampleAngle}, {"vertical_speed", resampleLinear},
+		{"true_airspeed", resampleLinear}, {"ambient_temperature", resampleLinear},
+	}
+	attitudeResampleColumns = []resampleColumn{
+		{"pitch", resampleLinear}, {"bank", resampleLinear}, {"true_heading", resampleAngle},
+		{"velocity_x", resampleLinear}, {"velocity_y", resampleLinear}, {"velocity_z", resampleLinear},
+		{"on_ground", resampleStep},
+	}
+	engineResampleColumns = engineColumnsForResampling()
+)
+
+// engineColumnsForResampling lists the four-engine lever, cowl flap and switch columns
+func engineColumnsForResampling() []resampleColumn {
+	var columns []resampleColumn
+	for _, prefix := range []string{"throttle_lever_position", "propeller_lever_position", "mixture_lever_position", "cowl_flap_position"} {
+		for i := 1; i <= 4; i++ {
+			columns = append(columns, resampleColumn{fmt.Sprintf("%s%d", prefix, i), resampleLinear})
+		}
+	}
+	for _, prefix := range []string{"electrical_master_battery", "general_engine_starter", "general_engine_combustion"} {
+		for i := 1; i <= 4; i++ {
+			columns = append(columns, resampleColumn{fmt.Sprintf("%s%d", prefix, i), resampleStep})
+		}
+	}
+	return columns
+}
+
+// resampleRow is one stored sample of a table
+type resampleRow struct {
+	timestamp int64
+	values    []sql.NullFloat64
+}
+
+// interpolateResampleValue interpolates a column value at fraction ratio between two samples. Missing
+// values are not interpolated across; the nearer sample's value is used instead.
+func interpolateResampleValue(mode resampleMode, a, b sql.NullFloat64, ratio float64) sql.NullFloat64 {
+	if mode == resampleStep || !a.Valid || !b.Valid {
+		if ratio < 0.5 || mode == resampleStep {
+			return a
+		}
+		return b
+	}
+
+	if mode == resampleAngle {
+		delta := math.Mod(b.Float64-a.Float64+540, 360) - 180
+		return sql.NullFloat64{Float64: math.Mod(a.Float64+delta*ratio+360, 360), Valid: true}
+	}
+	return sql.NullFloat64{Float64: a.Float64 + (b.Float64-a.Float64)*ratio, Valid: true}
+}
+
+// resampleTableData writes the samples of an aircraft in a table at a fixed interval to the new
+// aircraft. Samples are placed at origin plus multiples of the interval within the recorded range.
+func resampleTableData(tx *sql.Tx, table string, columns []resampleColumn, originalAircraftID, newAircraftID int, origin, intervalMs int64) (int, error) {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+
+	query := fmt.Sprintf("SELECT timestamp, %s FROM %s WHERE aircraft_id = ? ORDER BY timestamp", strings.Join(names, ", "), table)
+	rows, err := tx.Query(query, originalAircraftID)
+	if err != nil {
+		return 0, err
+	}
+
+	var samples []resampleRow
+	for rows.Next() {
+		row := resampleRow{values: make([]sql.NullFloat64, len(columns))}
+		dest := []interface{}{&row.timestamp}
+		for i := range row.values {
+			dest = append(dest, &row.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		// Samples with repeated timestamps cannot be interpolated between
+		if len(samples) > 0 && row.timestamp <= samples[len(samples)-1].timestamp {
+			continue
+		}
+		samples = append(samples, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(samples) == 0 {
+		return 0, nil
+	}
+
+	insertQuery := fmt.Sprintf("INSERT INTO %s (aircraft_id, timestamp, %s) VALUES (?, ?%s)",
+		table, strings.Join(names, ", "), strings.Repeat(", ?", len(columns)))
+	stmt, err := tx.Prepare(insertQuery)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	first, last := samples[0].timestamp, samples[len(samples)-1].timestamp
+	t := origin
+	if t < first {
+		t += (first - origin + intervalMs - 1) / intervalMs * intervalMs
+	}
+
+	written := 0
+	i := 0
+	args := make([]interface{}, len(columns)+2)
+	for ; t <= last; t += intervalMs {
+		for i+1 < len(samples) && samples[i+1].timestamp <= t {
+			i++
+		}
+
+		args[0], args[1] = newAircraftID, t
+		if i+1 == len(samples) {
+			for c := range columns {
+				args[c+2] = samples[i].values[c]
+			}
+		} else {
+			a, b := samples[i], samples[i+1]
+			ratio := float64(t-a.timestamp) / float64(b.timestamp-a.timestamp)
+			for c, column := range columns {
+				args[c+2] = interpolateResampleValue(column.mode, a.values[c], b.values[c], ratio)
+			}
+		}
+
+		if _, err := stmt.Exec(args...); err != nil {
+			return 0, err
+		}
+		written++
+	}
+	return written, nil
+}
+
+// resampleFlight creates a copy of a flight with every aircraft's position, attitude and engine data
+// resampled to a fixed rate; markers are copied unchanged
+func resampleFlight(originalFlightID int, newTitle string, rateHz float64) (int, error) {
+	intervalMs := int64(math.Round(1000 / rateHz))
+
+	// Start transaction
+	tx, err := mainDB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	newFlightID, err := duplicateFlightRecord(tx, originalFlightID, newTitle)
+	if err != nil {
+		return 0, fmt.Errorf("failed to duplicate flight record: %w", err)
+	}
+
+	aircraft, err := getAircraftByFlightIDFromMainDB(originalFlightID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get aircraft: %w", err)
+	}
+
+	for _, ac := range aircraft {
+		newAircraftID, err := duplicateAircraftRecord(tx, ac, newFlightID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to duplicate aircraft %d: %w", ac.ID, err)
+		}
+
+		// All tables of an aircraft share the sample times, starting at its first position sample
+		var origin sql.NullInt64
+		if err := tx.QueryRow("SELECT MIN(timestamp) FROM position WHERE aircraft_id = ?", ac.ID).Scan(&origin); err != nil {
+			return 0, fmt.Errorf("failed to get start of aircraft %d: %w", ac.ID, err)
+		}
+
+		for _, table := range []struct {
+			name    string
+			columns []resampleColumn
+		}{
+			{"position", positionResampleColumns},
+			{"attitude", attitudeResampleColumns},
+			{"engine", engineResampleColumns},
+		} {
+			tableOrigin := origin.Int64
+			if !origin.Valid {
+				if err := tx.QueryRow(fmt.Sprintf("SELECT COALESCE(MIN(timestamp), 0) FROM %s WHERE aircraft_id = ?", table.name), ac.ID).Scan(&tableOrigin); err != nil {
+					return 0, fmt.Errorf("failed to get start of %s data for aircraft %d: %w", table.name, ac.ID, err)
+				}
+			}
+			if _, err := resampleTableData(tx, table.name, table.columns, ac.ID, newAircraftID, tableOrigin, intervalMs); err != nil {
+				return 0, fmt.Errorf("failed to resample %s data for aircraft %d: %w", table.name, ac.ID, err)
+			}
+		}
+	}
+
+	// Markers keep their times, which are relative to the unchanged flight start
+	if err := duplicateMarkersTrimmed(tx, originalFlightID, newFlightID, 0, math.MaxFloat64); err != nil {
+		return 0, fmt.Errorf("failed to duplicate markers: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("Successfully resampled flight %d to %g Hz as flight %d with title '%s'", originalFlightID, rateHz, newFlightID, newTitle)
+	return newFlightID, nil
+}
+
+// handleResampleFlight creates a copy of a flight resampled to a fixed rate
+func handleResampleFlight(w http.ResponseWriter, r *http.Request, flightId int) {
+	var request struct {
+		NewTitle string  `json:"new_title"`
+		RateHz   float64 `json:"rate_hz"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpError(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	if request.NewTitle == "" {
+		httpError(w, "New title is required", http.StatusBadRequest)
+		return
+	}
+	if request.RateHz < minResampleRateHz || request.RateHz > maxResampleRateHz {
+		httpError(w, fmt.Sprintf("Rate must be between %g and %g Hz", minResampleRateHz, maxResampleRateHz), http.StatusBadRequest)
+		return
+	}
+
+	exists, err := flightTitleExists(request.NewTitle)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to check title uniqueness: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if exists {
+		httpError(w, "A flight with this title already exists", http.StatusConflict)
+		return
+	}
+
+	newFlightID, err := resampleFlight(flightId, request.NewTitle, request.RateHz)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to resample flight: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if _, err := assessFlightQuality(newFlightID); err != nil {
+		log.Printf("Failed to assess quality of flight %d: %v", newFlightID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":        "success",
+		"message":       fmt.Sprintf("Flight resampled to %g Hz with ID %d", request.RateHz, newFlightID),
+		"new_flight_id": newFlightID,
+	})
+}
@@ -0,0 +1,147 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// failureMarkerLabel labels the event markers of the failures injected by the operator
+const failureMarkerLabel = "failure_started"
+
+// FailureResponse is the participant's first significant input after one failure. Latencies are nil when
+// the input did not change significantly before the next failure or the end of the flight.
+type FailureResponse struct {
+	FailureTime            float64  `json:"failure_time"` // Flight time of the failure_started marker
+	ThrottleLatencySeconds *float64 `json:"throttle_latency_seconds"`
+	PitchLatencySeconds    *float64 `json:"pitch_latency_seconds"`
+	LatencySeconds         *float64 `json:"latency_seconds"` // The earlier of the two
+	Input                  string   `json:"input,omitempty"` // "throttle" or "pitch", whichever responded first
+}
+
+// ResponseLatency measures how long the participant took to react to the failures logged during a flight,
+// the primary dependent variable of the study
+type ResponseLatency struct {
+	Aircraft          string            `json:"aircraft"`
+	ThrottleThreshold float64           `json:"throttle_threshold"` // Fraction of full travel
+	PitchThresholdDeg float64           `json:"pitch_threshold_deg"`
+	Failures          []FailureResponse `json:"failures"`
+	LatencySeconds    *float64          `json:"latency_seconds"` // Of the first failure
+}
+
+// timedValue is a sample of an input on the flight timeline
+type timedValue struct {
+	time, value float64
+}
+
+// firstDeviation returns the seconds from start until the input first differs from its value at start by
+// more than threshold, looking until end; false if it does not, or if there is no sample up to start
+func firstDeviation(samples []timedValue, start, end, threshold float64) (float64, bool) {
+	i := sort.Search(len(samples), func(i int) bool { return samples[i].time > start })
+	if i == 0 {
+		return 0, false
+	}
+	reference := samples[i-1].value
+	for ; i < len(samples) && samples[i].time <= end; i++ {
+		if math.Abs(samples[i].value-reference) > threshold {
+			return samples[i].time - start, true
+		}
+	}
+	return 0, false
+}
+
+// calculateResponseLatency measures the response of the participant's aircraft to every failure_started
+// marker of a flight, from the throttle of engine 1 and the pitch attitude
+func calculateResponseLatency(flightID int) (*ResponseLatency, error) {
+	aircraftID, label, err := getUserAircraftID(flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get aircraft: %w", err)
+	}
+
+	markers, err := getMarkersForFlight(flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get markers: %w", err)
+	}
+	var failureTimes []float64
+	for _, m := range markers {
+		if m.Type == EventMarkerType && m.Label == failureMarkerLabel {
+			failureTimes = append(failureTimes, m.Time)
+		}
+	}
+
+	latency := &ResponseLatency{
+		Aircraft:          label,
+		ThrottleThreshold: analysisConfig.ResponseThrottleThreshold,
+		PitchThresholdDeg: analysisConfig.ResponsePitchThresholdDeg,
+		Failures:          []FailureResponse{},
+	}
+	if len(failureTimes) == 0 {
+		return latency, nil
+	}
+
+	engineData, err := getEngineDataFromMainDB(aircraftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get engine data: %w", err)
+	}
+	positions := make([]float64, len(engineData))
+	for i, e := range engineData {
+		positions[i] = e.ThrottlePosition1
+	}
+	normalizeControlPositions(positions)
+	throttle := make([]timedValue, len(engineData))
+	for i, e := range engineData {
+		throttle[i] = timedValue{e.TimestampSeconds, positions[i]}
+	}
+
+	attitudeData, err := getAttitudeDataFromMainDB(aircraftID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attitude data: %w", err)
+	}
+	pitch := make([]timedValue, len(attitudeData))
+	for i, a := range attitudeData {
+		pitch[i] = timedValue{a.TimestampSeconds, a.Pitch}
+	}
+
+	for i, start := range failureTimes {
+		end := math.Inf(1)
+		if i+1 < len(failureTimes) {
+			end = failureTimes[i+1]
+		}
+
+		response := FailureResponse{FailureTime: start}
+		if seconds, ok := firstDeviation(throttle, start, end, latency.ThrottleThreshold); ok {
+			response.ThrottleLatencySeconds = &seconds
+			response.LatencySeconds, response.Input = &seconds, "throttle"
+		}
+		if seconds, ok := firstDeviation(pitch, start, end, latency.PitchThresholdDeg); ok {
+			response.PitchLatencySeconds = &seconds
+			if response.LatencySeconds == nil || seconds < *response.LatencySeconds {
+				response.LatencySeconds, response.Input = &seconds, "pitch"
+			}
+		}
+		latency.Failures = append(latency.Failures, response)
+	}
+	latency.LatencySeconds = latency.Failures[0].LatencySeconds
+	return latency, nil
+}
+
+// handleGetResponseLatency returns the delay between each failure logged during a flight and the
+// participant's first significant throttle or pitch input
+func handleGetResponseLatency(w http.ResponseWriter, r *http.Request, flightId int) {
+	latency, err := calculateResponseLatency(flightId)
+	if errors.Is(err, sql.ErrNoRows) {
+		httpError(w, "Flight has no aircraft", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to calculate response latency: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(latency)
+}
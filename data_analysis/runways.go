@@ -0,0 +1,225 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Runway is the landing threshold of a runway approaches are flown to
+type Runway struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"` // e.g. "EGNT 25"
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	ElevationFt float64 `json:"elevation_ft"` // Threshold elevation
+	HeadingDeg  float64 `json:"heading_deg"`  // True heading of the runway in the landing direction
+	// Angle of the glidepath to the threshold, defaultGlidepathDeg if omitted
+	GlidepathDeg float64 `json:"glidepath_deg"`
+}
+
+// defaultGlidepathDeg is the glidepath angle of runways added without one
+const defaultGlidepathDeg = 3.0
+
+// errDuplicateRunway is returned when a runway name is already taken
+var errDuplicateRunway = errors.New("a runway with this name already exists")
+
+// ensureRunwaysTable creates the runway library
+func ensureRunwaysTable() error {
+	runwaysSchema := `
+		CREATE TABLE IF NOT EXISTS runway (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL UNIQUE,
+			latitude REAL NOT NULL,
+			longitude REAL NOT NULL,
+			elevation_ft REAL NOT NULL DEFAULT 0,
+			heading_deg REAL NOT NULL
+		);
+	`
+	if _, err := mainDB.Exec(runwaysSchema); err != nil {
+		return fmt.Errorf("failed to create runway table: %w", err)
+	}
+	return nil
+}
+
+// ensureRunwayGlidepathColumn adds the glidepath angle to runways added before glidepaths were analyzed
+func ensureRunwayGlidepathColumn() error {
+	var exists bool
+	err := mainDB.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('runway') WHERE name = 'glidepath_deg'").Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get runway table info: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	log.Println("Adding glidepath_deg column to runway table...")
+	if _, err := mainDB.Exec(fmt.Sprintf("ALTER TABLE runway ADD COLUMN glidepath_deg REAL NOT NULL DEFAULT %g", defaultGlidepathDeg)); err != nil {
+		return fmt.Errorf("failed to add glidepath_deg column: %w", err)
+	}
+	return nil
+}
+
+// validate checks the name, threshold coordinates, heading and glidepath of a runway
+func (rw *Runway) validate() error {
+	rw.Name = strings.TrimSpace(rw.Name)
+	if rw.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if rw.Latitude < -90 || rw.Latitude > 90 || rw.Longitude < -180 || rw.Longitude > 180 {
+		return fmt.Errorf("invalid threshold coordinates: %f, %f", rw.Latitude, rw.Longitude)
+	}
+	if rw.HeadingDeg < 0 || rw.HeadingDeg >= 360 {
+		return fmt.Errorf("heading_deg must be at least 0 and below 360")
+	}
+	if rw.GlidepathDeg == 0 {
+		rw.GlidepathDeg = defaultGlidepathDeg
+	}
+	if rw.GlidepathDeg < 1 || rw.GlidepathDeg > 10 {
+		return fmt.Errorf("glidepath_deg must be between 1 and 10")
+	}
+	return nil
+}
+
+const runwayColumns = "id, name, latitude, longitude, elevation_ft, heading_deg, glidepath_deg"
+
+func scanRunway(row interface{ Scan(...interface{}) error }) (*Runway, error) {
+	var rw Runway
+	if err := row.Scan(&rw.ID, &rw.Name, &rw.Latitude, &rw.Longitude, &rw.ElevationFt, &rw.HeadingDeg, &rw.GlidepathDeg); err != nil {
+		return nil, err
+	}
+	return &rw, nil
+}
+
+// getRunways returns the library ordered by name
+func getRunways() ([]Runway, error) {
+	rows, err := mainDB.Query("SELECT " + runwayColumns + " FROM runway ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runways := []Runway{}
+	for rows.Next() {
+		rw, err := scanRunway(rows)
+		if err != nil {
+			return nil, err
+		}
+		runways = append(runways, *rw)
+	}
+	return runways, rows.Err()
+}
+
+// getRunway returns one runway, or sql.ErrNoRows
+func getRunway(id int) (*Runway, error) {
+	return scanRunway(mainDB.QueryRow("SELECT "+runwayColumns+" FROM runway WHERE id = ?", id))
+}
+
+// getRunwayByName returns the runway of a name, or sql.ErrNoRows
+func getRunwayByName(name string) (*Runway, error) {
+	return scanRunway(mainDB.QueryRow("SELECT "+runwayColumns+" FROM runway WHERE name = ?", name))
+}
+
+// createRunway stores a validated runway
+func createRunway(rw Runway) (*Runway, error) {
+	result, err := mainDB.Exec("INSERT INTO runway (name, latitude, longitude, elevation_ft, heading_deg, glidepath_deg) VALUES (?, ?, ?, ?, ?, ?)",
+		rw.Name, rw.Latitude, rw.Longitude, rw.ElevationFt, rw.HeadingDeg, rw.GlidepathDeg)
+	if isUniqueViolation(err) {
+		return nil, errDuplicateRunway
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	rw.ID = int(id)
+	return &rw, nil
+}
+
+// lookupRunway returns the runway selected by an ID parameter; on failure it writes the error response
+// and returns false
+func lookupRunway(w http.ResponseWriter, value string) (*Runway, bool) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Invalid runway '%s'", value), http.StatusBadRequest)
+		return nil, false
+	}
+	rw, err := getRunway(id)
+	if err == sql.ErrNoRows {
+		httpError(w, fmt.Sprintf("Runway %d not found", id), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get runway: %v", err), http.StatusInternalServerError)
+		return nil, false
+	}
+	return rw, true
+}
+
+// handleGetRunways lists the runway library
+func handleGetRunways(w http.ResponseWriter, r *http.Request) {
+	runways, err := getRunways()
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get runways: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runways)
+}
+
+// handleCreateRunway adds a runway to the library
+func handleCreateRunway(w http.ResponseWriter, r *http.Request) {
+	var rw Runway
+	if err := json.NewDecoder(r.Body).Decode(&rw); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := rw.validate(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := createRunway(rw)
+	if err == errDuplicateRunway {
+		httpError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create runway: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleDeleteRunway removes a runway from the library
+func handleDeleteRunway(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("runwayId"))
+	if err != nil {
+		httpError(w, "Invalid runway ID", http.StatusBadRequest)
+		return
+	}
+
+	result, err := mainDB.Exec("DELETE FROM runway WHERE id = ?", id)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to delete runway: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		httpError(w, "Runway not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
@@ -0,0 +1,132 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kaireichart/master-thesis-operator-station/gps"
+)
+
+// DistanceMarkerSettings configures the reference point distance markers are measured from
+// and how the resulting markers are labelled
+type DistanceMarkerSettings struct {
+	ReferencePointID int     `json:"reference_point_id,omitempty"` // Library point the reference was selected from
+	ReferenceName    string  `json:"reference_name"`
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	DistanceNM       float64 `json:"distance_nm"`
+	// LabelTemplate may use the variables {distance}, {reference} and {aircraft}
+	LabelTemplate string `json:"label_template"`
+}
+
+var (
+	// The reference is taken from the study site's reference point configuration by initDistanceMarkerSettings
+	distanceMarkerSettings = DistanceMarkerSettings{
+		LabelTemplate: "{distance}nm from {reference} - {aircraft}",
+	}
+	distanceMarkerSettingsMutex = &sync.Mutex{}
+)
+
+// initDistanceMarkerSettings measures distance markers from the site reference point and its radius,
+// following changes to it while no library point is selected for them
+func initDistanceMarkerSettings() {
+	followSiteReference := func(reference gps.ReferenceConfig) {
+		distanceMarkerSettingsMutex.Lock()
+		defer distanceMarkerSettingsMutex.Unlock()
+		if distanceMarkerSettings.ReferencePointID != 0 {
+			return
+		}
+		distanceMarkerSettings.ReferenceName = reference.Name
+		distanceMarkerSettings.Latitude = reference.Latitude
+		distanceMarkerSettings.Longitude = reference.Longitude
+		distanceMarkerSettings.DistanceNM = reference.RadiusNM
+	}
+
+	followSiteReference(gps.GetReferenceConfig())
+	gps.OnReferenceChange(followSiteReference)
+}
+
+// GetDistanceMarkerSettings returns the current distance marker settings
+func GetDistanceMarkerSettings() DistanceMarkerSettings {
+	distanceMarkerSettingsMutex.Lock()
+	defer distanceMarkerSettingsMutex.Unlock()
+	return distanceMarkerSettings
+}
+
+// Label renders the label template for a marker of the given aircraft
+func (s DistanceMarkerSettings) Label(aircraftLabel string) string {
+	replacer := strings.NewReplacer(
+		"{distance}", strconv.FormatFloat(s.DistanceNM, 'f', -1, 64),
+		"{reference}", s.ReferenceName,
+		"{aircraft}", aircraftLabel,
+	)
+	return replacer.Replace(s.LabelTemplate)
+}
+
+// applyReferencePoint takes the name and coordinates of a library point as the reference
+func (s *DistanceMarkerSettings) applyReferencePoint(point *ReferencePoint) {
+	s.ReferencePointID = point.ID
+	s.ReferenceName = point.Name
+	s.Latitude = point.Latitude
+	s.Longitude = point.Longitude
+}
+
+// validate checks that the settings describe a usable reference point and label
+func (s DistanceMarkerSettings) validate() error {
+	if s.Latitude < -90 || s.Latitude > 90 || s.Longitude < -180 || s.Longitude > 180 {
+		return fmt.Errorf("invalid reference coordinates: %f, %f", s.Latitude, s.Longitude)
+	}
+	if s.DistanceNM <= 0 {
+		return fmt.Errorf("distance must be positive")
+	}
+	if strings.TrimSpace(s.LabelTemplate) == "" {
+		return fmt.Errorf("label template must not be empty")
+	}
+	return nil
+}
+
+// handleGetDistanceMarkerSettings returns the current distance marker settings
+func handleGetDistanceMarkerSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetDistanceMarkerSettings())
+}
+
+// handleUpdateDistanceMarkerSettings replaces the distance marker settings; omitted fields keep their current value
+func handleUpdateDistanceMarkerSettings(w http.ResponseWriter, r *http.Request) {
+	distanceMarkerSettingsMutex.Lock()
+	defer distanceMarkerSettingsMutex.Unlock()
+
+	settings := distanceMarkerSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	// A selected library point overrides the reference name and coordinates
+	if settings.ReferencePointID != 0 {
+		point, err := getReferencePoint(settings.ReferencePointID)
+		if err == sql.ErrNoRows {
+			httpError(w, "Reference point not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			httpError(w, fmt.Sprintf("Failed to get reference point: %v", err), http.StatusInternalServerError)
+			return
+		}
+		settings.applyReferencePoint(point)
+	}
+
+	if err := settings.validate(); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	distanceMarkerSettings = settings
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
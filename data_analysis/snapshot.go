@@ -0,0 +1,88 @@
+package data_analysis
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+)
+
+// readOnly is set while the dataset is frozen; the inbox then leaves new recordings alone and computed
+// metrics are not stored
+var readOnly atomic.Bool
+
+// SetReadOnly stops or resumes the background imports of the inbox and the storing of computed metrics,
+// e.g. while the dataset is frozen. Requests changing data are rejected by the caller.
+func SetReadOnly(enabled bool) {
+	readOnly.Store(enabled)
+	if !enabled {
+		// Catch up on the metrics of flights viewed while frozen
+		triggerPrecomputation()
+	}
+}
+
+// DatabaseFile is the path of the analysis database, left out when copying the data folder as the
+// database is snapshotted with SnapshotDatabase instead
+func DatabaseFile() string {
+	return mainDatabasePath
+}
+
+// SnapshotDatabase writes a consistent copy of the analysis database to path, which must not exist
+func SnapshotDatabase(path string) error {
+	if mainDB == nil {
+		return fmt.Errorf("analysis database is not open")
+	}
+	if _, err := mainDB.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to snapshot analysis database: %w", err)
+	}
+	return nil
+}
+
+// WriteDatasetExports writes the batch export and the statistics export of all flights that are not
+// rejected to dir, as flights_export.zip and flight_statistics.csv. It returns the written files.
+func WriteDatasetExports(dir string) ([]string, error) {
+	all, err := getFlightsFromMainDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flights: %w", err)
+	}
+	var flights []*Flight
+	for i := range all {
+		if slices.Contains(defaultReviewFilter, all[i].ReviewStatus) {
+			flights = append(flights, &all[i])
+		}
+	}
+
+	exports := []struct {
+		name  string
+		write func(io.Writer, []*Flight) error
+	}{
+		{"flights_export.zip", writeBatchExport},
+		{"flight_statistics.csv", func(w io.Writer, flights []*Flight) error { return writeStatisticsCSV(w, flights, false) }},
+	}
+
+	var written []string
+	for _, export := range exports {
+		path := filepath.Join(dir, export.name)
+		if err := writeExportFile(path, flights, export.write); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", export.name, err)
+		}
+		written = append(written, path)
+	}
+	log.Printf("Wrote dataset exports of %d flights to %s", len(flights), dir)
+	return written, nil
+}
+
+func writeExportFile(path string, flights []*Flight, write func(io.Writer, []*Flight) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file, flights); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
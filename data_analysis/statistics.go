@@ -7,9 +7,19 @@ import (
 // FlightStatistics represents statistical analysis of flight data
 type FlightStatistics struct {
 	AirspeedStats       *DataStatistics `json:"airspeed_stats"`
+	TrueAirspeedStats   *DataStatistics `json:"true_airspeed_stats"`
+	AirspeedSource      string          `json:"airspeed_source"` // "recorded", "estimated" or "mixed"
 	IndicatedAltitudeStats *DataStatistics `json:"indicated_altitude_stats"`
 	AltitudeStats       *DataStatistics `json:"altitude_stats"`
 	PressureAltitudeStats *DataStatistics `json:"pressure_altitude_stats"`
+	VerticalSpeedStats  *DataStatistics `json:"vertical_speed_stats"`
+	BankAngleStats      *DataStatistics `json:"bank_angle_stats"`     // Absolute bank in degrees
+	PitchStats          *DataStatistics `json:"pitch_stats"`          // Degrees
+	TurnRateStats       *DataStatistics `json:"turn_rate_stats"`      // Absolute heading change in degrees per second
+	BankExceedances     *BankExceedances `json:"bank_exceedances"`
+	ControlActivity     *ControlActivity `json:"control_activity"` // Throttle and control surface inputs
+	Units               *UnitSystem      `json:"units,omitempty"`  // Of the altitude, airspeed and vertical speed statistics, set for responses
+	Glidepath           *GlidepathDeviation `json:"glidepath,omitempty"` // On the final approach to the target runway, set for responses
 }
 
 // DataStatistics represents statistical measures for a data series
@@ -22,37 +32,36 @@ type DataStatistics struct {
 	Max        float64 `json:"max"`
 	Range      float64 `json:"range"`
 	Median     float64 `json:"median"`
+	P5         float64 `json:"p5"`
+	P25        float64 `json:"p25"`
+	P75        float64 `json:"p75"`
+	P95        float64 `json:"p95"`
+	IQR        float64 `json:"iqr"` // P75 - P25
 }
 
 // CalculateFlightStatistics calculates comprehensive statistics for flight data
 func CalculateFlightStatistics(flightData *FlightData) map[string]*FlightStatistics {
+	return calculateColumnStatistics(flightDataToColumns(flightData))
+}
+
+// calculateColumnStatistics calculates comprehensive statistics from columnar flight series
+func calculateColumnStatistics(columns map[string]*SeriesColumns) map[string]*FlightStatistics {
 	result := make(map[string]*FlightStatistics)
 
-	for aircraftLabel, positionData := range flightData.PositionData {
-		if len(positionData) == 0 {
+	positive := func(v float64) bool { return v > 0 }
+	nonZero := func(v float64) bool { return v != 0 }
+
+	for aircraftLabel, series := range columns {
+		if series.Len() == 0 {
 			continue
 		}
 
-		// Extract data series
-		airspeeds := make([]float64, 0, len(positionData))
-		indicatedAltitudes := make([]float64, 0, len(positionData))
-		altitudes := make([]float64, 0, len(positionData))
-		pressureAltitudes := make([]float64, 0, len(positionData))
-
-		for _, point := range positionData {
-			if point.Airspeed > 0 { // Only include positive airspeed values
-				airspeeds = append(airspeeds, point.Airspeed)
-			}
-			if point.IndicatedAltitude != 0 { // Only include non-zero altitude values
-				indicatedAltitudes = append(indicatedAltitudes, point.IndicatedAltitude)
-			}
-			if point.Altitude != 0 {
-				altitudes = append(altitudes, point.Altitude)
-			}
-			if point.PressureAltitude != 0 {
-				pressureAltitudes = append(pressureAltitudes, point.PressureAltitude)
-			}
-		}
+		// Only include positive airspeed values and non-zero altitude values
+		airspeeds := filterValues(series.Airspeed, positive)
+		trueAirspeeds := filterValues(series.TrueAirspeed, positive)
+		indicatedAltitudes := filterValues(series.IndicatedAltitude, nonZero)
+		altitudes := filterValues(series.Altitude, nonZero)
+		pressureAltitudes := filterValues(series.PressureAltitude, nonZero)
 
 		// Calculate statistics
 		stats := &FlightStatistics{}
@@ -60,6 +69,10 @@ func CalculateFlightStatistics(flightData *FlightData) map[string]*FlightStatist
 		if len(airspeeds) > 0 {
 			stats.AirspeedStats = calculateDataStatistics(airspeeds)
 		}
+		if len(trueAirspeeds) > 0 {
+			stats.TrueAirspeedStats = calculateDataStatistics(trueAirspeeds)
+		}
+		stats.AirspeedSource = airspeedSource(series.AirspeedEstimated)
 		if len(indicatedAltitudes) > 0 {
 			stats.IndicatedAltitudeStats = calculateDataStatistics(indicatedAltitudes)
 		}
@@ -69,6 +82,16 @@ func CalculateFlightStatistics(flightData *FlightData) map[string]*FlightStatist
 		if len(pressureAltitudes) > 0 {
 			stats.PressureAltitudeStats = calculateDataStatistics(pressureAltitudes)
 		}
+		// Level flight is a valid vertical speed, so every sample counts
+		if len(series.VerticalSpeed) > 0 {
+			stats.VerticalSpeedStats = calculateDataStatistics(series.VerticalSpeed)
+		}
+		if series.Attitude != nil {
+			calculateAttitudeStatistics(stats, series.Attitude)
+		}
+		if series.Controls != nil {
+			calculateControlActivity(stats, series.Controls)
+		}
 
 		result[aircraftLabel] = stats
 	}
@@ -116,6 +139,9 @@ func calculateDataStatistics(data []float64) *DataStatistics {
 		median = sortedData[count/2]
 	}
 
+	p25 := Percentile(sortedData, 0.25)
+	p75 := Percentile(sortedData, 0.75)
+
 	return &DataStatistics{
 		Count:    count,
 		Mean:     mean,
@@ -125,7 +151,23 @@ func calculateDataStatistics(data []float64) *DataStatistics {
 		Max:      max,
 		Range:    max - min,
 		Median:   median,
+		P5:       Percentile(sortedData, 0.05),
+		P25:      p25,
+		P75:      p75,
+		P95:      Percentile(sortedData, 0.95),
+		IQR:      p75 - p25,
+	}
+}
+
+// Percentile returns the p-th percentile (0..1) of sorted values using linear interpolation
+func Percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lower := int(pos)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
 	}
+	fraction := pos - float64(lower)
+	return sorted[lower] + fraction*(sorted[lower+1]-sorted[lower])
 }
 
 // quickSort implements quicksort algorithm for sorting float64 slices
@@ -152,21 +194,41 @@ func partition(arr []float64, low, high int) int {
 	return i + 1
 }
 
-// CalculateVarianceOverTime calculates variance for time windows
+// CalculateVarianceOverTime calculates variance for time windows using running sums,
+// so each window costs O(1) instead of a full statistics pass
 func CalculateVarianceOverTime(data []float64, windowSize int) []float64 {
 	if len(data) < windowSize || windowSize <= 1 {
 		return []float64{}
 	}
 
 	variances := make([]float64, 0, len(data)-windowSize+1)
+	n := float64(windowSize)
+
+	// Shift values by the first sample to keep the running sums numerically stable
+	shift := data[0]
+	sum, sumSquares := 0.0, 0.0
+	for _, value := range data[:windowSize] {
+		d := value - shift
+		sum += d
+		sumSquares += d * d
+	}
+
+	for i := 0; ; i++ {
+		mean := sum / n
+		variance := sumSquares/n - mean*mean
+		if variance < 0 {
+			variance = 0
+		}
+		variances = append(variances, variance)
 
-	for i := 0; i <= len(data)-windowSize; i++ {
-		window := data[i : i+windowSize]
-		stats := calculateDataStatistics(window)
-		if stats != nil {
-			variances = append(variances, stats.Variance)
+		if i+windowSize >= len(data) {
+			break
 		}
+		outgoing := data[i] - shift
+		incoming := data[i+windowSize] - shift
+		sum += incoming - outgoing
+		sumSquares += incoming*incoming - outgoing*outgoing
 	}
 
 	return variances
-}
\ No newline at end of file
+}
@@ -0,0 +1,150 @@
+package data_analysis
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// statisticsCSVHeader is the header of the statistics export, one row per aircraft per metric
+var statisticsCSVHeader = []string{
+	"flight_id", "flight_title", "participant_id", "condition", "aircraft", "metric", "unit",
+	"count", "mean", "variance", "std_dev", "min", "max", "range", "median",
+	"p5", "p25", "p75", "p95", "iqr",
+}
+
+// statisticsRecords returns the CSV rows of the statistics of one flight, converted to the given units;
+// metrics without data are skipped
+func statisticsRecords(flight *Flight, statistics map[string]*FlightStatistics, units UnitSystem) [][]string {
+	statistics = units.convertStatistics(statistics)
+
+	labels := make([]string, 0, len(statistics))
+	for label := range statistics {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	formatFloat := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	var records [][]string
+	for _, label := range labels {
+		stats := statistics[label]
+		if stats == nil {
+			continue
+		}
+
+		metrics := []struct {
+			name  string
+			unit  string
+			stats *DataStatistics
+		}{
+			{"airspeed", units.Speed, stats.AirspeedStats},
+			{"true_airspeed", units.Speed, stats.TrueAirspeedStats},
+			{"indicated_altitude", units.Altitude, stats.IndicatedAltitudeStats},
+			{"altitude", units.Altitude, stats.AltitudeStats},
+			{"pressure_altitude", units.Altitude, stats.PressureAltitudeStats},
+			{"vertical_speed", units.VerticalSpeed, stats.VerticalSpeedStats},
+			{"bank_angle", "deg", stats.BankAngleStats},
+			{"pitch", "deg", stats.PitchStats},
+			{"turn_rate", "deg/s", stats.TurnRateStats},
+		}
+		if activity := stats.ControlActivity; activity != nil {
+			metrics = append(metrics, []struct {
+				name  string
+				unit  string
+				stats *DataStatistics
+			}{
+				{"throttle_rate", "travel/s", activity.Throttle.rateStats()},
+				{"elevator_rate", "travel/s", activity.Elevator.rateStats()},
+				{"aileron_rate", "travel/s", activity.Aileron.rateStats()},
+				{"rudder_rate", "travel/s", activity.Rudder.rateStats()},
+			}...)
+		}
+		for _, metric := range metrics {
+			if metric.stats == nil {
+				continue
+			}
+			records = append(records, []string{
+				strconv.Itoa(flight.ID),
+				flight.Title,
+				flight.ParticipantID,
+				flight.Condition,
+				label,
+				metric.name,
+				metric.unit,
+				strconv.Itoa(metric.stats.Count),
+				formatFloat(metric.stats.Mean),
+				formatFloat(metric.stats.Variance),
+				formatFloat(metric.stats.StdDev),
+				formatFloat(metric.stats.Min),
+				formatFloat(metric.stats.Max),
+				formatFloat(metric.stats.Range),
+				formatFloat(metric.stats.Median),
+				formatFloat(metric.stats.P5),
+				formatFloat(metric.stats.P25),
+				formatFloat(metric.stats.P75),
+				formatFloat(metric.stats.P95),
+				formatFloat(metric.stats.IQR),
+			})
+		}
+	}
+	return records
+}
+
+// handleExportStatistics exports the statistics of all flights, or those listed in the flight_ids
+// parameter, as one CSV with a row per metric of each target aircraft (of every aircraft with aircraft=all)
+func handleExportStatistics(w http.ResponseWriter, r *http.Request) {
+	flights, ok := selectExportFlights(w, r)
+	if !ok {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	if err := writeStatisticsCSV(buf, flights, allAircraftRequested(r)); err != nil {
+		httpError(w, fmt.Sprintf("Failed to export statistics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("flight_statistics_%s.csv", time.Now().Format("20060102_150405"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Write(buf.Bytes())
+}
+
+// writeStatisticsCSV writes the statistics of the flights as CSV with a row per metric of the target
+// aircraft, or of every aircraft if allAircraft is set
+func writeStatisticsCSV(w io.Writer, flights []*Flight, allAircraft bool) error {
+	units := configuredUnits()
+	writer := csv.NewWriter(w)
+	if err := writer.Write(statisticsCSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, flight := range flights {
+		// Cached statistics are used when they have been precomputed
+		statistics, err := getFlightStatistics(flight.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get statistics of flight %d: %w", flight.ID, err)
+		}
+		if !allAircraft {
+			if statistics, err = targetAircraftOnly(flight.ID, statistics); err != nil {
+				return fmt.Errorf("failed to get statistics of flight %d: %w", flight.ID, err)
+			}
+		}
+		if err := writer.WriteAll(statisticsRecords(flight, statistics, units)); err != nil {
+			return fmt.Errorf("failed to write CSV rows: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("CSV writer error: %w", err)
+	}
+	return nil
+}
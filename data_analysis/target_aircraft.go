@@ -0,0 +1,136 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// SetTargetAircraftRequest designates the aircraft flown by the participant by sequence number or tail number
+type SetTargetAircraftRequest struct {
+	SeqNr      int    `json:"seq_nr"`
+	TailNumber string `json:"tail_number"`
+}
+
+// findTargetAircraft returns the aircraft with the tail number (ignoring case) or, if none has it, the
+// sequence number; an empty tail number or zero sequence number is not matched
+func findTargetAircraft(aircraft []Aircraft, tailNumber string, seqNr int) *Aircraft {
+	tailNumber = strings.TrimSpace(tailNumber)
+	if tailNumber != "" {
+		for i := range aircraft {
+			if strings.EqualFold(aircraft[i].TailNumber, tailNumber) {
+				return &aircraft[i]
+			}
+		}
+	}
+	if seqNr != 0 {
+		for i := range aircraft {
+			if aircraft[i].SeqNr == seqNr {
+				return &aircraft[i]
+			}
+		}
+	}
+	return nil
+}
+
+// setTargetAircraft makes an aircraft the user aircraft of its flight, which distance and warning
+// markers and statistics are limited to by default
+func setTargetAircraft(flightID, seqNr int) error {
+	_, err := mainDB.Exec("UPDATE flight SET user_aircraft_seq_nr = ? WHERE id = ?", seqNr, flightID)
+	return err
+}
+
+// applyTargetAircraftRule designates the aircraft matching the configured target tail number or sequence
+// number as the user aircraft of imported flights with several aircraft, e.g. formation or AI traffic.
+// Flights without a matching aircraft keep their recorded user aircraft.
+func applyTargetAircraftRule(flights []Flight) {
+	if analysisConfig.TargetTailNumber == "" && analysisConfig.TargetSeqNr == 0 {
+		return
+	}
+
+	for _, flight := range flights {
+		aircraft, err := getAircraftByFlightIDFromMainDB(flight.ID)
+		if err != nil {
+			log.Printf("Failed to get aircraft of flight %d: %v", flight.ID, err)
+			continue
+		}
+		if len(aircraft) < 2 {
+			continue
+		}
+		target := findTargetAircraft(aircraft, analysisConfig.TargetTailNumber, analysisConfig.TargetSeqNr)
+		if target == nil {
+			log.Printf("No aircraft of flight %d matches the target aircraft, keeping the recorded user aircraft", flight.ID)
+			continue
+		}
+		if err := setTargetAircraft(flight.ID, target.SeqNr); err != nil {
+			log.Printf("Failed to set target aircraft of flight %d: %v", flight.ID, err)
+			continue
+		}
+		log.Printf("Designated %s as the target aircraft of flight %d", target.Label(), flight.ID)
+	}
+}
+
+// allAircraftRequested reports whether the request asks for every aircraft of a flight with aircraft=all
+// instead of only the target aircraft
+func allAircraftRequested(r *http.Request) bool {
+	return r.URL.Query().Get("aircraft") == "all"
+}
+
+// targetAircraftOnly reduces per-aircraft results of a flight to its user aircraft. Without aircraft the
+// results are returned as they are.
+func targetAircraftOnly[T any](flightID int, byAircraft map[string]T) (map[string]T, error) {
+	label, err := getUserAircraftLabel(flightID)
+	if err == sql.ErrNoRows {
+		return byAircraft, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target aircraft: %w", err)
+	}
+
+	filtered := map[string]T{}
+	if value, exists := byAircraft[label]; exists {
+		filtered[label] = value
+	}
+	return filtered, nil
+}
+
+// handleSetTargetAircraft designates the aircraft flown by the participant in a flight with several
+// aircraft, returning the aircraft of the flight
+func handleSetTargetAircraft(w http.ResponseWriter, r *http.Request, flightId int) {
+	var req SetTargetAircraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if (req.SeqNr == 0) == (strings.TrimSpace(req.TailNumber) == "") {
+		httpError(w, "Either seq_nr or tail_number is required", http.StatusBadRequest)
+		return
+	}
+
+	aircraft, err := getAircraftByFlightIDFromMainDB(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get aircraft: %v", err), http.StatusInternalServerError)
+		return
+	}
+	target := findTargetAircraft(aircraft, req.TailNumber, req.SeqNr)
+	if target == nil {
+		httpError(w, fmt.Sprintf("No aircraft of flight %d matches", flightId), http.StatusNotFound)
+		return
+	}
+	if err := setTargetAircraft(flightId, target.SeqNr); err != nil {
+		httpError(w, fmt.Sprintf("Failed to set target aircraft: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Designated %s as the target aircraft of flight %d", target.Label(), flightId)
+
+	summaries, err := getAircraftSummaries(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get aircraft: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summaries)
+}
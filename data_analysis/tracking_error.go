@@ -0,0 +1,183 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// trackingChannels are the position series a flight can be compared with a target on
+var trackingChannels = map[string]func(*SeriesColumns) []float64{
+	"altitude":           func(c *SeriesColumns) []float64 { return c.Altitude },
+	"indicated_altitude": func(c *SeriesColumns) []float64 { return c.IndicatedAltitude },
+	"airspeed":           func(c *SeriesColumns) []float64 { return c.Airspeed },
+}
+
+// TrackingError is the deviation of a flight from its target over some samples; the error is the
+// flight's value minus the target
+type TrackingError struct {
+	Start   float64 `json:"start"` // Seconds, on the time base of the target
+	End     float64 `json:"end"`
+	Samples int     `json:"samples"`
+	RMSE    float64 `json:"rmse"`
+	MAE     float64 `json:"mae"`
+	Bias    float64 `json:"bias"` // Mean error, positive when the flight was above the target
+}
+
+// AircraftTrackingError holds the tracking error of one aircraft over the compared time and per window;
+// Overall is nil when no sample has a target
+type AircraftTrackingError struct {
+	Overall *TrackingError  `json:"overall"`
+	Windows []TrackingError `json:"windows"`
+}
+
+// trackingErrorSum sums the errors of the samples of one span
+type trackingErrorSum struct {
+	start, end                 float64
+	samples                    int
+	sumSquared, sumAbs, sumErr float64
+}
+
+func (s *trackingErrorSum) add(err float64) {
+	s.samples++
+	s.sumSquared += err * err
+	s.sumAbs += math.Abs(err)
+	s.sumErr += err
+}
+
+func (s *trackingErrorSum) result() TrackingError {
+	n := float64(s.samples)
+	return TrackingError{
+		Start:   s.start,
+		End:     s.end,
+		Samples: s.samples,
+		RMSE:    math.Sqrt(s.sumSquared / n),
+		MAE:     s.sumAbs / n,
+		Bias:    s.sumErr / n,
+	}
+}
+
+// calculateTrackingError compares a series with the target at each sample time, shifted by offset
+// seconds onto the time base of the target. target returns false where there is none.
+func calculateTrackingError(times, values []float64, offset float64, target func(float64) (float64, bool), windowSeconds float64) AircraftTrackingError {
+	result := AircraftTrackingError{Windows: []TrackingError{}}
+
+	var overall, window *trackingErrorSum
+	for i, value := range values {
+		t := times[i] - offset
+		targetValue, ok := target(t)
+		if !ok || math.IsNaN(value) {
+			continue
+		}
+		err := value - targetValue
+
+		if overall == nil {
+			overall = &trackingErrorSum{start: t}
+		}
+		overall.end = t
+		overall.add(err)
+
+		if windowSeconds <= 0 {
+			continue
+		}
+		// Windows are aligned to multiples of the window length, so they line up across flights
+		windowStart := math.Floor(t/windowSeconds) * windowSeconds
+		if window == nil || window.start != windowStart {
+			if window != nil {
+				result.Windows = append(result.Windows, window.result())
+			}
+			window = &trackingErrorSum{start: windowStart, end: windowStart + windowSeconds}
+		}
+		window.add(err)
+	}
+
+	if overall != nil {
+		r := overall.result()
+		result.Overall = &r
+	}
+	if window != nil {
+		result.Windows = append(result.Windows, window.result())
+	}
+	return result
+}
+
+// handleGetTrackingError computes the RMSE, MAE and bias of the altitude or airspeed per aircraft against
+// a constant target or a stored reference profile, over the whole flight and per time window
+func handleGetTrackingError(w http.ResponseWriter, r *http.Request, flightId int) {
+	query := r.URL.Query()
+
+	var channelName string
+	var target func(float64) (float64, bool)
+	response := map[string]interface{}{"flight_id": flightId}
+	switch {
+	case query.Get("profile") != "" && query.Get("target") != "":
+		httpError(w, "Either target or profile is required, not both", http.StatusBadRequest)
+		return
+	case query.Get("profile") != "":
+		profile, ok := lookupReferenceProfile(w, query.Get("profile"))
+		if !ok {
+			return
+		}
+		channelName, target = profile.Channel, profile.value
+		response["profile"] = map[string]interface{}{"id": profile.ID, "name": profile.Name}
+	case query.Get("target") != "":
+		value, err := strconv.ParseFloat(query.Get("target"), 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			httpError(w, fmt.Sprintf("Invalid target '%s'", query.Get("target")), http.StatusBadRequest)
+			return
+		}
+		channelName = query.Get("channel")
+		if _, ok := trackingChannels[channelName]; !ok {
+			httpError(w, fmt.Sprintf("Invalid channel '%s' (expected %s)", channelName, strings.Join(trackingChannelNames(), ", ")), http.StatusBadRequest)
+			return
+		}
+		target = func(float64) (float64, bool) { return value, true }
+		response["target"] = value
+	default:
+		httpError(w, "Either target or profile is required", http.StatusBadRequest)
+		return
+	}
+
+	var windowSeconds float64
+	if value := query.Get("window"); value != "" {
+		var err error
+		windowSeconds, err = strconv.ParseFloat(value, 64)
+		if err != nil || !(windowSeconds > 0) || math.IsInf(windowSeconds, 1) {
+			httpError(w, fmt.Sprintf("Invalid window '%s' (expected seconds above 0)", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The target's times count from the alignment marker, e.g. the failure onset, if one is given
+	alignment, err := parseAlignment(r, flightId)
+	if err != nil {
+		writeAlignmentError(w, err)
+		return
+	}
+	var offset float64
+	if alignment != nil {
+		offset = alignment.OffsetSeconds
+	}
+	response["alignment"] = alignment
+
+	columns, err := getFlightColumns(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	channel := trackingChannels[channelName]
+	aircraft := map[string]AircraftTrackingError{}
+	for label, series := range columns {
+		aircraft[label] = calculateTrackingError(series.Time, channel(series), offset, target, windowSeconds)
+	}
+	response["channel"] = channelName
+	response["window_seconds"] = windowSeconds
+	response["aircraft"] = aircraft
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -1,13 +1,51 @@
 package data_analysis
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Flight represents a flight record from the database
 type Flight struct {
-	ID           int    `json:"id"`
-	SourceID     int    `json:"source_id,omitempty"` // ID from original database for import tracking
-	Title        string `json:"title"`
-	FlightNumber string `json:"flight_number"`
-	StartTime    string `json:"start_time"`
-	EndTime      string `json:"end_time"`
+	ID            int               `json:"id"`
+	SourceID      int               `json:"source_id,omitempty"` // ID from original database for import tracking
+	Title         string            `json:"title"`
+	FlightNumber  string            `json:"flight_number"`
+	StartTime     string            `json:"start_time"`
+	EndTime       string            `json:"end_time"`
+	ParticipantID string            `json:"participant_id,omitempty"` // Study participant who flew this flight, if assigned
+	Condition     string            `json:"condition,omitempty"`      // Study condition the flight was flown under ("baseline" or "failure")
+	Weather       *FlightWeather    `json:"weather,omitempty"`        // Weather at the start of the recording, nil if not recorded
+	Conditions    *FlightConditions `json:"conditions,omitempty"`     // Simulator conditions logged on the session the flight was imported in
+	Quality       *FlightQuality    `json:"quality,omitempty"`        // Data quality assessed at import, nil until assessed
+	ReviewStatus  string            `json:"review_status"`            // "unreviewed", "accepted" or "rejected"
+	ReviewReason  string            `json:"review_reason,omitempty"`  // Why the flight was rejected
+	NoEngineData  bool              `json:"no_engine_data,omitempty"` // No aircraft of the flight has engine samples, e.g. CSV recordings without engine columns
+	ArchivedAt    string            `json:"archived_at,omitempty"`    // When the flight was archived; archived flights are left out of the flight list, exports and statistics
+	PurgeAt       string            `json:"purge_at,omitempty"`       // When an archived flight is purged automatically, empty if it is kept until purged by hand
+}
+
+// FlightConditions is the weather preset and failure configuration the operator logged on a session,
+// attached to the flights imported during that session
+type FlightConditions struct {
+	SessionID     int      `json:"session_id"`
+	LoggedAt      string   `json:"logged_at"`
+	WeatherPreset string   `json:"weather_preset,omitempty"`
+	Failures      []string `json:"failures,omitempty"`
+	Notes         string   `json:"notes,omitempty"`
+}
+
+// FlightWeather holds the weather recorded with a flight; nil fields were not recorded
+type FlightWeather struct {
+	AmbientTemperature  *float64 `json:"ambient_temperature"`   // Celsius
+	TotalAirTemperature *float64 `json:"total_air_temperature"` // Celsius
+	WindSpeed           *float64 `json:"wind_speed"`            // Knots
+	WindDirection       *float64 `json:"wind_direction"`        // Degrees, direction the wind blows from
+	Visibility          *float64 `json:"visibility"`            // Meters
+	SeaLevelPressure    *float64 `json:"sea_level_pressure"`    // Millibars
+	PrecipitationState  *int     `json:"precipitation_state"`   // Simulator precipitation enum (none, rain, snow)
+	InClouds            *bool    `json:"in_clouds"`
 }
 
 // Aircraft represents an aircraft in a flight
@@ -20,16 +58,43 @@ type Aircraft struct {
 	Airline    string `json:"airline"`
 }
 
+// AircraftSummary describes an aircraft of a flight without its sample data
+type AircraftSummary struct {
+	Aircraft
+	Label           string              `json:"label"`
+	Target          bool                `json:"target"` // Flown by the participant; distance and warning markers and statistics default to it
+	SampleCount     int                 `json:"sample_count"`
+	StartTimestamp  int64               `json:"start_timestamp"`
+	EndTimestamp    int64               `json:"end_timestamp"`
+	DurationSeconds float64             `json:"duration_seconds"`
+	Provenance      *PositionProvenance `json:"provenance,omitempty"`
+}
+
+// AircraftUpdate holds the editable aircraft metadata; nil fields are left unchanged
+type AircraftUpdate struct {
+	Type       *string `json:"type"`
+	TailNumber *string `json:"tail_number"`
+	Airline    *string `json:"airline"`
+}
+
 // PositionPoint represents a single position data point
 type PositionPoint struct {
-	Timestamp         int64   `json:"timestamp"`
-	TimestampSeconds  float64 `json:"timestamp_seconds"`
-	Altitude          float64 `json:"altitude"`
-	Latitude          float64 `json:"latitude"`
-	Longitude         float64 `json:"longitude"`
-	IndicatedAltitude float64 `json:"indicated_altitude"`
-	PressureAltitude  float64 `json:"pressure_altitude"`
-	Airspeed          float64 `json:"airspeed"`
+	Timestamp          int64    `json:"timestamp"`
+	TimestampSeconds   float64  `json:"timestamp_seconds"`
+	Altitude           float64  `json:"altitude"`
+	Latitude           float64  `json:"latitude"`
+	Longitude          float64  `json:"longitude"`
+	IndicatedAltitude  float64  `json:"indicated_altitude"`
+	PressureAltitude   float64  `json:"pressure_altitude"`
+	Airspeed           float64  `json:"airspeed"`                      // Indicated airspeed in knots, recorded or estimated (see AirspeedSource)
+	TrueAirspeed       float64  `json:"true_airspeed"`                 // Knots, recorded by CSV imports, else converted from the indicated airspeed or estimated
+	AirspeedSource     string   `json:"airspeed_source"`               // "recorded" or "estimated"
+	WindSpeed          *float64 `json:"wind_speed,omitempty"`          // Ambient wind in knots, recorded per sample by CSV imports
+	WindDirection      *float64 `json:"wind_direction,omitempty"`      // Degrees, direction the wind blows from
+	AmbientTemperature *float64 `json:"ambient_temperature,omitempty"` // Celsius, recorded per sample by CSV imports
+	VerticalSpeed      float64  `json:"vertical_speed"`                // Feet per minute, recorded by CSV imports or derived from altitude
+	GroundSpeed        float64  `json:"ground_speed"`                  // Knots, derived from the position track
+	GroundTrack        float64  `json:"ground_track"`                  // Degrees true, derived from the position track
 }
 
 // EnginePoint represents a single engine data point
@@ -40,6 +105,46 @@ type EnginePoint struct {
 	ThrottlePosition2 float64 `json:"throttle_position2"`
 	ThrottlePosition3 float64 `json:"throttle_position3"`
 	ThrottlePosition4 float64 `json:"throttle_position4"`
+
+	PropellerPosition1 float64 `json:"propeller_position1"`
+	PropellerPosition2 float64 `json:"propeller_position2"`
+	PropellerPosition3 float64 `json:"propeller_position3"`
+	PropellerPosition4 float64 `json:"propeller_position4"`
+
+	MixturePosition1 float64 `json:"mixture_position1"`
+	MixturePosition2 float64 `json:"mixture_position2"`
+	MixturePosition3 float64 `json:"mixture_position3"`
+	MixturePosition4 float64 `json:"mixture_position4"`
+
+	CowlFlapPosition1 float64 `json:"cowl_flap_position1"`
+	CowlFlapPosition2 float64 `json:"cowl_flap_position2"`
+	CowlFlapPosition3 float64 `json:"cowl_flap_position3"`
+	CowlFlapPosition4 float64 `json:"cowl_flap_position4"`
+
+	ElectricalMasterBattery1 bool `json:"electrical_master_battery1"`
+	ElectricalMasterBattery2 bool `json:"electrical_master_battery2"`
+	ElectricalMasterBattery3 bool `json:"electrical_master_battery3"`
+	ElectricalMasterBattery4 bool `json:"electrical_master_battery4"`
+
+	Starter1 bool `json:"starter1"`
+	Starter2 bool `json:"starter2"`
+	Starter3 bool `json:"starter3"`
+	Starter4 bool `json:"starter4"`
+
+	Combustion1 bool `json:"combustion1"`
+	Combustion2 bool `json:"combustion2"`
+	Combustion3 bool `json:"combustion3"`
+	Combustion4 bool `json:"combustion4"`
+}
+
+// AttitudePoint represents a single attitude data point
+type AttitudePoint struct {
+	Timestamp        int64   `json:"timestamp"`
+	TimestampSeconds float64 `json:"timestamp_seconds"`
+	Pitch            float64 `json:"pitch"`        // Degrees
+	Bank             float64 `json:"bank"`         // Degrees
+	TrueHeading      float64 `json:"true_heading"` // Degrees
+	OnGround         bool    `json:"on_ground"`
 }
 
 // FlightData represents all data for a flight
@@ -47,6 +152,19 @@ type FlightData struct {
 	Flight       *Flight                    `json:"flight"`
 	PositionData map[string][]PositionPoint `json:"position_data"`
 	EngineData   map[string][]EnginePoint   `json:"engine_data"`
+	AttitudeData map[string][]AttitudePoint `json:"attitude_data"`
+
+	// Flaps, gear, fuel, g-force and warnings of CSV recordings, for aircraft that have them
+	StateData map[string][]StatePoint `json:"state_data,omitempty"`
+
+	// Position sample counts before downsampling, set only for aircraft reduced by max_points
+	OriginalPointCounts map[string]int `json:"original_point_counts,omitempty"`
+
+	// Marker the times were shifted to, set only when requested with align_marker or align_label
+	Alignment *FlightAlignment `json:"alignment,omitempty"`
+
+	// Units of the position series, set for responses
+	Units *UnitSystem `json:"units,omitempty"`
 }
 
 // Marker represents a user-defined marker on the timeline
@@ -55,7 +173,9 @@ type Marker struct {
 	FlightID  int     `json:"flight_id"`
 	Time      float64 `json:"time"`
 	Label     string  `json:"label"`
-	Type      string  `json:"type"` // "regular", "trim_start", "trim_end"
+	Type      string  `json:"type"`     // "regular", "trim_start", "trim_end", "event", "oscillation_start", "oscillation_end"
+	Category  string  `json:"category"` // "observation", "failure", "phase", "distance"
+	Color     string  `json:"color"`    // #rrggbb, defaults to the color of the category
 	CreatedAt string  `json:"created_at,omitempty"`
 }
 
@@ -80,18 +200,33 @@ type DatabaseInfo struct {
 
 // CSVFlightData represents flight data parsed from a CSV file
 type CSVFlightData struct {
-	Metadata CSVMetadata       `json:"metadata"`
-	Headers  []string          `json:"headers"`
-	Records  []CSVFlightRecord `json:"records"`
+	Metadata      CSVMetadata       `json:"metadata"`
+	Headers       []string          `json:"headers"`
+	Records       []CSVFlightRecord `json:"records"`
+	EngineColumns []string          `json:"engine_columns"` // Engine table columns recorded in the CSV, empty if it has no engine data
+	Warnings      []CSVWarning      `json:"warnings"`       // Skipped rows and times that may have been misread
+
+	warningSeries *csvWarningSeries // Recorded stall and overspeed warnings, nil without warning columns
+}
+
+// CSVWarning reports a row of a CSV recording that was skipped or whose time may have been misread
+type CSVWarning struct {
+	Row     int    `json:"row,omitempty"` // Record number in the file, 0 for warnings about the whole file
+	Message string `json:"message"`
 }
 
 // CSVMetadata contains metadata about the CSV file
 type CSVMetadata struct {
-	Source       string `json:"source"`        // e.g., "FS-FlightControl"
-	RecordedAt   string `json:"recorded_at"`   // Original recording timestamp
-	FlightTitle  string `json:"flight_title"`  // User-provided or derived title
-	AircraftType string `json:"aircraft_type"` // User-provided aircraft type
-	TotalRecords int    `json:"total_records"`
+	Source     string `json:"source"`      // e.g., "FS-FlightControl"
+	RecordedAt string `json:"recorded_at"` // Original recording timestamp
+	// Absolute time of the first and last record, from the records' timestamps or else the recording
+	// timestamp; zero when neither could be parsed
+	StartTime    time.Time `json:"start_time,omitzero"`
+	EndTime      time.Time `json:"end_time,omitzero"`
+	FlightTitle  string    `json:"flight_title"`  // User-provided or derived title
+	AircraftType string    `json:"aircraft_type"` // User-provided aircraft type
+	TotalRecords int       `json:"total_records"`
+	SkippedRows  int       `json:"skipped_rows"` // Data rows left out because of a wrong field count or unreadable time
 }
 
 // CSVFlightRecord represents a single data point from CSV
@@ -137,6 +272,14 @@ type CSVFlightRecord struct {
 	// Warnings and alerts
 	OverspeedWarning bool `csv:"OverspeedWarning (bool)"`
 	StallWarning     bool `csv:"StallWarning (bool)"`
+
+	// Whether the record has a value for the attitude channels; attitude samples need pitch and bank
+	HasPitch       bool `json:"-"`
+	HasBank        bool `json:"-"`
+	HasHeadingTrue bool `json:"-"`
+
+	// Engine values keyed by engine table column, for the engine columns the CSV has
+	Engine map[string]float64 `json:"engine,omitempty"`
 }
 
 // CSVImportOptions defines options for CSV import
@@ -145,3 +288,105 @@ type CSVImportOptions struct {
 	AircraftType string `json:"aircraft_type"`
 	SkipRows     int    `json:"skip_rows"` // Number of header rows to skip
 }
+
+// ImportOptions defines options for importing an uploaded database
+type ImportOptions struct {
+	Partial      bool     `json:"partial"`       // Keep importing when a table fails, reporting the error instead of rolling back
+	SkipTables   []string `json:"skip_tables"`   // Optional per-aircraft tables to leave out, see optionalImportTables
+	PositionOnly bool     `json:"position_only"` // Import only the position table, for trajectory-only analysis
+	Force        bool     `json:"force"`         // Import flights that were imported before, see duplicates.go
+
+	// Position thinning for quick-look imports of long recordings; zero keeps every sample
+	ThinEveryN     int   `json:"thin_every_n"`      // Keep every Nth position sample
+	ThinMinDeltaMs int64 `json:"thin_min_delta_ms"` // Minimum time between kept position samples
+}
+
+// thinsPositions reports whether position samples are decimated on import
+func (o ImportOptions) thinsPositions() bool {
+	return o.ThinEveryN > 1 || o.ThinMinDeltaMs > 0
+}
+
+// optionalImportTables lists the per-aircraft tables that may be skipped on import.
+// The position table is always imported.
+var optionalImportTables = append([]string{"attitude", "engine"}, recordedAircraftTables...)
+
+// importsTable reports whether the given per-aircraft table should be imported
+func (o ImportOptions) importsTable(table string) bool {
+	if table == "position" {
+		return true
+	}
+	if o.PositionOnly {
+		return false
+	}
+	for _, skipped := range o.SkipTables {
+		if skipped == table {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks that all skipped tables are known optional tables and thinning settings are sane
+func (o ImportOptions) validate() error {
+	if o.ThinEveryN < 0 || o.ThinMinDeltaMs < 0 {
+		return fmt.Errorf("thinning settings must not be negative")
+	}
+	for _, skipped := range o.SkipTables {
+		known := false
+		for _, table := range optionalImportTables {
+			if skipped == table {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("table '%s' cannot be skipped (optional tables: %s)", skipped, strings.Join(optionalImportTables, ", "))
+		}
+	}
+	return nil
+}
+
+// ImportError describes one part of an import that failed and was skipped
+type ImportError struct {
+	SourceFlightID   int    `json:"source_flight_id"`
+	SourceAircraftID int    `json:"source_aircraft_id,omitempty"`
+	Table            string `json:"table"` // "aircraft", "position", "attitude", "engine"
+	Error            string `json:"error"`
+}
+
+// DuplicateFlight is a flight of a recording that was not imported because it was imported before
+type DuplicateFlight struct {
+	SourceFlightID   int    `json:"source_flight_id,omitempty"`
+	Title            string `json:"title"`
+	StartTime        string `json:"start_time"`
+	EndTime          string `json:"end_time"`
+	ExistingFlightID int    `json:"existing_flight_id"`
+}
+
+// UploadFileResult is the import outcome of one file of an upload
+type UploadFileResult struct {
+	Filename   string            `json:"filename"`
+	Status     string            `json:"status"` // "success", "partial" when some records failed to import, "skipped" when every flight was imported before, or "failed"
+	Message    string            `json:"message"`
+	Flights    []Flight          `json:"flights"`
+	Errors     []ImportError     `json:"errors"`
+	Warnings   []CSVWarning      `json:"warnings,omitempty"`   // CSV rows that were skipped or whose times may have been misread
+	Duplicates []DuplicateFlight `json:"duplicates,omitempty"` // Flights skipped because they were imported before
+}
+
+// PositionProvenance records how the stored position samples of an aircraft relate to its source recording
+type PositionProvenance struct {
+	AircraftID          int     `json:"aircraft_id"`
+	SourceSampleCount   int     `json:"source_sample_count"`
+	SourceSampleRateHz  float64 `json:"source_sample_rate_hz"`
+	ImportedSampleCount int     `json:"imported_sample_count"`
+	ThinEveryN          int     `json:"thin_every_n"`
+	ThinMinDeltaMs      int64   `json:"thin_min_delta_ms"`
+}
+
+// ImportReport summarizes the result of a database import
+type ImportReport struct {
+	Flights    []Flight          `json:"flights"`
+	Errors     []ImportError     `json:"errors"`
+	Duplicates []DuplicateFlight `json:"duplicates,omitempty"`
+}
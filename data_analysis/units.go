@@ -0,0 +1,201 @@
+package data_analysis
+
+import (
+	"fmt"
+	"log"
+)
+
+// Unit systems of flight data responses, statistics and CSV exports, set by the units setting of the
+// analysis configuration
+const (
+	UnitsImperial = "imperial" // Feet, knots and feet per minute, as stored
+	UnitsMetric   = "metric"   // Meters, kilometers per hour and meters per second
+)
+
+// UnitSystem names the units of altitudes, speeds and vertical speeds and converts them from the feet,
+// knots and feet per minute the database stores
+type UnitSystem struct {
+	System        string `json:"system"`
+	Altitude      string `json:"altitude"`
+	Speed         string `json:"speed"`
+	VerticalSpeed string `json:"vertical_speed"`
+
+	altitudeFactor, speedFactor, verticalSpeedFactor float64
+}
+
+var unitSystems = map[string]UnitSystem{
+	UnitsImperial: {System: UnitsImperial, Altitude: "ft", Speed: "kt", VerticalSpeed: "ft/min",
+		altitudeFactor: 1, speedFactor: 1, verticalSpeedFactor: 1},
+	UnitsMetric: {System: UnitsMetric, Altitude: "m", Speed: "km/h", VerticalSpeed: "m/s",
+		altitudeFactor: 0.3048, speedFactor: 1.852, verticalSpeedFactor: 0.3048 / 60},
+}
+
+// configuredUnits returns the unit system of this deployment
+func configuredUnits() UnitSystem {
+	return unitSystems[analysisConfig.Units]
+}
+
+// ConfiguredUnits returns the unit system of this deployment for modules reporting flight statistics
+func ConfiguredUnits() UnitSystem {
+	return configuredUnits()
+}
+
+// ConvertStatistics returns a copy of the statistics of one aircraft in the configured units, as flight
+// statistics responses give them, with the units recorded
+func ConvertStatistics(stats *FlightStatistics) *FlightStatistics {
+	return configuredUnits().convertStatistics(map[string]*FlightStatistics{"": stats})[""]
+}
+
+// ConvertAltitude converts an altitude, or an altitude difference, from feet to the configured units
+func ConvertAltitude(feet float64) float64 {
+	return feet * configuredUnits().altitudeFactor
+}
+
+// header labels a CSV column with its unit, e.g. "Altitude (ft)"
+func (u UnitSystem) header(name, unit string) string {
+	return fmt.Sprintf("%s (%s)", name, unit)
+}
+
+// convertPositions converts the altitudes and speeds of position samples in place
+func (u UnitSystem) convertPositions(positions []PositionPoint) {
+	if u.System == UnitsImperial {
+		return
+	}
+	for i := range positions {
+		p := &positions[i]
+		p.Altitude *= u.altitudeFactor
+		p.IndicatedAltitude *= u.altitudeFactor
+		p.PressureAltitude *= u.altitudeFactor
+		p.Airspeed *= u.speedFactor
+		p.TrueAirspeed *= u.speedFactor
+		p.GroundSpeed *= u.speedFactor
+		p.VerticalSpeed *= u.verticalSpeedFactor
+		if p.WindSpeed != nil {
+			windSpeed := *p.WindSpeed * u.speedFactor
+			p.WindSpeed = &windSpeed
+		}
+	}
+}
+
+// convertFlightData converts the position series of a flight and records the units in it
+func (u UnitSystem) convertFlightData(flightData *FlightData) {
+	for _, positions := range flightData.PositionData {
+		u.convertPositions(positions)
+	}
+	flightData.Units = &u
+}
+
+// convertColumns returns copies of columnar series with the altitudes and speeds converted; the stored
+// series may be shared, so they are not changed
+func (u UnitSystem) convertColumns(columns map[string]*SeriesColumns) map[string]*SeriesColumns {
+	if u.System == UnitsImperial {
+		return columns
+	}
+	scale := func(values []float64, factor float64) []float64 {
+		scaled := make([]float64, len(values))
+		for i, v := range values {
+			scaled[i] = v * factor
+		}
+		return scaled
+	}
+
+	converted := make(map[string]*SeriesColumns, len(columns))
+	for label, series := range columns {
+		c := *series
+		c.Altitude = scale(series.Altitude, u.altitudeFactor)
+		c.IndicatedAltitude = scale(series.IndicatedAltitude, u.altitudeFactor)
+		c.PressureAltitude = scale(series.PressureAltitude, u.altitudeFactor)
+		c.Airspeed = scale(series.Airspeed, u.speedFactor)
+		c.TrueAirspeed = scale(series.TrueAirspeed, u.speedFactor)
+		c.VerticalSpeed = scale(series.VerticalSpeed, u.verticalSpeedFactor)
+		converted[label] = &c
+	}
+	return converted
+}
+
+// scaleStatistics returns statistics of a series multiplied by factor
+func scaleStatistics(s *DataStatistics, factor float64) *DataStatistics {
+	if s == nil || factor == 1 {
+		return s
+	}
+	return &DataStatistics{
+		Count:    s.Count,
+		Mean:     s.Mean * factor,
+		Variance: s.Variance * factor * factor,
+		StdDev:   s.StdDev * factor,
+		Min:      s.Min * factor,
+		Max:      s.Max * factor,
+		Range:    s.Range * factor,
+		Median:   s.Median * factor,
+		P5:       s.P5 * factor,
+		P25:      s.P25 * factor,
+		P75:      s.P75 * factor,
+		P95:      s.P95 * factor,
+		IQR:      s.IQR * factor,
+	}
+}
+
+// convertStatistics returns copies of flight statistics with the altitude, airspeed and vertical speed
+// statistics and the glidepath deviation converted and the units recorded; angles and control rates are
+// unit independent
+func (u UnitSystem) convertStatistics(statistics map[string]*FlightStatistics) map[string]*FlightStatistics {
+	converted := make(map[string]*FlightStatistics, len(statistics))
+	for label, stats := range statistics {
+		if stats == nil {
+			converted[label] = nil
+			continue
+		}
+		s := *stats
+		s.AirspeedStats = scaleStatistics(stats.AirspeedStats, u.speedFactor)
+		s.TrueAirspeedStats = scaleStatistics(stats.TrueAirspeedStats, u.speedFactor)
+		s.IndicatedAltitudeStats = scaleStatistics(stats.IndicatedAltitudeStats, u.altitudeFactor)
+		s.AltitudeStats = scaleStatistics(stats.AltitudeStats, u.altitudeFactor)
+		s.PressureAltitudeStats = scaleStatistics(stats.PressureAltitudeStats, u.altitudeFactor)
+		s.VerticalSpeedStats = scaleStatistics(stats.VerticalSpeedStats, u.verticalSpeedFactor)
+		if stats.Glidepath != nil {
+			glidepath := *stats.Glidepath
+			glidepath.DeviationStats = scaleStatistics(glidepath.DeviationStats, u.altitudeFactor)
+			s.Glidepath = &glidepath
+		}
+		s.Units = &u
+		converted[label] = &s
+	}
+	return converted
+}
+
+// convertWindCorrected returns copies of wind-corrected statistics with the speeds converted
+func (u UnitSystem) convertWindCorrected(statistics map[string]*WindCorrectedStatistics) map[string]*WindCorrectedStatistics {
+	converted := make(map[string]*WindCorrectedStatistics, len(statistics))
+	for label, stats := range statistics {
+		if stats == nil {
+			converted[label] = nil
+			continue
+		}
+		s := *stats
+		s.AirspeedStats = scaleStatistics(stats.AirspeedStats, u.speedFactor)
+		s.GroundSpeedStats = scaleStatistics(stats.GroundSpeedStats, u.speedFactor)
+		s.EstimatedTASStats = scaleStatistics(stats.EstimatedTASStats, u.speedFactor)
+		s.HeadwindStats = scaleStatistics(stats.HeadwindStats, u.speedFactor)
+		converted[label] = &s
+	}
+	return converted
+}
+
+// ensurePositionAltitudeInFeet converts the altitudes that CSV imports stored in meters to feet, the unit
+// of Sky Dolly imports. CSV imports stored the recorded altitude in feet as indicated altitude and in
+// meters as altitude, so only samples with exactly that ratio are converted and the update runs once.
+func ensurePositionAltitudeInFeet() error {
+	result, err := mainDB.Exec(`
+		UPDATE position
+		SET altitude = indicated_altitude
+		WHERE indicated_altitude != 0 AND altitude != indicated_altitude
+		  AND ABS(altitude - indicated_altitude * 0.3048) < 0.0001
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to convert altitudes to feet: %w", err)
+	}
+	if converted, err := result.RowsAffected(); err == nil && converted > 0 {
+		log.Printf("Converted the altitude of %d CSV position samples from meters to feet", converted)
+	}
+	return nil
+}
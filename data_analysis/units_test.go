@@ -0,0 +1,45 @@
+package data_analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvertStatistics(t *testing.T) {
+	previous := analysisConfig
+	t.Cleanup(func() { analysisConfig = previous })
+
+	stats := &FlightStatistics{
+		AirspeedStats: &DataStatistics{Count: 2, Mean: 100, Variance: 4, StdDev: 2},
+		AltitudeStats: &DataStatistics{Count: 2, Mean: 1000, Variance: 100, StdDev: 10},
+	}
+
+	analysisConfig.Units = UnitsMetric
+	converted := ConvertStatistics(stats)
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"airspeed mean", converted.AirspeedStats.Mean, 185.2},
+		{"airspeed variance", converted.AirspeedStats.Variance, 4 * 1.852 * 1.852},
+		{"altitude mean", converted.AltitudeStats.Mean, 304.8},
+		{"altitude standard deviation", converted.AltitudeStats.StdDev, 3.048},
+		{"altitude", ConvertAltitude(1000), 304.8},
+	}
+	for _, c := range checks {
+		if math.Abs(c.got-c.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if converted.Units == nil || converted.Units.System != UnitsMetric {
+		t.Errorf("units = %+v, want metric", converted.Units)
+	}
+	if stats.AirspeedStats.Mean != 100 || stats.Units != nil {
+		t.Errorf("statistics were changed in place: %+v", stats)
+	}
+
+	analysisConfig.Units = UnitsImperial
+	if converted := ConvertStatistics(stats); converted.AltitudeStats.Mean != 1000 || ConvertAltitude(1000) != 1000 {
+		t.Errorf("imperial statistics were converted: %+v", converted.AltitudeStats)
+	}
+}
@@ -0,0 +1,173 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultVarianceWindowSeconds is the length of the sliding variance window unless requested otherwise
+const defaultVarianceWindowSeconds = 10.0
+
+// varianceSamples tells which samples of each signal count towards its variance; like the flight
+// statistics, airspeeds must be positive and altitudes non-zero, as the columns hold zeros for gaps
+var varianceSamples = map[string]func(float64) bool{
+	"altitude":           func(v float64) bool { return v != 0 },
+	"indicated_altitude": func(v float64) bool { return v != 0 },
+	"airspeed":           func(v float64) bool { return v > 0 },
+}
+
+// varianceUnit returns the unit of the variance of a signal in the configured units and the factor
+// converting it from the stored units, the square of the signal's
+func (u UnitSystem) varianceUnit(signal string) (string, float64) {
+	if signal == "airspeed" {
+		return u.Speed + "^2", u.speedFactor * u.speedFactor
+	}
+	return u.Altitude + "^2", u.altitudeFactor * u.altitudeFactor
+}
+
+// VariancePoint is the variance of a signal over the window centered on a time
+type VariancePoint struct {
+	Time     float64 `json:"time"`
+	Variance float64 `json:"variance"`
+	Samples  int     `json:"samples"` // Valid samples within the window
+}
+
+// AircraftVariance is the sliding-window variance of one aircraft
+type AircraftVariance struct {
+	Points []VariancePoint `json:"points"`
+}
+
+// VarianceReport holds the sliding-window variance of a signal of a flight's aircraft
+type VarianceReport struct {
+	FlightID      int                          `json:"flight_id"`
+	Signal        string                       `json:"signal"`
+	Unit          string                       `json:"unit"` // Of the variance
+	WindowSeconds float64                      `json:"window_seconds"`
+	Aircraft      map[string]*AircraftVariance `json:"aircraft"`
+}
+
+// calculateSlidingVariance computes the variance of a series over windows of windowSeconds centered on
+// each sample, for the windows within the series. Only samples passing valid count; windows with fewer
+// than two of them have no point.
+func calculateSlidingVariance(times, values []float64, windowSeconds float64, valid func(float64) bool) *AircraftVariance {
+	result := &AircraftVariance{Points: []VariancePoint{}}
+
+	var t, v []float64
+	for i, value := range values {
+		if valid(value) {
+			t = append(t, times[i])
+			v = append(v, value)
+		}
+	}
+	if len(t) < 2 || t[len(t)-1]-t[0] < windowSeconds {
+		return result
+	}
+
+	// Values are shifted by the first sample to keep the running sums numerically stable
+	shift := v[0]
+	var sum, sumSquares float64
+	lo, hi := 0, 0 // Samples lo to hi-1 are within the window
+	for _, center := range t {
+		if center-windowSeconds/2 < t[0] {
+			continue
+		}
+		if center+windowSeconds/2 > t[len(t)-1] {
+			break
+		}
+		for hi < len(t) && t[hi] <= center+windowSeconds/2 {
+			d := v[hi] - shift
+			sum += d
+			sumSquares += d * d
+			hi++
+		}
+		for t[lo] < center-windowSeconds/2 {
+			d := v[lo] - shift
+			sum -= d
+			sumSquares -= d * d
+			lo++
+		}
+
+		n := hi - lo
+		if n < 2 {
+			continue
+		}
+		mean := sum / float64(n)
+		result.Points = append(result.Points, VariancePoint{
+			Time:     center,
+			Variance: math.Max(0, sumSquares/float64(n)-mean*mean),
+			Samples:  n,
+		})
+	}
+	return result
+}
+
+// handleGetVariance returns the variance of the airspeed or altitude of the target aircraft over a window
+// sliding along the flight, or of every aircraft with aircraft=all
+func handleGetVariance(w http.ResponseWriter, r *http.Request, flightId int) {
+	query := r.URL.Query()
+
+	signal := query.Get("signal")
+	if signal == "" {
+		signal = "airspeed"
+	}
+	channel, ok := trackingChannels[signal]
+	if !ok {
+		httpError(w, fmt.Sprintf("Invalid signal '%s' (expected %s)", signal, strings.Join(trackingChannelNames(), ", ")), http.StatusBadRequest)
+		return
+	}
+
+	windowSeconds := defaultVarianceWindowSeconds
+	if value := query.Get("window"); value != "" {
+		var err error
+		windowSeconds, err = strconv.ParseFloat(value, 64)
+		if err != nil || !(windowSeconds > 0) || math.IsInf(windowSeconds, 1) {
+			httpError(w, fmt.Sprintf("Invalid window '%s' (expected seconds above 0)", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	columns, err := getFlightColumns(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	unit, factor := configuredUnits().varianceUnit(signal)
+	aircraft := map[string]*AircraftVariance{}
+	for label, series := range columns {
+		variance := calculateSlidingVariance(series.Time, channel(series), windowSeconds, varianceSamples[signal])
+		for i := range variance.Points {
+			variance.Points[i].Variance *= factor
+		}
+		aircraft[label] = variance
+	}
+	if !allAircraftRequested(r) {
+		if aircraft, err = targetAircraftOnly(flightId, aircraft); err != nil {
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(VarianceReport{
+		FlightID:      flightId,
+		Signal:        signal,
+		Unit:          unit,
+		WindowSeconds: windowSeconds,
+		Aircraft:      aircraft,
+	})
+}
+
+// handleGetVarianceQuery serves the variance for the flight given by the flightId query parameter
+func handleGetVarianceQuery(w http.ResponseWriter, r *http.Request) {
+	r.SetPathValue("id", r.URL.Query().Get("flightId"))
+	if _, err := strconv.Atoi(r.PathValue("id")); err != nil {
+		httpError(w, "Missing or invalid flightId parameter", http.StatusBadRequest)
+		return
+	}
+	withFlightID(handleGetVariance)(w, r)
+}
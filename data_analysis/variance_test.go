@@ -0,0 +1,81 @@
+package data_analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCalculateSlidingVariance(t *testing.T) {
+	positive := varianceSamples["airspeed"]
+
+	tests := []struct {
+		name   string
+		times  []float64
+		values []float64
+		window float64
+		want   []VariancePoint
+	}{
+		{
+			name:   "constant rate",
+			times:  []float64{0, 1, 2, 3, 4},
+			values: []float64{1, 3, 1, 3, 1},
+			window: 2,
+			want:   []VariancePoint{{1, 8.0 / 9, 3}, {2, 8.0 / 9, 3}, {3, 8.0 / 9, 3}},
+		},
+		{
+			// Zero-filled gaps do not count, so the window at 2 holds two samples
+			name:   "gaps",
+			times:  []float64{0, 1, 2, 3, 4},
+			values: []float64{2, 4, 0, 0, 6},
+			window: 2,
+			want:   []VariancePoint{{1, 1, 2}},
+		},
+		{
+			// A thinned stretch keeps the window length in seconds, with fewer samples
+			name:   "thinned",
+			times:  []float64{0, 1, 2, 6, 7, 8},
+			values: []float64{1, 3, 1, 5, 7, 5},
+			window: 4,
+			want:   []VariancePoint{{2, 8.0 / 9, 3}, {6, 8.0 / 9, 3}},
+		},
+		{
+			name:   "shorter than the window",
+			times:  []float64{0, 1, 2},
+			values: []float64{1, 2, 3},
+			window: 10,
+			want:   []VariancePoint{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateSlidingVariance(tt.times, tt.values, tt.window, positive).Points
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d points %v, want %v", len(got), got, tt.want)
+			}
+			for i, want := range tt.want {
+				if got[i].Time != want.Time || got[i].Samples != want.Samples || math.Abs(got[i].Variance-want.Variance) > 1e-9 {
+					t.Errorf("point %d = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestVarianceUnit(t *testing.T) {
+	tests := []struct {
+		system, signal string
+		unit           string
+		factor         float64
+	}{
+		{UnitsImperial, "airspeed", "kt^2", 1},
+		{UnitsImperial, "altitude", "ft^2", 1},
+		{UnitsMetric, "airspeed", "km/h^2", 1.852 * 1.852},
+		{UnitsMetric, "indicated_altitude", "m^2", 0.3048 * 0.3048},
+	}
+	for _, tt := range tests {
+		unit, factor := unitSystems[tt.system].varianceUnit(tt.signal)
+		if unit != tt.unit || math.Abs(factor-tt.factor) > 1e-12 {
+			t.Errorf("varianceUnit(%s) in %s = %s, %v, want %s, %v", tt.signal, tt.system, unit, factor, tt.unit, tt.factor)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package data_analysis
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ensurePositionVerticalSpeedColumn adds the vertical speed column filled by CSV imports
+func ensurePositionVerticalSpeedColumn() error {
+	var exists bool
+	err := mainDB.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('position') WHERE name = 'vertical_speed'").Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get position table info: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	log.Println("Adding vertical_speed column to position table...")
+	if _, err := mainDB.Exec("ALTER TABLE position ADD COLUMN vertical_speed REAL"); err != nil {
+		return fmt.Errorf("failed to add vertical_speed column: %w", err)
+	}
+	return nil
+}
+
+// csvHasVerticalSpeedColumn reports whether a CSV file records the vertical speed
+func csvHasVerticalSpeedColumn(headers []string) bool {
+	for _, header := range headers {
+		if strings.Contains(strings.ToLower(header), "verticalspeed") {
+			return true
+		}
+	}
+	return false
+}
+
+// deriveVerticalSpeed fills the vertical speed of samples without a recorded value from the altitude
+// change to their neighbours (central differences, one-sided at the ends)
+func deriveVerticalSpeed(positions []PositionPoint, recorded []bool) {
+	n := len(positions)
+	if n < 2 {
+		return
+	}
+
+	for i := range positions {
+		if recorded[i] {
+			continue
+		}
+		prev, next := max(i-1, 0), min(i+1, n-1)
+		dt := positions[next].TimestampSeconds - positions[prev].TimestampSeconds
+		if dt <= 0 {
+			positions[i].VerticalSpeed = 0
+			continue
+		}
+		climb := (positions[next].Altitude - positions[prev].Altitude) / dt // ft/s
+		positions[i].VerticalSpeed = climb * 60
+	}
+}
@@ -0,0 +1,210 @@
+package data_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Labels of the automatically created warning markers
+const (
+	StallWarningLabel     = "Stall warning"
+	OverspeedWarningLabel = "Overspeed warning"
+)
+
+// warningRearmSeconds is how long a warning must have been off before it counts as activating again,
+// so a flickering stall horn gives one marker
+const warningRearmSeconds = 2.0
+
+// warningOnsets returns the times at which a warning activates, given its state at each time
+func warningOnsets(times []float64, active []bool) []float64 {
+	var onsets []float64
+	on := false
+	lastActive := 0.0
+	for i, t := range times {
+		if active[i] {
+			if !on && (len(onsets) == 0 || t-lastActive >= warningRearmSeconds) {
+				onsets = append(onsets, t)
+			}
+			on = true
+			lastActive = t
+			continue
+		}
+		on = false
+	}
+	return onsets
+}
+
+// csvHasWarningColumns reports whether a CSV recording carries the simulator's stall and overspeed warnings
+func csvHasWarningColumns(headers []string) bool {
+	for _, header := range headers {
+		headerLower := strings.ToLower(header)
+		if strings.Contains(headerLower, "stallwarning") || strings.Contains(headerLower, "overspeedwarning") {
+			return true
+		}
+	}
+	return false
+}
+
+// csvWarningSeries collects the recorded stall and overspeed warnings of a CSV recording while its
+// records are streamed
+type csvWarningSeries struct {
+	times     []float64
+	stall     []bool
+	overspeed []bool
+}
+
+func (s *csvWarningSeries) add(record *CSVFlightRecord) {
+	s.times = append(s.times, record.TimestampSeconds)
+	s.stall = append(s.stall, record.StallWarning)
+	s.overspeed = append(s.overspeed, record.OverspeedWarning)
+}
+
+// onsets finds the activations of the recorded warnings, keyed by label
+func (s *csvWarningSeries) onsets() map[string][]float64 {
+	return map[string][]float64{
+		StallWarningLabel:     warningOnsets(s.times, s.stall),
+		OverspeedWarningLabel: warningOnsets(s.times, s.overspeed),
+	}
+}
+
+// airspeedWarningOnsets finds the activations of stall and overspeed warnings from the airspeed
+// thresholds of the analysis configuration. Stalls are only detected while airborne, which needs
+// the on-ground state of the attitude data.
+func airspeedWarningOnsets(positions []PositionPoint, attitudes []AttitudePoint) map[string][]float64 {
+	n := len(positions)
+	times := make([]float64, n)
+	stall := make([]bool, n)
+	overspeed := make([]bool, n)
+
+	j := 0
+	for i, p := range positions {
+		times[i] = p.TimestampSeconds
+		if analysisConfig.OverspeedKnots > 0 {
+			overspeed[i] = p.Airspeed > analysisConfig.OverspeedKnots
+		}
+		if analysisConfig.StallSpeedKnots <= 0 || len(attitudes) == 0 {
+			continue
+		}
+		// On-ground state of the latest attitude sample at this position sample
+		for j+1 < len(attitudes) && attitudes[j+1].Timestamp <= p.Timestamp {
+			j++
+		}
+		stall[i] = !attitudes[j].OnGround && p.Airspeed < analysisConfig.StallSpeedKnots
+	}
+
+	onsets := map[string][]float64{}
+	if analysisConfig.StallSpeedKnots > 0 && len(attitudes) > 0 {
+		onsets[StallWarningLabel] = warningOnsets(times, stall)
+	}
+	if analysisConfig.OverspeedKnots > 0 {
+		onsets[OverspeedWarningLabel] = warningOnsets(times, overspeed)
+	}
+	return onsets
+}
+
+// replaceWarningMarkers replaces the warning markers of a flight with markers at the given onsets,
+// returning the number of markers created
+func replaceWarningMarkers(flightID int, onsets map[string][]float64) (int, error) {
+	if _, err := mainDB.Exec("DELETE FROM markers WHERE flight_id = ? AND category = ?", flightID, MarkerCategoryWarning); err != nil {
+		return 0, fmt.Errorf("failed to delete warning markers: %w", err)
+	}
+
+	labels := make([]string, 0, len(onsets))
+	for label := range onsets {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	created := 0
+	for _, label := range labels {
+		for _, t := range onsets[label] {
+			marker := Marker{FlightID: flightID, Time: t, Label: label, Category: MarkerCategoryWarning}
+			if _, err := createMarker(marker); err != nil {
+				return created, fmt.Errorf("failed to create warning marker: %w", err)
+			}
+			created++
+		}
+	}
+	return created, nil
+}
+
+// createWarningMarkersForFlight marks the stall and overspeed warnings of a flight detected from the
+// airspeed of its target aircraft, or of each aircraft if allAircraft is set; with several aircraft the
+// labels name the aircraft
+func createWarningMarkersForFlight(flightID int, allAircraft bool) (int, error) {
+	flightData, err := getFlightDataFromMainDB(flightID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get flight data: %w", err)
+	}
+	positionData := flightData.PositionData
+	if !allAircraft {
+		if positionData, err = targetAircraftOnly(flightID, positionData); err != nil {
+			return 0, err
+		}
+	}
+
+	onsets := map[string][]float64{}
+	for aircraftLabel, positions := range positionData {
+		for label, times := range airspeedWarningOnsets(positions, flightData.AttitudeData[aircraftLabel]) {
+			if len(positionData) > 1 {
+				label = fmt.Sprintf("%s (%s)", label, aircraftLabel)
+			}
+			onsets[label] = append(onsets[label], times...)
+		}
+	}
+	return replaceWarningMarkers(flightID, onsets)
+}
+
+// createCSVWarningMarkers marks the warnings of an imported CSV recording, from its warning columns if
+// it has them and otherwise from the airspeed
+func createCSVWarningMarkers(flightID int, csvData *CSVFlightData) {
+	var created int
+	var err error
+	if csvData.warningSeries != nil {
+		created, err = replaceWarningMarkers(flightID, csvData.warningSeries.onsets())
+	} else {
+		created, err = createWarningMarkersForFlight(flightID, false)
+	}
+	if err != nil {
+		log.Printf("Failed to create warning markers for flight %d: %v", flightID, err)
+		return
+	}
+	if created > 0 {
+		log.Printf("Created %d warning markers for flight %d", created, flightID)
+	}
+}
+
+// createImportedWarningMarkers marks the warnings of flights imported from a database
+func createImportedWarningMarkers(flights []Flight) {
+	for _, flight := range flights {
+		created, err := createWarningMarkersForFlight(flight.ID, false)
+		if err != nil {
+			log.Printf("Failed to create warning markers for flight %d: %v", flight.ID, err)
+			continue
+		}
+		if created > 0 {
+			log.Printf("Created %d warning markers for flight %d", created, flight.ID)
+		}
+	}
+}
+
+// handleCreateWarningMarkers detects the stall and overspeed warnings of a flight from its airspeed
+// again, replacing its warning markers; aircraft=all detects them for every aircraft
+func handleCreateWarningMarkers(w http.ResponseWriter, r *http.Request, flightId int) {
+	created, err := createWarningMarkersForFlight(flightId, allAircraftRequested(r))
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to create warning markers: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": fmt.Sprintf("Created %d warning markers", created),
+		"created": created,
+	})
+}
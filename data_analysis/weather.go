@@ -0,0 +1,140 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// flightWeatherColumns selects the weather columns of the flight table aliased as f
+const flightWeatherColumns = `f.ambient_temperature, f.total_air_temperature, f.wind_speed, f.wind_direction,
+		f.visibility, f.sea_level_pressure, f.precipitation_state, f.in_clouds`
+
+// flightWeatherScan receives the flightWeatherColumns of a flight row
+type flightWeatherScan struct {
+	ambientTemperature, totalAirTemperature, windSpeed, windDirection sql.NullFloat64
+	visibility, seaLevelPressure                                      sql.NullFloat64
+	precipitationState, inClouds                                      sql.NullInt64
+}
+
+// dest returns the scan destinations in the order of flightWeatherColumns
+func (s *flightWeatherScan) dest() []interface{} {
+	return []interface{}{
+		&s.ambientTemperature, &s.totalAirTemperature, &s.windSpeed, &s.windDirection,
+		&s.visibility, &s.seaLevelPressure, &s.precipitationState, &s.inClouds,
+	}
+}
+
+// weather returns the scanned weather, or nil if the flight has no weather recorded (e.g. CSV imports)
+func (s *flightWeatherScan) weather() *FlightWeather {
+	w := &FlightWeather{
+		AmbientTemperature:  nullFloat(s.ambientTemperature),
+		TotalAirTemperature: nullFloat(s.totalAirTemperature),
+		WindSpeed:           nullFloat(s.windSpeed),
+		WindDirection:       nullFloat(s.windDirection),
+		Visibility:          nullFloat(s.visibility),
+		SeaLevelPressure:    nullFloat(s.seaLevelPressure),
+	}
+	if s.precipitationState.Valid {
+		state := int(s.precipitationState.Int64)
+		w.PrecipitationState = &state
+	}
+	if s.inClouds.Valid {
+		inClouds := s.inClouds.Int64 != 0
+		w.InClouds = &inClouds
+	}
+
+	if *w == (FlightWeather{}) {
+		return nil
+	}
+	return w
+}
+
+func nullFloat(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+// weatherRecords returns the flight weather as name/value rows for CSV exports, the wind speed in the
+// given units; missing values are left empty
+func weatherRecords(w *FlightWeather, units UnitSystem) [][]string {
+	if w == nil {
+		w = &FlightWeather{}
+	}
+	formatFloat := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+
+	precipitationState, inClouds := "", ""
+	if w.PrecipitationState != nil {
+		precipitationState = strconv.Itoa(*w.PrecipitationState)
+	}
+	if w.InClouds != nil {
+		inClouds = strconv.FormatBool(*w.InClouds)
+	}
+	windSpeedField, windSpeed := "wind_speed_kts", w.WindSpeed
+	if units.System == UnitsMetric {
+		windSpeedField = "wind_speed_kmh"
+		if windSpeed != nil {
+			converted := *windSpeed * units.speedFactor
+			windSpeed = &converted
+		}
+	}
+
+	return [][]string{
+		{"ambient_temperature_c", formatFloat(w.AmbientTemperature)},
+		{"total_air_temperature_c", formatFloat(w.TotalAirTemperature)},
+		{windSpeedField, formatFloat(windSpeed)},
+		{"wind_direction_deg", formatFloat(w.WindDirection)},
+		{"visibility_m", formatFloat(w.Visibility)},
+		{"sea_level_pressure_mb", formatFloat(w.SeaLevelPressure)},
+		{"precipitation_state", precipitationState},
+		{"in_clouds", inClouds},
+	}
+}
+
+// ensurePositionWindColumns adds the per-sample ambient wind columns filled by CSV imports
+func ensurePositionWindColumns() error {
+	for _, column := range []string{"wind_speed", "wind_direction"} {
+		var exists bool
+		err := mainDB.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('position') WHERE name = ?", column).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to get position table info: %w", err)
+		}
+		if exists {
+			continue
+		}
+
+		log.Printf("Adding %s column to position table...", column)
+		if _, err := mainDB.Exec(fmt.Sprintf("ALTER TABLE position ADD COLUMN %s REAL", column)); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// csvHasWindColumns reports whether a CSV file records the ambient wind
+func csvHasWindColumns(headers []string) bool {
+	var velocity, direction bool
+	for _, header := range headers {
+		headerLower := strings.ToLower(header)
+		velocity = velocity || strings.Contains(headerLower, "ambientwindvelocity")
+		direction = direction || strings.Contains(headerLower, "ambientwinddirection")
+	}
+	return velocity && direction
+}
+
+// optionalValue returns the value to store, NULL if the source does not record it
+func optionalValue(recorded bool, value float64) interface{} {
+	if !recorded {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,176 @@
+package data_analysis
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+)
+
+// minGroundVelocityInterval is the minimum time between the two samples a ground velocity is derived
+// from, so high sample rates do not amplify position noise
+const minGroundVelocityInterval = 1.0 // seconds
+
+// Wind sources of the wind correction
+const (
+	WindSourceSamples = "samples" // Per-sample ambient wind recorded by CSV imports
+	WindSourceFlight  = "flight"  // Wind stored with the flight (Sky Dolly recordings)
+	WindSourceNone    = "none"
+)
+
+// WindCorrectedStatistics holds raw and wind-corrected airspeed metrics of one aircraft
+type WindCorrectedStatistics struct {
+	WindSource        string          `json:"wind_source"`
+	AirspeedStats     *DataStatistics `json:"airspeed_stats"`      // Indicated airspeed as recorded, nil if it was estimated
+	GroundSpeedStats  *DataStatistics `json:"ground_speed_stats"`  // Derived from the position track
+	EstimatedTASStats *DataStatistics `json:"estimated_tas_stats"` // Ground velocity minus wind
+	HeadwindStats     *DataStatistics `json:"headwind_stats"`      // Along the ground track, negative for tailwind
+}
+
+// groundVelocity returns the east and north ground velocity in knots between two positions
+func groundVelocity(from, to PositionPoint) (east, north float64) {
+	dt := (to.TimestampSeconds - from.TimestampSeconds) / 3600 // hours
+	meanLat := (from.Latitude + to.Latitude) / 2 * math.Pi / 180
+	east = (to.Longitude - from.Longitude) * 60 * math.Cos(meanLat) / dt
+	north = (to.Latitude - from.Latitude) * 60 / dt
+	return east, north
+}
+
+// windVector returns the east and north components in knots of a wind blowing from the given direction
+func windVector(speed, fromDirection float64) (east, north float64) {
+	rad := fromDirection * math.Pi / 180
+	return -speed * math.Sin(rad), -speed * math.Cos(rad)
+}
+
+// calculateWindCorrectedStatistics derives ground speed from the position track and removes the wind to
+// estimate the true airspeed. Per-sample wind is preferred over the flight's wind.
+func calculateWindCorrectedStatistics(positions []PositionPoint, weather *FlightWeather) *WindCorrectedStatistics {
+	stats := &WindCorrectedStatistics{WindSource: WindSourceNone}
+
+	var airspeeds, groundSpeeds, estimatedTAS, headwinds []float64
+	for _, pos := range positions {
+		if pos.Airspeed > 0 && pos.AirspeedSource == AirspeedRecorded {
+			airspeeds = append(airspeeds, pos.Airspeed)
+		}
+	}
+
+	hasSampleWind := false
+	for _, pos := range positions {
+		if pos.WindSpeed != nil && pos.WindDirection != nil {
+			hasSampleWind = true
+			break
+		}
+	}
+	hasFlightWind := weather != nil && weather.WindSpeed != nil && weather.WindDirection != nil
+	switch {
+	case hasSampleWind:
+		stats.WindSource = WindSourceSamples
+	case hasFlightWind:
+		stats.WindSource = WindSourceFlight
+	}
+
+	var valid []PositionPoint
+	for _, pos := range positions {
+		if pos.Latitude != 0 || pos.Longitude != 0 {
+			valid = append(valid, pos)
+		}
+	}
+
+	from := 0
+	for i := 1; i < len(valid); i++ {
+		for from+1 < i && valid[i].TimestampSeconds-valid[from+1].TimestampSeconds >= minGroundVelocityInterval {
+			from++
+		}
+		if valid[i].TimestampSeconds-valid[from].TimestampSeconds < minGroundVelocityInterval {
+			continue
+		}
+
+		groundEast, groundNorth := groundVelocity(valid[from], valid[i])
+		groundSpeed := math.Hypot(groundEast, groundNorth)
+		groundSpeeds = append(groundSpeeds, groundSpeed)
+
+		var windSpeed, windDirection float64
+		switch stats.WindSource {
+		case WindSourceSamples:
+			if valid[i].WindSpeed == nil || valid[i].WindDirection == nil {
+				continue
+			}
+			windSpeed, windDirection = *valid[i].WindSpeed, *valid[i].WindDirection
+		case WindSourceFlight:
+			windSpeed, windDirection = *weather.WindSpeed, *weather.WindDirection
+		default:
+			continue
+		}
+
+		windEast, windNorth := windVector(windSpeed, windDirection)
+		estimatedTAS = append(estimatedTAS, math.Hypot(groundEast-windEast, groundNorth-windNorth))
+		if groundSpeed > 0 {
+			// Headwind is the wind component against the direction of travel
+			headwinds = append(headwinds, -(windEast*groundEast+windNorth*groundNorth)/groundSpeed)
+		}
+	}
+
+	stats.AirspeedStats = calculateDataStatistics(airspeeds)
+	stats.GroundSpeedStats = calculateDataStatistics(groundSpeeds)
+	stats.EstimatedTASStats = calculateDataStatistics(estimatedTAS)
+	stats.HeadwindStats = calculateDataStatistics(headwinds)
+	return stats
+}
+
+// calculateFlightWindCorrectedStatistics calculates the wind-corrected statistics of every aircraft of a flight
+func calculateFlightWindCorrectedStatistics(flightData *FlightData) map[string]*WindCorrectedStatistics {
+	statistics := make(map[string]*WindCorrectedStatistics, len(flightData.PositionData))
+	for aircraftLabel, positions := range flightData.PositionData {
+		statistics[aircraftLabel] = calculateWindCorrectedStatistics(positions, flightData.Flight.Weather)
+	}
+	return statistics
+}
+
+// getWindCorrectedStatistics returns the stored wind-corrected statistics of a flight, computing and
+// storing them if they are missing or outdated
+func getWindCorrectedStatistics(flightID int) (map[string]*WindCorrectedStatistics, error) {
+	var statistics map[string]*WindCorrectedStatistics
+	err := getStoredMetric(flightID, metricWindCorrected, &statistics)
+	if err == nil {
+		return statistics, nil
+	}
+	if err != sql.ErrNoRows {
+		log.Printf("Failed to read stored wind-corrected statistics for flight %d: %v", flightID, err)
+	}
+
+	flightData, err := getFlightDataFromMainDB(flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight data: %w", err)
+	}
+	statistics = calculateFlightWindCorrectedStatistics(flightData)
+	if err := storeMetric(flightID, metricWindCorrected, statistics); err != nil {
+		log.Printf("Failed to store wind-corrected statistics for flight %d: %v", flightID, err)
+	}
+	return statistics, nil
+}
+
+// handleGetWindCorrectedStatistics returns raw and wind-corrected airspeed metrics per aircraft
+func handleGetWindCorrectedStatistics(w http.ResponseWriter, r *http.Request, flightId int) {
+	flight, err := getFlightByIDFromMainDB(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get flight: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	statistics, err := getWindCorrectedStatistics(flightId)
+	if err != nil {
+		httpError(w, fmt.Sprintf("Failed to get wind-corrected statistics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	units := configuredUnits()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"flight_id":  flightId,
+		"weather":    flight.Weather,
+		"statistics": units.convertWindCorrected(statistics),
+		"units":      units,
+	})
+}
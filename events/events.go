@@ -11,12 +11,15 @@ import (
 )
 
 var (
-	mutex   = &sync.Mutex{}
-	events  []Event
-	logFile *os.File
+	mutex        = &sync.Mutex{}
+	events       []Event
+	logFile      *os.File
+	logListeners []func(Event)
 )
 
 func Init() {
+	initTriggers()
+
 	// Create log file with current timestamp
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
 	logPath := filepath.Join("logs", fmt.Sprintf("events_%s.log", timestamp))
@@ -30,14 +33,39 @@ func Init() {
 
 	// Write initial log entry
 	logFile.WriteString(fmt.Sprintf("=== Event Log Started at %s ===\n", time.Now().Format("2006-01-02 15:04:05")))
+
+	initJournal()
+}
+
+// OnLog registers a function called with every logged event, after it was recorded
+func OnLog(fn func(Event)) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	logListeners = append(logListeners, fn)
 }
 
 func LogEvent(event Event) {
+	recordEvent(event)
 
+	mutex.Lock()
+	listeners := append([]func(Event){}, logListeners...)
+	mutex.Unlock()
+	for _, fn := range listeners {
+		fn(event)
+	}
+}
+
+// recordEvent journals the event, keeps it in memory and appends it to the log file
+func recordEvent(event Event) {
 	mutex.Lock()
 	defer mutex.Unlock()
+	appendJournal(event)
 	events = append(events, event)
+	writeLogLine(event)
+}
 
+// writeLogLine appends an event to the log file; the caller holds the mutex
+func writeLogLine(event Event) {
 	if logFile == nil {
 		return
 	}
@@ -51,7 +79,12 @@ func LogEvent(event Event) {
 	if _, err := logFile.WriteString(logLine); err != nil {
 		log.Printf("Failed to write to log file: %v", err)
 	}
+}
 
+// RecordReplica keeps and logs an event replicated from the primary station this station is a backup
+// of. Listeners are not called, so the backup does not act on replicated events.
+func RecordReplica(event Event) {
+	recordEvent(event)
 }
 
 // GetEvents returns the recent events (last 50)
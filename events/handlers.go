@@ -13,6 +13,7 @@ import (
 func SetupHandlers() {
 	http.HandleFunc("/events", handleEvents)
 	http.HandleFunc("/manual-event", handleManualEvent)
+	http.HandleFunc("/events/triggers", handleTriggers)
 
 	// New HTMX endpoints
 	http.HandleFunc("/events/list", handleEventsList)
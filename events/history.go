@@ -0,0 +1,98 @@
+package events
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// logLinePattern matches the lines recordEvent writes: [timestamp] EVENT_TYPE: program_name
+var logLinePattern = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\] ([A-Z_]+): (.*)$`)
+
+// Between returns the events that occurred from start to end, in time order. Events of this run come
+// from memory; those of earlier runs are read from their log files, which have second precision.
+func Between(start, end time.Time) ([]Event, error) {
+	mutex.Lock()
+	current := ""
+	if logFile != nil {
+		current = logFile.Name()
+	}
+	var result []Event
+	for _, event := range events {
+		if !event.Timestamp.Before(start) && !event.Timestamp.After(end) {
+			result = append(result, event)
+		}
+	}
+	mutex.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join("logs", "events_*.log"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if path == current {
+			continue
+		}
+		logged, err := readLogFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range logged {
+			if !event.Timestamp.Before(start) && !event.Timestamp.After(end) {
+				result = append(result, event)
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	return deduplicate(result), nil
+}
+
+// deduplicate drops repeated events, which events recovered from the journal after a crash are when
+// they had reached the previous run's log file. Log files have second precision, so events are
+// compared by second.
+func deduplicate(sorted []Event) []Event {
+	type key struct {
+		eventType, program string
+		second             int64
+	}
+	seen := map[key]bool{}
+	var unique []Event
+	for _, event := range sorted {
+		k := key{event.Type, event.Program, event.Timestamp.Unix()}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		unique = append(unique, event)
+	}
+	return unique
+}
+
+// readLogFile reads the events of an event log file
+func readLogFile(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var logged []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		match := logLinePattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue // Header line
+		}
+		timestamp, err := time.ParseInLocation("2006-01-02 15:04:05", match[1], time.Local)
+		if err != nil {
+			continue
+		}
+		logged = append(logged, Event{Type: strings.ToLower(match[2]), Program: match[3], Timestamp: timestamp})
+	}
+	return logged, scanner.Err()
+}
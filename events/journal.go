@@ -0,0 +1,160 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// journalCheckpointInterval is the number of events after which the log file is synced and the
+// journal emptied
+const journalCheckpointInterval = 100
+
+var journalPath = filepath.Join("logs", "events.journal")
+
+var (
+	// journal is the write-ahead journal of the events not synced to a log file yet. Each event is
+	// synced to it before it is recorded, so no logged event is lost on a crash or power failure.
+	journal         *os.File
+	journalSeq      int64 // Sequence number of the last journaled event
+	sinceCheckpoint int
+)
+
+// journalEntry is one line of the journal: an event with its sequence number, or a checkpoint
+type journalEntry struct {
+	Seq        int64  `json:"seq"`
+	Checkpoint bool   `json:"checkpoint,omitempty"` // Events up to Seq are synced to the log files
+	Event      *Event `json:"event,omitempty"`
+}
+
+// initJournal opens the journal, replaying the events after its last checkpoint into memory and the
+// log file of this run, as they may not have reached the previous run's log file
+func initJournal() {
+	recovered, err := readJournal()
+	if err != nil {
+		log.Printf("Failed to read event journal: %v", err)
+	}
+
+	journal, err = os.OpenFile(journalPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		log.Printf("Failed to open event journal: %v", err)
+		journal = nil
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, event := range recovered {
+		events = append(events, event)
+		writeLogLine(event)
+	}
+	checkpointJournal()
+	if len(recovered) > 0 {
+		log.Printf("Recovered %d events from the event journal", len(recovered))
+	}
+}
+
+// readJournal returns the events after the last checkpoint of the journal and sets the sequence number
+// to the last one used. A line cut off by a crash ends the journal.
+func readJournal() ([]Event, error) {
+	file, err := os.Open(journalPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var recovered []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			break
+		}
+		switch {
+		case entry.Checkpoint:
+			recovered = nil
+		case entry.Event != nil && entry.Seq > journalSeq:
+			recovered = append(recovered, *entry.Event)
+		}
+		journalSeq = max(journalSeq, entry.Seq)
+	}
+	return recovered, scanner.Err()
+}
+
+// appendJournal writes an event to the journal and syncs it to disk; the caller holds the mutex
+func appendJournal(event Event) {
+	if journal == nil {
+		return
+	}
+
+	journalSeq++
+	line, err := json.Marshal(journalEntry{Seq: journalSeq, Event: &event})
+	if err != nil {
+		log.Printf("Failed to encode event for the journal: %v", err)
+		return
+	}
+	if _, err := journal.Write(append(line, '\n')); err != nil {
+		log.Printf("Failed to write to event journal: %v", err)
+		return
+	}
+	if err := journal.Sync(); err != nil {
+		log.Printf("Failed to sync event journal: %v", err)
+	}
+
+	sinceCheckpoint++
+	if sinceCheckpoint >= journalCheckpointInterval {
+		checkpointJournal()
+	}
+}
+
+// checkpointJournal syncs the log file and empties the journal, keeping the sequence number; the
+// caller holds the mutex
+func checkpointJournal() {
+	if err := logFile.Sync(); err != nil {
+		log.Printf("Failed to sync log file: %v", err)
+		return
+	}
+
+	line, err := json.Marshal(journalEntry{Seq: journalSeq, Checkpoint: true})
+	if err != nil {
+		log.Printf("Failed to encode journal checkpoint: %v", err)
+		return
+	}
+	if err := journal.Truncate(0); err != nil {
+		log.Printf("Failed to empty event journal: %v", err)
+		return
+	}
+	if _, err := journal.WriteAt(append(line, '\n'), 0); err != nil {
+		log.Printf("Failed to write journal checkpoint: %v", err)
+		return
+	}
+	if _, err := journal.Seek(0, io.SeekEnd); err != nil {
+		log.Printf("Failed to seek event journal: %v", err)
+	}
+	if err := journal.Sync(); err != nil {
+		log.Printf("Failed to sync event journal: %v", err)
+	}
+	sinceCheckpoint = 0
+}
+
+// Close checkpoints the journal and closes the log files, so the next start has nothing to recover
+func Close() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if journal != nil {
+		checkpointJournal()
+		journal.Close()
+		journal = nil
+	}
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+}
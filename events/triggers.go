@@ -0,0 +1,178 @@
+package events
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// triggerTimeout bounds connecting to and writing to the trigger receiver
+const triggerTimeout = time.Second
+
+// TriggerConfig configures the trigger messages sent to external recording hardware when selected events are logged
+type TriggerConfig struct {
+	Enabled  bool              `json:"enabled"`
+	Protocol string            `json:"protocol"` // "udp" or "tcp"
+	Address  string            `json:"address"`  // host:port of the receiver
+	Payloads map[string]string `json:"payloads"` // Payload per event type; only these types send a trigger. "0x" payloads are hex bytes.
+}
+
+var (
+	triggerConfig = TriggerConfig{Protocol: "udp", Payloads: map[string]string{}}
+	triggerMutex  = &sync.Mutex{}
+	triggerFile   string
+)
+
+// initTriggers loads the trigger configuration and sends triggers for logged events
+func initTriggers() {
+	triggerFile = filepath.Join("data", "event_triggers.json")
+
+	if data, err := os.ReadFile(triggerFile); err == nil {
+		var config TriggerConfig
+		if err := json.Unmarshal(data, &config); err != nil {
+			log.Printf("Failed to load event trigger configuration: %v", err)
+		} else if err := config.validate(); err != nil {
+			log.Printf("Ignoring invalid event trigger configuration: %v", err)
+		} else {
+			triggerConfig = config
+		}
+	}
+
+	OnLog(sendTrigger)
+}
+
+// validate checks the protocol, address and payloads of a configuration
+func (c *TriggerConfig) validate() error {
+	if c.Protocol != "udp" && c.Protocol != "tcp" {
+		return fmt.Errorf("invalid protocol '%s' (expected udp or tcp)", c.Protocol)
+	}
+	if c.Enabled || c.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			return fmt.Errorf("invalid address '%s': %w", c.Address, err)
+		}
+	}
+	if c.Payloads == nil {
+		c.Payloads = map[string]string{}
+	}
+	for eventType, payload := range c.Payloads {
+		if _, err := decodePayload(payload); err != nil {
+			return fmt.Errorf("invalid payload for '%s': %w", eventType, err)
+		}
+	}
+	return nil
+}
+
+// decodePayload returns the bytes to send: hex-decoded for "0x" payloads, the text itself otherwise
+func decodePayload(payload string) ([]byte, error) {
+	data := []byte(payload)
+	if hexPayload, ok := strings.CutPrefix(payload, "0x"); ok {
+		var err error
+		if data, err = hex.DecodeString(hexPayload); err != nil {
+			return nil, err
+		}
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("payload is empty")
+	}
+	return data, nil
+}
+
+// saveTriggerConfig persists the trigger configuration.
+// Must be called with triggerMutex held.
+func saveTriggerConfig() error {
+	if err := os.MkdirAll(filepath.Dir(triggerFile), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(triggerConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := triggerFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, triggerFile)
+}
+
+// sendTrigger sends the configured payload for an event, if its type has one
+func sendTrigger(event Event) {
+	triggerMutex.Lock()
+	config := triggerConfig
+	payload, selected := triggerConfig.Payloads[event.Type]
+	triggerMutex.Unlock()
+
+	if !config.Enabled || !selected {
+		return
+	}
+	data, err := decodePayload(payload)
+	if err != nil {
+		log.Printf("Failed to decode trigger payload for %s: %v", event.Type, err)
+		return
+	}
+
+	// Sent in the background so a slow receiver never delays event logging
+	go func() {
+		conn, err := net.DialTimeout(config.Protocol, config.Address, triggerTimeout)
+		if err != nil {
+			log.Printf("Failed to connect to trigger receiver %s: %v", config.Address, err)
+			return
+		}
+		defer conn.Close()
+
+		conn.SetWriteDeadline(time.Now().Add(triggerTimeout))
+		if _, err := conn.Write(data); err != nil {
+			log.Printf("Failed to send trigger for %s to %s: %v", event.Type, config.Address, err)
+		}
+	}()
+}
+
+// handleTriggers returns (GET) or replaces (PUT) the trigger configuration
+func handleTriggers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		triggerMutex.Lock()
+		defer triggerMutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(triggerConfig)
+	case http.MethodPut:
+		var config TriggerConfig
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if config.Protocol == "" {
+			config.Protocol = "udp"
+		}
+		if err := config.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		triggerMutex.Lock()
+		defer triggerMutex.Unlock()
+
+		previous := triggerConfig
+		triggerConfig = config
+		if err := saveTriggerConfig(); err != nil {
+			triggerConfig = previous
+			http.Error(w, fmt.Sprintf("Failed to save trigger configuration: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(triggerConfig)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -3,7 +3,7 @@ package events
 import "time"
 
 type Event struct {
-	Type      string    `json:"type"`      // "launch", "kill", "failure_started", "failure_recognised", "back_on_track", "flight_started", "flight_ended", "confused"
+	Type      string    `json:"type"`      // "launch", "kill", "failure_started", "failure_recognised", "back_on_track", "flight_started", "flight_ended", "confused", "completion", "session_started", "session_ended", "scheduled_launch", "scheduled_kill", "external_launch", "simulator_connected", "simulator_disconnected", "conditions_logged", "flight_imported", "flight_import_failed", "replication_target_set", "dataset_frozen", "dataset_unfrozen", "alert_triggered", "alert_cleared", "alert_rules_set"
 	Program   string    `json:"program"`   // program name
 	Timestamp time.Time `json:"timestamp"` // when the event occurred
 }
@@ -0,0 +1,374 @@
+package gps
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+)
+
+// Parameters of the fs2ff stream alert rules can watch
+const (
+	AlertAltitude    = "altitude_ft"     // Altitude MSL in feet
+	AlertGroundSpeed = "ground_speed_kt" // Ground speed in knots; fs2ff sends no airspeed
+	AlertBank        = "bank_deg"        // Bank angle in degrees, left or right
+)
+
+// Alert conditions
+const (
+	AlertBelow = "below"
+	AlertAbove = "above"
+)
+
+// alertHysteresis is how far a value must be back within its threshold before an alert clears, so a
+// value hovering around the threshold does not raise an alert with every packet
+var alertHysteresis = map[string]float64{
+	AlertAltitude:    50,
+	AlertGroundSpeed: 5,
+	AlertBank:        5,
+}
+
+// maxAlertHistory is how many past alerts are kept
+const maxAlertHistory = 50
+
+// AlertRule raises an alert while a parameter of the fs2ff stream is beyond a threshold
+type AlertRule struct {
+	Name      string  `json:"name"`
+	Parameter string  `json:"parameter"` // "altitude_ft", "ground_speed_kt" or "bank_deg"
+	Condition string  `json:"condition"` // "below" or "above"
+	Threshold float64 `json:"threshold"`
+	Enabled   bool    `json:"enabled"`
+}
+
+// AlertConfig holds the alert rules
+type AlertConfig struct {
+	// Rules are only evaluated above this ground speed, so taxiing and parking do not raise alerts
+	MinGroundSpeedKt float64     `json:"min_ground_speed_kt"`
+	Rules            []AlertRule `json:"rules"`
+}
+
+// Alert is a rule that was triggered by the fs2ff stream
+type Alert struct {
+	Rule        string     `json:"rule"`
+	Parameter   string     `json:"parameter"`
+	Condition   string     `json:"condition"`
+	Threshold   float64    `json:"threshold"`
+	Value       float64    `json:"value"` // Value that triggered the alert
+	TriggeredAt time.Time  `json:"triggered_at"`
+	ClearedAt   *time.Time `json:"cleared_at,omitempty"` // Unset while the alert is active
+}
+
+// AlertStatus lists the active alerts and the latest alerts, newest first
+type AlertStatus struct {
+	Active []Alert `json:"active"`
+	Recent []Alert `json:"recent"`
+}
+
+// defaultAlertConfig is used until alert rules are configured
+var defaultAlertConfig = AlertConfig{
+	MinGroundSpeedKt: 40,
+	Rules: []AlertRule{
+		{Name: "Low altitude", Parameter: AlertAltitude, Condition: AlertBelow, Threshold: 500, Enabled: true},
+		{Name: "Low speed", Parameter: AlertGroundSpeed, Condition: AlertBelow, Threshold: 60, Enabled: true},
+		{Name: "Steep bank", Parameter: AlertBank, Condition: AlertAbove, Threshold: 45, Enabled: true},
+	},
+}
+
+// alertState holds the latest values of the stream and the alerts they raised
+type alertState struct {
+	values       map[string]float64 // Latest value per parameter
+	active       map[string]*Alert  // Active alert per rule name
+	history      []*Alert           // Alerts in the order they were triggered
+	lastPosition time.Time          // Arrival of the latest position, which the ground speed belongs to
+}
+
+var (
+	alertConfig = defaultAlertConfig
+	alertFile   string
+	alerts      = alertState{values: map[string]float64{}, active: map[string]*Alert{}}
+	alertMutex  = &sync.Mutex{}
+)
+
+// initAlerts loads the alert rules
+func initAlerts() {
+	alertFile = filepath.Join("data", "gps_alerts.json")
+
+	data, err := os.ReadFile(alertFile)
+	if err != nil {
+		return
+	}
+	var config AlertConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		log.Printf("Failed to load alert rules: %v", err)
+	} else if err := config.validate(); err != nil {
+		log.Printf("Ignoring invalid alert rules: %v", err)
+	} else {
+		alertConfig = config
+	}
+}
+
+// validate checks the parameter, condition and threshold of every rule; rule names must be unique
+func (c *AlertConfig) validate() error {
+	if c.MinGroundSpeedKt < 0 {
+		return fmt.Errorf("min_ground_speed_kt must not be negative")
+	}
+	if c.Rules == nil {
+		c.Rules = []AlertRule{}
+	}
+	names := map[string]bool{}
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		rule.Name = strings.TrimSpace(rule.Name)
+		if rule.Name == "" {
+			return fmt.Errorf("name of rule %d is required", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate rule name '%s'", rule.Name)
+		}
+		names[rule.Name] = true
+		if _, ok := alertHysteresis[rule.Parameter]; !ok {
+			return fmt.Errorf("invalid parameter '%s' of rule '%s' (expected %s, %s or %s)", rule.Parameter, rule.Name, AlertAltitude, AlertGroundSpeed, AlertBank)
+		}
+		if rule.Condition != AlertBelow && rule.Condition != AlertAbove {
+			return fmt.Errorf("invalid condition '%s' of rule '%s' (expected %s or %s)", rule.Condition, rule.Name, AlertBelow, AlertAbove)
+		}
+		if math.IsNaN(rule.Threshold) || math.IsInf(rule.Threshold, 0) {
+			return fmt.Errorf("invalid threshold of rule '%s'", rule.Name)
+		}
+	}
+	return nil
+}
+
+// saveAlertConfig persists the alert rules.
+// Must be called with alertMutex held.
+func saveAlertConfig() error {
+	if alertFile == "" {
+		return nil // Not initialized
+	}
+	if err := os.MkdirAll(filepath.Dir(alertFile), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(alertConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := alertFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, alertFile)
+}
+
+// beyond reports whether a value is beyond the rule's threshold; with clearing set, the value must be
+// back within the threshold by the hysteresis of the parameter to count as within
+func (r AlertRule) beyond(value float64, clearing bool) bool {
+	threshold := r.Threshold
+	if clearing {
+		if r.Condition == AlertBelow {
+			threshold += alertHysteresis[r.Parameter]
+		} else {
+			threshold -= alertHysteresis[r.Parameter]
+		}
+	}
+	if r.Condition == AlertBelow {
+		return value < threshold
+	}
+	return value > threshold
+}
+
+// updateAlerts takes the values of a packet and raises or clears the alerts of the rules watching them
+func updateAlerts(values map[string]float64, at time.Time) {
+	var changed []events.Event
+
+	alertMutex.Lock()
+	for parameter, value := range values {
+		alerts.values[parameter] = value
+	}
+	if _, ok := values[AlertGroundSpeed]; ok {
+		alerts.lastPosition = at
+	}
+
+	// Without a recent position the ground speed is unknown; the aircraft may have landed long ago
+	speed, hasSpeed := alerts.values[AlertGroundSpeed]
+	flying := hasSpeed && at.Sub(alerts.lastPosition) <= streamTimeout && speed >= alertConfig.MinGroundSpeedKt
+
+	for _, rule := range alertConfig.Rules {
+		value, ok := alerts.values[rule.Parameter]
+		active := alerts.active[rule.Name]
+		switch {
+		case active == nil && rule.Enabled && flying && ok && rule.beyond(value, false):
+			alert := &Alert{
+				Rule:        rule.Name,
+				Parameter:   rule.Parameter,
+				Condition:   rule.Condition,
+				Threshold:   rule.Threshold,
+				Value:       value,
+				TriggeredAt: at,
+			}
+			alerts.active[rule.Name] = alert
+			alerts.history = append(alerts.history, alert)
+			if len(alerts.history) > maxAlertHistory {
+				alerts.history = alerts.history[len(alerts.history)-maxAlertHistory:]
+			}
+			changed = append(changed, events.Event{Type: "alert_triggered", Program: alertLabel(rule.Name, rule.Parameter, value), Timestamp: at})
+		case active != nil && (!rule.Enabled || !flying || (ok && !rule.beyond(value, true))):
+			clearedAt := at
+			active.ClearedAt = &clearedAt
+			delete(alerts.active, rule.Name)
+			changed = append(changed, events.Event{Type: "alert_cleared", Program: alertLabel(rule.Name, rule.Parameter, value), Timestamp: at})
+		}
+	}
+	alertMutex.Unlock()
+
+	for _, event := range changed {
+		log.Printf("Alert changed: %s %s", event.Type, event.Program)
+		events.LogEvent(event)
+	}
+}
+
+// alertLabel names the rule and the value that triggered or cleared its alert, e.g. "Low altitude (412 ft)"
+func alertLabel(rule, parameter string, value float64) string {
+	return fmt.Sprintf("%s (%s)", rule, formatAlertValue(parameter, value))
+}
+
+// formatAlertValue formats a value of a parameter with its unit
+func formatAlertValue(parameter string, value float64) string {
+	switch parameter {
+	case AlertAltitude:
+		return fmt.Sprintf("%.0f ft", value)
+	case AlertGroundSpeed:
+		return fmt.Sprintf("%.0f kt", value)
+	case AlertBank:
+		return fmt.Sprintf("%.0f°", value)
+	}
+	return fmt.Sprint(value)
+}
+
+// summary describes when and by which value the alert was triggered and when it cleared
+func (a Alert) summary() string {
+	text := fmt.Sprintf("%s %s", a.TriggeredAt.Format("15:04:05"), alertLabel(a.Rule, a.Parameter, a.Value))
+	if a.ClearedAt != nil {
+		text += ", cleared " + a.ClearedAt.Format("15:04:05")
+	}
+	return text
+}
+
+// GetAlertConfig returns the alert rules
+func GetAlertConfig() AlertConfig {
+	alertMutex.Lock()
+	defer alertMutex.Unlock()
+	config := alertConfig
+	config.Rules = append([]AlertRule{}, alertConfig.Rules...)
+	return config
+}
+
+// SetAlertConfig replaces the alert rules and persists them. Active alerts of removed rules are cleared
+// with the next packet.
+func SetAlertConfig(config AlertConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	alertMutex.Lock()
+	alertConfig = config
+	err := saveAlertConfig()
+	for name, alert := range alerts.active {
+		if !config.hasRule(name) {
+			clearedAt := time.Now()
+			alert.ClearedAt = &clearedAt
+			delete(alerts.active, name)
+		}
+	}
+	alertMutex.Unlock()
+	if err != nil {
+		log.Printf("Failed to save alert rules: %v", err)
+	}
+
+	events.LogEvent(events.Event{
+		Type:      "alert_rules_set",
+		Program:   "GPS",
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// hasRule reports whether the configuration has a rule with the name
+func (c AlertConfig) hasRule(name string) bool {
+	for _, rule := range c.Rules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAlertStatus returns the active and the latest alerts
+func GetAlertStatus() AlertStatus {
+	alertMutex.Lock()
+	defer alertMutex.Unlock()
+
+	status := AlertStatus{Active: []Alert{}, Recent: []Alert{}}
+	for i := len(alerts.history) - 1; i >= 0; i-- {
+		alert := *alerts.history[i]
+		if alert.ClearedAt == nil {
+			status.Active = append(status.Active, alert)
+		}
+		status.Recent = append(status.Recent, alert)
+	}
+	return status
+}
+
+// handleAlerts renders the active and latest alerts, or returns them as JSON when requested
+func handleAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := GetAlertStatus()
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	if err := AlertsView(status).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleAlertRules returns (GET) or replaces (PUT) the alert rules
+func handleAlertRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetAlertConfig())
+	case http.MethodPut:
+		var config AlertConfig
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := SetAlertConfig(config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetAlertConfig())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -2,7 +2,9 @@ package gps
 
 import (
 	"bytes"
+	"errors"
 	"log"
+	"math"
 	"net"
 	"sync"
 	"time"
@@ -21,15 +23,23 @@ var (
 	isSendingToTarget = false
 	sendingMutex      = &sync.Mutex{}
 
-	// Currock Hill coordinates
-	currockHillLat = 54.9275
-	currockHillLon = -1.8342
-	maxDistanceNM  = 9.0
-	maxDistanceMux = &sync.Mutex{}
+	// Gate center and radius, taken from the reference point configuration
+	gateReference    = defaultReference.ReferencePoint
+	gateReferenceMux = &sync.Mutex{}
+	maxDistanceNM    = defaultReference.RadiusNM
+	maxDistanceMux   = &sync.Mutex{}
+
+	positionListeners    []func(Position)
+	positionListenersMux = &sync.Mutex{}
 )
 
 func Init() {
+	initReference()
+	initRoutes()
+	initRecording()
+	initAlerts()
 	go startUDPListener()
+	go monitorSimulator()
 }
 
 func startUDPListener() {
@@ -78,10 +88,11 @@ func startUDPListener() {
 
 			// Convert to our GPSPosition type and update
 			position := Position{
-				Latitude:  float64(gpsData.Latitude),
-				Longitude: float64(gpsData.Longitude),
-				Altitude:  float64(gpsData.AltitudeMSL * 0.3048), // Convert feet to meters
-				Timestamp: time.Now(),
+				Latitude:    float64(gpsData.Latitude),
+				Longitude:   float64(gpsData.Longitude),
+				Altitude:    float64(gpsData.AltitudeMSL * 0.3048), // Convert feet to meters
+				GroundSpeed: float64(gpsData.GroundSpeed) * metersPerSecondToKnots,
+				Timestamp:   time.Now(),
 			}
 
 			// Update current GPS position
@@ -89,13 +100,21 @@ func startUDPListener() {
 			currentGPS = &position
 			gpsMutex.Unlock()
 
-			// Calculate distance to Currock Hill
-			distance := calculateDistanceNM(
-				position.Latitude,
-				position.Longitude,
-				currockHillLat,
-				currockHillLon,
-			)
+			detectSimPause(position, float64(gpsData.GroundSpeed))
+			updateAlerts(map[string]float64{
+				AlertAltitude:    position.Altitude * metersToFeet,
+				AlertGroundSpeed: position.GroundSpeed,
+			}, position.Timestamp)
+
+			positionListenersMux.Lock()
+			listeners := append([]func(Position){}, positionListeners...)
+			positionListenersMux.Unlock()
+			for _, fn := range listeners {
+				fn(position)
+			}
+
+			// Calculate distance to the gate center or the active route
+			distance, gatedOn := gateDistance(position.Latitude, position.Longitude)
 
 			// Check if we should send based on distance
 			shouldSend := distance <= maxDistanceNM
@@ -149,17 +168,33 @@ func startUDPListener() {
 			wsClientsMux.Unlock()
 
 			// Log the position update
-			log.Printf("Position: Lat=%.6f, Lon=%.6f, Alt=%.1fm, Hdg=%.1f°, GS=%.1fkts, Distance to Currock Hill=%.1fnm",
+			log.Printf("Position: Lat=%.6f, Lon=%.6f, Alt=%.1fm, Hdg=%.1f°, GS=%.1fkts, Distance to %s=%.1fnm",
 				position.Latitude,
 				position.Longitude,
 				position.Altitude,
 				gpsData.TrueHeading,
 				gpsData.GroundSpeed,
+				gatedOn,
 				distance)
+		} else if bytes.Equal(buffer[0:4], []byte("XATT")) {
+			// Attitude packets only feed the bank alerts
+			attitude, err := parseXATTPacket(buffer[5:n])
+			if err != nil {
+				log.Printf("Error parsing attitude data: %v", err)
+				continue
+			}
+			updateAlerts(map[string]float64{AlertBank: math.Abs(float64(attitude.Roll))}, time.Now())
 		}
 	}
 }
 
+// OnPosition registers a function called with every position received from fs2ff
+func OnPosition(fn func(Position)) {
+	positionListenersMux.Lock()
+	defer positionListenersMux.Unlock()
+	positionListeners = append(positionListeners, fn)
+}
+
 // GetCurrentPosition returns the current GPS position
 func GetCurrentPosition() *Position {
 	gpsMutex.Lock()
@@ -174,6 +209,30 @@ func GetTargetIP() string {
 	return targetIP
 }
 
+// GetConfig returns the forwarding configuration
+func GetConfig() Config {
+	config := Config{
+		TargetIP:          GetTargetIP(),
+		DistanceThreshold: GetDistanceThreshold(),
+		IsSending:         IsSendingToTarget(),
+		Reference:         GetGateReference(),
+		GateMode:          GateModeRadius,
+	}
+	if scenario, waypoints, ok := GetActiveRoute(); ok {
+		config.GateMode = GateModeRoute
+		config.Scenario = scenario
+		config.Route = waypoints
+	}
+	return config
+}
+
+// GetGateReference returns the center of the distance gate
+func GetGateReference() ReferencePoint {
+	gateReferenceMux.Lock()
+	defer gateReferenceMux.Unlock()
+	return gateReference
+}
+
 // GetDistanceThreshold returns the current distance threshold
 func GetDistanceThreshold() float64 {
 	maxDistanceMux.Lock()
@@ -187,3 +246,97 @@ func IsSendingToTarget() bool {
 	defer sendingMutex.Unlock()
 	return isSendingToTarget
 }
+
+var (
+	// ErrInvalidReference is returned when setting a gate center with invalid coordinates
+	ErrInvalidReference = errors.New("invalid reference point coordinates")
+	// ErrInvalidTargetIP is returned when setting a target IP that does not parse
+	ErrInvalidTargetIP = errors.New("invalid IP address")
+	// ErrInvalidDistanceThreshold is returned when setting a distance threshold that is not positive
+	ErrInvalidDistanceThreshold = errors.New("distance threshold must be positive")
+)
+
+// SetTargetIP changes the IP GPS data is forwarded to
+func SetTargetIP(ip string) error {
+	if net.ParseIP(ip) == nil {
+		return ErrInvalidTargetIP
+	}
+
+	targetIPMutex.Lock()
+	targetIP = ip
+	targetIPMutex.Unlock()
+
+	events.LogEvent(events.Event{
+		Type:      "target_ip_set",
+		Program:   "GPS",
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// SetGateReference moves the center of the distance gate, keeping it in the reference point configuration
+func SetGateReference(reference ReferencePoint) error {
+	if reference.Latitude < -90 || reference.Latitude > 90 || reference.Longitude < -180 || reference.Longitude > 180 {
+		return ErrInvalidReference
+	}
+
+	gateReferenceMux.Lock()
+	gateReference = reference
+	gateReferenceMux.Unlock()
+	referenceChanged()
+
+	events.LogEvent(events.Event{
+		Type:      "gate_reference_set",
+		Program:   "GPS",
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// SetDistanceThreshold changes the distance in nautical miles within which GPS data is forwarded, keeping
+// it as the radius of the reference point configuration
+func SetDistanceThreshold(threshold float64) error {
+	if !(threshold > 0) {
+		return ErrInvalidDistanceThreshold
+	}
+
+	maxDistanceMux.Lock()
+	maxDistanceNM = threshold
+	maxDistanceMux.Unlock()
+	referenceChanged()
+
+	events.LogEvent(events.Event{
+		Type:      "distance_threshold_updated",
+		Program:   "GPS",
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// SetSending enables or disables forwarding GPS data to the target
+func SetSending(sending bool) {
+	sendingMutex.Lock()
+	isSendingToTarget = sending
+	sendingMutex.Unlock()
+
+	events.LogEvent(events.Event{
+		Type:      "sending_toggled",
+		Program:   "GPS",
+		Timestamp: time.Now(),
+	})
+}
+
+// ToggleSending flips forwarding GPS data to the target and returns the new state
+func ToggleSending() bool {
+	sendingMutex.Lock()
+	isSendingToTarget = !isSendingToTarget
+	sending := isSendingToTarget
+	sendingMutex.Unlock()
+
+	events.LogEvent(events.Event{
+		Type:      "sending_toggled",
+		Program:   "GPS",
+		Timestamp: time.Now(),
+	})
+	return sending
+}
@@ -1,14 +1,11 @@
 package gps
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
-	"net"
 	"net/http"
 	"strconv"
-	"time"
-
-	"github.com/kaireichart/master-thesis-operator-station/events"
 )
 
 //go:generate go tool templ generate
@@ -21,6 +18,32 @@ func SetupHandlers() {
 	http.HandleFunc("/gps/set-target-ip", handleSetTargetIPHTMX)
 	http.HandleFunc("/gps/set-distance-threshold", handleSetDistanceThresholdHTMX)
 	http.HandleFunc("/gps/broadcast-toggle", handleBroadcastToggleHTMX)
+	http.HandleFunc("/gps/simulator-status", handleSimulatorStatus)
+	http.HandleFunc("/gps/routes", handleRoutes)
+	http.HandleFunc("/gps/reference", handleReference)
+	http.HandleFunc("/gps/recording.csv", handleRecordingCSV)
+	http.HandleFunc("/gps/live-statistics", handleLiveStatistics)
+	http.HandleFunc("/gps/live-statistics/stream", handleLiveStatisticsStream)
+	http.HandleFunc("/gps/alerts", handleAlerts)
+	http.HandleFunc("/gps/alerts/rules", handleAlertRules)
+}
+
+// handleSimulatorStatus renders the simulator connection status, or returns it as JSON when requested
+func handleSimulatorStatus(w http.ResponseWriter, r *http.Request) {
+	status := GetSimulatorStatus()
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	err := SimulatorStatusView(status).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
 
 // HTMX Handlers
@@ -37,18 +60,10 @@ func handleGPSPosition(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleGPSConfig(w http.ResponseWriter, r *http.Request) {
-	ip := GetTargetIP()
-	threshold := GetDistanceThreshold()
-	sending := IsSendingToTarget()
-
-	config := &Config{
-		TargetIP:          ip,
-		DistanceThreshold: threshold,
-		IsSending:         sending,
-	}
+	config := GetConfig()
 
 	w.Header().Set("Content-Type", "text/html")
-	err := GPSConfig(config).Render(r.Context(), w)
+	err := GPSConfig(&config).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -67,24 +82,11 @@ func handleSetTargetIPHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate IP address
-	if net.ParseIP(ip) == nil {
+	if err := SetTargetIP(ip); err != nil {
 		http.Error(w, "Invalid IP address", http.StatusBadRequest)
 		return
 	}
 
-	targetIPMutex.Lock()
-	targetIP = ip
-	targetIPMutex.Unlock()
-
-	// Create and record the event
-	event := events.Event{
-		Type:      "target_ip_set",
-		Program:   "GPS",
-		Timestamp: time.Now(),
-	}
-	events.LogEvent(event)
-
 	// Return updated config
 	handleGPSConfig(w, r)
 }
@@ -102,22 +104,14 @@ func handleSetDistanceThresholdHTMX(w http.ResponseWriter, r *http.Request) {
 	}
 
 	threshold, err := strconv.ParseFloat(thresholdStr, 64)
-	if err != nil || threshold <= 0 {
+	if err != nil {
 		http.Error(w, "Invalid distance threshold", http.StatusBadRequest)
 		return
 	}
-
-	maxDistanceMux.Lock()
-	maxDistanceNM = threshold
-	maxDistanceMux.Unlock()
-
-	// Create and record the event
-	event := events.Event{
-		Type:      "distance_threshold_updated",
-		Program:   "GPS",
-		Timestamp: time.Now(),
+	if err := SetDistanceThreshold(threshold); err != nil {
+		http.Error(w, "Invalid distance threshold", http.StatusBadRequest)
+		return
 	}
-	events.LogEvent(event)
 
 	// Return updated config
 	handleGPSConfig(w, r)
@@ -129,18 +123,7 @@ func handleBroadcastToggleHTMX(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sendingMutex.Lock()
-	isSendingToTarget = !isSendingToTarget
-	newState := isSendingToTarget
-	sendingMutex.Unlock()
-
-	// Create and record the event
-	event := events.Event{
-		Type:      "sending_toggled",
-		Program:   "GPS",
-		Timestamp: time.Now(),
-	}
-	events.LogEvent(event)
+	newState := ToggleSending()
 
 	w.Header().Set("Content-Type", "text/html")
 	err := BroadcastToggle(newState).Render(r.Context(), w)
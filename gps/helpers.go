@@ -47,6 +47,42 @@ func parseXGPSPacket(data []byte) (GPSData, error) {
 	return gps, nil
 }
 
+// parseXATTPacket parses the attitude data of an XATT packet: simulator name, true heading, pitch and roll
+func parseXATTPacket(data []byte) (AttitudeData, error) {
+	var attitude AttitudeData
+
+	parts := strings.Split(string(data), ",")
+	if len(parts) < 4 {
+		return attitude, fmt.Errorf("invalid data format: expected at least 4 parts, got %d", len(parts))
+	}
+
+	hdg, err := strconv.ParseFloat(parts[1], 32)
+	if err != nil {
+		return attitude, fmt.Errorf("error parsing heading: %v", err)
+	}
+	pitch, err := strconv.ParseFloat(parts[2], 32)
+	if err != nil {
+		return attitude, fmt.Errorf("error parsing pitch: %v", err)
+	}
+	roll, err := strconv.ParseFloat(parts[3], 32)
+	if err != nil {
+		return attitude, fmt.Errorf("error parsing roll: %v", err)
+	}
+
+	attitude.TrueHeading = float32(hdg)
+	attitude.Pitch = float32(pitch)
+	attitude.Roll = float32(roll)
+	return attitude, nil
+}
+
+// onOff formats a probe result for display
+func onOff(ok bool) string {
+	if ok {
+		return "OK"
+	}
+	return "—"
+}
+
 // calculateDistanceNM calculates the distance between two points in nautical miles
 func calculateDistanceNM(lat1, lon1, lat2, lon2 float64) float64 {
 	const R = 3440.065 // Earth's radius in nautical miles
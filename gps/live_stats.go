@@ -0,0 +1,208 @@
+package gps
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+)
+
+const (
+	// liveStatsWindow is the span of the latest recorded positions the live window statistics cover
+	liveStatsWindow = 60 * time.Second
+	// liveStatsStreamInterval is how often the live statistics stream checks for new positions
+	liveStatsStreamInterval = time.Second
+
+	metersPerSecondToKnots = 1.943844
+	metersToFeet           = 1 / 0.3048
+)
+
+// ChannelStats are the mean and standard deviation of one channel
+type ChannelStats struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+}
+
+// SpanStats describe the positions recorded within a span. fs2ff sends no airspeed, so the ground
+// speed stands in for it.
+type SpanStats struct {
+	Samples     int          `json:"samples"`
+	Altitude    ChannelStats `json:"altitude_ft"`
+	GroundSpeed ChannelStats `json:"ground_speed_kt"`
+}
+
+// LiveStatistics are updated with every position recorded during a session, so the operator sees the
+// performance of the last minute next to that of the whole recording
+type LiveStatistics struct {
+	SessionID     int       `json:"session_id"`
+	ParticipantID string    `json:"participant_id"`
+	Active        bool      `json:"active"` // The session is still recording
+	WindowSeconds float64   `json:"window_seconds"`
+	Window        SpanStats `json:"window"`     // Positions of the last WindowSeconds
+	Recording     SpanStats `json:"recording"`  // All positions of the recording
+	UpdatedAt     time.Time `json:"updated_at"` // Time of the latest position
+}
+
+// runningSums accumulate a channel for its mean and standard deviation; samples can be removed again
+// when they leave a window
+type runningSums struct {
+	n          int
+	sum, sumSq float64
+}
+
+func (s *runningSums) add(v float64) {
+	s.n++
+	s.sum += v
+	s.sumSq += v * v
+}
+
+func (s *runningSums) remove(v float64) {
+	s.n--
+	s.sum -= v
+	s.sumSq -= v * v
+}
+
+// stats returns the mean and population standard deviation of the accumulated samples
+func (s runningSums) stats() ChannelStats {
+	if s.n == 0 {
+		return ChannelStats{}
+	}
+	n := float64(s.n)
+	mean := s.sum / n
+	// Rounding of the sums can make a constant channel's variance slightly negative
+	variance := math.Max(s.sumSq/n-mean*mean, 0)
+	return ChannelStats{Mean: mean, StdDev: math.Sqrt(variance)}
+}
+
+// spanSums accumulate the channels of the positions within a span
+type spanSums struct {
+	altitude, groundSpeed runningSums
+}
+
+func (s *spanSums) add(p Position) {
+	s.altitude.add(p.Altitude * metersToFeet)
+	s.groundSpeed.add(p.GroundSpeed)
+}
+
+func (s *spanSums) remove(p Position) {
+	s.altitude.remove(p.Altitude * metersToFeet)
+	s.groundSpeed.remove(p.GroundSpeed)
+}
+
+func (s spanSums) stats() SpanStats {
+	return SpanStats{Samples: s.altitude.n, Altitude: s.altitude.stats(), GroundSpeed: s.groundSpeed.stats()}
+}
+
+// liveStats are updated incrementally as positions are appended to a recording
+type liveStats struct {
+	window      spanSums
+	windowStart int // Index of the first recorded position within the window
+	recording   spanSums
+}
+
+// add takes the last of the recorded positions into the statistics and drops the positions that left
+// the window
+func (s *liveStats) add(positions []Position) {
+	latest := positions[len(positions)-1]
+	s.window.add(latest)
+	s.recording.add(latest)
+	for positions[s.windowStart].Timestamp.Before(latest.Timestamp.Add(-liveStatsWindow)) {
+		s.window.remove(positions[s.windowStart])
+		s.windowStart++
+	}
+}
+
+// GetLiveStatistics returns the statistics of the current recording, false if none was started
+func GetLiveStatistics() (LiveStatistics, bool) {
+	recordingMutex.Lock()
+	defer recordingMutex.Unlock()
+	if currentRecording == nil {
+		return LiveStatistics{}, false
+	}
+
+	stats := LiveStatistics{
+		SessionID:     currentRecording.SessionID,
+		ParticipantID: currentRecording.ParticipantID,
+		Active:        currentRecording.Active,
+		WindowSeconds: liveStatsWindow.Seconds(),
+		Window:        currentRecording.stats.window.stats(),
+		Recording:     currentRecording.stats.recording.stats(),
+	}
+	if n := len(currentRecording.Positions); n > 0 {
+		stats.UpdatedAt = currentRecording.Positions[n-1].Timestamp
+	}
+	return stats, true
+}
+
+// handleLiveStatistics renders the live statistics of the session being recorded, or returns them as JSON
+// when requested
+func handleLiveStatistics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, ok := GetLiveStatistics()
+	if r.URL.Query().Get("format") == "json" {
+		if !ok {
+			http.Error(w, "No recording available (start a session to record)", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	var view *LiveStatistics
+	if ok {
+		view = &stats
+	}
+	if err := LiveStatisticsView(view).Render(r.Context(), w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleLiveStatisticsStream sends the live statistics as server-sent events whenever a position was
+// recorded, and once more when the recording ends
+func handleLiveStatisticsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(liveStatsStreamInterval)
+	defer ticker.Stop()
+	var last LiveStatistics
+	for {
+		if stats, ok := GetLiveStatistics(); ok && stats != last {
+			data, err := json.Marshal(stats)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+			last = stats
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package gps
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/sessions"
+)
+
+// recording holds the fs2ff positions received during one session
+type recording struct {
+	SessionID     int
+	ParticipantID string
+	StartedAt     time.Time
+	Active        bool
+	Positions     []Position               // Append-only, so a prefix can be read without holding the lock
+	Pauses        []sessions.PauseInterval // Replaced, never modified, when the session is paused or resumed
+	stats         liveStats                // Updated with every recorded position
+}
+
+var (
+	currentRecording *recording // Recording of the active session, or of the last one until the next starts
+	recordingMutex   = &sync.Mutex{}
+)
+
+// recordingCSVHeader lists the columns of the recording CSV. Record is "position", or "pause_start" and
+// "pause_end" for the boundaries of a session pause, which have no coordinates.
+var recordingCSVHeader = []string{"record", "timestamp", "session_seconds", "latitude", "longitude", "altitude_m"}
+
+// initRecording records positions while a session is active
+func initRecording() {
+	sessions.OnStart(startRecording)
+	sessions.OnEnd(func(session sessions.Session) {
+		updateRecordingPauses(session)
+		stopRecording()
+	})
+	sessions.OnPause(updateRecordingPauses)
+	sessions.OnResume(updateRecordingPauses)
+	OnPosition(recordPosition)
+}
+
+// startRecording begins a new recording for a session
+func startRecording(session sessions.Session) {
+	recordingMutex.Lock()
+	defer recordingMutex.Unlock()
+	currentRecording = &recording{
+		SessionID:     session.ID,
+		ParticipantID: session.ParticipantID,
+		StartedAt:     session.StartedAt,
+		Active:        true,
+		Pauses:        session.Pauses,
+	}
+	log.Printf("Recording GPS positions of session %d", session.ID)
+}
+
+// stopRecording ends the current recording; its data stays available for download
+func stopRecording() {
+	recordingMutex.Lock()
+	defer recordingMutex.Unlock()
+	if currentRecording != nil {
+		currentRecording.Active = false
+	}
+}
+
+// updateRecordingPauses takes over the pauses of the recorded session
+func updateRecordingPauses(session sessions.Session) {
+	recordingMutex.Lock()
+	defer recordingMutex.Unlock()
+	if currentRecording != nil && currentRecording.SessionID == session.ID {
+		currentRecording.Pauses = session.Pauses
+	}
+}
+
+// recordPosition appends a position to the active recording, unless the session is paused
+func recordPosition(position Position) {
+	recordingMutex.Lock()
+	defer recordingMutex.Unlock()
+	if currentRecording == nil || !currentRecording.Active {
+		return
+	}
+	if n := len(currentRecording.Pauses); n > 0 && currentRecording.Pauses[n-1].EndedAt == nil {
+		return
+	}
+	currentRecording.Positions = append(currentRecording.Positions, position)
+	currentRecording.stats.add(currentRecording.Positions)
+}
+
+// Recording is a snapshot of the positions recorded during a session so far
+type Recording struct {
+	SessionID     int
+	ParticipantID string
+	StartedAt     time.Time
+	Active        bool
+	Positions     []Position
+	Pauses        []sessions.PauseInterval
+}
+
+// GetRecording returns the positions recorded so far during the active session, or during the last one
+// until the next starts; false before the first session
+func GetRecording() (Recording, bool) {
+	recordingMutex.Lock()
+	defer recordingMutex.Unlock()
+	if currentRecording == nil {
+		return Recording{}, false
+	}
+	return Recording{
+		SessionID:     currentRecording.SessionID,
+		ParticipantID: currentRecording.ParticipantID,
+		StartedAt:     currentRecording.StartedAt,
+		Active:        currentRecording.Active,
+		Positions:     currentRecording.Positions,
+		Pauses:        currentRecording.Pauses,
+	}, true
+}
+
+// Seconds places an instant on the recording's timeline: the seconds since the first recorded position,
+// without the time the session was paused
+func (r Recording) Seconds(t time.Time) float64 {
+	if len(r.Positions) == 0 {
+		return 0
+	}
+	first := r.Positions[0].Timestamp
+	elapsed := t.Sub(first)
+	for _, pause := range r.Pauses {
+		start, end := pause.StartedAt, t
+		if pause.EndedAt != nil && pause.EndedAt.Before(t) {
+			end = *pause.EndedAt
+		}
+		if start.Before(first) {
+			start = first
+		}
+		if end.After(start) {
+			elapsed -= end.Sub(start)
+		}
+	}
+	return elapsed.Seconds()
+}
+
+// recordingRow is one row of the recording CSV
+type recordingRow struct {
+	Record   string
+	At       time.Time
+	Position *Position
+}
+
+// recordingRows merges the positions and pause boundaries of a recording in time order
+func recordingRows(rec recording) []recordingRow {
+	var boundaries []recordingRow
+	for _, pause := range rec.Pauses {
+		boundaries = append(boundaries, recordingRow{Record: "pause_start", At: pause.StartedAt})
+		if pause.EndedAt != nil {
+			boundaries = append(boundaries, recordingRow{Record: "pause_end", At: *pause.EndedAt})
+		}
+	}
+
+	rows := make([]recordingRow, 0, len(rec.Positions)+len(boundaries))
+	for i := range rec.Positions {
+		p := &rec.Positions[i]
+		for len(boundaries) > 0 && boundaries[0].At.Before(p.Timestamp) {
+			rows = append(rows, boundaries[0])
+			boundaries = boundaries[1:]
+		}
+		rows = append(rows, recordingRow{Record: "position", At: p.Timestamp, Position: p})
+	}
+	return append(rows, boundaries...)
+}
+
+// handleRecordingCSV downloads the positions recorded so far, without interrupting the recording
+func handleRecordingCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	recordingMutex.Lock()
+	if currentRecording == nil {
+		recordingMutex.Unlock()
+		http.Error(w, "No recording available (start a session to record)", http.StatusNotFound)
+		return
+	}
+	snapshot := *currentRecording
+	recordingMutex.Unlock()
+
+	filename := fmt.Sprintf("recording_session%d_%s.csv", snapshot.SessionID, time.Now().Format("2006-01-02_15-04-05"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	writer := csv.NewWriter(w)
+	writer.Write(recordingCSVHeader)
+	for _, row := range recordingRows(snapshot) {
+		record := []string{
+			row.Record,
+			row.At.Format(time.RFC3339Nano),
+			strconv.FormatFloat(row.At.Sub(snapshot.StartedAt).Seconds(), 'f', 3, 64),
+			"", "", "",
+		}
+		if p := row.Position; p != nil {
+			record[3] = strconv.FormatFloat(p.Latitude, 'f', 6, 64)
+			record[4] = strconv.FormatFloat(p.Longitude, 'f', 6, 64)
+			record[5] = strconv.FormatFloat(p.Altitude, 'f', 1, 64)
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Printf("Failed to write recording CSV: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package gps
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+)
+
+// ReferenceConfig is the reference point of the study site: the center and radius of the distance gate,
+// which distance markers are measured from unless another point is selected for them
+type ReferenceConfig struct {
+	ReferencePoint
+	RadiusNM float64 `json:"radius_nm"`
+}
+
+// defaultReference is used until a reference point is configured for the study site
+var defaultReference = ReferenceConfig{
+	ReferencePoint: ReferencePoint{Name: "Currock Hill", Latitude: 54.9275, Longitude: -1.8342},
+	RadiusNM:       9.0,
+}
+
+var (
+	referenceFile         string
+	referenceListeners    []func(ReferenceConfig)
+	referenceListenersMux = &sync.Mutex{}
+)
+
+// initReference loads the reference point configuration
+func initReference() {
+	referenceFile = filepath.Join("data", "gps_reference.json")
+
+	data, err := os.ReadFile(referenceFile)
+	if err != nil {
+		return
+	}
+	var config ReferenceConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		log.Printf("Failed to load reference point configuration: %v", err)
+	} else if err := config.validate(); err != nil {
+		log.Printf("Ignoring invalid reference point configuration: %v", err)
+	} else {
+		applyReferenceConfig(config)
+	}
+}
+
+// validate checks the name, coordinates and radius of the reference point
+func (c *ReferenceConfig) validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if c.Latitude < -90 || c.Latitude > 90 || c.Longitude < -180 || c.Longitude > 180 {
+		return ErrInvalidReference
+	}
+	if !(c.RadiusNM > 0) {
+		return ErrInvalidDistanceThreshold
+	}
+	return nil
+}
+
+// applyReferenceConfig makes a reference point configuration the gate center and radius
+func applyReferenceConfig(config ReferenceConfig) {
+	gateReferenceMux.Lock()
+	gateReference = config.ReferencePoint
+	gateReferenceMux.Unlock()
+
+	maxDistanceMux.Lock()
+	maxDistanceNM = config.RadiusNM
+	maxDistanceMux.Unlock()
+}
+
+// GetReferenceConfig returns the reference point of the study site
+func GetReferenceConfig() ReferenceConfig {
+	return ReferenceConfig{ReferencePoint: GetGateReference(), RadiusNM: GetDistanceThreshold()}
+}
+
+// SetReferenceConfig replaces the reference point of the study site and persists it
+func SetReferenceConfig(config ReferenceConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	applyReferenceConfig(config)
+	referenceChanged()
+
+	events.LogEvent(events.Event{
+		Type:      "reference_point_set",
+		Program:   "GPS",
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// OnReferenceChange registers a function called with the reference point configuration whenever
+// its center or radius changes
+func OnReferenceChange(fn func(ReferenceConfig)) {
+	referenceListenersMux.Lock()
+	defer referenceListenersMux.Unlock()
+	referenceListeners = append(referenceListeners, fn)
+}
+
+// referenceChanged persists the reference point configuration and notifies the listeners
+func referenceChanged() {
+	config := GetReferenceConfig()
+	if err := saveReferenceConfig(config); err != nil {
+		log.Printf("Failed to save reference point configuration: %v", err)
+	}
+
+	referenceListenersMux.Lock()
+	listeners := append([]func(ReferenceConfig){}, referenceListeners...)
+	referenceListenersMux.Unlock()
+	for _, fn := range listeners {
+		fn(config)
+	}
+}
+
+// saveReferenceConfig writes the reference point configuration to its file
+func saveReferenceConfig(config ReferenceConfig) error {
+	if referenceFile == "" {
+		return nil // Not initialized
+	}
+	if err := os.MkdirAll(filepath.Dir(referenceFile), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := referenceFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, referenceFile)
+}
+
+// handleReference returns (GET) or replaces (PUT) the reference point configuration
+func handleReference(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetReferenceConfig())
+	case http.MethodPut:
+		var config ReferenceConfig
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := SetReferenceConfig(config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetReferenceConfig())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
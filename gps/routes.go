@@ -0,0 +1,229 @@
+package gps
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+)
+
+// Gate modes
+const (
+	GateModeRadius = "radius" // Forward within the distance threshold of the gate center
+	GateModeRoute  = "route"  // Forward within the distance threshold of the active route
+)
+
+// Waypoint is one point of a route
+type Waypoint struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// RouteConfig holds the routes of the study scenarios and selects how GPS forwarding is gated
+type RouteConfig struct {
+	Mode           string                `json:"mode"`            // "radius" or "route"
+	ActiveScenario string                `json:"active_scenario"` // Scenario whose route gates forwarding in route mode
+	Routes         map[string][]Waypoint `json:"routes"`          // Ordered waypoints per scenario
+}
+
+var (
+	routeConfig = RouteConfig{Mode: GateModeRadius, Routes: map[string][]Waypoint{}}
+	routeMutex  = &sync.Mutex{}
+	routeFile   string
+)
+
+// initRoutes loads the route configuration
+func initRoutes() {
+	routeFile = filepath.Join("data", "gps_routes.json")
+
+	data, err := os.ReadFile(routeFile)
+	if err != nil {
+		return
+	}
+	var config RouteConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		log.Printf("Failed to load GPS route configuration: %v", err)
+	} else if err := config.validate(); err != nil {
+		log.Printf("Ignoring invalid GPS route configuration: %v", err)
+	} else {
+		routeConfig = config
+	}
+}
+
+// validate checks the mode, the waypoints of every route and the active scenario
+func (c *RouteConfig) validate() error {
+	if c.Mode == "" {
+		c.Mode = GateModeRadius
+	}
+	if c.Mode != GateModeRadius && c.Mode != GateModeRoute {
+		return fmt.Errorf("invalid mode '%s' (expected %s or %s)", c.Mode, GateModeRadius, GateModeRoute)
+	}
+	if c.Routes == nil {
+		c.Routes = map[string][]Waypoint{}
+	}
+	for scenario, waypoints := range c.Routes {
+		if strings.TrimSpace(scenario) == "" {
+			return fmt.Errorf("scenario name is required")
+		}
+		if len(waypoints) == 0 {
+			return fmt.Errorf("route of '%s' has no waypoints", scenario)
+		}
+		for i, wp := range waypoints {
+			if wp.Latitude < -90 || wp.Latitude > 90 || wp.Longitude < -180 || wp.Longitude > 180 {
+				return fmt.Errorf("invalid coordinates of waypoint %d of '%s': %f, %f", i+1, scenario, wp.Latitude, wp.Longitude)
+			}
+		}
+	}
+	if c.Mode == GateModeRoute {
+		if _, ok := c.Routes[c.ActiveScenario]; !ok {
+			return fmt.Errorf("route mode requires an active scenario with a route")
+		}
+	}
+	return nil
+}
+
+// saveRouteConfig persists the route configuration.
+// Must be called with routeMutex held.
+func saveRouteConfig() error {
+	if err := os.MkdirAll(filepath.Dir(routeFile), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(routeConfig, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := routeFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, routeFile)
+}
+
+// GetActiveRoute returns the scenario and waypoints gating forwarding, or ok=false in radius mode
+func GetActiveRoute() (scenario string, waypoints []Waypoint, ok bool) {
+	routeMutex.Lock()
+	defer routeMutex.Unlock()
+	if routeConfig.Mode != GateModeRoute {
+		return "", nil, false
+	}
+	return routeConfig.ActiveScenario, routeConfig.Routes[routeConfig.ActiveScenario], true
+}
+
+// gateDistance returns the distance in nautical miles a position is gated on and a description of
+// what it was measured to: the cross-track distance to the active route, or the distance to the gate center
+func gateDistance(latitude, longitude float64) (float64, string) {
+	if scenario, waypoints, ok := GetActiveRoute(); ok {
+		return crossTrackDistanceNM(latitude, longitude, waypoints), "route " + scenario
+	}
+	reference := GetGateReference()
+	return calculateDistanceNM(latitude, longitude, reference.Latitude, reference.Longitude), reference.Name
+}
+
+// handleRoutes returns (GET) or replaces (PUT) the route configuration
+func handleRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		routeMutex.Lock()
+		defer routeMutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(routeConfig)
+	case http.MethodPut:
+		var config RouteConfig
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := config.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		routeMutex.Lock()
+		previous := routeConfig
+		routeConfig = config
+		if err := saveRouteConfig(); err != nil {
+			routeConfig = previous
+			routeMutex.Unlock()
+			http.Error(w, fmt.Sprintf("Failed to save route configuration: %v", err), http.StatusInternalServerError)
+			return
+		}
+		routeMutex.Unlock()
+
+		events.LogEvent(events.Event{
+			Type:      "gate_route_set",
+			Program:   "GPS",
+			Timestamp: time.Now(),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(config)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// crossTrackDistanceNM returns the distance in nautical miles from a position to the nearest leg of
+// a route. Beyond the ends of a leg the distance to its nearer waypoint is used.
+func crossTrackDistanceNM(latitude, longitude float64, waypoints []Waypoint) float64 {
+	if len(waypoints) == 0 {
+		return math.Inf(1)
+	}
+	if len(waypoints) == 1 {
+		return calculateDistanceNM(latitude, longitude, waypoints[0].Latitude, waypoints[0].Longitude)
+	}
+
+	nearest := math.Inf(1)
+	for i := 0; i+1 < len(waypoints); i++ {
+		nearest = math.Min(nearest, legDistanceNM(latitude, longitude, waypoints[i], waypoints[i+1]))
+	}
+	return nearest
+}
+
+// legDistanceNM returns the distance in nautical miles from a position to the great-circle segment from a to b
+func legDistanceNM(latitude, longitude float64, a, b Waypoint) float64 {
+	const R = 3440.065 // Earth's radius in nautical miles
+
+	toA := calculateDistanceNM(a.Latitude, a.Longitude, latitude, longitude)
+	toB := calculateDistanceNM(b.Latitude, b.Longitude, latitude, longitude)
+	legLength := calculateDistanceNM(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+	if legLength == 0 {
+		return toA
+	}
+
+	// Bearing difference between the leg and the position, as seen from a
+	delta := initialBearing(a.Latitude, a.Longitude, latitude, longitude) - initialBearing(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+	if math.Cos(delta) < 0 {
+		return toA // Before the start of the leg
+	}
+
+	angular := toA / R
+	crossTrack := math.Asin(math.Sin(angular) * math.Sin(delta))
+	alongTrack := math.Acos(math.Min(1, math.Cos(angular)/math.Cos(crossTrack))) * R
+	if alongTrack > legLength {
+		return toB // Past the end of the leg
+	}
+	return math.Abs(crossTrack) * R
+}
+
+// initialBearing returns the initial great-circle bearing in radians from one point to another
+func initialBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	lat1Rad := lat1 * math.Pi / 180
+	lat2Rad := lat2 * math.Pi / 180
+	dlon := (lon2 - lon1) * math.Pi / 180
+
+	y := math.Sin(dlon) * math.Cos(lat2Rad)
+	x := math.Cos(lat1Rad)*math.Sin(lat2Rad) - math.Sin(lat1Rad)*math.Cos(lat2Rad)*math.Cos(dlon)
+	return math.Atan2(y, x)
+}
@@ -0,0 +1,163 @@
+package gps
+
+import (
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+	"github.com/kaireichart/master-thesis-operator-station/programs"
+)
+
+var (
+	// simulatorProcessNames are the executables of the supported simulator versions
+	simulatorProcessNames = []string{"FlightSimulator.exe", "FlightSimulator2024.exe"}
+	// simConnectAddr is the SimConnect TCP endpoint configured in SimConnect.xml; empty disables the check
+	simConnectAddr = "127.0.0.1:500"
+	// streamTimeout is how old the last fs2ff packet may be for the stream to count as alive
+	streamTimeout = 5 * time.Second
+
+	simulatorStatus      = SimulatorStatus{}
+	simulatorStatusMutex = &sync.Mutex{}
+
+	// simPauseDelay is how long the position must stay frozen before the simulator counts as paused
+	simPauseDelay = 3 * time.Second
+	// airborneGroundSpeed is the ground speed, as reported by fs2ff, above which the aircraft is taken to
+	// be flying; an aircraft that moved this fast cannot come to a standstill between two packets
+	airborneGroundSpeed = 30.0
+
+	simPause      = simPauseState{}
+	simPauseMutex = &sync.Mutex{}
+)
+
+// simPauseState tracks frozen positions in the fs2ff stream to detect a paused simulator
+type simPauseState struct {
+	Paused       bool
+	LastPosition Position  // Last packet that moved the aircraft
+	LastSpeed    float64   // Ground speed of that packet
+	FrozenSince  time.Time // Arrival of the first packet repeating LastPosition; zero while moving
+}
+
+// SimulatorStatus is the result of the last simulator connection probe
+type SimulatorStatus struct {
+	Connected           bool       `json:"connected"`             // SimConnect answers or fs2ff data is flowing
+	ProcessRunning      bool       `json:"process_running"`       // A simulator process runs on this machine
+	SimConnectReachable bool       `json:"simconnect_reachable"`  // The SimConnect endpoint accepts connections
+	StreamAlive         bool       `json:"stream_alive"`          // An fs2ff packet arrived within streamTimeout
+	LastPacket          *time.Time `json:"last_packet,omitempty"` // Arrival time of the last fs2ff packet
+	SimPaused           bool       `json:"sim_paused"`            // The fs2ff position is frozen in flight
+	CheckedAt           time.Time  `json:"checked_at"`
+}
+
+// monitorSimulator probes the simulator connection periodically and logs connection changes
+func monitorSimulator() {
+	for {
+		status := probeSimulator()
+
+		simulatorStatusMutex.Lock()
+		changed := status.Connected != simulatorStatus.Connected
+		simulatorStatus = status
+		simulatorStatusMutex.Unlock()
+
+		// Alerts clear once the stream stopped, as no packet will clear them
+		updateAlerts(nil, status.CheckedAt)
+
+		if changed {
+			eventType := "simulator_disconnected"
+			if status.Connected {
+				eventType = "simulator_connected"
+			}
+			log.Printf("Simulator connection changed: %s", eventType)
+			events.LogEvent(events.Event{
+				Type:      eventType,
+				Program:   "Simulator",
+				Timestamp: status.CheckedAt,
+			})
+		}
+
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// probeSimulator checks the simulator process, the SimConnect endpoint and the fs2ff stream
+func probeSimulator() SimulatorStatus {
+	status := SimulatorStatus{CheckedAt: time.Now()}
+
+	for _, name := range simulatorProcessNames {
+		if len(programs.ListProcessIDs(name)) > 0 {
+			status.ProcessRunning = true
+			break
+		}
+	}
+
+	if simConnectAddr != "" {
+		conn, err := net.DialTimeout("tcp", simConnectAddr, time.Second)
+		if err == nil {
+			conn.Close()
+			status.SimConnectReachable = true
+		}
+	}
+
+	if position := GetCurrentPosition(); position != nil {
+		lastPacket := position.Timestamp
+		status.LastPacket = &lastPacket
+		status.StreamAlive = status.CheckedAt.Sub(lastPacket) <= streamTimeout
+	}
+
+	status.Connected = status.SimConnectReachable || status.StreamAlive
+	status.SimPaused = IsSimPaused()
+	return status
+}
+
+// GetSimulatorStatus returns the result of the last simulator probe
+func GetSimulatorStatus() SimulatorStatus {
+	simulatorStatusMutex.Lock()
+	defer simulatorStatusMutex.Unlock()
+	return simulatorStatus
+}
+
+// detectSimPause logs sim_paused when the fs2ff position freezes in flight with zero ground speed for
+// simPauseDelay, and sim_resumed once it moves again. fs2ff packets carry no simulator time, so a paused
+// simulator shows as a repeated position.
+func detectSimPause(position Position, groundSpeed float64) {
+	simPauseMutex.Lock()
+	frozen := position.Latitude == simPause.LastPosition.Latitude &&
+		position.Longitude == simPause.LastPosition.Longitude &&
+		position.Altitude == simPause.LastPosition.Altitude
+
+	var eventType string
+	switch {
+	case !frozen:
+		if simPause.Paused {
+			eventType = "sim_resumed"
+		}
+		simPause = simPauseState{LastPosition: position, LastSpeed: groundSpeed}
+	case simPause.Paused:
+	case groundSpeed > 0 || simPause.LastSpeed < airborneGroundSpeed:
+		// Still moving, or parked/taxiing on the ground
+		simPause.FrozenSince = time.Time{}
+	case simPause.FrozenSince.IsZero():
+		simPause.FrozenSince = position.Timestamp
+	case position.Timestamp.Sub(simPause.FrozenSince) >= simPauseDelay:
+		simPause.Paused = true
+		eventType = "sim_paused"
+	}
+	simPauseMutex.Unlock()
+
+	if eventType != "" {
+		log.Printf("Simulator pause changed: %s", eventType)
+		events.LogEvent(events.Event{
+			Type:      eventType,
+			Program:   "Simulator",
+			Timestamp: position.Timestamp,
+		})
+	}
+}
+
+// IsSimPaused reports whether the simulator was detected as paused
+func IsSimPaused() bool {
+	simPauseMutex.Lock()
+	defer simPauseMutex.Unlock()
+	return simPause.Paused
+}
@@ -4,17 +4,29 @@ import "time"
 
 // Position represents GPS position data
 type Position struct {
-	Latitude  float64   `json:"latitude"`
-	Longitude float64   `json:"longitude"`
-	Altitude  float64   `json:"altitude"`
-	Timestamp time.Time `json:"timestamp"`
+	Latitude    float64   `json:"latitude"`
+	Longitude   float64   `json:"longitude"`
+	Altitude    float64   `json:"altitude"`
+	GroundSpeed float64   `json:"ground_speed"` // Knots
+	Timestamp   time.Time `json:"timestamp"`
 }
 
 // Config represents GPS configuration
 type Config struct {
-	TargetIP          string  `json:"target_ip"`
-	DistanceThreshold float64 `json:"distance_threshold"`
-	IsSending         bool    `json:"is_sending"`
+	TargetIP          string         `json:"target_ip"`
+	DistanceThreshold float64        `json:"distance_threshold"`
+	IsSending         bool           `json:"is_sending"`
+	Reference         ReferencePoint `json:"reference"`          // Center of the distance gate
+	GateMode          string         `json:"gate_mode"`          // "radius" or "route"
+	Scenario          string         `json:"scenario,omitempty"` // Scenario of the route gating forwarding in route mode
+	Route             []Waypoint     `json:"route,omitempty"`
+}
+
+// ReferencePoint is the center of the distance gate GPS data is forwarded within
+type ReferencePoint struct {
+	Name      string  `json:"name"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
 }
 
 // GPSData represents the position information from an XGPS packet
@@ -29,3 +41,10 @@ type GPSData struct {
 	TAS           float32
 	VerticalSpeed float32
 }
+
+// AttitudeData represents the attitude information from an XATT packet
+type AttitudeData struct {
+	TrueHeading float32 // Degrees
+	Pitch       float32 // Degrees, nose up positive
+	Roll        float32 // Degrees, right wing down positive
+}
@@ -0,0 +1,82 @@
+package lsl
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+	"github.com/kaireichart/master-thesis-operator-station/gps"
+)
+
+// channel describes one channel of a stream in the LSL stream metadata
+type channel struct {
+	Label string
+	Unit  string
+}
+
+// streamSpec describes an LSL stream; all streams have an irregular sampling rate
+type streamSpec struct {
+	Name     string
+	Type     string
+	SourceID string // Lets LabRecorder reconnect to the stream after a station restart
+	Strings  bool   // String samples (markers) instead of double samples
+	Channels []channel
+}
+
+var (
+	eventStream = streamSpec{
+		Name:     "OperatorStation-Events",
+		Type:     "Markers",
+		SourceID: "operator-station-events",
+		Strings:  true,
+		Channels: []channel{{Label: "event"}},
+	}
+	positionStream = streamSpec{
+		Name:     "OperatorStation-GPS",
+		Type:     "Position",
+		SourceID: "operator-station-gps",
+		Channels: []channel{
+			{Label: "latitude", Unit: "degrees"},
+			{Label: "longitude", Unit: "degrees"},
+			{Label: "altitude", Unit: "meters"},
+		},
+	}
+)
+
+// eventMarker returns the marker string of an event, e.g. {"type":"launch","program":"FS2FF"}
+func eventMarker(event events.Event) string {
+	marker, _ := json.Marshal(struct {
+		Type    string `json:"type"`
+		Program string `json:"program,omitempty"`
+	}{event.Type, event.Program})
+	return string(marker)
+}
+
+// Init opens the LSL outlets and publishes every logged event and received position on them.
+// Samples are stamped with the time the event or position was recorded, converted to the LSL clock.
+func Init() {
+	if !available {
+		log.Println("LSL outlets disabled (build with -tags lsl and liblsl installed to enable)")
+		return
+	}
+
+	eventOutlet, err := newOutlet(eventStream)
+	if err != nil {
+		log.Printf("Failed to open LSL event outlet: %v", err)
+		return
+	}
+	events.OnLog(func(event events.Event) {
+		eventOutlet.pushString(eventMarker(event), event.Timestamp)
+	})
+
+	positionOutlet, err := newOutlet(positionStream)
+	if err != nil {
+		log.Printf("Failed to open LSL position outlet: %v", err)
+		return
+	}
+	gps.OnPosition(func(position gps.Position) {
+		positionOutlet.pushValues([]float64{position.Latitude, position.Longitude, position.Altitude}, position.Timestamp)
+	})
+
+	log.Printf("LSL outlets %s and %s opened", eventStream.Name, positionStream.Name)
+}
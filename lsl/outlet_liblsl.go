@@ -0,0 +1,98 @@
+//go:build lsl
+
+package lsl
+
+/*
+#cgo LDFLAGS: -llsl
+#include <stdlib.h>
+#include <lsl_c.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+// available reports whether the station was built with liblsl
+const available = true
+
+// maxBuffered is the number of seconds of samples an outlet buffers for slow inlets
+const maxBuffered = 360
+
+// outlet is an LSL stream outlet
+type outlet struct {
+	handle C.lsl_outlet
+}
+
+// newOutlet creates the stream info and opens an outlet for it
+func newOutlet(spec streamSpec) (*outlet, error) {
+	name := C.CString(spec.Name)
+	defer C.free(unsafe.Pointer(name))
+	streamType := C.CString(spec.Type)
+	defer C.free(unsafe.Pointer(streamType))
+	sourceID := C.CString(spec.SourceID)
+	defer C.free(unsafe.Pointer(sourceID))
+
+	format := C.lsl_channel_format_t(C.cft_double64)
+	if spec.Strings {
+		format = C.cft_string
+	}
+
+	info := C.lsl_create_streaminfo(name, streamType, C.int32_t(len(spec.Channels)), C.double(0) /* irregular rate */, format, sourceID)
+	if info == nil {
+		return nil, fmt.Errorf("failed to create stream info for %s", spec.Name)
+	}
+	defer C.lsl_destroy_streaminfo(info)
+
+	channels := appendChild(C.lsl_get_desc(info), "channels")
+	for _, ch := range spec.Channels {
+		element := appendChild(channels, "channel")
+		appendChildValue(element, "label", ch.Label)
+		if ch.Unit != "" {
+			appendChildValue(element, "unit", ch.Unit)
+		}
+	}
+
+	handle := C.lsl_create_outlet(info, 0, maxBuffered)
+	if handle == nil {
+		return nil, fmt.Errorf("failed to create outlet for %s", spec.Name)
+	}
+	return &outlet{handle: handle}, nil
+}
+
+// appendChild adds a child element to the stream metadata
+func appendChild(parent C.lsl_xml_ptr, name string) C.lsl_xml_ptr {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	return C.lsl_append_child(parent, cName)
+}
+
+// appendChildValue adds a child element with a text value to the stream metadata
+func appendChildValue(parent C.lsl_xml_ptr, name, value string) {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	cValue := C.CString(value)
+	defer C.free(unsafe.Pointer(cValue))
+	C.lsl_append_child_value(parent, cName, cValue)
+}
+
+// lslTimestamp converts a wall-clock time to the LSL clock
+func lslTimestamp(at time.Time) C.double {
+	return C.lsl_local_clock() - C.double(time.Since(at).Seconds())
+}
+
+// pushString sends a single-channel string sample
+func (o *outlet) pushString(value string, at time.Time) {
+	cValue := C.CString(value)
+	defer C.free(unsafe.Pointer(cValue))
+
+	sample := []*C.char{cValue}
+	C.lsl_push_sample_strt(o.handle, &sample[0], lslTimestamp(at))
+}
+
+// pushValues sends a sample with one value per channel
+func (o *outlet) pushValues(values []float64, at time.Time) {
+	C.lsl_push_sample_dt(o.handle, (*C.double)(unsafe.Pointer(&values[0])), lslTimestamp(at))
+}
@@ -0,0 +1,22 @@
+//go:build !lsl
+
+package lsl
+
+import (
+	"errors"
+	"time"
+)
+
+// available reports whether the station was built with liblsl
+const available = false
+
+// outlet does nothing without liblsl
+type outlet struct{}
+
+func newOutlet(streamSpec) (*outlet, error) {
+	return nil, errors.New("built without liblsl")
+}
+
+func (*outlet) pushString(string, time.Time) {}
+
+func (*outlet) pushValues([]float64, time.Time) {}
@@ -9,17 +9,19 @@ import (
 
 	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
 	"github.com/kaireichart/master-thesis-operator-station/events"
-	"github.com/kaireichart/master-thesis-operator-station/gps"
-	"github.com/kaireichart/master-thesis-operator-station/mental_rotation"
-	"github.com/kaireichart/master-thesis-operator-station/programs"
+	"github.com/kaireichart/master-thesis-operator-station/study"
 )
 
+// enabled are the modules this deployment runs
+var enabled []module
+
 func init() {
-	events.Init()
-	gps.Init()
-	programs.Init()
-	mental_rotation.Init()
-	data_analysis.Init()
+	enabled = enabledModules()
+	for _, m := range enabled {
+		if m.init != nil {
+			m.init()
+		}
+	}
 }
 
 func main() {
@@ -32,6 +34,7 @@ func main() {
 		if err := data_analysis.CloseMainDatabase(); err != nil {
 			log.Printf("Error closing main database: %v", err)
 		}
+		events.Close()
 		os.Exit(0)
 	}()
 
@@ -40,14 +43,15 @@ func main() {
 	http.Handle("/icons/", http.StripPrefix("/icons/", http.FileServer(http.Dir("icons"))))
 	http.HandleFunc("/", serveFrontend)
 
-	events.SetupHandlers()
-	gps.SetupHandlers()
-	programs.SetupHandlers()
-	mental_rotation.SetupHandlers()
-	data_analysis.SetupHandlers()
+	for _, m := range enabled {
+		if m.setupHandlers != nil {
+			m.setupHandlers()
+		}
+	}
 
 	log.Printf("Server started at http://127.0.0.1:8080")
-	http.ListenAndServe(":8080", nil)
+	// Requests changing data are rejected while the dataset is frozen
+	http.ListenAndServe(":8080", study.RejectWritesWhenFrozen(http.DefaultServeMux))
 }
 
 func serveFrontend(w http.ResponseWriter, r *http.Request) {
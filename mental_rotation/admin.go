@@ -0,0 +1,127 @@
+package mental_rotation
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// participantSelection selects the participants an administrative action applies to
+type participantSelection struct {
+	ParticipantIDs []string `json:"participantIds"`
+}
+
+// decodeParticipantSelection reads a non-empty participant selection from the request body
+func decodeParticipantSelection(w http.ResponseWriter, r *http.Request) (map[string]bool, bool) {
+	var selection participantSelection
+	if err := json.NewDecoder(r.Body).Decode(&selection); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if len(selection.ParticipantIDs) == 0 {
+		http.Error(w, "participantIds is required", http.StatusBadRequest)
+		return nil, false
+	}
+
+	selected := make(map[string]bool, len(selection.ParticipantIDs))
+	for _, id := range selection.ParticipantIDs {
+		selected[id] = true
+	}
+	return selected, true
+}
+
+// splitResults separates the active results of the selected participants from the rest
+func splitResults(selected map[string]bool) (matching, remaining []Result) {
+	remaining = []Result{}
+	for _, result := range results {
+		if selected[result.ParticipantID] {
+			matching = append(matching, result)
+		} else {
+			remaining = append(remaining, result)
+		}
+	}
+	return matching, remaining
+}
+
+// handleArchiveResults lists archived results (GET) or moves the results of completed participants into the archive (POST)
+func handleArchiveResults(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		mu.RLock()
+		defer mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(archivedResults)
+	case http.MethodPost:
+		selected, ok := decodeParticipantSelection(w, r)
+		if !ok {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		matching, remaining := splitResults(selected)
+		now := time.Now()
+		previousArchive := archivedResults
+		for _, result := range matching {
+			archivedResults = append(archivedResults, ArchivedResult{Result: result, ArchivedAt: now})
+		}
+
+		// Save the archive first so a failure can never lose results
+		if err := saveArchive(); err != nil {
+			archivedResults = previousArchive
+			http.Error(w, "Failed to save archive", http.StatusInternalServerError)
+			return
+		}
+		previousResults := results
+		results = remaining
+		if err := saveResults(); err != nil {
+			results = previousResults
+			http.Error(w, "Failed to save results", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Archived %d mental rotation results", len(matching))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":   "success",
+			"archived": len(matching),
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDeleteResults permanently removes the active results of the given participants, e.g. test runs made during setup
+func handleDeleteResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	selected, ok := decodeParticipantSelection(w, r)
+	if !ok {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	matching, remaining := splitResults(selected)
+	previousResults := results
+	results = remaining
+	if err := saveResults(); err != nil {
+		results = previousResults
+		http.Error(w, "Failed to save results", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Deleted %d mental rotation results", len(matching))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"deleted": len(matching),
+	})
+}
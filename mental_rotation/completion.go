@@ -0,0 +1,140 @@
+package mental_rotation
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+)
+
+// completionCodeAlphabet leaves out characters that are easily confused when copied by hand (0/O, 1/I/L)
+const completionCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// completionCodeLength is the number of characters in a completion code
+const completionCodeLength = 8
+
+// Completion records the code handed to a participant after finishing the session
+type Completion struct {
+	ParticipantID string    `json:"participantId"`
+	Code          string    `json:"code"`
+	CompletedAt   time.Time `json:"completedAt"`
+}
+
+// generateCompletionCode returns a random code not yet handed out.
+// Must be called with mu held.
+func generateCompletionCode() (string, error) {
+	max := big.NewInt(int64(len(completionCodeAlphabet)))
+	for {
+		var code strings.Builder
+		for i := 0; i < completionCodeLength; i++ {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			code.WriteByte(completionCodeAlphabet[n.Int64()])
+		}
+
+		if findCompletion(func(c Completion) bool { return c.Code == code.String() }) == nil {
+			return code.String(), nil
+		}
+	}
+}
+
+// findCompletion returns the first completion matching the predicate, or nil.
+// Must be called with mu held.
+func findCompletion(match func(Completion) bool) *Completion {
+	for i := range completions {
+		if match(completions[i]) {
+			return &completions[i]
+		}
+	}
+	return nil
+}
+
+// GetCompletion returns the completion issued to a participant, if any
+func GetCompletion(participantID string) (Completion, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	c := findCompletion(func(c Completion) bool { return c.ParticipantID == participantID })
+	if c == nil {
+		return Completion{}, false
+	}
+	return *c, true
+}
+
+// handleComplete issues the completion code for a participant. Repeated calls for the
+// same participant return the code issued first, so reloading the final screen is safe.
+func handleComplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		ParticipantID string `json:"participantId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	participantID := strings.TrimSpace(request.ParticipantID)
+	if participantID == "" {
+		http.Error(w, "participantId is required", http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	completion := findCompletion(func(c Completion) bool { return c.ParticipantID == participantID })
+	if completion == nil {
+		code, err := generateCompletionCode()
+		if err != nil {
+			http.Error(w, "Failed to generate completion code", http.StatusInternalServerError)
+			return
+		}
+
+		completions = append(completions, Completion{
+			ParticipantID: participantID,
+			Code:          code,
+			CompletedAt:   time.Now(),
+		})
+		if err := saveCompletions(); err != nil {
+			completions = completions[:len(completions)-1]
+			http.Error(w, "Failed to save completion code", http.StatusInternalServerError)
+			return
+		}
+		completion = &completions[len(completions)-1]
+
+		log.Printf("Issued completion code %s to participant %s", code, participantID)
+		events.LogEvent(events.Event{
+			Type:      "completion",
+			Program:   fmt.Sprintf("Mental Rotation - %s - %s", participantID, code),
+			Timestamp: completion.CompletedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(completion)
+}
+
+// handleGetCompletions lists all issued completion codes for compensation administration
+func handleGetCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(completions)
+}
@@ -36,16 +36,28 @@ type Result struct {
 	Timestamp     string        `json:"timestamp"`
 }
 
+// ArchivedResult is a result moved out of the active set once its participant completed the study
+type ArchivedResult struct {
+	Result
+	ArchivedAt time.Time `json:"archivedAt"`
+}
+
 var (
-	tasks       []Task
-	results     []Result
-	mu          sync.RWMutex
-	resultsFile string
+	tasks           []Task
+	results         []Result
+	archivedResults []ArchivedResult
+	mu              sync.RWMutex
+	resultsFile     string
+	archiveFile     string
+	completions     []Completion
+	completionsFile string
 )
 
 func Init() {
 	// Set up results file path
 	resultsFile = filepath.Join("data", "mental_rotation_results.json")
+	archiveFile = filepath.Join("data", "mental_rotation_archive.json")
+	completionsFile = filepath.Join("data", "mental_rotation_completions.json")
 
 	// Create data directory if it doesn't exist
 	if err := os.MkdirAll("data", 0755); err != nil {
@@ -53,10 +65,8 @@ func Init() {
 	}
 
 	// Load existing results if any
-	if data, err := os.ReadFile(resultsFile); err == nil {
-		if err := json.Unmarshal(data, &results); err != nil {
-			panic(err)
-		}
+	if err := loadResults(); err != nil {
+		panic(err)
 	}
 
 	// Discover all JPG images in the images directory
@@ -91,6 +101,10 @@ func SetupHandlers() {
 	http.HandleFunc("/mental-rotation/tasks", handleGetTasks)
 	http.HandleFunc("/mental-rotation/submit", handleSubmitResult)
 	http.HandleFunc("/mental-rotation/results", handleGetResults)
+	http.HandleFunc("/mental-rotation/results/archive", handleArchiveResults)
+	http.HandleFunc("/mental-rotation/results/delete", handleDeleteResults)
+	http.HandleFunc("/mental-rotation/complete", handleComplete)
+	http.HandleFunc("/mental-rotation/completions", handleGetCompletions)
 
 	// Create a sub-filesystem for the images directory
 	imagesFS, err := fs.Sub(images, "images")
@@ -119,14 +133,6 @@ func handleGetTasks(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tasks)
 }
 
-func saveResults() error {
-	data, err := json.MarshalIndent(results, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(resultsFile, data, 0644)
-}
-
 func handleSubmitResult(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -164,3 +170,16 @@ func handleGetResults(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(results)
 }
+
+// AllResults returns the active and archived results of all participants
+func AllResults() []Result {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	all := make([]Result, 0, len(results)+len(archivedResults))
+	all = append(all, results...)
+	for _, archived := range archivedResults {
+		all = append(all, archived.Result)
+	}
+	return all
+}
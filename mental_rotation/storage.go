@@ -0,0 +1,140 @@
+package mental_rotation
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// maxResultBackups is the number of previous versions of a results file kept next to it
+const maxResultBackups = 5
+
+// backupPath returns the path of the n-th most recent backup of a results file
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// loadResults reads the active results, the archive and the issued completion codes
+func loadResults() error {
+	if err := loadJSONFile(resultsFile, &results); err != nil {
+		return err
+	}
+	if err := loadJSONFile(archiveFile, &archivedResults); err != nil {
+		return err
+	}
+	return loadJSONFile(completionsFile, &completions)
+}
+
+// loadJSONFile reads a results file into v, falling back to the most recent readable backup
+func loadJSONFile(file string, v interface{}) error {
+	paths := []string{file}
+	for i := 1; i <= maxResultBackups; i++ {
+		paths = append(paths, backupPath(file, i))
+	}
+
+	var firstErr error
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err == nil {
+			if err = json.Unmarshal(data, v); err == nil {
+				if path != file {
+					log.Printf("Results file %s unreadable, restored from backup %s", file, path)
+				}
+				return nil
+			}
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("failed to load %s: %w", path, err)
+		}
+	}
+
+	return firstErr
+}
+
+// writeFileSync writes data to path and flushes it to disk before returning
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rotateBackups shifts the existing backups by one and copies the current file to backup 1
+func rotateBackups(path string) error {
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	os.Remove(backupPath(path, maxResultBackups))
+	for i := maxResultBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(path, i), backupPath(path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return writeFileSync(backupPath(path, 1), current)
+}
+
+// saveResults persists the active results
+func saveResults() error {
+	return saveJSONFile(resultsFile, results)
+}
+
+// saveArchive persists the archived results
+func saveArchive() error {
+	return saveJSONFile(archiveFile, archivedResults)
+}
+
+// saveCompletions persists the issued completion codes
+func saveCompletions() error {
+	return saveJSONFile(completionsFile, completions)
+}
+
+// saveJSONFile writes v to a temporary file and atomically renames it over the target
+// file, so a crash mid-write never leaves a truncated results file behind
+func saveJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := path + ".tmp"
+	if err := writeFileSync(tempFile, data); err != nil {
+		return fmt.Errorf("failed to write temporary results file: %w", err)
+	}
+
+	if err := rotateBackups(path); err != nil {
+		log.Printf("Failed to rotate backups of %s: %v", path, err)
+	}
+
+	if err := os.Rename(tempFile, path); err != nil {
+		return fmt.Errorf("failed to replace results file: %w", err)
+	}
+
+	// Persist the rename itself; directories cannot be synced on all platforms, so this is best effort
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	return nil
+}
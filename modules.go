@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+	"github.com/kaireichart/master-thesis-operator-station/events"
+	"github.com/kaireichart/master-thesis-operator-station/gps"
+	"github.com/kaireichart/master-thesis-operator-station/lsl"
+	"github.com/kaireichart/master-thesis-operator-station/mental_rotation"
+	"github.com/kaireichart/master-thesis-operator-station/programs"
+	"github.com/kaireichart/master-thesis-operator-station/replication"
+	"github.com/kaireichart/master-thesis-operator-station/rpc"
+	"github.com/kaireichart/master-thesis-operator-station/schema"
+	"github.com/kaireichart/master-thesis-operator-station/sessions"
+	"github.com/kaireichart/master-thesis-operator-station/study"
+)
+
+// module is a part of the station, initialized and serving its handlers unless disabled
+type module struct {
+	name          string
+	init          func() // Optional
+	setupHandlers func() // Optional
+	required      bool   // Other modules depend on it, so it cannot be disabled
+	requires      []string
+}
+
+// modules in the order they are initialized
+var modules = []module{
+	{name: "events", init: events.Init, setupHandlers: events.SetupHandlers, required: true},
+	{name: "sessions", init: sessions.Init, setupHandlers: sessions.SetupHandlers, required: true},
+	{name: "gps", init: gps.Init, setupHandlers: gps.SetupHandlers},
+	{name: "programs", init: programs.Init, setupHandlers: programs.SetupHandlers},
+	{name: "mental_rotation", init: mental_rotation.Init, setupHandlers: mental_rotation.SetupHandlers},
+	{name: "data_analysis", init: data_analysis.Init, setupHandlers: data_analysis.SetupHandlers},
+	{name: "study", init: study.Init, setupHandlers: study.SetupHandlers, requires: []string{"data_analysis"}},
+	{name: "lsl", init: lsl.Init},
+	{name: "schema", setupHandlers: schema.SetupHandlers, required: true},
+	{name: "rpc", setupHandlers: rpc.SetupHandlers, requires: []string{"gps"}},
+	{name: "replication", init: replication.Init, setupHandlers: replication.SetupHandlers},
+}
+
+// ModuleConfig selects the modules of a deployment, e.g. an analysis-only station without the UDP
+// listeners and process monitoring. It is read from data/modules.json at startup.
+type ModuleConfig struct {
+	Disabled []string `json:"disabled"` // Names of the modules not to start
+}
+
+var moduleConfigFile = filepath.Join("data", "modules.json")
+
+// enabledModules returns the modules not disabled by the module configuration. Modules requiring a
+// disabled module are disabled as well; an invalid configuration is ignored.
+func enabledModules() []module {
+	data, err := os.ReadFile(moduleConfigFile)
+	if err != nil {
+		return modules
+	}
+
+	// Unknown fields are rejected so a misspelled setting does not silently enable every module
+	var config ModuleConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		log.Printf("Failed to load module configuration: %v", err)
+		return modules
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range config.Disabled {
+		i := slices.IndexFunc(modules, func(m module) bool { return m.name == name })
+		switch {
+		case i < 0:
+			log.Printf("Ignoring invalid module configuration: unknown module '%s'", name)
+			return modules
+		case modules[i].required:
+			log.Printf("Ignoring invalid module configuration: module '%s' cannot be disabled", name)
+			return modules
+		}
+		disabled[name] = true
+	}
+
+	var enabled []module
+	for _, m := range modules {
+		if i := slices.IndexFunc(m.requires, func(name string) bool { return disabled[name] }); i >= 0 {
+			log.Printf("Disabling module %s, which requires the disabled module %s", m.name, m.requires[i])
+			disabled[m.name] = true
+		}
+		if disabled[m.name] {
+			continue
+		}
+		enabled = append(enabled, m)
+	}
+	log.Printf("Loaded module configuration from %s, disabled modules: %v", moduleConfigFile, config.Disabled)
+	return enabled
+}
@@ -1,10 +1,10 @@
 package programs
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
 	"time"
 
 	"github.com/kaireichart/master-thesis-operator-station/events"
@@ -25,6 +25,10 @@ func SetupHandlers() {
 	http.HandleFunc("/program-manager", serveProgramManager)
 
 	http.HandleFunc("/programs/status-all", handleStatusAll)
+	http.HandleFunc("/programs/status", handleStatusJSON)
+	http.HandleFunc("/programs/logs", handleProgramLogs)
+	http.HandleFunc("/programs/schedule", handleSchedule)
+	http.HandleFunc("/programs/schedule/delete", handleDeleteScheduledAction)
 	http.HandleFunc("/programs/launch", handleLaunchHTMX)
 	http.HandleFunc("/programs/kill", handleKillHTMX)
 }
@@ -43,52 +47,44 @@ func handleStatusAll(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleStatusJSON returns the state of all programs, including PIDs and whether they were started by the station
+func handleStatusJSON(w http.ResponseWriter, r *http.Request) {
+	states := GetProgramStates()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(states)
+}
+
 func handleLaunchHTMX(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 
-	mutex.Lock()
-	program, exists := programs[name]
-	if !exists {
-		mutex.Unlock()
+	program, state, launched, err := launchProgram(name)
+	if err == errProgramNotFound {
 		http.Error(w, "Program not found", http.StatusNotFound)
 		return
 	}
-
-	state, exists := programStates[name]
-	if exists && state.Running {
-		mutex.Unlock()
-		// Return the current card state without changes
-		w.Header().Set("Content-Type", "text/html")
-		err := ProgramCard(name, program, state).Render(r.Context(), w)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	}
-
-	cmd := exec.Command(program.Path)
-	err := cmd.Start()
 	if err != nil {
-		mutex.Unlock()
 		log.Printf("Failed to launch %s: %v", name, err)
 		http.Error(w, fmt.Sprintf("Failed to start program: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	programStates[name] = &ProgramState{Running: true, Cmd: cmd}
-	mutex.Unlock()
-
-	// Create and record the event
-	event := events.Event{
-		Type:      "launch",
-		Program:   name,
-		Timestamp: time.Now(),
+	if launched {
+		// Create and record the event
+		event := events.Event{
+			Type:      "launch",
+			Program:   name,
+			Timestamp: time.Now(),
+		}
+		events.LogEvent(event)
 	}
-	events.LogEvent(event)
 
 	// Return updated program card
 	w.Header().Set("Content-Type", "text/html")
-	err = ProgramCard(name, program, programStates[name]).Render(r.Context(), w)
+	err = ProgramCard(name, program, state).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -98,58 +94,29 @@ func handleLaunchHTMX(w http.ResponseWriter, r *http.Request) {
 func handleKillHTMX(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 
-	mutex.Lock()
-	program, exists := programs[name]
-	if !exists {
-		mutex.Unlock()
+	program, state, killed, err := killProgram(name)
+	if err == errProgramNotFound {
 		http.Error(w, "Program not found", http.StatusNotFound)
 		return
 	}
-
-	// Check if the process is actually running
-	if !isAppRunning(program.Name) {
-		mutex.Unlock()
-		// Return current state without changes
-		state := programStates[name]
-		if state == nil {
-			state = &ProgramState{Running: false}
-		}
-
-		w.Header().Set("Content-Type", "text/html")
-		err := ProgramCard(name, program, state).Render(r.Context(), w)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	}
-
-	cmd := exec.Command("taskkill", "/F", "/IM", program.Name)
-	err := cmd.Run()
 	if err != nil {
-		mutex.Unlock()
 		http.Error(w, fmt.Sprintf("Failed to kill program: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Update the state
-	if state, exists := programStates[name]; exists {
-		state.Running = false
-	} else {
-		programStates[name] = &ProgramState{Running: false}
-	}
-	mutex.Unlock()
-
-	// Create and record the event
-	event := events.Event{
-		Type:      "kill",
-		Program:   name,
-		Timestamp: time.Now(),
+	if killed {
+		// Create and record the event
+		event := events.Event{
+			Type:      "kill",
+			Program:   name,
+			Timestamp: time.Now(),
+		}
+		events.LogEvent(event)
 	}
-	events.LogEvent(event)
 
 	// Return updated program card
 	w.Header().Set("Content-Type", "text/html")
-	err = ProgramCard(name, program, programStates[name]).Render(r.Context(), w)
+	err = ProgramCard(name, program, state).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
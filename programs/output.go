@@ -0,0 +1,122 @@
+package programs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultTailLines is the number of log lines returned when none are requested
+	defaultTailLines = 100
+	// maxTailLines caps the number of log lines returned by one request
+	maxTailLines = 5000
+	// maxTailBytes limits how much of the end of a log file is read to find the last lines
+	maxTailBytes = 1 << 20
+)
+
+// programLogPath returns the path of the output log file of a program
+func programLogPath(name string) string {
+	return filepath.Join("logs", "programs", fmt.Sprintf("%s.log", name))
+}
+
+// openProgramLog opens the output log of a program for appending and marks the start of a new run
+func openProgramLog(name string) (*os.File, error) {
+	path := programLogPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(f, "=== %s launched at %s ===\n", name, time.Now().Format("2006-01-02 15:04:05"))
+	return f, nil
+}
+
+// waitAndCloseLog waits for a launched program to exit, records its exit status and closes its log
+func waitAndCloseLog(cmd *exec.Cmd, logFile *os.File) {
+	err := cmd.Wait()
+	status := "exited normally"
+	if err != nil {
+		status = fmt.Sprintf("exited with error: %v", err)
+	}
+
+	fmt.Fprintf(logFile, "=== %s at %s ===\n", status, time.Now().Format("2006-01-02 15:04:05"))
+	logFile.Close()
+}
+
+// tailFile returns the last n lines of a file
+func tailFile(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := info.Size() - maxTailBytes
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if offset > 0 && len(lines) > 0 {
+		lines = lines[1:] // Drop the partial line at the cut
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// handleProgramLogs returns the last lines of the captured output of a program as plain text
+func handleProgramLogs(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if _, exists := programs[name]; !exists {
+		http.Error(w, "Program not found", http.StatusNotFound)
+		return
+	}
+
+	lines := defaultTailLines
+	if value := r.URL.Query().Get("lines"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid lines parameter", http.StatusBadRequest)
+			return
+		}
+		lines = min(n, maxTailLines)
+	}
+
+	output, err := tailFile(programLogPath(name), lines)
+	if os.IsNotExist(err) {
+		http.Error(w, "No output captured for this program yet", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read program log: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, strings.Join(output, "\n"))
+}
@@ -41,7 +41,7 @@ func ProgramManager() templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"max-w-6xl mx-auto\"><div class=\"flex items-center justify-between mb-8\"><h1 class=\"text-3xl font-bold text-gray-800\">Program Manager</h1><div class=\"flex space-x-4\"><button id=\"broadcast-toggle\" hx-post=\"/gps/broadcast-toggle\" hx-trigger=\"click\" hx-target=\"#broadcast-status\" hx-swap=\"outerHTML\" class=\"px-4 py-2 bg-red-500 text-white rounded hover:bg-red-600 transition-colors\"><span class=\"htmx-indicator\">🔄</span> Not Sending to Target IP</button> <button hx-get=\"/programs/status-all\" hx-trigger=\"click\" hx-target=\"#programs-container\" hx-swap=\"innerHTML\" class=\"px-4 py-2 bg-gray-500 text-white rounded hover:bg-gray-600 transition-colors\"><span class=\"htmx-indicator\">🔄</span> Refresh Now</button></div></div><div class=\"grid grid-cols-1 md:grid-cols-2 gap-8\"><!-- Programs Section --><div><h2 class=\"text-2xl font-bold text-gray-800 mb-4\">Programs</h2><div id=\"programs-container\" class=\"space-y-4\" hx-get=\"/programs/status-all\" hx-trigger=\"load, every 5s\"><!-- Programs will be loaded here --></div><!-- GPS Section --><div class=\"mt-8\"><h2 class=\"text-2xl font-bold text-gray-800 mb-4\">GPS Position</h2><div id=\"gps-display\" class=\"bg-white rounded-lg shadow p-4\" hx-get=\"/gps/position\" hx-trigger=\"load, every 2s\" hx-swap=\"innerHTML\"><div class=\"text-gray-500\">Waiting for GPS data...</div></div><!-- Target Position Section --><div class=\"mt-4\"><h3 class=\"text-xl font-bold text-gray-800 mb-2\">Target Position</h3><div class=\"bg-white rounded-lg shadow p-4\"><div class=\"mb-4\"><p class=\"text-sm text-gray-600 mb-2\">Center: Currock Hill (54.9275°N, 1.8342°W)</p></div><!-- GPS Sending Configuration --><div id=\"gps-config\" hx-get=\"/gps/config\" hx-trigger=\"load\" hx-swap=\"innerHTML\"><!-- GPS config will be loaded here --></div></div></div></div></div><!-- Events Section --><div><h2 class=\"text-2xl font-bold text-gray-800 mb-4\">Recent Events</h2>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"max-w-6xl mx-auto\"><div class=\"flex items-center justify-between mb-8\"><h1 class=\"text-3xl font-bold text-gray-800\">Program Manager</h1><div class=\"flex space-x-4\"><button id=\"broadcast-toggle\" hx-post=\"/gps/broadcast-toggle\" hx-trigger=\"click\" hx-target=\"#broadcast-status\" hx-swap=\"outerHTML\" class=\"px-4 py-2 bg-red-500 text-white rounded hover:bg-red-600 transition-colors\"><span class=\"htmx-indicator\">🔄</span> Not Sending to Target IP</button> <button hx-get=\"/programs/status-all\" hx-trigger=\"click\" hx-target=\"#programs-container\" hx-swap=\"innerHTML\" class=\"px-4 py-2 bg-gray-500 text-white rounded hover:bg-gray-600 transition-colors\"><span class=\"htmx-indicator\">🔄</span> Refresh Now</button></div></div><div class=\"grid grid-cols-1 md:grid-cols-2 gap-8\"><!-- Programs Section --><div><h2 class=\"text-2xl font-bold text-gray-800 mb-4\">Programs</h2><div id=\"programs-container\" class=\"space-y-4\" hx-get=\"/programs/status-all\" hx-trigger=\"load, every 5s\"><!-- Programs will be loaded here --></div><!-- Simulator Section --><div class=\"mt-8\"><h2 class=\"text-2xl font-bold text-gray-800 mb-4\">Simulator</h2><div id=\"simulator-status\" class=\"bg-white rounded-lg shadow p-4\" hx-get=\"/gps/simulator-status\" hx-trigger=\"load, every 5s\" hx-swap=\"innerHTML\"><div class=\"text-gray-500\">Checking simulator connection...</div></div></div><!-- Alerts Section --><div class=\"mt-8\"><h2 class=\"text-2xl font-bold text-gray-800 mb-4\">Alerts</h2><div id=\"gps-alerts\" class=\"bg-white rounded-lg shadow p-4\" hx-get=\"/gps/alerts\" hx-trigger=\"load, every 1s\" hx-swap=\"innerHTML\"><div class=\"text-gray-500\">Loading alerts...</div></div></div><!-- GPS Section --><div class=\"mt-8\"><h2 class=\"text-2xl font-bold text-gray-800 mb-4\">GPS Position</h2><div id=\"gps-display\" class=\"bg-white rounded-lg shadow p-4\" hx-get=\"/gps/position\" hx-trigger=\"load, every 2s\" hx-swap=\"innerHTML\"><div class=\"text-gray-500\">Waiting for GPS data...</div></div><!-- Live Statistics Section --><div class=\"mt-4\"><h3 class=\"text-xl font-bold text-gray-800 mb-2\">Live Statistics</h3><div id=\"live-statistics\" class=\"bg-white rounded-lg shadow p-4\" hx-get=\"/gps/live-statistics\" hx-trigger=\"load, every 2s\" hx-swap=\"innerHTML\"><div class=\"text-gray-500\">Loading live statistics...</div></div></div><!-- Target Position Section --><div class=\"mt-4\"><h3 class=\"text-xl font-bold text-gray-800 mb-2\">Target Position</h3><div class=\"bg-white rounded-lg shadow p-4\"><!-- GPS Sending Configuration --><div id=\"gps-config\" hx-get=\"/gps/config\" hx-trigger=\"load\" hx-swap=\"innerHTML\"><!-- GPS config will be loaded here --></div></div></div></div></div><!-- Events Section --><div><h2 class=\"text-2xl font-bold text-gray-800 mb-4\">Recent Events</h2>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
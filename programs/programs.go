@@ -1,13 +1,22 @@
 package programs
 
 import (
+	"encoding/csv"
+	"errors"
 	"fmt"
+	"log"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
 )
 
+// errProgramNotFound is returned for program names that are not configured
+var errProgramNotFound = errors.New("program not found")
+
 var (
 	programs      = map[string]Program{}
 	programStates = map[string]*ProgramState{}
@@ -32,28 +41,96 @@ func Init() {
 	}
 
 	// Initialize program states
-	for name := range programs {
-		programStates[name] = &ProgramState{Running: isAppRunning(programs[name].Name)}
+	for name, program := range programs {
+		state := &ProgramState{}
+		refreshState(program, state)
+		programStates[name] = state
 	}
 	go monitorProgramStates()
+
+	initSchedule()
 }
 
-func isAppRunning(name string) bool {
-	cmd := exec.Command("tasklist", "/FI", fmt.Sprintf("IMAGENAME eq %s", name))
+// ListProcessIDs returns the PIDs of all running processes with the given image name
+func ListProcessIDs(name string) []int {
+	cmd := exec.Command("tasklist", "/FI", fmt.Sprintf("IMAGENAME eq %s", name), "/FO", "CSV", "/NH")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return false
+		return nil
+	}
+
+	// Without matches tasklist prints an informational line instead of CSV records
+	reader := csv.NewReader(strings.NewReader(string(output)))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil
+	}
+
+	var pids []int
+	for _, record := range records {
+		if len(record) < 2 || !strings.EqualFold(record[0], name) {
+			continue
+		}
+		if pid, err := strconv.Atoi(record[1]); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// refreshState updates a program state from the running processes, separating the
+// instance started by the station from same-named instances started externally
+func refreshState(program Program, state *ProgramState) {
+	pids := ListProcessIDs(program.Name)
+
+	stationAlive := false
+	var externalPIDs []int
+	for _, pid := range pids {
+		if state.PID != 0 && pid == state.PID {
+			stationAlive = true
+		} else {
+			externalPIDs = append(externalPIDs, pid)
+		}
+	}
+
+	if !stationAlive {
+		state.PID = 0
+		state.Cmd = nil
+	}
+	state.ExternalPIDs = externalPIDs
+	state.Running = stationAlive || len(externalPIDs) > 0
+
+	switch {
+	case stationAlive:
+		state.Origin = OriginStation
+	case len(externalPIDs) > 0:
+		state.Origin = OriginExternal
+	default:
+		state.Origin = ""
 	}
-	return strings.Contains(string(output), name)
 }
 
 func monitorProgramStates() {
 	for {
 		time.Sleep(5 * time.Second)
 		mutex.Lock()
-		for name, state := range programStates {
-			if state.Running {
-				state.Running = isAppRunning(name)
+		for name, program := range programs {
+			state, exists := programStates[name]
+			if !exists {
+				state = &ProgramState{}
+				programStates[name] = state
+			}
+
+			hadExternal := len(state.ExternalPIDs) > 0
+			refreshState(program, state)
+			if !hadExternal && len(state.ExternalPIDs) > 0 {
+				log.Printf("Detected externally started %s (PIDs %v)", name, state.ExternalPIDs)
+				events.LogEvent(events.Event{
+					Type:      "external_launch",
+					Program:   name,
+					Timestamp: time.Now(),
+				})
 			}
 		}
 		mutex.Unlock()
@@ -72,13 +149,98 @@ func GetProgramStates() map[string]*ProgramState {
 	
 	// Update states before returning
 	for name, program := range programs {
-		running := isAppRunning(program.Name)
-		if state, exists := programStates[name]; exists {
-			state.Running = running
-		} else {
-			programStates[name] = &ProgramState{Running: running}
+		state, exists := programStates[name]
+		if !exists {
+			state = &ProgramState{}
+			programStates[name] = state
 		}
+		refreshState(program, state)
 	}
 	
 	return programStates
 }
+
+// launchProgram starts a program unless an instance is already running. It returns the
+// program state and whether a new instance was launched.
+func launchProgram(name string) (Program, *ProgramState, bool, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	program, exists := programs[name]
+	if !exists {
+		return program, nil, false, errProgramNotFound
+	}
+
+	state, exists := programStates[name]
+	if exists && state.Running {
+		return program, state, false, nil
+	}
+
+	logFile, err := openProgramLog(name)
+	if err != nil {
+		return program, state, false, fmt.Errorf("failed to open program log: %w", err)
+	}
+
+	cmd := exec.Command(program.Path)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(logFile, "=== failed to start: %v ===\n", err)
+		logFile.Close()
+		return program, state, false, err
+	}
+
+	state = &ProgramState{
+		Running: true,
+		Origin:  OriginStation,
+		PID:     cmd.Process.Pid,
+		Cmd:     cmd,
+	}
+	programStates[name] = state
+
+	// Reap the process when it exits; the monitor notices the PID is gone
+	go waitAndCloseLog(cmd, logFile)
+
+	return program, state, true, nil
+}
+
+// killProgram terminates all tracked instances of a program. It returns the program state
+// and whether anything was running.
+func killProgram(name string) (Program, *ProgramState, bool, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	program, exists := programs[name]
+	if !exists {
+		return program, nil, false, errProgramNotFound
+	}
+
+	state, exists := programStates[name]
+	if !exists {
+		state = &ProgramState{}
+		programStates[name] = state
+	}
+
+	// Check if the process is actually running
+	refreshState(program, state)
+	if !state.Running {
+		return program, state, false, nil
+	}
+
+	// Kill the tracked instances by PID rather than by image name
+	pids := state.ExternalPIDs
+	if state.PID != 0 {
+		pids = append([]int{state.PID}, pids...)
+	}
+	for _, pid := range pids {
+		cmd := exec.Command("taskkill", "/F", "/PID", strconv.Itoa(pid))
+		if err := cmd.Run(); err != nil {
+			refreshState(program, state)
+			return program, state, false, fmt.Errorf("PID %d: %w", pid, err)
+		}
+	}
+
+	// Update the state
+	refreshState(program, state)
+	return program, state, true, nil
+}
@@ -0,0 +1,310 @@
+package programs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+	"github.com/kaireichart/master-thesis-operator-station/sessions"
+)
+
+// Scheduled session actions
+const (
+	ActionLaunch        = "launch"
+	ActionKill          = "kill"
+	ActionLogConditions = "log_conditions" // Record the simulator conditions on the session
+)
+
+// ScheduledAction launches or kills a program, or logs the simulator conditions, at a fixed time after the session start
+type ScheduledAction struct {
+	ID            int                  `json:"id"`
+	Program       string               `json:"program,omitempty"`
+	Action        string               `json:"action"`               // ActionLaunch, ActionKill or ActionLogConditions
+	OffsetMinutes float64              `json:"offsetMinutes"`        // Session time at which the action runs
+	Conditions    *sessions.Conditions `json:"conditions,omitempty"` // Conditions logged by ActionLogConditions
+
+	// Execution state within the active session
+	ExecutedAt *time.Time `json:"executedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// offset returns the session time at which the action runs
+func (a ScheduledAction) offset() time.Duration {
+	return time.Duration(a.OffsetMinutes * float64(time.Minute))
+}
+
+var (
+	schedule      []ScheduledAction
+	scheduleTimer = map[int]*time.Timer{}
+	scheduleMutex = &sync.Mutex{}
+	scheduleFile  string
+	nextActionID  = 1
+)
+
+// initSchedule loads the persisted schedule and arms it for the active session
+func initSchedule() {
+	scheduleFile = filepath.Join("data", "program_schedule.json")
+
+	if data, err := os.ReadFile(scheduleFile); err == nil {
+		if err := json.Unmarshal(data, &schedule); err != nil {
+			log.Printf("Failed to load program schedule: %v", err)
+		}
+	}
+	for _, action := range schedule {
+		nextActionID = max(nextActionID, action.ID+1)
+	}
+
+	sessions.OnStart(armSchedule)
+	sessions.OnEnd(func(sessions.Session) { disarmSchedule() })
+}
+
+// saveSchedule persists the schedule definition.
+// Must be called with scheduleMutex held.
+func saveSchedule() error {
+	if err := os.MkdirAll(filepath.Dir(scheduleFile), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(schedule, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := scheduleFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, scheduleFile)
+}
+
+// armSchedule starts timers for all actions of the schedule relative to the session start.
+// Actions whose time has already passed (e.g. after a restart) are not run.
+func armSchedule(session sessions.Session) {
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+
+	for i := range schedule {
+		schedule[i].ExecutedAt = nil
+		schedule[i].Error = ""
+		armAction(session, schedule[i])
+	}
+}
+
+// armAction starts the timer of one action.
+// Must be called with scheduleMutex held.
+func armAction(session sessions.Session, action ScheduledAction) {
+	if timer, exists := scheduleTimer[action.ID]; exists {
+		timer.Stop()
+		delete(scheduleTimer, action.ID)
+	}
+
+	delay := action.offset() - session.Elapsed(time.Now())
+	if delay < 0 {
+		log.Printf("Skipping scheduled %s of %s at %.1f min: session time already passed", action.Action, action.Program, action.OffsetMinutes)
+		return
+	}
+
+	id := action.ID
+	scheduleTimer[id] = time.AfterFunc(delay, func() {
+		runScheduledAction(session.ID, id)
+	})
+}
+
+// disarmSchedule stops all pending timers
+func disarmSchedule() {
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+
+	for id, timer := range scheduleTimer {
+		timer.Stop()
+		delete(scheduleTimer, id)
+	}
+}
+
+// runScheduledAction executes a scheduled action if its session is still active and logs it as an event
+func runScheduledAction(sessionID, actionID int) {
+	current, ok := sessions.Current()
+	if !ok || current.ID != sessionID {
+		return
+	}
+
+	scheduleMutex.Lock()
+	delete(scheduleTimer, actionID)
+	var action *ScheduledAction
+	for i := range schedule {
+		if schedule[i].ID == actionID {
+			action = &schedule[i]
+			break
+		}
+	}
+	if action == nil {
+		scheduleMutex.Unlock()
+		return
+	}
+	name, kind := action.Program, action.Action
+	var conditions sessions.Conditions
+	if action.Conditions != nil {
+		conditions = *action.Conditions
+	}
+	scheduleMutex.Unlock()
+
+	var acted bool
+	var err error
+	switch kind {
+	case ActionLaunch:
+		_, _, acted, err = launchProgram(name)
+	case ActionKill:
+		_, _, acted, err = killProgram(name)
+	case ActionLogConditions:
+		// LogConditions records its own event
+		_, err = sessions.LogConditions(conditions)
+	}
+
+	now := time.Now()
+	scheduleMutex.Lock()
+	for i := range schedule {
+		if schedule[i].ID == actionID {
+			schedule[i].ExecutedAt = &now
+			if err != nil {
+				schedule[i].Error = err.Error()
+			}
+		}
+	}
+	scheduleMutex.Unlock()
+
+	if err != nil {
+		log.Printf("Scheduled %s of %s failed: %v", kind, name, err)
+		return
+	}
+	if kind == ActionLogConditions {
+		return
+	}
+	if !acted {
+		log.Printf("Scheduled %s of %s had nothing to do", kind, name)
+		return
+	}
+
+	events.LogEvent(events.Event{
+		Type:      "scheduled_" + kind,
+		Program:   name,
+		Timestamp: now,
+	})
+}
+
+// handleSchedule lists (GET) or adds to (POST) the program schedule
+func handleSchedule(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		scheduleMutex.Lock()
+		defer scheduleMutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(schedule)
+	case http.MethodPost:
+		var action ScheduledAction
+		if err := json.NewDecoder(r.Body).Decode(&action); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch action.Action {
+		case ActionLaunch, ActionKill:
+			program, exists := programs[action.Program]
+			if !exists {
+				http.Error(w, "Program not found", http.StatusNotFound)
+				return
+			}
+			if action.Action == ActionKill && !program.CanKill {
+				http.Error(w, "Program is protected from termination", http.StatusForbidden)
+				return
+			}
+			action.Conditions = nil
+		case ActionLogConditions:
+			if action.Conditions == nil || (strings.TrimSpace(action.Conditions.WeatherPreset) == "" && len(action.Conditions.Failures) == 0) {
+				http.Error(w, sessions.ErrConditionsRequired.Error(), http.StatusBadRequest)
+				return
+			}
+			action.Program = ""
+		default:
+			http.Error(w, fmt.Sprintf("Invalid action '%s' (expected %s, %s or %s)", action.Action, ActionLaunch, ActionKill, ActionLogConditions), http.StatusBadRequest)
+			return
+		}
+		if action.OffsetMinutes < 0 {
+			http.Error(w, "offsetMinutes must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		scheduleMutex.Lock()
+		defer scheduleMutex.Unlock()
+
+		action.ID = nextActionID
+		action.ExecutedAt = nil
+		action.Error = ""
+		schedule = append(schedule, action)
+		if err := saveSchedule(); err != nil {
+			schedule = schedule[:len(schedule)-1]
+			http.Error(w, fmt.Sprintf("Failed to save schedule: %v", err), http.StatusInternalServerError)
+			return
+		}
+		nextActionID++
+
+		if current, ok := sessions.Current(); ok {
+			armAction(current, action)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(action)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleDeleteScheduledAction removes an action from the schedule
+func handleDeleteScheduledAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, "Invalid action ID", http.StatusBadRequest)
+		return
+	}
+
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+
+	for i := range schedule {
+		if schedule[i].ID != id {
+			continue
+		}
+
+		previous := append([]ScheduledAction{}, schedule...)
+		schedule = append(schedule[:i], schedule[i+1:]...)
+		if err := saveSchedule(); err != nil {
+			schedule = previous
+			http.Error(w, fmt.Sprintf("Failed to save schedule: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if timer, exists := scheduleTimer[id]; exists {
+			timer.Stop()
+			delete(scheduleTimer, id)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+		return
+	}
+
+	http.Error(w, "Scheduled action not found", http.StatusNotFound)
+}
@@ -8,7 +8,16 @@ type Program struct {
 	CanKill bool   `json:"canKill"`
 }
 
+// Origins of a running program
+const (
+	OriginStation  = "station"  // Started by the operator station
+	OriginExternal = "external" // Started outside the operator station
+)
+
 type ProgramState struct {
-	Running bool      `json:"running"`
-	Cmd     *exec.Cmd `json:"-"`
+	Running      bool      `json:"running"`
+	Origin       string    `json:"origin,omitempty"`       // OriginStation or OriginExternal while running
+	PID          int       `json:"pid,omitempty"`          // PID of the instance started by the station
+	ExternalPIDs []int     `json:"externalPids,omitempty"` // PIDs of same-named instances not started by the station
+	Cmd          *exec.Cmd `json:"-"`
 }
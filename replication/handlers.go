@@ -0,0 +1,75 @@
+package replication
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+	"github.com/kaireichart/master-thesis-operator-station/sessions"
+)
+
+func SetupHandlers() {
+	http.HandleFunc("GET /replication", handleGetStatus)
+	http.HandleFunc("PUT /replication", handleSetTarget)
+
+	// Receiving side, called by the primary station on a backup
+	http.HandleFunc("POST /replication/events", handleReplicatedEvent)
+	http.HandleFunc("POST /replication/sessions", handleReplicatedSessions)
+}
+
+func handleGetStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetStatus())
+}
+
+func handleSetTarget(w http.ResponseWriter, r *http.Request) {
+	var request Config
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := SetTarget(request.TargetURL)
+	if err == ErrInvalidTargetURL {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetStatus())
+}
+
+// handleReplicatedEvent records an event of the primary station
+func handleReplicatedEvent(w http.ResponseWriter, r *http.Request) {
+	var event events.Event
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if event.Type == "" || event.Timestamp.IsZero() {
+		http.Error(w, "type and timestamp are required", http.StatusBadRequest)
+		return
+	}
+
+	events.RecordReplica(event)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleReplicatedSessions replaces the sessions with those of the primary station
+func handleReplicatedSessions(w http.ResponseWriter, r *http.Request) {
+	var replica []sessions.Session
+	if err := json.NewDecoder(r.Body).Decode(&replica); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := sessions.ReplaceWithReplica(replica); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,226 @@
+package replication
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+	"github.com/kaireichart/master-thesis-operator-station/sessions"
+)
+
+// ErrInvalidTargetURL is returned when setting a replication target that is not an http(s) URL
+var ErrInvalidTargetURL = errors.New("target URL must be an http or https URL")
+
+const (
+	maxPending     = 10000 // Changes kept while the backup is unreachable; the oldest are dropped beyond
+	retryInterval  = 5 * time.Second
+	requestTimeout = 5 * time.Second
+)
+
+// Config is the replication setup of this station, kept in data/replication.json
+type Config struct {
+	TargetURL string `json:"target_url"` // Base URL of the backup station, e.g. http://192.168.1.20:8080; empty disables replication
+}
+
+// Status describes the replication to the backup station
+type Status struct {
+	TargetURL   string     `json:"target_url"`
+	Pending     int        `json:"pending"` // Changes not delivered yet
+	Dropped     int        `json:"dropped"` // Changes dropped because the backup was unreachable for too long
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"` // Error of the last failed delivery, cleared on success
+}
+
+// change is an event or the session list, delivered to the backup in the order they happened
+type change struct {
+	seq  int
+	path string
+	body []byte
+}
+
+var (
+	mutex       = &sync.Mutex{}
+	configFile  string
+	config      Config
+	pending     []change
+	nextSeq     int
+	dropped     int
+	lastSuccess *time.Time
+	lastError   string
+	wake        = make(chan struct{}, 1)
+	client      = &http.Client{Timeout: requestTimeout}
+)
+
+func Init() {
+	configFile = filepath.Join("data", "replication.json")
+
+	if data, err := os.ReadFile(configFile); err == nil {
+		if err := json.Unmarshal(data, &config); err != nil {
+			log.Printf("Failed to load replication configuration: %v", err)
+		}
+	}
+	if config.TargetURL != "" {
+		log.Printf("Replicating events and sessions to %s", config.TargetURL)
+		// The backup may have missed session changes while this station was down
+		enqueue("/replication/sessions", sessions.GetSessions())
+	}
+
+	events.OnLog(replicateEvent)
+	go deliver()
+}
+
+// GetStatus returns the state of the replication
+func GetStatus() Status {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return Status{
+		TargetURL:   config.TargetURL,
+		Pending:     len(pending),
+		Dropped:     dropped,
+		LastSuccess: lastSuccess,
+		LastError:   lastError,
+	}
+}
+
+// SetTarget changes the backup station replicated to; an empty URL disables replication. Changes not
+// delivered to the previous target are discarded, and the new target receives all sessions.
+func SetTarget(targetURL string) error {
+	targetURL = strings.TrimRight(strings.TrimSpace(targetURL), "/")
+	if targetURL != "" {
+		parsed, err := url.Parse(targetURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return ErrInvalidTargetURL
+		}
+	}
+
+	mutex.Lock()
+	previous := config
+	config.TargetURL = targetURL
+	if err := saveConfig(); err != nil {
+		config = previous
+		mutex.Unlock()
+		return err
+	}
+	pending = nil
+	lastError = ""
+	mutex.Unlock()
+
+	if targetURL != "" {
+		enqueue("/replication/sessions", sessions.GetSessions())
+	}
+	events.LogEvent(events.Event{
+		Type:      "replication_target_set",
+		Program:   "Replication",
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// replicateEvent queues a logged event for the backup, followed by the sessions if it changed them
+func replicateEvent(event events.Event) {
+	enqueue("/replication/events", event)
+	if strings.HasPrefix(event.Type, "session_") || event.Type == "conditions_logged" {
+		enqueue("/replication/sessions", sessions.GetSessions())
+	}
+}
+
+// enqueue queues a change for delivery if replication is enabled
+func enqueue(path string, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode change for replication: %v", err)
+		return
+	}
+
+	mutex.Lock()
+	if config.TargetURL == "" {
+		mutex.Unlock()
+		return
+	}
+	if len(pending) >= maxPending {
+		pending = pending[1:]
+		dropped++
+	}
+	nextSeq++
+	pending = append(pending, change{seq: nextSeq, path: path, body: body})
+	mutex.Unlock()
+
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends the queued changes to the backup one by one, retrying a failed change until it is
+// delivered so the backup receives them in order
+func deliver() {
+	for {
+		mutex.Lock()
+		if len(pending) == 0 {
+			mutex.Unlock()
+			<-wake
+			continue
+		}
+		next, target := pending[0], config.TargetURL
+		mutex.Unlock()
+
+		err := post(target+next.path, next.body)
+
+		mutex.Lock()
+		if err != nil {
+			lastError = err.Error()
+			mutex.Unlock()
+			time.Sleep(retryInterval)
+			continue
+		}
+		// The queue may have been cleared or trimmed meanwhile
+		if len(pending) > 0 && pending[0].seq == next.seq {
+			pending = pending[1:]
+		}
+		now := time.Now()
+		lastSuccess = &now
+		lastError = ""
+		mutex.Unlock()
+	}
+}
+
+// post sends one change to the backup station
+func post(target string, body []byte) error {
+	response, err := client.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("backup station responded with %s", response.Status)
+	}
+	return nil
+}
+
+func saveConfig() error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := configFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write replication configuration: %w", err)
+	}
+	if err := os.Rename(tempFile, configFile); err != nil {
+		return fmt.Errorf("failed to replace replication configuration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+	"github.com/kaireichart/master-thesis-operator-station/gps"
+	"github.com/kaireichart/master-thesis-operator-station/sessions"
+)
+
+// methods maps the JSON-RPC method names to their implementation
+var methods map[string]method
+
+func init() {
+	methods = map[string]method{
+		"session.list":          sessionList,
+		"session.current":       sessionCurrent,
+		"session.start":         sessionStart,
+		"session.end":           sessionEnd,
+		"session.pause":         sessionPause,
+		"session.resume":        sessionResume,
+		"session.logConditions": sessionLogConditions,
+
+		"event.list": eventList,
+		"event.log":  eventLog,
+
+		"gps.position":             gpsPosition,
+		"gps.config":               gpsConfig,
+		"gps.simulatorStatus":      gpsSimulatorStatus,
+		"gps.setTargetIP":          gpsSetTargetIP,
+		"gps.setDistanceThreshold": gpsSetDistanceThreshold,
+		"gps.setSending":           gpsSetSending,
+
+		"rpc.methods": listMethods,
+	}
+}
+
+// listMethods returns the names of all methods
+func listMethods(json.RawMessage) (interface{}, error) {
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Sessions
+
+func sessionList(json.RawMessage) (interface{}, error) {
+	return sessions.GetSessions(), nil
+}
+
+// sessionCurrent returns the active session, or null if none is active
+func sessionCurrent(json.RawMessage) (interface{}, error) {
+	current, ok := sessions.Current()
+	if !ok {
+		return json.RawMessage("null"), nil
+	}
+	return current, nil
+}
+
+func sessionStart(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ParticipantID string `json:"participantId"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	session, err := sessions.Start(p.ParticipantID)
+	switch {
+	case errors.Is(err, sessions.ErrParticipantRequired):
+		return nil, invalidParams("%v", err)
+	case errors.Is(err, sessions.ErrSessionActive):
+		return nil, stationError(err)
+	case err != nil:
+		return nil, err
+	}
+	return session, nil
+}
+
+func sessionEnd(json.RawMessage) (interface{}, error) {
+	session, err := sessions.End()
+	if errors.Is(err, sessions.ErrNoActiveSession) {
+		return nil, stationError(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func sessionPause(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Reason string `json:"reason"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	session, err := sessions.Pause(p.Reason)
+	if errors.Is(err, sessions.ErrNoActiveSession) || errors.Is(err, sessions.ErrSessionPaused) {
+		return nil, stationError(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func sessionResume(json.RawMessage) (interface{}, error) {
+	session, err := sessions.Resume()
+	if errors.Is(err, sessions.ErrNoActiveSession) || errors.Is(err, sessions.ErrSessionNotPaused) {
+		return nil, stationError(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func sessionLogConditions(params json.RawMessage) (interface{}, error) {
+	var conditions sessions.Conditions
+	if err := decodeParams(params, &conditions); err != nil {
+		return nil, err
+	}
+
+	logged, err := sessions.LogConditions(conditions)
+	switch {
+	case errors.Is(err, sessions.ErrConditionsRequired):
+		return nil, invalidParams("%v", err)
+	case errors.Is(err, sessions.ErrNoActiveSession):
+		return nil, stationError(err)
+	case err != nil:
+		return nil, err
+	}
+	return logged, nil
+}
+
+// Events
+
+func eventList(json.RawMessage) (interface{}, error) {
+	return events.GetEvents(), nil
+}
+
+// eventLog records an event, e.g. a trial boundary marked by the experiment script
+func eventLog(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Type    string `json:"type"`
+		Program string `json:"program"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(p.Type) == "" {
+		return nil, invalidParams("type is required")
+	}
+
+	event := events.Event{
+		Type:      p.Type,
+		Program:   p.Program,
+		Timestamp: time.Now(),
+	}
+	events.LogEvent(event)
+	return event, nil
+}
+
+// GPS
+
+// gpsPosition returns the last received position, or null before the first packet
+func gpsPosition(json.RawMessage) (interface{}, error) {
+	position := gps.GetCurrentPosition()
+	if position == nil {
+		return json.RawMessage("null"), nil
+	}
+	return position, nil
+}
+
+func gpsConfig(json.RawMessage) (interface{}, error) {
+	return gps.GetConfig(), nil
+}
+
+func gpsSimulatorStatus(json.RawMessage) (interface{}, error) {
+	return gps.GetSimulatorStatus(), nil
+}
+
+func gpsSetTargetIP(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		TargetIP string `json:"targetIp"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := gps.SetTargetIP(p.TargetIP); err != nil {
+		return nil, invalidParams("%v", err)
+	}
+	return gpsConfig(nil)
+}
+
+func gpsSetDistanceThreshold(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		DistanceThreshold float64 `json:"distanceThreshold"` // Nautical miles
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if err := gps.SetDistanceThreshold(p.DistanceThreshold); err != nil {
+		return nil, invalidParams("%v", err)
+	}
+	return gpsConfig(nil)
+}
+
+func gpsSetSending(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Sending *bool `json:"sending"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Sending == nil {
+		return nil, invalidParams("sending is required")
+	}
+	gps.SetSending(*p.Sending)
+	return gpsConfig(nil)
+}
@@ -0,0 +1,158 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+	codeStationError   = -32000 // The station refused the call in its current state, e.g. a session is already active
+)
+
+// request is a JSON-RPC 2.0 request; requests without an ID are notifications and get no response
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// response is a JSON-RPC 2.0 response carrying either a result or an error
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Error is a JSON-RPC 2.0 error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// invalidParams returns the error for parameters that are missing or malformed
+func invalidParams(format string, args ...interface{}) *Error {
+	return &Error{Code: codeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// stationError returns the error for calls the station refuses in its current state
+func stationError(err error) *Error {
+	return &Error{Code: codeStationError, Message: err.Error()}
+}
+
+// method handles one JSON-RPC method; params is nil when the request has none
+type method func(params json.RawMessage) (interface{}, error)
+
+// decodeParams decodes the named parameters of a call into v
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return invalidParams("invalid params: %v", err)
+	}
+	return nil
+}
+
+// call runs a single request, returning nil for notifications
+func call(req request) *response {
+	resp := &response{JSONRPC: "2.0", ID: req.ID}
+	if len(resp.ID) == 0 {
+		resp.ID = json.RawMessage("null")
+	}
+
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		resp.Error = &Error{Code: codeInvalidRequest, Message: "invalid request"}
+		return resp
+	}
+
+	handler, exists := methods[req.Method]
+	if !exists {
+		resp.Error = &Error{Code: codeMethodNotFound, Message: fmt.Sprintf("method '%s' not found", req.Method)}
+	} else if result, err := handler(req.Params); err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			log.Printf("RPC %s failed: %v", req.Method, err)
+			rpcErr = &Error{Code: codeInternalError, Message: err.Error()}
+		}
+		resp.Error = rpcErr
+	} else {
+		if result == nil {
+			result = struct{}{}
+		}
+		resp.Result = result
+	}
+
+	if len(req.ID) == 0 {
+		return nil
+	}
+	return resp
+}
+
+// handleRPC serves JSON-RPC 2.0 calls, single or batched, posted as JSON
+func handleRPC(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, response{JSONRPC: "2.0", Error: &Error{Code: codeParseError, Message: "parse error"}, ID: json.RawMessage("null")})
+		return
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []request
+		if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+			writeJSON(w, response{JSONRPC: "2.0", Error: &Error{Code: codeInvalidRequest, Message: "invalid request"}, ID: json.RawMessage("null")})
+			return
+		}
+
+		responses := []*response{}
+		for _, req := range batch {
+			if resp := call(req); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, responses)
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, response{JSONRPC: "2.0", Error: &Error{Code: codeInvalidRequest, Message: "invalid request"}, ID: json.RawMessage("null")})
+		return
+	}
+	resp := call(req)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+// writeJSON writes a JSON-RPC response body; protocol errors are reported in the body, not the status
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// SetupHandlers registers the JSON-RPC endpoint
+func SetupHandlers() {
+	http.HandleFunc("POST /rpc", handleRPC)
+}
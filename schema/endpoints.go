@@ -0,0 +1,506 @@
+package schema
+
+import (
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+	"github.com/kaireichart/master-thesis-operator-station/events"
+	"github.com/kaireichart/master-thesis-operator-station/gps"
+	"github.com/kaireichart/master-thesis-operator-station/mental_rotation"
+	"github.com/kaireichart/master-thesis-operator-station/programs"
+)
+
+// Tags of the documented endpoints, one per module
+const (
+	tagDataAnalysis   = "data-analysis"
+	tagEvents         = "events"
+	tagGPS            = "gps"
+	tagPrograms       = "programs"
+	tagMentalRotation = "mental-rotation"
+)
+
+// Content types of responses that are not JSON
+const (
+	contentHTML   = "text/html"
+	contentText   = "text/plain"
+	contentCSV    = "text/csv"
+	contentZIP    = "application/zip"
+	contentNDJSON = "application/x-ndjson"
+	contentSSE    = "text/event-stream"
+)
+
+// parameter is a query parameter of an endpoint, or a field of a form it accepts
+type parameter struct {
+	Name        string
+	Type        string // JSON Schema type, or "file" for an uploaded file
+	Description string
+}
+
+// endpoint documents one operation of the HTTP API. Path parameters are taken from the {name}
+// segments of the path.
+type endpoint struct {
+	Method   string
+	Path     string
+	Tag      string
+	Summary  string
+	Query    []parameter
+	Form     []parameter // Fields of a form body; multipart if one is a file
+	RawBody  string      // Content type of a body read as raw bytes
+	Request  interface{} // Value of the JSON body type
+	Response interface{} // Value of the JSON response type, nil for an empty or non-JSON response
+	Produces string      // Content type of a non-JSON response
+	Status   int         // Status of a successful response, 200 if zero
+}
+
+// Request bodies the handlers decode into anonymous structs
+type (
+	titleRequest struct {
+		NewTitle string `json:"new_title"`
+	}
+	trimRequest struct {
+		NewTitle  string  `json:"new_title"`
+		StartTime float64 `json:"start_time"`
+		EndTime   float64 `json:"end_time"`
+	}
+	resampleRequest struct {
+		NewTitle string  `json:"new_title"`
+		RateHz   float64 `json:"rate_hz"`
+	}
+	alignRequest struct {
+		NewTitle    string `json:"new_title"`
+		MarkerID    int    `json:"marker_id"`
+		MarkerLabel string `json:"marker_label"`
+	}
+	trimMarkerRequest struct {
+		Type  string  `json:"type"` // "trim_start" or "trim_end"
+		Time  float64 `json:"time"`
+		Label string  `json:"label"`
+	}
+	importURLRequest struct {
+		URL     string `json:"url"`
+		Partial bool   `json:"partial"`
+		Force   bool   `json:"force"`
+	}
+	importLogbookRequest struct {
+		Path    string `json:"path"`
+		Partial bool   `json:"partial"`
+		Force   bool   `json:"force"`
+	}
+	participantRequest struct {
+		ParticipantID string `json:"participant_id"`
+		Condition     string `json:"condition"`
+	}
+	reviewRequest struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	gpsGateRequest struct {
+		ReferencePointID int `json:"reference_point_id"`
+	}
+	manualEventRequest struct {
+		Type    string `json:"type"`
+		Program string `json:"program"`
+	}
+	participantSelection struct {
+		ParticipantIDs []string `json:"participantIds"`
+	}
+	completionRequest struct {
+		ParticipantID string `json:"participantId"`
+	}
+)
+
+// Responses the handlers encode from maps, where their fields are part of the contract
+type (
+	uploadResponse struct {
+		Status  string                    `json:"status"` // "queued"
+		Message string                    `json:"message"`
+		Jobs    []data_analysis.ImportJob `json:"jobs"`
+	}
+	windCorrectedResponse struct {
+		FlightID   int                                               `json:"flight_id"`
+		Weather    *data_analysis.FlightWeather                      `json:"weather"`
+		Statistics map[string]*data_analysis.WindCorrectedStatistics `json:"statistics"`
+		Units      data_analysis.UnitSystem                          `json:"units"`
+	}
+	crossCorrelationResponse struct {
+		FlightID      int                                                `json:"flight_id"`
+		Engine        int                                                `json:"engine"`
+		RateHz        float64                                            `json:"rate_hz"`
+		MaxLagSeconds float64                                            `json:"max_lag_seconds"`
+		Aircraft      map[string]*data_analysis.AircraftCrossCorrelation `json:"aircraft"`
+	}
+	trackingErrorResponse struct {
+		FlightID      int                                            `json:"flight_id"`
+		Target        float64                                        `json:"target,omitempty"`  // With a constant target
+		Profile       map[string]interface{}                         `json:"profile,omitempty"` // ID and name of the reference profile
+		Alignment     *data_analysis.FlightAlignment                 `json:"alignment"`
+		Channel       string                                         `json:"channel"`
+		WindowSeconds float64                                        `json:"window_seconds"`
+		Aircraft      map[string]data_analysis.AircraftTrackingError `json:"aircraft"`
+	}
+	oscillationMarkersResponse struct {
+		Status   string                                 `json:"status"`
+		Message  string                                 `json:"message"`
+		Created  int                                    `json:"created"` // Markers, a start and an end per oscillation
+		Aircraft map[string][]data_analysis.Oscillation `json:"aircraft"`
+	}
+	archiveResultsResponse struct {
+		Status   string `json:"status"`
+		Archived int    `json:"archived"`
+	}
+	deleteResultsResponse struct {
+		Status  string `json:"status"`
+		Deleted int    `json:"deleted"`
+	}
+)
+
+// object is a JSON object response without a fixed type, e.g. a status message
+var object = map[string]interface{}{}
+
+// Parameters shared by several endpoints
+var (
+	alignmentParameters = []parameter{
+		{"align_marker", "integer", "ID of the marker whose time becomes 0"},
+		{"align_label", "string", "Label of the marker whose time becomes 0"},
+	}
+	allAircraftParameter = parameter{"aircraft", "string", "\"all\" to include every aircraft, not only the target aircraft"}
+	exportParameters     = []parameter{
+		{"flight_ids", "string", "Comma-separated IDs of the flights to export"},
+		{"review_status", "string", "Comma-separated review statuses of the flights to export, or \"all\"; all but rejected flights by default"},
+	}
+	varianceParameters = []parameter{
+		{"signal", "string", "\"airspeed\" (default), \"altitude\" or \"indicated_altitude\""},
+		{"window", "number", "Length of the sliding window in seconds, 10 if omitted"},
+		allAircraftParameter,
+	}
+	programParameter = parameter{"name", "string", "Name of the program"}
+	formatParameter  = parameter{"format", "string", "\"json\" for JSON instead of HTML"}
+)
+
+// withParameters returns the parameter lists joined into a new list
+func withParameters(lists ...[]parameter) []parameter {
+	var joined []parameter
+	for _, list := range lists {
+		joined = append(joined, list...)
+	}
+	return joined
+}
+
+// endpoints are the documented operations of the data analysis, events, GPS, program and mental rotation
+// modules, in the order they are registered
+var endpoints = []endpoint{
+	// Data analysis
+	{Method: "GET", Path: "/data-analysis", Tag: tagDataAnalysis, Summary: "Data analysis page", Produces: contentHTML},
+	{Method: "POST", Path: "/data-analysis/upload", Tag: tagDataAnalysis, Summary: "Queue Sky Dolly databases or CSV recordings for import",
+		Form: []parameter{
+			{"database", "file", "Recording to import; repeat for several files"},
+			{"partial", "boolean", "Import the readable part of a damaged recording"},
+			{"position_only", "boolean", "Import only the position table"},
+			{"force", "boolean", "Import flights that were imported before"},
+			{"thin_every_n", "integer", "Keep every nth sample"},
+			{"thin_min_delta_ms", "integer", "Keep samples at least this many milliseconds apart"},
+			{"skip_tables", "string", "Comma-separated tables not to import"},
+		},
+		Response: uploadResponse{}, Status: 202},
+	{Method: "POST", Path: "/data-analysis/uploads", Tag: tagDataAnalysis, Summary: "Start a chunked upload",
+		Request: data_analysis.CreateChunkedUploadRequest{}, Response: data_analysis.ChunkedUpload{}, Status: 201},
+	{Method: "GET", Path: "/data-analysis/uploads/{uploadId}", Tag: tagDataAnalysis, Summary: "Progress and result of a chunked upload",
+		Response: data_analysis.ChunkedUpload{}},
+	{Method: "PUT", Path: "/data-analysis/uploads/{uploadId}", Tag: tagDataAnalysis, Summary: "Append a chunk to an upload",
+		Query:   []parameter{{"offset", "integer", "Bytes received before this chunk"}},
+		RawBody: "application/octet-stream", Response: data_analysis.ChunkedUpload{}},
+	{Method: "DELETE", Path: "/data-analysis/uploads/{uploadId}", Tag: tagDataAnalysis, Summary: "Cancel a chunked upload", Response: object},
+	{Method: "GET", Path: "/data-analysis/import-status", Tag: tagDataAnalysis, Summary: "State of the import jobs",
+		Query:    []parameter{{"ids", "string", "Comma-separated IDs of the jobs to report"}},
+		Response: data_analysis.ImportStatus{}},
+	{Method: "GET", Path: "/data-analysis/import-status/{jobId}", Tag: tagDataAnalysis, Summary: "State of an import job",
+		Response: data_analysis.ImportJob{}},
+	{Method: "POST", Path: "/data-analysis/import-status/{jobId}/cancel", Tag: tagDataAnalysis, Summary: "Cancel a queued or running import job",
+		Response: data_analysis.ImportJob{}},
+	{Method: "POST", Path: "/data-analysis/import-url", Tag: tagDataAnalysis, Summary: "Import a recording from a URL",
+		Request: importURLRequest{}, Response: data_analysis.UploadFileResult{}},
+	{Method: "GET", Path: "/data-analysis/logbooks", Tag: tagDataAnalysis, Summary: "Sky Dolly logbooks in the configured folders",
+		Response: []data_analysis.Logbook{}},
+	{Method: "POST", Path: "/data-analysis/logbooks/import", Tag: tagDataAnalysis, Summary: "Import a Sky Dolly logbook",
+		Request: importLogbookRequest{}, Response: data_analysis.UploadFileResult{}},
+	{Method: "GET", Path: "/data-analysis/settings/logbooks", Tag: tagDataAnalysis, Summary: "Logbook folders",
+		Response: data_analysis.LogbookSettings{}},
+	{Method: "GET", Path: "/data-analysis/admin/config", Tag: tagDataAnalysis, Summary: "Active analysis configuration",
+		Response: data_analysis.AnalysisConfig{}},
+	{Method: "PUT", Path: "/data-analysis/settings/logbooks", Tag: tagDataAnalysis, Summary: "Set the logbook folders",
+		Request: data_analysis.LogbookSettings{}, Response: data_analysis.LogbookSettings{}},
+	{Method: "GET", Path: "/data-analysis/flights", Tag: tagDataAnalysis, Summary: "Imported flights",
+		Query:    []parameter{{"archived", "boolean", "List the archived flights instead"}},
+		Response: []data_analysis.Flight{}},
+	{Method: "GET", Path: "/data-analysis/export", Tag: tagDataAnalysis, Summary: "CSV export of several flights as a ZIP archive",
+		Query: exportParameters, Produces: contentZIP},
+	{Method: "GET", Path: "/data-analysis/export-statistics", Tag: tagDataAnalysis, Summary: "Statistics of several flights as CSV",
+		Query: withParameters(exportParameters, []parameter{allAircraftParameter}), Produces: contentCSV},
+	{Method: "GET", Path: "/data-analysis/export-long", Tag: tagDataAnalysis, Summary: "Samples of several flights as one long-format CSV",
+		Query: withParameters(exportParameters, []parameter{
+			allAircraftParameter,
+			{"variables", "string", "Comma-separated variables to export, all if omitted"},
+		}), Produces: contentCSV},
+	{Method: "GET", Path: "/data-analysis/backup", Tag: tagDataAnalysis, Summary: "Snapshot of the analysis database",
+		Produces: "application/vnd.sqlite3"},
+	{Method: "POST", Path: "/data-analysis/admin/maintenance", Tag: tagDataAnalysis, Summary: "Check and compact the analysis database",
+		Response: data_analysis.MaintenanceResult{}},
+	{Method: "POST", Path: "/data-analysis/metrics/recompute", Tag: tagDataAnalysis, Summary: "Recompute the stored metrics of all flights",
+		Response: object, Status: 202},
+	{Method: "GET", Path: "/data-analysis/variance", Tag: tagDataAnalysis, Summary: "Sliding-window variance of the airspeed or altitude of a flight",
+		Query:    withParameters([]parameter{{"flightId", "integer", "ID of the flight"}}, varianceParameters),
+		Response: data_analysis.VarianceReport{}},
+	{Method: "GET", Path: "/data-analysis/aircraft", Tag: tagDataAnalysis, Summary: "Aircraft of a flight",
+		Query:    []parameter{{"flightId", "integer", "ID of the flight"}},
+		Response: []data_analysis.AircraftSummary{}},
+	{Method: "GET", Path: "/data-analysis/track.geojson", Tag: tagDataAnalysis, Summary: "Ground track of a flight as GeoJSON",
+		Query: []parameter{
+			{"flightId", "integer", "ID of the flight"},
+			{"aircraft", "string", "Aircraft whose position the markers are placed at"},
+		},
+		Response: data_analysis.GeoJSONFeatureCollection{}, Produces: "application/geo+json"},
+	{Method: "GET", Path: "/data-analysis/api/health", Tag: tagDataAnalysis, Summary: "Health check", Response: object},
+	{Method: "GET", Path: "/data-analysis/api/stats", Tag: tagDataAnalysis, Summary: "Row counts of the analysis database", Response: object},
+	{Method: "GET", Path: "/data-analysis/settings/distance-markers", Tag: tagDataAnalysis, Summary: "Distance marker settings",
+		Response: data_analysis.DistanceMarkerSettings{}},
+	{Method: "PUT", Path: "/data-analysis/settings/distance-markers", Tag: tagDataAnalysis, Summary: "Set the distance marker settings",
+		Request: data_analysis.DistanceMarkerSettings{}, Response: data_analysis.DistanceMarkerSettings{}},
+	{Method: "GET", Path: "/data-analysis/settings/gps-gate", Tag: tagDataAnalysis, Summary: "Reference point of the GPS distance gate",
+		Response: data_analysis.GPSGateSettings{}},
+	{Method: "PUT", Path: "/data-analysis/settings/gps-gate", Tag: tagDataAnalysis, Summary: "Center the GPS distance gate on a library point",
+		Request: gpsGateRequest{}, Response: data_analysis.GPSGateSettings{}},
+	{Method: "GET", Path: "/data-analysis/reference-points", Tag: tagDataAnalysis, Summary: "Reference point library",
+		Response: []data_analysis.ReferencePoint{}},
+	{Method: "POST", Path: "/data-analysis/reference-points", Tag: tagDataAnalysis, Summary: "Add a reference point",
+		Request: data_analysis.ReferencePoint{}, Response: data_analysis.ReferencePoint{}, Status: 201},
+	{Method: "PUT", Path: "/data-analysis/reference-points/{pointId}", Tag: tagDataAnalysis, Summary: "Update a reference point",
+		Request: data_analysis.ReferencePoint{}, Response: data_analysis.ReferencePoint{}},
+	{Method: "DELETE", Path: "/data-analysis/reference-points/{pointId}", Tag: tagDataAnalysis, Summary: "Delete a reference point", Response: object},
+	{Method: "GET", Path: "/data-analysis/reference-profiles", Tag: tagDataAnalysis, Summary: "Uploaded reference profiles",
+		Response: []data_analysis.ReferenceProfile{}},
+	{Method: "POST", Path: "/data-analysis/reference-profiles", Tag: tagDataAnalysis, Summary: "Upload a reference profile",
+		Request: data_analysis.ReferenceProfile{}, Response: data_analysis.ReferenceProfile{}, Status: 201},
+	{Method: "DELETE", Path: "/data-analysis/reference-profiles/{profileId}", Tag: tagDataAnalysis, Summary: "Delete a reference profile", Response: object},
+	{Method: "GET", Path: "/data-analysis/runways", Tag: tagDataAnalysis, Summary: "Runways approaches are assessed against",
+		Response: []data_analysis.Runway{}},
+	{Method: "POST", Path: "/data-analysis/runways", Tag: tagDataAnalysis, Summary: "Add a runway",
+		Request: data_analysis.Runway{}, Response: data_analysis.Runway{}, Status: 201},
+	{Method: "DELETE", Path: "/data-analysis/runways/{runwayId}", Tag: tagDataAnalysis, Summary: "Delete a runway", Response: object},
+	{Method: "GET", Path: "/data-analysis/participants", Tag: tagDataAnalysis, Summary: "Participant registry flights are attributed to",
+		Response: []data_analysis.Participant{}},
+	{Method: "POST", Path: "/data-analysis/participants", Tag: tagDataAnalysis, Summary: "Register a participant",
+		Request: data_analysis.Participant{}, Response: data_analysis.Participant{}, Status: 201},
+	{Method: "GET", Path: "/data-analysis/participants/{participantId}", Tag: tagDataAnalysis, Summary: "A participant and its flights",
+		Response: data_analysis.Participant{}},
+	{Method: "PUT", Path: "/data-analysis/participants/{participantId}", Tag: tagDataAnalysis, Summary: "Change the pseudonym, group and notes of a participant",
+		Request: data_analysis.Participant{}, Response: data_analysis.Participant{}},
+	{Method: "DELETE", Path: "/data-analysis/participants/{participantId}", Tag: tagDataAnalysis, Summary: "Delete a participant without assigned flights", Response: object},
+	{Method: "GET", Path: "/data-analysis/settings/distance-marker-waypoints", Tag: tagDataAnalysis, Summary: "Waypoints distance markers are measured from",
+		Response: []data_analysis.DistanceMarkerWaypoint{}},
+	{Method: "POST", Path: "/data-analysis/settings/distance-marker-waypoints", Tag: tagDataAnalysis, Summary: "Add a distance marker waypoint",
+		Request: data_analysis.DistanceMarkerWaypoint{}, Response: data_analysis.DistanceMarkerWaypoint{}, Status: 201},
+	{Method: "PUT", Path: "/data-analysis/settings/distance-marker-waypoints/{waypointId}", Tag: tagDataAnalysis, Summary: "Update a distance marker waypoint",
+		Request: data_analysis.DistanceMarkerWaypoint{}, Response: data_analysis.DistanceMarkerWaypoint{}},
+	{Method: "DELETE", Path: "/data-analysis/settings/distance-marker-waypoints/{waypointId}", Tag: tagDataAnalysis, Summary: "Delete a distance marker waypoint", Response: object},
+
+	// Data analysis, per flight
+	{Method: "GET", Path: "/data-analysis/flights/{id}", Tag: tagDataAnalysis, Summary: "Flight data of all aircraft",
+		Query:    withParameters([]parameter{{"max_points", "integer", "Downsample each series to at most this many points"}}, alignmentParameters),
+		Response: data_analysis.FlightData{}},
+	{Method: "DELETE", Path: "/data-analysis/flights/{id}", Tag: tagDataAnalysis, Summary: "Archive a flight", Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/restore", Tag: tagDataAnalysis, Summary: "Restore an archived flight", Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/purge", Tag: tagDataAnalysis, Summary: "Permanently delete an archived flight", Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/duplicate", Tag: tagDataAnalysis, Summary: "Copy a flight",
+		Request: titleRequest{}, Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/trim", Tag: tagDataAnalysis, Summary: "Copy a time range of a flight",
+		Request: trimRequest{}, Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/resample", Tag: tagDataAnalysis, Summary: "Copy a flight resampled to a fixed rate",
+		Request: resampleRequest{}, Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/align", Tag: tagDataAnalysis, Summary: "Copy a flight with its times counted from a marker",
+		Request: alignRequest{}, Response: object},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/positions", Tag: tagDataAnalysis, Summary: "Page of the position samples of an aircraft",
+		Query: []parameter{
+			{"offset", "integer", "Index of the first sample"},
+			{"limit", "integer", "Number of samples"},
+			{"aircraft", "string", "Aircraft label, the user aircraft by default"},
+		},
+		Response: data_analysis.PositionPage{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/positions.ndjson", Tag: tagDataAnalysis, Summary: "All position samples, one JSON object per line",
+		Produces: contentNDJSON},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/statistics", Tag: tagDataAnalysis, Summary: "Statistics per aircraft",
+		Query: []parameter{
+			allAircraftParameter,
+			{"runway", "integer", "ID of the runway to include the glidepath deviation for instead of the configured target runway"},
+		},
+		Response: map[string]*data_analysis.FlightStatistics{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/wind-corrected-statistics", Tag: tagDataAnalysis, Summary: "Airspeed statistics corrected for the wind",
+		Response: windCorrectedResponse{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/cross-correlation", Tag: tagDataAnalysis, Summary: "Lagged cross-correlation of the throttle with airspeed and altitude",
+		Query: []parameter{
+			{"engine", "integer", "Engine whose throttle is correlated"},
+			{"rate_hz", "number", "Resampling rate"},
+			{"max_lag", "number", "Largest lag in seconds"},
+		},
+		Response: crossCorrelationResponse{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/diff", Tag: tagDataAnalysis, Summary: "Sample-by-sample comparison with another flight",
+		Query: []parameter{
+			{"other", "integer", "ID of the flight to compare with"},
+			{"start_time", "number", "Start of the compared range in seconds"},
+			{"end_time", "number", "End of the compared range in seconds"},
+			{"time_tolerance", "number", "Largest time difference of matched samples in seconds"},
+			{"value_tolerance", "number", "Largest difference of equal values"},
+			{"distance_tolerance", "number", "Largest distance of equal positions in meters"},
+		},
+		Response: data_analysis.FlightDiff{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/tracking-error", Tag: tagDataAnalysis, Summary: "RMSE, MAE and bias against a target or reference profile",
+		Query: withParameters([]parameter{
+			{"target", "number", "Constant target value"},
+			{"channel", "string", "Channel compared with the target"},
+			{"profile", "integer", "ID of the reference profile to compare with"},
+			{"window", "number", "Length of the time windows in seconds"},
+		}, alignmentParameters),
+		Response: trackingErrorResponse{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/variance", Tag: tagDataAnalysis, Summary: "Sliding-window variance of the airspeed or altitude",
+		Query: varianceParameters, Response: data_analysis.VarianceReport{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/live-overlay", Tag: tagDataAnalysis, Summary: "Flight resampled to the timeline of the session being recorded",
+		Query: withParameters([]parameter{
+			{"offset", "number", "Shift of the reference in seconds"},
+			{"since", "number", "Only samples after this live time"},
+			{"ahead", "number", "Seconds of the reference ahead of the live time"},
+		}, alignmentParameters),
+		Response: data_analysis.LiveOverlay{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/response-latency", Tag: tagDataAnalysis, Summary: "Delay between each logged failure and the participant's response",
+		Response: data_analysis.ResponseLatency{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/approach-stability", Tag: tagDataAnalysis, Summary: "Stabilized approach assessment of each approach to a runway",
+		Query: []parameter{
+			{"runway", "integer", "ID of the runway"},
+			{"target_speed", "number", "Target approach speed in knots instead of the configured one"},
+			allAircraftParameter,
+		},
+		Response: data_analysis.ApproachReport{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/glidepath", Tag: tagDataAnalysis, Summary: "Vertical deviation from the glidepath of a runway on final approach",
+		Query:    []parameter{{"runway", "integer", "ID of the runway"}, allAircraftParameter},
+		Response: data_analysis.GlidepathReport{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/metrics", Tag: tagDataAnalysis, Summary: "Stored metrics of a flight",
+		Response: []data_analysis.FlightMetric{}},
+	{Method: "DELETE", Path: "/data-analysis/flights/{id}/metrics", Tag: tagDataAnalysis, Summary: "Remove the stored metrics of a flight", Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/metrics/recompute", Tag: tagDataAnalysis, Summary: "Recompute the stored metrics of a flight",
+		Response: []data_analysis.FlightMetric{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/export", Tag: tagDataAnalysis, Summary: "CSV export of a flight as a ZIP archive",
+		Query:    []parameter{{"format", "string", "\"airspeed-altitude\" (default) or \"full\""}},
+		Produces: contentZIP},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/aircraft", Tag: tagDataAnalysis, Summary: "Aircraft of a flight",
+		Response: []data_analysis.AircraftSummary{}},
+	{Method: "PATCH", Path: "/data-analysis/flights/{id}/aircraft/{aircraftId}", Tag: tagDataAnalysis, Summary: "Rename or relabel an aircraft",
+		Request: data_analysis.AircraftUpdate{}, Response: data_analysis.Aircraft{}},
+	{Method: "PUT", Path: "/data-analysis/flights/{id}/target-aircraft", Tag: tagDataAnalysis, Summary: "Select the aircraft flown by the participant",
+		Request: data_analysis.SetTargetAircraftRequest{}, Response: []data_analysis.AircraftSummary{}},
+	{Method: "PUT", Path: "/data-analysis/flights/{id}/participant", Tag: tagDataAnalysis, Summary: "Assign a flight to a participant and condition",
+		Request: participantRequest{}, Response: object},
+	{Method: "PUT", Path: "/data-analysis/flights/{id}/review", Tag: tagDataAnalysis, Summary: "Set the review status of a flight",
+		Request: reviewRequest{}, Response: object},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/track.geojson", Tag: tagDataAnalysis, Summary: "Ground track of a flight as GeoJSON",
+		Query:    []parameter{{"aircraft", "string", "Aircraft whose position the markers are placed at"}},
+		Response: data_analysis.GeoJSONFeatureCollection{}, Produces: "application/geo+json"},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/markers", Tag: tagDataAnalysis, Summary: "Markers of a flight",
+		Query:    withParameters([]parameter{{"category", "string", "Comma-separated marker categories to include"}}, alignmentParameters),
+		Response: []data_analysis.Marker{}},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/markers", Tag: tagDataAnalysis, Summary: "Add a marker",
+		Request: data_analysis.Marker{}, Response: data_analysis.Marker{}},
+	{Method: "DELETE", Path: "/data-analysis/flights/{id}/markers/{markerId}", Tag: tagDataAnalysis, Summary: "Delete a marker", Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/markers/{markerId}/nudge", Tag: tagDataAnalysis, Summary: "Move a marker by some seconds",
+		Request: data_analysis.NudgeMarkerRequest{}, Response: data_analysis.MarkerMove{}},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/markers/{markerId}/snap", Tag: tagDataAnalysis, Summary: "Move a marker to the nearest minimum or maximum of a channel",
+		Request: data_analysis.SnapMarkerRequest{}, Response: data_analysis.MarkerMove{}},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/replay", Tag: tagDataAnalysis, Summary: "Replay cursor of a flight",
+		Response: data_analysis.ReplaySession{}},
+	{Method: "PUT", Path: "/data-analysis/flights/{id}/replay", Tag: tagDataAnalysis, Summary: "Start or change the replay of a flight",
+		Request: data_analysis.ReplayUpdate{}, Response: data_analysis.ReplaySession{}},
+	{Method: "DELETE", Path: "/data-analysis/flights/{id}/replay", Tag: tagDataAnalysis, Summary: "Stop the replay of a flight", Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/replay/markers", Tag: tagDataAnalysis, Summary: "Add a marker at the replay cursor",
+		Request: data_analysis.Marker{}, Response: data_analysis.Marker{}},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/distance-markers", Tag: tagDataAnalysis, Summary: "Create the distance markers of a flight",
+		Query: []parameter{allAircraftParameter}, Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/warning-markers", Tag: tagDataAnalysis, Summary: "Create the stall and overspeed warning markers of a flight",
+		Query: []parameter{allAircraftParameter}, Response: object},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/oscillation-markers", Tag: tagDataAnalysis, Summary: "Detect pilot-induced oscillations and mark them",
+		Query: []parameter{allAircraftParameter}, Response: oscillationMarkersResponse{}},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/event-markers", Tag: tagDataAnalysis, Summary: "Match the operator events to a flight",
+		Query:    []parameter{{"clock_offset", "number", "Seconds the simulator clock is off real time"}},
+		Response: object},
+	{Method: "GET", Path: "/data-analysis/flights/{id}/trim-markers", Tag: tagDataAnalysis, Summary: "Trim markers of a flight",
+		Response: map[string]*data_analysis.Marker{}},
+	{Method: "POST", Path: "/data-analysis/flights/{id}/trim-markers", Tag: tagDataAnalysis, Summary: "Set a trim marker",
+		Request: trimMarkerRequest{}, Response: object},
+	{Method: "DELETE", Path: "/data-analysis/flights/{id}/trim-markers", Tag: tagDataAnalysis, Summary: "Remove the trim markers of a flight", Response: object},
+
+	// Events
+	{Method: "GET", Path: "/events", Tag: tagEvents, Summary: "Last 50 events", Response: []events.Event{}},
+	{Method: "POST", Path: "/manual-event", Tag: tagEvents, Summary: "Log an event", Request: manualEventRequest{}},
+	{Method: "GET", Path: "/events/triggers", Tag: tagEvents, Summary: "Trigger configuration", Response: events.TriggerConfig{}},
+	{Method: "PUT", Path: "/events/triggers", Tag: tagEvents, Summary: "Set the trigger configuration",
+		Request: events.TriggerConfig{}, Response: events.TriggerConfig{}},
+	{Method: "GET", Path: "/events/list", Tag: tagEvents, Summary: "Event list, newest first", Produces: contentHTML},
+	{Method: "POST", Path: "/events/manual", Tag: tagEvents, Summary: "Log an event from the operator form",
+		Form: []parameter{{"type", "string", "Event type"}, {"program", "string", "Program the event belongs to"}}, Produces: contentHTML},
+
+	// GPS
+	{Method: "GET", Path: "/gps/position", Tag: tagGPS, Summary: "Current position", Produces: contentHTML},
+	{Method: "GET", Path: "/gps/config", Tag: tagGPS, Summary: "Forwarding configuration", Produces: contentHTML},
+	{Method: "POST", Path: "/gps/set-target-ip", Tag: tagGPS, Summary: "Set the address GPS data is forwarded to",
+		Form: []parameter{{"target_ip", "string", "IP address"}}, Produces: contentHTML},
+	{Method: "POST", Path: "/gps/set-distance-threshold", Tag: tagGPS, Summary: "Set the distance GPS data is forwarded within",
+		Form: []parameter{{"distance_threshold", "number", "Distance in nautical miles"}}, Produces: contentHTML},
+	{Method: "POST", Path: "/gps/broadcast-toggle", Tag: tagGPS, Summary: "Start or stop forwarding", Produces: contentHTML},
+	{Method: "GET", Path: "/gps/simulator-status", Tag: tagGPS, Summary: "Simulator connection status",
+		Query: []parameter{formatParameter}, Response: gps.SimulatorStatus{}, Produces: contentHTML},
+	{Method: "GET", Path: "/gps/routes", Tag: tagGPS, Summary: "Route configuration", Response: gps.RouteConfig{}},
+	{Method: "PUT", Path: "/gps/routes", Tag: tagGPS, Summary: "Set the route configuration",
+		Request: gps.RouteConfig{}, Response: gps.RouteConfig{}},
+	{Method: "GET", Path: "/gps/reference", Tag: tagGPS, Summary: "Reference point of the study site", Response: gps.ReferenceConfig{}},
+	{Method: "PUT", Path: "/gps/reference", Tag: tagGPS, Summary: "Set the reference point of the study site",
+		Request: gps.ReferenceConfig{}, Response: gps.ReferenceConfig{}},
+	{Method: "GET", Path: "/gps/recording.csv", Tag: tagGPS, Summary: "Positions recorded so far in the current session", Produces: contentCSV},
+	{Method: "GET", Path: "/gps/live-statistics", Tag: tagGPS, Summary: "Live statistics of the session being recorded",
+		Query: []parameter{formatParameter}, Response: gps.LiveStatistics{}, Produces: contentHTML},
+	{Method: "GET", Path: "/gps/live-statistics/stream", Tag: tagGPS, Summary: "Live statistics as server-sent events", Produces: contentSSE},
+	{Method: "GET", Path: "/gps/alerts", Tag: tagGPS, Summary: "Active and latest alerts",
+		Query: []parameter{formatParameter}, Response: gps.AlertStatus{}, Produces: contentHTML},
+	{Method: "GET", Path: "/gps/alerts/rules", Tag: tagGPS, Summary: "Alert rules", Response: gps.AlertConfig{}},
+	{Method: "PUT", Path: "/gps/alerts/rules", Tag: tagGPS, Summary: "Set the alert rules",
+		Request: gps.AlertConfig{}, Response: gps.AlertConfig{}},
+
+	// Programs
+	{Method: "GET", Path: "/program-manager", Tag: tagPrograms, Summary: "Program manager page", Produces: contentHTML},
+	{Method: "GET", Path: "/programs/status-all", Tag: tagPrograms, Summary: "Program list", Produces: contentHTML},
+	{Method: "GET", Path: "/programs/status", Tag: tagPrograms, Summary: "State of all programs",
+		Response: map[string]*programs.ProgramState{}},
+	{Method: "GET", Path: "/programs/logs", Tag: tagPrograms, Summary: "Latest output of a program",
+		Query:    []parameter{programParameter, {"lines", "integer", "Number of lines"}},
+		Produces: contentText},
+	{Method: "GET", Path: "/programs/schedule", Tag: tagPrograms, Summary: "Program schedule", Response: []programs.ScheduledAction{}},
+	{Method: "POST", Path: "/programs/schedule", Tag: tagPrograms, Summary: "Add an action to the program schedule",
+		Request: programs.ScheduledAction{}, Response: programs.ScheduledAction{}},
+	{Method: "POST", Path: "/programs/schedule/delete", Tag: tagPrograms, Summary: "Remove an action from the program schedule",
+		Query: []parameter{{"id", "integer", "ID of the action"}}},
+	{Method: "POST", Path: "/programs/launch", Tag: tagPrograms, Summary: "Launch a program",
+		Query: []parameter{programParameter}, Produces: contentHTML},
+	{Method: "POST", Path: "/programs/kill", Tag: tagPrograms, Summary: "Terminate a program",
+		Query: []parameter{programParameter}, Produces: contentHTML},
+
+	// Mental rotation
+	{Method: "GET", Path: "/mental-rotation", Tag: tagMentalRotation, Summary: "Mental rotation test page", Produces: contentHTML},
+	{Method: "GET", Path: "/mental-rotation/tasks", Tag: tagMentalRotation, Summary: "Tasks of the test", Response: []mental_rotation.Task{}},
+	{Method: "POST", Path: "/mental-rotation/submit", Tag: tagMentalRotation, Summary: "Record the answer to a task",
+		Request: mental_rotation.Result{}},
+	{Method: "GET", Path: "/mental-rotation/results", Tag: tagMentalRotation, Summary: "Active results", Response: []mental_rotation.Result{}},
+	{Method: "GET", Path: "/mental-rotation/results/archive", Tag: tagMentalRotation, Summary: "Archived results",
+		Response: []mental_rotation.ArchivedResult{}},
+	{Method: "POST", Path: "/mental-rotation/results/archive", Tag: tagMentalRotation, Summary: "Archive the results of participants",
+		Request: participantSelection{}, Response: archiveResultsResponse{}},
+	{Method: "POST", Path: "/mental-rotation/results/delete", Tag: tagMentalRotation, Summary: "Delete the active results of participants",
+		Request: participantSelection{}, Response: deleteResultsResponse{}},
+	{Method: "POST", Path: "/mental-rotation/complete", Tag: tagMentalRotation, Summary: "Issue the completion code of a participant",
+		Request: completionRequest{}, Response: mental_rotation.Completion{}},
+	{Method: "GET", Path: "/mental-rotation/completions", Tag: tagMentalRotation, Summary: "Issued completion codes",
+		Response: []mental_rotation.Completion{}},
+	{Method: "GET", Path: "/mental-rotation/images/{file}", Tag: tagMentalRotation, Summary: "Image of a task", Produces: "image/jpeg"},
+}
@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"reflect"
+)
+
+// schemaRefs resolves references to definitions within a schema document
+type schemaRefs struct {
+	prefix string                  // Location of the definitions, e.g. "#/$defs/"
+	names  map[reflect.Type]string // Definition name per type
+}
+
+// jsonSchemaDocument returns a JSON Schema (draft 2020-12) with one definition per type
+func jsonSchemaDocument(definitions []definition) map[string]interface{} {
+	refs := schemaRefs{prefix: "#/$defs/", names: definitionNames(definitions)}
+	defs := map[string]interface{}{}
+	for _, d := range definitions {
+		defs[d.Name] = objectSchema(d.Fields, refs)
+	}
+
+	return map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     "/api/schema",
+		"title":   "Operator Station data contract",
+		"$defs":   defs,
+	}
+}
+
+// objectSchema returns the schema of a struct with the given JSON properties
+func objectSchema(fields []field, refs schemaRefs) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, f := range fields {
+		properties[f.Name] = jsonSchemaFor(f.Type, refs)
+		if !f.Optional {
+			required = append(required, f.Name)
+		}
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// jsonSchemaFor returns the schema of a Go type as encoding/json writes it.
+// Pointers, slices and maps may be written as null; anonymous structs are written inline.
+func jsonSchemaFor(t reflect.Type, refs schemaRefs) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(jsonSchemaFor(t.Elem(), refs))
+	case reflect.Slice:
+		return nullable(map[string]interface{}{"type": "array", "items": jsonSchemaFor(t.Elem(), refs)})
+	case reflect.Array:
+		return map[string]interface{}{"type": "array", "items": jsonSchemaFor(t.Elem(), refs), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		return nullable(map[string]interface{}{"type": "object", "additionalProperties": jsonSchemaFor(t.Elem(), refs)})
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return objectSchema(jsonFields(t), refs)
+		}
+		return map[string]interface{}{"$ref": refs.prefix + refs.names[t]}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	default:
+		// interface{} holds any JSON value
+		return map[string]interface{}{}
+	}
+}
+
+// nullable allows null in addition to the given schema
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+}
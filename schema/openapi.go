@@ -0,0 +1,186 @@
+package schema
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+)
+
+// pathParameterPattern matches the {name} parameters of an endpoint path
+var pathParameterPattern = regexp.MustCompile(`\{([A-Za-z]+)\}`)
+
+// stringPathParameters are the path parameters that are not integer IDs
+var stringPathParameters = map[string]bool{"uploadId": true, "jobId": true, "file": true}
+
+// tagDescriptions describe the modules the endpoints are grouped by
+var tagDescriptions = []map[string]interface{}{
+	{"name": tagDataAnalysis, "description": "Flight recordings, their statistics, markers and exports"},
+	{"name": tagEvents, "description": "Event log and hardware triggers"},
+	{"name": tagGPS, "description": "GPS forwarding, recording and alerts"},
+	{"name": tagPrograms, "description": "Study software on the station and its schedule"},
+	{"name": tagMentalRotation, "description": "Mental rotation test"},
+}
+
+// openAPIDocument returns an OpenAPI 3.1 document of the endpoints. The published types and the request
+// and response types of the endpoints are its component schemas.
+func openAPIDocument() map[string]interface{} {
+	types := append([]interface{}{}, publishedTypes...)
+	for _, e := range endpoints {
+		for _, v := range []interface{}{e.Request, e.Response} {
+			if v != nil {
+				types = append(types, v)
+			}
+		}
+	}
+	definitions := collectDefinitions(types)
+	refs := schemaRefs{prefix: "#/components/schemas/", names: definitionNames(definitions)}
+
+	schemas := map[string]interface{}{}
+	for _, d := range definitions {
+		schemas[d.Name] = objectSchema(d.Fields, refs)
+	}
+
+	paths := map[string]map[string]interface{}{}
+	for _, e := range endpoints {
+		if paths[e.Path] == nil {
+			paths[e.Path] = map[string]interface{}{}
+		}
+		paths[e.Path][strings.ToLower(e.Method)] = openAPIOperation(e, refs)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":       "Operator Station API",
+			"version":     "1.0.0",
+			"description": "HTTP endpoints of the operator station. Data analysis errors are JSON error envelopes; the other modules answer errors in plain text.",
+		},
+		"tags":       tagDescriptions,
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+}
+
+// openAPIOperation returns the operation object of an endpoint
+func openAPIOperation(e endpoint, refs schemaRefs) map[string]interface{} {
+	parameters := []interface{}{}
+	for _, match := range pathParameterPattern.FindAllStringSubmatch(e.Path, -1) {
+		schemaType := "integer"
+		if stringPathParameters[match[1]] {
+			schemaType = "string"
+		}
+		parameters = append(parameters, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": schemaType},
+		})
+	}
+	for _, p := range e.Query {
+		parameters = append(parameters, map[string]interface{}{
+			"name":        p.Name,
+			"in":          "query",
+			"description": p.Description,
+			"schema":      parameterSchema(p),
+		})
+	}
+
+	operation := map[string]interface{}{
+		"operationId": operationID(e.Method, e.Path),
+		"summary":     e.Summary,
+		"tags":        []string{e.Tag},
+		"parameters":  parameters,
+	}
+	if body := requestBody(e, refs); body != nil {
+		operation["requestBody"] = body
+	}
+
+	status := e.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	content := map[string]interface{}{}
+	if e.Response != nil {
+		content["application/json"] = map[string]interface{}{"schema": jsonSchemaFor(reflect.TypeOf(e.Response), refs)}
+	}
+	if e.Produces != "" {
+		content[e.Produces] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+	}
+	if len(content) > 0 {
+		success["content"] = content
+	}
+
+	errorContent := map[string]interface{}{contentText: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+	if e.Tag == tagDataAnalysis {
+		errorContent = map[string]interface{}{"application/json": map[string]interface{}{
+			"schema": jsonSchemaFor(reflect.TypeOf(data_analysis.APIError{}), refs),
+		}}
+	}
+	operation["responses"] = map[string]interface{}{
+		strconv.Itoa(status): success,
+		"default":            map[string]interface{}{"description": "Error", "content": errorContent},
+	}
+	return operation
+}
+
+// requestBody returns the request body object of an endpoint, nil if it takes no body
+func requestBody(e endpoint, refs schemaRefs) map[string]interface{} {
+	var contentType string
+	var schema map[string]interface{}
+	switch {
+	case e.Request != nil:
+		contentType, schema = "application/json", jsonSchemaFor(reflect.TypeOf(e.Request), refs)
+	case e.RawBody != "":
+		contentType, schema = e.RawBody, map[string]interface{}{"type": "string", "format": "binary"}
+	case len(e.Form) > 0:
+		contentType = "application/x-www-form-urlencoded"
+		properties := map[string]interface{}{}
+		for _, p := range e.Form {
+			if p.Type == "file" {
+				contentType = "multipart/form-data"
+			}
+			schema := parameterSchema(p)
+			schema["description"] = p.Description
+			properties[p.Name] = schema
+		}
+		schema = map[string]interface{}{"type": "object", "properties": properties}
+	default:
+		return nil
+	}
+	return map[string]interface{}{
+		"required": true,
+		"content":  map[string]interface{}{contentType: map[string]interface{}{"schema": schema}},
+	}
+}
+
+// parameterSchema returns the schema of a query or form parameter
+func parameterSchema(p parameter) map[string]interface{} {
+	if p.Type == "file" {
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "format": "binary"}}
+	}
+	return map[string]interface{}{"type": p.Type}
+}
+
+// operationID derives a unique operation name from the method and path, e.g. "getDataAnalysisFlightsIdMarkers"
+func operationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, word := range strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		id.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return id.String()
+}
+
+// handleOpenAPI serves the OpenAPI document of the station's HTTP endpoints
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(openAPIDocument())
+}
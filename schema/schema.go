@@ -0,0 +1,198 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+	"github.com/kaireichart/master-thesis-operator-station/events"
+	"github.com/kaireichart/master-thesis-operator-station/gps"
+)
+
+// publishedTypes are the backend structures the chart frontend consumes
+var publishedTypes = []interface{}{
+	// Data analysis
+	data_analysis.Flight{},
+	data_analysis.FlightConditions{},
+	data_analysis.FlightWeather{},
+	data_analysis.Aircraft{},
+	data_analysis.AircraftSummary{},
+	data_analysis.AircraftUpdate{},
+	data_analysis.PositionPoint{},
+	data_analysis.EnginePoint{},
+	data_analysis.AttitudePoint{},
+	data_analysis.FlightData{},
+	data_analysis.PositionPage{},
+	data_analysis.Marker{},
+	data_analysis.VisualizationRequest{},
+	data_analysis.DatabaseInfo{},
+	data_analysis.ImportError{},
+	data_analysis.PositionProvenance{},
+	data_analysis.ImportReport{},
+	data_analysis.FlightStatistics{},
+	data_analysis.DataStatistics{},
+	data_analysis.WindCorrectedStatistics{},
+	data_analysis.DistanceMarkerSettings{},
+	data_analysis.DistanceMarkerWaypoint{},
+	data_analysis.GeoJSONFeatureCollection{},
+	data_analysis.APIError{},
+
+	// Events
+	events.Event{},
+
+	// GPS
+	gps.Position{},
+	gps.Config{},
+	gps.SimulatorStatus{},
+}
+
+// field is one JSON property of a struct
+type field struct {
+	Name     string
+	Type     reflect.Type
+	Optional bool // Omitted from the JSON when empty (omitempty/omitzero)
+}
+
+// definition is a named struct type with its JSON properties
+type definition struct {
+	Name   string // Type name, prefixed with its package if another package has a type of that name
+	Type   reflect.Type
+	Fields []field
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// collectDefinitions returns the given types and all named struct types they reference, sorted by name.
+// Anonymous structs, e.g. of request bodies, are not defined but written inline.
+func collectDefinitions(values []interface{}) []definition {
+	seen := map[reflect.Type]bool{}
+	var definitions []definition
+
+	var visit func(t reflect.Type)
+	visit = func(t reflect.Type) {
+		t = elemType(t)
+		if t.Kind() != reflect.Struct || t == timeType || seen[t] {
+			return
+		}
+		seen[t] = true
+
+		fields := jsonFields(t)
+		if name := t.Name(); name != "" {
+			// Unexported request and response types of the endpoint table are named like the others
+			name = strings.ToUpper(name[:1]) + name[1:]
+			definitions = append(definitions, definition{Name: name, Type: t, Fields: fields})
+		}
+		for _, f := range fields {
+			visit(f.Type)
+		}
+	}
+	for _, v := range values {
+		visit(reflect.TypeOf(v))
+	}
+
+	// Types of the same name in different packages, e.g. the GPS and data analysis reference points
+	count := map[string]int{}
+	for _, d := range definitions {
+		count[d.Name]++
+	}
+	for i, d := range definitions {
+		if count[d.Name] > 1 {
+			definitions[i].Name = packagePrefix(d.Type) + d.Name
+		}
+	}
+
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+	return definitions
+}
+
+// packagePrefix returns the package name of a type in PascalCase, e.g. "DataAnalysis" for data_analysis
+func packagePrefix(t reflect.Type) string {
+	path := t.PkgPath()
+	var prefix strings.Builder
+	for _, word := range strings.Split(path[strings.LastIndex(path, "/")+1:], "_") {
+		if word != "" {
+			prefix.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return prefix.String()
+}
+
+// definitionNames maps the defined types to their names, for references between definitions
+func definitionNames(definitions []definition) map[reflect.Type]string {
+	names := make(map[reflect.Type]string, len(definitions))
+	for _, d := range definitions {
+		names[d.Type] = d.Name
+	}
+	return names
+}
+
+// elemType strips pointers, slices and maps down to the type of the contained values
+func elemType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+// jsonFields returns the properties encoding/json writes for a struct, with embedded structs flattened
+func jsonFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if sf.Anonymous && name == "" && elemType(sf.Type).Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(elemType(sf.Type))...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{
+			Name:     name,
+			Type:     sf.Type,
+			Optional: strings.Contains(options, "omitempty") || strings.Contains(options, "omitzero"),
+		})
+	}
+	return fields
+}
+
+// handleSchema serves the published types as JSON Schema or, with ?format=typescript, as TypeScript definitions
+func handleSchema(w http.ResponseWriter, r *http.Request) {
+	definitions := collectDefinitions(publishedTypes)
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json-schema":
+		w.Header().Set("Content-Type", "application/schema+json")
+		json.NewEncoder(w).Encode(jsonSchemaDocument(definitions))
+	case "typescript":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", "inline; filename=\"operator-station.d.ts\"")
+		fmt.Fprint(w, typeScriptDefinitions(definitions))
+	default:
+		http.Error(w, fmt.Sprintf("Invalid format '%s' (expected json-schema or typescript)", format), http.StatusBadRequest)
+	}
+}
+
+// SetupHandlers registers the schema and OpenAPI endpoints
+func SetupHandlers() {
+	http.HandleFunc("GET /api/schema", handleSchema)
+	http.HandleFunc("GET /api/openapi.json", handleOpenAPI)
+}
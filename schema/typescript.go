@@ -0,0 +1,76 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// typeScriptDefinitions returns one exported interface per type
+func typeScriptDefinitions(definitions []definition) string {
+	var b strings.Builder
+	b.WriteString("// Generated by the operator station from its Go types (GET /api/schema?format=typescript).\n")
+	b.WriteString("// Do not edit; fetch again after backend changes.\n")
+
+	names := definitionNames(definitions)
+	for _, d := range definitions {
+		fmt.Fprintf(&b, "\nexport interface %s {\n", d.Name)
+		for _, f := range d.Fields {
+			optional := ""
+			if f.Optional {
+				optional = "?"
+			}
+			fmt.Fprintf(&b, "  %s%s: %s;\n", typeScriptName(f.Name), optional, typeScriptFor(f.Type, names))
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// typeScriptName quotes property names that are not valid identifiers
+func typeScriptName(name string) string {
+	for i, r := range name {
+		if !(r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return fmt.Sprintf("%q", name)
+		}
+	}
+	return name
+}
+
+// typeScriptFor returns the TypeScript type of a Go type as encoding/json writes it, naming structs
+// as in names
+func typeScriptFor(t reflect.Type, names map[reflect.Type]string) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeScriptFor(t.Elem(), names) + " | null"
+	case reflect.Slice:
+		return arrayOf(typeScriptFor(t.Elem(), names)) + " | null"
+	case reflect.Array:
+		return arrayOf(typeScriptFor(t.Elem(), names))
+	case reflect.Map:
+		return fmt.Sprintf("Record<string, %s> | null", typeScriptFor(t.Elem(), names))
+	case reflect.Struct:
+		if t == timeType {
+			return "string" // RFC 3339
+		}
+		return names[t]
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+// arrayOf returns the array type of an element type, parenthesizing unions
+func arrayOf(elem string) string {
+	if strings.Contains(elem, " ") {
+		return "(" + elem + ")[]"
+	}
+	return elem + "[]"
+}
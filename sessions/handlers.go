@@ -0,0 +1,139 @@
+package sessions
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+func SetupHandlers() {
+	http.HandleFunc("GET /sessions", handleGetSessions)
+	http.HandleFunc("GET /sessions/current", handleGetCurrent)
+	http.HandleFunc("POST /sessions/start", handleStart)
+	http.HandleFunc("POST /sessions/end", handleEnd)
+	http.HandleFunc("POST /sessions/pause", handlePause)
+	http.HandleFunc("POST /sessions/resume", handleResume)
+	http.HandleFunc("POST /sessions/current/conditions", handleLogConditions)
+}
+
+func handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetSessions())
+}
+
+func handleGetCurrent(w http.ResponseWriter, r *http.Request) {
+	current, ok := Current()
+	if !ok {
+		http.Error(w, ErrNoActiveSession.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(current)
+}
+
+func handleStart(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ParticipantID string `json:"participantId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := Start(request.ParticipantID)
+	if err == ErrParticipantRequired {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == ErrSessionActive {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+func handleEnd(w http.ResponseWriter, r *http.Request) {
+	session, err := End()
+	if err == ErrNoActiveSession {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// handlePause pauses the active session; the request body with a reason is optional
+func handlePause(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session, err := Pause(request.Reason)
+	if err == ErrNoActiveSession || err == ErrSessionPaused {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+func handleResume(w http.ResponseWriter, r *http.Request) {
+	session, err := Resume()
+	if err == ErrNoActiveSession || err == ErrSessionNotPaused {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+func handleLogConditions(w http.ResponseWriter, r *http.Request) {
+	var conditions Conditions
+	if err := json.NewDecoder(r.Body).Decode(&conditions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logged, err := LogConditions(conditions)
+	if err == ErrConditionsRequired {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == ErrNoActiveSession {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(logged)
+}
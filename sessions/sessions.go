@@ -0,0 +1,356 @@
+package sessions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/events"
+)
+
+var (
+	// ErrSessionActive is returned when starting a session while another one is running
+	ErrSessionActive = errors.New("a session is already active")
+	// ErrNoActiveSession is returned when ending a session while none is running
+	ErrNoActiveSession = errors.New("no session is active")
+	// ErrParticipantRequired is returned when starting a session without a participant ID
+	ErrParticipantRequired = errors.New("participant ID is required")
+	// ErrConditionsRequired is returned when logging conditions without a weather preset or failures
+	ErrConditionsRequired = errors.New("weather preset or failures are required")
+	// ErrSessionPaused is returned when pausing a session that is already paused
+	ErrSessionPaused = errors.New("the session is already paused")
+	// ErrSessionNotPaused is returned when resuming a session that is not paused
+	ErrSessionNotPaused = errors.New("the session is not paused")
+)
+
+var (
+	sessions        []Session
+	mutex           = &sync.Mutex{}
+	sessionsFile    string
+	startListeners  []func(Session)
+	endListeners    []func(Session)
+	pauseListeners  []func(Session)
+	resumeListeners []func(Session)
+)
+
+func Init() {
+	sessionsFile = filepath.Join("data", "sessions.json")
+
+	if err := os.MkdirAll("data", 0755); err != nil {
+		panic(err)
+	}
+
+	if data, err := os.ReadFile(sessionsFile); err == nil {
+		if err := json.Unmarshal(data, &sessions); err != nil {
+			panic(err)
+		}
+	}
+
+	if current, ok := Current(); ok {
+		log.Printf("Resuming active session %d (participant %s, started %s)",
+			current.ID, current.ParticipantID, current.StartedAt.Format("2006-01-02 15:04:05"))
+	}
+}
+
+// OnStart registers a function called whenever a session starts. If a session is already
+// active (e.g. restored after a restart), the function is called for it right away.
+func OnStart(fn func(Session)) {
+	mutex.Lock()
+	startListeners = append(startListeners, fn)
+	mutex.Unlock()
+
+	if current, ok := Current(); ok {
+		fn(current)
+	}
+}
+
+// OnEnd registers a function called whenever a session ends
+func OnEnd(fn func(Session)) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	endListeners = append(endListeners, fn)
+}
+
+// OnPause registers a function called whenever the active session is paused
+func OnPause(fn func(Session)) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	pauseListeners = append(pauseListeners, fn)
+}
+
+// OnResume registers a function called whenever the active session is resumed
+func OnResume(fn func(Session)) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	resumeListeners = append(resumeListeners, fn)
+}
+
+// Current returns the active session, if any
+func Current() (Session, bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(sessions) == 0 || !sessions[len(sessions)-1].Active() {
+		return Session{}, false
+	}
+	return sessions[len(sessions)-1], true
+}
+
+// GetSessions returns all sessions, oldest first
+func GetSessions() []Session {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return append([]Session{}, sessions...)
+}
+
+// Start begins a new session for a participant
+func Start(participantID string) (Session, error) {
+	participantID = strings.TrimSpace(participantID)
+	if participantID == "" {
+		return Session{}, ErrParticipantRequired
+	}
+
+	mutex.Lock()
+	if len(sessions) > 0 && sessions[len(sessions)-1].Active() {
+		mutex.Unlock()
+		return Session{}, ErrSessionActive
+	}
+
+	session := Session{
+		ID:            len(sessions) + 1,
+		ParticipantID: participantID,
+		StartedAt:     time.Now(),
+	}
+	sessions = append(sessions, session)
+	if err := saveSessions(); err != nil {
+		sessions = sessions[:len(sessions)-1]
+		mutex.Unlock()
+		return Session{}, err
+	}
+	listeners := append([]func(Session){}, startListeners...)
+	mutex.Unlock()
+
+	events.LogEvent(events.Event{
+		Type:      "session_started",
+		Program:   fmt.Sprintf("Session %d - %s", session.ID, session.ParticipantID),
+		Timestamp: session.StartedAt,
+	})
+
+	for _, fn := range listeners {
+		fn(session)
+	}
+	return session, nil
+}
+
+// End finishes the active session
+func End() (Session, error) {
+	mutex.Lock()
+	if len(sessions) == 0 || !sessions[len(sessions)-1].Active() {
+		mutex.Unlock()
+		return Session{}, ErrNoActiveSession
+	}
+
+	now := time.Now()
+	session := &sessions[len(sessions)-1]
+	pauses := session.Pauses
+	if session.Paused() {
+		session.Pauses = endPause(pauses, now)
+	}
+	session.EndedAt = &now
+	if err := saveSessions(); err != nil {
+		session.EndedAt = nil
+		session.Pauses = pauses
+		mutex.Unlock()
+		return Session{}, err
+	}
+	ended := *session
+	listeners := append([]func(Session){}, endListeners...)
+	mutex.Unlock()
+
+	events.LogEvent(events.Event{
+		Type:      "session_ended",
+		Program:   fmt.Sprintf("Session %d - %s", ended.ID, ended.ParticipantID),
+		Timestamp: now,
+	})
+
+	for _, fn := range listeners {
+		fn(ended)
+	}
+	return ended, nil
+}
+
+// Pause interrupts the active session until it is resumed. The pause is recorded on the session so
+// analysis can exclude it.
+func Pause(reason string) (Session, error) {
+	mutex.Lock()
+	if len(sessions) == 0 || !sessions[len(sessions)-1].Active() {
+		mutex.Unlock()
+		return Session{}, ErrNoActiveSession
+	}
+	session := &sessions[len(sessions)-1]
+	if session.Paused() {
+		mutex.Unlock()
+		return Session{}, ErrSessionPaused
+	}
+
+	pause := PauseInterval{StartedAt: time.Now(), Reason: strings.TrimSpace(reason)}
+	session.Pauses = append(session.Pauses, pause)
+	if err := saveSessions(); err != nil {
+		session.Pauses = session.Pauses[:len(session.Pauses)-1]
+		mutex.Unlock()
+		return Session{}, err
+	}
+	paused := *session
+	listeners := append([]func(Session){}, pauseListeners...)
+	mutex.Unlock()
+
+	description := fmt.Sprintf("Session %d - %s", paused.ID, paused.ParticipantID)
+	if pause.Reason != "" {
+		description += ": " + pause.Reason
+	}
+	events.LogEvent(events.Event{
+		Type:      "session_paused",
+		Program:   description,
+		Timestamp: pause.StartedAt,
+	})
+
+	for _, fn := range listeners {
+		fn(paused)
+	}
+	return paused, nil
+}
+
+// Resume continues the paused active session
+func Resume() (Session, error) {
+	mutex.Lock()
+	if len(sessions) == 0 || !sessions[len(sessions)-1].Active() {
+		mutex.Unlock()
+		return Session{}, ErrNoActiveSession
+	}
+	session := &sessions[len(sessions)-1]
+	if !session.Paused() {
+		mutex.Unlock()
+		return Session{}, ErrSessionNotPaused
+	}
+
+	now := time.Now()
+	pauses := session.Pauses
+	session.Pauses = endPause(pauses, now)
+	if err := saveSessions(); err != nil {
+		session.Pauses = pauses
+		mutex.Unlock()
+		return Session{}, err
+	}
+	resumed := *session
+	listeners := append([]func(Session){}, resumeListeners...)
+	mutex.Unlock()
+
+	events.LogEvent(events.Event{
+		Type:      "session_resumed",
+		Program:   fmt.Sprintf("Session %d - %s", resumed.ID, resumed.ParticipantID),
+		Timestamp: now,
+	})
+
+	for _, fn := range listeners {
+		fn(resumed)
+	}
+	return resumed, nil
+}
+
+// LogConditions records the simulator conditions of the active session, e.g. after the operator
+// loaded a weather preset or armed failures
+func LogConditions(conditions Conditions) (Conditions, error) {
+	conditions.WeatherPreset = strings.TrimSpace(conditions.WeatherPreset)
+	conditions.Notes = strings.TrimSpace(conditions.Notes)
+	var failures []string
+	for _, failure := range conditions.Failures {
+		if failure = strings.TrimSpace(failure); failure != "" {
+			failures = append(failures, failure)
+		}
+	}
+	conditions.Failures = failures
+	if conditions.WeatherPreset == "" && len(conditions.Failures) == 0 {
+		return Conditions{}, ErrConditionsRequired
+	}
+
+	mutex.Lock()
+	if len(sessions) == 0 || !sessions[len(sessions)-1].Active() {
+		mutex.Unlock()
+		return Conditions{}, ErrNoActiveSession
+	}
+
+	conditions.LoggedAt = time.Now()
+	session := &sessions[len(sessions)-1]
+	session.Conditions = append(session.Conditions, conditions)
+	if err := saveSessions(); err != nil {
+		session.Conditions = session.Conditions[:len(session.Conditions)-1]
+		mutex.Unlock()
+		return Conditions{}, err
+	}
+	sessionID, participantID := session.ID, session.ParticipantID
+	mutex.Unlock()
+
+	var details []string
+	if conditions.WeatherPreset != "" {
+		details = append(details, "weather "+conditions.WeatherPreset)
+	}
+	if len(conditions.Failures) > 0 {
+		details = append(details, "failures "+strings.Join(conditions.Failures, ", "))
+	}
+	description := fmt.Sprintf("Session %d - %s: %s", sessionID, participantID, strings.Join(details, ", "))
+	events.LogEvent(events.Event{
+		Type:      "conditions_logged",
+		Program:   description,
+		Timestamp: conditions.LoggedAt,
+	})
+
+	return conditions, nil
+}
+
+// ReplaceWithReplica replaces all sessions with those of the primary station this station is a
+// backup of. Listeners are not called and no events are logged, as the primary replicates its events.
+func ReplaceWithReplica(replica []Session) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	previous := sessions
+	sessions = append([]Session{}, replica...)
+	if err := saveSessions(); err != nil {
+		sessions = previous
+		return err
+	}
+	return nil
+}
+
+// endPause returns a copy of the pauses with the open last pause ended at the given time. Copying
+// keeps sessions handed out earlier unchanged.
+func endPause(pauses []PauseInterval, at time.Time) []PauseInterval {
+	ended := append([]PauseInterval{}, pauses...)
+	ended[len(ended)-1].EndedAt = &at
+	return ended
+}
+
+// saveSessions writes the sessions to a temporary file and renames it over the sessions file.
+// Must be called with mutex held.
+func saveSessions() error {
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := sessionsFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write sessions: %w", err)
+	}
+	if err := os.Rename(tempFile, sessionsFile); err != nil {
+		return fmt.Errorf("failed to replace sessions file: %w", err)
+	}
+	return nil
+}
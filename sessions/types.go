@@ -0,0 +1,61 @@
+package sessions
+
+import "time"
+
+// Session is one experiment run with a participant, from start until it is ended by the operator
+type Session struct {
+	ID            int             `json:"id"`
+	ParticipantID string          `json:"participantId"`
+	StartedAt     time.Time       `json:"startedAt"`
+	EndedAt       *time.Time      `json:"endedAt,omitempty"`
+	Conditions    []Conditions    `json:"conditions,omitempty"` // Simulator conditions, in the order they were logged
+	Pauses        []PauseInterval `json:"pauses,omitempty"`     // Breaks to exclude from analysis, in order
+}
+
+// PauseInterval is an interval during which a session was interrupted, e.g. for a break or a simulator restart
+type PauseInterval struct {
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"` // nil while the session is paused
+	Reason    string     `json:"reason,omitempty"`
+}
+
+// Conditions describes the simulator setup of a session from the time it was logged until the next log
+type Conditions struct {
+	LoggedAt      time.Time `json:"loggedAt,omitzero"`
+	WeatherPreset string    `json:"weatherPreset,omitempty"`
+	Failures      []string  `json:"failures,omitempty"` // Active failure configuration
+	Notes         string    `json:"notes,omitempty"`
+}
+
+// CurrentConditions returns the most recently logged conditions of the session, if any
+func (s Session) CurrentConditions() (Conditions, bool) {
+	if len(s.Conditions) == 0 {
+		return Conditions{}, false
+	}
+	return s.Conditions[len(s.Conditions)-1], true
+}
+
+// Active reports whether the session has not been ended yet
+func (s Session) Active() bool {
+	return s.EndedAt == nil
+}
+
+// Paused reports whether the session is currently paused
+func (s Session) Paused() bool {
+	return len(s.Pauses) > 0 && s.Pauses[len(s.Pauses)-1].EndedAt == nil
+}
+
+// PausedAt reports whether the given moment falls into one of the pauses of the session
+func (s Session) PausedAt(at time.Time) bool {
+	for _, pause := range s.Pauses {
+		if !at.Before(pause.StartedAt) && (pause.EndedAt == nil || at.Before(*pause.EndedAt)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Elapsed returns the session time at the given moment
+func (s Session) Elapsed(at time.Time) time.Duration {
+	return at.Sub(s.StartedAt)
+}
@@ -0,0 +1,211 @@
+package study
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+)
+
+const (
+	defaultBootstrapIterations = 2000
+	maxBootstrapIterations     = 100000
+	defaultConfidence          = 0.95
+	defaultBootstrapSeed       = 1
+)
+
+var errUnknownAggregation = errors.New("unknown aggregation")
+
+// flightMetrics extract one per-flight metric from the statistics of the participant's aircraft
+var flightMetrics = map[string]func(*data_analysis.FlightStatistics) (float64, bool){
+	"airspeed_mean": func(s *data_analysis.FlightStatistics) (float64, bool) {
+		return statsValue(s.AirspeedStats, func(d *data_analysis.DataStatistics) float64 { return d.Mean })
+	},
+	"airspeed_variance": func(s *data_analysis.FlightStatistics) (float64, bool) {
+		return statsValue(s.AirspeedStats, func(d *data_analysis.DataStatistics) float64 { return d.Variance })
+	},
+	"altitude_mean": func(s *data_analysis.FlightStatistics) (float64, bool) {
+		return statsValue(s.AltitudeStats, func(d *data_analysis.DataStatistics) float64 { return d.Mean })
+	},
+	"altitude_std_dev": func(s *data_analysis.FlightStatistics) (float64, bool) {
+		return statsValue(s.AltitudeStats, func(d *data_analysis.DataStatistics) float64 { return d.StdDev })
+	},
+}
+
+// flightMetricUnits give the unit of each per-flight metric in a unit system
+var flightMetricUnits = map[string]func(data_analysis.UnitSystem) string{
+	"airspeed_mean":     func(u data_analysis.UnitSystem) string { return u.Speed },
+	"airspeed_variance": func(u data_analysis.UnitSystem) string { return u.Speed + "^2" },
+	"altitude_mean":     func(u data_analysis.UnitSystem) string { return u.Altitude },
+	"altitude_std_dev":  func(u data_analysis.UnitSystem) string { return u.Altitude },
+}
+
+// flightGroupings assign a flight to a group
+var flightGroupings = map[string]func(data_analysis.ParticipantFlightStatistics) string{
+	"title":       func(f data_analysis.ParticipantFlightStatistics) string { return f.Flight.Title },
+	"participant": func(f data_analysis.ParticipantFlightStatistics) string { return f.Flight.ParticipantID },
+	"condition":   func(f data_analysis.ParticipantFlightStatistics) string { return f.Flight.Condition },
+}
+
+// GroupStatistics summarizes one metric over the flights of one group
+type GroupStatistics struct {
+	Group     string              `json:"group"`
+	N         int                 `json:"n"`
+	Mean      float64             `json:"mean"`
+	CI        *ConfidenceInterval `json:"ci,omitempty"`
+	FlightIDs []int               `json:"flightIds"`
+}
+
+// AggregateStatistics is the result of aggregating a per-flight metric by group
+type AggregateStatistics struct {
+	Metric     string            `json:"metric"`
+	Unit       string            `json:"unit"` // Of the metric, in the configured units
+	GroupBy    string            `json:"groupBy"`
+	Iterations int               `json:"iterations"`
+	Confidence float64           `json:"confidence"`
+	Seed       uint64            `json:"seed"`
+	Groups     []GroupStatistics `json:"groups"`
+}
+
+func statsValue(d *data_analysis.DataStatistics, value func(*data_analysis.DataStatistics) float64) (float64, bool) {
+	if d == nil || d.Count == 0 {
+		return 0, false
+	}
+	return value(d), true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AggregateFlightMetric groups the participant flights, computes the mean of the metric per group and
+// bootstrap-resamples the per-flight values within each group for a confidence interval
+func AggregateFlightMetric(metric, groupBy string, iterations int, confidence float64, seed uint64) (*AggregateStatistics, error) {
+	extract, ok := flightMetrics[metric]
+	if !ok {
+		return nil, fmt.Errorf("%w: metric '%s' (available: %s)", errUnknownAggregation, metric, strings.Join(sortedKeys(flightMetrics), ", "))
+	}
+	group, ok := flightGroupings[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: grouping '%s' (available: %s)", errUnknownAggregation, groupBy, strings.Join(sortedKeys(flightGroupings), ", "))
+	}
+
+	flightStatistics, err := data_analysis.GetParticipantFlightStatistics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight statistics: %w", err)
+	}
+
+	groups := map[string]*GroupStatistics{}
+	values := map[string][]float64{}
+	for _, participantID := range sortedKeys(flightStatistics) {
+		for _, flight := range flightStatistics[participantID] {
+			if flight.Statistics == nil {
+				continue
+			}
+			value, ok := extract(data_analysis.ConvertStatistics(flight.Statistics))
+			if !ok {
+				continue
+			}
+
+			name := group(flight)
+			if groups[name] == nil {
+				groups[name] = &GroupStatistics{Group: name}
+			}
+			groups[name].FlightIDs = append(groups[name].FlightIDs, flight.Flight.ID)
+			values[name] = append(values[name], value)
+		}
+	}
+
+	// A fixed seed keeps the intervals reproducible between exports
+	rng := rand.New(rand.NewPCG(seed, seed))
+	result := &AggregateStatistics{
+		Metric:     metric,
+		Unit:       flightMetricUnits[metric](data_analysis.ConfiguredUnits()),
+		GroupBy:    groupBy,
+		Iterations: iterations,
+		Confidence: confidence,
+		Seed:       seed,
+		Groups:     []GroupStatistics{},
+	}
+	for _, name := range sortedKeys(groups) {
+		g := groups[name]
+		var sum float64
+		for _, v := range values[name] {
+			sum += v
+		}
+		g.N = len(values[name])
+		g.Mean = sum / float64(g.N)
+		g.CI, _ = bootstrapMeanCI(values[name], iterations, confidence, rng)
+		result.Groups = append(result.Groups, *g)
+	}
+
+	return result, nil
+}
+
+// handleAggregateStatistics reports a per-flight metric aggregated by group with bootstrap confidence intervals
+func handleAggregateStatistics(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	metric := query.Get("metric")
+	if metric == "" {
+		metric = "airspeed_variance"
+	}
+	groupBy := query.Get("group_by")
+	if groupBy == "" {
+		groupBy = "title"
+	}
+
+	iterations := defaultBootstrapIterations
+	if v := query.Get("iterations"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxBootstrapIterations {
+			http.Error(w, fmt.Sprintf("iterations must be between 1 and %d", maxBootstrapIterations), http.StatusBadRequest)
+			return
+		}
+		iterations = n
+	}
+
+	confidence := defaultConfidence
+	if v := query.Get("confidence"); v != "" {
+		c, err := strconv.ParseFloat(v, 64)
+		if err != nil || c <= 0 || c >= 1 {
+			http.Error(w, "confidence must be between 0 and 1", http.StatusBadRequest)
+			return
+		}
+		confidence = c
+	}
+
+	seed := uint64(defaultBootstrapSeed)
+	if v := query.Get("seed"); v != "" {
+		s, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid seed", http.StatusBadRequest)
+			return
+		}
+		seed = s
+	}
+
+	result, err := AggregateFlightMetric(metric, groupBy, iterations, confidence, seed)
+	if errors.Is(err, errUnknownAggregation) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to aggregate statistics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
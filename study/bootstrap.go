@@ -0,0 +1,38 @@
+package study
+
+import (
+	"math/rand/v2"
+	"sort"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+)
+
+// ConfidenceInterval is a bootstrap percentile interval around a mean
+type ConfidenceInterval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// bootstrapMeanCI resamples values with replacement and returns the percentile confidence
+// interval of the mean. At least two values are needed for a meaningful interval.
+func bootstrapMeanCI(values []float64, iterations int, confidence float64, rng *rand.Rand) (*ConfidenceInterval, bool) {
+	if len(values) < 2 || iterations <= 0 {
+		return nil, false
+	}
+
+	means := make([]float64, iterations)
+	for i := range means {
+		var sum float64
+		for range values {
+			sum += values[rng.IntN(len(values))]
+		}
+		means[i] = sum / float64(len(values))
+	}
+	sort.Float64s(means)
+
+	alpha := (1 - confidence) / 2
+	return &ConfidenceInterval{
+		Lower: data_analysis.Percentile(means, alpha),
+		Upper: data_analysis.Percentile(means, 1-alpha),
+	}, true
+}
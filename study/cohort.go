@@ -0,0 +1,190 @@
+package study
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+)
+
+// CohortFilter selects the participant flights of a cohort; empty fields select all
+type CohortFilter struct {
+	Participants []string `json:"participants"`
+	Condition    string   `json:"condition"`
+	FlightIDs    []int    `json:"flightIds"`
+}
+
+// CohortFlight holds the per-flight metrics of one flight of a cohort
+type CohortFlight struct {
+	FlightID      int                `json:"flightId"`
+	Title         string             `json:"title"`
+	ParticipantID string             `json:"participantId"`
+	Condition     string             `json:"condition"`
+	Group         string             `json:"group"`
+	Metrics       map[string]float64 `json:"metrics"` // Metrics without valid samples are left out
+}
+
+// MetricSummary is the mean and sample standard deviation of a metric over the flights of a group
+type MetricSummary struct {
+	N    int      `json:"n"`
+	Mean float64  `json:"mean"`
+	SD   *float64 `json:"sd"` // nil for fewer than two flights
+}
+
+// CohortGroup summarizes the metrics over the flights of one group
+type CohortGroup struct {
+	Group     string                   `json:"group"`
+	FlightIDs []int                    `json:"flightIds"`
+	Metrics   map[string]MetricSummary `json:"metrics"`
+}
+
+// CohortStatistics holds the per-flight metrics of a cohort and their summary per group
+type CohortStatistics struct {
+	Filter  CohortFilter      `json:"filter"`
+	GroupBy string            `json:"groupBy"`
+	Units   map[string]string `json:"units"` // Of each metric, in the configured units
+	Flights []CohortFlight    `json:"flights"`
+	Groups  []CohortGroup     `json:"groups"`
+}
+
+// matches reports whether a participant flight belongs to the cohort
+func (f CohortFilter) matches(flight data_analysis.ParticipantFlightStatistics) bool {
+	if len(f.Participants) > 0 && !slices.Contains(f.Participants, flight.Flight.ParticipantID) {
+		return false
+	}
+	if f.Condition != "" && flight.Flight.Condition != f.Condition {
+		return false
+	}
+	return len(f.FlightIDs) == 0 || slices.Contains(f.FlightIDs, flight.Flight.ID)
+}
+
+// summarizeMetric returns the mean and sample standard deviation of per-flight values
+func summarizeMetric(values []float64) MetricSummary {
+	summary := MetricSummary{N: len(values)}
+	for _, v := range values {
+		summary.Mean += v
+	}
+	summary.Mean /= float64(summary.N)
+
+	if summary.N > 1 {
+		var sumSquared float64
+		for _, v := range values {
+			sumSquared += (v - summary.Mean) * (v - summary.Mean)
+		}
+		sd := math.Sqrt(sumSquared / float64(summary.N-1))
+		summary.SD = &sd
+	}
+	return summary
+}
+
+// CohortFlightStatistics computes the airspeed and altitude metrics of the participant flights selected by
+// the filter and summarizes each per group
+func CohortFlightStatistics(filter CohortFilter, groupBy string) (*CohortStatistics, error) {
+	group, ok := flightGroupings[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: grouping '%s' (available: %s)", errUnknownAggregation, groupBy, strings.Join(sortedKeys(flightGroupings), ", "))
+	}
+
+	flightStatistics, err := data_analysis.GetParticipantFlightStatistics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight statistics: %w", err)
+	}
+
+	units := data_analysis.ConfiguredUnits()
+	result := &CohortStatistics{Filter: filter, GroupBy: groupBy, Units: map[string]string{}, Flights: []CohortFlight{}, Groups: []CohortGroup{}}
+	for metric, unit := range flightMetricUnits {
+		result.Units[metric] = unit(units)
+	}
+	groups := map[string]*CohortGroup{}
+	values := map[string]map[string][]float64{}
+	for _, participantID := range sortedKeys(flightStatistics) {
+		for _, flight := range flightStatistics[participantID] {
+			if !filter.matches(flight) {
+				continue
+			}
+
+			name := group(flight)
+			cohortFlight := CohortFlight{
+				FlightID:      flight.Flight.ID,
+				Title:         flight.Flight.Title,
+				ParticipantID: flight.Flight.ParticipantID,
+				Condition:     flight.Flight.Condition,
+				Group:         name,
+				Metrics:       map[string]float64{},
+			}
+			if groups[name] == nil {
+				groups[name] = &CohortGroup{Group: name, FlightIDs: []int{}, Metrics: map[string]MetricSummary{}}
+				values[name] = map[string][]float64{}
+			}
+			groups[name].FlightIDs = append(groups[name].FlightIDs, flight.Flight.ID)
+
+			if flight.Statistics != nil {
+				statistics := data_analysis.ConvertStatistics(flight.Statistics)
+				for metric, extract := range flightMetrics {
+					if value, ok := extract(statistics); ok {
+						cohortFlight.Metrics[metric] = value
+						values[name][metric] = append(values[name][metric], value)
+					}
+				}
+			}
+			result.Flights = append(result.Flights, cohortFlight)
+		}
+	}
+
+	for _, name := range sortedKeys(groups) {
+		g := groups[name]
+		for metric, v := range values[name] {
+			g.Metrics[metric] = summarizeMetric(v)
+		}
+		result.Groups = append(result.Groups, *g)
+	}
+	return result, nil
+}
+
+// handleCohortStatistics reports the per-flight airspeed and altitude metrics of the participant flights
+// selected by participant, condition or flight ID, with their mean and standard deviation per group
+func handleCohortStatistics(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var filter CohortFilter
+	for _, participantID := range strings.Split(query.Get("participant"), ",") {
+		if participantID = strings.TrimSpace(participantID); participantID != "" {
+			filter.Participants = append(filter.Participants, participantID)
+		}
+	}
+	filter.Condition = query.Get("condition")
+	if value := query.Get("flight_ids"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || id <= 0 {
+				http.Error(w, fmt.Sprintf("invalid flight ID '%s'", part), http.StatusBadRequest)
+				return
+			}
+			filter.FlightIDs = append(filter.FlightIDs, id)
+		}
+	}
+
+	groupBy := query.Get("group_by")
+	if groupBy == "" {
+		groupBy = "condition"
+	}
+
+	result, err := CohortFlightStatistics(filter, groupBy)
+	if errors.Is(err, errUnknownAggregation) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to compute cohort statistics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
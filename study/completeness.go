@@ -0,0 +1,139 @@
+package study
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+	"github.com/kaireichart/master-thesis-operator-station/mental_rotation"
+)
+
+// Artifact statuses reported by the completeness check
+const (
+	ArtifactPresent    = "present"
+	ArtifactIncomplete = "incomplete"
+	ArtifactMissing    = "missing"
+)
+
+// Artifact is one piece of data expected from every participant
+type Artifact struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Completeness reports which expected artifacts of a participant exist
+type Completeness struct {
+	ParticipantID string     `json:"participantId"`
+	Complete      bool       `json:"complete"`
+	Artifacts     []Artifact `json:"artifacts"`
+	Missing       []string   `json:"missing"`
+}
+
+// CheckCompleteness collects the expected artifacts of a participant, in study order
+func CheckCompleteness(participantID string) (*Completeness, error) {
+	flightStatistics, err := data_analysis.GetParticipantFlightStatistics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get participant flights: %w", err)
+	}
+	flights := flightStatistics[participantID]
+
+	artifacts := []Artifact{
+		questionnaireArtifact(participantID, "pre"),
+		mentalRotationArtifact(participantID),
+		flightArtifact(flights, "baseline"),
+		flightArtifact(flights, "failure"),
+		questionnaireArtifact(participantID, "post"),
+	}
+
+	result := &Completeness{
+		ParticipantID: participantID,
+		Complete:      true,
+		Artifacts:     artifacts,
+		Missing:       []string{},
+	}
+	for _, a := range artifacts {
+		if a.Status != ArtifactPresent {
+			result.Complete = false
+			result.Missing = append(result.Missing, a.Name)
+		}
+	}
+	return result, nil
+}
+
+func questionnaireArtifact(participantID, name string) Artifact {
+	artifact := Artifact{Name: name + "_questionnaire", Status: ArtifactMissing}
+	if q, ok := getQuestionnaire(participantID, name); ok {
+		artifact.Status = ArtifactPresent
+		artifact.Detail = fmt.Sprintf("recorded %s", q.RecordedAt.Format("2006-01-02 15:04:05"))
+	}
+	return artifact
+}
+
+// mentalRotationArtifact is present once the participant received a completion code; results
+// without one mean the test was aborted
+func mentalRotationArtifact(participantID string) Artifact {
+	artifact := Artifact{Name: "mental_rotation", Status: ArtifactMissing}
+
+	var trials int
+	for _, result := range mental_rotation.AllResults() {
+		if result.ParticipantID == participantID {
+			trials++
+		}
+	}
+
+	if completion, ok := mental_rotation.GetCompletion(participantID); ok {
+		artifact.Status = ArtifactPresent
+		artifact.Detail = fmt.Sprintf("%d trials, completed %s", trials, completion.CompletedAt.Format("2006-01-02 15:04:05"))
+	} else if trials > 0 {
+		artifact.Status = ArtifactIncomplete
+		artifact.Detail = fmt.Sprintf("%d trials, not completed", trials)
+	}
+	return artifact
+}
+
+// flightArtifact is present when a flight with position data was assigned to the participant under the condition
+func flightArtifact(flights []data_analysis.ParticipantFlightStatistics, condition string) Artifact {
+	artifact := Artifact{Name: condition + "_flight", Status: ArtifactMissing}
+
+	var ids []string
+	for _, f := range flights {
+		if f.Flight.Condition != condition {
+			continue
+		}
+		ids = append(ids, fmt.Sprintf("%d", f.Flight.ID))
+		if f.Statistics == nil {
+			artifact.Status = ArtifactIncomplete
+		} else {
+			artifact.Status = ArtifactPresent
+		}
+	}
+
+	switch artifact.Status {
+	case ArtifactPresent:
+		artifact.Detail = "flight " + strings.Join(ids, ", ")
+	case ArtifactIncomplete:
+		artifact.Detail = "flight " + strings.Join(ids, ", ") + " has no data for the participant's aircraft"
+	}
+	return artifact
+}
+
+// handleCompleteness reports which expected artifacts of a participant exist and which are missing
+func handleCompleteness(w http.ResponseWriter, r *http.Request) {
+	participantID := strings.TrimSpace(r.PathValue("id"))
+	if participantID == "" {
+		http.Error(w, "participant ID is required", http.StatusBadRequest)
+		return
+	}
+
+	completeness, err := CheckCompleteness(participantID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to check completeness: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(completeness)
+}
@@ -0,0 +1,453 @@
+package study
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+	"github.com/kaireichart/master-thesis-operator-station/events"
+)
+
+// ErrAlreadyFrozen is returned when freezing a dataset that is frozen already
+var ErrAlreadyFrozen = errors.New("dataset is already frozen")
+
+// ErrNotFrozen is returned when unfreezing or verifying a dataset that is not frozen
+var ErrNotFrozen = errors.New("dataset is not frozen")
+
+// FreezeState marks the dataset as read-only since a snapshot of it was taken. It is kept in
+// data/freeze.json.
+type FreezeState struct {
+	FrozenAt time.Time `json:"frozenAt"`
+	Snapshot string    `json:"snapshot"` // Folder of the snapshot, e.g. snapshots/2025-09-30_17-00-00
+}
+
+// ManifestFile is the checksum of one file of a snapshot
+type ManifestFile struct {
+	Path   string `json:"path"` // Relative to the snapshot folder
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists the files of a snapshot with their checksums
+type Manifest struct {
+	FrozenAt time.Time      `json:"frozenAt"`
+	Files    []ManifestFile `json:"files"`
+}
+
+// Verification is the result of checking a snapshot against its manifest
+type Verification struct {
+	Snapshot   string   `json:"snapshot"`
+	Valid      bool     `json:"valid"`
+	Files      int      `json:"files"`
+	Mismatched []string `json:"mismatched"` // Files whose size or checksum changed
+	Missing    []string `json:"missing"`
+}
+
+// Layout of the snapshots; each snapshot folder has data, logs and exports subfolders
+const (
+	snapshotsDir    = "snapshots"
+	manifestName    = "manifest.json"
+	checksumsName   = "SHA256SUMS"
+	freezeStateFile = "freeze.json"
+)
+
+var (
+	freezeMutex = &sync.Mutex{}
+	freezeState *FreezeState // nil while the dataset is not frozen
+)
+
+// initFreeze restores the freeze state, keeping a frozen dataset read-only across restarts
+func initFreeze() {
+	data, err := os.ReadFile(filepath.Join("data", freezeStateFile))
+	if err != nil {
+		return
+	}
+	var state FreezeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("Failed to load freeze state: %v", err)
+		return
+	}
+	freezeState = &state
+	data_analysis.SetReadOnly(true)
+	log.Printf("Dataset is frozen since %s (snapshot %s)", state.FrozenAt.Format(time.RFC3339), state.Snapshot)
+}
+
+// GetFreezeState returns the freeze state, nil if the dataset is not frozen
+func GetFreezeState() *FreezeState {
+	freezeMutex.Lock()
+	defer freezeMutex.Unlock()
+	return freezeState
+}
+
+// Freeze makes the dataset read-only and takes a snapshot of it for archiving: a copy of the analysis
+// database, the other data files and the event logs, the flight, statistics and study metrics exports,
+// and a manifest with the SHA-256 checksum of every file.
+func Freeze() (*FreezeState, *Manifest, error) {
+	freezeMutex.Lock()
+	defer freezeMutex.Unlock()
+	if freezeState != nil {
+		return nil, nil, ErrAlreadyFrozen
+	}
+
+	// Read-only before copying, so nothing changes while the snapshot is taken
+	now := time.Now()
+	state := &FreezeState{FrozenAt: now, Snapshot: filepath.Join(snapshotsDir, now.Format("2006-01-02_15-04-05"))}
+	freezeState = state
+	data_analysis.SetReadOnly(true)
+
+	manifest, err := writeSnapshot(state)
+	if err == nil {
+		err = saveFreezeState(state)
+	}
+	if err != nil {
+		freezeState = nil
+		data_analysis.SetReadOnly(false)
+		os.RemoveAll(state.Snapshot)
+		return nil, nil, err
+	}
+
+	log.Printf("Froze dataset with %d files in %s", len(manifest.Files), state.Snapshot)
+	events.LogEvent(events.Event{
+		Type:      "dataset_frozen",
+		Program:   state.Snapshot,
+		Timestamp: now,
+	})
+	return state, manifest, nil
+}
+
+// Unfreeze makes the dataset writable again; the snapshot is kept
+func Unfreeze() error {
+	freezeMutex.Lock()
+	defer freezeMutex.Unlock()
+	if freezeState == nil {
+		return ErrNotFrozen
+	}
+
+	if err := os.Remove(filepath.Join("data", freezeStateFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove freeze state: %w", err)
+	}
+	snapshot := freezeState.Snapshot
+	freezeState = nil
+	data_analysis.SetReadOnly(false)
+
+	events.LogEvent(events.Event{
+		Type:      "dataset_unfrozen",
+		Program:   snapshot,
+		Timestamp: time.Now(),
+	})
+	return nil
+}
+
+// writeSnapshot copies the dataset into the snapshot folder and writes its manifest
+func writeSnapshot(state *FreezeState) (*Manifest, error) {
+	dataDir := filepath.Join(state.Snapshot, "data")
+	logsDir := filepath.Join(state.Snapshot, "logs")
+	exportsDir := filepath.Join(state.Snapshot, "exports")
+	for _, dir := range []string{dataDir, logsDir, exportsDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create snapshot folder: %w", err)
+		}
+	}
+
+	database := data_analysis.DatabaseFile()
+	if err := data_analysis.SnapshotDatabase(filepath.Join(dataDir, filepath.Base(database))); err != nil {
+		return nil, err
+	}
+
+	// Session, result and questionnaire files; the database and its journal files are snapshotted above
+	err := copyFiles("data", dataDir, func(name string) bool {
+		return !strings.HasPrefix(name, filepath.Base(database)) && !strings.HasSuffix(name, ".tmp") && name != freezeStateFile
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Event logs; the journal only holds events that are in the logs as well
+	if err := copyFiles("logs", logsDir, func(name string) bool { return strings.HasSuffix(name, ".log") }); err != nil {
+		return nil, err
+	}
+
+	if _, err := data_analysis.WriteDatasetExports(exportsDir); err != nil {
+		return nil, err
+	}
+	if err := writeMetricsFile(filepath.Join(exportsDir, "study_metrics.csv")); err != nil {
+		return nil, err
+	}
+
+	manifest, err := checksumSnapshot(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeManifest(state.Snapshot, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// copyFiles copies the regular files of a folder selected by include; subfolders are left out
+func copyFiles(from, to string, include func(name string) bool) error {
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", from, err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !include(entry.Name()) {
+			continue
+		}
+		if err := copyFile(filepath.Join(from, entry.Name()), filepath.Join(to, entry.Name())); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+func copyFile(from, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+	return target.Close()
+}
+
+// writeMetricsFile writes the study metrics matrix as CSV
+func writeMetricsFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create study metrics export: %w", err)
+	}
+	if err := writeMetricsCSV(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// checksumSnapshot computes the checksums of all files in the snapshot folder
+func checksumSnapshot(state *FreezeState) (*Manifest, error) {
+	manifest := &Manifest{FrozenAt: state.FrozenAt, Files: []ManifestFile{}}
+	err := filepath.WalkDir(state.Snapshot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relative, err := filepath.Rel(state.Snapshot, path)
+		if err != nil {
+			return err
+		}
+		if relative == manifestName || relative == checksumsName {
+			return nil
+		}
+
+		size, sum, err := checksumFile(path)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, ManifestFile{Path: filepath.ToSlash(relative), Size: size, SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to checksum snapshot: %w", err)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	return manifest, nil
+}
+
+func checksumFile(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeManifest writes the manifest as JSON and as a SHA256SUMS file, which sha256sum -c can check
+// without the station
+func writeManifest(snapshot string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(snapshot, manifestName), data, 0444); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	var sums strings.Builder
+	for _, file := range manifest.Files {
+		fmt.Fprintf(&sums, "%s  %s\n", file.SHA256, file.Path)
+	}
+	if err := os.WriteFile(filepath.Join(snapshot, checksumsName), []byte(sums.String()), 0444); err != nil {
+		return fmt.Errorf("failed to write checksums: %w", err)
+	}
+	return nil
+}
+
+func saveFreezeState(state *FreezeState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join("data", freezeStateFile)
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write freeze state: %w", err)
+	}
+	if err := os.Rename(tempFile, path); err != nil {
+		return fmt.Errorf("failed to replace freeze state: %w", err)
+	}
+	return nil
+}
+
+// VerifySnapshot checks the files of the frozen dataset's snapshot against its manifest
+func VerifySnapshot() (*Verification, error) {
+	state := GetFreezeState()
+	if state == nil {
+		return nil, ErrNotFrozen
+	}
+
+	data, err := os.ReadFile(filepath.Join(state.Snapshot, manifestName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	verification := &Verification{
+		Snapshot:   state.Snapshot,
+		Files:      len(manifest.Files),
+		Mismatched: []string{},
+		Missing:    []string{},
+	}
+	for _, file := range manifest.Files {
+		size, sum, err := checksumFile(filepath.Join(state.Snapshot, filepath.FromSlash(file.Path)))
+		if os.IsNotExist(err) {
+			verification.Missing = append(verification.Missing, file.Path)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum %s: %w", file.Path, err)
+		}
+		if size != file.Size || sum != file.SHA256 {
+			verification.Mismatched = append(verification.Mismatched, file.Path)
+		}
+	}
+	verification.Valid = len(verification.Mismatched) == 0 && len(verification.Missing) == 0
+	return verification, nil
+}
+
+// frozenPaths are the path prefixes of the modules writing the dataset. Live-station features such as
+// event logging, GPS recording, programs and RPC are not part of it and keep working while frozen.
+var frozenPaths = []string{"/data-analysis/", "/mental-rotation/", "/study/", "/participants/"}
+
+// RejectWritesWhenFrozen wraps the station's handlers so requests that could change the dataset are
+// rejected with 423 Locked while it is frozen; reading and unfreezing stay possible
+func RejectWritesWhenFrozen(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if GetFreezeState() != nil && writesDataset(r) {
+				http.Error(w, "Dataset is frozen; unfreeze it with DELETE /study/freeze to make changes", http.StatusLocked)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writesDataset reports whether a request that is not a read goes to a module writing the dataset;
+// unfreezing is let through
+func writesDataset(r *http.Request) bool {
+	if r.Method == http.MethodDelete && r.URL.Path == "/study/freeze" {
+		return false
+	}
+	return slices.ContainsFunc(frozenPaths, func(prefix string) bool {
+		return strings.HasPrefix(r.URL.Path, prefix)
+	})
+}
+
+func handleGetFreeze(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"frozen": GetFreezeState() != nil,
+		"state":  GetFreezeState(),
+	})
+}
+
+func handleFreeze(w http.ResponseWriter, r *http.Request) {
+	state, manifest, err := Freeze()
+	if err == ErrAlreadyFrozen {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to freeze dataset: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to freeze dataset: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"state":    state,
+		"manifest": manifest,
+	})
+}
+
+func handleUnfreeze(w http.ResponseWriter, r *http.Request) {
+	err := Unfreeze()
+	if err == ErrNotFrozen {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleVerifySnapshot(w http.ResponseWriter, r *http.Request) {
+	verification, err := VerifySnapshot()
+	if err == ErrNotFrozen {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+}
@@ -0,0 +1,48 @@
+package study
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRejectWritesWhenFrozen(t *testing.T) {
+	previous := freezeState
+	freezeState = &FreezeState{}
+	t.Cleanup(func() { freezeState = previous })
+
+	handler := RejectWritesWhenFrozen(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodPost, "/data-analysis/flights/1/markers", http.StatusLocked},
+		{http.MethodPut, "/data-analysis/flights/1/participant", http.StatusLocked},
+		{http.MethodPost, "/mental-rotation/submit", http.StatusLocked},
+		{http.MethodPost, "/participants/P001/questionnaires/pre", http.StatusLocked},
+		{http.MethodPost, "/study/freeze", http.StatusLocked},
+		{http.MethodDelete, "/study/freeze", http.StatusNoContent},
+		{http.MethodGet, "/data-analysis/flights", http.StatusNoContent},
+		{http.MethodPost, "/events", http.StatusNoContent},
+		{http.MethodPost, "/gps/reference", http.StatusNoContent},
+		{http.MethodPost, "/programs/start", http.StatusNoContent},
+		{http.MethodPost, "/sessions/start", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.path, nil))
+		if recorder.Code != tt.want {
+			t.Errorf("%s %s returned %d, want %d", tt.method, tt.path, recorder.Code, tt.want)
+		}
+	}
+
+	freezeState = nil
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/data-analysis/flights/1/markers", nil))
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("POST while not frozen returned %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,155 @@
+package study
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// questionnaireNames are the questionnaires each participant is expected to fill in
+var questionnaireNames = []string{"pre", "post"}
+
+// QuestionnaireRecord marks a questionnaire as filled in by a participant. Questionnaires are
+// administered outside the station, so the operator records them here.
+type QuestionnaireRecord struct {
+	ParticipantID string    `json:"participantId"`
+	Questionnaire string    `json:"questionnaire"`
+	RecordedAt    time.Time `json:"recordedAt"`
+	TLXScore      *float64  `json:"tlxScore,omitempty"` // NASA-TLX workload score from 0 to 100, if scored
+}
+
+var (
+	questionnaires     []QuestionnaireRecord
+	questionnaireMutex = &sync.Mutex{}
+	questionnairesFile string
+)
+
+// getQuestionnaire returns the record of a participant's questionnaire, if it was recorded
+func getQuestionnaire(participantID, questionnaire string) (QuestionnaireRecord, bool) {
+	questionnaireMutex.Lock()
+	defer questionnaireMutex.Unlock()
+
+	for _, q := range questionnaires {
+		if q.ParticipantID == participantID && q.Questionnaire == questionnaire {
+			return q, true
+		}
+	}
+	return QuestionnaireRecord{}, false
+}
+
+// tlxScores returns the mean NASA-TLX score recorded for each participant
+func tlxScores() map[string]float64 {
+	questionnaireMutex.Lock()
+	defer questionnaireMutex.Unlock()
+
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	for _, q := range questionnaires {
+		if q.TLXScore == nil {
+			continue
+		}
+		sums[q.ParticipantID] += *q.TLXScore
+		counts[q.ParticipantID]++
+	}
+	for id, count := range counts {
+		sums[id] /= float64(count)
+	}
+	return sums
+}
+
+// saveQuestionnaires writes the questionnaire records to a temporary file and renames it over the
+// questionnaires file. Must be called with questionnaireMutex held.
+func saveQuestionnaires() error {
+	data, err := json.MarshalIndent(questionnaires, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile := questionnairesFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write questionnaires: %w", err)
+	}
+	if err := os.Rename(tempFile, questionnairesFile); err != nil {
+		return fmt.Errorf("failed to replace questionnaires file: %w", err)
+	}
+	return nil
+}
+
+// handleRecordQuestionnaire records that a participant filled in a questionnaire (POST) or removes
+// the record again (DELETE). A POST body of {"tlxScore": ...} records the participant's NASA-TLX score
+// with it.
+func handleRecordQuestionnaire(w http.ResponseWriter, r *http.Request) {
+	participantID := strings.TrimSpace(r.PathValue("id"))
+	name := r.PathValue("name")
+	if participantID == "" {
+		http.Error(w, "participant ID is required", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(questionnaireNames, name) {
+		http.Error(w, fmt.Sprintf("unknown questionnaire '%s' (available: %s)", name, strings.Join(questionnaireNames, ", ")), http.StatusNotFound)
+		return
+	}
+
+	var body struct {
+		TLXScore *float64 `json:"tlxScore"`
+	}
+	if r.Method == http.MethodPost && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if body.TLXScore != nil && (*body.TLXScore < 0 || *body.TLXScore > 100) {
+			http.Error(w, "tlxScore must be between 0 and 100", http.StatusBadRequest)
+			return
+		}
+	}
+
+	questionnaireMutex.Lock()
+	defer questionnaireMutex.Unlock()
+
+	index := slices.IndexFunc(questionnaires, func(q QuestionnaireRecord) bool {
+		return q.ParticipantID == participantID && q.Questionnaire == name
+	})
+
+	if r.Method == http.MethodDelete {
+		if index < 0 {
+			http.Error(w, "questionnaire not recorded", http.StatusNotFound)
+			return
+		}
+		questionnaires = slices.Delete(questionnaires, index, index+1)
+		if err := saveQuestionnaires(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Recording again keeps the original time, replacing the score if one is given
+	if index < 0 || body.TLXScore != nil {
+		if index < 0 {
+			questionnaires = append(questionnaires, QuestionnaireRecord{
+				ParticipantID: participantID,
+				Questionnaire: name,
+				RecordedAt:    time.Now(),
+			})
+			index = len(questionnaires) - 1
+		}
+		if body.TLXScore != nil {
+			questionnaires[index].TLXScore = body.TLXScore
+		}
+		if err := saveQuestionnaires(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(questionnaires[index])
+}
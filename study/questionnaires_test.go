@@ -0,0 +1,59 @@
+package study
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordQuestionnaire posts a questionnaire record with a body, returning the response status
+func recordQuestionnaire(t *testing.T, participantID, name, body string) int {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /participants/{id}/questionnaires/{name}", handleRecordQuestionnaire)
+	request := httptest.NewRequest(http.MethodPost, "/participants/"+participantID+"/questionnaires/"+name, strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func TestTLXScores(t *testing.T) {
+	previousRecords, previousFile := questionnaires, questionnairesFile
+	questionnaires, questionnairesFile = nil, filepath.Join(t.TempDir(), "questionnaires.json")
+	t.Cleanup(func() { questionnaires, questionnairesFile = previousRecords, previousFile })
+
+	requests := []struct {
+		participantID, name, body string
+		status                    int
+	}{
+		{"P001", "pre", `{"tlxScore": 30}`, http.StatusOK},
+		{"P001", "post", `{"tlxScore": 70}`, http.StatusOK},
+		{"P002", "pre", "", http.StatusOK},
+		{"P002", "post", `{"tlxScore": 101}`, http.StatusBadRequest},
+		{"P003", "post", `{"tlxScore": 10}`, http.StatusOK},
+		{"P003", "post", `{"tlxScore": 20}`, http.StatusOK}, // Replaces the score
+		{"P003", "post", "", http.StatusOK},                 // Keeps the score
+	}
+	for _, r := range requests {
+		if status := recordQuestionnaire(t, r.participantID, r.name, r.body); status != r.status {
+			t.Fatalf("recording %s of %s with %q returned %d, want %d", r.name, r.participantID, r.body, status, r.status)
+		}
+	}
+
+	scores := tlxScores()
+	want := map[string]float64{"P001": 50, "P003": 20}
+	if len(scores) != len(want) {
+		t.Fatalf("tlxScores() = %v, want %v", scores, want)
+	}
+	for id, score := range want {
+		if scores[id] != score {
+			t.Errorf("tlxScores()[%s] = %v, want %v", id, scores[id], score)
+		}
+	}
+	if record, ok := getQuestionnaire("P002", "post"); ok {
+		t.Errorf("questionnaire with an invalid score was recorded: %+v", record)
+	}
+}
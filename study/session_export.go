@@ -0,0 +1,206 @@
+package study
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+	"github.com/kaireichart/master-thesis-operator-station/events"
+	"github.com/kaireichart/master-thesis-operator-station/mental_rotation"
+	"github.com/kaireichart/master-thesis-operator-station/sessions"
+)
+
+// BundleFile describes one file of a session export bundle
+type BundleFile struct {
+	Path        string `json:"path"`
+	Description string `json:"description"`
+}
+
+// BundleManifest describes the contents of a session export bundle, so the archived bundle can be read
+// without the station
+type BundleManifest struct {
+	SessionID     int          `json:"sessionId"`
+	ParticipantID string       `json:"participantId"`
+	GeneratedAt   time.Time    `json:"generatedAt"`
+	FlightIDs     []int        `json:"flightIds"`
+	Notes         []string     `json:"notes"`
+	Files         []BundleFile `json:"files"`
+}
+
+// bundleNotes explain the conventions shared by the files of a bundle
+var bundleNotes = []string{
+	"Timestamps in JSON files are RFC 3339; times in flight CSV files are seconds from the flight start.",
+	"Flights are those imported while the session was active, and those assigned to the participant that were not imported during another session.",
+	"MRT results and questionnaires belong to the participant and are included in full, also when the participant had several sessions.",
+}
+
+// sessionBundle holds the data of a session export, collected before the archive is streamed
+type sessionBundle struct {
+	session        sessions.Session
+	flights        []*data_analysis.Flight
+	events         []events.Event
+	mrtResults     []mental_rotation.Result
+	completion     *mental_rotation.Completion
+	questionnaires []QuestionnaireRecord
+}
+
+// getSession returns the session with the given ID
+func getSession(id int) (sessions.Session, bool) {
+	for _, s := range sessions.GetSessions() {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return sessions.Session{}, false
+}
+
+// collectSessionBundle gathers the flights, events, MRT results and questionnaires of a session
+func collectSessionBundle(session sessions.Session) (*sessionBundle, error) {
+	bundle := &sessionBundle{session: session, mrtResults: []mental_rotation.Result{}, questionnaires: []QuestionnaireRecord{}}
+
+	flights, err := data_analysis.SessionFlights(session.ID, session.ParticipantID)
+	if err != nil {
+		return nil, err
+	}
+	bundle.flights = flights
+
+	// An active session covers the events up to now
+	end := time.Now()
+	if session.EndedAt != nil {
+		end = *session.EndedAt
+	}
+	bundle.events, err = events.Between(session.StartedAt, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+	if bundle.events == nil {
+		bundle.events = []events.Event{}
+	}
+
+	for _, result := range mental_rotation.AllResults() {
+		if result.ParticipantID == session.ParticipantID {
+			bundle.mrtResults = append(bundle.mrtResults, result)
+		}
+	}
+	if completion, ok := mental_rotation.GetCompletion(session.ParticipantID); ok {
+		bundle.completion = &completion
+	}
+
+	for _, name := range questionnaireNames {
+		if record, ok := getQuestionnaire(session.ParticipantID, name); ok {
+			bundle.questionnaires = append(bundle.questionnaires, record)
+		}
+	}
+	return bundle, nil
+}
+
+// writeJSONFile adds a JSON file to the archive and describes it in the manifest
+func writeJSONFile(zw *zip.Writer, manifest *BundleManifest, name, description string, v interface{}) error {
+	file, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s in zip: %w", name, err)
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	manifest.Files = append(manifest.Files, BundleFile{Path: name, Description: description})
+	return nil
+}
+
+// writeSessionBundle writes the ZIP of a session export, with manifest.json describing every other file
+func writeSessionBundle(zw *zip.Writer, bundle *sessionBundle) error {
+	manifest := &BundleManifest{
+		SessionID:     bundle.session.ID,
+		ParticipantID: bundle.session.ParticipantID,
+		GeneratedAt:   time.Now().UTC(),
+		FlightIDs:     []int{},
+		Notes:         bundleNotes,
+		Files:         []BundleFile{},
+	}
+
+	if err := writeJSONFile(zw, manifest, "session.json", "The session with its start and end, pauses and logged simulator conditions", bundle.session); err != nil {
+		return err
+	}
+	if err := writeJSONFile(zw, manifest, "events.json", "Events logged from the start to the end of the session (type, program, timestamp)", bundle.events); err != nil {
+		return err
+	}
+	if err := writeJSONFile(zw, manifest, "mental_rotation/results.json", "MRT trials of the participant: isCorrect, timeTaken in nanoseconds, timestamp", bundle.mrtResults); err != nil {
+		return err
+	}
+	if bundle.completion != nil {
+		if err := writeJSONFile(zw, manifest, "mental_rotation/completion.json", "Completion code issued to the participant after the MRT", bundle.completion); err != nil {
+			return err
+		}
+	}
+	if err := writeJSONFile(zw, manifest, "questionnaires.json", "Questionnaires recorded as filled in by the participant, with the time they were recorded", bundle.questionnaires); err != nil {
+		return err
+	}
+
+	folders, err := data_analysis.WriteFlightFolders(zw, "flights", bundle.flights)
+	if err != nil {
+		return err
+	}
+	for i, folder := range folders {
+		manifest.FlightIDs = append(manifest.FlightIDs, bundle.flights[i].ID)
+		for _, f := range data_analysis.FlightFolderFiles {
+			description := fmt.Sprintf("Flight %d (%s): %s", bundle.flights[i].ID, bundle.flights[i].Title, f.Description)
+			manifest.Files = append(manifest.Files, BundleFile{Path: path.Join(folder, f.Name), Description: description})
+		}
+	}
+
+	file, err := zw.Create("manifest.json")
+	if err != nil {
+		return fmt.Errorf("failed to create manifest.json in zip: %w", err)
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write manifest.json: %w", err)
+	}
+	return nil
+}
+
+// handleSessionExport exports everything recorded for one session into a ZIP, one archive per participant:
+// its flights as CSV with their markers, the events, MRT results, questionnaires and a manifest
+func handleSessionExport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid session ID", http.StatusBadRequest)
+		return
+	}
+	session, ok := getSession(id)
+	if !ok {
+		http.Error(w, fmt.Sprintf("session %d not found", id), http.StatusNotFound)
+		return
+	}
+
+	bundle, err := collectSessionBundle(session)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to collect session data: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("session_%d_%s.zip", session.ID, session.ParticipantID)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	// The archive is streamed, so errors while writing it can only be logged
+	zw := zip.NewWriter(w)
+	if err := writeSessionBundle(zw, bundle); err != nil {
+		log.Printf("Session export %d: %v", session.ID, err)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Session export %d: failed to close zip writer: %v", session.ID, err)
+		return
+	}
+	log.Printf("Session export %d: exported %d flights", session.ID, len(bundle.flights))
+}
@@ -0,0 +1,230 @@
+package study
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kaireichart/master-thesis-operator-station/data_analysis"
+	"github.com/kaireichart/master-thesis-operator-station/mental_rotation"
+	"github.com/kaireichart/master-thesis-operator-station/sessions"
+)
+
+// metricsColumns are the columns of the study metrics matrix, one row per participant
+var metricsColumns = []string{
+	"participant_id",
+	"sessions",
+	"mrt_trials",
+	"mrt_score",
+	"mrt_mean_rt_ms",
+	"flights",
+	"altitude_rmse",
+	"airspeed_variance",
+	"response_latency_s",
+	"tlx_score",
+}
+
+// ParticipantMetrics holds the metrics of one participant collected from all subsystems
+type ParticipantMetrics struct {
+	ParticipantID    string
+	Sessions         int
+	MRTTrials        int
+	MRTScore         int
+	MRTMeanRTMs      *float64
+	Flights          int
+	AltitudeRMSE     *float64 // Against the configured altitude reference profile, in the configured units
+	AirspeedVariance *float64 // In the square of the configured speed unit
+	ResponseLatency  *float64 // Seconds from a failure to the first significant input
+	TLXScore         *float64 // Mean of the NASA-TLX scores recorded with the questionnaires
+}
+
+func Init() {
+	questionnairesFile = filepath.Join("data", "questionnaires.json")
+
+	if err := os.MkdirAll("data", 0755); err != nil {
+		panic(err)
+	}
+
+	if data, err := os.ReadFile(questionnairesFile); err == nil {
+		if err := json.Unmarshal(data, &questionnaires); err != nil {
+			panic(err)
+		}
+	}
+
+	initFreeze()
+}
+
+func SetupHandlers() {
+	http.HandleFunc("GET /study/metrics.csv", handleMetricsCSV)
+	http.HandleFunc("GET /study/aggregate-statistics", handleAggregateStatistics)
+	http.HandleFunc("GET /study/cohort-statistics", handleCohortStatistics)
+	http.HandleFunc("GET /study/freeze", handleGetFreeze)
+	http.HandleFunc("POST /study/freeze", handleFreeze)
+	http.HandleFunc("DELETE /study/freeze", handleUnfreeze)
+	http.HandleFunc("GET /study/freeze/verify", handleVerifySnapshot)
+	http.HandleFunc("GET /sessions/{id}/export", handleSessionExport)
+	http.HandleFunc("GET /participants/{id}/completeness", handleCompleteness)
+	http.HandleFunc("POST /participants/{id}/questionnaires/{name}", handleRecordQuestionnaire)
+	http.HandleFunc("DELETE /participants/{id}/questionnaires/{name}", handleRecordQuestionnaire)
+}
+
+// CollectMetrics gathers the metrics of every participant known to any subsystem
+func CollectMetrics() ([]ParticipantMetrics, error) {
+	metrics := map[string]*ParticipantMetrics{}
+	participant := func(id string) *ParticipantMetrics {
+		if metrics[id] == nil {
+			metrics[id] = &ParticipantMetrics{ParticipantID: id}
+		}
+		return metrics[id]
+	}
+
+	for _, session := range sessions.GetSessions() {
+		participant(session.ParticipantID).Sessions++
+	}
+
+	// The frontend submits timeTaken in milliseconds, so the stored duration holds milliseconds as well
+	rtSums := map[string]float64{}
+	for _, result := range mental_rotation.AllResults() {
+		m := participant(result.ParticipantID)
+		m.MRTTrials++
+		if result.IsCorrect {
+			m.MRTScore++
+		}
+		rtSums[result.ParticipantID] += float64(result.TimeTaken)
+	}
+	for id, sum := range rtSums {
+		mean := sum / float64(metrics[id].MRTTrials)
+		metrics[id].MRTMeanRTMs = &mean
+	}
+
+	flightStatistics, err := data_analysis.GetParticipantFlightStatistics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight statistics: %w", err)
+	}
+	for id, flights := range flightStatistics {
+		m := participant(id)
+		m.Flights = len(flights)
+
+		// Altitude RMSE is averaged over the participant's flights with samples within the reference profile
+		var rmseSum float64
+		var rmseCount int
+		for _, flight := range flights {
+			if flight.AltitudeRMSE == nil {
+				continue
+			}
+			rmseSum += data_analysis.ConvertAltitude(*flight.AltitudeRMSE)
+			rmseCount++
+		}
+		if rmseCount > 0 {
+			rmse := rmseSum / float64(rmseCount)
+			m.AltitudeRMSE = &rmse
+		}
+
+		// Airspeed variance is averaged over the participant's flights
+		var varianceSum float64
+		var varianceCount int
+		for _, flight := range flights {
+			if flight.Statistics == nil || flight.Statistics.AirspeedStats == nil {
+				continue
+			}
+			varianceSum += data_analysis.ConvertStatistics(flight.Statistics).AirspeedStats.Variance
+			varianceCount++
+		}
+		if varianceCount > 0 {
+			variance := varianceSum / float64(varianceCount)
+			m.AirspeedVariance = &variance
+		}
+
+		// Response latency is averaged over the participant's flights with a response to a failure
+		var latencySum float64
+		var latencyCount int
+		for _, flight := range flights {
+			if flight.ResponseLatency == nil || flight.ResponseLatency.LatencySeconds == nil {
+				continue
+			}
+			latencySum += *flight.ResponseLatency.LatencySeconds
+			latencyCount++
+		}
+		if latencyCount > 0 {
+			latency := latencySum / float64(latencyCount)
+			m.ResponseLatency = &latency
+		}
+	}
+
+	for id, score := range tlxScores() {
+		score := score
+		participant(id).TLXScore = &score
+	}
+
+	list := make([]ParticipantMetrics, 0, len(metrics))
+	for _, m := range metrics {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ParticipantID < list[j].ParticipantID
+	})
+	return list, nil
+}
+
+// record formats the metrics as a CSV row; metrics without data are left empty
+func (m ParticipantMetrics) record() []string {
+	return []string{
+		m.ParticipantID,
+		strconv.Itoa(m.Sessions),
+		strconv.Itoa(m.MRTTrials),
+		strconv.Itoa(m.MRTScore),
+		formatOptional(m.MRTMeanRTMs),
+		strconv.Itoa(m.Flights),
+		formatOptional(m.AltitudeRMSE),
+		formatOptional(m.AirspeedVariance),
+		formatOptional(m.ResponseLatency),
+		formatOptional(m.TLXScore),
+	}
+}
+
+func formatOptional(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', 4, 64)
+}
+
+// handleMetricsCSV exports the participants × metrics matrix as CSV
+func handleMetricsCSV(w http.ResponseWriter, r *http.Request) {
+	buf := new(bytes.Buffer)
+	if err := writeMetricsCSV(buf); err != nil {
+		log.Printf("Failed to write study metrics CSV: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to write study metrics CSV: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("study_metrics_%s.csv", time.Now().Format("20060102_150405"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	w.Write(buf.Bytes())
+}
+
+// writeMetricsCSV writes the participants × metrics matrix as CSV
+func writeMetricsCSV(w io.Writer) error {
+	metrics, err := CollectMetrics()
+	if err != nil {
+		return fmt.Errorf("failed to collect study metrics: %w", err)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write(metricsColumns)
+	for _, m := range metrics {
+		writer.Write(m.record())
+	}
+	writer.Flush()
+	return writer.Error()
+}